@@ -0,0 +1,267 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// AES-GCM-SIV (RFC 8452) parameters.
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+	gcmSIVBlockSize = 16
+	// gcmSIVMaxPlaintext is the RFC 8452 plaintext and AAD limit of 2^36 bytes.
+	gcmSIVMaxPlaintext = 1 << 36
+)
+
+// aesGCMSIV is a software implementation of AES-GCM-SIV as a cipher.AEAD.
+//
+// Unlike AES-GCM, repeating a nonce under the same key only reveals whether
+// two messages (with the same AAD) were identical; it does not leak the
+// authentication key or the XOR of plaintexts. Message keys are derived
+// per nonce from the key-generating key, as specified in RFC 8452 section 4.
+type aesGCMSIV struct {
+	keyGen  cipher.Block
+	keySize int
+}
+
+// newAESGCMSIV returns an AES-GCM-SIV AEAD for a 16 or 32 byte key.
+func newAESGCMSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, errors.New("AES-GCM-SIV key must be 16 or 32 bytes long")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesGCMSIV{keyGen: block, keySize: len(key)}, nil
+}
+
+func (a *aesGCMSIV) NonceSize() int { return gcmSIVNonceSize }
+
+func (a *aesGCMSIV) Overhead() int { return gcmSIVTagSize }
+
+func (a *aesGCMSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("aes-gcm-siv: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxPlaintext || uint64(len(additionalData)) > gcmSIVMaxPlaintext {
+		panic("aes-gcm-siv: message too large for AES-GCM-SIV")
+	}
+
+	authKey, encBlock := a.deriveKeys(nonce)
+	tag := a.tag(authKey, encBlock, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+	gcmSIVCTR(encBlock, tag, out[:len(plaintext)], plaintext)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (a *aesGCMSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("aes-gcm-siv: incorrect nonce length given to AES-GCM-SIV")
+	}
+	if len(ciphertext) < gcmSIVTagSize ||
+		uint64(len(ciphertext)) > gcmSIVMaxPlaintext+gcmSIVTagSize ||
+		uint64(len(additionalData)) > gcmSIVMaxPlaintext {
+		return nil, errors.New("aes-gcm-siv: message authentication failed")
+	}
+
+	authKey, encBlock := a.deriveKeys(nonce)
+
+	var expected [gcmSIVTagSize]byte
+	copy(expected[:], ciphertext[len(ciphertext)-gcmSIVTagSize:])
+	body := ciphertext[:len(ciphertext)-gcmSIVTagSize]
+
+	ret, out := sliceForAppend(dst, len(body))
+	gcmSIVCTR(encBlock, expected, out, body)
+
+	tag := a.tag(authKey, encBlock, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(tag[:], expected[:]) != 1 {
+		clear(out)
+		return nil, errors.New("aes-gcm-siv: message authentication failed")
+	}
+	return ret, nil
+}
+
+// deriveKeys derives the per-nonce POLYVAL key and AES encryption block.
+func (a *aesGCMSIV) deriveKeys(nonce []byte) ([gcmSIVBlockSize]byte, cipher.Block) {
+	var authKey [gcmSIVBlockSize]byte
+	encKey := make([]byte, a.keySize)
+
+	var in, out [gcmSIVBlockSize]byte
+	copy(in[4:], nonce)
+
+	chunks := 2 + a.keySize/8 // two chunks for the auth key, then the encryption key
+	for i := range chunks {
+		binary.LittleEndian.PutUint32(in[:4], uint32(i)) //nolint:gosec // i is at most 5
+		a.keyGen.Encrypt(out[:], in[:])
+		if i < 2 {
+			copy(authKey[i*8:], out[:8])
+		} else {
+			copy(encKey[(i-2)*8:], out[:8])
+		}
+	}
+
+	// aes.NewCipher only fails on invalid key sizes, which keySize rules out.
+	encBlock, _ := aes.NewCipher(encKey)
+	Zeroize(encKey)
+	return authKey, encBlock
+}
+
+// tag computes the AES-GCM-SIV authentication tag for plaintext and additionalData.
+func (a *aesGCMSIV) tag(
+	authKey [gcmSIVBlockSize]byte,
+	encBlock cipher.Block,
+	nonce, plaintext, additionalData []byte,
+) [gcmSIVTagSize]byte {
+	p := newPolyval(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+
+	var lengths [gcmSIVBlockSize]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+
+	var tag [gcmSIVTagSize]byte
+	encBlock.Encrypt(tag[:], s[:])
+	return tag
+}
+
+// gcmSIVCTR applies the AES-GCM-SIV counter mode keystream, whose initial
+// counter block is the tag with the top bit set and whose counter is the
+// first 32 bits interpreted little-endian.
+func gcmSIVCTR(block cipher.Block, tag [gcmSIVTagSize]byte, dst, src []byte) {
+	counter := tag
+	counter[15] |= 0x80
+	ctr := binary.LittleEndian.Uint32(counter[:4])
+
+	var keystream [gcmSIVBlockSize]byte
+	for len(src) > 0 {
+		binary.LittleEndian.PutUint32(counter[:4], ctr)
+		block.Encrypt(keystream[:], counter[:])
+		n := subtle.XORBytes(dst, src, keystream[:])
+		dst, src = dst[n:], src[n:]
+		ctr++
+	}
+}
+
+// sliceForAppend extends in by n bytes, returning the whole slice and the tail.
+func sliceForAppend(in []byte, n int) ([]byte, []byte) {
+	total := len(in) + n
+	var head []byte
+	if cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	return head, head[len(in):]
+}
+
+// fieldElement is an element of GF(2^128) in POLYVAL's little-endian bit order:
+// bit i of (lo, hi) is the coefficient of x^i.
+type fieldElement struct {
+	lo, hi uint64
+}
+
+// polyval accumulates the POLYVAL universal hash of RFC 8452 section 3.
+type polyval struct {
+	key fieldElement
+	acc fieldElement
+}
+
+func newPolyval(key [gcmSIVBlockSize]byte) *polyval {
+	return &polyval{key: fieldElement{
+		lo: binary.LittleEndian.Uint64(key[:8]),
+		hi: binary.LittleEndian.Uint64(key[8:]),
+	}}
+}
+
+// update absorbs data, zero padding the final partial block.
+func (p *polyval) update(data []byte) {
+	for len(data) > 0 {
+		var block [gcmSIVBlockSize]byte
+		n := copy(block[:], data)
+		data = data[n:]
+
+		p.acc.lo ^= binary.LittleEndian.Uint64(block[:8])
+		p.acc.hi ^= binary.LittleEndian.Uint64(block[8:])
+		p.acc = polyvalDot(p.acc, p.key)
+	}
+}
+
+func (p *polyval) sum() [gcmSIVBlockSize]byte {
+	var out [gcmSIVBlockSize]byte
+	binary.LittleEndian.PutUint64(out[:8], p.acc.lo)
+	binary.LittleEndian.PutUint64(out[8:], p.acc.hi)
+	return out
+}
+
+// polyvalDot computes a*b*x^-128 modulo x^128 + x^127 + x^126 + x^121 + 1.
+func polyvalDot(a, b fieldElement) fieldElement {
+	// Karatsuba carry-less multiplication into the 256-bit product r1:r0.
+	low := clmul64(a.lo, b.lo)
+	high := clmul64(a.hi, b.hi)
+	mid := clmul64(a.lo^a.hi, b.lo^b.hi)
+	mid.lo ^= low.lo ^ high.lo
+	mid.hi ^= low.hi ^ high.hi
+
+	r0 := fieldElement{lo: low.lo, hi: low.hi ^ mid.lo}
+	r1 := fieldElement{lo: high.lo ^ mid.hi, hi: high.hi}
+
+	// Montgomery reduction: pick q with q*P ≡ r0 (mod x^128); then
+	// (r1*x^128 + r0 + q*P) / x^128 = r1 + q + q/x + q/x^2 + q/x^7.
+	q := fieldElement{lo: r0.lo, hi: r0.hi ^ r0.lo<<57 ^ r0.lo<<62 ^ r0.lo<<63}
+
+	r1.lo ^= q.lo ^ (q.lo>>1 | q.hi<<63) ^ (q.lo>>2 | q.hi<<62) ^ (q.lo>>7 | q.hi<<57)
+	r1.hi ^= q.hi ^ q.hi>>1 ^ q.hi>>2 ^ q.hi>>7
+	return r1
+}
+
+// clmul64 returns the 128-bit carry-less product of x and y in constant time,
+// combining three 32-bit products Karatsuba style.
+func clmul64(x, y uint64) fieldElement {
+	x0, x1 := uint32(x), uint32(x>>32)
+	y0, y1 := uint32(y), uint32(y>>32)
+
+	lo := clmul32(x0, y0)
+	hi := clmul32(x1, y1)
+	mid := clmul32(x0^x1, y0^y1) ^ lo ^ hi
+
+	return fieldElement{lo: lo ^ mid<<32, hi: hi ^ mid>>32}
+}
+
+// clmul32 returns the carry-less product of x and y using integer multiplies.
+// Each operand is split into four interleaved bit masks, leaving three zero
+// bits between the sampled bits so that integer carries never reach a bit
+// position that is kept in the result.
+func clmul32(x, y uint32) uint64 {
+	const (
+		m0 = 0x1111111111111111
+		m1 = 0x2222222222222222
+		m2 = 0x4444444444444444
+		m3 = 0x8888888888888888
+	)
+
+	x0, x1, x2, x3 := uint64(x)&m0, uint64(x)&m1, uint64(x)&m2, uint64(x)&m3
+	y0, y1, y2, y3 := uint64(y)&m0, uint64(y)&m1, uint64(y)&m2, uint64(y)&m3
+
+	z0 := x0*y0 ^ x1*y3 ^ x2*y2 ^ x3*y1
+	z1 := x0*y1 ^ x1*y0 ^ x2*y3 ^ x3*y2
+	z2 := x0*y2 ^ x1*y1 ^ x2*y0 ^ x3*y3
+	z3 := x0*y3 ^ x1*y2 ^ x2*y1 ^ x3*y0
+
+	return z0&m0 | z1&m1 | z2&m2 | z3&m3
+}
@@ -0,0 +1,64 @@
+package util_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+// Known answers produced by an independent RFC 8452 implementation, wrapped in
+// the version 1 payload header (which is also the additional data).
+func TestDecryptValueAESGCMSIVKnownAnswers(t *testing.T) {
+	skipUnlessFIPSApproved(t, util.AlgorithmAESGCMSIV)
+
+	tests := []struct {
+		name      string
+		key       string
+		payload   string
+		plaintext string
+	}{
+		{
+			name:      "AES-128-GCM-SIV",
+			key:       "ee8e1ed9ff2540ae8f2ba9f50bc2f27c",
+			payload:   "0104fe87d5b4ac3af3bfa143e4c94a93082b5c933108bab7c9df910a6739a34a99716e787ce52cf7a4",
+			plaintext: "Hello world",
+		},
+		{
+			name:      "AES-256-GCM-SIV",
+			key:       "0100000000000000000000000000000000000000000000000000000000000000",
+			payload:   "0104c654f35c153508bed946929a6b8bb18dbfe0875a2499b013c4021f0eb81c1ca8064627aedb928de1d8f2d9bbef4f1496215c",
+			plaintext: "nonce misuse resistant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(tt.key)
+			payload, _ := hex.DecodeString(tt.payload)
+
+			got, err := util.DecryptValue(key, payload)
+			if err != nil {
+				t.Fatalf("DecryptValue() failed: %v", err)
+			}
+			if string(got) != tt.plaintext {
+				t.Errorf("DecryptValue() = %q, want %q", got, tt.plaintext)
+			}
+
+			payload[len(payload)-1] ^= 0x01
+			if _, err = util.DecryptValue(key, payload); err == nil {
+				t.Error("DecryptValue() should reject a modified tag")
+			}
+		})
+	}
+}
+
+func BenchmarkEncryptValueAESGCMSIV(b *testing.B) {
+	key := make([]byte, 32)
+	plaintext := make([]byte, 1024)
+
+	b.ResetTimer()
+	for range b.N {
+		_, _ = util.EncryptValueWithAlgo(util.AlgorithmAESGCMSIV, key, plaintext)
+	}
+}
@@ -0,0 +1,224 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+const (
+	sivBlockSize = 16
+	// sivRb is the CMAC doubling constant for 128-bit blocks (RFC 4493).
+	sivRb = 0x87
+)
+
+// aesSIV is AES-SIV (RFC 5297) exposed as a cipher.AEAD with a zero-length nonce.
+//
+// The synthetic IV is a CMAC-based PRF over the additional data and the
+// plaintext, so the same inputs always produce the same ciphertext. That is
+// what makes equality lookups on encrypted columns possible, and also why it
+// must not be used where revealing repeated plaintexts is unacceptable.
+type aesSIV struct {
+	mac cipher.Block // S2V key, the first half of the SIV key
+	ctr cipher.Block // CTR key, the second half of the SIV key
+	k1  [sivBlockSize]byte
+	k2  [sivBlockSize]byte
+}
+
+// newAESSIV returns an AES-SIV AEAD for a 32, 48 or 64 byte key
+// (AES-SIV-CMAC-256/384/512).
+func newAESSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 && len(key) != 48 && len(key) != 64 {
+		return nil, errors.New("AES-SIV key must be 32, 48, or 64 bytes long")
+	}
+
+	half := len(key) / 2
+	mac, err := aes.NewCipher(key[:half])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[half:])
+	if err != nil {
+		return nil, err
+	}
+
+	s := &aesSIV{mac: mac, ctr: ctr}
+	var l [sivBlockSize]byte
+	mac.Encrypt(l[:], l[:])
+	s.k1 = sivDouble(l)
+	s.k2 = sivDouble(s.k1)
+	return s, nil
+}
+
+func (s *aesSIV) NonceSize() int { return 0 }
+
+func (s *aesSIV) Overhead() int { return sivBlockSize }
+
+func (s *aesSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	v := s.s2v(additionalData, nonce, plaintext)
+
+	ret, out := sliceForAppend(dst, sivBlockSize+len(plaintext))
+	copy(out, v[:])
+	s.xorKeyStream(v, out[sivBlockSize:], plaintext)
+	return ret
+}
+
+func (s *aesSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < sivBlockSize {
+		return nil, errors.New("aes-siv: message authentication failed")
+	}
+
+	var v [sivBlockSize]byte
+	copy(v[:], ciphertext[:sivBlockSize])
+	body := ciphertext[sivBlockSize:]
+
+	ret, out := sliceForAppend(dst, len(body))
+	s.xorKeyStream(v, out, body)
+
+	expected := s.s2v(additionalData, nonce, out)
+	if subtle.ConstantTimeCompare(expected[:], v[:]) != 1 {
+		clear(out)
+		return nil, errors.New("aes-siv: message authentication failed")
+	}
+	return ret, nil
+}
+
+// s2v implements the S2V construction over the additional data, the optional
+// nonce and the plaintext (RFC 5297 section 2.4).
+func (s *aesSIV) s2v(additionalData, nonce, plaintext []byte) [sivBlockSize]byte {
+	var zero [sivBlockSize]byte
+	d := s.cmac(zero[:])
+
+	components := [][]byte{additionalData}
+	if len(nonce) > 0 {
+		components = append(components, nonce)
+	}
+	for _, component := range components {
+		d = sivDouble(d)
+		mac := s.cmac(component)
+		subtle.XORBytes(d[:], d[:], mac[:])
+	}
+
+	var t []byte
+	if len(plaintext) >= sivBlockSize {
+		t = make([]byte, len(plaintext))
+		copy(t, plaintext)
+		tail := t[len(t)-sivBlockSize:]
+		subtle.XORBytes(tail, tail, d[:])
+	} else {
+		d = sivDouble(d)
+		var padded [sivBlockSize]byte
+		copy(padded[:], plaintext)
+		padded[len(plaintext)] = 0x80
+		subtle.XORBytes(d[:], d[:], padded[:])
+		t = d[:]
+	}
+
+	return s.cmac(t)
+}
+
+// cmac computes AES-CMAC (RFC 4493) of msg under the S2V key.
+func (s *aesSIV) cmac(msg []byte) [sivBlockSize]byte {
+	var x [sivBlockSize]byte
+	for len(msg) > sivBlockSize {
+		subtle.XORBytes(x[:], x[:], msg[:sivBlockSize])
+		s.mac.Encrypt(x[:], x[:])
+		msg = msg[sivBlockSize:]
+	}
+
+	var last [sivBlockSize]byte
+	copy(last[:], msg)
+	if len(msg) == sivBlockSize {
+		subtle.XORBytes(last[:], last[:], s.k1[:])
+	} else {
+		last[len(msg)] = 0x80
+		subtle.XORBytes(last[:], last[:], s.k2[:])
+	}
+	subtle.XORBytes(x[:], x[:], last[:])
+	s.mac.Encrypt(x[:], x[:])
+	return x
+}
+
+// xorKeyStream runs AES-CTR from the synthetic IV with bits 31 and 63 cleared.
+func (s *aesSIV) xorKeyStream(v [sivBlockSize]byte, dst, src []byte) {
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(s.ctr, v[:]).XORKeyStream(dst, src)
+}
+
+// sivDouble multiplies a block by x in GF(2^128), as used by CMAC and S2V.
+func sivDouble(in [sivBlockSize]byte) [sivBlockSize]byte {
+	var out [sivBlockSize]byte
+	carry := in[0] >> 7
+	for i := range sivBlockSize - 1 {
+		out[i] = in[i]<<1 | in[i+1]>>7
+	}
+	out[sivBlockSize-1] = in[sivBlockSize-1]<<1 ^ byte(subtle.ConstantTimeSelect(int(carry), sivRb, 0))
+	return out
+}
+
+// EncryptDeterministic encrypts plaintext with AES-SIV so that identical
+// (key, plaintext, context) inputs always produce identical ciphertexts.
+//
+// Deterministic ciphertexts can be stored in an indexed column and queried
+// with an equality match by encrypting the search value the same way. It is
+// the recoverable complement to ComputeLookupToken: use a lookup token when
+// the column only needs to be searched, and EncryptDeterministic when the
+// original value must also be decrypted from the same column.
+//
+// Security properties:
+//   - Authenticated: tampering with the ciphertext or context is detected
+//   - Nonce-free: no nonce is stored or needs to be managed
+//   - Leaks equality: an observer learns which rows hold the same value,
+//     so prefer EncryptValue for anything that is never searched
+//
+// Parameters:
+//   - key: AES-SIV key (32, 48, or 64 bytes for AES-SIV-CMAC-256/384/512)
+//   - plaintext: Data to be encrypted
+//   - context: Associated data binding the ciphertext to its use, such as
+//     a table and column name or a tenant ID; it is required again to decrypt
+//
+// The returned payload format is: [version][algorithm][synthetic-iv][ciphertext]
+//
+// Example:
+//
+//	ciphertext, err := EncryptDeterministic(key, []byte("user@example.com"), []byte("users.email"))
+func EncryptDeterministic(key []byte, plaintext []byte, context []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, errors.New("plaintext cannot be empty")
+	}
+
+	siv, err := newAEAD(AlgorithmAESSIV, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []byte{payloadVersion1, byte(AlgorithmAESSIV)}
+	return siv.Seal(header, nil, plaintext, append(header[:payloadHeaderSize:payloadHeaderSize], context...)), nil
+}
+
+// DecryptDeterministic decrypts a payload produced by EncryptDeterministic.
+// The context must match the one used for encryption.
+func DecryptDeterministic(key []byte, payload []byte, context []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("payload cannot be empty")
+	}
+	if len(payload) < payloadHeaderSize ||
+		payload[0] != payloadVersion1 || Algorithm(payload[1]) != AlgorithmAESSIV {
+		return nil, errors.New("payload was not produced by EncryptDeterministic")
+	}
+
+	siv, err := newAEAD(AlgorithmAESSIV, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := payload[:payloadHeaderSize:payloadHeaderSize]
+	plaintext, err := siv.Open(nil, nil, payload[payloadHeaderSize:], append(header, context...))
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+	return plaintext, nil
+}
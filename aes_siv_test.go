@@ -0,0 +1,91 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEncryptDeterministicKnownAnswer(t *testing.T) {
+	skipUnlessFIPSApproved(t, util.AlgorithmAESSIV)
+
+	// Produced by an independent RFC 5297 implementation with the payload
+	// header followed by the context as associated data.
+	key := make([]byte, 64)
+	want, _ := hex.DecodeString(
+		"0105b0bdb1a6c21f874c6c702fa5d32ce0858bbed9ca17e235f94b4aa555d1d15f6a",
+	)
+
+	got, err := util.EncryptDeterministic(key, []byte("user@example.com"), []byte("users.email"))
+	if err != nil {
+		t.Fatalf("EncryptDeterministic() failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("EncryptDeterministic() = %x, want %x", got, want)
+	}
+
+	plaintext, err := util.DecryptDeterministic(key, want, []byte("users.email"))
+	if err != nil {
+		t.Fatalf("DecryptDeterministic() failed: %v", err)
+	}
+	if string(plaintext) != "user@example.com" {
+		t.Errorf("DecryptDeterministic() = %q, want %q", plaintext, "user@example.com")
+	}
+}
+
+func TestEncryptDeterministicProperties(t *testing.T) {
+	skipUnlessFIPSApproved(t, util.AlgorithmAESSIV)
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	context := []byte("tenant-a/users.email")
+
+	first, err := util.EncryptDeterministic(key, []byte("alice@example.com"), context)
+	if err != nil {
+		t.Fatalf("EncryptDeterministic() failed: %v", err)
+	}
+	second, err := util.EncryptDeterministic(key, []byte("alice@example.com"), context)
+	if err != nil {
+		t.Fatalf("EncryptDeterministic() failed: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("EncryptDeterministic() should be stable for identical inputs")
+	}
+
+	other, err := util.EncryptDeterministic(key, []byte("alice@example.com"), []byte("tenant-b/users.email"))
+	if err != nil {
+		t.Fatalf("EncryptDeterministic() failed: %v", err)
+	}
+	if bytes.Equal(first, other) {
+		t.Error("EncryptDeterministic() should differ across contexts")
+	}
+
+	if _, err = util.DecryptDeterministic(key, first, []byte("tenant-b/users.email")); err == nil {
+		t.Error("DecryptDeterministic() should fail with the wrong context")
+	}
+
+	first[len(first)-1] ^= 0x01
+	if _, err = util.DecryptDeterministic(key, first, context); err == nil {
+		t.Error("DecryptDeterministic() should fail on tampered ciphertext")
+	}
+}
+
+func TestEncryptDeterministicErrors(t *testing.T) {
+	if _, err := util.EncryptDeterministic(make([]byte, 16), []byte("x"), nil); err == nil {
+		t.Error("EncryptDeterministic() should reject a 16 byte key")
+	}
+	if _, err := util.EncryptDeterministic(make([]byte, 32), nil, nil); err == nil {
+		t.Error("EncryptDeterministic() should reject empty plaintext")
+	}
+
+	randomized, err := util.EncryptValue(make([]byte, 32), []byte("x"))
+	if err != nil {
+		t.Fatalf("EncryptValue() failed: %v", err)
+	}
+	if _, err = util.DecryptDeterministic(make([]byte, 32), randomized, nil); err == nil {
+		t.Error("DecryptDeterministic() should reject payloads from EncryptValue")
+	}
+}
@@ -0,0 +1,196 @@
+// Package agex encrypts files and streams in the age format
+// (https://age-encryption.org/v1), so backups written by services can be
+// decrypted by operators with the standard age CLI and vice versa.
+package agex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// options contains configuration for encryption.
+type options struct {
+	// armor wraps the output in the ASCII armor produced by "age --armor"
+	armor bool
+
+	// scryptWorkFactor overrides the scrypt log2(N) for passphrase encryption; 0 keeps age's default
+	scryptWorkFactor int
+}
+
+// Option is a function that configures encryption.
+type Option func(*options)
+
+// WithArmor produces PEM-style ASCII armored output, as "age --armor" does,
+// for channels that only carry text. Decryption detects armor automatically.
+func WithArmor() Option {
+	return func(o *options) {
+		o.armor = true
+	}
+}
+
+// WithScryptWorkFactor sets the scrypt work factor log2(N) for passphrase
+// encryption. The age default of 18 takes about a second; lower it only in tests.
+func WithScryptWorkFactor(logN int) Option {
+	return func(o *options) {
+		o.scryptWorkFactor = logN
+	}
+}
+
+// GenerateIdentity creates a new X25519 key pair and returns the secret
+// identity ("AGE-SECRET-KEY-1...") and the public recipient ("age1...").
+// The identity string is a valid age identity file on its own.
+func GenerateIdentity() (string, string, error) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate age identity: %w", err)
+	}
+	return identity.String(), identity.Recipient().String(), nil
+}
+
+// Encrypt returns a writer that encrypts everything written to it to the
+// given recipients and writes the age file to dst. The caller must Close the
+// writer to flush the final chunk; dst itself is not closed.
+//
+// Recipients are "age1..." public keys or the contents of a recipients file
+// (one per line, "#" comments allowed), as accepted by "age -R".
+//
+// Example:
+//
+//	w, err := agex.Encrypt(file, []string{operatorRecipient})
+//	if err != nil {
+//		return err
+//	}
+//	if _, err = io.Copy(w, backup); err != nil {
+//		return err
+//	}
+//	return w.Close()
+func Encrypt(dst io.Writer, recipients []string, opts ...Option) (io.WriteCloser, error) {
+	parsed, err := age.ParseRecipients(strings.NewReader(strings.Join(recipients, "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age recipients: %w", err)
+	}
+	return encrypt(dst, parsed, newOptions(opts))
+}
+
+// EncryptWithPassphrase returns a writer that encrypts to a passphrase with
+// scrypt, compatible with "age --passphrase". The caller must Close the writer.
+func EncryptWithPassphrase(dst io.Writer, passphrase string, opts ...Option) (io.WriteCloser, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	options := newOptions(opts)
+	if options.scryptWorkFactor > 0 {
+		recipient.SetWorkFactor(options.scryptWorkFactor)
+	}
+	return encrypt(dst, []age.Recipient{recipient}, options)
+}
+
+// Decrypt returns a reader yielding the plaintext of the age file read from
+// src. Identities are "AGE-SECRET-KEY-1..." strings or identity file contents.
+// Armored input is detected automatically.
+func Decrypt(src io.Reader, identities ...string) (io.Reader, error) {
+	parsed, err := age.ParseIdentities(strings.NewReader(strings.Join(identities, "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid age identities: %w", err)
+	}
+	return decrypt(src, parsed)
+}
+
+// DecryptWithPassphrase returns a reader yielding the plaintext of a
+// passphrase-encrypted age file. Armored input is detected automatically.
+func DecryptWithPassphrase(src io.Reader, passphrase string) (io.Reader, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase: %w", err)
+	}
+	return decrypt(src, []age.Identity{identity})
+}
+
+// EncryptBytes encrypts plaintext to recipients in one call. See Encrypt.
+func EncryptBytes(plaintext []byte, recipients []string, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := Encrypt(&buf, recipients, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DecryptBytes decrypts an age file held in memory. See Decrypt.
+func DecryptBytes(ciphertext []byte, identities ...string) ([]byte, error) {
+	r, err := Decrypt(bytes.NewReader(ciphertext), identities...)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func encrypt(dst io.Writer, recipients []age.Recipient, o *options) (io.WriteCloser, error) {
+	if !o.armor {
+		w, err := age.Encrypt(dst, recipients...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start age encryption: %w", err)
+		}
+		return w, nil
+	}
+
+	armored := armor.NewWriter(dst)
+	w, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start age encryption: %w", err)
+	}
+	return &armoredWriteCloser{WriteCloser: w, armor: armored}, nil
+}
+
+func decrypt(src io.Reader, identities []age.Identity) (io.Reader, error) {
+	buffered := bufio.NewReader(src)
+	if head, _ := buffered.Peek(len(armor.Header)); string(head) == armor.Header {
+		src = armor.NewReader(buffered)
+	} else {
+		src = buffered
+	}
+
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt age file: %w", err)
+	}
+	return r, nil
+}
+
+// armoredWriteCloser closes the age writer and then the armor writer so both
+// the final chunk and the armor footer are written.
+type armoredWriteCloser struct {
+	io.WriteCloser
+
+	armor io.WriteCloser
+}
+
+func (w *armoredWriteCloser) Close() error {
+	return errors.Join(w.WriteCloser.Close(), w.armor.Close())
+}
@@ -0,0 +1,114 @@
+package agex_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util/agex"
+)
+
+// Produced by the age v1.3.2 CLI: printf 'backup contents\n' | age -a -r <recipient>.
+const (
+	cliIdentity = `# created: 2026-10-16T23:44:28Z
+# public key: age1ydt4n320gldvpkh8zx56zq6kdag2mwlelspx0ffzjpcp6n02hsnqtaa8p8
+AGE-SECRET-KEY-12DJRVN4LP90NMTY5W0SLKX297Y2RT7VAQ0ZW6Y5YGGLEYX4NUXXSUYN473`
+	cliArmoredFile = `-----BEGIN AGE ENCRYPTED FILE-----
+YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSBmaWZtSml0b1Ywa3pWTjJD
+SWxUZm1hc2dVNWFTdUFNSUhsK0lLYm5IZHhFCjY3RVpNWDJvb3M0UDVTbWE5T1gy
+UXhQREQ5di9oVWx5U21KWk1Sd3RKQjgKLS0tIFlCSjhDUDhpaGs3elVGZkV6aDZN
+eDBGd3pjVGNhdFRwVEJYTXQ3UVBJcUkKt2WHiuD4b2nNTFrpIKvthw51M9IpRnRA
+j25lxgBUVIaTWh74TtREqRngxjJjr8i7
+-----END AGE ENCRYPTED FILE-----
+`
+)
+
+func TestDecryptCLIFile(t *testing.T) {
+	got, err := agex.DecryptBytes([]byte(cliArmoredFile), cliIdentity)
+	if err != nil {
+		t.Fatalf("DecryptBytes() failed: %v", err)
+	}
+	if string(got) != "backup contents\n" {
+		t.Errorf("DecryptBytes() = %q, want %q", got, "backup contents\n")
+	}
+}
+
+func TestEncryptDecryptRecipients(t *testing.T) {
+	identity, recipient, err := agex.GenerateIdentity()
+	if err != nil {
+		t.Fatalf("GenerateIdentity() failed: %v", err)
+	}
+	if !strings.HasPrefix(identity, "AGE-SECRET-KEY-1") || !strings.HasPrefix(recipient, "age1") {
+		t.Fatalf("GenerateIdentity() = %q, %q", identity, recipient)
+	}
+	other, _, _ := agex.GenerateIdentity()
+	plaintext := bytes.Repeat([]byte("0123456789"), 10_000)
+
+	tests := []struct {
+		name       string
+		opts       []agex.Option
+		wantPrefix string
+	}{
+		{"binary", nil, "age-encryption.org/v1\n"},
+		{"armored", []agex.Option{agex.WithArmor()}, "-----BEGIN AGE ENCRYPTED FILE-----\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, encryptErr := agex.EncryptBytes(plaintext, []string{recipient}, tt.opts...)
+			if encryptErr != nil {
+				t.Fatalf("EncryptBytes() failed: %v", encryptErr)
+			}
+			if !strings.HasPrefix(string(ciphertext), tt.wantPrefix) {
+				t.Errorf("EncryptBytes() output starts with %q, want %q", ciphertext[:24], tt.wantPrefix)
+			}
+
+			got, decryptErr := agex.DecryptBytes(ciphertext, identity)
+			if decryptErr != nil {
+				t.Fatalf("DecryptBytes() failed: %v", decryptErr)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Error("DecryptBytes() did not round trip")
+			}
+
+			if _, decryptErr = agex.DecryptBytes(ciphertext, other); decryptErr == nil {
+				t.Error("DecryptBytes() should fail with the wrong identity")
+			}
+		})
+	}
+}
+
+func TestEncryptDecryptPassphrase(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := agex.EncryptWithPassphrase(&buf, "correct horse battery staple", agex.WithScryptWorkFactor(10))
+	if err != nil {
+		t.Fatalf("EncryptWithPassphrase() failed: %v", err)
+	}
+	if _, err = io.WriteString(w, "secret backup"); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := buf.Bytes()
+
+	r, err := agex.DecryptWithPassphrase(bytes.NewReader(ciphertext), "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("DecryptWithPassphrase() failed: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "secret backup" {
+		t.Errorf("DecryptWithPassphrase() = %q, %v", got, err)
+	}
+
+	if _, err = agex.DecryptWithPassphrase(bytes.NewReader(ciphertext), "wrong"); err == nil {
+		t.Error("DecryptWithPassphrase() should fail with the wrong passphrase")
+	}
+}
+
+func TestEncryptRejectsInvalidRecipient(t *testing.T) {
+	if _, err := agex.EncryptBytes([]byte("x"), []string{"not-a-recipient"}); err == nil {
+		t.Error("EncryptBytes() should reject invalid recipients")
+	}
+}
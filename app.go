@@ -0,0 +1,224 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// appOptions contains configuration for an App.
+type appOptions struct {
+	// args are the command line arguments, without the program name
+	args []string
+
+	// output receives usage and flag errors
+	output io.Writer
+
+	// envOptions configure how the config is loaded
+	envOptions []EnvLoadOption
+
+	// logOptions apply on top of LogOptionsFromEnv
+	logOptions []Option
+
+	// shutdownOptions configure the ShutdownManager
+	shutdownOptions []ShutdownOption
+}
+
+// AppOption is a function that configures an App.
+type AppOption func(*appOptions)
+
+// WithAppArgs sets the command line arguments parsed for flags, without the
+// program name. The default is os.Args[1:].
+func WithAppArgs(args []string) AppOption {
+	return func(o *appOptions) {
+		o.args = args
+	}
+}
+
+// WithAppOutput sets where usage and flag errors are written. The default
+// is os.Stderr.
+func WithAppOutput(w io.Writer) AppOption {
+	return func(o *appOptions) {
+		o.output = w
+	}
+}
+
+// WithAppEnvOptions configures how the config is loaded, such as with
+// WithEnvPrefix.
+func WithAppEnvOptions(opts ...EnvLoadOption) AppOption {
+	return func(o *appOptions) {
+		o.envOptions = append(o.envOptions, opts...)
+	}
+}
+
+// WithAppLogOptions adds logger options applied on top of those of
+// LogOptionsFromEnv.
+func WithAppLogOptions(opts ...Option) AppOption {
+	return func(o *appOptions) {
+		o.logOptions = append(o.logOptions, opts...)
+	}
+}
+
+// WithAppShutdownOptions configures the ShutdownManager of the App.
+func WithAppShutdownOptions(opts ...ShutdownOption) AppOption {
+	return func(o *appOptions) {
+		o.shutdownOptions = append(o.shutdownOptions, opts...)
+	}
+}
+
+// App is the skeleton of a service's main function: it loads the config
+// struct from environment variables with LoadEnv, letting command line
+// flags override them, builds the logger from LogOptionsFromEnv, runs the
+// service until it returns or a shutdown signal arrives, and then runs the
+// shutdown hooks of a ShutdownManager.
+//
+// Every variable of the config gets a flag named after it in lowercase with
+// dashes, without the prefix, so `env:"DB_HOST"` can be set with -db-host;
+// -help lists them with their descriptions and defaults.
+//
+// Example:
+//
+//	type Config struct {
+//	    Port  int    `env:"PORT,default=8080" desc:"HTTP listen port"`
+//	    DBURL string `env:"DB_URL,required,secret"`
+//	}
+//
+//	func main() {
+//	    var cfg Config
+//	    app := NewApp("orders", &cfg, WithAppEnvOptions(WithEnvPrefix("ORDERS_")))
+//	    err := app.Run(context.Background(), func(ctx context.Context, shutdown *ShutdownManager) error {
+//	        server := &http.Server{Addr: fmt.Sprintf(":%d", cfg.Port), Handler: routes(cfg)}
+//	        shutdown.Register("http server", server.Shutdown)
+//	        if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+//	            return err
+//	        }
+//	        return nil
+//	    })
+//	    if err != nil && !errors.Is(err, flag.ErrHelp) {
+//	        os.Exit(1)
+//	    }
+//	}
+type App struct {
+	name    string
+	cfg     any
+	options appOptions
+}
+
+// NewApp returns an App called name loading its configuration into the
+// struct cfg points to.
+func NewApp(name string, cfg any, opts ...AppOption) *App {
+	options := appOptions{output: os.Stderr}
+	if len(os.Args) > 1 {
+		options.args = os.Args[1:]
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &App{name: name, cfg: cfg, options: options}
+}
+
+// Run loads the configuration, puts the logger on ctx and calls run with a
+// ShutdownManager for registering shutdown hooks. When a shutdown signal
+// arrives or ctx is done, the context passed to run is cancelled first and
+// the hooks run next; otherwise the hooks run once run returns. Run returns
+// the error of run, other than the cancellation, joined with those of the
+// hooks. It returns flag.ErrHelp, after printing the usage, for -help, and
+// the flag or configuration error, logged, without calling run when the
+// command line or environment is invalid.
+func (a *App) Run(ctx context.Context, run func(ctx context.Context, shutdown *ShutdownManager) error) error {
+	log := NewLoggerFromEnv(ctx, a.options.logOptions...).WithField("app", a.name)
+	ctx = ContextWithLogger(ctx, log)
+
+	if err := a.loadConfig(); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			log.WithError(err).Error("invalid configuration")
+		}
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	shutdown := NewShutdownManager(ctx, a.options.shutdownOptions...)
+	// The first phase tells run to stop, while the hooks it registered, such
+	// as closing its server, make it return.
+	shutdown.Register(a.name, func(context.Context) error {
+		cancel()
+		return nil
+	}, WithHookPriority(math.MinInt))
+
+	log.Info("starting")
+	runErr := run(runCtx, shutdown)
+	if runErr != nil && !(errors.Is(runErr, context.Canceled) && runCtx.Err() != nil) {
+		log.WithError(runErr).Error("stopped with an error")
+	} else {
+		runErr = nil
+	}
+
+	// The hooks still need a live context when ctx was cancelled.
+	return errors.Join(runErr, shutdown.Shutdown(context.WithoutCancel(ctx)))
+}
+
+// loadConfig parses the flags and loads the config, flags taking
+// precedence over environment variables.
+func (a *App) loadConfig() error {
+	envOptions := envLoadOptions{lookup: os.LookupEnv}
+	for _, opt := range a.options.envOptions {
+		opt(&envOptions)
+	}
+	specs, err := EnvSpecs(a.cfg, a.options.envOptions...)
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet(a.name, flag.ContinueOnError)
+	flags.SetOutput(a.options.output)
+	flagVars := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		name := appFlagName(spec.Name, envOptions.prefix)
+		flagVars[name] = spec.Name
+		flags.String(name, "", appFlagUsage(spec))
+	}
+	if err = flags.Parse(a.options.args); err != nil {
+		return err
+	}
+
+	set := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		set[flagVars[f.Name]] = f.Value.String()
+	})
+	lookup := func(key string) (string, bool) {
+		if value, ok := set[key]; ok {
+			return value, true
+		}
+		return envOptions.lookup(key)
+	}
+	return LoadEnv(a.cfg, append(slices.Clip(a.options.envOptions), WithEnvLookup(lookup))...)
+}
+
+// appFlagName derives the flag of the variable name: DB_HOST is db-host.
+func appFlagName(name, prefix string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, prefix)), "_", "-")
+}
+
+func appFlagUsage(spec EnvSpec) string {
+	var b strings.Builder
+	b.WriteString(spec.Description)
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(&b, "(env %s", spec.Name)
+	switch {
+	case spec.Required:
+		b.WriteString(", required")
+	case spec.Default != "" && !spec.Secret:
+		fmt.Fprintf(&b, ", default %q", spec.Default)
+	}
+	b.WriteString(")")
+	return b.String()
+}
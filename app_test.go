@@ -0,0 +1,128 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type appConfig struct {
+	Port   int    `env:"PORT,default=8080" desc:"HTTP listen port"`
+	DBHost string `env:"DB_HOST,required"`
+	Token  string `env:"TOKEN,secret,default=s3cret"`
+}
+
+func newTestApp(cfg *appConfig, out *bytes.Buffer, args ...string) *util.App {
+	return util.NewApp("orders", cfg,
+		util.WithAppArgs(args),
+		util.WithAppOutput(out),
+		util.WithAppLogOptions(util.WithLogOutput(out)),
+		util.WithAppEnvOptions(util.WithEnvPrefix("APP_")),
+	)
+}
+
+func TestAppFlagsOverrideEnv(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "env-host")
+	t.Setenv("APP_PORT", "9000")
+
+	var cfg appConfig
+	var out bytes.Buffer
+	err := newTestApp(&cfg, &out, "-db-host", "flag-host").Run(t.Context(),
+		func(context.Context, *util.ShutdownManager) error { return nil })
+	if err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if cfg.DBHost != "flag-host" || cfg.Port != 9000 || cfg.Token != "s3cret" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestAppHelp(t *testing.T) {
+	var cfg appConfig
+	var out bytes.Buffer
+	called := false
+	err := newTestApp(&cfg, &out, "-help").Run(t.Context(),
+		func(context.Context, *util.ShutdownManager) error { called = true; return nil })
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("Run = %v, want flag.ErrHelp", err)
+	}
+	if called {
+		t.Error("run was called")
+	}
+	usage := out.String()
+	for _, want := range []string{"-port", `HTTP listen port (env APP_PORT, default "8080")`, "(env APP_DB_HOST, required)"} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("usage is missing %q:\n%s", want, usage)
+		}
+	}
+	if strings.Contains(usage, "s3cret") {
+		t.Errorf("usage shows the secret default:\n%s", usage)
+	}
+}
+
+func TestAppMissingRequired(t *testing.T) {
+	var cfg appConfig
+	var out bytes.Buffer
+	called := false
+	err := newTestApp(&cfg, &out).Run(t.Context(),
+		func(context.Context, *util.ShutdownManager) error { called = true; return nil })
+	if !errors.Is(err, util.ErrMissingEnv) {
+		t.Fatalf("Run = %v, want ErrMissingEnv", err)
+	}
+	if called {
+		t.Error("run was called")
+	}
+}
+
+func TestAppRunErrorAndHooks(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "db")
+	errRun := errors.New("listen failed")
+	errHook := errors.New("flush failed")
+
+	var cfg appConfig
+	var out bytes.Buffer
+	hookRan := false
+	err := newTestApp(&cfg, &out).Run(t.Context(), func(ctx context.Context, shutdown *util.ShutdownManager) error {
+		if util.Log(ctx) == nil {
+			t.Error("no logger on the context")
+		}
+		shutdown.Register("flush", func(context.Context) error {
+			hookRan = true
+			return errHook
+		})
+		return errRun
+	})
+	if !errors.Is(err, errRun) || !errors.Is(err, errHook) {
+		t.Errorf("Run = %v, want both the run and hook errors", err)
+	}
+	if !hookRan {
+		t.Error("shutdown hook did not run")
+	}
+}
+
+func TestAppContextCancel(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "db")
+	ctx, cancel := context.WithCancel(t.Context())
+
+	var cfg appConfig
+	var out bytes.Buffer
+	stopped := make(chan struct{})
+	err := newTestApp(&cfg, &out).Run(ctx, func(ctx context.Context, shutdown *util.ShutdownManager) error {
+		shutdown.Register("server", func(context.Context) error {
+			<-stopped
+			return nil
+		})
+		cancel()
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Errorf("Run = %v, want nil after cancellation", err)
+	}
+}
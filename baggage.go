@@ -0,0 +1,171 @@
+package util
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// HeaderBaggage is the W3C Baggage header (https://www.w3.org/TR/baggage/).
+const HeaderBaggage = "Baggage"
+
+const (
+	ctxValueBaggage = contextKeyType("baggage")
+
+	// Propagation limits from the W3C Baggage specification.
+	maxBaggageMembers = 180
+	maxBaggageBytes   = 8192
+)
+
+// ContextWithBaggage returns a copy of ctx whose baggage is the baggage of
+// ctx with members added, replacing members with the same key.
+//
+// Baggage is a set of key/value pairs, such as experiment flags or shard
+// hints, that travels with a request across services alongside the request
+// ID and tenancy. Keys must be HTTP tokens; members with other keys are kept
+// on the context but not propagated. Baggage is neither signed nor private:
+// never put secrets in it or trust it for authorization.
+//
+// Example:
+//
+//	ctx = ContextWithBaggage(ctx, map[string]string{"experiment": "checkout-v2"})
+func ContextWithBaggage(ctx context.Context, members map[string]string) context.Context {
+	baggage := GetBaggage(ctx)
+	if baggage == nil {
+		baggage = make(map[string]string, len(members))
+	}
+	maps.Copy(baggage, members)
+	return context.WithValue(ctx, ctxValueBaggage, baggage)
+}
+
+// GetBaggage returns a copy of the baggage on ctx, or nil when there is none.
+func GetBaggage(ctx context.Context) map[string]string {
+	baggage, ok := ctx.Value(ctxValueBaggage).(map[string]string)
+	if !ok {
+		return nil
+	}
+	return maps.Clone(baggage)
+}
+
+// GetBaggageValue returns the baggage member key on ctx, or "" when it is not set.
+func GetBaggageValue(ctx context.Context, key string) string {
+	baggage, _ := ctx.Value(ctxValueBaggage).(map[string]string)
+	return baggage[key]
+}
+
+// FormatBaggage encodes members as a W3C Baggage header value, with members
+// sorted by key and values percent-encoded as URL path segments.
+//
+// Members whose key is not an HTTP token are skipped, as are members that
+// would take the header past the specification's limits of 180 members and
+// 8192 bytes.
+func FormatBaggage(members map[string]string) string {
+	var b strings.Builder
+	count := 0
+	for _, key := range slices.Sorted(maps.Keys(members)) {
+		if !isBaggageToken(key) || count == maxBaggageMembers {
+			continue
+		}
+		member := key + "=" + url.PathEscape(members[key])
+		if b.Len() > 0 {
+			member = "," + member
+		}
+		if b.Len()+len(member) > maxBaggageBytes {
+			continue
+		}
+		b.WriteString(member)
+		count++
+	}
+	return b.String()
+}
+
+// ParseBaggage decodes a W3C Baggage header value. Member properties are
+// ignored and malformed members are skipped, so one bad member does not
+// discard the rest. At most 180 members and the first 8192 bytes are read.
+func ParseBaggage(header string) map[string]string {
+	if len(header) > maxBaggageBytes {
+		header = header[:maxBaggageBytes]
+		// Drop the member cut in two by the limit.
+		if i := strings.LastIndexByte(header, ','); i >= 0 {
+			header = header[:i]
+		}
+	}
+
+	members := make(map[string]string)
+	for member := range strings.SplitSeq(header, ",") {
+		if len(members) == maxBaggageMembers {
+			break
+		}
+		member, _, _ = strings.Cut(member, ";")
+		key, value, ok := strings.Cut(member, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isBaggageToken(key) {
+			continue
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		members[key] = decoded
+	}
+	return members
+}
+
+// SetBaggageHeader writes the baggage on ctx to the Baggage header of h,
+// replacing any value there. h is left unchanged when ctx carries no baggage.
+//
+// Example:
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	SetBaggageHeader(ctx, req.Header)
+func SetBaggageHeader(ctx context.Context, h http.Header) {
+	baggage, _ := ctx.Value(ctxValueBaggage).(map[string]string)
+	if value := FormatBaggage(baggage); value != "" {
+		h.Set(HeaderBaggage, value)
+	}
+}
+
+// ContextWithBaggageHeader returns a copy of ctx with the members of the
+// Baggage headers in h added to its baggage. ctx is returned unchanged when
+// h carries no baggage.
+func ContextWithBaggageHeader(ctx context.Context, h http.Header) context.Context {
+	values := h.Values(HeaderBaggage)
+	if len(values) == 0 {
+		return ctx
+	}
+	members := ParseBaggage(strings.Join(values, ","))
+	if len(members) == 0 {
+		return ctx
+	}
+	return ContextWithBaggage(ctx, members)
+}
+
+// WithBaggageFromHeaders returns middleware that puts the baggage of each
+// request's Baggage header on the request context, where GetBaggage finds it.
+//
+// Example:
+//
+//	handler := WithBaggageFromHeaders(mux)
+func WithBaggageFromHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ContextWithBaggageHeader(r.Context(), r.Header)))
+	})
+}
+
+// isBaggageToken reports whether s is a non-empty RFC 7230 token.
+func isBaggageToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := range len(s) {
+		c := s[i]
+		isAlnum := c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !isAlnum && !strings.ContainsRune("!#$%&'*+-.^_`|~", rune(c)) {
+			return false
+		}
+	}
+	return true
+}
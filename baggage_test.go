@@ -0,0 +1,97 @@
+package util_test
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestContextWithBaggage(t *testing.T) {
+	ctx := util.ContextWithBaggage(context.Background(), map[string]string{"experiment": "a", "shard": "7"})
+	child := util.ContextWithBaggage(ctx, map[string]string{"experiment": "b"})
+
+	if got := util.GetBaggageValue(ctx, "experiment"); got != "a" {
+		t.Errorf("parent baggage changed to %q", got)
+	}
+	want := map[string]string{"experiment": "b", "shard": "7"}
+	if got := util.GetBaggage(child); !maps.Equal(got, want) {
+		t.Errorf("GetBaggage() = %v, want %v", got, want)
+	}
+
+	util.GetBaggage(child)["shard"] = "mutated"
+	if got := util.GetBaggageValue(child, "shard"); got != "7" {
+		t.Errorf("mutating the GetBaggage copy changed the context to %q", got)
+	}
+	if util.GetBaggage(context.Background()) != nil {
+		t.Error("GetBaggage() without baggage should be nil")
+	}
+}
+
+func TestFormatParseBaggage(t *testing.T) {
+	members := map[string]string{"user": "Ada Lovelace", "list": "a,b;c", "pct": "100%", "bad key": "x"}
+	header := util.FormatBaggage(members)
+	if want := "list=a%2Cb%3Bc,pct=100%25,user=Ada%20Lovelace"; header != want {
+		t.Errorf("FormatBaggage() = %q, want %q", header, want)
+	}
+
+	delete(members, "bad key")
+	if got := util.ParseBaggage(header); !maps.Equal(got, members) {
+		t.Errorf("ParseBaggage() = %v, want %v", got, members)
+	}
+
+	got := util.ParseBaggage(" a = 1 ;prop=x, =2,b,c=%zz, d=4")
+	if want := map[string]string{"a": "1", "d": "4"}; !maps.Equal(got, want) {
+		t.Errorf("ParseBaggage() of malformed members = %v, want %v", got, want)
+	}
+}
+
+func TestBaggageLimits(t *testing.T) {
+	members := make(map[string]string, 200)
+	for i := range 200 {
+		members["k"+strconv.Itoa(i)] = "v"
+	}
+	if got := strings.Count(util.FormatBaggage(members), ",") + 1; got != 180 {
+		t.Errorf("FormatBaggage() wrote %d members, want 180", got)
+	}
+
+	long := util.FormatBaggage(map[string]string{"big": strings.Repeat("x", 9000), "small": "1"})
+	if long != "small=1" {
+		t.Errorf("FormatBaggage() = %.20q, want only the small member", long)
+	}
+
+	header := "a=1," + strings.Repeat("b", 9000) + "=2"
+	if got := util.ParseBaggage(header); !maps.Equal(got, map[string]string{"a": "1"}) {
+		t.Errorf("ParseBaggage() of an oversized header = %v", got)
+	}
+}
+
+func TestBaggageHeaders(t *testing.T) {
+	ctx := util.ContextWithBaggage(context.Background(), map[string]string{"experiment": "checkout-v2"})
+	outgoing := http.Header{}
+	util.SetBaggageHeader(ctx, outgoing)
+
+	var got map[string]string
+	handler := util.WithBaggageFromHeaders(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = util.GetBaggage(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header = outgoing
+	req.Header.Add(util.HeaderBaggage, "shard=3")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if want := map[string]string{"experiment": "checkout-v2", "shard": "3"}; !maps.Equal(got, want) {
+		t.Errorf("handler baggage = %v, want %v", got, want)
+	}
+
+	empty := http.Header{}
+	util.SetBaggageHeader(context.Background(), empty)
+	if len(empty) != 0 {
+		t.Errorf("SetBaggageHeader() without baggage wrote %v", empty)
+	}
+}
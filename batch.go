@@ -0,0 +1,165 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultBatchInterval = time.Second
+)
+
+// ErrBatchClosed is returned when adding to a BatchFunc after Close.
+var ErrBatchClosed = errors.New("batch is closed")
+
+// batchOptions contains configuration for BatchFunc.
+type batchOptions struct {
+	// size flushes a batch once it holds this many items
+	size int
+
+	// interval flushes a batch this long after its first item arrived
+	interval time.Duration
+
+	// onError receives the errors of flushes triggered by the interval
+	onError func(error)
+}
+
+// BatchOption is a function that configures a BatchFunc.
+type BatchOption func(*batchOptions)
+
+// WithBatchSize flushes a batch once it holds size items. The default is 100.
+func WithBatchSize(size int) BatchOption {
+	return func(o *batchOptions) {
+		o.size = size
+	}
+}
+
+// WithBatchInterval flushes a batch at most interval after its first item
+// arrived, however few items it holds. The default is one second.
+func WithBatchInterval(interval time.Duration) BatchOption {
+	return func(o *batchOptions) {
+		o.interval = interval
+	}
+}
+
+// WithBatchErrorHandler receives the errors of flushes triggered by the
+// interval, which have no caller to return them to. By default they are
+// logged to the logger in the BatchFunc's context.
+func WithBatchErrorHandler(onError func(error)) BatchOption {
+	return func(o *batchOptions) {
+		o.onError = onError
+	}
+}
+
+// BatchFunc accumulates items and passes them to a flush function in
+// batches, when a batch is full or when the interval since its first item
+// elapses, for bulk database writes and API calls with page-size limits.
+//
+// Flushes run one at a time and in order; Add blocks while a flush is in
+// progress, which applies backpressure when the flush function falls behind.
+// A BatchFunc is safe for concurrent use.
+//
+// Example:
+//
+//	batch := NewBatchFunc(ctx, func(ctx context.Context, events []Event) error {
+//	    return store.InsertEvents(ctx, events)
+//	}, WithBatchSize(500), WithBatchInterval(2*time.Second))
+//	defer batch.Close(ctx)
+//
+//	for event := range events {
+//	    if err := batch.Add(ctx, event); err != nil {
+//	        return err
+//	    }
+//	}
+type BatchFunc[T any] struct {
+	ctx     context.Context //nolint:containedctx // used by flushes the timer triggers
+	flush   func(context.Context, []T) error
+	options batchOptions
+
+	mu    sync.Mutex
+	items []T
+	timer *time.Timer
+	// batch counts flushed batches, so a timer that fired while its batch
+	// was being flushed does not flush the next one early
+	batch  uint64
+	closed bool
+}
+
+// NewBatchFunc returns a BatchFunc passing batches to flush. ctx is used for
+// the flushes triggered by the interval.
+func NewBatchFunc[T any](ctx context.Context, flush func(context.Context, []T) error, opts ...BatchOption) *BatchFunc[T] {
+	options := batchOptions{size: defaultBatchSize, interval: defaultBatchInterval}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.size = max(options.size, 1)
+	if options.onError == nil {
+		options.onError = func(err error) {
+			Log(ctx).WithError(err).Error("failed to flush batch")
+		}
+	}
+	return &BatchFunc[T]{ctx: ctx, flush: flush, options: options}
+}
+
+// Add appends item to the current batch. When that fills the batch, Add
+// flushes it and returns the flush's error.
+func (b *BatchFunc[T]) Add(ctx context.Context, item T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBatchClosed
+	}
+	b.items = append(b.items, item)
+	if len(b.items) >= b.options.size {
+		return b.flushLocked(ctx)
+	}
+	if len(b.items) == 1 && b.options.interval > 0 {
+		batch := b.batch
+		b.timer = time.AfterFunc(b.options.interval, func() { b.flushOnTimer(batch) })
+	}
+	return nil
+}
+
+// Flush passes the current batch to the flush function, if it holds any items.
+func (b *BatchFunc[T]) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked(ctx)
+}
+
+// Close flushes the current batch and rejects further items.
+func (b *BatchFunc[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return b.flushLocked(ctx)
+}
+
+func (b *BatchFunc[T]) flushOnTimer(batch uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if batch != b.batch {
+		return
+	}
+	if err := b.flushLocked(b.ctx); err != nil {
+		b.options.onError(err)
+	}
+}
+
+func (b *BatchFunc[T]) flushLocked(ctx context.Context) error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.items) == 0 {
+		return nil
+	}
+	items := b.items
+	b.items = make([]T, 0, b.options.size)
+	b.batch++
+	return b.flush(ctx, items)
+}
@@ -0,0 +1,98 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+// recorder collects the batches a BatchFunc flushes.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	flushed chan struct{}
+	err     error
+}
+
+func (r *recorder) flush(_ context.Context, items []int) error {
+	r.mu.Lock()
+	r.batches = append(r.batches, items)
+	r.mu.Unlock()
+	if r.flushed != nil {
+		r.flushed <- struct{}{}
+	}
+	return r.err
+}
+
+func (r *recorder) snapshot() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+func TestBatchFuncBySize(t *testing.T) {
+	ctx := t.Context()
+	rec := &recorder{}
+	batch := util.NewBatchFunc(ctx, rec.flush, util.WithBatchSize(2), util.WithBatchInterval(time.Hour))
+
+	for i := 1; i <= 5; i++ {
+		if err := batch.Add(ctx, i); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if got := rec.snapshot(); !slices.EqualFunc(got, [][]int{{1, 2}, {3, 4}}, slices.Equal) {
+		t.Errorf("batches before Close = %v", got)
+	}
+
+	if err := batch.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := rec.snapshot(); len(got) != 3 || !slices.Equal(got[2], []int{5}) {
+		t.Errorf("batches after Close = %v", got)
+	}
+	if err := batch.Add(ctx, 6); !errors.Is(err, util.ErrBatchClosed) {
+		t.Errorf("Add after Close = %v, want ErrBatchClosed", err)
+	}
+}
+
+func TestBatchFuncByInterval(t *testing.T) {
+	ctx := t.Context()
+	rec := &recorder{flushed: make(chan struct{}, 1), err: errors.New("store down")}
+	errs := make(chan error, 1)
+	batch := util.NewBatchFunc(ctx, rec.flush,
+		util.WithBatchSize(100),
+		util.WithBatchInterval(10*time.Millisecond),
+		util.WithBatchErrorHandler(func(err error) { errs <- err }),
+	)
+
+	_ = batch.Add(ctx, 1)
+	_ = batch.Add(ctx, 2)
+	select {
+	case <-rec.flushed:
+	case <-time.After(time.Second):
+		t.Fatal("the interval did not flush the batch")
+	}
+	if got := rec.snapshot(); !slices.EqualFunc(got, [][]int{{1, 2}}, slices.Equal) {
+		t.Errorf("batches = %v", got)
+	}
+	if err := <-errs; err == nil || err.Error() != "store down" {
+		t.Errorf("error handler received %v", err)
+	}
+}
+
+func TestBatchFuncFlushError(t *testing.T) {
+	ctx := t.Context()
+	rec := &recorder{err: errors.New("rejected")}
+	batch := util.NewBatchFunc(ctx, rec.flush, util.WithBatchSize(1))
+	if err := batch.Add(ctx, 1); err == nil {
+		t.Error("Add did not return the flush error")
+	}
+	if err := batch.Flush(ctx); err != nil {
+		t.Errorf("Flush of an empty batch = %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package util
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+
+// cacheOptions contains configuration for a Cache.
+type cacheOptions struct {
+	// ttl is how long entries stay fresh unless set with their own TTL
+	ttl time.Duration
+
+	// maxEntries bounds the number of entries, evicting the least recently
+	// used; zero means unbounded
+	maxEntries int
+
+	// stale is how long after expiring an entry is still served by
+	// GetOrLoad while it is reloaded in the background
+	stale time.Duration
+
+	// now is the time source
+	now func() time.Time
+}
+
+// CacheOption is a function that configures a Cache.
+type CacheOption func(*cacheOptions)
+
+// WithCacheTTL sets how long entries stay fresh. The default is five
+// minutes.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithCacheMaxEntries bounds the number of entries, evicting the least
+// recently used when a new one is added. By default the cache is unbounded.
+func WithCacheMaxEntries(maxEntries int) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithCacheStaleWhileRevalidate lets GetOrLoad return an entry for up to
+// stale after it expired, reloading it in the background, so callers do not
+// wait on the loader for hot keys.
+func WithCacheStaleWhileRevalidate(stale time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.stale = stale
+	}
+}
+
+// WithCacheClock overrides the time source used for expiry.
+func WithCacheClock(now func() time.Time) CacheOption {
+	return func(o *cacheOptions) {
+		o.now = now
+	}
+}
+
+// CacheStats are the counters of a Cache since it was created.
+type CacheStats struct {
+	// Hits counts lookups answered with a fresh entry.
+	Hits uint64 `json:"hits"`
+
+	// StaleHits counts lookups answered with an expired entry while it was
+	// reloaded.
+	StaleHits uint64 `json:"stale_hits"`
+
+	// Misses counts lookups that found no usable entry.
+	Misses uint64 `json:"misses"`
+
+	// Loads counts loader calls, LoadErrors those that failed.
+	Loads      uint64 `json:"loads"`
+	LoadErrors uint64 `json:"load_errors"`
+
+	// Evictions counts entries dropped to respect the size bound.
+	Evictions uint64 `json:"evictions"`
+}
+
+// Cache is an in-memory cache with per-entry expiry and an optional size
+// bound enforced by evicting the least recently used entry. GetOrLoad shares
+// one load among concurrent callers of the same key. A Cache is safe for
+// concurrent use.
+//
+// Example:
+//
+//	users := NewCache[string, *User](WithCacheTTL(time.Minute), WithCacheMaxEntries(10000))
+//	user, err := users.GetOrLoad(ctx, id, func(ctx context.Context) (*User, error) {
+//	    return store.GetUser(ctx, id)
+//	})
+type Cache[K comparable, V any] struct {
+	options cacheOptions
+
+	mu       sync.Mutex
+	entries  map[K]*list.Element
+	lru      *list.List // of *cacheEntry, most recently used first
+	inflight map[K]*cacheCall[V]
+
+	hits, staleHits, misses, loads, loadErrors, evictions atomic.Uint64
+}
+
+type cacheEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// cacheCall is a load in progress that concurrent callers wait on.
+type cacheCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+
+	// superseded is set under the cache lock when the key is written or
+	// deleted during the load, whose older result must then not be cached
+	superseded bool
+}
+
+// NewCache returns an empty Cache.
+func NewCache[K comparable, V any](opts ...CacheOption) *Cache[K, V] {
+	options := cacheOptions{ttl: defaultCacheTTL, now: time.Now}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Cache[K, V]{
+		options:  options,
+		entries:  make(map[K]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[K]*cacheCall[V]),
+	}
+}
+
+// Get returns the value of key if it is cached and fresh.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.lookupLocked(key); e != nil && c.options.now().Before(e.expires) {
+		c.hits.Add(1)
+		return e.value, true
+	}
+	c.misses.Add(1)
+	var zero V
+	return zero, false
+}
+
+// Set caches value for key with the cache's TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.options.ttl)
+}
+
+// SetWithTTL caches value for key, fresh for ttl.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.supersedeLoadLocked(key)
+	c.setLocked(key, value, ttl)
+}
+
+// Delete removes key from the cache.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.supersedeLoadLocked(key)
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet
+// dropped.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Stats returns the cache's counters.
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:       c.hits.Load(),
+		StaleHits:  c.staleHits.Load(),
+		Misses:     c.misses.Load(),
+		Loads:      c.loads.Load(),
+		LoadErrors: c.loadErrors.Load(),
+		Evictions:  c.evictions.Load(),
+	}
+}
+
+// RegisterMetrics exposes the cache's counters and size in reg, as metrics
+// named prefix followed by "_hits_total", "_stale_hits_total",
+// "_misses_total", "_loads_total", "_load_errors_total",
+// "_evictions_total" and "_entries".
+//
+// Example:
+//
+//	users.RegisterMetrics(metrics, "user_cache")
+func (c *Cache[K, V]) RegisterMetrics(reg *MetricsRegistry, prefix string) {
+	for _, counter := range []struct {
+		suffix, help string
+		value        *atomic.Uint64
+	}{
+		{"_hits_total", "Lookups answered with a fresh entry.", &c.hits},
+		{"_stale_hits_total", "Lookups answered with an expired entry while it was reloaded.", &c.staleHits},
+		{"_misses_total", "Lookups that found no usable entry.", &c.misses},
+		{"_loads_total", "Loader calls.", &c.loads},
+		{"_load_errors_total", "Loader calls that failed.", &c.loadErrors},
+		{"_evictions_total", "Entries evicted to respect the maximum size.", &c.evictions},
+	} {
+		reg.CounterFunc(prefix+counter.suffix, counter.help, func() float64 { return float64(counter.value.Load()) })
+	}
+	reg.GaugeFunc(prefix+"_entries", "Entries in the cache.", func() float64 { return float64(c.Len()) })
+}
+
+// GetOrLoad returns the value of key, calling load to fill the cache when
+// it holds no fresh entry. Concurrent callers for the same key share one
+// call of load, which outlives a caller that gives up when its ctx is done,
+// so the value is still cached for the others. Errors from load are
+// returned but not cached.
+//
+// With WithCacheStaleWhileRevalidate, an entry that expired recently is
+// returned at once while load refreshes it in the background.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	if e := c.lookupLocked(key); e != nil {
+		now, value := c.options.now(), e.value
+		if now.Before(e.expires) {
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return value, nil
+		}
+		if now.Before(e.expires.Add(c.options.stale)) {
+			c.startLoadLocked(ctx, key, load)
+			c.mu.Unlock()
+			c.staleHits.Add(1)
+			return value, nil
+		}
+	}
+	call := c.startLoadLocked(ctx, key, load)
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// startLoadLocked returns the load in progress for key, starting one if
+// there is none.
+func (c *Cache[K, V]) startLoadLocked(ctx context.Context, key K, load func(ctx context.Context) (V, error)) *cacheCall[V] {
+	if call, ok := c.inflight[key]; ok {
+		return call
+	}
+	call := &cacheCall[V]{done: make(chan struct{})}
+	c.inflight[key] = call
+	go c.load(context.WithoutCancel(ctx), key, call, load)
+	return call
+}
+
+// load runs the loader for call, turning a panic into a *PanicError so
+// that it fails the callers rather than the process.
+func (c *Cache[K, V]) load(ctx context.Context, key K, call *cacheCall[V], load func(ctx context.Context) (V, error)) {
+	c.loads.Add(1)
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = panicError(r)
+		}
+
+		c.mu.Lock()
+		delete(c.inflight, key)
+		switch {
+		case call.err != nil:
+			c.loadErrors.Add(1)
+		case !call.superseded:
+			c.setLocked(key, call.value, c.options.ttl)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = load(ctx)
+}
+
+// supersedeLoadLocked keeps a load of key in progress from overwriting a
+// newer Set or Delete when it finishes.
+func (c *Cache[K, V]) supersedeLoadLocked(key K) {
+	if call, ok := c.inflight[key]; ok {
+		call.superseded = true
+	}
+}
+
+// lookupLocked returns the entry of key, marking it recently used, and drops
+// it instead when it is past serving even as stale.
+func (c *Cache[K, V]) lookupLocked(key K) *cacheEntry[K, V] {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	e, _ := el.Value.(*cacheEntry[K, V])
+	if !c.options.now().Before(e.expires.Add(c.options.stale)) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return e
+}
+
+func (c *Cache[K, V]) setLocked(key K, value V, ttl time.Duration) {
+	expires := c.options.now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		e, _ := el.Value.(*cacheEntry[K, V])
+		e.value, e.expires = value, expires
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry[K, V]{key: key, value: value, expires: expires})
+	for c.options.maxEntries > 0 && len(c.entries) > c.options.maxEntries {
+		oldest := c.lru.Back()
+		e, _ := oldest.Value.(*cacheEntry[K, V])
+		c.lru.Remove(oldest)
+		delete(c.entries, e.key)
+		c.evictions.Add(1)
+	}
+}
@@ -0,0 +1,194 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestCacheExpiryAndLRU(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	cache := util.NewCache[string, int](
+		util.WithCacheTTL(time.Minute),
+		util.WithCacheMaxEntries(2),
+		util.WithCacheClock(clock.Now),
+	)
+
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, time.Hour)
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	cache.Set("c", 3) // evicts b, the least recently used
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b was not evicted")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("a is served after its TTL")
+	}
+	cache.Delete("c")
+	if cache.Len() != 0 {
+		t.Errorf("Len = %d, want 0", cache.Len())
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Evictions != 1 {
+		t.Errorf("Stats = %+v", stats)
+	}
+}
+
+func TestCacheGetOrLoadSharesLoads(t *testing.T) {
+	cache := util.NewCache[string, string]()
+	release := make(chan struct{})
+	var calls atomic.Int32
+	load := func(context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if v, err := cache.GetOrLoad(t.Context(), "k", load); err != nil || v != "value" {
+				t.Errorf("GetOrLoad = %q, %v", v, err)
+			}
+		})
+	}
+	for cache.Stats().Misses < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+	if v, err := cache.GetOrLoad(t.Context(), "k", load); err != nil || v != "value" {
+		t.Errorf("cached GetOrLoad = %q, %v", v, err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Loads != 1 {
+		t.Errorf("Stats = %+v", stats)
+	}
+}
+
+func TestCacheGetOrLoadErrors(t *testing.T) {
+	cache := util.NewCache[string, int]()
+	errLoad := errors.New("store down")
+	if _, err := cache.GetOrLoad(t.Context(), "k", func(context.Context) (int, error) { return 0, errLoad }); !errors.Is(err, errLoad) {
+		t.Errorf("GetOrLoad = %v, want the load error", err)
+	}
+	if cache.Len() != 0 || cache.Stats().LoadErrors != 1 {
+		t.Errorf("failed load was cached: Len %d, %+v", cache.Len(), cache.Stats())
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	blocked := make(chan struct{})
+	defer close(blocked)
+	_, err := cache.GetOrLoad(ctx, "slow", func(context.Context) (int, error) {
+		<-blocked
+		return 1, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("GetOrLoad with a cancelled context = %v", err)
+	}
+}
+
+func TestCacheGetOrLoadPanic(t *testing.T) {
+	cache := util.NewCache[string, int]()
+	_, err := cache.GetOrLoad(t.Context(), "k", func(context.Context) (int, error) { panic("loader bug") })
+	var panicErr *util.PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "loader bug" {
+		t.Fatalf("GetOrLoad = %v, want a *PanicError", err)
+	}
+
+	v, err := cache.GetOrLoad(t.Context(), "k", func(context.Context) (int, error) { return 7, nil })
+	if err != nil || v != 7 {
+		t.Errorf("GetOrLoad after a panicking load = %d, %v; want a fresh load", v, err)
+	}
+}
+
+func TestCacheWriteDuringLoad(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		write  func(cache *util.Cache[string, int])
+		want   int
+		wantOK bool
+	}{
+		{"set", func(cache *util.Cache[string, int]) { cache.Set("k", 2) }, 2, true},
+		{"delete", func(cache *util.Cache[string, int]) { cache.Delete("k") }, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := util.NewCache[string, int]()
+			started, release := make(chan struct{}), make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = cache.GetOrLoad(t.Context(), "k", func(context.Context) (int, error) {
+					close(started)
+					<-release
+					return 1, nil
+				})
+			}()
+
+			<-started
+			tt.write(cache)
+			close(release)
+			<-done
+
+			if v, ok := cache.Get("k"); v != tt.want || ok != tt.wantOK {
+				t.Errorf("Get after the load = %d, %v; want %d, %v", v, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Unix(1000, 0)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	cache := util.NewCache[string, int](
+		util.WithCacheTTL(time.Minute),
+		util.WithCacheStaleWhileRevalidate(time.Minute),
+		util.WithCacheClock(clock),
+	)
+	cache.Set("k", 1)
+
+	mu.Lock()
+	now = now.Add(90 * time.Second)
+	mu.Unlock()
+
+	reloaded := make(chan struct{})
+	v, err := cache.GetOrLoad(t.Context(), "k", func(context.Context) (int, error) {
+		defer close(reloaded)
+		return 2, nil
+	})
+	if err != nil || v != 1 {
+		t.Errorf("stale GetOrLoad = %d, %v, want the stale 1", v, err)
+	}
+	<-reloaded
+	for {
+		if v, ok := cache.Get("k"); ok {
+			if v != 2 {
+				t.Errorf("value after revalidation = %d, want 2", v)
+			}
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if cache.Stats().StaleHits != 1 {
+		t.Errorf("Stats = %+v", cache.Stats())
+	}
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// OrDone returns a channel receiving the values of in until in is closed or
+// ctx is done, so a range loop over a channel also stops on cancellation.
+// The returned channel is closed then.
+//
+// Example:
+//
+//	for event := range OrDone(ctx, events) {
+//	    handle(ctx, event)
+//	}
+func OrDone[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok || !pipelineSend(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// FanIn returns a channel receiving the values of all ins, in the order they
+// arrive, closed once every one of ins is closed or ctx is done. A slow
+// reader slows all senders rather than buffering without bound.
+//
+// Example:
+//
+//	for result := range FanIn(ctx, fetch(ctx, primary), fetch(ctx, replica)) {
+//	    results = append(results, result)
+//	}
+func FanIn[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Go(func() {
+			for v := range OrDone(ctx, in) {
+				if !pipelineSend(ctx, out, v) {
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Merge returns a channel receiving the values of ins, each sorted by
+// compare, as one sorted stream, such as time-ordered events from several
+// shards; it is the channel counterpart of MergeSortedFunc. It waits for a
+// value from every open channel before sending the smallest, and closes the
+// returned channel once every one of ins is closed or ctx is done.
+func Merge[T any](ctx context.Context, compare func(a, b T) int, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		h := &channelMergeHeap[T]{compare: compare}
+		receive := func(i int) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case v, ok := <-ins[i]:
+				if ok {
+					heap.Push(h, channelMergeItem[T]{value: v, source: i})
+				}
+				return true
+			}
+		}
+		for i := range ins {
+			if !receive(i) {
+				return
+			}
+		}
+		for h.Len() > 0 {
+			next, _ := heap.Pop(h).(channelMergeItem[T])
+			if !pipelineSend(ctx, out, next.value) || !receive(next.source) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+type channelMergeItem[T any] struct {
+	value  T
+	source int
+}
+
+// channelMergeHeap orders the next values of the merged channels, ties going
+// to the earlier channel.
+type channelMergeHeap[T any] struct {
+	items   []channelMergeItem[T]
+	compare func(a, b T) int
+}
+
+func (h *channelMergeHeap[T]) Len() int { return len(h.items) }
+
+func (h *channelMergeHeap[T]) Less(i, j int) bool {
+	if c := h.compare(h.items[i].value, h.items[j].value); c != 0 {
+		return c < 0
+	}
+	return h.items[i].source < h.items[j].source
+}
+
+func (h *channelMergeHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *channelMergeHeap[T]) Push(x any) {
+	item, _ := x.(channelMergeItem[T])
+	h.items = append(h.items, item)
+}
+
+func (h *channelMergeHeap[T]) Pop() any {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items = h.items[:n-1]
+	return item
+}
+
+// FanOut distributes the values of in over n channels, each value going to
+// whichever reader is ready first, to spread work over n consumers. The
+// channels are closed when in is closed or ctx is done.
+//
+// Example:
+//
+//	for _, jobs := range FanOut(ctx, queue, 4) {
+//	    go worker(ctx, jobs)
+//	}
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	shared := OrDone(ctx, in)
+	outs := make([]<-chan T, max(n, 1))
+	for i := range outs {
+		outs[i] = shared
+	}
+	return outs
+}
+
+// Tee sends every value of in to each of n channels, for example to both
+// persist and index a stream. A value is only taken from in once every
+// channel received the previous one, so the slowest reader sets the pace.
+// The channels are closed when in is closed or ctx is done.
+func Tee[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]chan T, max(n, 1))
+	readOnly := make([]<-chan T, len(outs))
+	for i := range outs {
+		outs[i] = make(chan T)
+		readOnly[i] = outs[i]
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for v := range OrDone(ctx, in) {
+			var wg sync.WaitGroup
+			for _, out := range outs {
+				wg.Go(func() { pipelineSend(ctx, out, v) })
+			}
+			wg.Wait()
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return readOnly
+}
@@ -0,0 +1,106 @@
+package util_test
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func sendAll[T any](values ...T) <-chan T {
+	ch := make(chan T, len(values))
+	for _, v := range values {
+		ch <- v
+	}
+	close(ch)
+	return ch
+}
+
+func drain[T any](ch <-chan T) []T {
+	var out []T
+	for v := range ch {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestOrDone(t *testing.T) {
+	if got := drain(util.OrDone(t.Context(), sendAll(1, 2, 3))); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("OrDone = %v, want [1 2 3]", got)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	never := make(chan int)
+	out := util.OrDone(ctx, never)
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("OrDone sent a value after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OrDone did not close its channel on cancellation")
+	}
+}
+
+func TestFanIn(t *testing.T) {
+	got := drain(util.FanIn(t.Context(), sendAll(1, 2), sendAll(3), sendAll[int]()))
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("FanIn = %v, want [1 2 3]", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	got := drain(util.Merge(t.Context(), cmp.Compare[int], sendAll(1, 4, 7), sendAll(2, 5), sendAll[int](), sendAll(3, 6, 8, 9)))
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Fatalf("Merge = %v, want %v", got, want)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := range 100 {
+			in <- i
+		}
+	}()
+
+	var mu sync.Mutex
+	var got []int
+	var wg sync.WaitGroup
+	for _, out := range util.FanOut(t.Context(), in, 4) {
+		wg.Go(func() {
+			for v := range out {
+				mu.Lock()
+				got = append(got, v)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	slices.Sort(got)
+	if len(got) != 100 || got[0] != 0 || got[99] != 99 {
+		t.Fatalf("FanOut delivered %d values, want each of 100 once", len(got))
+	}
+}
+
+func TestTee(t *testing.T) {
+	outs := util.Tee(t.Context(), sendAll("a", "b", "c"), 2)
+	results := make([][]string, len(outs))
+	var wg sync.WaitGroup
+	for i, out := range outs {
+		wg.Go(func() { results[i] = drain(out) })
+	}
+	wg.Wait()
+	for i, got := range results {
+		if !slices.Equal(got, []string{"a", "b", "c"}) {
+			t.Errorf("Tee output %d = %v, want [a b c]", i, got)
+		}
+	}
+}
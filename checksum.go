@@ -0,0 +1,167 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// ErrChecksumMismatch is returned when content does not match its expected checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// HashAlgorithm selects the hash used by HashReader and HashFile.
+type HashAlgorithm int
+
+const (
+	// HashSHA256 is SHA-256, the default choice for integrity checks.
+	HashSHA256 HashAlgorithm = iota
+	// HashSHA512 is SHA-512.
+	HashSHA512
+	// HashBLAKE2b256 is unkeyed BLAKE2b with a 256-bit digest, faster than SHA-256 without hardware support.
+	HashBLAKE2b256
+	// HashCRC32C is CRC-32 with the Castagnoli polynomial. It detects
+	// accidental corruption only and offers no protection against tampering.
+	HashCRC32C
+)
+
+// String returns the algorithm prefix used in checksum strings.
+func (h HashAlgorithm) String() string {
+	switch h {
+	case HashSHA256:
+		return "sha256"
+	case HashSHA512:
+		return "sha512"
+	case HashBLAKE2b256:
+		return "blake2b-256"
+	case HashCRC32C:
+		return "crc32c"
+	default:
+		return "unknown"
+	}
+}
+
+func (h HashAlgorithm) newHash() (hash.Hash, error) {
+	switch h {
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashBLAKE2b256:
+		if err := checkFIPSApproved("BLAKE2b"); err != nil {
+			return nil, err
+		}
+		// New256 only fails for keys longer than 64 bytes.
+		b, _ := blake2b.New256(nil)
+		return b, nil
+	case HashCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %d", h)
+	}
+}
+
+// Checksum is a digest together with the algorithm that produced it.
+type Checksum struct {
+	Algorithm HashAlgorithm
+	Sum       []byte
+}
+
+// String returns the self-describing form "<algorithm>:<hex digest>",
+// e.g. "sha256:e3b0c442...", as used in OCI image digests.
+func (c Checksum) String() string {
+	return c.Algorithm.String() + ":" + hex.EncodeToString(c.Sum)
+}
+
+// Equal reports whether both checksums use the same algorithm and digest.
+// Digests are compared in constant time.
+func (c Checksum) Equal(other Checksum) bool {
+	return c.Algorithm == other.Algorithm && SecureCompare(c.Sum, other.Sum)
+}
+
+// ParseChecksum parses the "<algorithm>:<hex digest>" form produced by Checksum.String.
+func ParseChecksum(s string) (Checksum, error) {
+	name, digest, ok := strings.Cut(s, ":")
+	if !ok {
+		return Checksum{}, errors.New("checksum must have the form <algorithm>:<hex digest>")
+	}
+
+	for _, algo := range []HashAlgorithm{HashSHA256, HashSHA512, HashBLAKE2b256, HashCRC32C} {
+		if !strings.EqualFold(name, algo.String()) {
+			continue
+		}
+		sum, err := hex.DecodeString(digest)
+		if err != nil {
+			return Checksum{}, fmt.Errorf("invalid checksum digest: %w", err)
+		}
+		h, err := algo.newHash()
+		if err != nil {
+			return Checksum{}, err
+		}
+		if len(sum) != h.Size() {
+			return Checksum{}, fmt.Errorf("%s digest must be %d bytes long", algo, h.Size())
+		}
+		return Checksum{Algorithm: algo, Sum: sum}, nil
+	}
+	return Checksum{}, fmt.Errorf("unsupported checksum algorithm %q", name)
+}
+
+// HashReader streams r through the selected hash and returns its checksum.
+// Memory use is constant regardless of the size of r.
+func HashReader(r io.Reader, algo HashAlgorithm) (Checksum, error) {
+	h, err := algo.newHash()
+	if err != nil {
+		return Checksum{}, err
+	}
+	if _, err = io.Copy(h, r); err != nil {
+		return Checksum{}, fmt.Errorf("failed to hash content: %w", err)
+	}
+	return Checksum{Algorithm: algo, Sum: h.Sum(nil)}, nil
+}
+
+// HashFile returns the checksum of the file at path, streaming its content.
+//
+// Example:
+//
+//	sum, err := HashFile("backup.tar.gz", HashSHA256)
+//	fmt.Println(sum) // sha256:9f86d081...
+func HashFile(path string, algo HashAlgorithm) (Checksum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Checksum{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	return HashReader(f, algo)
+}
+
+// VerifyReader hashes r with the algorithm named in expected (as produced by
+// Checksum.String) and returns ErrChecksumMismatch if the digests differ.
+//
+// Example:
+//
+//	if err := VerifyReader(upload, r.Header.Get("X-Checksum")); err != nil {
+//		return err
+//	}
+func VerifyReader(r io.Reader, expected string) error {
+	want, err := ParseChecksum(expected)
+	if err != nil {
+		return err
+	}
+	got, err := HashReader(r, want.Algorithm)
+	if err != nil {
+		return err
+	}
+	if !got.Equal(want) {
+		return fmt.Errorf("%w: got %s", ErrChecksumMismatch, got)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package util_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(path, []byte("abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		algo util.HashAlgorithm
+		want string
+	}{
+		{util.HashSHA256, "sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{
+			util.HashSHA512,
+			"sha512:ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a" +
+				"2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+		},
+		{util.HashBLAKE2b256, "blake2b-256:bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{util.HashCRC32C, "crc32c:364b3fb7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algo.String(), func(t *testing.T) {
+			if tt.algo == util.HashBLAKE2b256 {
+				skipInFIPSMode(t, "BLAKE2b")
+			}
+			sum, err := util.HashFile(path, tt.algo)
+			if err != nil {
+				t.Fatalf("HashFile() failed: %v", err)
+			}
+			if sum.String() != tt.want {
+				t.Errorf("HashFile() = %s, want %s", sum, tt.want)
+			}
+
+			parsed, err := util.ParseChecksum(tt.want)
+			if err != nil {
+				t.Fatalf("ParseChecksum() failed: %v", err)
+			}
+			if !parsed.Equal(sum) {
+				t.Error("ParseChecksum() should round trip Checksum.String()")
+			}
+		})
+	}
+
+	if _, err := util.HashFile(filepath.Join(t.TempDir(), "missing"), util.HashSHA256); err == nil {
+		t.Error("HashFile() should fail for a missing file")
+	}
+}
+
+func TestVerifyReader(t *testing.T) {
+	const sum = "sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+	if err := util.VerifyReader(strings.NewReader("abc"), sum); err != nil {
+		t.Errorf("VerifyReader() failed: %v", err)
+	}
+	if err := util.VerifyReader(strings.NewReader("abd"), sum); !errors.Is(err, util.ErrChecksumMismatch) {
+		t.Errorf("VerifyReader() error = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestParseChecksumRejects(t *testing.T) {
+	for _, s := range []string{
+		"ba7816bf",
+		"md5:900150983cd24fb0d6963f7d28e17f72",
+		"sha256:zz",
+		"sha256:ba7816bf",
+	} {
+		if _, err := util.ParseChecksum(s); err == nil {
+			t.Errorf("ParseChecksum(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package util_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestIPClassification(t *testing.T) {
+	tests := []struct {
+		addr                       string
+		private, loopback, cloudMD bool
+	}{
+		{"10.1.2.3", true, false, false},
+		{"172.31.255.255", true, false, false},
+		{"172.32.0.1", false, false, false},
+		{"100.64.0.1", true, false, false},
+		{"fd12::1", true, false, false},
+		{"127.0.0.1", false, true, false},
+		{"::1", false, true, false},
+		{"::ffff:127.0.0.1", false, true, false},
+		{"169.254.169.254", false, false, true},
+		{"::ffff:169.254.169.254", false, false, true},
+		{"fd00:ec2::254", true, false, true},
+		{"8.8.8.8", false, false, false},
+	}
+
+	for _, tt := range tests {
+		addr := netip.MustParseAddr(tt.addr)
+		if got := util.IsPrivateIP(addr); got != tt.private {
+			t.Errorf("IsPrivateIP(%s) = %v, want %v", tt.addr, got, tt.private)
+		}
+		if got := util.IsLoopback(addr); got != tt.loopback {
+			t.Errorf("IsLoopback(%s) = %v, want %v", tt.addr, got, tt.loopback)
+		}
+		if got := util.IsCloudMetadataIP(addr); got != tt.cloudMD {
+			t.Errorf("IsCloudMetadataIP(%s) = %v, want %v", tt.addr, got, tt.cloudMD)
+		}
+	}
+}
+
+func TestCIDRSet(t *testing.T) {
+	set, err := util.NewCIDRSet([]string{
+		"10.0.0.0/8", "10.1.0.0/16", "192.0.2.1", "198.51.100.0/31", "2001:db8::/32", "::ffff:203.0.113.0/120",
+	})
+	if err != nil {
+		t.Fatalf("NewCIDRSet() error = %v", err)
+	}
+
+	for addr, want := range map[string]bool{
+		"10.255.0.1":        true,
+		"10.1.2.3":          true,
+		"11.0.0.0":          false,
+		"192.0.2.1":         true,
+		"192.0.2.2":         false,
+		"198.51.100.1":      true,
+		"198.51.100.2":      false,
+		"::ffff:10.0.0.1":   true,
+		"203.0.113.77":      true,
+		"2001:db8:1::1":     true,
+		"2001:db9::1":       false,
+		"::a00:1":           false,
+		"0.0.0.0":           false,
+		"255.255.255.255":   false,
+		"2001:db8:ffff::ff": true,
+	} {
+		if got := set.Contains(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if set.Contains(netip.Addr{}) {
+		t.Error("Contains() of the zero Addr = true")
+	}
+
+	all, _ := util.NewCIDRSet([]string{"0.0.0.0/0"})
+	if !all.Contains(netip.MustParseAddr("1.2.3.4")) || all.Contains(netip.MustParseAddr("::1")) {
+		t.Error("0.0.0.0/0 should contain every IPv4 address and no IPv6 address")
+	}
+
+	if _, err = util.NewCIDRSet([]string{"10.0.0.0/8", "bogus"}); err == nil {
+		t.Error("NewCIDRSet() accepted an invalid entry")
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// ClientKeyGranularity selects how coarsely ClientKey groups clients.
+type ClientKeyGranularity int
+
+const (
+	// ClientKeyIP keys each client address separately.
+	ClientKeyIP ClientKeyGranularity = iota
+	// ClientKeySubnet keys IPv4 clients by /24 and IPv6 clients by /64, so
+	// a client rotating through the addresses of its network, as IPv6
+	// privacy extensions do, still shares one key.
+	ClientKeySubnet
+)
+
+// Subnet sizes used by ClientKeySubnet.
+const (
+	clientKeySubnetV4Bits = 24
+	clientKeySubnetV6Bits = 64
+)
+
+// ClientKey returns a stable key for the client that sent r, for rate
+// limiting and consistent-hash sharding, such as "ip:203.0.113.7" or
+// "net:2001:db8:1:2::/64". It keys the peer address (RemoteAddr) without
+// consulting any header; behind proxies use IPResolver.ClientKey.
+func ClientKey(r *http.Request, granularity ClientKeyGranularity) string {
+	addr, err := netip.ParseAddr(remoteHost(r.RemoteAddr))
+	return clientKey(r, addr, err, granularity)
+}
+
+// ClientKey returns a stable key for the client that sent r, like the
+// package-level ClientKey, using the client address resolved by ClientAddr
+// so clients behind trusted proxies and CDNs are told apart.
+//
+// Example:
+//
+//	key := resolver.ClientKey(r, ClientKeySubnet)
+//	if !limiter.Allow(key) {
+//	    http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+//	    return
+//	}
+func (ir *IPResolver) ClientKey(r *http.Request, granularity ClientKeyGranularity) string {
+	addr, err := ir.ClientAddr(r)
+	return clientKey(r, addr, err, granularity)
+}
+
+// clientKey builds the key for addr, falling back to the raw RemoteAddr when
+// the request carries no parsable address.
+func clientKey(r *http.Request, addr netip.Addr, err error, granularity ClientKeyGranularity) string {
+	if err != nil {
+		return "raw:" + r.RemoteAddr
+	}
+	addr = addr.Unmap()
+	if granularity != ClientKeySubnet {
+		return "ip:" + addr.String()
+	}
+
+	bits := clientKeySubnetV6Bits
+	if addr.Is4() {
+		bits = clientKeySubnetV4Bits
+	}
+	prefix, _ := addr.Prefix(bits)
+	return "net:" + prefix.String()
+}
@@ -0,0 +1,50 @@
+package util_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		remoteAddr  string
+		granularity util.ClientKeyGranularity
+		want        string
+	}{
+		{"203.0.113.7:1234", util.ClientKeyIP, "ip:203.0.113.7"},
+		{"203.0.113.7:1234", util.ClientKeySubnet, "net:203.0.113.0/24"},
+		{"[2001:db8:1:2:aaaa::1]:443", util.ClientKeyIP, "ip:2001:db8:1:2:aaaa::1"},
+		{"[2001:db8:1:2:aaaa::1]:443", util.ClientKeySubnet, "net:2001:db8:1:2::/64"},
+		{"[::ffff:203.0.113.7]:80", util.ClientKeySubnet, "net:203.0.113.0/24"},
+		{"pipe", util.ClientKeyIP, "raw:pipe"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		req.Header.Set("X-Forwarded-For", "198.51.100.1")
+		if got := util.ClientKey(req, tt.granularity); got != tt.want {
+			t.Errorf("ClientKey(%s, %d) = %q, want %q", tt.remoteAddr, tt.granularity, got, tt.want)
+		}
+	}
+}
+
+func TestIPResolverClientKey(t *testing.T) {
+	resolver, err := util.NewIPResolver([]string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatalf("NewIPResolver() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.5:80"
+	req.Header.Set("X-Forwarded-For", "198.51.100.77")
+
+	if got := resolver.ClientKey(req, util.ClientKeyIP); got != "ip:198.51.100.77" {
+		t.Errorf("ClientKey() = %q, want ip:198.51.100.77", got)
+	}
+	if got := resolver.ClientKey(req, util.ClientKeySubnet); got != "net:198.51.100.0/24" {
+		t.Errorf("ClientKey() = %q, want net:198.51.100.0/24", got)
+	}
+}
@@ -4,7 +4,10 @@ package util
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"sync"
 )
 
 // CloseAndLogOnError Closes io.Closer and logs the error if any with the messages supplied.
@@ -17,3 +20,182 @@ func CloseAndLogOnError(ctx context.Context, closer io.Closer, message ...string
 		Log(ctx).WithError(err).Error(message[0])
 	}
 }
+
+// CloseAll closes every closer, continuing past failures, and returns their
+// errors joined. Unlike CloseAndLogOnError it never drops an error: each
+// failure is also logged to the logger in ctx with the closer's name. The
+// name is the one given to NamedCloser, else the result of a Name or String
+// method, such as *os.File's, else the closer's type. Nil closers are
+// skipped.
+//
+// Example:
+//
+//	defer func() {
+//	    err = errors.Join(err, CloseAll(ctx, NamedCloser("orders db", db), file))
+//	}()
+func CloseAll(ctx context.Context, closers ...io.Closer) error {
+	var errs []error
+	for _, closer := range closers {
+		if closer == nil {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			name := closerName(closer)
+			Log(ctx).WithError(err).WithField("closer", name).Error("failed to close")
+			errs = append(errs, fmt.Errorf("close %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// namedCloser is the io.Closer returned by NamedCloser.
+type namedCloser struct {
+	io.Closer
+
+	name string
+}
+
+// NamedCloser wraps c so that CloseAll reports its failures under name.
+func NamedCloser(name string, c io.Closer) io.Closer {
+	if c == nil {
+		return nil
+	}
+	return namedCloser{Closer: c, name: name}
+}
+
+// closerName returns the name CloseAll reports c's failures under.
+func closerName(c io.Closer) string {
+	switch named := c.(type) {
+	case namedCloser:
+		return named.name
+	case interface{ Name() string }:
+		return named.Name()
+	case fmt.Stringer:
+		return named.String()
+	default:
+		return fmt.Sprintf("%T", c)
+	}
+}
+
+// Closers collects resources to release together, replacing chains of
+// deferred CloseAndLogOnError calls. Close releases them in the reverse of
+// the order they were added, like deferred calls, so a resource is closed
+// before the ones it was built on.
+//
+// Each resource is closed once: Close releases what was added since the
+// previous Close, so calling it again is safe. The zero value is ready to
+// use, and a Closers is safe for concurrent use.
+//
+// Example:
+//
+//	var closers Closers
+//	defer func() { err = errors.Join(err, closers.Close()) }()
+//
+//	db, err := sql.Open("pgx", dsn)
+//	if err != nil {
+//	    return err
+//	}
+//	closers.Add(db)
+//	closers.AddFunc(func() error { return tracer.Shutdown(ctx) })
+type Closers struct {
+	mu      sync.Mutex
+	closers []func() error
+}
+
+// Add registers closer to be closed. A nil closer is ignored.
+func (c *Closers) Add(closer io.Closer) {
+	if closer == nil {
+		return
+	}
+	c.add(closerName(closer), closer.Close)
+}
+
+// AddFunc registers a cleanup function to be called by Close. A nil
+// function is ignored.
+func (c *Closers) AddFunc(fn func() error) {
+	if fn == nil {
+		return
+	}
+	c.add(funcName(fn), fn)
+}
+
+func (c *Closers) add(name string, fn func() error) {
+	if res := trackResource(name); res != nil {
+		closeFn := fn
+		fn = func() error {
+			res.release()
+			return closeFn()
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closers = append(c.closers, fn)
+}
+
+// Close closes the registered resources in reverse order, continuing past
+// failures, and returns their errors joined.
+func (c *Closers) Close() error {
+	c.mu.Lock()
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CloserFunc adapts a cleanup function to io.Closer.
+//
+// Example:
+//
+//	CloseAndLogOnError(ctx, CloserFunc(func() error { return tracer.Shutdown(ctx) }), "failed to stop tracer")
+type CloserFunc func() error
+
+// Close calls f.
+func (f CloserFunc) Close() error {
+	return f()
+}
+
+// onceCloser is the io.Closer returned by OnceCloser.
+type onceCloser struct {
+	closer  io.Closer
+	tracked *trackedResource
+	once    sync.Once
+	err     error
+}
+
+// OnceCloser wraps c so that it is closed only once, however many times and
+// from however many goroutines Close is called, for resources whose cleanup
+// is reachable from several paths. Every call returns the error of the
+// first, waiting for it to finish.
+//
+// Example:
+//
+//	conn := OnceCloser(rawConn)
+//	defer conn.Close()
+//	go func() {
+//	    <-ctx.Done()
+//	    conn.Close()
+//	}()
+func OnceCloser(c io.Closer) io.Closer {
+	once := &onceCloser{closer: c}
+	if c != nil {
+		once.tracked = trackResource(closerName(c))
+	}
+	return once
+}
+
+func (c *onceCloser) Close() error {
+	c.once.Do(func() {
+		c.tracked.release()
+		if c.closer != nil {
+			c.err = c.closer.Close()
+		}
+	})
+	return c.err
+}
@@ -0,0 +1,112 @@
+package util_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+// orderedCloser is an io.Closer that records the order it was closed in.
+type orderedCloser struct {
+	name  string
+	order *[]string
+	err   error
+}
+
+func (c *orderedCloser) Close() error {
+	*c.order = append(*c.order, c.name)
+	return c.err
+}
+
+func TestClosers(t *testing.T) {
+	var order []string
+	errB := errors.New("b failed")
+	errC := errors.New("c failed")
+
+	var closers util.Closers
+	closers.Add(&orderedCloser{name: "a", order: &order})
+	closers.Add(nil)
+	closers.Add(&orderedCloser{name: "b", order: &order, err: errB})
+	closers.AddFunc(func() error {
+		order = append(order, "c")
+		return errC
+	})
+
+	err := closers.Close()
+	if !slices.Equal(order, []string{"c", "b", "a"}) {
+		t.Errorf("close order = %q, want [c b a]", order)
+	}
+	if !errors.Is(err, errB) || !errors.Is(err, errC) {
+		t.Errorf("Close = %v, want both failures joined", err)
+	}
+
+	if err := closers.Close(); err != nil || len(order) != 3 {
+		t.Errorf("second Close = %v and closed %q again", err, order[3:])
+	}
+
+	closers.Add(&orderedCloser{name: "d", order: &order})
+	if err := closers.Close(); err != nil || order[len(order)-1] != "d" {
+		t.Errorf("Close after adding again = %v, order %q", err, order)
+	}
+}
+
+func TestOnceCloser(t *testing.T) {
+	errClose := errors.New("close failed")
+	var calls atomic.Int32
+	closer := util.OnceCloser(util.CloserFunc(func() error {
+		calls.Add(1)
+		return errClose
+	}))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if err := closer.Close(); !errors.Is(err, errClose) {
+				t.Errorf("Close = %v, want the first close's error", err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("underlying Close called %d times, want 1", n)
+	}
+
+	if err := util.OnceCloser(nil).Close(); err != nil {
+		t.Errorf("Close of a nil closer = %v", err)
+	}
+}
+
+func TestCloseAll(t *testing.T) {
+	var order []string
+	errA := errors.New("a failed")
+	errC := errors.New("c failed")
+
+	err := util.CloseAll(t.Context(),
+		util.NamedCloser("first", &orderedCloser{name: "a", order: &order, err: errA}),
+		nil,
+		&orderedCloser{name: "b", order: &order},
+		util.CloserFunc(func() error {
+			order = append(order, "c")
+			return errC
+		}),
+	)
+	if !slices.Equal(order, []string{"a", "b", "c"}) {
+		t.Errorf("close order = %q, want every closer in order", order)
+	}
+	if !errors.Is(err, errA) || !errors.Is(err, errC) {
+		t.Errorf("CloseAll = %v, want both failures joined", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "close first: a failed") ||
+		!strings.Contains(msg, "close util.CloserFunc: c failed") {
+		t.Errorf("CloseAll error %q does not name the closers", msg)
+	}
+
+	if err := util.CloseAll(t.Context()); err != nil {
+		t.Errorf("CloseAll() = %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package util
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const maxTrackedStackDepth = 32
+
+// closerTracking holds the resources registered while tracking is enabled
+// and not closed since.
+//
+//nolint:gochecknoglobals // process-wide leak detection
+var closerTracking struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	open    map[*trackedResource]struct{}
+}
+
+// trackedResource is a registered resource and where it was registered.
+type trackedResource struct {
+	name  string
+	stack string
+}
+
+// SetCloserTracking turns leak detection for managed resources on or off.
+// While it is on, resources registered with Closers, OnceCloser or
+// TrackCloser record the stack that registered them until they are closed,
+// and ReportUnclosed lists those never closed. Capturing stacks is costly,
+// so enable it in tests rather than in production. Turning it off forgets
+// the resources tracked so far.
+//
+// Example:
+//
+//	func TestMain(m *testing.M) {
+//	    util.SetCloserTracking(true)
+//	    code := m.Run()
+//	    if util.ReportUnclosed(context.Background()) > 0 && code == 0 {
+//	        code = 1
+//	    }
+//	    os.Exit(code)
+//	}
+func SetCloserTracking(enabled bool) {
+	closerTracking.mu.Lock()
+	defer closerTracking.mu.Unlock()
+	closerTracking.enabled.Store(enabled)
+	if !enabled {
+		closerTracking.open = nil
+	}
+}
+
+// TrackCloser registers c for leak detection under name and returns a
+// closer that unregisters it when closed. While tracking is off it returns
+// c unchanged.
+//
+// Example:
+//
+//	conn = TrackCloser("upstream conn", conn)
+func TrackCloser(name string, c io.Closer) io.Closer {
+	res := trackResource(name)
+	if res == nil || c == nil {
+		return c
+	}
+	return CloserFunc(func() error {
+		res.release()
+		return c.Close()
+	})
+}
+
+// ReportUnclosed logs every resource registered while tracking was on that
+// has not been closed, with the stack that registered it, to the logger in
+// ctx, and returns how many there are.
+func ReportUnclosed(ctx context.Context) int {
+	closerTracking.mu.Lock()
+	open := make([]*trackedResource, 0, len(closerTracking.open))
+	for res := range closerTracking.open {
+		open = append(open, res)
+	}
+	closerTracking.mu.Unlock()
+
+	for _, res := range open {
+		Log(ctx).WithField("resource", res.name).WithField("stack", res.stack).Warn("resource was never closed")
+	}
+	return len(open)
+}
+
+// trackResource records a resource being registered, or returns nil when
+// tracking is off.
+func trackResource(name string) *trackedResource {
+	if !closerTracking.enabled.Load() {
+		return nil
+	}
+	res := &trackedResource{name: name, stack: registrationStack()}
+
+	closerTracking.mu.Lock()
+	defer closerTracking.mu.Unlock()
+	if closerTracking.open == nil {
+		closerTracking.open = make(map[*trackedResource]struct{})
+	}
+	closerTracking.open[res] = struct{}{}
+	return res
+}
+
+// release marks r closed. It is a no-op on a nil resource.
+func (r *trackedResource) release() {
+	if r == nil {
+		return
+	}
+	closerTracking.mu.Lock()
+	defer closerTracking.mu.Unlock()
+	delete(closerTracking.open, r)
+}
+
+// registrationStack formats the current stack without the frames of this
+// package, so it starts where the resource was registered.
+func registrationStack() string {
+	pcs := make([]uintptr, maxTrackedStackDepth)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+
+	var b strings.Builder
+	inPackage := true
+	for {
+		frame, more := frames.Next()
+		if inPackage && strings.HasPrefix(frame.Function, packagePath+".") {
+			if !more {
+				break
+			}
+			continue
+		}
+		inPackage = false
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// funcName returns the name of fn for leak reports.
+func funcName(fn any) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "func"
+}
+
+// packagePath is the import path of this package, used to trim its frames.
+//
+//nolint:gochecknoglobals // derived once from the type system
+var packagePath = reflect.TypeFor[trackedResource]().PkgPath()
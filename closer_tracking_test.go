@@ -0,0 +1,54 @@
+package util_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestCloserTracking(t *testing.T) {
+	util.SetCloserTracking(true)
+	defer util.SetCloserTracking(false)
+
+	var logs bytes.Buffer
+	ctx := util.ContextWithLogger(t.Context(), util.NewLogger(t.Context(), util.WithLogOutput(&logs)))
+
+	var closers util.Closers
+	closers.Add(io.NopCloser(nil))
+	closers.AddFunc(func() error { return nil })
+	once := util.OnceCloser(io.NopCloser(nil))
+	tracked := util.TrackCloser("upstream conn", io.NopCloser(nil))
+
+	if n := util.ReportUnclosed(ctx); n != 4 {
+		t.Errorf("ReportUnclosed = %d, want 4", n)
+	}
+	out := logs.String()
+	if !strings.Contains(out, "upstream conn") || !strings.Contains(out, "TestCloserTracking") {
+		t.Errorf("report does not name the resource and where it was registered:\n%s", out)
+	}
+	if strings.Contains(out, "util.trackResource") {
+		t.Errorf("report includes the package's own frames:\n%s", out)
+	}
+
+	_ = closers.Close()
+	_ = once.Close()
+	_ = tracked.Close()
+	if n := util.ReportUnclosed(ctx); n != 0 {
+		t.Errorf("ReportUnclosed after closing everything = %d", n)
+	}
+}
+
+func TestCloserTrackingDisabled(t *testing.T) {
+	closer := io.NopCloser(nil)
+	if got := util.TrackCloser("conn", closer); got != closer {
+		t.Error("TrackCloser wrapped the closer while tracking is off")
+	}
+	var closers util.Closers
+	closers.Add(closer)
+	if n := util.ReportUnclosed(t.Context()); n != 0 {
+		t.Errorf("ReportUnclosed with tracking off = %d", n)
+	}
+}
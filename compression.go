@@ -0,0 +1,132 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression identifies how plaintext was compressed before sealing.
+type Compression byte
+
+const (
+	// CompressionNone stores plaintext as is.
+	CompressionNone Compression = 0x00
+	// CompressionGzip compresses plaintext with gzip (RFC 1952).
+	CompressionGzip Compression = 0x01
+	// CompressionZstd compresses plaintext with Zstandard (RFC 8878). It is
+	// faster than gzip at a similar or better ratio and is the better default.
+	CompressionZstd Compression = 0x02
+)
+
+const (
+	// payloadVersion2 marks a compressed payload: [version][algorithm][compression][nonce][ciphertext][tag].
+	payloadVersion2     = 0x02
+	payloadV2HeaderSize = 3
+
+	// maxDecompressedSize bounds the plaintext a compressed payload may expand to.
+	maxDecompressedSize = 256 << 20
+)
+
+// String returns the conventional name of the compression.
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("Compression(%d)", byte(c))
+	}
+}
+
+// compressor compresses plaintext before sealing. The zstd encoder is built
+// once and shared, since EncodeAll is safe for concurrent use.
+type compressor struct {
+	compression Compression
+	zstd        *zstd.Encoder
+}
+
+func newCompressor(c Compression) (*compressor, error) {
+	switch c {
+	case CompressionNone, CompressionGzip:
+		return &compressor{compression: c}, nil
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		return &compressor{compression: c, zstd: enc}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", c)
+	}
+}
+
+// compress returns the compressed plaintext, or nil when compression is
+// disabled or would not make the plaintext smaller.
+func (c *compressor) compress(plaintext []byte) ([]byte, error) {
+	var out []byte
+	switch c.compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(plaintext); err != nil {
+			return nil, fmt.Errorf("failed to compress plaintext: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress plaintext: %w", err)
+		}
+		out = buf.Bytes()
+	case CompressionZstd:
+		out = c.zstd.EncodeAll(plaintext, nil)
+	default:
+		return nil, nil
+	}
+
+	if len(out) >= len(plaintext) {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// decompress reverses compress, refusing output larger than maxDecompressedSize.
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return bytes.Clone(data), nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress plaintext: %w", err)
+		}
+		out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress plaintext: %w", err)
+		}
+		if len(out) > maxDecompressedSize {
+			return nil, errors.New("decompressed plaintext exceeds the size limit")
+		}
+		return out, nil
+	case CompressionZstd:
+		dec, err := zstd.NewReader(nil,
+			zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecompressedSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+		}
+		defer dec.Close()
+
+		out, err := dec.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress plaintext: %w", err)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %s", c)
+	}
+}
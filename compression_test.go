@@ -0,0 +1,126 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEncryptorCompression(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	// A large, repetitive JSON document of the kind compression targets.
+	document := []byte(`{"items":[` + strings.Repeat(`{"sku":"ABC-123","qty":1,"note":"standard delivery"},`, 200) + `{}]}`)
+
+	for _, compression := range []util.Compression{util.CompressionGzip, util.CompressionZstd} {
+		t.Run(compression.String(), func(t *testing.T) {
+			enc, err := util.NewEncryptor(key, util.WithEncryptorCompression(compression))
+			if err != nil {
+				t.Fatalf("NewEncryptor() failed: %v", err)
+			}
+			if enc.Compression() != compression {
+				t.Errorf("Compression() = %s, want %s", enc.Compression(), compression)
+			}
+
+			ciphertext, err := enc.Encrypt(document)
+			if err != nil {
+				t.Fatalf("Encrypt() failed: %v", err)
+			}
+			if len(ciphertext) >= len(document)/4 {
+				t.Errorf("ciphertext is %d bytes for a %d byte document, want it compressed", len(ciphertext), len(document))
+			}
+
+			decrypted, err := enc.Decrypt(ciphertext)
+			if err != nil || !bytes.Equal(decrypted, document) {
+				t.Fatalf("Decrypt() = %d bytes, %v; want the original document", len(decrypted), err)
+			}
+
+			// DecryptValue and uncompressed Encryptors decompress transparently.
+			decrypted, err = util.DecryptValue(key, ciphertext)
+			if err != nil || !bytes.Equal(decrypted, document) {
+				t.Errorf("DecryptValue() = %d bytes, %v; want the original document", len(decrypted), err)
+			}
+			plain, _ := util.NewEncryptor(key)
+			decrypted, err = plain.Decrypt(ciphertext)
+			if err != nil || !bytes.Equal(decrypted, document) {
+				t.Errorf("uncompressed Decrypt() = %d bytes, %v; want the original document", len(decrypted), err)
+			}
+		})
+	}
+}
+
+func TestEncryptorCompressionSkipsIncompressibleValues(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	random := make([]byte, 256)
+	rand.Read(random)
+
+	compressed, _ := util.NewEncryptor(key, util.WithEncryptorCompression(util.CompressionZstd))
+	plain, _ := util.NewEncryptor(key)
+
+	withCompression, err := compressed.Encrypt(random)
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+	withoutCompression, err := plain.Encrypt(random)
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+	if len(withCompression) != len(withoutCompression) {
+		t.Errorf("incompressible payload is %d bytes, want %d", len(withCompression), len(withoutCompression))
+	}
+}
+
+func TestEncryptorCompressionHeaderIsAuthenticated(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	enc, _ := util.NewEncryptor(key, util.WithEncryptorCompression(util.CompressionGzip))
+	ciphertext, err := enc.Encrypt([]byte(strings.Repeat("compress me ", 50)))
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+
+	// Relabel the compression byte from gzip to zstd.
+	tampered := bytes.Clone(ciphertext)
+	tampered[2] = byte(util.CompressionZstd)
+	if _, err = enc.Decrypt(tampered); err == nil {
+		t.Error("Decrypt() accepted a payload with a modified compression header")
+	}
+}
+
+func TestNewEncryptorRejectsUnknownCompression(t *testing.T) {
+	if _, err := util.NewEncryptor(make([]byte, 32), util.WithEncryptorCompression(util.Compression(9))); err == nil {
+		t.Error("NewEncryptor() accepted an unknown compression")
+	}
+}
+
+func TestReEncryptKeepsCompression(t *testing.T) {
+	oldKey, newKey := make([]byte, 32), make([]byte, 32)
+	rand.Read(oldKey)
+	rand.Read(newKey)
+	document := []byte(strings.Repeat(`{"status":"active"}`, 100))
+
+	enc, _ := util.NewEncryptor(oldKey, util.WithEncryptorCompression(util.CompressionZstd))
+	payload, err := enc.Encrypt(document)
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+
+	ring, _ := util.NewKeyring(oldKey)
+	migrated, err := util.ReEncrypt(ring, newKey, payload)
+	if err != nil {
+		t.Fatalf("ReEncrypt() failed: %v", err)
+	}
+	if len(migrated) != len(payload) {
+		t.Errorf("ReEncrypt() payload is %d bytes, want %d", len(migrated), len(payload))
+	}
+	decrypted, err := util.DecryptValue(newKey, migrated)
+	if err != nil || !bytes.Equal(decrypted, document) {
+		t.Errorf("DecryptValue() = %d bytes, %v; want the original document", len(decrypted), err)
+	}
+}
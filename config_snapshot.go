@@ -0,0 +1,244 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretValue is implemented by Secret, giving the snapshot code alone
+// access to the wrapped value.
+type secretValue interface {
+	secretJSON() ([]byte, error)
+}
+
+// configSecretKey keys the digests of secret settings for this process, so
+// that a published Hash cannot be used to guess a secret offline.
+var configSecretKey = sync.OnceValue(func() []byte { //nolint:gochecknoglobals // per-process key
+	key := make([]byte, sha256.Size)
+	_, _ = rand.Read(key)
+	return key
+})
+
+// ConfigSnapshot is a canonical view of a configuration value, as taken by
+// SnapshotConfig: its settings flattened to dotted paths, and a hash that
+// changes whenever any of them, secrets included, does.
+type ConfigSnapshot struct {
+	// Hash is the hex SHA-256 of the canonical form of every setting. Secrets
+	// enter it through an HMAC under a random per-process key, so hashes of
+	// configurations with secrets only compare within one process.
+	Hash string `json:"hash"`
+
+	// Values maps the path of each setting, such as "Database.Timeout", to
+	// its value encoded as JSON, with secrets shown as "[REDACTED]".
+	Values map[string]string `json:"values"`
+
+	// digests maps paths to a digest of the unmasked value
+	digests map[string]string
+}
+
+// ConfigChange is a setting that differs between two snapshots.
+type ConfigChange struct {
+	// Path is the setting's dotted path.
+	Path string `json:"path"`
+
+	// Old and New are the JSON-encoded values, "" when the setting is
+	// absent, and "[REDACTED]" for secrets.
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// SnapshotConfig takes a ConfigSnapshot of cfg, typically a configuration
+// struct loaded by LoadEnv, to tell precisely whether and how it changed on
+// a reload. Struct fields are named by their json tag, or else the field
+// name, and nested structs and maps are flattened into dotted paths; other
+// values, including slices and types with a MarshalText method, are single
+// settings. Unexported fields and fields tagged json:"-" are skipped.
+//
+// Values of type Secret and fields tagged env:"...,secret" are masked, but
+// still take part in the hash, keyed so that it reveals nothing about them,
+// and rotating a secret is detected.
+//
+// Example:
+//
+//	settings.OnChange(func(prev, next Tuning) {
+//	    changes, _ := DiffConfig(prev, next)
+//	    Log(ctx).WithField("changes", changes).Info("config changed")
+//	})
+func SnapshotConfig(cfg any) (ConfigSnapshot, error) {
+	snapshot := ConfigSnapshot{Values: make(map[string]string), digests: make(map[string]string)}
+	if err := snapshot.add("", reflect.ValueOf(cfg), false); err != nil {
+		return ConfigSnapshot{}, err
+	}
+
+	h := sha256.New()
+	for _, path := range slices.Sorted(maps.Keys(snapshot.digests)) {
+		fmt.Fprintf(h, "%q=%s\n", path, snapshot.digests[path])
+	}
+	snapshot.Hash = hex.EncodeToString(h.Sum(nil))
+	return snapshot, nil
+}
+
+// DiffConfig returns the settings that differ between the configuration
+// values prev and next, sorted by path, with secrets masked.
+func DiffConfig(prev, next any) ([]ConfigChange, error) {
+	prevSnapshot, err := SnapshotConfig(prev)
+	if err != nil {
+		return nil, err
+	}
+	nextSnapshot, err := SnapshotConfig(next)
+	if err != nil {
+		return nil, err
+	}
+	return prevSnapshot.Diff(nextSnapshot), nil
+}
+
+// Diff returns the settings that differ between s and next, sorted by
+// path, with secrets masked.
+func (s ConfigSnapshot) Diff(next ConfigSnapshot) []ConfigChange {
+	var changes []ConfigChange
+	paths := slices.Sorted(maps.Keys(s.digests))
+	for path := range next.digests {
+		if _, ok := s.digests[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		if s.digests[path] != next.digests[path] {
+			changes = append(changes, ConfigChange{Path: path, Old: s.Values[path], New: next.Values[path]})
+		}
+	}
+	return changes
+}
+
+// add records v at path, flattening structs and maps.
+func (s *ConfigSnapshot) add(path string, v reflect.Value, secret bool) error {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if _, ok := v.Interface().(secretValue); ok {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return s.addLeaf(path, nil, secret)
+	}
+	if !isConfigLeaf(v) {
+		switch v.Kind() { //nolint:exhaustive // other kinds are leaves
+		case reflect.Struct:
+			return s.addStruct(path, v, secret)
+		case reflect.Map:
+			keys := v.MapKeys()
+			slices.SortFunc(keys, func(a, b reflect.Value) int {
+				return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+			})
+			for _, key := range keys {
+				if err := s.add(joinConfigPath(path, fmt.Sprint(key.Interface())), v.MapIndex(key), secret); err != nil {
+					return err
+				}
+			}
+			if len(keys) == 0 {
+				return s.addLeaf(path, v.Interface(), secret)
+			}
+			return nil
+		}
+	}
+	return s.addLeaf(path, v.Interface(), secret)
+}
+
+func (s *ConfigSnapshot) addStruct(path string, v reflect.Value, secret bool) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, tagged := field.Name, false
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name, tagged = tagName, true
+			}
+		}
+		fieldPath := joinConfigPath(path, name)
+		if field.Anonymous && !tagged && indirectType(field.Type).Kind() == reflect.Struct {
+			// Embedded structs are flattened into their parent, as by encoding/json.
+			fieldPath = path
+		}
+		fieldSecret := secret || parseEnvTag(field.Tag.Get("env")).secret
+		if err := s.add(fieldPath, v.Field(i), fieldSecret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addLeaf records value as the setting at path.
+func (s *ConfigSnapshot) addLeaf(path string, value any, secret bool) error {
+	var raw []byte
+	var err error
+	_, isSecret := value.(secretValue)
+	switch val := value.(type) {
+	case secretValue:
+		raw, err = val.secretJSON()
+	case time.Duration:
+		raw, err = json.Marshal(val.String())
+	default:
+		raw, err = json.Marshal(value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode config setting %s: %w", path, err)
+	}
+
+	if secret || isSecret {
+		mac := hmac.New(sha256.New, configSecretKey())
+		mac.Write(raw)
+		s.digests[path] = hex.EncodeToString(mac.Sum(nil))
+		s.Values[path] = `"` + redacted + `"`
+		return nil
+	}
+	digest := sha256.Sum256(raw)
+	s.digests[path] = hex.EncodeToString(digest[:])
+	s.Values[path] = string(raw)
+	return nil
+}
+
+// isConfigLeaf reports whether v is a single setting rather than a struct
+// or map to flatten.
+func isConfigLeaf(v reflect.Value) bool {
+	if _, ok := v.Interface().(secretValue); ok {
+		return true
+	}
+	if v.Type().Implements(reflect.TypeFor[encoding.TextMarshaler]()) ||
+		v.Type().Implements(reflect.TypeFor[json.Marshaler]()) {
+		return true
+	}
+	return v.Kind() != reflect.Struct && v.Kind() != reflect.Map
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+func joinConfigPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
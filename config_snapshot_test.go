@@ -0,0 +1,140 @@
+package util_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type snapshotDatabase struct {
+	URL      util.Secret[string] `json:"url"`
+	Password string              `env:"DB_PASSWORD,secret"`
+	Timeout  time.Duration
+}
+
+type SnapshotBase struct {
+	Region string `json:"region"`
+}
+
+type snapshotConfig struct {
+	SnapshotBase
+	Database snapshotDatabase  `json:"database"`
+	Limits   map[string]int    `json:"limits"`
+	Origins  []string          `json:"origins"`
+	Started  time.Time         `json:"started"`
+	Ignored  string            `json:"-"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	hidden   string
+}
+
+func newSnapshotConfig() snapshotConfig {
+	return snapshotConfig{
+		SnapshotBase: SnapshotBase{Region: "eu"},
+		Database: snapshotDatabase{
+			URL:      util.NewSecret("postgres://old"),
+			Password: "hunter2",
+			Timeout:  5 * time.Second,
+		},
+		Limits:  map[string]int{"upload": 10, "download": 20},
+		Origins: []string{"https://a.example"},
+		Started: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Ignored: "x",
+		hidden:  "y",
+	}
+}
+
+func TestSnapshotConfig(t *testing.T) {
+	cfg := newSnapshotConfig()
+	snapshot, err := util.SnapshotConfig(cfg)
+	if err != nil {
+		t.Fatalf("SnapshotConfig: %v", err)
+	}
+
+	want := map[string]string{
+		"region":            `"eu"`,
+		"database.url":      `"[REDACTED]"`,
+		"database.Password": `"[REDACTED]"`,
+		"database.Timeout":  `"5s"`,
+		"limits.download":   `20`,
+		"limits.upload":     `10`,
+		"origins":           `["https://a.example"]`,
+		"started":           `"2024-01-02T03:04:05Z"`,
+		"labels":            `null`,
+	}
+	if len(snapshot.Values) != len(want) {
+		t.Errorf("Values = %v", snapshot.Values)
+	}
+	for path, value := range want {
+		if snapshot.Values[path] != value {
+			t.Errorf("Values[%q] = %s, want %s", path, snapshot.Values[path], value)
+		}
+	}
+
+	again, _ := util.SnapshotConfig(&cfg)
+	if again.Hash != snapshot.Hash || len(snapshot.Hash) != 64 {
+		t.Fatalf("hash not stable: %s and %s", snapshot.Hash, again.Hash)
+	}
+	cfg.Ignored = "changed"
+	if unchanged, _ := util.SnapshotConfig(cfg); unchanged.Hash != snapshot.Hash {
+		t.Fatal("an ignored field changed the hash")
+	}
+	cfg.Database.URL = util.NewSecret("postgres://new")
+	if rotated, _ := util.SnapshotConfig(cfg); rotated.Hash == snapshot.Hash {
+		t.Fatal("rotating a secret did not change the hash")
+	}
+}
+
+func TestSnapshotConfigHashHidesSecrets(t *testing.T) {
+	type secretConfig struct {
+		Password string `env:"PASSWORD,secret" json:"password"`
+	}
+	snapshot, err := util.SnapshotConfig(secretConfig{Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("SnapshotConfig: %v", err)
+	}
+
+	// Recompute the hash as an attacker guessing the password would.
+	digest := sha256.Sum256([]byte(`"hunter2"`))
+	guess := sha256.Sum256([]byte(fmt.Sprintf("%q=%s\n", "password", hex.EncodeToString(digest[:]))))
+	if snapshot.Hash == hex.EncodeToString(guess[:]) {
+		t.Fatal("the hash can be recomputed from a guessed secret")
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	prev := newSnapshotConfig()
+	next := newSnapshotConfig()
+	next.Database.URL = util.NewSecret("postgres://new")
+	next.Database.Timeout = 10 * time.Second
+	delete(next.Limits, "download")
+	next.Limits["burst"] = 5
+
+	changes, err := util.DiffConfig(prev, next)
+	if err != nil {
+		t.Fatalf("DiffConfig: %v", err)
+	}
+	want := []util.ConfigChange{
+		{Path: "database.Timeout", Old: `"5s"`, New: `"10s"`},
+		{Path: "database.url", Old: `"[REDACTED]"`, New: `"[REDACTED]"`},
+		{Path: "limits.burst", Old: "", New: "5"},
+		{Path: "limits.download", Old: "20", New: ""},
+	}
+	if !slices.Equal(changes, want) {
+		t.Fatalf("DiffConfig = %+v, want %+v", changes, want)
+	}
+	for _, change := range changes {
+		if strings.Contains(change.Old+change.New, "postgres") {
+			t.Fatalf("secret leaked in %+v", change)
+		}
+	}
+
+	if same, _ := util.DiffConfig(prev, newSnapshotConfig()); len(same) != 0 {
+		t.Fatalf("DiffConfig of equal configs = %+v", same)
+	}
+}
@@ -4,6 +4,10 @@ package util
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
 )
 
 // ctxValueRequestID is the key to extract the request ID for an HTTP request.
@@ -26,3 +30,156 @@ func GetRequestID(ctx context.Context) string {
 	}
 	return str
 }
+
+// DetachContext returns a context for fire-and-forget work started from a
+// request handler. It is never canceled and has no deadline, so the work
+// outlives the request, but it keeps the logger, request ID, tenancy,
+// baggage and actor (see AsSystem and Impersonate) of ctx so the work still
+// logs and authorizes as part of that request. The random source and ID
+// generator injected by tests are kept too.
+//
+// Other values of ctx are dropped, so the detached work does not keep the
+// request's resources reachable. Use context.WithoutCancel to keep every value.
+//
+// Example:
+//
+//	go sendReceipt(DetachContext(req.Context()), order)
+func DetachContext(ctx context.Context) context.Context {
+	detached := context.Background()
+	if requestID := GetRequestID(ctx); requestID != "" {
+		detached = ContextWithRequestID(detached, requestID)
+	}
+	if tenancy := GetTenancy(ctx); tenancy != nil {
+		detached = SetTenancy(detached, tenancy)
+	}
+	for _, key := range []contextKeyType{
+		ctxValueSystemActor, ctxValueImpersonation, ctxValueRandSource, ctxValueIDGenerator,
+	} {
+		if value := ctx.Value(key); value != nil {
+			detached = context.WithValue(detached, key, value)
+		}
+	}
+	if baggage, ok := ctx.Value(ctxValueBaggage).(map[string]string); ok {
+		detached = context.WithValue(detached, ctxValueBaggage, baggage)
+	}
+	if logger, ok := ctx.Value(ctxValueLogger).(*LogEntry); ok {
+		// Rebind the logger so it no longer refers to the request context.
+		detached = ContextWithLogger(detached, logger.WithContext(detached))
+	}
+	return detached
+}
+
+// ContextKey is a typed, collision-safe context key created by NewContextKey.
+type ContextKey[T any] struct {
+	// key is unique per NewContextKey call, so keys with equal names never collide.
+	key *contextKey
+}
+
+type contextKey struct {
+	name string
+}
+
+// NewContextKey creates a key for storing values of type T on a context.
+// Each call returns a distinct key, even for the same name; the name only
+// appears in String, for debugging.
+//
+// Declare keys once at package level and share them instead of defining
+// untyped keys:
+//
+//	var experimentKey = util.NewContextKey[Experiment]("experiment")
+//
+//	ctx = experimentKey.Set(ctx, exp)
+//	exp, ok := experimentKey.Get(ctx)
+func NewContextKey[T any](name string) ContextKey[T] {
+	return ContextKey[T]{key: &contextKey{name: name}}
+}
+
+// Set returns a copy of ctx carrying value under k.
+func (k ContextKey[T]) Set(ctx context.Context, value T) context.Context {
+	return context.WithValue(ctx, k.key, value)
+}
+
+// Get returns the value stored under k on ctx. The boolean result is false,
+// and the value the zero T, when ctx carries no value for k.
+func (k ContextKey[T]) Get(ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(k.key).(T)
+	return value, ok
+}
+
+// Value returns the value stored under k on ctx, or the zero T when there is none.
+func (k ContextKey[T]) Value(ctx context.Context) T {
+	value, _ := k.Get(ctx)
+	return value
+}
+
+// String returns the name the key was created with.
+func (k ContextKey[T]) String() string {
+	if k.key == nil {
+		return ""
+	}
+	return k.key.name
+}
+
+// WithDeadlineCause is context.WithDeadlineCause, except that the cause
+// reported by context.Cause once the deadline passes also wraps
+// context.DeadlineExceeded, so errors.Is checks for a timeout keep working
+// while the cause explains which deadline was missed. A nil cause is
+// reported as context.DeadlineExceeded.
+//
+// Example:
+//
+//	ctx, cancel := WithDeadlineCause(ctx, batchEnd, errors.New("nightly batch window closed"))
+//	defer cancel()
+func WithDeadlineCause(ctx context.Context, d time.Time, cause error) (context.Context, context.CancelFunc) {
+	return context.WithDeadlineCause(ctx, d, deadlineCause(cause))
+}
+
+// WithTimeoutCause is WithDeadlineCause with a deadline timeout from now.
+func WithTimeoutCause(ctx context.Context, timeout time.Duration, cause error) (context.Context, context.CancelFunc) {
+	return context.WithTimeoutCause(ctx, timeout, deadlineCause(cause))
+}
+
+func deadlineCause(cause error) error {
+	if cause == nil || errors.Is(cause, context.DeadlineExceeded) {
+		return cause
+	}
+	return fmt.Errorf("%w: %w", cause, context.DeadlineExceeded)
+}
+
+// OnDone registers cleanup to run in its own goroutine once ctx is canceled
+// or its deadline passes, tying resource teardown to a request's lifetime.
+// A panic in cleanup is recovered and logged with the logger on ctx.
+//
+// The returned stop function unregisters cleanup, reporting false when it
+// already started, as with context.AfterFunc.
+//
+// Example:
+//
+//	stop := OnDone(ctx, func() { tempDir.Remove() })
+//	defer stop()
+func OnDone(ctx context.Context, cleanup func()) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		defer func() {
+			if r := recover(); r != nil {
+				Log(ctx).WithField("panic", r).Error("context cleanup panicked")
+			}
+		}()
+		cleanup()
+	})
+}
+
+// CloseOnDone closes closer once ctx ends, logging a close error with
+// message through CloseAndLogOnError.
+//
+// Example:
+//
+//	conn, err := pool.Acquire(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	CloseOnDone(ctx, conn, "failed to release connection")
+func CloseOnDone(ctx context.Context, closer io.Closer, message ...string) (stop func() bool) {
+	return OnDone(ctx, func() {
+		CloseAndLogOnError(ctx, closer, message...)
+	})
+}
@@ -0,0 +1,156 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ContextDescription is a snapshot of the values this package keeps on a
+// context, returned by DescribeContext for debugging lost values.
+type ContextDescription struct {
+	HasLogger   bool
+	RequestID   string
+	TenantID    string
+	PartitionID string
+	// HasTenancy is true when tenancy is set, even with empty IDs.
+	HasTenancy   bool
+	System       bool
+	Impersonated bool
+	BaggageKeys  []string
+	HasStore     bool
+	// Deadline is zero when the context has no deadline.
+	Deadline time.Time
+	// Err is the context's error once it is canceled or past its deadline.
+	Err error
+}
+
+// DescribeContext reports which of this package's values ctx carries, for
+// debugging "where did my value go" issues such as a goroutine started with
+// context.Background() instead of DetachContext.
+//
+// Example:
+//
+//	Log(ctx).Debug("handler context", "context", DescribeContext(ctx).String())
+func DescribeContext(ctx context.Context) ContextDescription {
+	_, hasLogger := ctx.Value(ctxValueLogger).(*LogEntry)
+	_, impersonated := GetImpersonation(ctx)
+	deadline, _ := ctx.Deadline()
+
+	var baggageKeys []string
+	if baggage, ok := ctx.Value(ctxValueBaggage).(map[string]string); ok {
+		baggageKeys = slices.Sorted(maps.Keys(baggage))
+	}
+
+	return ContextDescription{
+		HasLogger:    hasLogger,
+		RequestID:    GetRequestID(ctx),
+		TenantID:     GetTenantID(ctx),
+		PartitionID:  GetPartitionID(ctx),
+		HasTenancy:   GetTenancy(ctx) != nil,
+		System:       IsSystemContext(ctx),
+		Impersonated: impersonated,
+		BaggageKeys:  baggageKeys,
+		HasStore:     StoreInContext(ctx) != nil,
+		Deadline:     deadline,
+		Err:          ctx.Err(),
+	}
+}
+
+// String formats the description on one line, listing only what is set.
+func (d ContextDescription) String() string {
+	var parts []string
+	add := func(format string, args ...any) {
+		parts = append(parts, fmt.Sprintf(format, args...))
+	}
+	if d.HasLogger {
+		add("logger")
+	}
+	if d.RequestID != "" {
+		add("request_id=%s", d.RequestID)
+	}
+	if d.HasTenancy {
+		add("tenancy=%s/%s", d.TenantID, d.PartitionID)
+	}
+	if d.System {
+		add("system")
+	}
+	if d.Impersonated {
+		add("impersonated")
+	}
+	if len(d.BaggageKeys) > 0 {
+		add("baggage=%s", strings.Join(d.BaggageKeys, ","))
+	}
+	if d.HasStore {
+		add("store")
+	}
+	if !d.Deadline.IsZero() {
+		add("deadline=%s", d.Deadline.Format(time.RFC3339Nano))
+	}
+	if d.Err != nil {
+		add("err=%v", d.Err)
+	}
+	if len(parts) == 0 {
+		return "{}"
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// ContextValue names a value checked by AssertContext.
+type ContextValue string
+
+// Values AssertContext can require.
+const (
+	ContextValueLogger    ContextValue = "logger"
+	ContextValueRequestID ContextValue = "request ID"
+	ContextValueTenancy   ContextValue = "tenancy"
+	ContextValueBaggage   ContextValue = "baggage"
+	ContextValueStore     ContextValue = "store"
+	ContextValueDeadline  ContextValue = "deadline"
+)
+
+// present reports whether d records the value.
+func (v ContextValue) present(d ContextDescription) bool {
+	switch v {
+	case ContextValueLogger:
+		return d.HasLogger
+	case ContextValueRequestID:
+		return d.RequestID != ""
+	case ContextValueTenancy:
+		return d.HasTenancy
+	case ContextValueBaggage:
+		return len(d.BaggageKeys) > 0
+	case ContextValueStore:
+		return d.HasStore
+	case ContextValueDeadline:
+		return !d.Deadline.IsZero()
+	default:
+		return false
+	}
+}
+
+// AssertContext reports a test error for each of required that ctx lacks,
+// along with a description of ctx, and returns whether all were present.
+// Use it to check that middleware chains and goroutines hand the expected
+// values on.
+//
+// Example:
+//
+//	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//	    util.AssertContext(t, r.Context(), util.ContextValueLogger, util.ContextValueTenancy)
+//	})
+func AssertContext(t TestingT, ctx context.Context, required ...ContextValue) bool {
+	t.Helper()
+	description := DescribeContext(ctx)
+	ok := true
+	for _, value := range required {
+		if !value.present(description) {
+			t.Errorf("context is missing its %s; context has %s", value, description)
+			ok = false
+		}
+	}
+	return ok
+}
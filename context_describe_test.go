@@ -0,0 +1,65 @@
+package util_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+// recordingT captures AssertContext failures instead of failing the test.
+type recordingT struct {
+	*testing.T
+
+	errors []string
+}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestDescribeContext(t *testing.T) {
+	logger := util.NewLogger(t.Context())
+	defer logger.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	ctx = util.ContextWithLogger(ctx, logger)
+	ctx = util.ContextWithRequestID(ctx, "req-1")
+	ctx = util.NewTenancy().TenantID("acme").PartitionID("eu").Context(ctx)
+	ctx = util.ContextWithBaggage(ctx, map[string]string{"shard": "1", "experiment": "b"})
+
+	d := util.DescribeContext(ctx)
+	if !d.HasLogger || d.RequestID != "req-1" || !d.HasTenancy || d.TenantID != "acme" ||
+		!slices.Equal(d.BaggageKeys, []string{"experiment", "shard"}) || d.Deadline.IsZero() || d.HasStore {
+		t.Errorf("DescribeContext() = %+v", d)
+	}
+	for _, want := range []string{"logger", "request_id=req-1", "tenancy=acme/eu", "baggage=experiment,shard", "deadline="} {
+		if !strings.Contains(d.String(), want) {
+			t.Errorf("String() = %s, missing %s", d, want)
+		}
+	}
+
+	if got := util.DescribeContext(context.Background()).String(); got != "{}" {
+		t.Errorf("String() of an empty context = %s, want {}", got)
+	}
+}
+
+func TestAssertContext(t *testing.T) {
+	ctx := util.ContextWithRequestID(context.Background(), "req-1")
+
+	rec := &recordingT{T: t}
+	if !util.AssertContext(rec, ctx, util.ContextValueRequestID) || len(rec.errors) != 0 {
+		t.Errorf("AssertContext() failed for a present value: %v", rec.errors)
+	}
+	if util.AssertContext(rec, ctx, util.ContextValueTenancy, util.ContextValueLogger) {
+		t.Error("AssertContext() = true with missing values")
+	}
+	if len(rec.errors) != 2 || !strings.Contains(rec.errors[0], "tenancy") || !strings.Contains(rec.errors[0], "request_id=req-1") {
+		t.Errorf("AssertContext() errors = %q", rec.errors)
+	}
+}
@@ -0,0 +1,139 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type otherKey struct{}
+
+func TestDetachContext(t *testing.T) {
+	logger := util.NewLogger(t.Context())
+	defer logger.Release()
+
+	parent, cancel := context.WithCancel(context.Background())
+	parent = util.ContextWithLogger(parent, logger)
+	parent = util.ContextWithRequestID(parent, "req-1")
+	parent = util.NewTenancy().TenantID("acme").PartitionID("eu").Context(parent)
+	parent = util.ContextWithBaggage(parent, map[string]string{"experiment": "b"})
+	parent = context.WithValue(parent, otherKey{}, "dropped")
+
+	detached := util.DetachContext(parent)
+	cancel()
+
+	if err := detached.Err(); err != nil {
+		t.Errorf("detached context was canceled with its parent: %v", err)
+	}
+	if _, ok := detached.Deadline(); ok {
+		t.Error("detached context has a deadline")
+	}
+	if got := util.GetRequestID(detached); got != "req-1" {
+		t.Errorf("GetRequestID() = %q, want req-1", got)
+	}
+	if got := util.GetTenantID(detached); got != "acme" {
+		t.Errorf("GetTenantID() = %q, want acme", got)
+	}
+	if got := util.GetBaggageValue(detached, "experiment"); got != "b" {
+		t.Errorf("GetBaggageValue() = %q, want b", got)
+	}
+	if got := util.Log(detached); got.SLog() != logger.SLog() {
+		t.Error("detached context does not carry the request logger")
+	}
+	if detached.Value(otherKey{}) != nil {
+		t.Error("detached context kept an unrelated value")
+	}
+
+	if got := util.DetachContext(context.Background()); util.GetTenancy(got) != nil || util.GetRequestID(got) != "" {
+		t.Error("DetachContext() invented values for an empty context")
+	}
+}
+
+func TestContextKey(t *testing.T) {
+	type experiment struct{ Variant string }
+
+	key := util.NewContextKey[experiment]("experiment")
+	other := util.NewContextKey[experiment]("experiment")
+
+	ctx := key.Set(context.Background(), experiment{Variant: "b"})
+	if got, ok := key.Get(ctx); !ok || got.Variant != "b" {
+		t.Errorf("Get() = %+v, %v, want variant b", got, ok)
+	}
+	if _, ok := other.Get(ctx); ok {
+		t.Error("a key with the same name read another key's value")
+	}
+	if got := other.Value(ctx); got.Variant != "" {
+		t.Errorf("Value() = %+v, want zero value", got)
+	}
+	if key.String() != "experiment" {
+		t.Errorf("String() = %q, want experiment", key.String())
+	}
+
+	count := util.NewContextKey[int]("count")
+	ctx = count.Set(ctx, 0)
+	if got, ok := count.Get(ctx); !ok || got != 0 {
+		t.Errorf("Get() = %d, %v, want a stored zero", got, ok)
+	}
+}
+
+func TestWithDeadlineCause(t *testing.T) {
+	cause := errors.New("batch window closed")
+	ctx, cancel := util.WithDeadlineCause(context.Background(), time.Now().Add(-time.Second), cause)
+	defer cancel()
+
+	<-ctx.Done()
+	got := context.Cause(ctx)
+	if !errors.Is(got, cause) || !errors.Is(got, context.DeadlineExceeded) {
+		t.Errorf("Cause() = %v, want it to wrap the cause and DeadlineExceeded", got)
+	}
+
+	ctx, cancel = util.WithTimeoutCause(context.Background(), time.Millisecond, nil)
+	defer cancel()
+	<-ctx.Done()
+	if got = context.Cause(ctx); !errors.Is(got, context.DeadlineExceeded) {
+		t.Errorf("Cause() with nil cause = %v, want DeadlineExceeded", got)
+	}
+}
+
+type closeRecorder struct {
+	closed chan struct{}
+}
+
+func (c closeRecorder) Close() error {
+	close(c.closed)
+	return errors.New("already closed")
+}
+
+func TestOnDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{})
+	util.OnDone(ctx, func() {
+		close(ran)
+		panic("cleanup failure is logged, not fatal")
+	})
+	stopped := make(chan struct{})
+	stop := util.OnDone(ctx, func() { close(stopped) })
+	if !stop() {
+		t.Error("stop() = false before the context ended")
+	}
+
+	closer := closeRecorder{closed: make(chan struct{})}
+	util.CloseOnDone(ctx, closer, "close failed")
+
+	cancel()
+	for name, done := range map[string]chan struct{}{"cleanup": ran, "closer": closer.closed} {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Errorf("%s did not run after the context ended", name)
+		}
+	}
+	select {
+	case <-stopped:
+		t.Error("stopped cleanup ran")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
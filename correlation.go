@@ -0,0 +1,152 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Headers carrying the correlation ID between services.
+const (
+	HeaderCorrelationID = "X-Correlation-ID"
+	HeaderRequestID     = "X-Request-ID"
+	// HeaderTraceParent is the W3C Trace Context header; its trace ID is used
+	// as the correlation ID when no correlation header is present.
+	HeaderTraceParent = "Traceparent"
+)
+
+const (
+	maxCorrelationIDLength = 128
+	traceParentLength      = 55
+	traceIDStart           = 3
+	traceIDEnd             = 35
+)
+
+// traceIDExtractor holds the function installed by SetTraceIDExtractor.
+var traceIDExtractor atomic.Pointer[func(context.Context) string] //nolint:gochecknoglobals // process-wide tracing hook
+
+// SetTraceIDExtractor installs the function CorrelationID uses to read the
+// trace ID of the active span, so this package works with a tracing library
+// without depending on it. extract returns "" when ctx carries no trace.
+// Passing nil removes the extractor.
+//
+// Example, with OpenTelemetry:
+//
+//	util.SetTraceIDExtractor(func(ctx context.Context) string {
+//	    if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+//	        return sc.TraceID().String()
+//	    }
+//	    return ""
+//	})
+func SetTraceIDExtractor(extract func(context.Context) string) {
+	if extract == nil {
+		traceIDExtractor.Store(nil)
+		return
+	}
+	traceIDExtractor.Store(&extract)
+}
+
+// CorrelationID returns the token that ties together all telemetry of the
+// work ctx belongs to: the trace ID of the active span (see
+// SetTraceIDExtractor), else the request ID on ctx, else a new ID.
+//
+// A generated ID differs on every call; use ContextWithCorrelationID at the
+// start of work that may lack both a trace and a request ID.
+func CorrelationID(ctx context.Context) string {
+	if id, ok := existingCorrelationID(ctx); ok {
+		return id
+	}
+	return IDString()
+}
+
+// ContextWithCorrelationID returns ctx unchanged when it has a trace or
+// request ID, and otherwise a copy of ctx with a new request ID, so that
+// CorrelationID is stable from then on.
+func ContextWithCorrelationID(ctx context.Context) context.Context {
+	if _, ok := existingCorrelationID(ctx); ok {
+		return ctx
+	}
+	return ContextWithRequestID(ctx, IDString())
+}
+
+func existingCorrelationID(ctx context.Context) (string, bool) {
+	if extract := traceIDExtractor.Load(); extract != nil {
+		if id := (*extract)(ctx); id != "" {
+			return id, true
+		}
+	}
+	if id := GetRequestID(ctx); id != "" {
+		return id, true
+	}
+	return "", false
+}
+
+// SetCorrelationHeader stamps the correlation ID of ctx onto the headers of
+// an outgoing call, for the receiving service's WithCorrelationFromHeaders.
+//
+// Example:
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	SetCorrelationHeader(ctx, req.Header)
+func SetCorrelationHeader(ctx context.Context, h http.Header) {
+	h.Set(HeaderCorrelationID, CorrelationID(ctx))
+}
+
+// ContextWithCorrelationHeader returns a copy of ctx whose request ID is
+// taken from the first valid one of the X-Correlation-ID, X-Request-ID and
+// Traceparent headers in h, or newly generated when none is valid. A request
+// ID already on ctx is kept.
+func ContextWithCorrelationHeader(ctx context.Context, h http.Header) context.Context {
+	if GetRequestID(ctx) != "" {
+		return ctx
+	}
+	for _, id := range []string{h.Get(HeaderCorrelationID), h.Get(HeaderRequestID), traceIDFromParent(h.Get(HeaderTraceParent))} {
+		if validCorrelationID(id) {
+			return ContextWithRequestID(ctx, id)
+		}
+	}
+	return ContextWithCorrelationID(ctx)
+}
+
+// WithCorrelationFromHeaders returns middleware that gives each request a
+// correlation ID using ContextWithCorrelationHeader and echoes it in the
+// X-Correlation-ID response header.
+//
+// Example:
+//
+//	handler := WithCorrelationFromHeaders(mux)
+func WithCorrelationFromHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := ContextWithCorrelationHeader(r.Context(), r.Header)
+		w.Header().Set(HeaderCorrelationID, CorrelationID(ctx))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// traceIDFromParent returns the trace ID of a W3C traceparent header value,
+// or "" when it is malformed or the all-zero invalid trace ID.
+func traceIDFromParent(traceParent string) string {
+	if len(traceParent) < traceParentLength || traceParent[2] != '-' || traceParent[traceIDEnd] != '-' {
+		return ""
+	}
+	traceID := traceParent[traceIDStart:traceIDEnd]
+	if strings.Trim(traceID, "0123456789abcdef") != "" || strings.Trim(traceID, "0") == "" {
+		return ""
+	}
+	return traceID
+}
+
+// validCorrelationID rejects empty, oversized and non-printable IDs, so
+// incoming headers cannot inject arbitrary content into logs.
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
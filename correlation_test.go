@@ -0,0 +1,94 @@
+package util_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type traceKey struct{}
+
+func TestCorrelationID(t *testing.T) {
+	ctx := util.ContextWithRequestID(context.Background(), "req-1")
+	if got := util.CorrelationID(ctx); got != "req-1" {
+		t.Errorf("CorrelationID() = %q, want the request ID", got)
+	}
+
+	util.SetTraceIDExtractor(func(ctx context.Context) string {
+		id, _ := ctx.Value(traceKey{}).(string)
+		return id
+	})
+	t.Cleanup(func() { util.SetTraceIDExtractor(nil) })
+
+	traced := context.WithValue(ctx, traceKey{}, "4bf92f3577b34da6a3ce929d0e0e4736")
+	if got := util.CorrelationID(traced); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("CorrelationID() = %q, want the trace ID", got)
+	}
+	if got := util.CorrelationID(ctx); got != "req-1" {
+		t.Errorf("CorrelationID() without a trace = %q, want the request ID", got)
+	}
+
+	empty := context.Background()
+	if util.CorrelationID(empty) == "" {
+		t.Error("CorrelationID() did not generate an ID")
+	}
+	stable := util.ContextWithCorrelationID(empty)
+	if first := util.CorrelationID(stable); first == "" || first != util.CorrelationID(stable) {
+		t.Error("ContextWithCorrelationID() did not make the ID stable")
+	}
+	if util.ContextWithCorrelationID(ctx) != ctx {
+		t.Error("ContextWithCorrelationID() replaced an existing request ID")
+	}
+}
+
+func TestCorrelationHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"correlation header", http.Header{"X-Correlation-Id": {"corr-1"}, "X-Request-Id": {"req-1"}}, "corr-1"},
+		{"request header", http.Header{"X-Request-Id": {"req-1"}}, "req-1"},
+		{
+			"traceparent",
+			http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			"4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{"invalid correlation header", http.Header{"X-Correlation-Id": {"bad\nid"}, "X-Request-Id": {"req-1"}}, "req-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := util.WithCorrelationFromHeaders(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = util.CorrelationID(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header = tt.header
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got != tt.want {
+				t.Errorf("CorrelationID() = %q, want %q", got, tt.want)
+			}
+			if echoed := rec.Header().Get(util.HeaderCorrelationID); echoed != tt.want {
+				t.Errorf("response header = %q, want %q", echoed, tt.want)
+			}
+		})
+	}
+
+	ctx := util.ContextWithCorrelationHeader(context.Background(), http.Header{
+		"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+	})
+	if id := util.GetRequestID(ctx); id == "" || id == "00000000000000000000000000000000" {
+		t.Errorf("invalid trace ID was used as the correlation ID: %q", id)
+	}
+
+	outgoing := http.Header{}
+	util.SetCorrelationHeader(util.ContextWithRequestID(context.Background(), "req-9"), outgoing)
+	if got := outgoing.Get(util.HeaderCorrelationID); got != "req-9" {
+		t.Errorf("SetCorrelationHeader() wrote %q, want req-9", got)
+	}
+}
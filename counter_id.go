@@ -0,0 +1,78 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultCounterIDWidth = 6
+
+// counterIDOptions contains configuration for NewCounterID.
+type counterIDOptions struct {
+	// width is the minimum number of digits, padded with zeros
+	width int
+
+	// start is the number of the first ID
+	start uint64
+}
+
+// CounterIDOption is a function that configures NewCounterID.
+type CounterIDOption func(*counterIDOptions)
+
+// WithCounterIDWidth sets the minimum number of digits; shorter numbers are
+// padded with zeros. The default is 6. Numbers that outgrow the width are
+// written in full, so IDs stay unique but no longer sort as strings.
+func WithCounterIDWidth(width int) CounterIDOption {
+	return func(o *counterIDOptions) {
+		o.width = width
+	}
+}
+
+// WithCounterIDStart sets the number of the first ID. The default is 1.
+func WithCounterIDStart(start uint64) CounterIDOption {
+	return func(o *counterIDOptions) {
+		o.start = start
+	}
+}
+
+// CounterID generates sequential, human-readable IDs such as "job-000123"
+// for numbering jobs in logs and CLIs. IDs are unique within a CounterID
+// only; use IDString for IDs unique across processes. A CounterID is safe
+// for concurrent use.
+type CounterID struct {
+	prefix string
+	width  int
+	next   atomic.Uint64
+}
+
+// NewCounterID creates a CounterID whose IDs are prefix, a dash and the
+// zero-padded number, or just the number when prefix is empty.
+//
+// Example:
+//
+//	jobs := NewCounterID("job")
+//	jobs.Next() // "job-000001"
+//	jobs.Next() // "job-000002"
+func NewCounterID(prefix string, opts ...CounterIDOption) *CounterID {
+	options := counterIDOptions{width: defaultCounterIDWidth, start: 1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	c := &CounterID{width: options.width}
+	if prefix != "" {
+		c.prefix = prefix + "-"
+	}
+	c.next.Store(options.start)
+	return c
+}
+
+// Next returns the next ID.
+func (c *CounterID) Next() string {
+	digits := strconv.FormatUint(c.next.Add(1)-1, 10)
+	if pad := c.width - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	return c.prefix + digits
+}
@@ -0,0 +1,51 @@
+package util_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestCounterID(t *testing.T) {
+	jobs := util.NewCounterID("job")
+	if got := jobs.Next(); got != "job-000001" {
+		t.Errorf("Next() = %q, want job-000001", got)
+	}
+	if got := jobs.Next(); got != "job-000002" {
+		t.Errorf("Next() = %q, want job-000002", got)
+	}
+
+	custom := util.NewCounterID("", util.WithCounterIDWidth(2), util.WithCounterIDStart(99))
+	for _, want := range []string{"99", "100"} {
+		if got := custom.Next(); got != want {
+			t.Errorf("Next() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestCounterIDConcurrent(t *testing.T) {
+	ids := util.NewCounterID("task")
+	var mu sync.Mutex
+	seen := map[string]bool{}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				id := ids.Next()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(seen) != 800 {
+		t.Errorf("concurrent Next() produced %d distinct IDs, want 800", len(seen))
+	}
+	if got := ids.Next(); got != "task-000801" {
+		t.Errorf("Next() after 800 IDs = %q, want task-000801", got)
+	}
+}
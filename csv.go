@@ -0,0 +1,259 @@
+package util
+
+import (
+	"context"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineError reports a record of a CSV or NDJSON stream that could not be
+// decoded. Reading goes on with the next record, so an import can skip or
+// report bad lines instead of failing as a whole.
+type LineError struct {
+	// Line is the 1-based line number the record starts on.
+	Line int
+
+	// Err is the decoding error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the decoding error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// csvField is a struct field mapped to a CSV column.
+type csvField struct {
+	name  string
+	index []int
+}
+
+// csvFields returns the columns of struct type t: its exported fields, named
+// by their csv tag or else the field name, except those tagged "-".
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("CSV records must be structs, not %s", t)
+	}
+	var fields []csvField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag, _, _ = strings.Cut(tag, ","); tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, csvField{name: name, index: f.Index})
+	}
+	return fields, nil
+}
+
+// StreamCSV writes rows to w as CSV, with a header row of the column names
+// of T, a struct type whose exported fields are the columns, named by their
+// `csv:"name"` tag or else the field name; fields tagged "-" are left out.
+// Values are formatted with their MarshalText method when they have one,
+// slices as comma-separated lists, and nil pointers as empty cells. It stops
+// with ctx's error when ctx is done, for exports streamed to a client that
+// went away.
+//
+// Example:
+//
+//	type OrderRow struct {
+//	    ID     string    `csv:"id"`
+//	    Total  float64   `csv:"total"`
+//	    Placed time.Time `csv:"placed_at"`
+//	}
+//
+//	w.Header().Set("Content-Type", "text/csv")
+//	err := StreamCSV(ctx, w, orderRows(ctx))
+func StreamCSV[T any](ctx context.Context, w io.Writer, rows iter.Seq[T]) error {
+	fields, err := csvFields(reflect.TypeFor[T]())
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	record := make([]string, len(fields))
+	for i, f := range fields {
+		record[i] = f.name
+	}
+	if err = cw.Write(record); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for row := range rows {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		v := reflect.ValueOf(row)
+		for i, f := range fields {
+			if record[i], err = formatCSVValue(v.FieldByIndex(f.index)); err != nil {
+				return fmt.Errorf("failed to format CSV column %s: %w", f.name, err)
+			}
+		}
+		if err = cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// ReadCSV returns an iterator over the records of the CSV in r decoded into
+// T, mapping columns to fields by the header row as StreamCSV writes it.
+// Unknown columns are ignored and empty cells leave fields at their zero
+// value. Cells are parsed like LoadEnv parses environment variables.
+//
+// A record that cannot be decoded is yielded with a *LineError, and reading
+// goes on. Reading stops after yielding ctx's error when ctx is done, or a
+// read error.
+//
+// Example:
+//
+//	for row, err := range ReadCSV[OrderRow](ctx, req.Body) {
+//	    if lineErr, ok := errors.AsType[*LineError](err); ok {
+//	        rejected = append(rejected, lineErr)
+//	        continue
+//	    } else if err != nil {
+//	        return err
+//	    }
+//	    imported = append(imported, row)
+//	}
+func ReadCSV[T any](ctx context.Context, r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		fields, err := csvFields(reflect.TypeFor[T]())
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			yield(zero, fmt.Errorf("failed to read CSV header: %w", err))
+			return
+		}
+		columns := make([][]int, len(header))
+		for i, name := range header {
+			for _, f := range fields {
+				if f.name == strings.TrimSpace(name) {
+					columns[i] = f.index
+				}
+			}
+		}
+
+		for {
+			if err = ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			record, readErr := cr.Read()
+			if errors.Is(readErr, io.EOF) {
+				return
+			}
+			var parseErr *csv.ParseError
+			if errors.As(readErr, &parseErr) {
+				if !yield(zero, &LineError{Line: parseErr.StartLine, Err: parseErr.Err}) {
+					return
+				}
+				continue
+			}
+			if readErr != nil {
+				yield(zero, fmt.Errorf("failed to read CSV: %w", readErr))
+				return
+			}
+
+			line, _ := cr.FieldPos(0)
+			row, decodeErr := decodeCSVRecord[T](record, header, columns)
+			if decodeErr != nil {
+				if !yield(zero, &LineError{Line: line, Err: decodeErr}) {
+					return
+				}
+				continue
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+	}
+}
+
+func decodeCSVRecord[T any](record, header []string, columns [][]int) (T, error) {
+	var row T
+	v := reflect.ValueOf(&row).Elem()
+	for i, cell := range record {
+		if i >= len(columns) || columns[i] == nil || cell == "" {
+			continue
+		}
+		if err := setEnvValue(v.FieldByIndex(columns[i]), cell); err != nil {
+			return row, fmt.Errorf("column %s: %w", header[i], err)
+		}
+	}
+	return row, nil
+}
+
+func formatCSVValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() { //nolint:exhaustive // other kinds are formatted with fmt
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeFor[time.Duration]() {
+			return time.Duration(v.Int()).String(), nil
+		}
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range v.Len() {
+			part, err := formatCSVValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return fmt.Sprint(v.Interface()), nil
+	}
+}
@@ -0,0 +1,96 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type csvOrder struct {
+	ID       string        `csv:"id"`
+	Total    float64       `csv:"total"`
+	Paid     bool          `csv:"paid"`
+	Placed   time.Time     `csv:"placed_at"`
+	Tags     []string      `csv:"tags"`
+	Discount *int          `csv:"discount"`
+	Window   time.Duration `csv:"window"`
+	Note     string        `csv:"-"`
+	Quantity int
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	placed := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	orders := []csvOrder{
+		{ID: "o1", Total: 12.5, Paid: true, Placed: placed, Tags: []string{"gift", "rush"}, Discount: util.Ptr(5), Window: 90 * time.Minute, Note: "x", Quantity: 2},
+		{ID: "o,2", Total: 3, Placed: placed},
+	}
+
+	var b strings.Builder
+	if err := util.StreamCSV(t.Context(), &b, slices.Values(orders)); err != nil {
+		t.Fatalf("StreamCSV: %v", err)
+	}
+	want := "id,total,paid,placed_at,tags,discount,window,Quantity\n" +
+		"o1,12.5,true,2024-03-05T14:30:00Z,\"gift,rush\",5,1h30m0s,2\n" +
+		"\"o,2\",3,false,2024-03-05T14:30:00Z,,,0s,0\n"
+	if b.String() != want {
+		t.Fatalf("StreamCSV wrote\n%s\nwant\n%s", b.String(), want)
+	}
+
+	var got []csvOrder
+	for row, err := range util.ReadCSV[csvOrder](t.Context(), strings.NewReader(b.String())) {
+		if err != nil {
+			t.Fatalf("ReadCSV: %v", err)
+		}
+		got = append(got, row)
+	}
+	orders[0].Note = ""
+	if len(got) != 2 || got[0].ID != "o1" || *got[0].Discount != 5 || !slices.Equal(got[0].Tags, orders[0].Tags) ||
+		!got[0].Placed.Equal(placed) || got[0].Window != orders[0].Window || got[0].Quantity != 2 ||
+		got[1].ID != "o,2" || got[1].Discount != nil {
+		t.Fatalf("ReadCSV = %+v", got)
+	}
+}
+
+func TestReadCSVLineErrors(t *testing.T) {
+	input := "total,id,extra\n1,a,x\nnope,b,y\n3,\"c\n"
+	var ids []string
+	var lineErrs []*util.LineError
+	for row, err := range util.ReadCSV[csvOrder](t.Context(), strings.NewReader(input)) {
+		if lineErr, ok := errors.AsType[*util.LineError](err); ok {
+			lineErrs = append(lineErrs, lineErr)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ReadCSV: %v", err)
+		}
+		ids = append(ids, row.ID)
+	}
+	if !slices.Equal(ids, []string{"a"}) {
+		t.Fatalf("decoded IDs %v, want [a]", ids)
+	}
+	if len(lineErrs) != 2 || lineErrs[0].Line != 3 || !strings.Contains(lineErrs[0].Error(), "column total") {
+		t.Fatalf("line errors = %v", lineErrs)
+	}
+}
+
+func TestCSVContextAndType(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	var b strings.Builder
+	if err := util.StreamCSV(ctx, &b, slices.Values([]csvOrder{{}})); !errors.Is(err, context.Canceled) {
+		t.Fatalf("StreamCSV = %v, want Canceled", err)
+	}
+	for _, err := range util.ReadCSV[csvOrder](ctx, strings.NewReader("id\na\n")) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("ReadCSV = %v, want Canceled", err)
+		}
+	}
+	if err := util.StreamCSV(t.Context(), &b, slices.Values([]int{1})); err == nil {
+		t.Fatal("StreamCSV of a non-struct type should fail")
+	}
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// CoalescedFunc wraps a function so that bursts of calls run it fewer
+// times. Create one with Debounce or Throttle. The function never runs
+// concurrently with itself, and a CoalescedFunc is safe for concurrent use.
+type CoalescedFunc struct {
+	fn       func()
+	interval time.Duration
+	throttle bool
+
+	// run serializes calls of fn
+	run sync.Mutex
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending bool
+	stopped bool
+	// gen counts armed timers, so a timer that fired while being replaced
+	// does nothing
+	gen uint64
+}
+
+// Debounce returns a CoalescedFunc running fn once calls have stopped for
+// wait, for work that only needs the final state, such as reloading
+// configuration after a burst of file change events.
+//
+// Example:
+//
+//	reload := Debounce(500*time.Millisecond, func() { settings.Reload() })
+//	defer reload.Stop()
+//	for range watcher.Events {
+//	    reload.Call()
+//	}
+func Debounce(wait time.Duration, fn func()) *CoalescedFunc {
+	return &CoalescedFunc{fn: fn, interval: wait}
+}
+
+// Throttle returns a CoalescedFunc running fn at most once per interval:
+// the first call runs it at once, and calls during the interval run it once
+// more when the interval ends, for work such as notifications that must not
+// be sent more often than a rate.
+//
+// Example:
+//
+//	invalidate := Throttle(time.Second, func() { cache.Delete(key) })
+func Throttle(interval time.Duration, fn func()) *CoalescedFunc {
+	return &CoalescedFunc{fn: fn, interval: interval, throttle: true}
+}
+
+// Call schedules fn according to the debounce or throttle policy. Calls
+// after Stop are ignored.
+func (f *CoalescedFunc) Call() {
+	f.mu.Lock()
+	if f.stopped {
+		f.mu.Unlock()
+		return
+	}
+	if f.throttle && f.timer == nil {
+		f.armLocked()
+		f.mu.Unlock()
+		f.invoke()
+		return
+	}
+	f.pending = true
+	if !f.throttle {
+		f.armLocked()
+	}
+	f.mu.Unlock()
+}
+
+// Flush runs fn now if a call is pending, instead of when it was due.
+func (f *CoalescedFunc) Flush() {
+	f.mu.Lock()
+	if !f.pending {
+		f.mu.Unlock()
+		return
+	}
+	f.pending = false
+	f.disarmLocked()
+	if f.throttle {
+		f.armLocked()
+	}
+	f.mu.Unlock()
+	f.invoke()
+}
+
+// Stop drops a pending call and makes later calls do nothing. It does not
+// wait for a run of fn in progress.
+func (f *CoalescedFunc) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	f.pending = false
+	f.disarmLocked()
+}
+
+// armLocked starts a new interval, replacing the current one.
+func (f *CoalescedFunc) armLocked() {
+	f.disarmLocked()
+	gen := f.gen
+	f.timer = time.AfterFunc(f.interval, func() { f.fire(gen) })
+}
+
+func (f *CoalescedFunc) disarmLocked() {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	f.gen++
+}
+
+func (f *CoalescedFunc) fire(gen uint64) {
+	f.mu.Lock()
+	if gen != f.gen {
+		f.mu.Unlock()
+		return
+	}
+	f.timer = nil
+	if !f.pending {
+		f.mu.Unlock()
+		return
+	}
+	f.pending = false
+	if f.throttle {
+		// The trailing run starts the next interval.
+		f.armLocked()
+	}
+	f.mu.Unlock()
+	f.invoke()
+}
+
+func (f *CoalescedFunc) invoke() {
+	f.run.Lock()
+	defer f.run.Unlock()
+	f.fn()
+}
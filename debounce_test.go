@@ -0,0 +1,83 @@
+package util_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	var runs atomic.Int32
+	debounced := util.Debounce(20*time.Millisecond, func() { runs.Add(1) })
+	defer debounced.Stop()
+
+	for range 5 {
+		debounced.Call()
+	}
+	if runs.Load() != 0 {
+		t.Error("Debounce ran fn before the calls stopped")
+	}
+	waitFor(t, func() bool { return runs.Load() == 1 })
+	time.Sleep(40 * time.Millisecond)
+	if n := runs.Load(); n != 1 {
+		t.Errorf("fn ran %d times for one burst, want 1", n)
+	}
+
+	debounced.Call()
+	debounced.Flush()
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Flush did not run the pending call: %d runs", n)
+	}
+	debounced.Flush()
+	time.Sleep(40 * time.Millisecond)
+	if n := runs.Load(); n != 2 {
+		t.Errorf("flushed call ran again: %d runs", n)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	var runs atomic.Int32
+	throttled := util.Throttle(30*time.Millisecond, func() { runs.Add(1) })
+	defer throttled.Stop()
+
+	throttled.Call()
+	if runs.Load() != 1 {
+		t.Fatal("Throttle did not run the first call at once")
+	}
+	throttled.Call()
+	throttled.Call()
+	if runs.Load() != 1 {
+		t.Error("Throttle ran a call inside the interval")
+	}
+	waitFor(t, func() bool { return runs.Load() == 2 })
+	time.Sleep(70 * time.Millisecond)
+	if n := runs.Load(); n != 2 {
+		t.Errorf("fn ran %d times, want the first and one trailing run", n)
+	}
+}
+
+func TestCoalescedFuncStop(t *testing.T) {
+	var runs atomic.Int32
+	debounced := util.Debounce(10*time.Millisecond, func() { runs.Add(1) })
+	debounced.Call()
+	debounced.Stop()
+	debounced.Call()
+	time.Sleep(30 * time.Millisecond)
+	if n := runs.Load(); n != 0 {
+		t.Errorf("fn ran %d times after Stop", n)
+	}
+}
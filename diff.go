@@ -0,0 +1,143 @@
+package util
+
+// DiffChange is an element present on both sides of a diff with different
+// values.
+type DiffChange[T any] struct {
+	Old T
+	New T
+}
+
+// SliceDiff is the result of DiffSlices.
+type SliceDiff[T any] struct {
+	// Added holds the elements of the new slice whose key is not in the old
+	// one, in the order of the new slice.
+	Added []T
+
+	// Removed holds the elements of the old slice whose key is not in the
+	// new one, in the order of the old slice.
+	Removed []T
+
+	// Changed holds the elements whose key is in both slices with different
+	// values, in the order of the new slice.
+	Changed []DiffChange[T]
+}
+
+// Empty reports whether the diff holds no differences.
+func (d SliceDiff[T]) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSlices compares two versions of a collection whose elements are
+// identified by key, for reconciliation loops that apply the minimal set of
+// creates, deletes and updates. Keys are expected to be unique within each
+// slice; when they are not, the last element with a key is used.
+//
+// Example:
+//
+//	diff := DiffSlices(current, desired, func(r Route) string { return r.Name })
+//	for _, r := range diff.Added {
+//	    create(r)
+//	}
+//	for _, c := range diff.Changed {
+//	    update(c.Old, c.New)
+//	}
+func DiffSlices[T comparable, K comparable](oldValues, newValues []T, key func(T) K) SliceDiff[T] {
+	return DiffSlicesFunc(oldValues, newValues, key, func(a, b T) bool { return a == b })
+}
+
+// DiffSlicesFunc is DiffSlices for elements compared with equal, such as
+// structs holding slices or maps.
+//
+// Example:
+//
+//	diff := DiffSlicesFunc(current, desired, Policy.ID, func(a, b Policy) bool {
+//	    return reflect.DeepEqual(a, b)
+//	})
+func DiffSlicesFunc[T any, K comparable](oldValues, newValues []T, key func(T) K, equal func(a, b T) bool) SliceDiff[T] {
+	oldByKey := make(map[K]T, len(oldValues))
+	for _, v := range oldValues {
+		oldByKey[key(v)] = v
+	}
+	newByKey := make(map[K]T, len(newValues))
+	for _, v := range newValues {
+		newByKey[key(v)] = v
+	}
+
+	var diff SliceDiff[T]
+	handled := make(map[K]struct{}, len(newValues))
+	for _, v := range newValues {
+		k := key(v)
+		if _, ok := handled[k]; ok {
+			continue
+		}
+		handled[k] = struct{}{}
+		latest := newByKey[k]
+		prev, ok := oldByKey[k]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, latest)
+		case !equal(prev, latest):
+			diff.Changed = append(diff.Changed, DiffChange[T]{Old: prev, New: latest})
+		}
+	}
+	for _, v := range oldValues {
+		k := key(v)
+		if _, ok := handled[k]; ok {
+			continue
+		}
+		handled[k] = struct{}{}
+		diff.Removed = append(diff.Removed, oldByKey[k])
+	}
+	return diff
+}
+
+// MapDiff is the result of DiffMaps.
+type MapDiff[K comparable, V any] struct {
+	// Added holds the entries of the new map whose key is not in the old one.
+	Added map[K]V
+
+	// Removed holds the entries of the old map whose key is not in the new
+	// one.
+	Removed map[K]V
+
+	// Changed holds the keys in both maps with different values.
+	Changed map[K]DiffChange[V]
+}
+
+// Empty reports whether the diff holds no differences.
+func (d MapDiff[K, V]) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffMaps compares two versions of a map, such as the labels or
+// configuration of a resource, for reconciliation loops that apply only what
+// changed. The maps of the result are never nil.
+//
+// Example:
+//
+//	diff := DiffMaps(current.Labels, desired.Labels)
+//	for k := range diff.Removed {
+//	    removeLabel(k)
+//	}
+func DiffMaps[K, V comparable](oldMap, newMap map[K]V) MapDiff[K, V] {
+	diff := MapDiff[K, V]{
+		Added:   make(map[K]V),
+		Removed: make(map[K]V),
+		Changed: make(map[K]DiffChange[V]),
+	}
+	for k, v := range newMap {
+		prev, ok := oldMap[k]
+		switch {
+		case !ok:
+			diff.Added[k] = v
+		case prev != v:
+			diff.Changed[k] = DiffChange[V]{Old: prev, New: v}
+		}
+	}
+	for k, v := range oldMap {
+		if _, ok := newMap[k]; !ok {
+			diff.Removed[k] = v
+		}
+	}
+	return diff
+}
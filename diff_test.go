@@ -0,0 +1,89 @@
+package util_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type route struct {
+	Name   string
+	Target string
+}
+
+func routeName(r route) string { return r.Name }
+
+func TestDiffSlices(t *testing.T) {
+	current := []route{{"a", "1"}, {"b", "2"}, {"c", "3"}}
+	desired := []route{{"d", "4"}, {"c", "3"}, {"a", "9"}}
+
+	diff := util.DiffSlices(current, desired, routeName)
+	if !slices.Equal(diff.Added, []route{{"d", "4"}}) {
+		t.Errorf("Added = %v", diff.Added)
+	}
+	if !slices.Equal(diff.Removed, []route{{"b", "2"}}) {
+		t.Errorf("Removed = %v", diff.Removed)
+	}
+	want := []util.DiffChange[route]{{Old: route{"a", "1"}, New: route{"a", "9"}}}
+	if !slices.Equal(diff.Changed, want) {
+		t.Errorf("Changed = %v", diff.Changed)
+	}
+	if diff.Empty() {
+		t.Error("Empty reported no differences")
+	}
+
+	if diff := util.DiffSlices(current, slices.Clone(current), routeName); !diff.Empty() {
+		t.Errorf("diff of equal slices = %+v", diff)
+	}
+}
+
+func TestDiffSlicesDuplicateKeys(t *testing.T) {
+	current := []route{{"a", "1"}, {"a", "2"}, {"b", "1"}, {"b", "1"}}
+	desired := []route{{"a", "1"}, {"a", "2"}}
+
+	diff := util.DiffSlices(current, desired, routeName)
+	if len(diff.Added) != 0 || len(diff.Changed) != 0 {
+		t.Errorf("last element per key should match: %+v", diff)
+	}
+	if !slices.Equal(diff.Removed, []route{{"b", "1"}}) {
+		t.Errorf("Removed = %v, want b once", diff.Removed)
+	}
+}
+
+func TestDiffSlicesFunc(t *testing.T) {
+	type policy struct {
+		ID    int
+		Rules []string
+	}
+	current := []policy{{1, []string{"x"}}}
+	desired := []policy{{1, []string{"x", "y"}}}
+
+	diff := util.DiffSlicesFunc(current, desired, func(p policy) int { return p.ID }, func(a, b policy) bool {
+		return slices.Equal(a.Rules, b.Rules)
+	})
+	if len(diff.Changed) != 1 || len(diff.Changed[0].New.Rules) != 2 {
+		t.Errorf("Changed = %+v", diff.Changed)
+	}
+}
+
+func TestDiffMaps(t *testing.T) {
+	current := map[string]string{"env": "prod", "team": "core", "old": "x"}
+	desired := map[string]string{"env": "prod", "team": "edge", "new": "y"}
+
+	diff := util.DiffMaps(current, desired)
+	if !maps.Equal(diff.Added, map[string]string{"new": "y"}) {
+		t.Errorf("Added = %v", diff.Added)
+	}
+	if !maps.Equal(diff.Removed, map[string]string{"old": "x"}) {
+		t.Errorf("Removed = %v", diff.Removed)
+	}
+	if !maps.Equal(diff.Changed, map[string]util.DiffChange[string]{"team": {Old: "core", New: "edge"}}) {
+		t.Errorf("Changed = %v", diff.Changed)
+	}
+
+	if diff := util.DiffMaps(nil, map[string]string{}); !diff.Empty() || diff.Added == nil {
+		t.Errorf("diff of empty maps = %+v", diff)
+	}
+}
@@ -0,0 +1,273 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDNSTTL         = 5 * time.Minute
+	defaultDNSNegativeTTL = 30 * time.Second
+	defaultDNSTimeout     = 5 * time.Second
+	defaultDNSCacheSize   = 10000
+)
+
+// DNSResolver performs the lookups cached by DNSCache. *net.Resolver
+// implements it.
+type DNSResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// dnsCacheOptions contains configuration for NewDNSCache.
+type dnsCacheOptions struct {
+	// ttl is how long successful answers are cached
+	ttl time.Duration
+
+	// negativeTTL is how long failures are cached
+	negativeTTL time.Duration
+
+	// timeout bounds each lookup
+	timeout time.Duration
+
+	// maxEntries bounds the number of cached answers
+	maxEntries int
+
+	// resolver performs the lookups
+	resolver DNSResolver
+}
+
+// DNSCacheOption is a function that configures NewDNSCache.
+type DNSCacheOption func(*dnsCacheOptions)
+
+// WithDNSCacheTTL sets how long successful answers are cached. The default
+// is 5 minutes. The system resolver does not report record TTLs, so every
+// entry uses this one.
+func WithDNSCacheTTL(ttl time.Duration) DNSCacheOption {
+	return func(o *dnsCacheOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithDNSNegativeTTL sets how long failed lookups are cached, so names that
+// do not resolve are not retried on every request. The default is 30 seconds.
+func WithDNSNegativeTTL(ttl time.Duration) DNSCacheOption {
+	return func(o *dnsCacheOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithDNSTimeout bounds each lookup. The default is 5 seconds.
+func WithDNSTimeout(timeout time.Duration) DNSCacheOption {
+	return func(o *dnsCacheOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithDNSCacheSize bounds the number of cached answers. The default is 10000.
+func WithDNSCacheSize(maxEntries int) DNSCacheOption {
+	return func(o *dnsCacheOptions) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithDNSResolver sets the resolver performing the lookups. The default is
+// net.DefaultResolver.
+func WithDNSResolver(resolver DNSResolver) DNSCacheOption {
+	return func(o *dnsCacheOptions) {
+		o.resolver = resolver
+	}
+}
+
+// DNSCache caches forward and reverse DNS lookups, so access logging and
+// allowlist checks do not query the resolver on every request. Concurrent
+// lookups of the same name share one query. Create one with NewDNSCache; a
+// DNSCache is safe for concurrent use.
+type DNSCache struct {
+	options dnsCacheOptions
+
+	mu       sync.Mutex
+	entries  map[string]dnsEntry
+	inflight map[string]*dnsCall
+}
+
+type dnsEntry struct {
+	values  []string
+	err     error
+	expires time.Time
+}
+
+// dnsCall is a lookup in progress that concurrent callers wait on.
+type dnsCall struct {
+	done   chan struct{}
+	values []string
+	err    error
+}
+
+// NewDNSCache creates an empty DNSCache.
+//
+// Example:
+//
+//	cache := NewDNSCache(WithDNSCacheTTL(time.Minute))
+//	names, err := cache.LookupPTR(ctx, addr)
+func NewDNSCache(opts ...DNSCacheOption) *DNSCache {
+	options := dnsCacheOptions{
+		ttl:         defaultDNSTTL,
+		negativeTTL: defaultDNSNegativeTTL,
+		timeout:     defaultDNSTimeout,
+		maxEntries:  defaultDNSCacheSize,
+		resolver:    net.DefaultResolver,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &DNSCache{
+		options:  options,
+		entries:  map[string]dnsEntry{},
+		inflight: map[string]*dnsCall{},
+	}
+}
+
+//nolint:gochecknoglobals // shared cache behind LookupPTR and ResolveHost
+var defaultDNSCache = NewDNSCache()
+
+// LookupPTR returns the host names of ip from reverse DNS, using a shared
+// DNSCache with the default settings.
+//
+// Example:
+//
+//	names, err := LookupPTR(ctx, addr)
+func LookupPTR(ctx context.Context, ip netip.Addr) ([]string, error) {
+	return defaultDNSCache.LookupPTR(ctx, ip)
+}
+
+// ResolveHost returns the addresses of name, using a shared DNSCache with
+// the default settings.
+func ResolveHost(ctx context.Context, name string) ([]netip.Addr, error) {
+	return defaultDNSCache.ResolveHost(ctx, name)
+}
+
+// LookupPTR returns the host names of ip from reverse DNS, without the
+// trailing dot.
+func (c *DNSCache) LookupPTR(ctx context.Context, ip netip.Addr) ([]string, error) {
+	if !ip.IsValid() {
+		return nil, fmt.Errorf("invalid IP address %v", ip)
+	}
+	addr := ip.Unmap().String()
+	names, err := c.lookup(ctx, "ptr:"+addr, func(ctx context.Context) ([]string, error) {
+		return c.options.resolver.LookupAddr(ctx, addr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = strings.TrimSuffix(name, ".")
+	}
+	return out, nil
+}
+
+// ResolveHost returns the IPv4 and IPv6 addresses of name.
+func (c *DNSCache) ResolveHost(ctx context.Context, name string) ([]netip.Addr, error) {
+	name = strings.ToLower(name)
+	values, err := c.lookup(ctx, "host:"+name, func(ctx context.Context) ([]string, error) {
+		addrs, err := c.options.resolver.LookupNetIP(ctx, "ip", name)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]string, len(addrs))
+		for i, addr := range addrs {
+			out[i] = addr.Unmap().String()
+		}
+		return out, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]netip.Addr, 0, len(values))
+	for _, value := range values {
+		if addr, parseErr := netip.ParseAddr(value); parseErr == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// lookup returns the cached answer for key or runs fetch, sharing one fetch
+// among concurrent callers. The fetch outlives a caller that gives up, so
+// its answer is still cached for the others.
+func (c *DNSCache) lookup(
+	ctx context.Context,
+	key string,
+	fetch func(context.Context) ([]string, error),
+) ([]string, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return slices.Clone(entry.values), entry.err
+	}
+	call, ok := c.inflight[key]
+	if !ok {
+		call = &dnsCall{done: make(chan struct{})}
+		c.inflight[key] = call
+		go c.fetch(context.WithoutCancel(ctx), key, call, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return slices.Clone(call.values), call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *DNSCache) fetch(ctx context.Context, key string, call *dnsCall, fetch func(context.Context) ([]string, error)) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.timeout)
+	defer cancel()
+	call.values, call.err = fetch(ctx)
+	if call.err != nil {
+		call.err = fmt.Errorf("DNS lookup %s: %w", key, call.err)
+	}
+
+	ttl := c.options.ttl
+	if call.err != nil {
+		ttl = c.options.negativeTTL
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if ttl > 0 && c.options.maxEntries > 0 {
+		c.evictLocked()
+		c.entries[key] = dnsEntry{values: call.values, err: call.err, expires: time.Now().Add(ttl)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// evictLocked makes room for one entry: it drops expired entries and, when
+// the cache is still full, an arbitrary one.
+func (c *DNSCache) evictLocked() {
+	if len(c.entries) < c.options.maxEntries {
+		return
+	}
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.options.maxEntries {
+			break
+		}
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,109 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+// fakeResolver answers from fixed tables and counts the queries it receives.
+type fakeResolver struct {
+	queries atomic.Int32
+	delay   time.Duration
+	names   map[string][]string
+	addrs   map[string][]netip.Addr
+}
+
+func (f *fakeResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	f.queries.Add(1)
+	time.Sleep(f.delay)
+	if names, ok := f.names[addr]; ok {
+		return names, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (f *fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	f.queries.Add(1)
+	time.Sleep(f.delay)
+	if addrs, ok := f.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestDNSCache(t *testing.T) {
+	resolver := &fakeResolver{
+		names: map[string][]string{"192.0.2.1": {"host.example.com."}},
+		addrs: map[string][]netip.Addr{"example.com": {netip.MustParseAddr("192.0.2.1"), netip.MustParseAddr("2001:db8::1")}},
+	}
+	cache := util.NewDNSCache(util.WithDNSResolver(resolver))
+
+	for range 3 {
+		names, err := cache.LookupPTR(t.Context(), netip.MustParseAddr("192.0.2.1"))
+		if err != nil || len(names) != 1 || names[0] != "host.example.com" {
+			t.Fatalf("LookupPTR() = %v, %v; want [host.example.com]", names, err)
+		}
+		addrs, err := cache.ResolveHost(t.Context(), "Example.com")
+		if err != nil || len(addrs) != 2 {
+			t.Fatalf("ResolveHost() = %v, %v; want two addresses", addrs, err)
+		}
+	}
+	if n := resolver.queries.Load(); n != 2 {
+		t.Errorf("resolver queried %d times, want 2 with caching", n)
+	}
+
+	for range 2 {
+		if _, err := cache.ResolveHost(t.Context(), "missing.example.com"); err == nil {
+			t.Fatal("ResolveHost() of a missing name succeeded")
+		}
+	}
+	if n := resolver.queries.Load(); n != 3 {
+		t.Errorf("resolver queried %d times, want failures cached too", n)
+	}
+}
+
+func TestDNSCacheExpiry(t *testing.T) {
+	resolver := &fakeResolver{addrs: map[string][]netip.Addr{"example.com": {netip.MustParseAddr("192.0.2.1")}}}
+	cache := util.NewDNSCache(util.WithDNSResolver(resolver), util.WithDNSCacheTTL(10*time.Millisecond))
+
+	_, _ = cache.ResolveHost(t.Context(), "example.com")
+	time.Sleep(20 * time.Millisecond)
+	_, _ = cache.ResolveHost(t.Context(), "example.com")
+	if n := resolver.queries.Load(); n != 2 {
+		t.Errorf("resolver queried %d times, want 2 after the entry expired", n)
+	}
+}
+
+func TestDNSCacheDeduplicates(t *testing.T) {
+	resolver := &fakeResolver{
+		delay: 50 * time.Millisecond,
+		addrs: map[string][]netip.Addr{"example.com": {netip.MustParseAddr("192.0.2.1")}},
+	}
+	cache := util.NewDNSCache(util.WithDNSResolver(resolver))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if _, err := cache.ResolveHost(t.Context(), "example.com"); err != nil {
+				t.Errorf("ResolveHost() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := resolver.queries.Load(); n != 1 {
+		t.Errorf("resolver queried %d times by concurrent callers, want 1", n)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+	defer cancel()
+	if _, err := cache.ResolveHost(ctx, "slow.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ResolveHost() with an expiring context error = %v, want DeadlineExceeded", err)
+	}
+}
@@ -1,18 +1,13 @@
 package util
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/hmac"
-	"crypto/rand"
-	"crypto/sha256"
 	"errors"
-	"fmt"
 )
 
 // ComputeLookupToken generates a cryptographically secure lookup token from input data.
 //
-// The token is computed using HMAC-SHA256 with the provided key, making it suitable for:
+// The token is computed using HMAC-SHA256 (or the hash chosen with WithLookupHash)
+// with the provided key, making it suitable for:
 // - Database indexing operations
 // - Cache key generation
 // - Deduplication identifiers
@@ -24,24 +19,29 @@ import (
 //   - Rainbow table resistant: Requires secret HMAC key
 //
 // The function is tenant-scoped when tenant_id is included in the normalized input,
-// ensuring multi-tenant data isolation.
+// ensuring multi-tenant data isolation. ComputeTenantLookupToken does this from
+// the tenancy on the context.
 //
 // Parameters:
 //   - hmacKey: Secret key for HMAC (must be kept secure, recommended 32+ bytes)
 //   - normalized: Input data to be tokenized (should be pre-normalized for consistency)
+//   - opts: Optional Unicode normalization (WithLookupNFKC, WithLookupCaseFold)
+//     truncation (WithLookupTruncate) and hash selection (WithLookupHash)
 //
 // Returns:
-//   - 32-byte HMAC-SHA256 token suitable for indexing and comparison
+//   - 32-byte HMAC-SHA256 token suitable for indexing and comparison, or
+//     a prefix of it when truncated
 //
 // Example:
 //
 //	key := []byte("32-byte-secret-key-for-hmac")
 //	input := "user123@example.com"
 //	token := ComputeLookupToken(key, input)
-func ComputeLookupToken(hmacKey []byte, normalized string) []byte {
-	mac := hmac.New(sha256.New, hmacKey)
-	mac.Write([]byte(normalized))
-	return mac.Sum(nil)
+func ComputeLookupToken(hmacKey []byte, normalized string, opts ...LookupTokenOption) []byte {
+	options := newLookupTokenOptions(opts)
+	mac := options.newMAC(hmacKey)
+	mac.Write([]byte(options.normalize(normalized)))
+	return options.finish(mac.Sum(nil))
 }
 
 // EncryptValue encrypts plaintext using AES-GCM with authenticated encryption.
@@ -62,11 +62,18 @@ func ComputeLookupToken(hmacKey []byte, normalized string) []byte {
 //   - plaintext: Data to be encrypted
 //
 // Returns:
-//   - Combined nonce + ciphertext (nonce size + ciphertext + auth tag)
+//   - Versioned payload: 2 header bytes + nonce + ciphertext + auth tag
 //   - Error if key is invalid or encryption fails
 //
-// The returned payload format is: [nonce][ciphertext][authentication-tag]
-// Use DecryptValue with the same key to decrypt.
+// The returned payload format is version 1 of the payload format documented
+// on EncryptValueWithAlgo: [0x01][0x01 (AES-GCM)][nonce][ciphertext][authentication-tag].
+// Earlier releases wrote unversioned [nonce][ciphertext][authentication-tag]
+// payloads; DecryptValue still opens those. Use DecryptValue with the same key
+// to decrypt.
+//
+// Each call draws a fresh random 96-bit nonce, so a single key must not
+// encrypt more than 2^32 values in total (NIST SP 800-38D). Use an Encryptor,
+// which enforces that limit, for high-volume workloads.
 //
 // Example:
 //
@@ -74,38 +81,26 @@ func ComputeLookupToken(hmacKey []byte, normalized string) []byte {
 //	rand.Read(key)
 //	ciphertext, err := EncryptValue(key, []byte("sensitive data"))
 func EncryptValue(aesKey []byte, plaintext []byte) ([]byte, error) {
-	if len(aesKey) != 16 && len(aesKey) != 24 && len(aesKey) != 32 {
-		return nil, errors.New("AES key must be 16, 24, or 32 bytes long")
+	gcm, err := newAEAD(AlgorithmAESGCM, aesKey)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(plaintext) == 0 {
 		return nil, errors.New("plaintext cannot be empty")
 	}
 
-	block, err := aes.NewCipher(aesKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
-	}
-
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
-	}
-
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err = rand.Read(nonce); err != nil {
-		return nil, fmt.Errorf("failed to generate nonce: %w", err)
-	}
-
-	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
-	result := make([]byte, len(nonce)+len(ciphertext))
-	copy(result, nonce)
-	copy(result[len(nonce):], ciphertext)
-	return result, nil
+	return sealVersioned(gcm, AlgorithmAESGCM, plaintext)
 }
 
 // DecryptValue decrypts data encrypted with EncryptValue using AES-GCM.
 //
+// Versioned payloads produced by EncryptValueWithAlgo are detected from their
+// header and opened with the recorded algorithm, so callers can migrate to a
+// different cipher without changing their decryption code. The key is
+// checked against that algorithm, so a 64-byte AES-SIV key opens the
+// payloads of EncryptDeterministic without context.
+//
 // This function verifies the authentication tag to ensure the ciphertext
 // has not been tampered with before decryption. Any modification to the
 // ciphertext or nonce will cause decryption to fail.
@@ -116,8 +111,8 @@ func EncryptValue(aesKey []byte, plaintext []byte) ([]byte, error) {
 //   - Constant-time operations: Safe against timing attacks in verification
 //
 // Parameters:
-//   - aesKey: AES decryption key (must be identical to encryption key)
-//   - payload: Combined nonce + ciphertext from EncryptValue
+//   - aesKey: Decryption key (must be identical to encryption key)
+//   - payload: Versioned or legacy payload from EncryptValue
 //
 // Returns:
 //   - Decrypted plaintext data
@@ -136,40 +131,28 @@ func EncryptValue(aesKey []byte, plaintext []byte) ([]byte, error) {
 //	    // Handle decryption failure
 //	}
 func DecryptValue(aesKey []byte, payload []byte) ([]byte, error) {
-	if len(aesKey) != 16 && len(aesKey) != 24 && len(aesKey) != 32 {
-		return nil, errors.New("AES key must be 16, 24, or 32 bytes long")
-	}
-
 	if len(payload) == 0 {
 		return nil, errors.New("payload cannot be empty")
 	}
 
-	block, err := aes.NewCipher(aesKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	// A legacy payload starts with a random nonce, so it can collide with the
+	// version header; authentication decides, falling back to the legacy layout.
+	plaintext, versioned, err := decryptVersioned(aesKey, payload)
+	if versioned && err == nil {
+		return plaintext, nil
 	}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	// Only AES-GCM wrote legacy payloads, so other keys stop at the header.
+	gcm, gcmErr := newAEAD(AlgorithmAESGCM, aesKey)
+	if gcmErr != nil {
+		if versioned {
+			return nil, err
+		}
+		return nil, gcmErr
 	}
-
-	nonceSize := gcm.NonceSize()
-	if len(payload) < nonceSize {
-		return nil, errors.New("payload too short to contain nonce")
+	plaintext, legacyErr := openLegacy(nil, gcm, payload)
+	if legacyErr != nil && versioned {
+		return nil, err
 	}
-
-	nonce := payload[:nonceSize]
-	ciphertext := payload[nonceSize:]
-
-	if len(ciphertext) == 0 {
-		return nil, errors.New("payload contains no ciphertext")
-	}
-
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return nil, fmt.Errorf("decryption failed: %w", err)
-	}
-
-	return plaintext, nil
+	return plaintext, legacyErr
 }
@@ -0,0 +1,263 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm identifies the AEAD cipher used to seal a versioned payload.
+type Algorithm byte
+
+const (
+	// AlgorithmAESGCM is AES-GCM with a 96-bit random nonce (the EncryptValue default).
+	AlgorithmAESGCM Algorithm = 0x01
+	// AlgorithmChaCha20Poly1305 is ChaCha20-Poly1305 (RFC 8439) with a 96-bit random nonce.
+	// It is faster than AES-GCM on platforms without AES hardware acceleration.
+	AlgorithmChaCha20Poly1305 Algorithm = 0x02
+	// AlgorithmXChaCha20Poly1305 is XChaCha20-Poly1305 with a 192-bit random nonce,
+	// large enough that random nonces never realistically collide under one key.
+	AlgorithmXChaCha20Poly1305 Algorithm = 0x03
+	// AlgorithmAESGCMSIV is AES-GCM-SIV (RFC 8452), a nonce-misuse-resistant AEAD for
+	// high volumes of messages under one key. Keys must be 16 or 32 bytes long.
+	AlgorithmAESGCMSIV Algorithm = 0x04
+	// AlgorithmAESSIV is AES-SIV (RFC 5297). It is deterministic: equal plaintexts
+	// under one key produce equal ciphertexts. Keys must be 32, 48, or 64 bytes
+	// long. It is only available through EncryptDeterministic, never through
+	// the APIs promising a random nonce.
+	AlgorithmAESSIV Algorithm = 0x05
+)
+
+// Payload format versions; see EncryptValueWithAlgo for the layouts.
+const (
+	// payloadVersion1 marks a versioned payload: [version][algorithm][nonce][ciphertext][tag].
+	payloadVersion1   = 0x01
+	payloadHeaderSize = 2
+)
+
+// String returns the conventional name of the algorithm.
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmAESGCM:
+		return "AES-GCM"
+	case AlgorithmChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	case AlgorithmXChaCha20Poly1305:
+		return "XChaCha20-Poly1305"
+	case AlgorithmAESGCMSIV:
+		return "AES-GCM-SIV"
+	case AlgorithmAESSIV:
+		return "AES-SIV"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", byte(a))
+	}
+}
+
+// newAEAD constructs the AEAD for algo after validating the key size.
+func newAEAD(algo Algorithm, key []byte) (cipher.AEAD, error) {
+	if FIPSMode() && !algo.FIPSApproved() {
+		return nil, fmt.Errorf("%w: %s", ErrNotFIPSApproved, algo)
+	}
+
+	switch algo {
+	case AlgorithmAESGCM:
+		if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+			return nil, errors.New("AES key must be 16, 24, or 32 bytes long")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCM: %w", err)
+		}
+		return gcm, nil
+	case AlgorithmChaCha20Poly1305, AlgorithmXChaCha20Poly1305:
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("%s key must be %d bytes long", algo, chacha20poly1305.KeySize)
+		}
+		if algo == AlgorithmXChaCha20Poly1305 {
+			return chacha20poly1305.NewX(key)
+		}
+		return chacha20poly1305.New(key)
+	case AlgorithmAESGCMSIV:
+		return newAESGCMSIV(key)
+	case AlgorithmAESSIV:
+		return newAESSIV(key)
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %s", algo)
+	}
+}
+
+// EncryptValueWithAlgo encrypts plaintext with the selected AEAD algorithm.
+//
+// The returned payload is self-describing so DecryptValue can dispatch on it
+// transparently.
+//
+// Payload format:
+//
+// The first byte of every payload is the format version, which fixes the
+// layout of everything after it. A layout change always takes a new version
+// number; existing versions are never reinterpreted, and readers reject
+// versions they do not know. The whole header is bound to the ciphertext as
+// additional authenticated data, so a payload cannot be relabelled to a
+// different version, algorithm or compression.
+//
+//	Version 1, written by EncryptValue, EncryptValueWithAlgo and Encryptor:
+//	[0x01][algorithm][nonce][ciphertext][authentication-tag]
+//
+//	Version 2, written by Encryptors with WithEncryptorCompression:
+//	[0x02][algorithm][compression][nonce][ciphertext][authentication-tag]
+//
+// The algorithm byte is an Algorithm value and the compression byte a
+// Compression value. The nonce length is that of the algorithm: 12 bytes
+// for AES-GCM, ChaCha20-Poly1305 and AES-GCM-SIV, 24 for XChaCha20-Poly1305.
+// The tag is 16 bytes. Unversioned [nonce][ciphertext][authentication-tag]
+// AES-GCM payloads written by earlier releases of EncryptValue are still
+// accepted by DecryptValue.
+//
+// AlgorithmAESSIV is rejected: it takes no nonce, so its payloads would leak
+// which plaintexts are equal. Use EncryptDeterministic when that is wanted.
+//
+// Example:
+//
+//	key := make([]byte, 32)
+//	rand.Read(key)
+//	ciphertext, err := EncryptValueWithAlgo(AlgorithmXChaCha20Poly1305, key, []byte("sensitive data"))
+func EncryptValueWithAlgo(algo Algorithm, key []byte, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, errors.New("plaintext cannot be empty")
+	}
+	if err := checkRandomNonce(algo); err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(algo, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealVersioned(aead, algo, plaintext)
+}
+
+// checkRandomNonce rejects AES-SIV for the APIs sealing with a random nonce:
+// it has no nonce, so sealing with it would silently be deterministic.
+func checkRandomNonce(algo Algorithm) error {
+	if algo == AlgorithmAESSIV {
+		return fmt.Errorf("%s is deterministic, use EncryptDeterministic", algo)
+	}
+	return nil
+}
+
+// sealVersioned seals plaintext into a version 1 payload with a random nonce.
+func sealVersioned(aead cipher.AEAD, algo Algorithm, plaintext []byte) ([]byte, error) {
+	return sealPayload(nil, aead, []byte{payloadVersion1, byte(algo)}, plaintext)
+}
+
+// sealCompressed seals plaintext compressed by c into a version 2 payload, or
+// into a version 1 payload when compression would not make it smaller.
+// The payload is written to dst when its capacity suffices.
+func sealCompressed(dst []byte, aead cipher.AEAD, algo Algorithm, c *compressor, plaintext []byte) ([]byte, error) {
+	compressed, err := c.compress(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if compressed == nil {
+		return sealPayload(dst, aead, []byte{payloadVersion1, byte(algo)}, plaintext)
+	}
+	defer Zeroize(compressed)
+	return sealPayload(dst, aead, []byte{payloadVersion2, byte(algo), byte(c.compression)}, compressed)
+}
+
+// sealedSize returns the length of the version 1 payload sealing a plaintext
+// of plaintextLen bytes, an upper bound for version 2 payloads as well.
+func sealedSize(aead cipher.AEAD, plaintextLen int) int {
+	return payloadHeaderSize + aead.NonceSize() + plaintextLen + aead.Overhead()
+}
+
+// sealPayload writes header, a random nonce and the sealed plaintext to dst,
+// authenticating the header as additional data. dst is reused when its
+// capacity suffices and a new buffer is allocated otherwise.
+func sealPayload(dst []byte, aead cipher.AEAD, header []byte, plaintext []byte) ([]byte, error) {
+	headerSize, nonceSize := len(header), aead.NonceSize()
+	if size := headerSize + nonceSize + len(plaintext) + aead.Overhead(); cap(dst) < size {
+		dst = make([]byte, 0, size)
+	}
+	result := dst[:headerSize+nonceSize]
+	copy(result, header)
+
+	nonce := result[headerSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(result, nonce, plaintext, result[:headerSize]), nil
+}
+
+// versionedHeaderSize returns the header length of a versioned payload, or 0
+// when payload does not start with a known version.
+func versionedHeaderSize(payload []byte) int {
+	switch {
+	case len(payload) >= payloadHeaderSize && payload[0] == payloadVersion1:
+		return payloadHeaderSize
+	case len(payload) >= payloadV2HeaderSize && payload[0] == payloadVersion2:
+		return payloadV2HeaderSize
+	default:
+		return 0
+	}
+}
+
+// decryptVersioned opens a version 1 or 2 payload, checking key against the
+// algorithm in its header. The boolean result reports whether the payload
+// carried a recognised version header at all, letting callers fall back to
+// the legacy [nonce][ciphertext] layout otherwise.
+func decryptVersioned(key []byte, payload []byte) ([]byte, bool, error) {
+	if versionedHeaderSize(payload) == 0 {
+		return nil, false, nil
+	}
+
+	aead, err := newAEAD(Algorithm(payload[1]), key)
+	if err != nil {
+		return nil, true, err
+	}
+
+	plaintext, err := openVersioned(nil, aead, payload)
+	return plaintext, true, err
+}
+
+// openVersioned opens a version 1 or 2 payload with an already constructed
+// AEAD, decompressing the plaintext of version 2 payloads. The plaintext is
+// appended to dst.
+func openVersioned(dst []byte, aead cipher.AEAD, payload []byte) ([]byte, error) {
+	headerSize := versionedHeaderSize(payload)
+	if headerSize == 0 {
+		return nil, errors.New("payload has no version header")
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(payload) < headerSize+nonceSize {
+		return nil, errors.New("payload too short to contain nonce")
+	}
+
+	nonce := payload[headerSize : headerSize+nonceSize]
+	ciphertext := payload[headerSize+nonceSize:]
+	if len(ciphertext) == 0 {
+		return nil, errors.New("payload contains no ciphertext")
+	}
+
+	plaintext, err := aead.Open(dst, nonce, ciphertext, payload[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+	if headerSize == payloadHeaderSize {
+		return plaintext, nil
+	}
+
+	defer Zeroize(plaintext)
+	return decompress(Compression(payload[2]), plaintext)
+}
@@ -0,0 +1,144 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEncryptValueWithAlgoRoundTrip(t *testing.T) {
+	algorithms := []util.Algorithm{
+		util.AlgorithmAESGCM,
+		util.AlgorithmChaCha20Poly1305,
+		util.AlgorithmXChaCha20Poly1305,
+		util.AlgorithmAESGCMSIV,
+	}
+
+	for _, algo := range algorithms {
+		t.Run(algo.String(), func(t *testing.T) {
+			skipUnlessFIPSApproved(t, algo)
+			key := make([]byte, 32)
+			rand.Read(key)
+			plaintext := []byte("algorithm agnostic secret")
+
+			ciphertext, err := util.EncryptValueWithAlgo(algo, key, plaintext)
+			if err != nil {
+				t.Fatalf("EncryptValueWithAlgo() failed: %v", err)
+			}
+			if ciphertext[1] != byte(algo) {
+				t.Errorf("payload algorithm byte = %d, want %d", ciphertext[1], algo)
+			}
+
+			decrypted, err := util.DecryptValue(key, ciphertext)
+			if err != nil {
+				t.Fatalf("DecryptValue() failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Error("round trip failed: decrypted data doesn't match original")
+			}
+		})
+	}
+}
+
+func TestDecryptValueKeySizes(t *testing.T) {
+	keySizes := map[util.Algorithm][]int{
+		util.AlgorithmAESGCM:            {16, 24, 32},
+		util.AlgorithmChaCha20Poly1305:  {32},
+		util.AlgorithmXChaCha20Poly1305: {32},
+		util.AlgorithmAESGCMSIV:         {16, 32},
+		util.AlgorithmAESSIV:            {32, 48, 64},
+	}
+
+	for algo, sizes := range keySizes {
+		for _, size := range sizes {
+			t.Run(fmt.Sprintf("%s/%d", algo, size), func(t *testing.T) {
+				skipUnlessFIPSApproved(t, algo)
+				key := make([]byte, size)
+				rand.Read(key)
+				plaintext := []byte("sized secret")
+
+				var ciphertext []byte
+				var err error
+				if algo == util.AlgorithmAESSIV {
+					ciphertext, err = util.EncryptDeterministic(key, plaintext, nil)
+				} else {
+					ciphertext, err = util.EncryptValueWithAlgo(algo, key, plaintext)
+				}
+				if err != nil {
+					t.Fatalf("encrypt failed: %v", err)
+				}
+
+				decrypted, err := util.DecryptValue(key, ciphertext)
+				if err != nil {
+					t.Fatalf("DecryptValue() failed: %v", err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Error("round trip failed: decrypted data doesn't match original")
+				}
+			})
+		}
+	}
+}
+
+func TestEncryptValueWithAlgoErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		algo      util.Algorithm
+		keySize   int
+		plaintext []byte
+		errMsg    string
+	}{
+		{"chacha requires 32 byte key", util.AlgorithmChaCha20Poly1305, 16, []byte("x"), "must be 32 bytes"},
+		{"xchacha requires 32 byte key", util.AlgorithmXChaCha20Poly1305, 24, []byte("x"), "must be 32 bytes"},
+		{"gcm-siv rejects 24 byte key", util.AlgorithmAESGCMSIV, 24, []byte("x"), "must be 16 or 32 bytes"},
+		{"unknown algorithm", util.Algorithm(0x7f), 32, []byte("x"), "unsupported encryption algorithm"},
+		{"aes-siv is deterministic", util.AlgorithmAESSIV, 64, []byte("x"), "use EncryptDeterministic"},
+		{"empty plaintext", util.AlgorithmChaCha20Poly1305, 32, nil, "plaintext cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := util.EncryptValueWithAlgo(tt.algo, make([]byte, tt.keySize), tt.plaintext)
+			if util.FIPSMode() && errors.Is(err, util.ErrNotFIPSApproved) {
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("EncryptValueWithAlgo() error = %v, want containing %q", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestDecryptValueRejectsRelabelledAlgorithm(t *testing.T) {
+	skipUnlessFIPSApproved(t, util.AlgorithmChaCha20Poly1305)
+
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	ciphertext, err := util.EncryptValueWithAlgo(util.AlgorithmChaCha20Poly1305, key, []byte("bound header"))
+	if err != nil {
+		t.Fatalf("EncryptValueWithAlgo() failed: %v", err)
+	}
+
+	ciphertext[1] = byte(util.AlgorithmAESGCM)
+	if _, err = util.DecryptValue(key, ciphertext); err == nil {
+		t.Error("DecryptValue() should fail when the algorithm byte is tampered with")
+	}
+}
+
+func BenchmarkEncryptValueChaCha20Poly1305(b *testing.B) {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	plaintext := make([]byte, 1024)
+	_, _ = rand.Read(plaintext)
+
+	b.ResetTimer()
+	for range b.N {
+		_, _ = util.EncryptValueWithAlgo(util.AlgorithmChaCha20Poly1305, key, plaintext)
+	}
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// defaultJSONCompressionThreshold is the marshalled size from which
+// WithJSONCompression starts compressing; smaller documents rarely shrink.
+const defaultJSONCompressionThreshold = 1024
+
+// jsonCipherOptions contains configuration for EncryptJSON.
+type jsonCipherOptions struct {
+	// algorithm selects the AEAD used to seal the document
+	algorithm Algorithm
+
+	// compressAbove enables gzip for documents of at least this many bytes; 0 disables it
+	compressAbove int
+}
+
+// JSONCipherOption is a function that configures EncryptJSON.
+type JSONCipherOption func(*jsonCipherOptions)
+
+// WithJSONAlgorithm selects the AEAD algorithm used to seal the document.
+// The default is AlgorithmAESGCM.
+func WithJSONAlgorithm(algo Algorithm) JSONCipherOption {
+	return func(o *jsonCipherOptions) {
+		o.algorithm = algo
+	}
+}
+
+// WithJSONCompression gzips the marshalled document before encryption when it
+// is at least minSize bytes long (1 KiB when minSize <= 0). Compression must
+// happen before encryption because ciphertext does not compress.
+func WithJSONCompression(minSize int) JSONCipherOption {
+	return func(o *jsonCipherOptions) {
+		if minSize <= 0 {
+			minSize = defaultJSONCompressionThreshold
+		}
+		o.compressAbove = minSize
+	}
+}
+
+// EncryptJSON marshals value to JSON and encrypts it in one step.
+//
+// The result is a versioned payload (see EncryptValueWithAlgo) that
+// DecryptJSON restores into a value of the same type; compressed documents
+// use version 2, which records the compression in the header.
+//
+// Example:
+//
+//	payload, err := EncryptJSON(key, profile, WithJSONCompression(0))
+//	restored, err := DecryptJSON[Profile](key, payload)
+func EncryptJSON[T any](key []byte, value T, opts ...JSONCipherOption) ([]byte, error) {
+	options := &jsonCipherOptions{algorithm: AlgorithmAESGCM}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	if options.compressAbove == 0 || len(plaintext) < options.compressAbove {
+		return EncryptValueWithAlgo(options.algorithm, key, plaintext)
+	}
+
+	if err = checkRandomNonce(options.algorithm); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(options.algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	return sealCompressed(nil, aead, options.algorithm, &compressor{compression: CompressionGzip}, plaintext)
+}
+
+// DecryptJSON decrypts a payload produced by EncryptJSON and unmarshals it into T.
+// Compressed documents are decompressed automatically.
+func DecryptJSON[T any](key []byte, payload []byte) (T, error) {
+	var value T
+
+	plaintext, err := DecryptValue(key, payload)
+	if err != nil {
+		return value, err
+	}
+
+	if err = json.Unmarshal(plaintext, &value); err != nil {
+		return value, fmt.Errorf("failed to unmarshal value: %w", err)
+	}
+	return value, nil
+}
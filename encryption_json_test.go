@@ -0,0 +1,87 @@
+package util_test
+
+import (
+	"crypto/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type encryptedProfile struct {
+	Name  string            `json:"name"`
+	Email string            `json:"email"`
+	Tags  []string          `json:"tags"`
+	Meta  map[string]string `json:"meta"`
+}
+
+func TestEncryptJSONRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	profile := encryptedProfile{
+		Name:  "Ada",
+		Email: "ada@example.com",
+		Tags:  []string{"admin", "beta"},
+		Meta:  map[string]string{"notes": strings.Repeat("lorem ipsum ", 500)},
+	}
+
+	tests := []struct {
+		name string
+		algo util.Algorithm
+		opts []util.JSONCipherOption
+	}{
+		{"plain", util.AlgorithmAESGCM, nil},
+		{"compressed", util.AlgorithmAESGCM, []util.JSONCipherOption{util.WithJSONCompression(0)}},
+		{
+			"chacha", util.AlgorithmChaCha20Poly1305,
+			[]util.JSONCipherOption{util.WithJSONAlgorithm(util.AlgorithmChaCha20Poly1305)},
+		},
+	}
+
+	sizes := map[string]int{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skipUnlessFIPSApproved(t, tt.algo)
+			payload, err := util.EncryptJSON(key, profile, tt.opts...)
+			if err != nil {
+				t.Fatalf("EncryptJSON() failed: %v", err)
+			}
+			sizes[tt.name] = len(payload)
+
+			got, err := util.DecryptJSON[encryptedProfile](key, payload)
+			if err != nil {
+				t.Fatalf("DecryptJSON() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, profile) {
+				t.Error("DecryptJSON() did not restore the original value")
+			}
+		})
+	}
+
+	compressed, err := util.EncryptJSON(key, profile, util.WithJSONCompression(0))
+	if err != nil {
+		t.Fatalf("EncryptJSON() failed: %v", err)
+	}
+	if compressed[0] != 0x02 {
+		t.Errorf("compressed payload version = %d, want 2", compressed[0])
+	}
+	if sizes["compressed"] >= sizes["plain"] {
+		t.Errorf("compressed payload (%d bytes) should be smaller than plain (%d bytes)",
+			sizes["compressed"], sizes["plain"])
+	}
+}
+
+func TestDecryptJSONTypeMismatch(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	payload, err := util.EncryptJSON(key, []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("EncryptJSON() failed: %v", err)
+	}
+	if _, err = util.DecryptJSON[encryptedProfile](key, payload); err == nil {
+		t.Error("DecryptJSON() should fail to unmarshal into a mismatched type")
+	}
+}
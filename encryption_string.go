@@ -0,0 +1,126 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TextEncoding selects how EncryptToString renders ciphertext as text.
+type TextEncoding int
+
+const (
+	// TextEncodingBase64URL renders unpadded base64url, safe in URLs, JSON and
+	// text columns. This is the default.
+	TextEncodingBase64URL TextEncoding = iota
+	// TextEncodingHex renders lowercase hexadecimal.
+	TextEncodingHex
+)
+
+// Text payload prefixes. The prefix records the format version and encoding
+// so DecryptFromString never has to guess.
+const (
+	textPrefixBase64URL = "v1."
+	textPrefixHex       = "v1x."
+)
+
+// textCipherOptions contains configuration for EncryptToString.
+type textCipherOptions struct {
+	// encoding selects the text encoding of the ciphertext
+	encoding TextEncoding
+
+	// algorithm selects the AEAD used to seal the plaintext
+	algorithm Algorithm
+}
+
+// TextCipherOption is a function that configures EncryptToString.
+type TextCipherOption func(*textCipherOptions)
+
+// WithTextEncoding selects the text encoding of the ciphertext.
+func WithTextEncoding(encoding TextEncoding) TextCipherOption {
+	return func(o *textCipherOptions) {
+		o.encoding = encoding
+	}
+}
+
+// WithTextAlgorithm selects the AEAD algorithm used to seal the plaintext.
+// The default is AlgorithmAESGCM.
+func WithTextAlgorithm(algo Algorithm) TextCipherOption {
+	return func(o *textCipherOptions) {
+		o.algorithm = algo
+	}
+}
+
+// EncryptToString encrypts plaintext and returns it as prefixed text suitable
+// for JSON fields, text columns and URL parameters.
+//
+// The output is "v1." followed by unpadded base64url, or "v1x." followed by
+// hex when TextEncodingHex is selected. The encoded bytes are a versioned
+// payload as produced by EncryptValueWithAlgo.
+//
+// Example:
+//
+//	token, err := EncryptToString(key, "4111111111111111")
+//	// token == "v1.AQG3..."
+//	plaintext, err := DecryptFromString(key, token)
+func EncryptToString(key []byte, plaintext string, opts ...TextCipherOption) (string, error) {
+	options := &textCipherOptions{encoding: TextEncodingBase64URL, algorithm: AlgorithmAESGCM}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	payload, err := EncryptValueWithAlgo(options.algorithm, key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	switch options.encoding {
+	case TextEncodingBase64URL:
+		return textPrefixBase64URL + base64.RawURLEncoding.EncodeToString(payload), nil
+	case TextEncodingHex:
+		return textPrefixHex + hex.EncodeToString(payload), nil
+	default:
+		return "", fmt.Errorf("unsupported text encoding %d", options.encoding)
+	}
+}
+
+// DecryptFromString decrypts text produced by EncryptToString, detecting the
+// encoding from its prefix.
+func DecryptFromString(key []byte, text string) (string, error) {
+	payload, err := decodeCipherText(text)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := DecryptValue(key, payload)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func decodeCipherText(text string) ([]byte, error) {
+	if text == "" {
+		return nil, errors.New("ciphertext cannot be empty")
+	}
+
+	if encoded, ok := strings.CutPrefix(text, textPrefixHex); ok {
+		payload, err := hex.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex ciphertext: %w", err)
+		}
+		return payload, nil
+	}
+
+	if encoded, ok := strings.CutPrefix(text, textPrefixBase64URL); ok {
+		payload, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64url ciphertext: %w", err)
+		}
+		return payload, nil
+	}
+
+	return nil, errors.New("ciphertext is missing a recognised version prefix")
+}
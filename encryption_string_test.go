@@ -0,0 +1,64 @@
+package util_test
+
+import (
+	"crypto/rand"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEncryptToStringRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	tests := []struct {
+		name   string
+		algo   util.Algorithm
+		opts   []util.TextCipherOption
+		prefix string
+	}{
+		{"default base64url", util.AlgorithmAESGCM, nil, "v1."},
+		{"hex", util.AlgorithmAESGCM, []util.TextCipherOption{util.WithTextEncoding(util.TextEncodingHex)}, "v1x."},
+		{
+			"xchacha base64url", util.AlgorithmXChaCha20Poly1305,
+			[]util.TextCipherOption{util.WithTextAlgorithm(util.AlgorithmXChaCha20Poly1305)},
+			"v1.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skipUnlessFIPSApproved(t, tt.algo)
+			text, err := util.EncryptToString(key, "4111 1111 1111 1111", tt.opts...)
+			if err != nil {
+				t.Fatalf("EncryptToString() failed: %v", err)
+			}
+			if !strings.HasPrefix(text, tt.prefix) {
+				t.Errorf("EncryptToString() = %q, want prefix %q", text, tt.prefix)
+			}
+			if url.QueryEscape(text) != text {
+				t.Errorf("EncryptToString() = %q is not URL safe", text)
+			}
+
+			plaintext, err := util.DecryptFromString(key, text)
+			if err != nil {
+				t.Fatalf("DecryptFromString() failed: %v", err)
+			}
+			if plaintext != "4111 1111 1111 1111" {
+				t.Errorf("DecryptFromString() = %q, want original", plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptFromStringErrors(t *testing.T) {
+	key := make([]byte, 32)
+
+	for _, text := range []string{"", "AQIDBA", "v1.***", "v1x.zz", "v1.AQID"} {
+		if _, err := util.DecryptFromString(key, text); err == nil {
+			t.Errorf("DecryptFromString(%q) expected error", text)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package util_test
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"fmt"
 	"strings"
@@ -91,6 +93,23 @@ func TestComputeLookupTokenInputIsolation(t *testing.T) {
 	}
 }
 
+// sealLegacyPayload builds the unversioned [nonce][ciphertext][tag] AES-GCM
+// payload written by EncryptValue before payloads carried a version byte.
+func sealLegacyPayload(t testing.TB, key, plaintext []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, nil)
+}
+
 func validateEncryptResult(t *testing.T, got []byte, plaintext []byte, err error, wantErr bool, errMsg string) {
 	if (err != nil) != wantErr {
 		t.Errorf("util.EncryptValue() error = %v, wantErr %v", err, wantErr)
@@ -282,7 +301,8 @@ func TestDecryptValue(t *testing.T) {
 			setup: func() ([]byte, []byte, []byte, error) {
 				key := make([]byte, 32)
 				rand.Read(key)
-				return key, []byte{1, 2, 3}, []byte{}, nil
+				// A version 1 AES-GCM header followed by a single byte.
+				return key, []byte{1, 1, 3}, []byte{}, nil
 			},
 			wantErr: true,
 			errMsg:  "payload too short to contain nonce",
@@ -321,6 +341,60 @@ func TestDecryptValue(t *testing.T) {
 	}
 }
 
+func TestEncryptValuePayloadFormat(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	plaintext := []byte("versioned")
+
+	payload, err := util.EncryptValue(key, plaintext)
+	if err != nil {
+		t.Fatalf("EncryptValue() failed: %v", err)
+	}
+
+	// [0x01][AES-GCM][12-byte nonce][ciphertext][16-byte tag]
+	if payload[0] != 0x01 || util.Algorithm(payload[1]) != util.AlgorithmAESGCM {
+		t.Errorf("header = %x, want 0101", payload[:2])
+	}
+	if want := 2 + 12 + len(plaintext) + 16; len(payload) != want {
+		t.Errorf("len(payload) = %d, want %d", len(payload), want)
+	}
+}
+
+func TestDecryptValueLegacyPayload(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+
+	got, err := util.DecryptValue(key, sealLegacyPayload(t, key, []byte("written before versioning")))
+	if err != nil || string(got) != "written before versioning" {
+		t.Errorf("DecryptValue() = %q, %v; want the legacy plaintext", got, err)
+	}
+}
+
+// FuzzDecryptValue checks that DecryptValue never panics on malformed input
+// and never returns anything but the sealed plaintext.
+func FuzzDecryptValue(f *testing.F) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	plaintext := []byte("fuzz plaintext")
+
+	versioned, _ := util.EncryptValue(key, plaintext)
+	chacha, _ := util.EncryptValueWithAlgo(util.AlgorithmChaCha20Poly1305, key, plaintext)
+	f.Add(versioned)
+	f.Add(chacha)
+	f.Add(sealLegacyPayload(f, key, plaintext))
+	f.Add([]byte{})
+	f.Add([]byte{0x01})
+	f.Add([]byte{0x01, 0x01})
+	f.Add([]byte{0x02, 0x01, 0x02})
+	f.Add([]byte{0x01, 0xff, 0x00})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		got, err := util.DecryptValue(key, payload)
+		if err == nil && !bytes.Equal(got, plaintext) {
+			t.Errorf("DecryptValue() returned %q for a forged payload", got)
+		}
+	})
+}
+
 func TestEncryptDecryptRoundTrip(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -0,0 +1,207 @@
+package util
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// randomNonceLimit is the number of encryptions under one key after which
+// random 96-bit nonces risk colliding (NIST SP 800-38D, section 8.3).
+const randomNonceLimit = 1 << 32
+
+// ErrEncryptionLimitReached is returned by Encryptor.Encrypt once the
+// Encryptor has sealed as many values as its key can safely protect.
+var ErrEncryptionLimitReached = errors.New("encryption limit for this key reached, rotate the key")
+
+// encryptorOptions contains configuration for an Encryptor.
+type encryptorOptions struct {
+	// algorithm selects the AEAD used for new payloads
+	algorithm Algorithm
+
+	// compression selects how plaintext is compressed before sealing
+	compression Compression
+
+	// maxEncryptions lowers the number of values the Encryptor may seal
+	maxEncryptions uint64
+}
+
+// EncryptorOption is a function that configures an Encryptor.
+type EncryptorOption func(*encryptorOptions)
+
+// WithEncryptorAlgorithm selects the AEAD algorithm the Encryptor seals with.
+// The default is AlgorithmAESGCM. NewEncryptor rejects the deterministic
+// AlgorithmAESSIV.
+func WithEncryptorAlgorithm(algo Algorithm) EncryptorOption {
+	return func(o *encryptorOptions) {
+		o.algorithm = algo
+	}
+}
+
+// WithEncryptorCompression compresses plaintext with c before sealing, to cut
+// the storage cost of large, repetitive values such as JSON documents.
+//
+// The compression is recorded in the payload header, which is authenticated
+// along with the ciphertext, and Decrypt and DecryptValue decompress
+// transparently. A value that does not shrink is stored uncompressed, so
+// enabling compression never makes a payload larger.
+// Decompressed plaintext is limited to 256 MiB.
+//
+// Compression reveals information about the plaintext through the
+// ciphertext length. Do not compress values that mix attacker-controlled
+// input with secrets, the setting exploited by the CRIME and BREACH attacks.
+func WithEncryptorCompression(c Compression) EncryptorOption {
+	return func(o *encryptorOptions) {
+		o.compression = c
+	}
+}
+
+// WithEncryptorMaxEncryptions caps the number of values the Encryptor seals
+// before Encrypt fails with ErrEncryptionLimitReached, for deployments that
+// rotate keys on a stricter schedule than the cryptographic limit.
+//
+// AES-GCM and ChaCha20-Poly1305 Encryptors are always capped at 2^32
+// encryptions, the NIST SP 800-38D bound for random 96-bit nonces; a larger
+// value has no effect. Other algorithms are uncapped by default.
+func WithEncryptorMaxEncryptions(n uint64) EncryptorOption {
+	return func(o *encryptorOptions) {
+		o.maxEncryptions = n
+	}
+}
+
+// Encryptor encrypts and decrypts values under a single key, caching the
+// cipher setup that EncryptValue and DecryptValue repeat on every call.
+//
+// An Encryptor is safe for concurrent use by multiple goroutines, so one
+// instance per key can be shared across a bulk workload.
+//
+// Payloads use the versioned format documented on EncryptValueWithAlgo and
+// can be opened with DecryptValue as well as Encryptor.Decrypt.
+type Encryptor struct {
+	algorithm  Algorithm
+	aead       cipher.AEAD
+	compressor *compressor
+
+	// limit is the maximum number of encryptions, or 0 when uncapped
+	limit uint64
+	count atomic.Uint64
+}
+
+// NewEncryptor validates key for the selected algorithm and returns a reusable Encryptor.
+//
+// Example:
+//
+//	enc, err := NewEncryptor(key, WithEncryptorAlgorithm(AlgorithmChaCha20Poly1305))
+//	if err != nil {
+//	    return err
+//	}
+//	for _, row := range rows {
+//	    row.Secret, err = enc.Encrypt(row.Plain)
+//	}
+func NewEncryptor(key []byte, opts ...EncryptorOption) (*Encryptor, error) {
+	options := &encryptorOptions{algorithm: AlgorithmAESGCM}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if err := checkRandomNonce(options.algorithm); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(options.algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	compressor, err := newCompressor(options.compression)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := options.maxEncryptions
+	if options.algorithm == AlgorithmAESGCM || options.algorithm == AlgorithmChaCha20Poly1305 {
+		if limit == 0 || limit > randomNonceLimit {
+			limit = randomNonceLimit
+		}
+	}
+
+	return &Encryptor{algorithm: options.algorithm, aead: aead, compressor: compressor, limit: limit}, nil
+}
+
+// Algorithm returns the AEAD algorithm used by the Encryptor.
+func (e *Encryptor) Algorithm() Algorithm {
+	return e.algorithm
+}
+
+// Compression returns the compression applied before sealing.
+func (e *Encryptor) Compression() Compression {
+	return e.compressor.compression
+}
+
+// Encrypt seals plaintext with a fresh random nonce, compressing it first
+// when the Encryptor was created with WithEncryptorCompression.
+//
+// Returns ErrEncryptionLimitReached once the Encryptor has sealed its
+// maximum number of values (see WithEncryptorMaxEncryptions); every later
+// call fails the same way until the key is rotated.
+func (e *Encryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	return e.encrypt(nil, plaintext)
+}
+
+// encrypt implements Encrypt, writing the payload to dst when its capacity suffices.
+func (e *Encryptor) encrypt(dst, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, errors.New("plaintext cannot be empty")
+	}
+	if e.limit > 0 && e.count.Add(1) > e.limit {
+		return nil, ErrEncryptionLimitReached
+	}
+	return sealCompressed(dst, e.aead, e.algorithm, e.compressor, plaintext)
+}
+
+// Decrypt opens a payload produced by Encrypt. AES-GCM encryptors also accept
+// the legacy [nonce][ciphertext] payloads produced by EncryptValue.
+func (e *Encryptor) Decrypt(payload []byte) ([]byte, error) {
+	return e.decrypt(nil, payload)
+}
+
+// decrypt implements Decrypt, appending the plaintext to dst.
+func (e *Encryptor) decrypt(dst, payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("payload cannot be empty")
+	}
+
+	versioned := versionedHeaderSize(payload) > 0
+	if versioned && Algorithm(payload[1]) == e.algorithm {
+		plaintext, err := openVersioned(dst, e.aead, payload)
+		if err == nil || e.algorithm != AlgorithmAESGCM {
+			return plaintext, err
+		}
+	} else if e.algorithm != AlgorithmAESGCM {
+		return nil, fmt.Errorf("payload was not sealed with %s", e.algorithm)
+	}
+
+	return openLegacy(dst, e.aead, payload)
+}
+
+// openLegacy opens the unversioned [nonce][ciphertext][tag] layout of EncryptValue,
+// appending the plaintext to dst.
+func openLegacy(dst []byte, aead cipher.AEAD, payload []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(payload) < nonceSize {
+		return nil, errors.New("payload too short to contain nonce")
+	}
+
+	nonce := payload[:nonceSize]
+	ciphertext := payload[nonceSize:]
+
+	if len(ciphertext) == 0 {
+		return nil, errors.New("payload contains no ciphertext")
+	}
+
+	plaintext, err := aead.Open(dst, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// EncryptBatch encrypts every plaintext like Encrypt, spreading the work over
+// a bounded pool of GOMAXPROCS workers, for bulk imports and ETL jobs.
+//
+// Output buffers are sized up front and carved from a single allocation, so
+// a batch costs one large allocation instead of one per row. The payloads
+// therefore share a backing array, which stays in memory while any of them
+// is referenced; clone a payload that must outlive the rest of the batch.
+//
+// Failures do not stop the batch: the result for a failed plaintext is nil and
+// the returned error joins one error per failure, each naming its index.
+// Results are in input order.
+//
+// Example:
+//
+//	payloads, err := enc.EncryptBatch(plaintexts)
+//	if err != nil {
+//	    return err
+//	}
+//	for i, row := range rows {
+//	    row.Secret = payloads[i]
+//	}
+func (e *Encryptor) EncryptBatch(plaintexts [][]byte) ([][]byte, error) {
+	// A compressed payload is never larger than an uncompressed one, so the
+	// version 1 size bounds every result.
+	arena := newBatchArena(plaintexts, func(plaintext []byte) int {
+		return sealedSize(e.aead, len(plaintext))
+	})
+
+	results := make([][]byte, len(plaintexts))
+	errs := make([]error, len(plaintexts))
+	runBatch(len(plaintexts), func(i int) {
+		results[i], errs[i] = e.encrypt(arena.buffer(i), plaintexts[i])
+	})
+	return results, joinBatchErrors("plaintext", errs)
+}
+
+// DecryptBatch decrypts every payload like Decrypt, spreading the work over
+// a bounded pool of GOMAXPROCS workers.
+//
+// As with EncryptBatch, plaintexts share one pre-allocated backing array
+// (except those of compressed payloads, which are allocated when they are
+// decompressed), failed entries are nil and the returned error joins one
+// error per failure, each naming its index.
+func (e *Encryptor) DecryptBatch(payloads [][]byte) ([][]byte, error) {
+	// A plaintext is never longer than its payload.
+	arena := newBatchArena(payloads, func(payload []byte) int {
+		return len(payload)
+	})
+
+	results := make([][]byte, len(payloads))
+	errs := make([]error, len(payloads))
+	runBatch(len(payloads), func(i int) {
+		results[i], errs[i] = e.decrypt(arena.buffer(i), payloads[i])
+	})
+	return results, joinBatchErrors("payload", errs)
+}
+
+// batchArena is one allocation split into per-item output buffers.
+type batchArena struct {
+	buf     []byte
+	offsets []int
+}
+
+func newBatchArena(items [][]byte, size func([]byte) int) *batchArena {
+	offsets := make([]int, len(items)+1)
+	for i, item := range items {
+		offsets[i+1] = offsets[i] + size(item)
+	}
+	return &batchArena{buf: make([]byte, offsets[len(items)]), offsets: offsets}
+}
+
+// buffer returns an empty slice whose capacity is exactly item i's share of the arena.
+func (a *batchArena) buffer(i int) []byte {
+	return a.buf[a.offsets[i]:a.offsets[i]:a.offsets[i+1]]
+}
+
+// runBatch calls fn for every index in [0, n) from at most GOMAXPROCS goroutines.
+func runBatch(n int, fn func(i int)) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), n) {
+		wg.Go(func() {
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				fn(i)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// joinBatchErrors joins the non-nil errors, prefixing each with its item and index.
+func joinBatchErrors(item string, errs []error) error {
+	var joined []error
+	for i, err := range errs {
+		if err != nil {
+			joined = append(joined, fmt.Errorf("%s %d: %w", item, i, err))
+		}
+	}
+	return errors.Join(joined...)
+}
@@ -0,0 +1,105 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEncryptorBatchRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	plaintexts := make([][]byte, 500)
+	for i := range plaintexts {
+		plaintexts[i] = fmt.Appendf(nil, "row %d: %s", i, strings.Repeat("x", i%40))
+	}
+
+	for _, opts := range [][]util.EncryptorOption{
+		nil,
+		{util.WithEncryptorAlgorithm(util.AlgorithmXChaCha20Poly1305)},
+		{util.WithEncryptorCompression(util.CompressionZstd)},
+	} {
+		enc, err := util.NewEncryptor(key, opts...)
+		if util.FIPSMode() && errors.Is(err, util.ErrNotFIPSApproved) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("NewEncryptor() failed: %v", err)
+		}
+		t.Run(fmt.Sprintf("%s/%s", enc.Algorithm(), enc.Compression()), func(t *testing.T) {
+			payloads, err := enc.EncryptBatch(plaintexts)
+			if err != nil {
+				t.Fatalf("EncryptBatch() failed: %v", err)
+			}
+			if len(payloads) != len(plaintexts) {
+				t.Fatalf("EncryptBatch() returned %d payloads, want %d", len(payloads), len(plaintexts))
+			}
+
+			decrypted, err := enc.DecryptBatch(payloads)
+			if err != nil {
+				t.Fatalf("DecryptBatch() failed: %v", err)
+			}
+			for i := range plaintexts {
+				if !bytes.Equal(decrypted[i], plaintexts[i]) {
+					t.Fatalf("DecryptBatch()[%d] = %q, want %q", i, decrypted[i], plaintexts[i])
+				}
+				// Batch payloads are ordinary payloads.
+				if single, decErr := enc.Decrypt(payloads[i]); decErr != nil || !bytes.Equal(single, plaintexts[i]) {
+					t.Fatalf("Decrypt(payloads[%d]) = %q, %v", i, single, decErr)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptorBatchReportsFailuresByIndex(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	enc, _ := util.NewEncryptor(key)
+
+	payloads, err := enc.EncryptBatch([][]byte{[]byte("a"), nil, []byte("c")})
+	if err == nil || !strings.Contains(err.Error(), "plaintext 1:") {
+		t.Fatalf("EncryptBatch() error = %v, want a failure for plaintext 1", err)
+	}
+	if payloads[0] == nil || payloads[1] != nil || payloads[2] == nil {
+		t.Fatalf("EncryptBatch() should only leave the failed entry nil")
+	}
+
+	payloads[2][len(payloads[2])-1] ^= 0x01
+	decrypted, err := enc.DecryptBatch(payloads)
+	if err == nil || !strings.Contains(err.Error(), "payload 1:") || !strings.Contains(err.Error(), "payload 2:") {
+		t.Fatalf("DecryptBatch() error = %v, want failures for payloads 1 and 2", err)
+	}
+	if string(decrypted[0]) != "a" || decrypted[1] != nil || decrypted[2] != nil {
+		t.Errorf("DecryptBatch() = %q, want only the first entry", decrypted)
+	}
+}
+
+func TestEncryptorBatchEmpty(t *testing.T) {
+	enc, _ := util.NewEncryptor(make([]byte, 32))
+	payloads, err := enc.EncryptBatch(nil)
+	if err != nil || len(payloads) != 0 {
+		t.Errorf("EncryptBatch(nil) = %v, %v; want empty", payloads, err)
+	}
+}
+
+func BenchmarkEncryptorEncryptBatch(b *testing.B) {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	enc, _ := util.NewEncryptor(key)
+
+	plaintexts := make([][]byte, 10_000)
+	for i := range plaintexts {
+		plaintexts[i] = fmt.Appendf(nil, "customer-%08d@example.com", i)
+	}
+
+	for b.Loop() {
+		_, _ = enc.EncryptBatch(plaintexts)
+	}
+}
@@ -0,0 +1,207 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEncryptorRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	for _, algo := range []util.Algorithm{
+		util.AlgorithmAESGCM,
+		util.AlgorithmChaCha20Poly1305,
+		util.AlgorithmXChaCha20Poly1305,
+		util.AlgorithmAESGCMSIV,
+	} {
+		t.Run(algo.String(), func(t *testing.T) {
+			skipUnlessFIPSApproved(t, algo)
+			enc, err := util.NewEncryptor(key, util.WithEncryptorAlgorithm(algo))
+			if err != nil {
+				t.Fatalf("NewEncryptor() failed: %v", err)
+			}
+
+			plaintext := []byte("reusable encryptor data")
+			ciphertext, err := enc.Encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() failed: %v", err)
+			}
+
+			decrypted, err := enc.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatalf("Decrypt() failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Error("Decrypt() doesn't match original plaintext")
+			}
+
+			// Payloads stay interoperable with the one-shot API.
+			decrypted, err = util.DecryptValue(key, ciphertext)
+			if err != nil || !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("DecryptValue() = %q, %v; want %q", decrypted, err, plaintext)
+			}
+		})
+	}
+}
+
+func TestEncryptorDecryptsLegacyPayloads(t *testing.T) {
+	key := make([]byte, 16)
+	rand.Read(key)
+
+	legacy := sealLegacyPayload(t, key, []byte("legacy payload"))
+
+	enc, err := util.NewEncryptor(key)
+	if err != nil {
+		t.Fatalf("NewEncryptor() failed: %v", err)
+	}
+	decrypted, err := enc.Decrypt(legacy)
+	if err != nil {
+		t.Fatalf("Decrypt() failed: %v", err)
+	}
+	if string(decrypted) != "legacy payload" {
+		t.Errorf("Decrypt() = %q, want %q", decrypted, "legacy payload")
+	}
+}
+
+func TestEncryptorErrors(t *testing.T) {
+	if _, err := util.NewEncryptor(make([]byte, 10)); err == nil {
+		t.Error("NewEncryptor() should reject an invalid key size")
+	}
+	if _, err := util.NewEncryptor(make([]byte, 64), util.WithEncryptorAlgorithm(util.AlgorithmAESSIV)); err == nil {
+		t.Error("NewEncryptor() should reject the deterministic AES-SIV")
+	}
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	enc, err := util.NewEncryptor(key)
+	if err != nil {
+		t.Fatalf("NewEncryptor() failed: %v", err)
+	}
+	if _, err = enc.Encrypt(nil); err == nil {
+		t.Error("Encrypt() should reject empty plaintext")
+	}
+
+	skipInFIPSMode(t, util.AlgorithmChaCha20Poly1305.String())
+	chacha, err := util.NewEncryptor(key, util.WithEncryptorAlgorithm(util.AlgorithmChaCha20Poly1305))
+	if err != nil {
+		t.Fatalf("NewEncryptor() failed: %v", err)
+	}
+	aesPayload, err := util.EncryptValueWithAlgo(util.AlgorithmAESGCM, key, []byte("data"))
+	if err != nil {
+		t.Fatalf("EncryptValueWithAlgo() failed: %v", err)
+	}
+	if _, err = chacha.Decrypt(aesPayload); err == nil {
+		t.Error("Decrypt() should reject a payload sealed with another algorithm")
+	}
+}
+
+func TestEncryptorEncryptionLimit(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	enc, err := util.NewEncryptor(key, util.WithEncryptorMaxEncryptions(3))
+	if err != nil {
+		t.Fatalf("NewEncryptor() failed: %v", err)
+	}
+	for i := range 3 {
+		if _, err = enc.Encrypt([]byte("counted")); err != nil {
+			t.Fatalf("Encrypt() #%d failed: %v", i+1, err)
+		}
+	}
+	for range 2 {
+		if _, err = enc.Encrypt([]byte("counted")); !errors.Is(err, util.ErrEncryptionLimitReached) {
+			t.Fatalf("Encrypt() past the limit error = %v, want ErrEncryptionLimitReached", err)
+		}
+	}
+
+	// Decryption is unaffected by the limit.
+	payload, _ := util.EncryptValue(key, []byte("still readable"))
+	if _, err = enc.Decrypt(payload); err != nil {
+		t.Errorf("Decrypt() failed after the limit: %v", err)
+	}
+}
+
+// FuzzEncryptorDecrypt checks that Decrypt never panics on malformed version 1
+// and version 2 payloads and never returns anything but the sealed plaintext.
+func FuzzEncryptorDecrypt(f *testing.F) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	plaintext := bytes.Repeat([]byte("fuzz plaintext "), 8)
+
+	enc, err := util.NewEncryptor(key, util.WithEncryptorCompression(util.CompressionZstd))
+	if err != nil {
+		f.Fatal(err)
+	}
+	compressed, _ := enc.Encrypt(plaintext)
+	plain, _ := util.EncryptValue(key, plaintext)
+	f.Add(compressed)
+	f.Add(plain)
+	f.Add([]byte{0x02, 0x01})
+	f.Add([]byte{0x02, 0x01, 0x09})
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		got, decErr := enc.Decrypt(payload)
+		if decErr == nil && !bytes.Equal(got, plaintext) {
+			t.Errorf("Decrypt() returned %q for a forged payload", got)
+		}
+	})
+}
+
+func TestEncryptorConcurrentUse(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	enc, err := util.NewEncryptor(key)
+	if err != nil {
+		t.Fatalf("NewEncryptor() failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				ct, encErr := enc.Encrypt([]byte("concurrent"))
+				if encErr != nil {
+					t.Error(encErr)
+					return
+				}
+				if _, decErr := enc.Decrypt(ct); decErr != nil {
+					t.Error(decErr)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func BenchmarkEncryptorEncryptAES256(b *testing.B) {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	plaintext := make([]byte, 1024)
+	_, _ = rand.Read(plaintext)
+	enc, _ := util.NewEncryptor(key)
+
+	b.ResetTimer()
+	for range b.N {
+		_, _ = enc.Encrypt(plaintext)
+	}
+}
+
+func BenchmarkEncryptorDecryptAES256(b *testing.B) {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	plaintext := make([]byte, 1024)
+	_, _ = rand.Read(plaintext)
+	enc, _ := util.NewEncryptor(key)
+	ciphertext, _ := enc.Encrypt(plaintext)
+
+	b.ResetTimer()
+	for range b.N {
+		_, _ = enc.Decrypt(ciphertext)
+	}
+}
@@ -3,9 +3,15 @@
 package util
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 )
 
+// ErrMissingEnv is returned when required environment variables are unset.
+var ErrMissingEnv = errors.New("missing required environment variables")
+
 // GetEnv Obtains the environment key or returns the first fallback value.
 func GetEnv(key string, fallback ...string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -18,3 +24,105 @@ func GetEnv(key string, fallback ...string) string {
 
 	return ""
 }
+
+// GetEnvAny returns the value of the first of keys that is set, or fallback
+// when none is. List the current name first, so a renamed variable keeps
+// working for deployments that still set the old one.
+//
+// Example:
+//
+//	port := GetEnvAny([]string{"SERVICE_PORT", "PORT"}, "8080")
+func GetEnvAny(keys []string, fallback string) string {
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			return value
+		}
+	}
+	return fallback
+}
+
+// MustGetEnv returns the value of the environment variable key and panics
+// when it is unset or empty, for configuration a service cannot start
+// without. Prefer RequireEnv at startup to report every missing variable at
+// once.
+//
+// Example:
+//
+//	dsn := MustGetEnv("DATABASE_URL")
+func MustGetEnv(key string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		panic(fmt.Sprintf("required environment variable %s is not set", key))
+	}
+	return value
+}
+
+// RequireEnv checks that every environment variable in keys is set to a
+// non-empty value. The error wraps ErrMissingEnv and names all missing
+// variables, so a misconfigured deployment is fixed in one round.
+//
+// Example:
+//
+//	if err := RequireEnv("DATABASE_URL", "REDIS_URL", "JWT_SECRET"); err != nil {
+//	    log.Fatal("invalid configuration", "error", err)
+//	}
+func RequireEnv(keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingEnv, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// GetEnvSlice splits the environment variable key on sep, trimming spaces
+// around elements and dropping empty ones, so "a, b,,c" yields [a b c].
+// When key is unset or holds no elements, fallback is returned.
+//
+// Example:
+//
+//	origins := GetEnvSlice("CORS_ORIGINS", ",", "http://localhost:3000")
+func GetEnvSlice(key, sep string, fallback ...string) []string {
+	if parts := splitEnvList(os.Getenv(key), sep); len(parts) > 0 {
+		return parts
+	}
+	return fallback
+}
+
+// GetEnvMap parses the environment variable key as comma-separated key=value
+// pairs, such as "team=core, tier=1", trimming spaces around keys and values.
+// Entries without = map to "", and later entries override earlier ones. The
+// result is nil when key is unset or empty.
+//
+// Example:
+//
+//	labels := GetEnvMap("RESOURCE_LABELS")
+func GetEnvMap(key string) map[string]string {
+	parts := splitEnvList(os.Getenv(key), ",")
+	if len(parts) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(parts))
+	for _, part := range parts {
+		k, v, _ := strings.Cut(part, "=")
+		if k = strings.TrimSpace(k); k != "" {
+			m[k] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+// splitEnvList splits value on sep, trimming spaces and dropping empty elements.
+func splitEnvList(value, sep string) []string {
+	var parts []string
+	for part := range strings.SplitSeq(value, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,132 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+)
+
+// EnvSpec describes an environment variable a service reads.
+type EnvSpec struct {
+	// Name is the variable name, including any prefix.
+	Name string `json:"name"`
+
+	// Type is the Go type the value is parsed into, such as "int" or "[]string".
+	Type string `json:"type"`
+
+	// Default is used when the variable is unset.
+	Default string `json:"default,omitempty"`
+
+	// Required variables must be set.
+	Required bool `json:"required,omitempty"`
+
+	// Secret values are never printed.
+	Secret bool `json:"secret,omitempty"`
+
+	// Description explains what the variable configures.
+	Description string `json:"description,omitempty"`
+}
+
+// EnvSpecs lists the variables LoadEnv reads for a config struct, from the
+// same `env` tags, so the documentation cannot drift from the loader. A
+// field's Description comes from its `desc` tag. cfg is a struct or a
+// pointer to one; WithEnvPrefix applies as it does to LoadEnv.
+//
+// Example:
+//
+//	type Config struct {
+//	    Port     int    `env:"PORT,default=8080" desc:"HTTP listen port"`
+//	    Password string `env:"DB_PASSWORD,required,secret"`
+//	}
+//
+//	specs, err := EnvSpecs(Config{}, WithEnvPrefix("APP_"))
+func EnvSpecs(cfg any, opts ...EnvLoadOption) ([]EnvSpec, error) {
+	options := envLoadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	t := reflect.TypeOf(cfg)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("EnvSpecs requires a struct or a pointer to one, got %T", cfg)
+	}
+	return appendEnvSpecs(nil, t, options.prefix), nil
+}
+
+func appendEnvSpecs(specs []EnvSpec, t reflect.Type, prefix string) []EnvSpec {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, tagged := field.Tag.Lookup(envTagName)
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		parsed := parseEnvTag(tag)
+		if isEnvNestedStruct(field.Type) {
+			specs = appendEnvSpecs(specs, field.Type, nestedEnvPrefix(prefix, parsed.name))
+			continue
+		}
+		if !tagged || parsed.name == "" {
+			continue
+		}
+		specs = append(specs, EnvSpec{
+			Name:        prefix + parsed.name,
+			Type:        field.Type.String(),
+			Default:     parsed.def,
+			Required:    parsed.required,
+			Secret:      parsed.secret,
+			Description: field.Tag.Get("desc"),
+		})
+	}
+	return specs
+}
+
+// DescribeEnv renders specs as an indented JSON array, for a --describe-env
+// flag, generated documentation or deployment tooling that checks manifests
+// against what a service expects.
+//
+// Example:
+//
+//	if *describeEnv {
+//	    out, _ := DescribeEnv(specs)
+//	    os.Stdout.Write(out)
+//	    return
+//	}
+func DescribeEnv(specs []EnvSpec) ([]byte, error) {
+	if specs == nil {
+		specs = []EnvSpec{}
+	}
+	return json.MarshalIndent(specs, "", "  ")
+}
+
+// LogEnv logs the effective value of every variable in specs at startup, in
+// one entry on the logger in ctx: the value when set, else the default, else
+// "". Set secrets are logged as [REDACTED], so the dump shows whether a
+// secret is configured without revealing it.
+//
+// Example:
+//
+//	specs, _ := EnvSpecs(cfg)
+//	LogEnv(ctx, specs)
+func LogEnv(ctx context.Context, specs []EnvSpec) {
+	options := envLoadOptions{lookup: os.LookupEnv}
+	attrs := make([]any, 0, len(specs))
+	for _, spec := range specs {
+		value, ok, err := options.value(spec.Name)
+		switch {
+		case err != nil:
+			value = "error: " + err.Error()
+		case ok && spec.Secret:
+			value = redacted
+		case !ok:
+			value = spec.Default
+		}
+		attrs = append(attrs, slog.String(spec.Name, value))
+	}
+	Log(ctx).Info("effective configuration", slog.Group("env", attrs...))
+}
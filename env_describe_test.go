@@ -0,0 +1,83 @@
+package util_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type describedConfig struct {
+	Port     int           `env:"PORT,default=8080"          desc:"HTTP listen port"`
+	Password string        `env:"DB_PASSWORD,required,secret"`
+	Token    string        `env:"TOKEN,secret"`
+	Timeout  time.Duration `env:"TIMEOUT"`
+	Cache    struct {
+		Size int `env:"SIZE,default=64"`
+	} `env:"CACHE"`
+	Internal string
+}
+
+func TestEnvSpecs(t *testing.T) {
+	specs, err := util.EnvSpecs(&describedConfig{}, util.WithEnvPrefix("APP_"))
+	if err != nil {
+		t.Fatalf("EnvSpecs: %v", err)
+	}
+	want := []util.EnvSpec{
+		{Name: "APP_PORT", Type: "int", Default: "8080", Description: "HTTP listen port"},
+		{Name: "APP_DB_PASSWORD", Type: "string", Required: true, Secret: true},
+		{Name: "APP_TOKEN", Type: "string", Secret: true},
+		{Name: "APP_TIMEOUT", Type: "time.Duration"},
+		{Name: "APP_CACHE_SIZE", Type: "int", Default: "64"},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("EnvSpecs =\n%+v\nwant\n%+v", specs, want)
+	}
+
+	if _, err := util.EnvSpecs("not a struct"); err == nil {
+		t.Error("EnvSpecs of a string succeeded")
+	}
+}
+
+func TestDescribeEnv(t *testing.T) {
+	specs, _ := util.EnvSpecs(describedConfig{})
+	out, err := util.DescribeEnv(specs)
+	if err != nil {
+		t.Fatalf("DescribeEnv: %v", err)
+	}
+	var decoded []util.EnvSpec
+	if err := json.Unmarshal(out, &decoded); err != nil || !reflect.DeepEqual(decoded, specs) {
+		t.Errorf("DescribeEnv output does not round-trip: %s, %v", out, err)
+	}
+
+	if out, _ := util.DescribeEnv(nil); string(out) != "[]" {
+		t.Errorf("DescribeEnv(nil) = %s, want []", out)
+	}
+}
+
+func TestLogEnv(t *testing.T) {
+	util.SetEnvForTest(t, "DB_PASSWORD", "hunter2")
+	util.SetEnvForTest(t, "TIMEOUT", "3s")
+
+	var buf bytes.Buffer
+	logger := util.NewLogger(t.Context(), util.WithLogHandler(slog.NewTextHandler(&buf, nil)), util.WithLogHandlerExclusive())
+	defer logger.Release()
+
+	specs, _ := util.EnvSpecs(describedConfig{})
+	util.LogEnv(util.ContextWithLogger(t.Context(), logger), specs)
+
+	output := buf.String()
+	for _, want := range []string{"env.PORT=8080", "env.DB_PASSWORD=[REDACTED]", "env.TOKEN=\"\"", "env.TIMEOUT=3s"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("LogEnv output %q does not contain %s", output, want)
+		}
+	}
+	if strings.Contains(output, "hunter2") {
+		t.Error("LogEnv printed a secret")
+	}
+}
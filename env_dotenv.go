@@ -0,0 +1,235 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// defaultDotEnvPath is loaded by LoadDotEnv when no path is given.
+const defaultDotEnvPath = ".env"
+
+// ErrDotEnvSyntax is wrapped by the errors ParseDotEnv returns for malformed input.
+var ErrDotEnvSyntax = errors.New("invalid dotenv syntax")
+
+// LoadDotEnv reads the dotenv files at paths, or ".env" when none are given,
+// into the process environment. Variables already set, whether in the real
+// environment or by an earlier file, are left alone, so deployments can
+// override the files' defaults.
+//
+// Example:
+//
+//	if err := LoadDotEnv(".env.local", ".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+//	    return err
+//	}
+func LoadDotEnv(paths ...string) error {
+	return loadDotEnv(paths, false)
+}
+
+// LoadDotEnvOverride is LoadDotEnv, except that the files override variables
+// already set and later files override earlier ones.
+func LoadDotEnvOverride(paths ...string) error {
+	return loadDotEnv(paths, true)
+}
+
+func loadDotEnv(paths []string, override bool) error {
+	if len(paths) == 0 {
+		paths = []string{defaultDotEnvPath}
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		vars, err := ParseDotEnv(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for key, value := range vars {
+			if _, set := os.LookupEnv(key); set && !override {
+				continue
+			}
+			if err := os.Setenv(key, value); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseDotEnv parses dotenv content into a map of variables. When a variable
+// is assigned more than once, the last assignment wins.
+//
+// Each line holds KEY=VALUE, optionally preceded by "export ". Blank lines
+// and lines starting with # are skipped, as is a # comment after an unquoted
+// value. Single-quoted values are taken literally; double-quoted values
+// interpret \n, \r, \t, \" and \\ escapes. Quoted values may span lines.
+//
+// Errors wrap ErrDotEnvSyntax and name the line they occur on.
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := dotEnvParser{src: strings.ReplaceAll(string(content), "\r\n", "\n"), line: 1}
+
+	vars := map[string]string{}
+	for {
+		key, value, ok, err := p.next()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+		if !ok {
+			return vars, nil
+		}
+		vars[key] = value
+	}
+}
+
+// dotEnvParser walks dotenv content, tracking the current line.
+type dotEnvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// next returns the next assignment, or false at the end of the input.
+func (p *dotEnvParser) next() (string, string, bool, error) {
+	for {
+		if p.pos >= len(p.src) {
+			return "", "", false, nil
+		}
+		end := strings.IndexByte(p.src[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.src) - p.pos
+		}
+		line := strings.TrimSpace(p.src[p.pos : p.pos+end])
+		if line != "" && line[0] != '#' {
+			break
+		}
+		p.advance(end + 1)
+	}
+
+	p.skipSpaces()
+	if rest, ok := strings.CutPrefix(p.src[p.pos:], "export "); ok {
+		p.advance(len(p.src) - p.pos - len(rest))
+		p.skipSpaces()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && isDotEnvKeyChar(p.src[p.pos]) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return "", "", false, fmt.Errorf("%w: expected a variable name", ErrDotEnvSyntax)
+	}
+	p.skipSpaces()
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return "", "", false, fmt.Errorf("%w: expected = after %s", ErrDotEnvSyntax, key)
+	}
+	p.pos++
+	p.skipSpaces()
+
+	value, err := p.value()
+	if err != nil {
+		return "", "", false, err
+	}
+	return key, value, true, nil
+}
+
+// value reads the value after =, up to and including the end of its line.
+func (p *dotEnvParser) value() (string, error) {
+	if p.pos < len(p.src) && (p.src[p.pos] == '"' || p.src[p.pos] == '\'') {
+		return p.quoted(p.src[p.pos])
+	}
+
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	value := p.src[p.pos : p.pos+end]
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = value[:i]
+	}
+	p.advance(end + 1)
+	return strings.TrimSpace(value), nil
+}
+
+// quoted reads a value in quote characters, which may span lines.
+func (p *dotEnvParser) quoted(quote byte) (string, error) {
+	startLine := p.line
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), p.endOfLine()
+		case c == '\\' && quote == '"' && p.pos+1 < len(p.src):
+			p.pos++
+			b.WriteByte(unescapeDotEnv(p.src[p.pos]))
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+		}
+		p.pos++
+	}
+	p.line = startLine
+	return "", fmt.Errorf("%w: unterminated %c quote", ErrDotEnvSyntax, quote)
+}
+
+// endOfLine allows only spaces and a comment after a closing quote.
+func (p *dotEnvParser) endOfLine() error {
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return nil
+	}
+	end := strings.IndexByte(p.src[p.pos:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	if rest := p.src[p.pos : p.pos+end]; rest != "" && rest[0] != '#' {
+		return fmt.Errorf("%w: unexpected %q after quoted value", ErrDotEnvSyntax, rest)
+	}
+	p.advance(end + 1)
+	return nil
+}
+
+// advance moves n bytes forward, counting the line breaks passed.
+func (p *dotEnvParser) advance(n int) {
+	end := min(p.pos+n, len(p.src))
+	p.line += strings.Count(p.src[p.pos:end], "\n")
+	p.pos = end
+}
+
+func (p *dotEnvParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func isDotEnvKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+func unescapeDotEnv(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	default:
+		return c
+	}
+}
@@ -0,0 +1,110 @@
+package util_test
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	input := `# database settings
+DB_HOST=localhost
+export DB_PORT = 5432
+DB_NAME=app # trailing comment
+DB_URL=postgres://u:p@host/db#frag
+
+SINGLE='literal \n $HOME'
+DOUBLE="tab\there \"quoted\""
+MULTI="first
+second"
+EMPTY=
+dotted.key=1
+`
+	vars, err := util.ParseDotEnv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDotEnv: %v", err)
+	}
+	want := map[string]string{
+		"DB_HOST":    "localhost",
+		"DB_PORT":    "5432",
+		"DB_NAME":    "app",
+		"DB_URL":     "postgres://u:p@host/db#frag",
+		"SINGLE":     `literal \n $HOME`,
+		"DOUBLE":     "tab\there \"quoted\"",
+		"MULTI":      "first\nsecond",
+		"EMPTY":      "",
+		"dotted.key": "1",
+	}
+	if !maps.Equal(vars, want) {
+		t.Errorf("ParseDotEnv =\n%q\nwant\n%q", vars, want)
+	}
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  string
+	}{
+		{"A=1\nnot an assignment\n", "line 2"},
+		{"A=1\n\nB=\"open\nstill open\n", "line 3"},
+		{"A='x' trailing\n", "line 1"},
+		{"=value\n", "line 1"},
+	}
+	for _, tt := range tests {
+		_, err := util.ParseDotEnv(strings.NewReader(tt.input))
+		if !errors.Is(err, util.ErrDotEnvSyntax) || !strings.Contains(err.Error(), tt.line) {
+			t.Errorf("ParseDotEnv(%q) = %v, want a syntax error on %s", tt.input, err, tt.line)
+		}
+	}
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, ".env.local")
+	shared := filepath.Join(dir, ".env")
+	if err := os.WriteFile(local, []byte("UTIL_DOTENV_A=local\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shared, []byte("UTIL_DOTENV_A=shared\nUTIL_DOTENV_B=shared\nUTIL_DOTENV_C=shared\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// t.Setenv restores the variables afterwards, including those unset here.
+	for _, key := range []string{"UTIL_DOTENV_A", "UTIL_DOTENV_B"} {
+		t.Setenv(key, "")
+		if err := os.Unsetenv(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("UTIL_DOTENV_C", "real")
+
+	if err := util.LoadDotEnv(local, shared); err != nil {
+		t.Fatalf("LoadDotEnv: %v", err)
+	}
+	for key, want := range map[string]string{
+		"UTIL_DOTENV_A": "local",
+		"UTIL_DOTENV_B": "shared",
+		"UTIL_DOTENV_C": "real",
+	} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	if err := util.LoadDotEnvOverride(local, shared); err != nil {
+		t.Fatalf("LoadDotEnvOverride: %v", err)
+	}
+	if got := os.Getenv("UTIL_DOTENV_C"); got != "shared" {
+		t.Errorf("UTIL_DOTENV_C after override = %q, want shared", got)
+	}
+
+	if err := util.LoadDotEnv(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadDotEnv of a missing file = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,190 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrInvalidExpansion is returned by ExpandEnv for malformed references.
+var ErrInvalidExpansion = errors.New("invalid variable reference")
+
+// expandEnvOptions contains configuration for ExpandEnv.
+type expandEnvOptions struct {
+	// lookup reads a variable
+	lookup func(string) (string, bool)
+
+	// strict rejects references to unset variables without a default
+	strict bool
+}
+
+// ExpandEnvOption is a function that configures ExpandEnv.
+type ExpandEnvOption func(*expandEnvOptions)
+
+// WithExpandLookup overrides how ExpandEnv reads variables, e.g. to expand
+// from a map parsed with ParseDotEnv. The default is os.LookupEnv.
+func WithExpandLookup(lookup func(string) (string, bool)) ExpandEnvOption {
+	return func(o *expandEnvOptions) {
+		o.lookup = lookup
+	}
+}
+
+// WithExpandStrict makes references to unset variables without a default an
+// error wrapping ErrMissingEnv, instead of expanding to "".
+func WithExpandStrict() ExpandEnvOption {
+	return func(o *expandEnvOptions) {
+		o.strict = true
+	}
+}
+
+// ExpandEnv replaces variable references in s with values from the
+// environment, using the bash forms:
+//
+//	$VAR, ${VAR}     the value of VAR
+//	${VAR:-default}  default when VAR is unset or empty
+//	${VAR-default}   default when VAR is unset
+//	${VAR:?message}  an error with message when VAR is unset or empty
+//	${VAR?message}   an error with message when VAR is unset
+//
+// Defaults may themselves contain references. $$ and \$ produce a literal $.
+// Unlike os.ExpandEnv it reports errors, which wrap ErrMissingEnv for
+// ${VAR:?message} and strict mode, and ErrInvalidExpansion for malformed
+// references such as an unclosed brace.
+//
+// Example:
+//
+//	dsn, err := ExpandEnv("postgres://${DB_USER:-app}:${DB_PASSWORD:?is required}@${DB_HOST:-localhost}/app")
+func ExpandEnv(s string, opts ...ExpandEnvOption) (string, error) {
+	options := expandEnvOptions{lookup: os.LookupEnv}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options.expand(s)
+}
+
+func (o *expandEnvOptions) expand(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c == '\\' || c == '$') && i+1 < len(s) && s[i+1] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
+		}
+		if c != '$' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("%w: unclosed ${ at offset %d", ErrInvalidExpansion, i)
+			}
+			value, err := o.expandBraced(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+			continue
+		}
+
+		end := i + 1
+		for end < len(s) && isEnvNameChar(s[end], end == i+1) {
+			end++
+		}
+		if end == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		value, err := o.variable(s[i+1 : end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		i = end - 1
+	}
+	return b.String(), nil
+}
+
+// expandBraced expands the inside of ${...}.
+func (o *expandEnvOptions) expandBraced(ref string) (string, error) {
+	nameEnd := 0
+	for nameEnd < len(ref) && isEnvNameChar(ref[nameEnd], nameEnd == 0) {
+		nameEnd++
+	}
+	name, rest := ref[:nameEnd], ref[nameEnd:]
+	if name == "" {
+		return "", fmt.Errorf("%w: ${%s}", ErrInvalidExpansion, ref)
+	}
+
+	colon := strings.HasPrefix(rest, ":")
+	op := strings.TrimPrefix(rest, ":")
+	if op == "" {
+		if colon {
+			return "", fmt.Errorf("%w: ${%s}", ErrInvalidExpansion, ref)
+		}
+		return o.variable(name)
+	}
+
+	value, ok := o.lookup(name)
+	unset := !ok || (colon && value == "")
+	switch op[0] {
+	case '-':
+		if unset {
+			return o.expand(op[1:])
+		}
+		return value, nil
+	case '?':
+		if unset {
+			message, err := o.expand(op[1:])
+			if err != nil {
+				return "", err
+			}
+			if message == "" {
+				message = "is not set"
+			}
+			return "", fmt.Errorf("%w: %s %s", ErrMissingEnv, name, message)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("%w: ${%s}", ErrInvalidExpansion, ref)
+	}
+}
+
+// variable returns the value of name, or "" when it is unset outside strict mode.
+func (o *expandEnvOptions) variable(name string) (string, error) {
+	value, ok := o.lookup(name)
+	if ok {
+		return value, nil
+	}
+	if o.strict {
+		return "", fmt.Errorf("%w: %s", ErrMissingEnv, name)
+	}
+	return "", nil
+}
+
+// matchingBrace returns the index of the } closing the { at open, or -1.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isEnvNameChar reports whether c may appear in a variable name; digits may
+// not start one.
+func isEnvNameChar(c byte, first bool) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || !first && c >= '0' && c <= '9'
+}
@@ -0,0 +1,86 @@
+package util_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{
+		"USER":  "app",
+		"HOST":  "db.internal",
+		"EMPTY": "",
+		"PORT1": "5432",
+	}
+	lookup := lookupExpand(env)
+
+	tests := []struct {
+		input, want string
+	}{
+		{"plain text", "plain text"},
+		{"$USER@$HOST", "app@db.internal"},
+		{"${USER}_suffix", "app_suffix"},
+		{"port=$PORT1", "port=5432"},
+		{"${MISSING}", ""},
+		{"${MISSING:-fallback}", "fallback"},
+		{"${EMPTY:-fallback}", "fallback"},
+		{"${EMPTY-fallback}", ""},
+		{"${MISSING-fallback}", "fallback"},
+		{"${MISSING:-${USER}-${HOST:-x}}", "app-db.internal"},
+		{"${HOST:?must be set}", "db.internal"},
+		{"cost: $$5 or \\$6", "cost: $5 or $6"},
+		{"trailing $", "trailing $"},
+		{"$1 and $-", "$1 and $-"},
+	}
+	for _, tt := range tests {
+		got, err := util.ExpandEnv(tt.input, lookup)
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandEnvErrors(t *testing.T) {
+	lookup := lookupExpand(map[string]string{"EMPTY": ""})
+
+	_, err := util.ExpandEnv("${PASSWORD:?is required for the database}", lookup)
+	if !errors.Is(err, util.ErrMissingEnv) || !strings.Contains(err.Error(), "PASSWORD is required for the database") {
+		t.Errorf("ExpandEnv with ${VAR:?} = %v", err)
+	}
+	if _, err = util.ExpandEnv("${EMPTY?set}", lookup); err != nil {
+		t.Errorf("ExpandEnv of ${EMPTY?} = %v, want no error for a set variable", err)
+	}
+	if _, err = util.ExpandEnv("${EMPTY:?}", lookup); !errors.Is(err, util.ErrMissingEnv) {
+		t.Errorf("ExpandEnv of ${EMPTY:?} = %v, want ErrMissingEnv", err)
+	}
+
+	for _, input := range []string{"${UNCLOSED", "${}", "${1X}", "${A:}", "${A+x}"} {
+		if _, err := util.ExpandEnv(input, lookup); !errors.Is(err, util.ErrInvalidExpansion) {
+			t.Errorf("ExpandEnv(%q) = %v, want ErrInvalidExpansion", input, err)
+		}
+	}
+
+	if _, err := util.ExpandEnv("$MISSING", lookup, util.WithExpandStrict()); !errors.Is(err, util.ErrMissingEnv) {
+		t.Errorf("strict ExpandEnv of an unset variable = %v, want ErrMissingEnv", err)
+	}
+	if got, err := util.ExpandEnv("${MISSING:-ok}", lookup, util.WithExpandStrict()); err != nil || got != "ok" {
+		t.Errorf("strict ExpandEnv with a default = %q, %v", got, err)
+	}
+}
+
+func TestExpandEnvFromProcess(t *testing.T) {
+	t.Setenv("UTIL_EXPAND_TEST", "value")
+	if got, err := util.ExpandEnv("x=${UTIL_EXPAND_TEST}"); err != nil || got != "x=value" {
+		t.Errorf("ExpandEnv = %q, %v", got, err)
+	}
+}
+
+func lookupExpand(env map[string]string) util.ExpandEnvOption {
+	return util.WithExpandLookup(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+}
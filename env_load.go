@@ -0,0 +1,300 @@
+package util
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	envTagName       = "env"
+	envDefaultOption = "default="
+	envFileSuffix    = "_FILE"
+)
+
+// envLoadOptions contains configuration for LoadEnv.
+type envLoadOptions struct {
+	// prefix is prepended to every variable name
+	prefix string
+
+	// lookup reads a variable
+	lookup func(string) (string, bool)
+}
+
+// EnvLoadOption is a function that configures LoadEnv.
+type EnvLoadOption func(*envLoadOptions)
+
+// WithEnvPrefix prepends prefix to every variable name LoadEnv reads, so
+// `env:"PORT"` reads APP_PORT with WithEnvPrefix("APP_").
+func WithEnvPrefix(prefix string) EnvLoadOption {
+	return func(o *envLoadOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithEnvLookup overrides how LoadEnv reads variables, e.g. from a map in
+// tests. The default is os.LookupEnv.
+func WithEnvLookup(lookup func(string) (string, bool)) EnvLoadOption {
+	return func(o *envLoadOptions) {
+		o.lookup = lookup
+	}
+}
+
+// textUnmarshalerType is used to detect fields that parse themselves.
+//
+//nolint:gochecknoglobals // immutable type descriptor
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// LoadEnv populates the struct cfg points to from environment variables
+// named by `env` field tags:
+//
+//	type Config struct {
+//	    Port     int               `env:"PORT,default=8080"`
+//	    Timeout  time.Duration     `env:"TIMEOUT,default=5s"`
+//	    Hosts    []string          `env:"HOSTS,default=a,b"`
+//	    Labels   map[string]string `env:"LABELS"` // "team=core,tier=1"
+//	    Password string            `env:"DB_PASSWORD,required"`
+//	    Cache    CacheConfig       `env:"CACHE"` // reads CACHE_SIZE, CACHE_TTL, ...
+//	}
+//
+// The tag holds the variable name followed by the options required, secret
+// (see EnvSpecs) and default=value; default must come last as its value may
+// contain commas.
+// When a variable is unset, its _FILE companion (DB_PASSWORD_FILE) is read
+// instead, so secrets can be mounted as files. Nested structs with a tag
+// prefix their fields' names with the tag and an underscore; untagged
+// nested structs are loaded with the same prefix.
+//
+// Supported field types are strings, booleans, integers, floats,
+// time.Duration (parsed by ParseDurationExtended, so "7d" works),
+// encoding.TextUnmarshaler implementations, pointers to
+// these, and comma-separated slices and maps of them. Fields without a tag
+// are left untouched.
+//
+// All problems are reported together: the error joins one error per field,
+// with missing required variables wrapping ErrMissingEnv.
+//
+// Example:
+//
+//	var cfg Config
+//	if err := LoadEnv(&cfg, WithEnvPrefix("APP_")); err != nil {
+//	    log.Fatal("invalid configuration", "error", err)
+//	}
+func LoadEnv(cfg any, opts ...EnvLoadOption) error {
+	options := envLoadOptions{lookup: os.LookupEnv}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("LoadEnv requires a non-nil pointer to a struct, got %T", cfg)
+	}
+	return errors.Join(options.loadStruct(v.Elem(), options.prefix)...)
+}
+
+func (o *envLoadOptions) loadStruct(v reflect.Value, prefix string) []error {
+	var errs []error
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, tagged := field.Tag.Lookup(envTagName)
+		if tag == "-" {
+			continue
+		}
+		parsed := parseEnvTag(tag)
+		fv := v.Field(i)
+
+		if isEnvNestedStruct(field.Type) {
+			errs = append(errs, o.loadStruct(fv, nestedEnvPrefix(prefix, parsed.name))...)
+			continue
+		}
+		if !tagged || parsed.name == "" {
+			continue
+		}
+
+		if err := o.loadField(fv, prefix+parsed.name, parsed); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// loadField sets v from the variable key, or from the tag's default when key is unset.
+func (o *envLoadOptions) loadField(v reflect.Value, key string, tag envTag) error {
+	value, ok, err := o.value(key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		switch {
+		case tag.hasDefault:
+			value = tag.def
+		case tag.required:
+			return fmt.Errorf("%w: %s", ErrMissingEnv, key)
+		default:
+			return nil
+		}
+	}
+	if err := setEnvValue(v, value); err != nil {
+		return fmt.Errorf("environment variable %s: %w", key, err)
+	}
+	return nil
+}
+
+// isEnvNestedStruct reports whether LoadEnv loads the fields of t rather
+// than t itself.
+func isEnvNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// nestedEnvPrefix returns the prefix of the fields of a nested struct.
+func nestedEnvPrefix(prefix, name string) string {
+	if name == "" {
+		return prefix
+	}
+	return prefix + name + "_"
+}
+
+// value reads key, falling back to the file named by key_FILE. Empty
+// variables count as unset.
+func (o *envLoadOptions) value(key string) (string, bool, error) {
+	if value, ok := o.lookup(key); ok && value != "" {
+		return value, true, nil
+	}
+	path, ok := o.lookup(key + envFileSuffix)
+	if !ok || path == "" {
+		return "", false, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("environment variable %s%s: %w", key, envFileSuffix, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), true, nil
+}
+
+// envTag is a parsed `env` struct tag.
+type envTag struct {
+	name       string
+	def        string
+	hasDefault bool
+	required   bool
+	secret     bool
+}
+
+// parseEnvTag splits a tag into the variable name and its options.
+func parseEnvTag(tag string) envTag {
+	name, rest, _ := strings.Cut(tag, ",")
+	parsed := envTag{name: name}
+	for rest != "" {
+		if def, ok := strings.CutPrefix(rest, envDefaultOption); ok {
+			parsed.def, parsed.hasDefault = def, true
+			break
+		}
+		var option string
+		option, rest, _ = strings.Cut(rest, ",")
+		switch option {
+		case "required":
+			parsed.required = true
+		case "secret":
+			parsed.secret = true
+		}
+	}
+	return parsed
+}
+
+func setEnvValue(v reflect.Value, value string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		u, _ := v.Addr().Interface().(encoding.TextUnmarshaler)
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch v.Kind() { //nolint:exhaustive // scalars are handled by setEnvScalar
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := setEnvValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Slice:
+		parts := splitEnvList(value, ",")
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setEnvValue(slice.Index(i), part); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range splitEnvList(value, ",") {
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("map entry %q is not key=value", pair)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			if err := setEnvValue(key, strings.TrimSpace(k)); err != nil {
+				return err
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setEnvValue(elem, strings.TrimSpace(val)); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	default:
+		return setEnvScalar(v, value)
+	}
+	return nil
+}
+
+func setEnvScalar(v reflect.Value, value string) error {
+	switch v.Kind() { //nolint:exhaustive // the remaining kinds are unsupported
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeFor[time.Duration]() {
+			d, err := ParseDurationExtended(value)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package util_test
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type cacheConfig struct {
+	Size int           `env:"SIZE,default=128"`
+	TTL  time.Duration `env:"TTL"`
+}
+
+type testConfig struct {
+	Name     string            `env:"NAME,required"`
+	Port     int               `env:"PORT,default=8080"`
+	Debug    bool              `env:"DEBUG"`
+	Ratio    float64           `env:"RATIO"`
+	Timeout  time.Duration     `env:"TIMEOUT,default=5s"`
+	Hosts    []string          `env:"HOSTS,default=a, b,,c"`
+	Ports    []uint16          `env:"PORTS"`
+	Labels   map[string]string `env:"LABELS"`
+	Password string            `env:"PASSWORD"`
+	Bind     netip.Addr        `env:"BIND"`
+	Limit    *int              `env:"LIMIT"`
+	Cache    cacheConfig       `env:"CACHE"`
+	Untagged string
+	Skipped  string `env:"-"`
+}
+
+func lookupFrom(env map[string]string) util.EnvLoadOption {
+	return util.WithEnvLookup(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+}
+
+func TestLoadEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"APP_NAME":          "billing",
+		"APP_DEBUG":         "true",
+		"APP_RATIO":         "0.25",
+		"APP_PORTS":         "80,443",
+		"APP_LABELS":        "team=core, tier = 1",
+		"APP_PASSWORD_FILE": secret,
+		"APP_BIND":          "10.0.0.1",
+		"APP_LIMIT":         "7",
+		"APP_CACHE_TTL":     "1d",
+		"APP_SKIPPED":       "ignored",
+	}
+	cfg := testConfig{Untagged: "kept"}
+	if err := util.LoadEnv(&cfg, util.WithEnvPrefix("APP_"), lookupFrom(env)); err != nil {
+		t.Fatalf("LoadEnv: %v", err)
+	}
+
+	limit := 7
+	want := testConfig{
+		Name:     "billing",
+		Port:     8080,
+		Debug:    true,
+		Ratio:    0.25,
+		Timeout:  5 * time.Second,
+		Hosts:    []string{"a", "b", "c"},
+		Ports:    []uint16{80, 443},
+		Labels:   map[string]string{"team": "core", "tier": "1"},
+		Password: "s3cret",
+		Bind:     netip.MustParseAddr("10.0.0.1"),
+		Limit:    &limit,
+		Cache:    cacheConfig{Size: 128, TTL: 24 * time.Hour},
+		Untagged: "kept",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadEnv =\n%+v\nwant\n%+v", cfg, want)
+	}
+}
+
+func TestLoadEnvReportsAllErrors(t *testing.T) {
+	env := map[string]string{
+		"PORT":      "http",
+		"LABELS":    "broken",
+		"CACHE_TTL": "soon",
+	}
+	var cfg testConfig
+	err := util.LoadEnv(&cfg, lookupFrom(env))
+	if !errors.Is(err, util.ErrMissingEnv) {
+		t.Errorf("LoadEnv = %v, want ErrMissingEnv for NAME", err)
+	}
+	for _, name := range []string{"NAME", "PORT", "LABELS", "CACHE_TTL"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("LoadEnv error %v does not mention %s", err, name)
+		}
+	}
+
+	if err := util.LoadEnv(cfg); err == nil {
+		t.Error("LoadEnv of a non-pointer succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+package util_test
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestMustGetEnv(t *testing.T) {
+	t.Setenv("UTIL_TEST_SET", "value")
+	if got := util.MustGetEnv("UTIL_TEST_SET"); got != "value" {
+		t.Errorf("MustGetEnv = %q, want value", got)
+	}
+
+	t.Setenv("UTIL_TEST_EMPTY", "")
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "UTIL_TEST_EMPTY") {
+			t.Errorf("panic = %q, want it to name the variable", msg)
+		}
+	}()
+	util.MustGetEnv("UTIL_TEST_EMPTY")
+	t.Error("MustGetEnv of an empty variable did not panic")
+}
+
+func TestRequireEnv(t *testing.T) {
+	t.Setenv("UTIL_TEST_A", "a")
+	t.Setenv("UTIL_TEST_B", "")
+
+	if err := util.RequireEnv("UTIL_TEST_A"); err != nil {
+		t.Errorf("RequireEnv = %v", err)
+	}
+
+	err := util.RequireEnv("UTIL_TEST_A", "UTIL_TEST_B", "UTIL_TEST_UNSET")
+	if !errors.Is(err, util.ErrMissingEnv) {
+		t.Fatalf("RequireEnv = %v, want ErrMissingEnv", err)
+	}
+	if !strings.Contains(err.Error(), "UTIL_TEST_B, UTIL_TEST_UNSET") {
+		t.Errorf("RequireEnv error %q does not list every missing variable", err)
+	}
+}
+
+func TestGetEnvSlice(t *testing.T) {
+	t.Setenv("UTIL_TEST_LIST", " a, b,,c ,")
+	if got := util.GetEnvSlice("UTIL_TEST_LIST", ","); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("GetEnvSlice = %q", got)
+	}
+	t.Setenv("UTIL_TEST_PATHS", "/bin:/usr/bin")
+	if got := util.GetEnvSlice("UTIL_TEST_PATHS", ":"); !slices.Equal(got, []string{"/bin", "/usr/bin"}) {
+		t.Errorf("GetEnvSlice with : = %q", got)
+	}
+
+	t.Setenv("UTIL_TEST_BLANK", " , ")
+	for _, key := range []string{"UTIL_TEST_BLANK", "UTIL_TEST_UNSET"} {
+		if got := util.GetEnvSlice(key, ",", "x", "y"); !slices.Equal(got, []string{"x", "y"}) {
+			t.Errorf("GetEnvSlice(%s) = %q, want the fallback", key, got)
+		}
+	}
+	if got := util.GetEnvSlice("UTIL_TEST_UNSET", ","); got != nil {
+		t.Errorf("GetEnvSlice without a fallback = %q, want nil", got)
+	}
+}
+
+func TestGetEnvMap(t *testing.T) {
+	t.Setenv("UTIL_TEST_MAP", "team=core, tier = 1,,flag, =orphan,team=platform")
+	want := map[string]string{"team": "platform", "tier": "1", "flag": ""}
+	if got := util.GetEnvMap("UTIL_TEST_MAP"); !maps.Equal(got, want) {
+		t.Errorf("GetEnvMap = %q, want %q", got, want)
+	}
+	if got := util.GetEnvMap("UTIL_TEST_UNSET"); got != nil {
+		t.Errorf("GetEnvMap of an unset variable = %q, want nil", got)
+	}
+}
+
+func TestGetEnvAny(t *testing.T) {
+	t.Setenv("UTIL_TEST_OLD_PORT", "9000")
+	keys := []string{"UTIL_TEST_NEW_PORT", "UTIL_TEST_OLD_PORT"}
+	if got := util.GetEnvAny(keys, "8080"); got != "9000" {
+		t.Errorf("GetEnvAny = %q, want the old name's value", got)
+	}
+
+	t.Setenv("UTIL_TEST_NEW_PORT", "7000")
+	if got := util.GetEnvAny(keys, "8080"); got != "7000" {
+		t.Errorf("GetEnvAny = %q, want the new name's value", got)
+	}
+
+	if got := util.GetEnvAny([]string{"UTIL_TEST_UNSET"}, "8080"); got != "8080" {
+		t.Errorf("GetEnvAny = %q, want the fallback", got)
+	}
+}
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvTestingT is the part of testing.TB used by SetEnvForTest, so this
+// package does not import testing.
+type EnvTestingT interface {
+	Helper()
+	Name() string
+	Cleanup(f func())
+	Fatalf(format string, args ...any)
+}
+
+// envTestLock serializes tests that change the environment. A test holding
+// it lets its subtests in through its own child lock, so subtests of a test
+// that set variables can set more, but only one at a time.
+type envTestLock struct {
+	// holders maps test names to the lock their subtests take
+	holders map[string]*sync.Mutex
+	mu      sync.Mutex
+	root    sync.Mutex
+}
+
+//nolint:gochecknoglobals // the environment is process-wide
+var envTestLocks = envTestLock{holders: map[string]*sync.Mutex{}}
+
+// acquire makes t hold the environment until it finishes, unless it already does.
+func (l *envTestLock) acquire(t EnvTestingT) {
+	name := t.Name()
+	l.mu.Lock()
+	if _, held := l.holders[name]; held {
+		l.mu.Unlock()
+		return
+	}
+	parent := &l.root
+	for ancestor := name; ; {
+		i := strings.LastIndexByte(ancestor, '/')
+		if i < 0 {
+			break
+		}
+		ancestor = ancestor[:i]
+		if child, ok := l.holders[ancestor]; ok {
+			parent = child
+			break
+		}
+	}
+	l.mu.Unlock()
+
+	parent.Lock()
+	l.mu.Lock()
+	l.holders[name] = &sync.Mutex{}
+	l.mu.Unlock()
+
+	t.Cleanup(func() {
+		l.mu.Lock()
+		delete(l.holders, name)
+		l.mu.Unlock()
+		parent.Unlock()
+	})
+}
+
+// SetEnvForTest sets the environment variable key to value for the rest of
+// t and restores its previous value, or unsets it, when t finishes.
+//
+// Unlike testing.T.Setenv it may be used in parallel tests: tests calling it
+// run one at a time from the first call until they finish, while other
+// parallel tests proceed. A test's subtests may call it too, one at a time.
+// Tests that read the environment without calling it are not serialized.
+//
+// Example:
+//
+//	func TestConfig(t *testing.T) {
+//	    t.Parallel()
+//	    util.SetEnvForTest(t, "DATABASE_URL", "postgres://localhost/test")
+//	    ...
+//	}
+func SetEnvForTest(t EnvTestingT, key, value string) {
+	t.Helper()
+	envTestLocks.acquire(t)
+
+	previous, wasSet := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("setting %s: %v", key, err)
+	}
+	t.Cleanup(func() {
+		if wasSet {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+// EnvState is a copy of the process environment taken by EnvSnapshot.
+type EnvState struct {
+	vars map[string]string
+}
+
+// EnvSnapshot copies the current environment, so a test or tool that changes
+// variables can put everything back with Restore.
+//
+// Example:
+//
+//	snapshot := util.EnvSnapshot()
+//	defer snapshot.Restore()
+//	_ = util.LoadDotEnv("testdata/.env")
+func EnvSnapshot() *EnvState {
+	vars := map[string]string{}
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok && key != "" {
+			vars[key] = value
+		}
+	}
+	return &EnvState{vars: vars}
+}
+
+// Restore returns the environment to the snapshot, unsetting variables added
+// since and resetting the ones changed or removed.
+func (s *EnvState) Restore() error {
+	var errs []error
+	for _, entry := range os.Environ() {
+		key, _, _ := strings.Cut(entry, "=")
+		if _, ok := s.vars[key]; !ok && key != "" {
+			errs = append(errs, os.Unsetenv(key))
+		}
+	}
+	for key, value := range s.vars {
+		if current, ok := os.LookupEnv(key); !ok || current != value {
+			errs = append(errs, os.Setenv(key, value))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,63 @@
+package util_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSetEnvForTest(t *testing.T) {
+	const key = "UTIL_TEST_SCOPED"
+
+	t.Run("group", func(t *testing.T) {
+		for _, value := range []string{"a", "b", "c"} {
+			t.Run(value, func(t *testing.T) {
+				t.Parallel()
+				util.SetEnvForTest(t, key, value)
+				time.Sleep(5 * time.Millisecond)
+				if got := os.Getenv(key); got != value {
+					t.Errorf("%s = %q while the test holds it, want %q", key, got, value)
+				}
+			})
+		}
+	})
+	if _, set := os.LookupEnv(key); set {
+		t.Errorf("%s is still set after the subtests", key)
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		util.SetEnvForTest(t, key, "outer")
+		util.SetEnvForTest(t, key, "again")
+		t.Run("inner", func(t *testing.T) {
+			util.SetEnvForTest(t, key, "inner")
+		})
+		if got := os.Getenv(key); got != "again" {
+			t.Errorf("%s = %q after the subtest, want again", key, got)
+		}
+	})
+}
+
+func TestEnvSnapshot(t *testing.T) {
+	util.SetEnvForTest(t, "UTIL_TEST_KEPT", "original")
+	util.SetEnvForTest(t, "UTIL_TEST_REMOVED", "present")
+
+	snapshot := util.EnvSnapshot()
+	_ = os.Setenv("UTIL_TEST_KEPT", "changed")
+	_ = os.Unsetenv("UTIL_TEST_REMOVED")
+	_ = os.Setenv("UTIL_TEST_ADDED", "new")
+
+	if err := snapshot.Restore(); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := os.Getenv("UTIL_TEST_KEPT"); got != "original" {
+		t.Errorf("UTIL_TEST_KEPT = %q, want original", got)
+	}
+	if got := os.Getenv("UTIL_TEST_REMOVED"); got != "present" {
+		t.Errorf("UTIL_TEST_REMOVED = %q, want present", got)
+	}
+	if _, set := os.LookupEnv("UTIL_TEST_ADDED"); set {
+		t.Error("UTIL_TEST_ADDED is still set")
+	}
+}
@@ -0,0 +1,317 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// ErrorCode classifies an Error by what the caller can do about it. The
+// codes are those of gRPC, so they map one to one to gRPC status codes and
+// by the usual conventions to HTTP status codes. An ErrorCode is itself an
+// error, so errors.Is(err, CodeNotFound) reports whether err has that code.
+type ErrorCode string
+
+const (
+	// CodeCanceled means the caller canceled the operation.
+	CodeCanceled ErrorCode = "canceled"
+	// CodeUnknown means the error could not be classified.
+	CodeUnknown ErrorCode = "unknown"
+	// CodeInvalidArgument means the request is malformed, whatever the state
+	// of the system.
+	CodeInvalidArgument ErrorCode = "invalid_argument"
+	// CodeDeadlineExceeded means the operation did not finish in time.
+	CodeDeadlineExceeded ErrorCode = "deadline_exceeded"
+	// CodeNotFound means a requested entity does not exist.
+	CodeNotFound ErrorCode = "not_found"
+	// CodeAlreadyExists means an entity to create already exists.
+	CodeAlreadyExists ErrorCode = "already_exists"
+	// CodePermissionDenied means the caller may not perform the operation.
+	CodePermissionDenied ErrorCode = "permission_denied"
+	// CodeResourceExhausted means a quota or rate limit was reached.
+	CodeResourceExhausted ErrorCode = "resource_exhausted"
+	// CodeFailedPrecondition means the system is not in the state the
+	// operation requires.
+	CodeFailedPrecondition ErrorCode = "failed_precondition"
+	// CodeAborted means the operation conflicted with another one, and may
+	// be retried from the start.
+	CodeAborted ErrorCode = "aborted"
+	// CodeOutOfRange means a value is past the valid range, such as a page
+	// after the last.
+	CodeOutOfRange ErrorCode = "out_of_range"
+	// CodeUnimplemented means the operation is not supported.
+	CodeUnimplemented ErrorCode = "unimplemented"
+	// CodeInternal means a bug or an unexpected failure.
+	CodeInternal ErrorCode = "internal"
+	// CodeUnavailable means the service is temporarily unavailable, and the
+	// operation may be retried.
+	CodeUnavailable ErrorCode = "unavailable"
+	// CodeDataLoss means data was lost or corrupted.
+	CodeDataLoss ErrorCode = "data_loss"
+	// CodeUnauthenticated means the caller's credentials are missing or
+	// invalid.
+	CodeUnauthenticated ErrorCode = "unauthenticated"
+)
+
+// errorCodeMapping is the HTTP status and gRPC code of an ErrorCode.
+type errorCodeMapping struct {
+	httpStatus int
+	grpcCode   uint32
+}
+
+// errorCodeMappings follows the mapping of google.rpc.Code to HTTP.
+var errorCodeMappings = map[ErrorCode]errorCodeMapping{ //nolint:gochecknoglobals // constant lookup table
+	CodeCanceled:           {httpStatus: 499, grpcCode: 1},
+	CodeUnknown:            {httpStatus: http.StatusInternalServerError, grpcCode: 2},
+	CodeInvalidArgument:    {httpStatus: http.StatusBadRequest, grpcCode: 3},
+	CodeDeadlineExceeded:   {httpStatus: http.StatusGatewayTimeout, grpcCode: 4},
+	CodeNotFound:           {httpStatus: http.StatusNotFound, grpcCode: 5},
+	CodeAlreadyExists:      {httpStatus: http.StatusConflict, grpcCode: 6},
+	CodePermissionDenied:   {httpStatus: http.StatusForbidden, grpcCode: 7},
+	CodeResourceExhausted:  {httpStatus: http.StatusTooManyRequests, grpcCode: 8},
+	CodeFailedPrecondition: {httpStatus: http.StatusBadRequest, grpcCode: 9},
+	CodeAborted:            {httpStatus: http.StatusConflict, grpcCode: 10},
+	CodeOutOfRange:         {httpStatus: http.StatusBadRequest, grpcCode: 11},
+	CodeUnimplemented:      {httpStatus: http.StatusNotImplemented, grpcCode: 12},
+	CodeInternal:           {httpStatus: http.StatusInternalServerError, grpcCode: 13},
+	CodeUnavailable:        {httpStatus: http.StatusServiceUnavailable, grpcCode: 14},
+	CodeDataLoss:           {httpStatus: http.StatusInternalServerError, grpcCode: 15},
+	CodeUnauthenticated:    {httpStatus: http.StatusUnauthorized, grpcCode: 16},
+}
+
+// Error implements the error interface.
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
+// HTTPStatus returns the HTTP status code for c, 500 for an unknown code.
+func (c ErrorCode) HTTPStatus() int {
+	if m, ok := errorCodeMappings[c]; ok {
+		return m.httpStatus
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the numeric gRPC status code for c, as defined by
+// google.golang.org/grpc/codes, and 2 (Unknown) for an unknown code.
+func (c ErrorCode) GRPCCode() uint32 {
+	if m, ok := errorCodeMappings[c]; ok {
+		return m.grpcCode
+	}
+	return errorCodeMappings[CodeUnknown].grpcCode
+}
+
+// maxErrorStackDepth is the number of frames an Error keeps.
+const maxErrorStackDepth = 32
+
+// Error is an application error with a code, a message that is safe to show
+// to users, internal detail that is only logged, the error it wraps and
+// the stack where it was created. ProblemResponse turns it into an HTTP
+// response and grpcx into a gRPC status, both showing only the code and
+// the message, while logging it with WithError records everything.
+//
+// Example:
+//
+//	order, err := store.Get(ctx, id)
+//	if errors.Is(err, sql.ErrNoRows) {
+//	    return nil, NewError(CodeNotFound, "order not found").WithDetail("order %s", id)
+//	}
+//	if err != nil {
+//	    return nil, WrapError(err, CodeUnavailable, "orders are unavailable, try again later")
+//	}
+type Error struct {
+	// Code classifies the error.
+	Code ErrorCode
+
+	// Message is the user-safe description of the error.
+	Message string
+
+	// Detail is internal information for logs, never shown to users.
+	Detail string
+
+	// Err is the wrapped cause, if any.
+	Err error
+
+	// Stack is the stack trace where the Error was created.
+	Stack string
+}
+
+// NewError returns an Error with code and the user-safe message, capturing
+// the caller's stack.
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message, Stack: callerStack()}
+}
+
+// WrapError returns an Error with code and the user-safe message wrapping
+// err, capturing the caller's stack. It returns nil when err is nil.
+func WrapError(err error, code ErrorCode, message string) *Error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: message, Err: err, Stack: callerStack()}
+}
+
+// WithDetail sets the internal detail of e to the formatted string and
+// returns e.
+func (e *Error) WithDetail(format string, args ...any) *Error {
+	e.Detail = fmt.Sprintf(format, args...)
+	return e
+}
+
+// Error implements the error interface. It includes the detail and the
+// wrapped error, so it is meant for logs; show users Message instead.
+func (e *Error) Error() string {
+	parts := []string{string(e.Code)}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	if e.Detail != "" {
+		parts = append(parts, e.Detail)
+	}
+	if e.Err != nil {
+		parts = append(parts, e.Err.Error())
+	}
+	return strings.Join(parts, ": ")
+}
+
+// Unwrap returns the wrapped error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is e's ErrorCode, or an *Error with the same
+// code and, when target has one, the same message.
+func (e *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case ErrorCode:
+		return e.Code == t
+	case *Error:
+		return e.Code == t.Code && (t.Message == "" || e.Message == t.Message)
+	}
+	return false
+}
+
+// LogValue implements slog.LogValuer, logging the code, message, detail,
+// cause and stack as separate attributes.
+func (e *Error) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("code", string(e.Code)), slog.String("message", e.Message)}
+	if e.Detail != "" {
+		attrs = append(attrs, slog.String("detail", e.Detail))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("cause", e.Err.Error()))
+	}
+	if e.Stack != "" {
+		attrs = append(attrs, slog.String("stack", e.Stack))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// ErrorCodeOf returns the code of the first Error or ErrorCode in err's
+// chain. Otherwise context cancellation and deadline errors and
+// ValidationErrors get their natural codes, and other errors CodeInternal.
+// It returns "" for a nil err.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	if appErr, ok := errors.AsType[*Error](err); ok {
+		return appErr.Code
+	}
+	if code, ok := errors.AsType[ErrorCode](err); ok {
+		return code
+	}
+	if _, ok := errors.AsType[*ValidationError](err); ok {
+		return CodeInvalidArgument
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	}
+	return CodeInternal
+}
+
+// ErrorMessageOf returns the user-safe message of the first Error in err's
+// chain with one, or else the HTTP status text of err's code, so that
+// internal error strings never reach users.
+func ErrorMessageOf(err error) string {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if appErr, ok := e.(*Error); ok && appErr.Message != "" {
+			return appErr.Message
+		}
+	}
+	return errorStatusText(ErrorCodeOf(err))
+}
+
+// errorStatusText returns the HTTP status text of code, or the code itself
+// for statuses without one, such as 499.
+func errorStatusText(code ErrorCode) string {
+	if text := http.StatusText(code.HTTPStatus()); text != "" {
+		return text
+	}
+	return string(code)
+}
+
+// Problem is an RFC 9457 problem details object, the body of
+// ProblemResponse.
+type Problem struct {
+	Type   string    `json:"type"`
+	Title  string    `json:"title"`
+	Status int       `json:"status"`
+	Detail string    `json:"detail,omitempty"`
+	Code   ErrorCode `json:"code"`
+}
+
+// ProblemResponse returns an application/problem+json JSONResponse for err,
+// with the HTTP status of its ErrorCodeOf and its user-safe ErrorMessageOf
+// as the detail.
+//
+// Example:
+//
+//	func (h *Handler) OnIncomingRequest(req *http.Request) JSONResponse {
+//	    order, err := h.orders.Get(req.Context(), req.PathValue("id"))
+//	    if err != nil {
+//	        Log(req.Context()).WithError(err).Warn("get order failed")
+//	        return ProblemResponse(err)
+//	    }
+//	    return JSONResponse{Code: http.StatusOK, JSON: order}
+//	}
+func ProblemResponse(err error) JSONResponse {
+	code := ErrorCodeOf(err)
+	if code == "" {
+		code = CodeUnknown
+	}
+	httpStatus := code.HTTPStatus()
+	return JSONResponse{
+		Code: httpStatus,
+		JSON: Problem{
+			Type:   "about:blank",
+			Title:  errorStatusText(code),
+			Status: httpStatus,
+			Detail: ErrorMessageOf(err),
+			Code:   code,
+		},
+		Headers: map[string]any{"Content-Type": "application/problem+json"},
+	}
+}
+
+// callerStack formats the stack of the caller of the function calling it.
+func callerStack() string {
+	pcs := make([]uintptr, maxErrorStackDepth)
+	n := runtime.Callers(3, pcs) //nolint:mnd // skip runtime.Callers, callerStack and its caller
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			return b.String()
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestErrorWrappingAndIs(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("load order: %w",
+		util.WrapError(cause, util.CodeUnavailable, "orders are unavailable").WithDetail("shard %d", 3))
+
+	if !errors.Is(err, cause) || !errors.Is(err, util.CodeUnavailable) || errors.Is(err, util.CodeNotFound) {
+		t.Fatal("errors.Is does not match the cause and code")
+	}
+	if !errors.Is(err, &util.Error{Code: util.CodeUnavailable}) ||
+		errors.Is(err, &util.Error{Code: util.CodeUnavailable, Message: "other"}) {
+		t.Fatal("errors.Is does not match an *Error target by code and message")
+	}
+	appErr, ok := errors.AsType[*util.Error](err)
+	if !ok {
+		t.Fatal("errors.As did not find the *Error")
+	}
+	if want := "unavailable: orders are unavailable: shard 3: connection refused"; appErr.Error() != want {
+		t.Errorf("Error() = %q, want %q", appErr.Error(), want)
+	}
+	if !strings.Contains(appErr.Stack, "TestErrorWrappingAndIs") {
+		t.Errorf("Stack does not start at the caller:\n%s", appErr.Stack)
+	}
+	if util.WrapError(nil, util.CodeInternal, "x") != nil {
+		t.Error("WrapError(nil) is not nil")
+	}
+}
+
+func TestErrorCodeOf(t *testing.T) {
+	for err, want := range map[error]util.ErrorCode{
+		nil:                                   "",
+		util.NewError(util.CodeNotFound, "x"): util.CodeNotFound,
+		util.CodeAborted:                      util.CodeAborted,
+		fmt.Errorf("x: %w", context.Canceled): util.CodeCanceled,
+		context.DeadlineExceeded:              util.CodeDeadlineExceeded,
+		util.ValidateEmail("nope"):            util.CodeInvalidArgument,
+		errors.New("boom"):                    util.CodeInternal,
+	} {
+		if got := util.ErrorCodeOf(err); got != want {
+			t.Errorf("ErrorCodeOf(%v) = %q, want %q", err, got, want)
+		}
+	}
+	if util.CodeNotFound.HTTPStatus() != http.StatusNotFound || util.CodeNotFound.GRPCCode() != 5 {
+		t.Error("CodeNotFound does not map to 404 and gRPC NotFound")
+	}
+	if util.ErrorCode("bogus").HTTPStatus() != http.StatusInternalServerError || util.ErrorCode("bogus").GRPCCode() != 2 {
+		t.Error("an unknown code does not map to 500 and gRPC Unknown")
+	}
+}
+
+func TestProblemResponse(t *testing.T) {
+	handler := util.MakeJSONAPI(util.NewJSONRequestHandler(func(*http.Request) util.JSONResponse {
+		return util.ProblemResponse(util.NewError(util.CodeNotFound, "order not found").WithDetail("secret internals"))
+	}))
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if got := rec.Header().Values("Content-Type"); len(got) != 1 || got[0] != "application/problem+json" {
+		t.Fatalf("Content-Type = %v", got)
+	}
+	var problem util.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	want := util.Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "order not found", Code: util.CodeNotFound}
+	if problem != want {
+		t.Errorf("problem = %+v, want %+v", problem, want)
+	}
+
+	internal, _ := util.ProblemResponse(errors.New("pq: password authentication failed")).JSON.(util.Problem)
+	if internal.Status != 500 || internal.Detail != "Internal Server Error" {
+		t.Errorf("problem for an internal error = %+v", internal)
+	}
+	if canceled, _ := util.ProblemResponse(context.Canceled).JSON.(util.Problem); canceled.Status != 499 || canceled.Title != "canceled" {
+		t.Errorf("problem for a canceled request = %+v", canceled)
+	}
+}
+
+func TestErrorLogValue(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := util.ContextWithLogger(context.Background(), util.NewLogger(context.Background(), util.WithLogOutput(&buf)))
+	err := util.WrapError(errors.New("disk full"), util.CodeDataLoss, "upload failed").WithDetail("bucket b1")
+	util.Log(ctx).WithError(err).Error("store upload")
+
+	for _, want := range []string{"data_loss", "upload failed", "bucket b1", "disk full", "TestErrorLogValue"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("log %q does not contain %q", buf.String(), want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultSubscriberBuffer = 64
+
+// OverflowPolicy decides what publishing does when a subscriber's buffer
+// is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest discards the event being published, keeping the
+	// subscriber's backlog. It is the default.
+	OverflowDropNewest OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest buffered event to make room,
+	// for subscribers that only care about recent state.
+	OverflowDropOldest
+
+	// OverflowBlock makes Publish wait for the subscriber to catch up, for
+	// events that must not be lost; a slow subscriber then slows publishers.
+	OverflowBlock
+)
+
+// subscribeOptions contains configuration for EventBus.Subscribe.
+type subscribeOptions struct {
+	// buffer is the capacity of the subscriber's channel
+	buffer int
+
+	// overflow applies when the channel is full
+	overflow OverflowPolicy
+}
+
+// SubscribeOption is a function that configures a subscription.
+type SubscribeOption func(*subscribeOptions)
+
+// WithSubscriberBuffer sets the capacity of the subscriber's channel. The
+// default is 64.
+func WithSubscriberBuffer(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.buffer = size
+	}
+}
+
+// WithOverflowPolicy sets what happens to events published while the
+// subscriber's channel is full. The default is OverflowDropNewest.
+func WithOverflowPolicy(policy OverflowPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.overflow = policy
+	}
+}
+
+// EventBus is an in-process publish/subscribe bus delivering events of type
+// T by topic, so components can react to each other's events without
+// depending on each other or on an external broker. Each subscriber has its
+// own buffered channel; events are delivered to it in publishing order. An
+// EventBus is safe for concurrent use.
+//
+// Example:
+//
+//	bus := NewEventBus[UserEvent]()
+//	events := bus.Subscribe(ctx, "user.created")
+//	go func() {
+//	    for event := range events {
+//	        sendWelcomeEmail(ctx, event.UserID)
+//	    }
+//	}()
+//
+//	bus.Publish(ctx, "user.created", UserEvent{UserID: id})
+type EventBus[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[*subscription[T]]struct{}
+	closed bool
+}
+
+type subscription[T any] struct {
+	bus     *EventBus[T]
+	topic   string
+	options subscribeOptions
+
+	// done is closed on unsubscribing, releasing blocked publishers
+	done chan struct{}
+	once sync.Once
+
+	// mu guards closed and the registration of senders, which close waits
+	// for before closing ch; the sends themselves happen without it
+	mu      sync.Mutex
+	senders sync.WaitGroup
+	ch      chan T
+	closed  bool
+}
+
+// NewEventBus returns an EventBus without subscribers.
+func NewEventBus[T any]() *EventBus[T] {
+	return &EventBus[T]{topics: make(map[string]map[*subscription[T]]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published to topic from
+// now on. The subscription ends, and the channel is closed, when ctx is done
+// or the bus is closed.
+func (b *EventBus[T]) Subscribe(ctx context.Context, topic string, opts ...SubscribeOption) <-chan T {
+	options := subscribeOptions{buffer: defaultSubscriberBuffer}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	sub := &subscription[T]{
+		bus:     b,
+		topic:   topic,
+		options: options,
+		done:    make(chan struct{}),
+		ch:      make(chan T, max(options.buffer, 0)),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		sub.close()
+		return sub.ch
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*subscription[T]]struct{})
+	}
+	b.topics[topic][sub] = struct{}{}
+	b.mu.Unlock()
+
+	context.AfterFunc(ctx, sub.unsubscribe)
+	return sub.ch
+}
+
+// Publish delivers event to the subscribers of topic according to their
+// overflow policies, and returns the number that received it. It returns
+// early with ctx's error when ctx ends while waiting on a subscriber with
+// OverflowBlock.
+func (b *EventBus[T]) Publish(ctx context.Context, topic string, event T) (int, error) {
+	b.mu.RLock()
+	subs := make([]*subscription[T], 0, len(b.topics[topic]))
+	for sub := range b.topics[topic] {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	delivered := 0
+	for _, sub := range subs {
+		ok, err := sub.send(ctx, event)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// Subscribers returns the number of subscribers of topic.
+func (b *EventBus[T]) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.topics[topic])
+}
+
+// Close ends every subscription, closing the subscribers' channels. Events
+// published afterwards are not delivered.
+func (b *EventBus[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	topics := b.topics
+	b.topics = make(map[string]map[*subscription[T]]struct{})
+	b.mu.Unlock()
+
+	for _, subs := range topics {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+}
+
+// send delivers event to the subscriber, reporting whether it was buffered.
+func (s *subscription[T]) send(ctx context.Context, event T) (bool, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return false, nil
+	}
+	s.senders.Add(1)
+	s.mu.Unlock()
+	defer s.senders.Done()
+
+	sent, _, err := sendWithOverflow(ctx, s.ch, event, s.options.overflow, s.done)
+	return sent, err
+}
+
+func (s *subscription[T]) unsubscribe() {
+	s.bus.mu.Lock()
+	if subs := s.bus.topics[s.topic]; subs != nil {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(s.bus.topics, s.topic)
+		}
+	}
+	s.bus.mu.Unlock()
+	s.close()
+}
+
+func (s *subscription[T]) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		s.senders.Wait()
+		close(s.ch)
+	})
+}
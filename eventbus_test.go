@@ -0,0 +1,116 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := util.NewEventBus[int]()
+	ctx, cancel := context.WithCancel(t.Context())
+	a := bus.Subscribe(ctx, "orders")
+	b := bus.Subscribe(t.Context(), "orders")
+	other := bus.Subscribe(t.Context(), "users")
+
+	if n, err := bus.Publish(t.Context(), "orders", 1); n != 2 || err != nil {
+		t.Errorf("Publish = %d, %v, want 2 subscribers", n, err)
+	}
+	if got, got2 := <-a, <-b; got != 1 || got2 != 1 {
+		t.Errorf("received %d and %d", got, got2)
+	}
+	select {
+	case v := <-other:
+		t.Errorf("subscriber of another topic received %d", v)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-a; ok {
+		t.Error("channel still open after the subscription's context ended")
+	}
+	waitFor(t, func() bool { return bus.Subscribers("orders") == 1 })
+
+	bus.Close()
+	if _, ok := <-b; ok {
+		t.Error("channel still open after Close")
+	}
+	if n, _ := bus.Publish(t.Context(), "orders", 2); n != 0 {
+		t.Errorf("Publish after Close delivered to %d", n)
+	}
+	if _, ok := <-bus.Subscribe(t.Context(), "orders"); ok {
+		t.Error("Subscribe after Close returned an open channel")
+	}
+}
+
+func TestEventBusOverflow(t *testing.T) {
+	bus := util.NewEventBus[int]()
+	newest := bus.Subscribe(t.Context(), "t", util.WithSubscriberBuffer(2))
+	oldest := bus.Subscribe(t.Context(), "t", util.WithSubscriberBuffer(2),
+		util.WithOverflowPolicy(util.OverflowDropOldest))
+	for i := 1; i <= 3; i++ {
+		_, _ = bus.Publish(t.Context(), "t", i)
+	}
+	if a, b := <-newest, <-newest; a != 1 || b != 2 {
+		t.Errorf("OverflowDropNewest kept %d, %d, want 1, 2", a, b)
+	}
+	if a, b := <-oldest, <-oldest; a != 2 || b != 3 {
+		t.Errorf("OverflowDropOldest kept %d, %d, want 2, 3", a, b)
+	}
+}
+
+func TestEventBusBlock(t *testing.T) {
+	bus := util.NewEventBus[string]()
+	events := bus.Subscribe(t.Context(), "t", util.WithSubscriberBuffer(1),
+		util.WithOverflowPolicy(util.OverflowBlock))
+	_, _ = bus.Publish(t.Context(), "t", "first")
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := bus.Publish(ctx, "t", "second"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Publish to a full blocking subscriber = %v", err)
+	}
+
+	go func() { <-events }()
+	if n, err := bus.Publish(t.Context(), "t", "third"); n != 1 || err != nil {
+		t.Errorf("Publish once the subscriber caught up = %d, %v", n, err)
+	}
+}
+
+func TestEventBusConcurrentBlockedPublishers(t *testing.T) {
+	bus := util.NewEventBus[string]()
+	defer bus.Close()
+	bus.Subscribe(t.Context(), "t", util.WithSubscriberBuffer(1), util.WithOverflowPolicy(util.OverflowBlock))
+	_, _ = bus.Publish(t.Context(), "t", "first")
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := bus.Publish(t.Context(), "t", "second")
+		blocked <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	published := make(chan error, 1)
+	go func() {
+		_, err := bus.Publish(ctx, "t", "third")
+		published <- err
+	}()
+	select {
+	case err := <-published:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("second publisher = %v, want a deadline error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second publisher waited on the first blocked one")
+	}
+
+	bus.Close()
+	if err := <-blocked; err != nil {
+		t.Errorf("blocked publisher after Close = %v", err)
+	}
+}
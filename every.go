@@ -0,0 +1,104 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// everyOptions contains configuration for Every.
+type everyOptions struct {
+	// jitter spreads each interval by up to this fraction
+	jitter float64
+
+	// immediate runs the task once before the first interval
+	immediate bool
+
+	// noOverlap skips runs while the previous one is in progress
+	noOverlap bool
+}
+
+// EveryOption is a function that configures Every.
+type EveryOption func(*everyOptions)
+
+// WithJitter spreads each interval randomly by up to fraction of it in
+// either direction, as Jitter does, so replicas started together do not run
+// their jobs in lockstep.
+func WithJitter(fraction float64) EveryOption {
+	return func(o *everyOptions) {
+		o.jitter = fraction
+	}
+}
+
+// WithImmediateStart runs the task as soon as Every is called instead of
+// after the first interval.
+func WithImmediateStart() EveryOption {
+	return func(o *everyOptions) {
+		o.immediate = true
+	}
+}
+
+// WithNoOverlap skips a run when the previous one is still in progress, for
+// tasks that must not run concurrently with themselves.
+func WithNoOverlap() EveryOption {
+	return func(o *everyOptions) {
+		o.noOverlap = true
+	}
+}
+
+// Every runs task every interval until ctx is done, then waits for runs in
+// progress to finish and returns. Each run gets its own goroutine, so a slow
+// run does not delay the schedule. Errors and panics of runs are logged to
+// the logger in ctx. It panics if interval is not positive.
+//
+// Example:
+//
+//	go Every(ctx, time.Minute, func(ctx context.Context) error {
+//	    return sessions.PurgeExpired(ctx)
+//	}, WithJitter(0.1), WithImmediateStart(), WithNoOverlap())
+func Every(ctx context.Context, interval time.Duration, task func(ctx context.Context) error, opts ...EveryOption) {
+	if interval <= 0 {
+		panic("util.Every: interval must be positive")
+	}
+	var options everyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		running atomic.Bool
+	)
+	defer wg.Wait()
+
+	run := func() {
+		if options.noOverlap && !running.CompareAndSwap(false, true) {
+			Log(ctx).Debug("skipping periodic task, previous run still in progress")
+			return
+		}
+		wg.Go(func() {
+			if options.noOverlap {
+				defer running.Store(false)
+			}
+			if err := runTask(ctx, task); err != nil {
+				Log(ctx).WithError(err).Error("periodic task failed")
+			}
+		})
+	}
+
+	if options.immediate {
+		run()
+	}
+	timer := time.NewTimer(Jitter(interval, options.jitter))
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			run()
+			timer.Reset(Jitter(interval, options.jitter))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEvery(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		util.Every(ctx, 5*time.Millisecond, func(context.Context) error {
+			if runs.Add(1) == 2 {
+				panic("recovered and logged")
+			}
+			return errors.New("logged")
+		}, util.WithJitter(0.2))
+	}()
+
+	waitFor(t, func() bool { return runs.Load() >= 3 })
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Every did not return after cancellation")
+	}
+}
+
+func TestEveryImmediateNoOverlap(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	var runs, running, overlaps atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		util.Every(ctx, time.Hour, func(context.Context) error {
+			runs.Add(1)
+			return nil
+		}, util.WithImmediateStart())
+	}()
+	waitFor(t, func() bool { return runs.Load() == 1 })
+	cancel()
+	<-done
+
+	ctx, cancel = context.WithCancel(t.Context())
+	defer cancel()
+	release := make(chan struct{})
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		util.Every(ctx, 2*time.Millisecond, func(context.Context) error {
+			if running.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			<-release
+			running.Add(-1)
+			return nil
+		}, util.WithNoOverlap())
+	}()
+	waitFor(t, func() bool { return running.Load() == 1 })
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	close(release)
+	<-done
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("%d runs overlapped despite WithNoOverlap", n)
+	}
+}
@@ -0,0 +1,200 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Fernet errors.
+var (
+	// ErrInvalidFernetToken is returned for malformed, tampered or wrongly keyed tokens.
+	ErrInvalidFernetToken = errors.New("invalid fernet token")
+	// ErrFernetTokenExpired is returned when a token is older than the TTL passed to FernetDecrypt.
+	ErrFernetTokenExpired = errors.New("fernet token has expired")
+)
+
+// Fernet layout: version | timestamp | IV | ciphertext | HMAC-SHA256.
+const (
+	fernetVersion       = 0x80
+	fernetKeySize       = 32
+	fernetTimestampSize = 8
+	fernetHeaderSize    = 1 + fernetTimestampSize + aes.BlockSize
+	fernetMinTokenSize  = fernetHeaderSize + aes.BlockSize + sha256.Size
+
+	// fernetMaxClockSkew matches the reference implementations, which reject
+	// tokens stamped further than this into the future.
+	fernetMaxClockSkew = 60 * time.Second
+)
+
+// fernetOptions contains configuration for FernetDecrypt.
+type fernetOptions struct {
+	// ttl rejects tokens older than this; 0 disables the check
+	ttl time.Duration
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// FernetOption is a function that configures FernetDecrypt.
+type FernetOption func(*fernetOptions)
+
+// WithFernetTTL rejects tokens whose timestamp is older than ttl with ErrFernetTokenExpired.
+func WithFernetTTL(ttl time.Duration) FernetOption {
+	return func(o *fernetOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithFernetClock overrides the time source used for the TTL and clock skew checks.
+func WithFernetClock(now func() time.Time) FernetOption {
+	return func(o *fernetOptions) {
+		o.now = now
+	}
+}
+
+// GenerateFernetKey returns a new random key in the base64url form used by
+// Python's Fernet.generate_key and the Ruby fernet gem.
+func GenerateFernetKey() (string, error) {
+	key := make([]byte, fernetKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate fernet key: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(key), nil
+}
+
+// DecodeFernetKey decodes a base64url Fernet key into the 32 raw bytes
+// expected by FernetEncrypt and FernetDecrypt.
+func DecodeFernetKey(encoded string) ([]byte, error) {
+	key, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fernet key encoding: %w", err)
+	}
+	if len(key) != fernetKeySize {
+		return nil, fmt.Errorf("fernet key must be %d bytes long", fernetKeySize)
+	}
+	return key, nil
+}
+
+// FernetEncrypt encrypts plaintext into a Fernet token.
+//
+// Fernet (https://github.com/fernet/spec) is AES-128-CBC with PKCS#7
+// padding, authenticated by HMAC-SHA256 over a versioned, timestamped
+// header. Use it to exchange tokens with services built on Python's
+// cryptography.fernet or the Ruby fernet gem; for new Go-only data prefer
+// EncryptValue.
+//
+// Parameters:
+//   - key: 32 raw bytes (signing key then encryption key); see DecodeFernetKey
+//   - plaintext: Data to be encrypted
+//
+// Example:
+//
+//	key, err := DecodeFernetKey(os.Getenv("FERNET_KEY"))
+//	token, err := FernetEncrypt(key, []byte("hello"))
+func FernetEncrypt(key []byte, plaintext []byte) (string, error) {
+	if len(key) != fernetKeySize {
+		return "", fmt.Errorf("fernet key must be %d bytes long", fernetKeySize)
+	}
+	block, err := aes.NewCipher(key[fernetKeySize/2:])
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	token := make([]byte, fernetHeaderSize, fernetHeaderSize+len(plaintext)+padding+sha256.Size)
+	token[0] = fernetVersion
+	binary.BigEndian.PutUint64(token[1:], uint64(time.Now().Unix())) //nolint:gosec // Fernet timestamps are unsigned
+	iv := token[1+fernetTimestampSize : fernetHeaderSize]
+	if _, err = rand.Read(iv); err != nil {
+		return "", fmt.Errorf("failed to generate IV: %w", err)
+	}
+
+	ciphertext := token[fernetHeaderSize : fernetHeaderSize+len(plaintext)+padding]
+	copy(ciphertext, plaintext)
+	for i := len(plaintext); i < len(ciphertext); i++ {
+		ciphertext[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	token = token[:fernetHeaderSize+len(ciphertext)]
+
+	mac := hmac.New(sha256.New, key[:fernetKeySize/2])
+	mac.Write(token)
+	token = mac.Sum(token)
+
+	return base64.URLEncoding.EncodeToString(token), nil
+}
+
+// FernetDecrypt verifies and decrypts a Fernet token.
+//
+// Tokens stamped more than 60 seconds in the future are rejected, as in the
+// reference implementations. Pass WithFernetTTL to also reject old tokens.
+// Errors wrap ErrInvalidFernetToken or ErrFernetTokenExpired.
+//
+// Example:
+//
+//	plaintext, err := FernetDecrypt(key, token, WithFernetTTL(time.Hour))
+func FernetDecrypt(key []byte, token string, opts ...FernetOption) ([]byte, error) {
+	options := &fernetOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if len(key) != fernetKeySize {
+		return nil, fmt.Errorf("fernet key must be %d bytes long", fernetKeySize)
+	}
+
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFernetToken, err)
+	}
+	if len(data) < fernetMinTokenSize || data[0] != fernetVersion ||
+		(len(data)-fernetHeaderSize-sha256.Size)%aes.BlockSize != 0 {
+		return nil, ErrInvalidFernetToken
+	}
+
+	signed, tag := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	mac := hmac.New(sha256.New, key[:fernetKeySize/2])
+	mac.Write(signed)
+	if !SecureCompare(tag, mac.Sum(nil)) {
+		return nil, ErrInvalidFernetToken
+	}
+
+	//nolint:gosec // timestamps beyond year 2262 are rejected as future tokens
+	issued := time.Unix(int64(binary.BigEndian.Uint64(data[1:])), 0)
+	now := options.now()
+	if issued.After(now.Add(fernetMaxClockSkew)) {
+		return nil, fmt.Errorf("%w: timestamp is in the future", ErrInvalidFernetToken)
+	}
+	if options.ttl > 0 && now.After(issued.Add(options.ttl)) {
+		return nil, ErrFernetTokenExpired
+	}
+
+	block, err := aes.NewCipher(key[fernetKeySize/2:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	iv := data[1+fernetTimestampSize : fernetHeaderSize]
+	plaintext := make([]byte, len(signed)-fernetHeaderSize)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, signed[fernetHeaderSize:])
+
+	// The HMAC has been verified, so bad padding means a buggy producer
+	// rather than a padding oracle.
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, fmt.Errorf("%w: invalid padding", ErrInvalidFernetToken)
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, fmt.Errorf("%w: invalid padding", ErrInvalidFernetToken)
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
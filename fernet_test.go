@@ -0,0 +1,98 @@
+package util_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+// Vector from the Fernet specification (generate.json / verify.json).
+const (
+	fernetSpecKey   = "cw_0x689RpI-jtRR7oE8h_eQsKImvJapLeSbXpwF4e4="
+	fernetSpecToken = "gAAAAAAdwJ6wAAECAwQFBgcICQoLDA0ODy021cpGVWKZ_eEwCGM4BLLF_5CV9dOPmrhuVUPgJobwOz7JcbmrR64jVmpU4IwqDA=="
+)
+
+func TestFernetDecryptSpecVector(t *testing.T) {
+	key, err := util.DecodeFernetKey(fernetSpecKey)
+	if err != nil {
+		t.Fatalf("DecodeFernetKey() failed: %v", err)
+	}
+	issued := time.Date(1985, 10, 26, 1, 20, 0, 0, time.FixedZone("", -7*3600))
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		ttl     time.Duration
+		wantErr error
+	}{
+		{"within ttl", issued.Add(time.Second), time.Minute, nil},
+		{"no ttl", issued.AddDate(30, 0, 0), 0, nil},
+		{"expired", issued.Add(2 * time.Minute), time.Minute, util.ErrFernetTokenExpired},
+		{"far future timestamp", issued.Add(-2 * time.Minute), 0, util.ErrInvalidFernetToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, decryptErr := util.FernetDecrypt(key, fernetSpecToken,
+				util.WithFernetTTL(tt.ttl), util.WithFernetClock(func() time.Time { return tt.now }))
+			if !errors.Is(decryptErr, tt.wantErr) || (tt.wantErr == nil && decryptErr != nil) {
+				t.Fatalf("FernetDecrypt() error = %v, want %v", decryptErr, tt.wantErr)
+			}
+			if tt.wantErr == nil && string(got) != "hello" {
+				t.Errorf("FernetDecrypt() = %q, want %q", got, "hello")
+			}
+		})
+	}
+}
+
+func TestFernetRoundTrip(t *testing.T) {
+	encoded, err := util.GenerateFernetKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := util.DecodeFernetKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plaintext := range [][]byte{{}, []byte("hello"), bytes.Repeat([]byte("x"), 16), bytes.Repeat([]byte("y"), 100)} {
+		token, encryptErr := util.FernetEncrypt(key, plaintext)
+		if encryptErr != nil {
+			t.Fatalf("FernetEncrypt() failed: %v", encryptErr)
+		}
+		got, decryptErr := util.FernetDecrypt(key, token, util.WithFernetTTL(time.Minute))
+		if decryptErr != nil {
+			t.Fatalf("FernetDecrypt() failed: %v", decryptErr)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("FernetDecrypt() = %q, want %q", got, plaintext)
+		}
+	}
+}
+
+func TestFernetDecryptRejectsTampering(t *testing.T) {
+	key, _ := util.DecodeFernetKey(fernetSpecKey)
+	otherKey := bytes.Repeat([]byte{1}, 32)
+
+	tests := []struct {
+		name  string
+		key   []byte
+		token string
+	}{
+		{"wrong key", otherKey, fernetSpecToken},
+		{"flipped byte", key, fernetSpecToken[:20] + "A" + fernetSpecToken[21:]},
+		{"truncated", key, fernetSpecToken[:40]},
+		{"not base64", key, "!!!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := util.FernetDecrypt(tt.key, tt.token); !errors.Is(err, util.ErrInvalidFernetToken) {
+				t.Errorf("FernetDecrypt() error = %v, want ErrInvalidFernetToken", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to path so that readers see either the old
+// content or the new, never a partial write, even if the process crashes:
+// it writes a temporary file in the same directory, syncs it to disk and
+// renames it over path. perm is applied to the new file.
+//
+// Example:
+//
+//	if err := WriteFileAtomic("state/snapshot.json", data, 0o600); err != nil {
+//	    return err
+//	}
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+
+	// Persist the rename itself. Directories cannot be opened for syncing
+	// on every platform, so this is best effort.
+	if d, openErr := os.Open(dir); openErr == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// TempDir creates a new temporary directory that is removed with its
+// contents once ctx is done, for scratch space scoped to a request or job.
+// The removal is registered for leak detection, see SetCloserTracking.
+//
+// Example:
+//
+//	dir, err := TempDir(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	archive := filepath.Join(dir, "export.zip")
+func TempDir(ctx context.Context) (string, error) {
+	dir, err := os.MkdirTemp("", "util-")
+	if err != nil {
+		return "", err
+	}
+	remove := TrackCloser("temp dir "+dir, CloserFunc(func() error { return os.RemoveAll(dir) }))
+	CloseOnDone(ctx, remove, "failed to remove temporary directory")
+	return dir, nil
+}
+
+// EnsureDir creates the directory path and any missing parents with perm,
+// succeeding when it already exists. It fails when path exists but is not a
+// directory.
+func EnsureDir(path string, perm os.FileMode) error {
+	if err := os.MkdirAll(path, perm); err != nil {
+		return fmt.Errorf("ensure directory %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package util_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	if err := util.WriteFileAtomic(path, []byte(`{"v":1}`), 0o600); err != nil {
+		t.Fatalf("WriteFileAtomic = %v", err)
+	}
+	if err := util.WriteFileAtomic(path, []byte(`{"v":2}`), 0o600); err != nil {
+		t.Fatalf("WriteFileAtomic over an existing file = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != `{"v":2}` {
+		t.Errorf("content = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	if err := util.WriteFileAtomic(filepath.Join(dir, "missing", "f"), nil, 0o600); err == nil {
+		t.Error("WriteFileAtomic into a missing directory succeeded")
+	}
+}
+
+func TestTempDir(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	dir, err := util.TempDir(ctx)
+	if err != nil {
+		t.Fatalf("TempDir = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "f"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	waitFor(t, func() bool {
+		_, err := os.Stat(dir)
+		return os.IsNotExist(err)
+	})
+}
+
+func TestEnsureDir(t *testing.T) {
+	base := t.TempDir()
+	nested := filepath.Join(base, "a", "b")
+	if err := util.EnsureDir(nested, 0o750); err != nil {
+		t.Fatalf("EnsureDir = %v", err)
+	}
+	if err := util.EnsureDir(nested, 0o750); err != nil {
+		t.Errorf("EnsureDir of an existing directory = %v", err)
+	}
+
+	file := filepath.Join(base, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.EnsureDir(file, 0o750); err == nil {
+		t.Error("EnsureDir of a file succeeded")
+	}
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// machineFingerprintDomain separates fingerprints from other SHA-256 uses.
+	machineFingerprintDomain = "util.machine-fingerprint.v1"
+	// macLocallyAdministered is the bit of the first MAC byte set on
+	// addresses assigned by software rather than the manufacturer.
+	macLocallyAdministered = 0x02
+)
+
+// ErrNoMachineIdentity is returned by MachineFingerprint when the machine
+// has no machine ID, hardware address or hostname to derive it from.
+var ErrNoMachineIdentity = errors.New("no machine identity available")
+
+//nolint:gochecknoglobals // fixed lookup paths
+var machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// machineFingerprintOptions contains configuration for MachineFingerprint.
+type machineFingerprintOptions struct {
+	// salt scopes the fingerprint to one application
+	salt string
+}
+
+// MachineFingerprintOption is a function that configures MachineFingerprint.
+type MachineFingerprintOption func(*machineFingerprintOptions)
+
+// WithMachineFingerprintSalt scopes the fingerprint to an application, so
+// two applications on one machine cannot correlate their fingerprints.
+func WithMachineFingerprintSalt(salt string) MachineFingerprintOption {
+	return func(o *machineFingerprintOptions) {
+		o.salt = salt
+	}
+}
+
+// MachineFingerprint returns a stable, anonymous identifier of the machine
+// as 64 hexadecimal characters, for licensing and node identity. It is a
+// salted SHA-256, so it reveals neither hardware addresses nor the hostname.
+//
+// It is derived from the first available of, in order:
+//   - the systemd/D-Bus machine ID, stable across reboots and network
+//     changes until the OS is reinstalled;
+//   - the sorted universally administered MAC addresses, stable until a
+//     network card is added or replaced (virtual interfaces are ignored);
+//   - the hostname, stable until the machine is renamed.
+//
+// Containers usually share or lack a machine ID; give them one (e.g. mount
+// /etc/machine-id) when each needs its own fingerprint.
+//
+// Returns ErrNoMachineIdentity when none of the sources is available.
+//
+// Example:
+//
+//	node, err := MachineFingerprint(WithMachineFingerprintSalt("billing-service"))
+func MachineFingerprint(opts ...MachineFingerprintOption) (string, error) {
+	options := &machineFingerprintOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	source, identity := machineIdentity()
+	if identity == "" {
+		return "", ErrNoMachineIdentity
+	}
+
+	h := sha256.New()
+	for _, part := range []string{machineFingerprintDomain, options.salt, source, identity} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// machineIdentity returns the name and value of the most stable identity
+// source available.
+func machineIdentity() (string, string) {
+	for _, path := range machineIDPaths {
+		if id, err := os.ReadFile(path); err == nil {
+			if trimmed := strings.TrimSpace(string(id)); trimmed != "" {
+				return "machine-id", trimmed
+			}
+		}
+	}
+
+	if macs := hardwareAddrs(); len(macs) > 0 {
+		return "mac", strings.Join(macs, ",")
+	}
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return "hostname", hostname
+	}
+	return "", ""
+}
+
+// hardwareAddrs returns the sorted, universally administered MAC addresses
+// of the machine. Locally administered addresses, used by virtual
+// interfaces such as bridges and veth pairs, come and go and are skipped.
+func hardwareAddrs() []string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var macs []string
+	for _, iface := range interfaces {
+		mac := iface.HardwareAddr
+		if iface.Flags&net.FlagLoopback != 0 || len(mac) == 0 ||
+			mac[0]&macLocallyAdministered != 0 || bytes.Equal(mac, make(net.HardwareAddr, len(mac))) {
+			continue
+		}
+		macs = append(macs, mac.String())
+	}
+	slices.Sort(macs)
+	return slices.Compact(macs)
+}
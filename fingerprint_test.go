@@ -0,0 +1,27 @@
+package util_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestMachineFingerprint(t *testing.T) {
+	first, err := util.MachineFingerprint()
+	if errors.Is(err, util.ErrNoMachineIdentity) {
+		t.Skip("no machine identity in this environment")
+	}
+	if err != nil || len(first) != 64 {
+		t.Fatalf("MachineFingerprint() = %q, %v; want 64 hex characters", first, err)
+	}
+
+	if again, _ := util.MachineFingerprint(); again != first {
+		t.Errorf("MachineFingerprint() = %q then %q, want stable", first, again)
+	}
+
+	salted, err := util.MachineFingerprint(util.WithMachineFingerprintSalt("billing"))
+	if err != nil || salted == first {
+		t.Errorf("MachineFingerprint() with a salt = %q, %v; want a different fingerprint", salted, err)
+	}
+}
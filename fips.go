@@ -0,0 +1,47 @@
+package util
+
+import (
+	"crypto/fips140"
+	"errors"
+	"fmt"
+)
+
+// ErrNotFIPSApproved is returned in FIPS mode when an operation requests an
+// algorithm that is not FIPS 140-3 approved.
+var ErrNotFIPSApproved = errors.New("algorithm is not FIPS 140-3 approved")
+
+// FIPSMode reports whether the package restricts itself to FIPS 140-3
+// approved algorithms.
+//
+// FIPS mode is on when the binary is built with the "fips" build tag or when
+// the Go Cryptographic Module runs in FIPS 140-3 mode (GODEBUG=fips140=on or
+// GOFIPS140 at build time). In FIPS mode these fail fast with
+// ErrNotFIPSApproved instead of silently using a non-approved primitive:
+//   - ChaCha20-Poly1305, XChaCha20-Poly1305, AES-GCM-SIV and AES-SIV, in
+//     every API taking an Algorithm and in EncryptDeterministic
+//   - scrypt in DeriveKeyFromPassphrase
+//   - Argon2id and bcrypt in HashPassword and VerifyPassword
+//   - BLAKE2b in HashReader, HashFile and ParseChecksum
+//   - X25519 in GenerateX25519KeyPair, SharedSecret, HPKESeal and HPKEOpen
+//
+// ComputeLookupToken and its variants cannot return an error, so in FIPS
+// mode they compute LookupHashBLAKE2b tokens with HMAC-SHA256 instead; those
+// tokens differ from the ones computed outside FIPS mode. CRC-32C is not a cryptographic primitive and stays available.
+func FIPSMode() bool {
+	return fipsBuild || fips140.Enabled()
+}
+
+// checkFIPSApproved fails with ErrNotFIPSApproved in FIPS mode, for the
+// named primitive that is never approved.
+func checkFIPSApproved(primitive string) error {
+	if FIPSMode() {
+		return fmt.Errorf("%w: %s", ErrNotFIPSApproved, primitive)
+	}
+	return nil
+}
+
+// FIPSApproved reports whether the algorithm is FIPS 140-3 approved.
+// Only AES-GCM is; the other AEADs are sound but not NIST-validated.
+func (a Algorithm) FIPSApproved() bool {
+	return a == AlgorithmAESGCM
+}
@@ -0,0 +1,6 @@
+//go:build fips
+
+package util
+
+// fipsBuild enables FIPS mode for binaries built with the "fips" tag.
+const fipsBuild = true
@@ -0,0 +1,153 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// skipInFIPSMode skips t in FIPS mode, where the named primitive that is
+// not FIPS approved fails with ErrNotFIPSApproved.
+func skipInFIPSMode(t *testing.T, primitive string) {
+	t.Helper()
+	if util.FIPSMode() {
+		t.Skipf("%s is not FIPS approved", primitive)
+	}
+}
+
+// skipUnlessFIPSApproved skips t in FIPS mode when algo is not approved.
+func skipUnlessFIPSApproved(t *testing.T, algo util.Algorithm) {
+	t.Helper()
+	if !algo.FIPSApproved() {
+		skipInFIPSMode(t, algo.String())
+	}
+}
+
+func TestAlgorithmFIPSApproved(t *testing.T) {
+	tests := []struct {
+		algo util.Algorithm
+		want bool
+	}{
+		{util.AlgorithmAESGCM, true},
+		{util.AlgorithmChaCha20Poly1305, false},
+		{util.AlgorithmXChaCha20Poly1305, false},
+		{util.AlgorithmAESGCMSIV, false},
+		{util.AlgorithmAESSIV, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algo.String(), func(t *testing.T) {
+			if got := tt.algo.FIPSApproved(); got != tt.want {
+				t.Errorf("FIPSApproved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFIPSModeGate runs in both modes: a plain go test checks that every
+// algorithm is usable, go test -tags fips -run FIPS checks that only approved
+// ones are.
+func TestFIPSModeGate(t *testing.T) {
+	key32 := make([]byte, 32)
+	key64 := make([]byte, 64)
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []struct {
+		name     string
+		approved bool
+		run      func() error
+	}{
+		{"AES-GCM", true, func() error {
+			_, err := util.EncryptValueWithAlgo(util.AlgorithmAESGCM, key32, []byte("data"))
+			return err
+		}},
+		{"ChaCha20-Poly1305", false, func() error {
+			_, err := util.EncryptValueWithAlgo(util.AlgorithmChaCha20Poly1305, key32, []byte("data"))
+			return err
+		}},
+		{"AES-GCM-SIV", false, func() error {
+			_, err := util.EncryptValueWithAlgo(util.AlgorithmAESGCMSIV, key32, []byte("data"))
+			return err
+		}},
+		{"EncryptDeterministic", false, func() error {
+			_, err := util.EncryptDeterministic(key64, []byte("data"), nil)
+			return err
+		}},
+		{"scrypt", false, func() error {
+			_, err := util.DeriveKeyFromPassphrase("correct horse battery staple", make([]byte, 16), 32, nil)
+			return err
+		}},
+		{"PBKDF2", true, func() error {
+			params := util.DefaultPassphraseKeyParams()
+			params.KDF = util.KDFPBKDF2
+			params.PBKDF2Iterations = 1000
+			_, err := util.DeriveKeyFromPassphrase("correct horse battery staple", make([]byte, 16), 32, &params)
+			return err
+		}},
+		{"Argon2id", false, func() error {
+			_, err := util.HashPassword("secret", fastPasswordParams())
+			return err
+		}},
+		{"bcrypt", false, func() error {
+			_, err := util.VerifyPassword(string(bcryptHash), "secret")
+			return err
+		}},
+		{"SHA-256", true, func() error {
+			_, err := util.HashReader(strings.NewReader("data"), util.HashSHA256)
+			return err
+		}},
+		{"BLAKE2b", false, func() error {
+			_, err := util.HashReader(strings.NewReader("data"), util.HashBLAKE2b256)
+			return err
+		}},
+		{"X25519 key pair", false, func() error {
+			_, _, err := util.GenerateX25519KeyPair()
+			return err
+		}},
+		{"X25519 key agreement", false, func() error {
+			_, err := util.SharedSecret(x25519.Bytes(), x25519.PublicKey().Bytes())
+			return err
+		}},
+		{"HPKE", false, func() error {
+			_, err := util.HPKESeal(x25519.PublicKey().Bytes(), []byte("data"))
+			return err
+		}},
+	}
+
+	for _, op := range operations {
+		t.Run(op.name, func(t *testing.T) {
+			err := op.run()
+			if util.FIPSMode() && !op.approved {
+				if !errors.Is(err, util.ErrNotFIPSApproved) {
+					t.Fatalf("error = %v, want ErrNotFIPSApproved", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFIPSModeLookupToken(t *testing.T) {
+	key := []byte("test-key-16-bytes-")
+	blake := util.ComputeLookupToken(key, "user@example.com", util.WithLookupHash(util.LookupHashBLAKE2b))
+	hmacSHA256 := util.ComputeLookupToken(key, "user@example.com")
+	if got := bytes.Equal(blake, hmacSHA256); got != util.FIPSMode() {
+		t.Errorf("BLAKE2b token equals the HMAC-SHA256 token: %v, want %v", got, util.FIPSMode())
+	}
+}
@@ -0,0 +1,233 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultFlagsPrefix = "FLAG_"
+	flagTenantsSuffix  = "_TENANTS"
+
+	// flagBuckets is the resolution of percentage rollouts, 0.01%.
+	flagBuckets = 10000
+
+	// flagAll is the percentage of a flag that is on for all tenants.
+	flagAll = 100
+)
+
+// flagsOptions contains configuration for Flags.
+type flagsOptions struct {
+	// prefix is prepended to the environment variable of a flag
+	prefix string
+}
+
+// FlagsOption is a function that configures Flags.
+type FlagsOption func(*flagsOptions)
+
+// WithFlagsPrefix sets the prefix of the environment variables flags are
+// read from. The default is "FLAG_".
+func WithFlagsPrefix(prefix string) FlagsOption {
+	return func(o *flagsOptions) {
+		o.prefix = prefix
+	}
+}
+
+// flagSetting is a runtime override of a flag.
+type flagSetting struct {
+	// percent of tenants the flag is on for, 0 to 100
+	percent float64
+	set     bool
+
+	// tenants overrides the flag per tenant ID
+	tenants map[string]bool
+}
+
+// Flags resolves feature flags from the environment, with runtime overrides,
+// giving services feature switches and gradual rollouts without a flag
+// service.
+//
+// A flag called "new-checkout" is read from FLAG_NEW_CHECKOUT, which is a
+// boolean ("true", "false", "on", "off", "1", "0") or a rollout percentage
+// such as "25%". With a percentage, the flag is on for that share of
+// tenants, chosen by a stable hash of the flag name and the tenant ID of
+// GetTenancy, so a tenant keeps its answer as the rollout widens.
+// FLAG_NEW_CHECKOUT_TENANTS lists tenant IDs, comma-separated, the flag is
+// always on for. Unset or malformed variables leave the flag off.
+//
+// The Set methods override the environment while the service runs, for
+// example from an admin endpoint, and log each change. Runtime overrides
+// take precedence over the environment, and per-tenant settings over
+// general ones. Flags is safe for concurrent use.
+//
+// Example:
+//
+//	flags := NewFlags()
+//	if flags.Enabled(ctx, "new-checkout") {
+//	    return newCheckout(ctx, cart)
+//	}
+//
+//	flags.Set(ctx, "new-checkout", false) // kill switch
+type Flags struct {
+	options flagsOptions
+
+	mu        sync.RWMutex
+	overrides map[string]*flagSetting
+}
+
+// NewFlags returns Flags without runtime overrides.
+func NewFlags(opts ...FlagsOption) *Flags {
+	options := flagsOptions{prefix: defaultFlagsPrefix}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Flags{options: options, overrides: make(map[string]*flagSetting)}
+}
+
+// Enabled reports whether the flag name is on for the tenant in ctx.
+func (f *Flags) Enabled(ctx context.Context, name string) bool {
+	tenantID := GetTenantID(ctx)
+
+	f.mu.RLock()
+	override := f.overrides[name]
+	if override != nil {
+		if enabled, ok := override.tenants[tenantID]; ok && tenantID != "" {
+			f.mu.RUnlock()
+			return enabled
+		}
+		if override.set {
+			percent := override.percent
+			f.mu.RUnlock()
+			return flagBucket(name, tenantID) < percent*flagBuckets/flagAll
+		}
+	}
+	f.mu.RUnlock()
+
+	key := f.envKey(name)
+	if tenantID != "" && slices.Contains(splitEnvList(os.Getenv(key+flagTenantsSuffix), ","), tenantID) {
+		return true
+	}
+	percent, err := parseFlagValue(os.Getenv(key))
+	if err != nil {
+		return false
+	}
+	return flagBucket(name, tenantID) < percent*flagBuckets/flagAll
+}
+
+// Set turns the flag name on or off for all tenants, overriding the
+// environment. Per-tenant settings made with SetForTenant still apply.
+func (f *Flags) Set(ctx context.Context, name string, enabled bool) {
+	percent := 0.0
+	if enabled {
+		percent = flagAll
+	}
+	f.SetPercentage(ctx, name, percent)
+}
+
+// SetPercentage turns the flag name on for percent of tenants, clamped to 0
+// to 100, overriding the environment.
+func (f *Flags) SetPercentage(ctx context.Context, name string, percent float64) {
+	percent = min(max(percent, 0), flagAll)
+	f.mu.Lock()
+	setting := f.settingLocked(name)
+	setting.percent, setting.set = percent, true
+	f.mu.Unlock()
+
+	Log(ctx).WithField("flag", name).WithField("percent", percent).Info("feature flag changed")
+}
+
+// SetForTenant turns the flag name on or off for tenantID only, overriding
+// every other setting for that tenant.
+func (f *Flags) SetForTenant(ctx context.Context, name, tenantID string, enabled bool) {
+	f.mu.Lock()
+	setting := f.settingLocked(name)
+	if setting.tenants == nil {
+		setting.tenants = make(map[string]bool)
+	}
+	setting.tenants[tenantID] = enabled
+	f.mu.Unlock()
+
+	Log(ctx).WithField("flag", name).WithField("tenant_id", tenantID).WithField("enabled", enabled).
+		Info("feature flag changed for tenant")
+}
+
+// Reset removes the runtime overrides of the flag name, returning it to
+// its environment configuration.
+func (f *Flags) Reset(ctx context.Context, name string) {
+	f.mu.Lock()
+	_, existed := f.overrides[name]
+	delete(f.overrides, name)
+	f.mu.Unlock()
+
+	if existed {
+		Log(ctx).WithField("flag", name).Info("feature flag reset")
+	}
+}
+
+func (f *Flags) settingLocked(name string) *flagSetting {
+	setting, ok := f.overrides[name]
+	if !ok {
+		setting = &flagSetting{}
+		f.overrides[name] = setting
+	}
+	return setting
+}
+
+// envKey returns the environment variable of the flag name: the prefix
+// followed by name upper-cased, with characters other than letters and
+// digits replaced by underscores.
+func (f *Flags) envKey(name string) string {
+	return f.options.prefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// parseFlagValue parses a boolean or percentage flag value into the
+// percentage of tenants the flag is on for.
+func parseFlagValue(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if number, ok := strings.CutSuffix(value, "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || percent < 0 || percent > flagAll {
+			return 0, fmt.Errorf("invalid flag percentage %q", value)
+		}
+		return percent, nil
+	}
+	switch strings.ToLower(value) {
+	case "on", "yes":
+		return flagAll, nil
+	case "off", "no":
+		return 0, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flag value %q", value)
+	}
+	if enabled {
+		return flagAll, nil
+	}
+	return 0, nil
+}
+
+// flagBucket places tenantID in one of flagBuckets buckets for the flag
+// name, independently of the other flags.
+func flagBucket(name, tenantID string) float64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(tenantID))
+	return float64(h.Sum32() % flagBuckets)
+}
@@ -0,0 +1,98 @@
+package util_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestFlagsFromEnv(t *testing.T) {
+	t.Setenv("FLAG_NEW_CHECKOUT", "on")
+	t.Setenv("FLAG_DARK_MODE", "false")
+	t.Setenv("FLAG_BETA_SEARCH", "nonsense")
+	t.Setenv("FLAG_BETA_SEARCH_TENANTS", "t1, t2")
+
+	flags := util.NewFlags()
+	ctx := t.Context()
+	tests := []struct {
+		name     string
+		tenantID string
+		want     bool
+	}{
+		{"new-checkout", "", true},
+		{"dark_mode", "", false},
+		{"unset-flag", "", false},
+		{"beta-search", "t2", true},
+		{"beta-search", "t3", false},
+	}
+	for _, tt := range tests {
+		tctx := ctx
+		if tt.tenantID != "" {
+			tctx = util.NewTenancy().TenantID(tt.tenantID).Context(ctx)
+		}
+		if got := flags.Enabled(tctx, tt.name); got != tt.want {
+			t.Errorf("Enabled(%s, tenant %q) = %v, want %v", tt.name, tt.tenantID, got, tt.want)
+		}
+	}
+}
+
+func TestFlagsPercentage(t *testing.T) {
+	t.Setenv("APP_FLAG_ROLLOUT", "30%")
+	flags := util.NewFlags(util.WithFlagsPrefix("APP_FLAG_"))
+
+	enabled := func() map[string]bool {
+		on := make(map[string]bool)
+		for i := range 2000 {
+			tenantID := fmt.Sprintf("tenant-%d", i)
+			if flags.Enabled(util.NewTenancy().TenantID(tenantID).Context(t.Context()), "rollout") {
+				on[tenantID] = true
+			}
+		}
+		return on
+	}
+
+	at30 := enabled()
+	if n := len(at30); n < 500 || n > 700 {
+		t.Fatalf("30%% rollout enabled %d of 2000 tenants", n)
+	}
+
+	flags.SetPercentage(t.Context(), "rollout", 60)
+	at60 := enabled()
+	for tenantID := range at30 {
+		if !at60[tenantID] {
+			t.Fatalf("tenant %s lost the flag when the rollout widened", tenantID)
+		}
+	}
+}
+
+func TestFlagsOverrides(t *testing.T) {
+	t.Setenv("FLAG_PAYMENTS", "true")
+	t.Setenv("FLAG_PAYMENTS_TENANTS", "vip")
+
+	var buf bytes.Buffer
+	ctx := util.ContextWithLogger(t.Context(), util.NewLogger(t.Context(), util.WithLogOutput(&buf)))
+	vip := util.NewTenancy().TenantID("vip").Context(ctx)
+	other := util.NewTenancy().TenantID("other").Context(ctx)
+
+	flags := util.NewFlags()
+	flags.Set(ctx, "payments", false)
+	if flags.Enabled(vip, "payments") || flags.Enabled(other, "payments") {
+		t.Fatal("Set(false) should turn the flag off for everyone")
+	}
+	if !strings.Contains(buf.String(), "feature flag changed") || !strings.Contains(buf.String(), "payments") {
+		t.Fatalf("change not logged: %s", buf.String())
+	}
+
+	flags.SetForTenant(ctx, "payments", "vip", true)
+	if !flags.Enabled(vip, "payments") || flags.Enabled(other, "payments") {
+		t.Fatal("SetForTenant should override the general setting for that tenant only")
+	}
+
+	flags.Reset(ctx, "payments")
+	if !flags.Enabled(other, "payments") {
+		t.Fatal("Reset should restore the environment configuration")
+	}
+}
@@ -0,0 +1,118 @@
+package util_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+// Samples from the NIST FF1 examples (FF1samples.pdf).
+func TestFPECipherNISTSamples(t *testing.T) {
+	const (
+		key128 = "2b7e151628aed2a6abf7158809cf4f3c"
+		key192 = key128 + "ef4359d8d580aa4f"
+		key256 = key192 + "7f036d6f04fc6a94"
+	)
+
+	tests := []struct {
+		name     string
+		key      string
+		alphabet string
+		tweak    string
+		plain    string
+		cipher   string
+	}{
+		{"sample 1", key128, util.FPEAlphabetDigits, "", "0123456789", "2433477484"},
+		{"sample 2", key128, util.FPEAlphabetDigits, "39383736353433323130", "0123456789", "6124200773"},
+		{
+			"sample 3", key128, util.FPEAlphabetLowerAlphanumeric, "3737373770717273373737",
+			"0123456789abcdefghi", "a9tv40mll9kdu509eum",
+		},
+		{"sample 4", key192, util.FPEAlphabetDigits, "", "0123456789", "2830668132"},
+		{"sample 7", key256, util.FPEAlphabetDigits, "", "0123456789", "6657667009"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := hex.DecodeString(tt.key)
+			tweak, _ := hex.DecodeString(tt.tweak)
+
+			fpe, err := util.NewFPECipher(key, tt.alphabet)
+			if err != nil {
+				t.Fatalf("NewFPECipher() failed: %v", err)
+			}
+			got, err := fpe.Encrypt(tt.plain, tweak)
+			if err != nil {
+				t.Fatalf("Encrypt() failed: %v", err)
+			}
+			if got != tt.cipher {
+				t.Errorf("Encrypt() = %s, want %s", got, tt.cipher)
+			}
+			back, err := fpe.Decrypt(got, tweak)
+			if err != nil {
+				t.Fatalf("Decrypt() failed: %v", err)
+			}
+			if back != tt.plain {
+				t.Errorf("Decrypt() = %s, want %s", back, tt.plain)
+			}
+		})
+	}
+}
+
+func TestFPECipherTenantTweak(t *testing.T) {
+	key := make([]byte, 32)
+	fpe, err := util.NewFPECipher(key, util.FPEAlphabetDigits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctxA := util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant-a", partitionID: "p1"})
+	ctxB := util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant-b", partitionID: "p1"})
+
+	tweakA, err := util.TenantTweak(ctxA, "card-number")
+	if err != nil {
+		t.Fatalf("TenantTweak() failed: %v", err)
+	}
+	tweakB, _ := util.TenantTweak(ctxB, "card-number")
+
+	card := "4111111111111111"
+	encA, _ := fpe.Encrypt(card, tweakA)
+	encB, _ := fpe.Encrypt(card, tweakB)
+	if len(encA) != len(card) || encA == card {
+		t.Errorf("Encrypt() = %s, want a different 16 digit value", encA)
+	}
+	if encA == encB {
+		t.Error("Encrypt() should differ across tenants")
+	}
+	if back, _ := fpe.Decrypt(encA, tweakA); back != card {
+		t.Errorf("Decrypt() = %s, want %s", back, card)
+	}
+
+	if _, err = util.TenantTweak(context.Background(), "card-number"); !errors.Is(err, util.ErrMissingTenancy) {
+		t.Errorf("TenantTweak() error = %v, want ErrMissingTenancy", err)
+	}
+}
+
+func TestFPECipherRejects(t *testing.T) {
+	key := make([]byte, 16)
+
+	if _, err := util.NewFPECipher(key[:10], util.FPEAlphabetDigits); err == nil {
+		t.Error("NewFPECipher() should reject invalid key sizes")
+	}
+	if _, err := util.NewFPECipher(key, "aa"); err == nil {
+		t.Error("NewFPECipher() should reject duplicate alphabet characters")
+	}
+
+	fpe, _ := util.NewFPECipher(key, util.FPEAlphabetDigits)
+	if _, err := fpe.Encrypt("12345", nil); err == nil {
+		t.Error("Encrypt() should reject values below the minimum domain size")
+	}
+	if _, err := fpe.Encrypt("12345a", nil); err == nil {
+		t.Error("Encrypt() should reject characters outside the alphabet")
+	}
+	if _, err := fpe.Encrypt("123456", nil); err != nil {
+		t.Errorf("Encrypt() should accept 6 digits: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package util
+
+import "strings"
+
+// friendlyCodeAlphabet holds the digits and uppercase letters without those
+// easily confused when read aloud or handwritten: 0 and O, 1, I and L, and U.
+const friendlyCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// friendlyCodeOptions contains configuration for RandomFriendlyCode.
+type friendlyCodeOptions struct {
+	// groupSize splits the code into groups of this many characters
+	groupSize int
+
+	// separator joins the groups
+	separator string
+
+	// checksum appends a check character
+	checksum bool
+}
+
+// FriendlyCodeOption is a function that configures RandomFriendlyCode.
+type FriendlyCodeOption func(*friendlyCodeOptions)
+
+// WithFriendlyCodeGroups splits the code into groups of size characters
+// joined by "-", e.g. "XXXX-XXXX". The check character, if any, counts
+// toward the last group.
+func WithFriendlyCodeGroups(size int) FriendlyCodeOption {
+	return func(o *friendlyCodeOptions) {
+		o.groupSize = size
+	}
+}
+
+// WithFriendlyCodeChecksum appends a check character (Luhn mod 30) that
+// detects any single mistyped character and most swaps of adjacent
+// characters. Verify codes with ValidFriendlyCode.
+func WithFriendlyCodeChecksum() FriendlyCodeOption {
+	return func(o *friendlyCodeOptions) {
+		o.checksum = true
+	}
+}
+
+// RandomFriendlyCode generates a code of n random characters for invite
+// codes, license keys and other codes people read aloud or type. The
+// alphabet has no characters that are easily confused (0/O, 1/I/L, U), and
+// each character carries almost 5 bits, so 16 characters exceed 78 bits.
+//
+// Example:
+//
+//	code := RandomFriendlyCode(8, WithFriendlyCodeGroups(4), WithFriendlyCodeChecksum())
+//	// "7KQ2-MZ9H-R"
+func RandomFriendlyCode(n int, opts ...FriendlyCodeOption) string {
+	options := friendlyCodeOptions{separator: "-"}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	code := RandomString(n, friendlyCodeAlphabet)
+	if options.checksum && code != "" {
+		code += string(friendlyCodeCheck(code))
+	}
+	if options.groupSize <= 0 || len(code) <= options.groupSize {
+		return code
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(code); i += options.groupSize {
+		if i > 0 {
+			b.WriteString(options.separator)
+		}
+		b.WriteString(code[i:min(i+options.groupSize, len(code))])
+	}
+	return b.String()
+}
+
+// NormalizeFriendlyCode converts a code as typed by a person to the form
+// RandomFriendlyCode generates it in, uppercased and without the spaces and
+// dashes people add when copying it.
+func NormalizeFriendlyCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || r == ' ':
+			return -1
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return r
+		}
+	}, code)
+}
+
+// ValidFriendlyCode reports whether code, generated with
+// WithFriendlyCodeChecksum, has a correct check character. The code is
+// normalized first, so grouping and case do not matter.
+func ValidFriendlyCode(code string) bool {
+	code = NormalizeFriendlyCode(code)
+	if len(code) < 2 { //nolint:mnd // at least one character and the check
+		return false
+	}
+	for i := range len(code) {
+		if strings.IndexByte(friendlyCodeAlphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	body := code[:len(code)-1]
+	return friendlyCodeCheck(body) == code[len(code)-1]
+}
+
+// friendlyCodeCheck computes the Luhn mod N check character of code.
+func friendlyCodeCheck(code string) byte {
+	base := len(friendlyCodeAlphabet)
+	sum := 0
+	double := true
+	for i := len(code) - 1; i >= 0; i-- {
+		value := strings.IndexByte(friendlyCodeAlphabet, code[i])
+		if double {
+			value *= 2
+			value = value/base + value%base
+		}
+		sum += value
+		double = !double
+	}
+	return friendlyCodeAlphabet[(base-sum%base)%base]
+}
@@ -0,0 +1,60 @@
+package util_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestRandomFriendlyCode(t *testing.T) {
+	code := util.RandomFriendlyCode(16)
+	if len(code) != 16 {
+		t.Fatalf("RandomFriendlyCode(16) = %q, want 16 characters", code)
+	}
+	if strings.ContainsAny(code, "01ILOU") {
+		t.Errorf("RandomFriendlyCode() = %q, contains an ambiguous character", code)
+	}
+
+	grouped := util.RandomFriendlyCode(8, util.WithFriendlyCodeGroups(4))
+	if parts := strings.Split(grouped, "-"); len(parts) != 2 || len(parts[0]) != 4 || len(parts[1]) != 4 {
+		t.Errorf("RandomFriendlyCode() with groups = %q, want XXXX-XXXX", grouped)
+	}
+
+	if got := util.RandomFriendlyCode(0, util.WithFriendlyCodeChecksum()); got != "" {
+		t.Errorf("RandomFriendlyCode(0) = %q, want empty", got)
+	}
+}
+
+func TestValidFriendlyCode(t *testing.T) {
+	code := util.RandomFriendlyCode(12, util.WithFriendlyCodeGroups(4), util.WithFriendlyCodeChecksum())
+	if len(code) != 16 {
+		t.Fatalf("RandomFriendlyCode() = %q, want 12 characters, a check character and 3 dashes", code)
+	}
+	if !util.ValidFriendlyCode(code) {
+		t.Errorf("ValidFriendlyCode(%q) = false", code)
+	}
+	if !util.ValidFriendlyCode(strings.ToLower(strings.ReplaceAll(code, "-", " "))) {
+		t.Errorf("ValidFriendlyCode() rejected a lowercase, space-separated %q", code)
+	}
+
+	const alphabet = "23456789ABCDEFGHJKMNPQRSTVWXYZ"
+	body := util.NormalizeFriendlyCode(code)
+	for i := range len(body) {
+		for _, r := range alphabet {
+			if byte(r) == body[i] {
+				continue
+			}
+			typo := body[:i] + string(r) + body[i+1:]
+			if util.ValidFriendlyCode(typo) {
+				t.Fatalf("ValidFriendlyCode(%q) accepted a single-character typo of %q", typo, body)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"", "A", "ABCD-0"} {
+		if util.ValidFriendlyCode(invalid) {
+			t.Errorf("ValidFriendlyCode(%q) = true, want false", invalid)
+		}
+	}
+}
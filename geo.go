@@ -0,0 +1,111 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync/atomic"
+)
+
+// ASNInfo describes the autonomous system that announces an address.
+type ASNInfo struct {
+	// Number is the autonomous system number, 0 when unknown.
+	Number uint32
+
+	// Organization is the name of the organization operating the system.
+	Organization string
+}
+
+// GeoInfo combines the country and autonomous system of an address, as
+// returned by LookupGeo.
+type GeoInfo struct {
+	// Country is the ISO 3166-1 alpha-2 country code, "" when unknown.
+	Country string
+
+	// ASN is the autonomous system of the address.
+	ASN ASNInfo
+}
+
+// GeoResolver maps IP addresses to their country and autonomous system, for
+// enriching logs and keying rate limits. Addresses a resolver knows nothing
+// about return zero values and a nil error; errors are reserved for failures
+// of the resolver itself.
+//
+// Implementations must be safe for concurrent use. This package ships only
+// NoopGeoResolver; the geoipx module provides one backed by MaxMind
+// databases.
+type GeoResolver interface {
+	// Country returns the ISO 3166-1 alpha-2 country code of addr.
+	Country(ctx context.Context, addr netip.Addr) (string, error)
+
+	// ASN returns the autonomous system that announces addr.
+	ASN(ctx context.Context, addr netip.Addr) (ASNInfo, error)
+}
+
+// NoopGeoResolver is a GeoResolver that knows no addresses. It is the
+// default until SetGeoResolver installs another.
+type NoopGeoResolver struct{}
+
+// Country returns "".
+func (NoopGeoResolver) Country(context.Context, netip.Addr) (string, error) {
+	return "", nil
+}
+
+// ASN returns a zero ASNInfo.
+func (NoopGeoResolver) ASN(context.Context, netip.Addr) (ASNInfo, error) {
+	return ASNInfo{}, nil
+}
+
+// geoResolver holds the resolver installed by SetGeoResolver.
+var geoResolver atomic.Pointer[GeoResolver] //nolint:gochecknoglobals // process-wide enrichment hook
+
+// SetGeoResolver installs the resolver returned by DefaultGeoResolver and
+// used by LookupGeo when given none. Passing nil restores NoopGeoResolver.
+//
+// Example:
+//
+//	resolver, err := geoipx.Open("GeoLite2-Country.mmdb", "GeoLite2-ASN.mmdb")
+//	if err != nil {
+//	    return err
+//	}
+//	util.SetGeoResolver(resolver)
+func SetGeoResolver(resolver GeoResolver) {
+	if resolver == nil {
+		geoResolver.Store(nil)
+		return
+	}
+	geoResolver.Store(&resolver)
+}
+
+// DefaultGeoResolver returns the resolver installed by SetGeoResolver, or
+// NoopGeoResolver when none is.
+func DefaultGeoResolver() GeoResolver {
+	if resolver := geoResolver.Load(); resolver != nil {
+		return *resolver
+	}
+	return NoopGeoResolver{}
+}
+
+// LookupGeo returns the country and autonomous system of addr from
+// resolver, or from DefaultGeoResolver when resolver is nil. Lookups are
+// best effort: the result holds whatever was found and the error joins the
+// failures of both lookups.
+//
+// Example:
+//
+//	if addr, err := resolver.ClientAddr(r); err == nil {
+//	    geo, _ := LookupGeo(ctx, nil, addr)
+//	    log.Info("request", "country", geo.Country, "asn", geo.ASN.Number)
+//	}
+func LookupGeo(ctx context.Context, resolver GeoResolver, addr netip.Addr) (GeoInfo, error) {
+	if resolver == nil {
+		resolver = DefaultGeoResolver()
+	}
+	addr = addr.Unmap()
+
+	var info GeoInfo
+	var countryErr, asnErr error
+	info.Country, countryErr = resolver.Country(ctx, addr)
+	info.ASN, asnErr = resolver.ASN(ctx, addr)
+	return info, errors.Join(countryErr, asnErr)
+}
@@ -0,0 +1,68 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type fakeGeoResolver struct {
+	countries map[netip.Addr]string
+	asnErr    error
+}
+
+func (f fakeGeoResolver) Country(_ context.Context, addr netip.Addr) (string, error) {
+	return f.countries[addr], nil
+}
+
+func (f fakeGeoResolver) ASN(context.Context, netip.Addr) (util.ASNInfo, error) {
+	if f.asnErr != nil {
+		return util.ASNInfo{}, f.asnErr
+	}
+	return util.ASNInfo{Number: 64500, Organization: "Example"}, nil
+}
+
+func TestLookupGeo(t *testing.T) {
+	ctx := context.Background()
+	addr := netip.MustParseAddr("192.0.2.1")
+
+	geo, err := util.LookupGeo(ctx, nil, addr)
+	if err != nil || geo != (util.GeoInfo{}) {
+		t.Fatalf("LookupGeo with the no-op default = %+v, %v", geo, err)
+	}
+
+	resolver := fakeGeoResolver{countries: map[netip.Addr]string{addr: "KE"}}
+	geo, err = util.LookupGeo(ctx, resolver, netip.MustParseAddr("::ffff:192.0.2.1"))
+	if err != nil {
+		t.Fatalf("LookupGeo: %v", err)
+	}
+	want := util.GeoInfo{Country: "KE", ASN: util.ASNInfo{Number: 64500, Organization: "Example"}}
+	if geo != want {
+		t.Errorf("LookupGeo = %+v, want %+v", geo, want)
+	}
+
+	boom := errors.New("boom")
+	resolver.asnErr = boom
+	geo, err = util.LookupGeo(ctx, resolver, addr)
+	if !errors.Is(err, boom) || geo.Country != "KE" {
+		t.Errorf("LookupGeo with a failing ASN lookup = %+v, %v", geo, err)
+	}
+}
+
+func TestSetGeoResolver(t *testing.T) {
+	t.Cleanup(func() { util.SetGeoResolver(nil) })
+
+	addr := netip.MustParseAddr("198.51.100.7")
+	util.SetGeoResolver(fakeGeoResolver{countries: map[netip.Addr]string{addr: "UG"}})
+	if country, _ := util.DefaultGeoResolver().Country(context.Background(), addr); country != "UG" {
+		t.Errorf("Country = %q, want UG", country)
+	}
+
+	util.SetGeoResolver(nil)
+	if _, ok := util.DefaultGeoResolver().(util.NoopGeoResolver); !ok {
+		t.Errorf("DefaultGeoResolver after SetGeoResolver(nil) = %T", util.DefaultGeoResolver())
+	}
+}
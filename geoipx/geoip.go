@@ -0,0 +1,128 @@
+// Package geoipx implements util.GeoResolver with MaxMind DB files, such as
+// the GeoLite2 and GeoIP2 Country, City and ASN databases, held in memory.
+package geoipx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pitabwire/util"
+)
+
+// ErrUnsupportedDatabase is returned for databases that carry neither
+// country nor autonomous system data.
+var ErrUnsupportedDatabase = errors.New("unsupported MaxMind database type")
+
+// countryRecord is the part of a Country or City record Resolver reads.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// asnRecord is the part of an ASN or ISP record Resolver reads.
+type asnRecord struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Resolver is a util.GeoResolver backed by MaxMind databases loaded into
+// memory. It answers Country from a Country or City database and ASN from an
+// ASN or ISP database; lookups without a matching database return zero
+// values. A Resolver is safe for concurrent use.
+type Resolver struct {
+	country *maxminddb.Reader
+	asn     *maxminddb.Reader
+}
+
+var _ util.GeoResolver = (*Resolver)(nil)
+
+// Open reads the database files at paths into memory and returns a Resolver
+// over them. Each database is assigned to country or ASN lookups from its
+// type, so the paths may be given in any order.
+//
+// Example:
+//
+//	resolver, err := geoipx.Open("GeoLite2-Country.mmdb", "GeoLite2-ASN.mmdb")
+//	if err != nil {
+//	    return err
+//	}
+//	util.SetGeoResolver(resolver)
+func Open(paths ...string) (*Resolver, error) {
+	dbs := make([][]byte, 0, len(paths))
+	for _, path := range paths {
+		db, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MaxMind database: %w", err)
+		}
+		dbs = append(dbs, db)
+	}
+	return FromBytes(dbs...)
+}
+
+// FromBytes returns a Resolver over databases already in memory, such as
+// ones embedded in the binary or downloaded at startup. The Resolver keeps
+// references to dbs, which must not be modified afterwards.
+func FromBytes(dbs ...[]byte) (*Resolver, error) {
+	resolver := &Resolver{}
+	for i, db := range dbs {
+		reader, err := maxminddb.FromBytes(db)
+		if err != nil {
+			return nil, fmt.Errorf("database %d: %w", i, err)
+		}
+
+		dbType := reader.Metadata.DatabaseType
+		switch {
+		case strings.Contains(dbType, "ASN") || strings.Contains(dbType, "ISP"):
+			resolver.asn = reader
+		case strings.Contains(dbType, "Country") || strings.Contains(dbType, "City"):
+			resolver.country = reader
+		default:
+			return nil, fmt.Errorf("database %d: %w %q", i, ErrUnsupportedDatabase, dbType)
+		}
+	}
+	return resolver, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country addr is located
+// in, falling back to the country it is registered in.
+func (r *Resolver) Country(_ context.Context, addr netip.Addr) (string, error) {
+	var record countryRecord
+	if err := lookup(r.country, addr, &record); err != nil {
+		return "", err
+	}
+	if record.Country.ISOCode != "" {
+		return record.Country.ISOCode, nil
+	}
+	return record.RegisteredCountry.ISOCode, nil
+}
+
+// ASN returns the autonomous system that announces addr.
+func (r *Resolver) ASN(_ context.Context, addr netip.Addr) (util.ASNInfo, error) {
+	var record asnRecord
+	if err := lookup(r.asn, addr, &record); err != nil {
+		return util.ASNInfo{}, err
+	}
+	return util.ASNInfo{Number: record.Number, Organization: record.Organization}, nil
+}
+
+// lookup decodes the record of addr in reader into result, leaving result
+// untouched when reader is nil or has no record for addr.
+func lookup(reader *maxminddb.Reader, addr netip.Addr, result any) error {
+	addr = addr.Unmap()
+	if reader == nil || !addr.IsValid() || (addr.Is6() && reader.Metadata.IPVersion == 4) {
+		return nil
+	}
+	if err := reader.Lookup(addr.AsSlice(), result); err != nil {
+		return fmt.Errorf("failed to look up %s: %w", addr, err)
+	}
+	return nil
+}
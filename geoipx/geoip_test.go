@@ -0,0 +1,196 @@
+package geoipx_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"github.com/pitabwire/util/geoipx"
+)
+
+// mmdbWriter builds a minimal IPv4 MaxMind DB with 24-bit records, enough to
+// exercise Resolver without shipping binary fixtures.
+type mmdbWriter struct {
+	// nodes holds the left and right record of each search tree node; a
+	// negative record points at data entry -record-1 and 0 means no data
+	// (node 0 is the root, so no record points back at it).
+	nodes [][2]int
+	data  [][]byte
+}
+
+func (w *mmdbWriter) insert(prefix string, record []byte) {
+	p := netip.MustParsePrefix(prefix)
+	w.data = append(w.data, record)
+	if len(w.nodes) == 0 {
+		w.nodes = append(w.nodes, [2]int{})
+	}
+
+	ip := p.Addr().As4()
+	node := 0
+	for i := range p.Bits() {
+		bit := (ip[i/8] >> (7 - i%8)) & 1
+		if i == p.Bits()-1 {
+			w.nodes[node][bit] = -len(w.data)
+			return
+		}
+		if w.nodes[node][bit] <= 0 {
+			w.nodes = append(w.nodes, [2]int{})
+			w.nodes[node][bit] = len(w.nodes) - 1
+		}
+		node = w.nodes[node][bit]
+	}
+}
+
+func (w *mmdbWriter) bytes(dbType string) []byte {
+	var dataSection []byte
+	offsets := make([]int, len(w.data))
+	for i, record := range w.data {
+		offsets[i] = len(dataSection)
+		dataSection = append(dataSection, record...)
+	}
+
+	nodeCount := len(w.nodes)
+	var out []byte
+	for _, node := range w.nodes {
+		for _, record := range node {
+			value := nodeCount
+			switch {
+			case record > 0:
+				value = record
+			case record < 0:
+				value = nodeCount + 16 + offsets[-record-1]
+			}
+			out = append(out, byte(value>>16), byte(value>>8), byte(value))
+		}
+	}
+	out = append(out, make([]byte, 16)...)
+	out = append(out, dataSection...)
+	out = append(out, "\xab\xcd\xefMaxMind.com"...)
+	return append(out, mmdbMap(
+		"binary_format_major_version", mmdbUint16(2),
+		"binary_format_minor_version", mmdbUint16(0),
+		"database_type", mmdbString(dbType),
+		"ip_version", mmdbUint16(4),
+		"node_count", mmdbUint32(uint32(nodeCount)),
+		"record_size", mmdbUint16(24),
+	)...)
+}
+
+func mmdbString(s string) []byte {
+	if len(s) >= 29 {
+		// Sizes from 29 to 284 spill into one extra byte.
+		return append([]byte{2<<5 | 29, byte(len(s) - 29)}, s...)
+	}
+	return append([]byte{2<<5 | byte(len(s))}, s...)
+}
+
+func mmdbUint16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16([]byte{5<<5 | 2}, v)
+}
+
+func mmdbUint32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32([]byte{6<<5 | 4}, v)
+}
+
+// mmdbMap encodes alternating keys and already encoded values.
+func mmdbMap(pairs ...any) []byte {
+	out := []byte{7<<5 | byte(len(pairs)/2)}
+	for i := 0; i < len(pairs); i += 2 {
+		key, _ := pairs[i].(string)
+		value, _ := pairs[i+1].([]byte)
+		out = append(out, mmdbString(key)...)
+		out = append(out, value...)
+	}
+	return out
+}
+
+func countryDB() []byte {
+	var w mmdbWriter
+	w.insert("192.0.2.0/24", mmdbMap("country", mmdbMap("iso_code", mmdbString("KE"))))
+	w.insert("198.51.100.0/24", mmdbMap("registered_country", mmdbMap("iso_code", mmdbString("UG"))))
+	return w.bytes("GeoLite2-Country")
+}
+
+func asnDB() []byte {
+	var w mmdbWriter
+	w.insert("192.0.2.0/25", mmdbMap(
+		"autonomous_system_number", mmdbUint32(64500),
+		"autonomous_system_organization", mmdbString("Example Networks"),
+	))
+	return w.bytes("GeoLite2-ASN")
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	resolver, err := geoipx.FromBytes(asnDB(), countryDB())
+	if err != nil {
+		t.Fatalf("FromBytes: %v", err)
+	}
+
+	tests := []struct {
+		addr    string
+		country string
+		asn     util.ASNInfo
+	}{
+		{"192.0.2.10", "KE", util.ASNInfo{Number: 64500, Organization: "Example Networks"}},
+		{"::ffff:192.0.2.10", "KE", util.ASNInfo{Number: 64500, Organization: "Example Networks"}},
+		{"192.0.2.200", "KE", util.ASNInfo{}},
+		{"198.51.100.1", "UG", util.ASNInfo{}},
+		{"203.0.113.1", "", util.ASNInfo{}},
+		{"2001:db8::1", "", util.ASNInfo{}},
+	}
+	for _, tt := range tests {
+		addr := netip.MustParseAddr(tt.addr)
+		country, err := resolver.Country(ctx, addr)
+		if err != nil || country != tt.country {
+			t.Errorf("Country(%s) = %q, %v, want %q", tt.addr, country, err, tt.country)
+		}
+		asn, err := resolver.ASN(ctx, addr)
+		if err != nil || asn != tt.asn {
+			t.Errorf("ASN(%s) = %+v, %v, want %+v", tt.addr, asn, err, tt.asn)
+		}
+	}
+
+	geo, err := util.LookupGeo(ctx, resolver, netip.MustParseAddr("192.0.2.1"))
+	if err != nil || geo.Country != "KE" || geo.ASN.Number != 64500 {
+		t.Errorf("LookupGeo = %+v, %v", geo, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	if err := os.WriteFile(path, countryDB(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := geoipx.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if country, _ := resolver.Country(context.Background(), netip.MustParseAddr("192.0.2.1")); country != "KE" {
+		t.Errorf("Country = %q, want KE", country)
+	}
+	if asn, err := resolver.ASN(context.Background(), netip.MustParseAddr("192.0.2.1")); err != nil || asn != (util.ASNInfo{}) {
+		t.Errorf("ASN without an ASN database = %+v, %v", asn, err)
+	}
+
+	if _, err := geoipx.Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("Open of a missing file succeeded")
+	}
+}
+
+func TestFromBytesRejectsInvalidDatabases(t *testing.T) {
+	var w mmdbWriter
+	w.insert("192.0.2.0/24", mmdbMap("domain", mmdbString("example.com")))
+	if _, err := geoipx.FromBytes(w.bytes("GeoIP2-Domain")); !errors.Is(err, geoipx.ErrUnsupportedDatabase) {
+		t.Errorf("FromBytes of a domain database = %v, want ErrUnsupportedDatabase", err)
+	}
+	if _, err := geoipx.FromBytes([]byte("not a database")); err == nil {
+		t.Error("FromBytes of garbage succeeded")
+	}
+}
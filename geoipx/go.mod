@@ -0,0 +1,17 @@
+module github.com/pitabwire/util/geoipx
+
+go 1.26.0
+
+require (
+	github.com/oschwald/maxminddb-golang v1.13.1
+	github.com/pitabwire/util v0.8.1-0.20261017031502-98f1c1d43eb5
+)
+
+require (
+	github.com/klauspost/compress v1.20.1 // indirect
+	github.com/lmittmann/tint v1.1.3 // indirect
+	github.com/rs/xid v1.6.0 // indirect
+	golang.org/x/crypto v0.57.0 // indirect
+	golang.org/x/sys v0.48.0 // indirect
+	golang.org/x/text v0.42.0 // indirect
+)
@@ -1,8 +1,12 @@
 module github.com/pitabwire/util
 
-go 1.26
+go 1.26.0
 
 require (
+	github.com/klauspost/compress v1.20.1
 	github.com/lmittmann/tint v1.1.3
 	github.com/rs/xid v1.6.0
+	golang.org/x/crypto v0.57.0
+	golang.org/x/sys v0.48.0
+	golang.org/x/text v0.42.0
 )
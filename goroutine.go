@@ -0,0 +1,116 @@
+package util
+
+import (
+	"context"
+	"time"
+)
+
+// goOptions contains configuration for Go and GoWithRecover.
+type goOptions struct {
+	// restarts is how many times fn is restarted after panicking, negative
+	// for no limit
+	restarts int
+
+	// delay is the wait before the first restart, doubling for each
+	// following one
+	delay time.Duration
+}
+
+// GoOption is a function that configures Go and GoWithRecover.
+type GoOption func(*goOptions)
+
+const (
+	// minRestartDelay is the first restart delay when none is configured, so
+	// a function that always panics does not restart in a tight loop.
+	minRestartDelay = 100 * time.Millisecond
+
+	// maxRestartDelay caps the doubling delay between restarts.
+	maxRestartDelay = time.Minute
+)
+
+// WithRestartOnPanic restarts the function after a panic, up to maxRestarts
+// times, or without limit when maxRestarts is negative, for long-running
+// loops such as queue consumers that must keep going. The first restart
+// waits delay, 100ms when not positive, and each following one twice as
+// long, up to a minute.
+// Restarting stops once ctx is done.
+func WithRestartOnPanic(maxRestarts int, delay time.Duration) GoOption {
+	return func(o *goOptions) {
+		o.restarts = maxRestarts
+		o.delay = delay
+	}
+}
+
+// Go runs fn in a new goroutine, recovering a panic instead of letting it
+// take down the process, and logging it with its stack to the logger in
+// ctx. It is what Protect does for HTTP handlers, for background work. The
+// returned channel is closed when fn returns for good.
+//
+// Example:
+//
+//	Go(ctx, func(ctx context.Context) {
+//	    consumer.Run(ctx)
+//	}, WithRestartOnPanic(-1, time.Second))
+func Go(ctx context.Context, fn func(ctx context.Context), opts ...GoOption) <-chan struct{} {
+	return GoWithRecover(ctx, fn, nil, opts...)
+}
+
+// GoWithRecover is Go calling onPanic, when not nil, with every panic of fn
+// after logging it, for example to count panics in a metric or fail a
+// health check.
+func GoWithRecover(
+	ctx context.Context,
+	fn func(ctx context.Context),
+	onPanic func(ctx context.Context, err *PanicError),
+	opts ...GoOption,
+) <-chan struct{} {
+	var options goOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		delay := options.delay
+		if delay <= 0 {
+			delay = minRestartDelay
+		}
+		for restarts := 0; ; restarts++ {
+			panicErr := runRecovered(ctx, fn)
+			if panicErr == nil {
+				return
+			}
+			Log(ctx).WithField("panic", panicErr.Value).WithField("stack", panicErr.Stack).
+				Error("goroutine panicked")
+			if onPanic != nil {
+				onPanic(ctx, panicErr)
+			}
+
+			if options.restarts >= 0 && restarts >= options.restarts {
+				return
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			delay = min(delay*2, maxRestartDelay) //nolint:mnd // exponential backoff
+			Log(ctx).WithField("restart", restarts+1).Warn("restarting goroutine after panic")
+		}
+	}()
+	return done
+}
+
+// runRecovered calls fn, returning its panic, if any, as a PanicError.
+func runRecovered(ctx context.Context, fn func(ctx context.Context)) (panicErr *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr, _ = panicError(r).(*PanicError)
+		}
+	}()
+	fn(ctx)
+	return nil
+}
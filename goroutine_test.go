@@ -0,0 +1,87 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestGoRecoversAndLogs(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := util.ContextWithLogger(t.Context(), util.NewLogger(t.Context(), util.WithLogOutput(&buf)))
+
+	var recovered *util.PanicError
+	done := util.GoWithRecover(ctx, func(context.Context) {
+		panic("worker exploded")
+	}, func(_ context.Context, err *util.PanicError) {
+		recovered = err
+	})
+	<-done
+
+	if recovered == nil || recovered.Value != "worker exploded" {
+		t.Fatalf("onPanic got %v", recovered)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "goroutine panicked") || !strings.Contains(out, "worker exploded") || !strings.Contains(out, "goroutine_test.go") {
+		t.Fatalf("panic not logged with its stack: %s", out)
+	}
+}
+
+func TestGoReturns(t *testing.T) {
+	var ran atomic.Bool
+	<-util.Go(t.Context(), func(context.Context) { ran.Store(true) })
+	if !ran.Load() {
+		t.Fatal("fn did not run")
+	}
+}
+
+func TestGoRestartOnPanic(t *testing.T) {
+	var runs atomic.Int32
+	done := util.Go(t.Context(), func(context.Context) {
+		if runs.Add(1) < 3 {
+			panic("flaky")
+		}
+	}, util.WithRestartOnPanic(5, time.Millisecond))
+	<-done
+	if n := runs.Load(); n != 3 {
+		t.Fatalf("fn ran %d times, want 3", n)
+	}
+
+	runs.Store(0)
+	began := time.Now()
+	<-util.Go(t.Context(), func(context.Context) {
+		runs.Add(1)
+		panic("always")
+	}, util.WithRestartOnPanic(2, 0))
+	if n := runs.Load(); n != 3 {
+		t.Fatalf("fn ran %d times with 2 restarts, want 3", n)
+	}
+	if elapsed := time.Since(began); elapsed < 250*time.Millisecond {
+		t.Errorf("2 restarts without a delay took %v, want the 100ms minimum doubling", elapsed)
+	}
+}
+
+func TestGoRestartStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	var runs atomic.Int32
+	done := util.Go(ctx, func(context.Context) {
+		if runs.Add(1) == 2 {
+			cancel()
+		}
+		panic("always")
+	}, util.WithRestartOnPanic(-1, time.Millisecond))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("restarts continued after the context ended")
+	}
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("fn ran %d times, want 2", n)
+	}
+}
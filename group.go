@@ -0,0 +1,105 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// Group suppresses duplicate calls: concurrent calls of Do with the same key
+// share one execution of the function and its result, so a burst of
+// requests for the same missing record causes one query. The zero value is
+// ready to use, and a Group is safe for concurrent use.
+//
+// Example:
+//
+//	var lookups Group[string, *Profile]
+//	profile, err := lookups.Do(ctx, userID, func(ctx context.Context) (*Profile, error) {
+//	    return api.FetchProfile(ctx, userID)
+//	})
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*groupCall[V]
+}
+
+// groupCall is an execution in progress that callers wait on.
+type groupCall[V any] struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	value   V
+	err     error
+}
+
+// Do runs fn for key, unless a call for key is already running, in which
+// case it waits for that call and returns its result.
+//
+// Each caller waits only as long as its own ctx: a caller whose ctx is done
+// returns its ctx's error while the others keep waiting. fn's context keeps
+// the values of the ctx of the caller that started it but not its
+// cancellation; it is cancelled once every caller has given up, and a later
+// Do for key starts afresh. A panic in fn is returned to every caller as an
+// error wrapping ErrTaskPanicked.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*groupCall[V])
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &groupCall[V]{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		go g.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		g.leave(key, call)
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Forget makes the next Do for key start a new call even if one is running,
+// for when the running call's result is known to be outdated.
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, call *groupCall[V], fn func(ctx context.Context) (V, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = panicError(r)
+		}
+		call.cancel()
+
+		g.mu.Lock()
+		if g.calls[key] == call {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		close(call.done)
+	}()
+	call.value, call.err = fn(ctx)
+}
+
+// leave records that a caller stopped waiting for call, cancelling it when
+// no caller is left.
+func (g *Group[K, V]) leave(key K, call *groupCall[V]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
@@ -0,0 +1,95 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestGroupSharesCalls(t *testing.T) {
+	var group util.Group[string, int]
+	release := make(chan struct{})
+	var calls atomic.Int32
+	fn := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan int, 5)
+	wg.Go(func() {
+		v, _ := group.Do(t.Context(), "k", fn)
+		results <- v
+	})
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for range 4 {
+		wg.Go(func() {
+			v, _ := group.Do(t.Context(), "k", fn)
+			results <- v
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for v := range results {
+		if v != 42 {
+			t.Errorf("Do = %d, want 42", v)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn called %d times, want 1", n)
+	}
+}
+
+func TestGroupCallerCancellation(t *testing.T) {
+	var group util.Group[string, int]
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := group.Do(ctx, "k", fn)
+		errs <- err
+	}()
+	<-started
+	cancel()
+
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do after the caller gave up = %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("fn was not cancelled once its only caller gave up")
+	}
+
+	v, err := group.Do(t.Context(), "k", func(context.Context) (int, error) { return 7, nil })
+	if err != nil || v != 7 {
+		t.Errorf("Do after an abandoned call = %d, %v, want a fresh call", v, err)
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	var group util.Group[int, string]
+	_, err := group.Do(t.Context(), 1, func(context.Context) (string, error) { panic("boom") })
+	if !errors.Is(err, util.ErrTaskPanicked) {
+		t.Errorf("Do of a panicking fn = %v", err)
+	}
+}
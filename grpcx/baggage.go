@@ -0,0 +1,102 @@
+package grpcx
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pitabwire/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// baggageMetadataKey is the metadata key carrying W3C baggage, the lowercase
+// form of util.HeaderBaggage.
+const baggageMetadataKey = "baggage"
+
+// BaggageUnaryInterceptor returns a server interceptor that adds the baggage
+// metadata of each call to the baggage on the handler context, mirroring
+// util.WithBaggageFromHeaders.
+//
+// Example:
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(grpcx.BaggageUnaryInterceptor()),
+//	    grpc.ChainStreamInterceptor(grpcx.BaggageStreamInterceptor()),
+//	)
+func BaggageUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(baggageFromIncoming(ctx), req)
+	}
+}
+
+// BaggageStreamInterceptor is the streaming counterpart of BaggageUnaryInterceptor.
+func BaggageStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := baggageFromIncoming(stream.Context())
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// BaggageUnaryClientInterceptor returns a client interceptor that sends the
+// baggage on the call context as baggage metadata, mirroring
+// util.SetBaggageHeader. Calls whose context carries no baggage are sent
+// unchanged.
+//
+// Example:
+//
+//	conn, err := grpc.NewClient(target,
+//	    grpc.WithChainUnaryInterceptor(grpcx.BaggageUnaryClientInterceptor()),
+//	    grpc.WithChainStreamInterceptor(grpcx.BaggageStreamClientInterceptor()),
+//	)
+func BaggageUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		return invoker(baggageToOutgoing(ctx), method, req, reply, cc, callOpts...)
+	}
+}
+
+// BaggageStreamClientInterceptor is the streaming counterpart of BaggageUnaryClientInterceptor.
+func BaggageStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(baggageToOutgoing(ctx), desc, cc, method, callOpts...)
+	}
+}
+
+// baggageFromIncoming adds the baggage in the incoming metadata of ctx to its baggage.
+func baggageFromIncoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(baggageMetadataKey)
+	if len(values) == 0 {
+		return ctx
+	}
+	members := util.ParseBaggage(strings.Join(values, ","))
+	if len(members) == 0 {
+		return ctx
+	}
+	return util.ContextWithBaggage(ctx, members)
+}
+
+// baggageToOutgoing sets the baggage on ctx as its outgoing baggage metadata.
+func baggageToOutgoing(ctx context.Context) context.Context {
+	value := util.FormatBaggage(util.GetBaggage(ctx))
+	if value == "" {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(baggageMetadataKey, value)
+	return metadata.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,53 @@
+package grpcx_test
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"github.com/pitabwire/util/grpcx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestBaggageUnaryInterceptors(t *testing.T) {
+	want := map[string]string{"experiment": "checkout v2", "shard": "7"}
+	clientCtx := util.ContextWithBaggage(context.Background(), want)
+
+	var sent metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := grpcx.BaggageUnaryClientInterceptor()(clientCtx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("client interceptor failed: %v", err)
+	}
+
+	var got map[string]string
+	handler := func(ctx context.Context, _ any) (any, error) {
+		got = util.GetBaggage(ctx)
+		return nil, nil
+	}
+	serverCtx := metadata.NewIncomingContext(context.Background(), sent)
+	if _, err := grpcx.BaggageUnaryInterceptor()(serverCtx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("server interceptor failed: %v", err)
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("handler baggage = %v, want %v", got, want)
+	}
+}
+
+func TestBaggageStreamInterceptor(t *testing.T) {
+	md := metadata.Pairs("baggage", "shard=3")
+	stream := fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+
+	var got string
+	err := grpcx.BaggageStreamInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(_ any, s grpc.ServerStream) error {
+		got = util.GetBaggageValue(s.Context(), "shard")
+		return nil
+	})
+	if err != nil || got != "3" {
+		t.Errorf("stream handler baggage shard = %q, %v; want 3", got, err)
+	}
+}
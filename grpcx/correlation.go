@@ -0,0 +1,95 @@
+package grpcx
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pitabwire/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// correlationMetadataKey is the metadata key carrying the correlation ID,
+// the lowercase form of util.HeaderCorrelationID.
+const correlationMetadataKey = "x-correlation-id"
+
+// CorrelationUnaryInterceptor returns a server interceptor that gives each
+// call a correlation ID from its x-correlation-id, x-request-id or
+// traceparent metadata, mirroring util.WithCorrelationFromHeaders.
+//
+// Example:
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(grpcx.CorrelationUnaryInterceptor()),
+//	    grpc.ChainStreamInterceptor(grpcx.CorrelationStreamInterceptor()),
+//	)
+func CorrelationUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(correlationFromIncoming(ctx), req)
+	}
+}
+
+// CorrelationStreamInterceptor is the streaming counterpart of CorrelationUnaryInterceptor.
+func CorrelationStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := correlationFromIncoming(stream.Context())
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// CorrelationUnaryClientInterceptor returns a client interceptor that stamps
+// util.CorrelationID of the call context onto its outgoing metadata,
+// mirroring util.SetCorrelationHeader.
+//
+// Example:
+//
+//	conn, err := grpc.NewClient(target,
+//	    grpc.WithChainUnaryInterceptor(grpcx.CorrelationUnaryClientInterceptor()),
+//	    grpc.WithChainStreamInterceptor(grpcx.CorrelationStreamClientInterceptor()),
+//	)
+func CorrelationUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		return invoker(correlationToOutgoing(ctx), method, req, reply, cc, callOpts...)
+	}
+}
+
+// CorrelationStreamClientInterceptor is the streaming counterpart of CorrelationUnaryClientInterceptor.
+func CorrelationStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(correlationToOutgoing(ctx), desc, cc, method, callOpts...)
+	}
+}
+
+// correlationFromIncoming sets the request ID of ctx from its incoming metadata.
+func correlationFromIncoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	h := http.Header{}
+	for _, key := range []string{util.HeaderCorrelationID, util.HeaderRequestID, util.HeaderTraceParent} {
+		if values := md.Get(key); len(values) > 0 {
+			h.Set(key, values[0])
+		}
+	}
+	return util.ContextWithCorrelationHeader(ctx, h)
+}
+
+// correlationToOutgoing sets the correlation ID of ctx as its outgoing metadata.
+func correlationToOutgoing(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(correlationMetadataKey, util.CorrelationID(ctx))
+	return metadata.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,51 @@
+package grpcx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"github.com/pitabwire/util/grpcx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCorrelationUnaryInterceptors(t *testing.T) {
+	clientCtx := util.ContextWithRequestID(context.Background(), "req-1")
+
+	var sent metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	if err := grpcx.CorrelationUnaryClientInterceptor()(clientCtx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("client interceptor failed: %v", err)
+	}
+
+	var got string
+	handler := func(ctx context.Context, _ any) (any, error) {
+		got = util.CorrelationID(ctx)
+		return nil, nil
+	}
+	serverCtx := metadata.NewIncomingContext(context.Background(), sent)
+	if _, err := grpcx.CorrelationUnaryInterceptor()(serverCtx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("server interceptor failed: %v", err)
+	}
+	if got != "req-1" {
+		t.Errorf("handler correlation ID = %q, want req-1", got)
+	}
+}
+
+func TestCorrelationStreamInterceptorTraceParent(t *testing.T) {
+	md := metadata.Pairs("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	stream := fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+
+	var got string
+	err := grpcx.CorrelationStreamInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(_ any, s grpc.ServerStream) error {
+		got = util.CorrelationID(s.Context())
+		return nil
+	})
+	if err != nil || got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("stream handler correlation ID = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,49 @@
+package grpcx
+
+import (
+	"context"
+
+	"github.com/pitabwire/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusError converts err into a gRPC status error with the code of
+// util.ErrorCodeOf and the user-safe message of util.ErrorMessageOf, so
+// internal detail never reaches clients. Errors that already carry a gRPC
+// status are returned unchanged, and nil gives nil.
+func StatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Code(util.ErrorCodeOf(err).GRPCCode())
+	return status.Error(code, util.ErrorMessageOf(err))
+}
+
+// ErrorUnaryInterceptor returns a server interceptor that converts the
+// errors of handlers with StatusError, the gRPC counterpart of
+// util.ProblemResponse.
+//
+// Example:
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(grpcx.ErrorUnaryInterceptor()),
+//	    grpc.ChainStreamInterceptor(grpcx.ErrorStreamInterceptor()),
+//	)
+func ErrorUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, StatusError(err)
+	}
+}
+
+// ErrorStreamInterceptor is the streaming counterpart of ErrorUnaryInterceptor.
+func ErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return StatusError(handler(srv, stream))
+	}
+}
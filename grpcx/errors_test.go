@@ -0,0 +1,50 @@
+package grpcx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"github.com/pitabwire/util/grpcx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorUnaryInterceptor(t *testing.T) {
+	for _, tc := range []struct {
+		err         error
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{
+			err:         util.NewError(util.CodeNotFound, "order not found").WithDetail("order 42 in shard 3"),
+			wantCode:    codes.NotFound,
+			wantMessage: "order not found",
+		},
+		{err: errors.New("dial tcp 10.0.0.1: refused"), wantCode: codes.Internal, wantMessage: "Internal Server Error"},
+		{err: context.DeadlineExceeded, wantCode: codes.DeadlineExceeded, wantMessage: "Gateway Timeout"},
+		{err: status.Error(codes.Aborted, "as is"), wantCode: codes.Aborted, wantMessage: "as is"},
+	} {
+		handler := func(context.Context, any) (any, error) { return nil, tc.err }
+		_, err := grpcx.ErrorUnaryInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+		st, _ := status.FromError(err)
+		if st.Code() != tc.wantCode || st.Message() != tc.wantMessage {
+			t.Errorf("status for %v = %v %q, want %v %q", tc.err, st.Code(), st.Message(), tc.wantCode, tc.wantMessage)
+		}
+	}
+
+	ok := func(context.Context, any) (any, error) { return "done", nil }
+	if resp, err := grpcx.ErrorUnaryInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, ok); err != nil || resp != "done" {
+		t.Errorf("interceptor = %v, %v; want done", resp, err)
+	}
+}
+
+func TestErrorStreamInterceptor(t *testing.T) {
+	err := grpcx.ErrorStreamInterceptor()(nil, fakeServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{},
+		func(any, grpc.ServerStream) error { return util.NewError(util.CodePermissionDenied, "not yours") })
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("stream status code = %v, want PermissionDenied", status.Code(err))
+	}
+}
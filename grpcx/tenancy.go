@@ -0,0 +1,155 @@
+// Package grpcx provides gRPC interceptors for the util package, kept in a
+// separate module so that util itself does not depend on gRPC.
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/pitabwire/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TenancyUnaryInterceptor returns a server interceptor that verifies the
+// tenancy metadata of each call and puts the tenancy on the handler context,
+// mirroring util.WithTenancyFromHeaders.
+//
+// The metadata keys are the lowercase forms of the util tenancy headers
+// (x-tenant-id, x-tenancy-signature and so on), so HTTP and gRPC services
+// share one key, one signature format and one tenancy model. Calls with
+// invalid or expired metadata fail with codes.Unauthenticated; calls without
+// tenancy metadata proceed without tenancy unless
+// util.WithTenancyHeaderRequired is set.
+//
+// Example:
+//
+//	server := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(grpcx.TenancyUnaryInterceptor(util.WithTenancyHeaderKey(key))),
+//	    grpc.ChainStreamInterceptor(grpcx.TenancyStreamInterceptor(util.WithTenancyHeaderKey(key))),
+//	)
+func TenancyUnaryInterceptor(opts ...util.TenancyHeaderOption) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := tenancyFromIncoming(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// TenancyStreamInterceptor is the streaming counterpart of TenancyUnaryInterceptor.
+func TenancyStreamInterceptor(opts ...util.TenancyHeaderOption) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := tenancyFromIncoming(stream.Context(), opts)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// TenancyUnaryClientInterceptor returns a client interceptor that signs the
+// tenancy on the call context into outgoing metadata, mirroring
+// util.InjectTenancyHeaders. Calls whose context carries no tenancy are sent
+// unchanged.
+//
+// Example:
+//
+//	conn, err := grpc.NewClient(target,
+//	    grpc.WithChainUnaryInterceptor(grpcx.TenancyUnaryClientInterceptor(util.WithTenancyHeaderKey(key))),
+//	    grpc.WithChainStreamInterceptor(grpcx.TenancyStreamClientInterceptor(util.WithTenancyHeaderKey(key))),
+//	)
+func TenancyUnaryClientInterceptor(opts ...util.TenancyHeaderOption) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		ctx, err := tenancyToOutgoing(ctx, opts)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
+
+// TenancyStreamClientInterceptor is the streaming counterpart of TenancyUnaryClientInterceptor.
+func TenancyStreamClientInterceptor(opts ...util.TenancyHeaderOption) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		callOpts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, err := tenancyToOutgoing(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, callOpts...)
+	}
+}
+
+// tenancyFromIncoming verifies the tenancy in the incoming metadata of ctx.
+func tenancyFromIncoming(ctx context.Context, opts []util.TenancyHeaderOption) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	// http.Header.Add canonicalizes the lowercase metadata keys.
+	h := make(http.Header, len(md))
+	for key, values := range md {
+		for _, value := range values {
+			h.Add(key, value)
+		}
+	}
+
+	ctx, err := util.ContextWithTenancyHeaders(ctx, h, opts...)
+	if err != nil {
+		if errors.Is(err, util.ErrMissingTenancy) {
+			return nil, status.Error(codes.Unauthenticated, "tenancy metadata is required")
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid tenancy metadata")
+	}
+	return ctx, nil
+}
+
+// tenancyToOutgoing appends the signed tenancy on ctx to its outgoing metadata.
+func tenancyToOutgoing(ctx context.Context, opts []util.TenancyHeaderOption) (context.Context, error) {
+	tenancy := util.GetTenancy(ctx)
+	if tenancy == nil {
+		return ctx, nil
+	}
+
+	h := http.Header{}
+	if err := util.SetTenancyHeaders(h, tenancy, opts...); err != nil {
+		return nil, err
+	}
+
+	pairs := make([]string, 0, 2*len(h)) //nolint:mnd // key and value per header
+	for key, values := range h {
+		for _, value := range values {
+			pairs = append(pairs, key, value)
+		}
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...), nil
+}
+
+// contextServerStream overrides the context of a server stream, for the
+// tenancy and baggage interceptors.
+type contextServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+// Context returns the context set by the interceptor.
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,131 @@
+package grpcx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"github.com/pitabwire/util/grpcx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef") //nolint:gochecknoglobals // test fixture
+
+// outgoingToIncoming signs tenancy through the client interceptor and returns
+// a server context carrying the resulting metadata.
+func outgoingToIncoming(t *testing.T, ctx context.Context) context.Context {
+	t.Helper()
+	var sent metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	interceptor := grpcx.TenancyUnaryClientInterceptor(util.WithTenancyHeaderKey(testKey))
+	if err := interceptor(ctx, "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("client interceptor failed: %v", err)
+	}
+	return metadata.NewIncomingContext(context.Background(), sent)
+}
+
+func TestTenancyUnaryInterceptors(t *testing.T) {
+	clientCtx := util.NewTenancy().TenantID("acme").PartitionID("eu").ProfileID("user-7").Context(context.Background())
+	serverCtx := outgoingToIncoming(t, clientCtx)
+
+	var got util.TenancyInfo
+	handler := func(ctx context.Context, _ any) (any, error) {
+		got = util.GetTenancy(ctx)
+		return "ok", nil
+	}
+	interceptor := grpcx.TenancyUnaryInterceptor(util.WithTenancyHeaderKey(testKey))
+	if _, err := interceptor(serverCtx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("server interceptor failed: %v", err)
+	}
+	if got == nil || got.GetTenantID() != "acme" || got.GetPartitionID() != "eu" {
+		t.Fatalf("handler tenancy = %+v, want acme/eu", got)
+	}
+	if profile, _ := got.(util.ProfileBearer); profile == nil || profile.GetProfileID() != "user-7" {
+		t.Errorf("handler tenancy profile = %+v, want user-7", got)
+	}
+}
+
+func TestTenancyUnaryInterceptorRejectsForgedMetadata(t *testing.T) {
+	serverCtx := outgoingToIncoming(t, util.NewTenancy().TenantID("acme").Context(context.Background()))
+	md, _ := metadata.FromIncomingContext(serverCtx)
+	md = md.Copy()
+	md.Set("x-tenant-id", "other")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []util.TenancyHeaderOption
+		want codes.Code
+	}{
+		{"forged", metadata.NewIncomingContext(context.Background(), md), nil, codes.Unauthenticated},
+		{"missing", context.Background(), nil, codes.OK},
+		{"missing when required", context.Background(),
+			[]util.TenancyHeaderOption{util.WithTenancyHeaderRequired()}, codes.Unauthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]util.TenancyHeaderOption{util.WithTenancyHeaderKey(testKey)}, tt.opts...)
+			interceptor := grpcx.TenancyUnaryInterceptor(opts...)
+			_, err := interceptor(tt.ctx, nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+				return nil, nil
+			})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s fakeServerStream) Context() context.Context { return s.ctx }
+
+func TestTenancyStreamInterceptors(t *testing.T) {
+	clientCtx := util.NewTenancy().TenantID("acme").Context(context.Background())
+
+	var sent metadata.MD
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string,
+		_ ...grpc.CallOption) (grpc.ClientStream, error) {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return nil, nil
+	}
+	clientInterceptor := grpcx.TenancyStreamClientInterceptor(util.WithTenancyHeaderKey(testKey))
+	if _, err := clientInterceptor(clientCtx, &grpc.StreamDesc{}, nil, "/svc/Stream", streamer); err != nil {
+		t.Fatalf("client stream interceptor failed: %v", err)
+	}
+
+	var got util.TenancyInfo
+	stream := fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), sent)}
+	serverInterceptor := grpcx.TenancyStreamInterceptor(util.WithTenancyHeaderKey(testKey))
+	err := serverInterceptor(nil, stream, &grpc.StreamServerInfo{}, func(_ any, s grpc.ServerStream) error {
+		got = util.GetTenancy(s.Context())
+		return nil
+	})
+	if err != nil || got == nil || got.GetTenantID() != "acme" {
+		t.Errorf("stream handler tenancy = %+v, %v; want acme", got, err)
+	}
+}
+
+func TestTenancyClientInterceptorWithoutTenancy(t *testing.T) {
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md) > 0 {
+			t.Errorf("outgoing metadata = %v, want none", md)
+		}
+		return nil
+	}
+	interceptor := grpcx.TenancyUnaryClientInterceptor(util.WithTenancyHeaderKey(testKey))
+	if err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, invoker); err != nil {
+		t.Errorf("client interceptor failed: %v", err)
+	}
+}
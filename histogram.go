@@ -0,0 +1,161 @@
+package util
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets returns the bucket upper bounds NewLatencyHistogram
+// uses when given none, from 5ms to 10s, suited to request latencies.
+func DefaultLatencyBuckets() []time.Duration {
+	return []time.Duration{
+		5 * time.Millisecond,
+		10 * time.Millisecond,
+		25 * time.Millisecond,
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		250 * time.Millisecond,
+		500 * time.Millisecond,
+		time.Second,
+		2500 * time.Millisecond,
+		5 * time.Second,
+		10 * time.Second,
+	}
+}
+
+// LatencyHistogram counts durations into buckets, from which quantiles such
+// as the 99th percentile latency are estimated, in constant memory. It is
+// safe for concurrent use, and Observe does not lock.
+//
+// Example:
+//
+//	latency := NewLatencyHistogram(nil)
+//	defer latency.Time()()
+//	...
+//	Log(ctx).WithField("p99", latency.Quantile(0.99)).Info("query latency")
+type LatencyHistogram struct {
+	bounds []time.Duration
+	// counts has one count per bound, and a last one for longer durations
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    atomic.Int64
+}
+
+// HistogramBucket is a bucket of a LatencyHistogram.
+type HistogramBucket struct {
+	// UpperBound is the longest duration counted in the bucket.
+	UpperBound time.Duration
+
+	// Count is the number of durations up to UpperBound, including those of
+	// the preceding buckets.
+	Count uint64
+}
+
+// NewLatencyHistogram returns an empty LatencyHistogram with the given
+// bucket upper bounds, or DefaultLatencyBuckets when buckets is empty.
+// Durations longer than the last bound are counted in an overflow bucket.
+// Quantile estimates are only as precise as the buckets around them, so
+// choose bounds close together around the latencies of interest.
+func NewLatencyHistogram(buckets []time.Duration) *LatencyHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets()
+	}
+	bounds := slices.Compact(slices.Sorted(slices.Values(buckets)))
+	return &LatencyHistogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// Observe records a duration.
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	i, _ := slices.BinarySearch(h.bounds, d)
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+	h.count.Add(1)
+}
+
+// Time starts timing an operation and returns the function that records
+// it, for use with defer.
+func (h *LatencyHistogram) Time() func() {
+	sw := NewStopwatch()
+	return func() { h.Observe(sw.Elapsed()) }
+}
+
+// Count returns the number of durations recorded.
+func (h *LatencyHistogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Sum returns the total of the durations recorded.
+func (h *LatencyHistogram) Sum() time.Duration {
+	return time.Duration(h.sum.Load())
+}
+
+// Buckets returns the cumulative count of each bucket, in increasing order
+// of UpperBound, ending with the overflow bucket, whose UpperBound is the
+// longest time.Duration.
+func (h *LatencyHistogram) Buckets() []HistogramBucket {
+	buckets := make([]HistogramBucket, len(h.counts))
+	var cumulative uint64
+	for i := range h.counts {
+		cumulative += h.counts[i].Load()
+		buckets[i] = HistogramBucket{UpperBound: time.Duration(math.MaxInt64), Count: cumulative}
+		if i < len(h.bounds) {
+			buckets[i].UpperBound = h.bounds[i]
+		}
+	}
+	return buckets
+}
+
+// Quantile estimates the q-quantile, for q between 0 and 1, of the durations
+// recorded, interpolating linearly within the bucket it falls in; 0.99 gives
+// the 99th percentile. It returns 0 when nothing was recorded, and the last
+// bucket bound when the quantile falls in the overflow bucket.
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	buckets := h.Buckets()
+	total := buckets[len(buckets)-1].Count
+	if total == 0 {
+		return 0
+	}
+	rank := min(max(q, 0), 1) * float64(total)
+
+	var lower time.Duration
+	var below uint64
+	for i, bucket := range buckets[:len(h.bounds)] {
+		if float64(bucket.Count) >= rank && bucket.Count > below {
+			fraction := (rank - float64(below)) / float64(bucket.Count-below)
+			return lower + time.Duration(fraction*float64(bucket.UpperBound-lower))
+		}
+		lower, below = h.bounds[i], bucket.Count
+	}
+	return h.bounds[len(h.bounds)-1]
+}
+
+func (h *LatencyHistogram) kind() string { return "histogram" }
+
+func (h *LatencyHistogram) samples() []metricSample {
+	buckets := h.Buckets()
+	samples := make([]metricSample, 0, len(buckets)+2) //nolint:mnd // the sum and count samples
+	for i, bucket := range buckets {
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = strconv.FormatFloat(bucket.UpperBound.Seconds(), 'g', -1, 64)
+		}
+		samples = append(samples, metricSample{suffix: `_bucket{le="` + le + `"}`, value: float64(bucket.Count)})
+	}
+	return append(samples,
+		metricSample{suffix: "_sum", value: h.Sum().Seconds()},
+		metricSample{suffix: "_count", value: float64(buckets[len(buckets)-1].Count)},
+	)
+}
+
+func (h *LatencyHistogram) expvarValue() any {
+	return map[string]any{
+		"count":   h.Count(),
+		"seconds": h.Sum().Seconds(),
+		"p50":     h.Quantile(0.5).Seconds(),  //nolint:mnd // median
+		"p90":     h.Quantile(0.9).Seconds(),  //nolint:mnd // 90th percentile
+		"p99":     h.Quantile(0.99).Seconds(), //nolint:mnd // 99th percentile
+	}
+}
@@ -0,0 +1,104 @@
+package util_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestLatencyHistogramQuantile(t *testing.T) {
+	h := util.NewLatencyHistogram([]time.Duration{100 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond})
+	if got := h.Quantile(0.5); got != 0 {
+		t.Fatalf("Quantile of an empty histogram = %v, want 0", got)
+	}
+
+	for range 50 {
+		h.Observe(5 * time.Millisecond)
+	}
+	for range 50 {
+		h.Observe(50 * time.Millisecond)
+	}
+	if h.Count() != 100 || h.Sum() != 2750*time.Millisecond {
+		t.Fatalf("Count, Sum = %d, %v", h.Count(), h.Sum())
+	}
+
+	want := []util.HistogramBucket{
+		{UpperBound: 10 * time.Millisecond, Count: 50},
+		{UpperBound: 100 * time.Millisecond, Count: 100},
+		{UpperBound: 1<<63 - 1, Count: 100},
+	}
+	buckets := h.Buckets()
+	if len(buckets) != len(want) {
+		t.Fatalf("Buckets = %v, want %v", buckets, want)
+	}
+	for i := range want {
+		if buckets[i] != want[i] {
+			t.Errorf("Buckets()[%d] = %v, want %v", i, buckets[i], want[i])
+		}
+	}
+
+	for q, want := range map[float64]time.Duration{
+		0:    0,
+		0.25: 5 * time.Millisecond,
+		0.5:  10 * time.Millisecond,
+		0.75: 55 * time.Millisecond,
+		1:    100 * time.Millisecond,
+	} {
+		if got := h.Quantile(q); got != want {
+			t.Errorf("Quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+
+	h.Observe(time.Hour)
+	if got := h.Quantile(1); got != 100*time.Millisecond {
+		t.Errorf("Quantile in the overflow bucket = %v, want the last bound", got)
+	}
+}
+
+func TestLatencyHistogramConcurrentObserve(t *testing.T) {
+	h := util.NewLatencyHistogram(nil)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for i := range 1000 {
+				h.Observe(time.Duration(i) * time.Millisecond)
+			}
+		})
+	}
+	wg.Wait()
+	if h.Count() != 8000 {
+		t.Fatalf("Count = %d, want 8000", h.Count())
+	}
+	if last := h.Buckets()[len(util.DefaultLatencyBuckets())]; last.Count != 8000 {
+		t.Fatalf("overflow bucket count = %d, want 8000", last.Count)
+	}
+}
+
+func TestMetricsRegistryHistogram(t *testing.T) {
+	reg := util.NewMetricsRegistry()
+	h := reg.Histogram("query_duration_seconds", "Query latency.", []time.Duration{time.Millisecond, time.Second})
+	if again := reg.Histogram("query_duration_seconds", "", nil); again != h {
+		t.Fatal("Histogram with an existing name returned a new histogram")
+	}
+	h.Observe(500 * time.Microsecond)
+	h.Observe(2 * time.Second)
+
+	var b strings.Builder
+	if err := reg.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	want := `# HELP query_duration_seconds Query latency.
+# TYPE query_duration_seconds histogram
+query_duration_seconds_bucket{le="0.001"} 1
+query_duration_seconds_bucket{le="1"} 1
+query_duration_seconds_bucket{le="+Inf"} 2
+query_duration_seconds_sum 2.0005
+query_duration_seconds_count 2
+`
+	if b.String() != want {
+		t.Fatalf("WritePrometheus =\n%s\nwant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"crypto/ecdh"
+	"crypto/hpke"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// HPKE payload layout: [version][KEM id][KDF id][AEAD id][encapsulated key][ciphertext].
+// The identifiers are the 16-bit RFC 9180 registry values, big-endian.
+const (
+	hpkePayloadVersion = 0x01
+	hpkeHeaderSize     = 7
+	x25519KeySize      = 32
+)
+
+// hpkeOptions contains configuration for HPKESeal and HPKEOpen.
+type hpkeOptions struct {
+	// algorithm selects the HPKE AEAD used by HPKESeal
+	algorithm Algorithm
+
+	// info is application context bound into the key schedule
+	info []byte
+}
+
+// HPKEOption is a function that configures HPKESeal and HPKEOpen.
+type HPKEOption func(*hpkeOptions)
+
+// WithHPKEAlgorithm selects the AEAD used by HPKESeal: AlgorithmAESGCM
+// (AES-256-GCM, the default) or AlgorithmChaCha20Poly1305. HPKEOpen reads the
+// AEAD from the payload and ignores this option.
+func WithHPKEAlgorithm(algo Algorithm) HPKEOption {
+	return func(o *hpkeOptions) {
+		o.algorithm = algo
+	}
+}
+
+// WithHPKEInfo binds the payload to an application context, e.g.
+// "invoices.v1". HPKEOpen must be given the same info.
+func WithHPKEInfo(info []byte) HPKEOption {
+	return func(o *hpkeOptions) {
+		o.info = info
+	}
+}
+
+func newHPKEOptions(opts []HPKEOption) *hpkeOptions {
+	options := &hpkeOptions{algorithm: AlgorithmAESGCM}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// HPKESeal encrypts plaintext to a recipient's X25519 public key using HPKE
+// (RFC 9180) in base mode with DHKEM(X25519, HKDF-SHA256) and HKDF-SHA256.
+//
+// Producers need only the recipient's public key, so no symmetric key has to
+// be shared or distributed. Every payload uses a fresh ephemeral key; only
+// the holder of the matching private key can open it, and the producer
+// itself cannot decrypt what it sealed.
+//
+// Key pairs come from GenerateX25519KeyPair.
+//
+// Security properties:
+//   - Confidentiality and integrity: AES-256-GCM or ChaCha20-Poly1305
+//   - Forward secrecy for the sender: ephemeral keys are discarded after sealing
+//   - Header binding: the version and suite identifiers are part of the HPKE
+//     info, so they cannot be altered without failing decryption
+//
+// The payload format is: [0x01][KEM id][KDF id][AEAD id][encapsulated key][ciphertext+tag].
+//
+// Example:
+//
+//	priv, pub, err := GenerateX25519KeyPair()
+//	payload, err := HPKESeal(pub, []byte("card data"), WithHPKEInfo([]byte("payments.v1")))
+//	plaintext, err := HPKEOpen(priv, payload, WithHPKEInfo([]byte("payments.v1")))
+func HPKESeal(recipientPublicKey, plaintext []byte, opts ...HPKEOption) ([]byte, error) {
+	options := newHPKEOptions(opts)
+	if err := checkFIPSApproved("X25519"); err != nil {
+		return nil, err
+	}
+
+	kem := hpke.DHKEM(ecdh.X25519())
+	kdf := hpke.HKDFSHA256()
+	aead, err := hpkeAEAD(options.algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := kem.NewPublicKey(recipientPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 public key: %w", err)
+	}
+
+	header := make([]byte, hpkeHeaderSize)
+	header[0] = hpkePayloadVersion
+	binary.BigEndian.PutUint16(header[1:], kem.ID())
+	binary.BigEndian.PutUint16(header[3:], kdf.ID())
+	binary.BigEndian.PutUint16(header[5:], aead.ID())
+
+	sealed, err := hpke.Seal(pub, kdf, aead, hpkeInfo(header, options.info), plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("HPKE seal failed: %w", err)
+	}
+	return append(header, sealed...), nil
+}
+
+// HPKEOpen decrypts a payload produced by HPKESeal with the recipient's
+// 32-byte X25519 private key.
+func HPKEOpen(privateKey, payload []byte, opts ...HPKEOption) ([]byte, error) {
+	options := newHPKEOptions(opts)
+
+	if len(payload) < hpkeHeaderSize+x25519KeySize {
+		return nil, errors.New("payload too short to contain HPKE header")
+	}
+	header := payload[:hpkeHeaderSize]
+	if header[0] != hpkePayloadVersion {
+		return nil, fmt.Errorf("unsupported HPKE payload version %d", header[0])
+	}
+	if err := checkFIPSApproved("X25519"); err != nil {
+		return nil, err
+	}
+
+	kem := hpke.DHKEM(ecdh.X25519())
+	kdf := hpke.HKDFSHA256()
+	if binary.BigEndian.Uint16(header[1:]) != kem.ID() || binary.BigEndian.Uint16(header[3:]) != kdf.ID() {
+		return nil, errors.New("unsupported HPKE KEM or KDF")
+	}
+	aead, err := hpkeAEADFromID(binary.BigEndian.Uint16(header[5:]))
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := kem.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+
+	plaintext, err := hpke.Open(priv, kdf, aead, hpkeInfo(header, options.info), payload[hpkeHeaderSize:])
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+// hpkeInfo binds the payload header and the application info into the HPKE key schedule.
+func hpkeInfo(header, info []byte) []byte {
+	out := make([]byte, 0, len(header)+len(info))
+	out = append(out, header...)
+	return append(out, info...)
+}
+
+func hpkeAEAD(algo Algorithm) (hpke.AEAD, error) {
+	if FIPSMode() && !algo.FIPSApproved() {
+		return nil, fmt.Errorf("%w: %s", ErrNotFIPSApproved, algo)
+	}
+
+	switch algo {
+	case AlgorithmAESGCM:
+		return hpke.AES256GCM(), nil
+	case AlgorithmChaCha20Poly1305:
+		return hpke.ChaCha20Poly1305(), nil
+	default:
+		return nil, fmt.Errorf("algorithm %s is not supported for HPKE", algo)
+	}
+}
+
+func hpkeAEADFromID(id uint16) (hpke.AEAD, error) {
+	for _, algo := range []Algorithm{AlgorithmAESGCM, AlgorithmChaCha20Poly1305} {
+		aead, err := hpkeAEAD(algo)
+		if err != nil {
+			continue
+		}
+		if aead.ID() == id {
+			return aead, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported HPKE AEAD 0x%04x", id)
+}
@@ -0,0 +1,110 @@
+package util_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestHPKESealOpen(t *testing.T) {
+	skipInFIPSMode(t, "X25519")
+	priv, pub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("card number 4111111111111111")
+
+	tests := []struct {
+		name   string
+		algo   util.Algorithm
+		aeadID []byte
+	}{
+		{"aes-256-gcm", util.AlgorithmAESGCM, []byte{0x00, 0x02}},
+		{"chacha20-poly1305", util.AlgorithmChaCha20Poly1305, []byte{0x00, 0x03}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, sealErr := util.HPKESeal(pub, plaintext, util.WithHPKEAlgorithm(tt.algo))
+			if sealErr != nil {
+				t.Fatalf("HPKESeal() failed: %v", sealErr)
+			}
+			wantHeader := append([]byte{0x01, 0x00, 0x20, 0x00, 0x01}, tt.aeadID...)
+			if !bytes.HasPrefix(payload, wantHeader) {
+				t.Errorf("HPKESeal() header = %x, want %x", payload[:7], wantHeader)
+			}
+
+			got, openErr := util.HPKEOpen(priv, payload)
+			if openErr != nil {
+				t.Fatalf("HPKEOpen() failed: %v", openErr)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("HPKEOpen() = %q, want %q", got, plaintext)
+			}
+
+			again, sealErr := util.HPKESeal(pub, plaintext, util.WithHPKEAlgorithm(tt.algo))
+			if sealErr != nil {
+				t.Fatalf("HPKESeal() failed: %v", sealErr)
+			}
+			if bytes.Equal(payload, again) {
+				t.Error("HPKESeal() should use a fresh ephemeral key per payload")
+			}
+		})
+	}
+}
+
+func TestHPKEOpenRejects(t *testing.T) {
+	skipInFIPSMode(t, "X25519")
+	priv, pub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPriv, _, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := util.WithHPKEInfo([]byte("payments.v1"))
+
+	payload, err := util.HPKESeal(pub, []byte("secret"), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swappedAEAD := bytes.Clone(payload)
+	swappedAEAD[6] = 0x03
+	tampered := bytes.Clone(payload)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		priv    []byte
+		payload []byte
+		opts    []util.HPKEOption
+	}{
+		{"wrong private key", otherPriv, payload, []util.HPKEOption{info}},
+		{"missing info", priv, payload, nil},
+		{"different info", priv, payload, []util.HPKEOption{util.WithHPKEInfo([]byte("other"))}},
+		{"swapped AEAD identifier", priv, swappedAEAD, []util.HPKEOption{info}},
+		{"tampered ciphertext", priv, tampered, []util.HPKEOption{info}},
+		{"truncated", priv, payload[:20], []util.HPKEOption{info}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, openErr := util.HPKEOpen(tt.priv, tt.payload, tt.opts...); openErr == nil {
+				t.Error("HPKEOpen() should fail")
+			}
+		})
+	}
+}
+
+func TestHPKESealRejectsUnsupportedAlgorithm(t *testing.T) {
+	skipInFIPSMode(t, "X25519")
+	_, pub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = util.HPKESeal(pub, []byte("x"), util.WithHPKEAlgorithm(util.AlgorithmAESSIV)); err == nil {
+		t.Error("HPKESeal() should reject AES-SIV")
+	}
+}
@@ -0,0 +1,180 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	durationDay  = 24 * time.Hour
+	durationWeek = 7 * durationDay
+
+	byteUnitBase = 1024
+)
+
+var (
+	// ErrInvalidByteSize is returned by ParseBytes for malformed sizes.
+	ErrInvalidByteSize = errors.New("invalid byte size")
+
+	// ErrInvalidDuration is returned by ParseDurationExtended for malformed
+	// durations.
+	ErrInvalidDuration = errors.New("invalid duration")
+)
+
+// byteUnits maps the lower-cased units accepted by ParseBytes to their size.
+//
+//nolint:gochecknoglobals,mnd // fixed lookup table
+var byteUnits = map[string]float64{
+	"": 1, "b": 1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15, "eb": 1e18,
+	"k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40, "p": 1 << 50, "e": 1 << 60,
+	"ki": 1 << 10, "mi": 1 << 20, "gi": 1 << 30, "ti": 1 << 40, "pi": 1 << 50, "ei": 1 << 60,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40, "pib": 1 << 50, "eib": 1 << 60,
+}
+
+// ParseBytes parses a size such as "10MiB", "1.5 GB" or "512" into a number
+// of bytes. Units are case-insensitive: KB, MB, GB, ... are decimal (1000),
+// while KiB, MiB, GiB, ..., their Kubernetes forms Ki, Mi, Gi, ... and the
+// single letters K, M, G, ... are binary (1024). A bare number is bytes.
+//
+// Example:
+//
+//	limit, err := ParseBytes(GetEnv("MAX_UPLOAD", "10MiB"))
+func ParseBytes(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	i := strings.IndexFunc(trimmed, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(trimmed)
+	}
+	number, unit := trimmed[:i], strings.ToLower(strings.TrimSpace(trimmed[i:]))
+
+	multiplier, ok := byteUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidByteSize, s)
+	}
+	size := value * multiplier
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q overflows int64", ErrInvalidByteSize, s)
+	}
+	return int64(size), nil
+}
+
+// FormatBytes formats n bytes with the largest binary unit that keeps the
+// value at least one, with up to one decimal: "512 B", "1.5 KiB", "10 MiB".
+func FormatBytes(n int64) string {
+	const units = "KMGTPE"
+	if n < byteUnitBase && n > -byteUnitBase {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	value := float64(n)
+	exp := -1
+	for math.Abs(value) >= byteUnitBase && exp < len(units)-1 {
+		value /= byteUnitBase
+		exp++
+	}
+	return strconv.FormatFloat(math.Round(value*10)/10, 'f', -1, 64) + " " + units[exp:exp+1] + "iB"
+}
+
+// ParseDurationExtended parses a duration like time.ParseDuration, also
+// accepting days ("d", 24 hours) and weeks ("w", 7 days), which
+// configuration values such as retention periods are usually stated in:
+// "1d2h", "2w", "1.5d", "-3d12h".
+//
+// Example:
+//
+//	retention, err := ParseDurationExtended(GetEnv("RETENTION", "30d"))
+func ParseDurationExtended(s string) (time.Duration, error) {
+	rest, negative := s, false
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		negative = rest[0] == '-'
+		rest = rest[1:]
+	}
+	if rest == "0" {
+		return 0, nil
+	}
+	if rest == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+	}
+
+	var total time.Duration
+	for rest != "" {
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+		}
+		j := i + strings.IndexFunc(rest[i:], func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if j < i {
+			j = len(rest)
+		}
+		number, unit := rest[:i], rest[i:j]
+		rest = rest[j:]
+
+		var part time.Duration
+		switch unit {
+		case "d", "w":
+			value, err := strconv.ParseFloat(number, 64)
+			scale := durationDay
+			if unit == "w" {
+				scale = durationWeek
+			}
+			if err != nil || value*float64(scale) >= math.MaxInt64 {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+			part = time.Duration(value * float64(scale))
+		default:
+			d, err := time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidDuration, s)
+			}
+			part = d
+		}
+		if total > math.MaxInt64-part {
+			return 0, fmt.Errorf("%w: %q overflows", ErrInvalidDuration, s)
+		}
+		total += part
+	}
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// FormatDurationShort formats d compactly for logs and CLI output: from a
+// minute up as whole days, hours, minutes and seconds without zero parts
+// ("1d2h", "5m30s"), and below a minute as time.Duration does, rounded to
+// the millisecond ("1.5s", "250ms").
+func FormatDurationShort(d time.Duration) string {
+	if d < 0 {
+		if d == math.MinInt64 {
+			d++ // -d would overflow
+		}
+		return "-" + FormatDurationShort(-d)
+	}
+	if d < time.Minute {
+		if d >= time.Millisecond {
+			d = d.Round(time.Millisecond)
+		}
+		return d.String()
+	}
+
+	var b strings.Builder
+	for _, part := range []struct {
+		unit time.Duration
+		name string
+	}{{durationDay, "d"}, {time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"}} {
+		if n := d / part.unit; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10))
+			b.WriteString(part.name)
+			d -= n * part.unit
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package util_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"10MiB":  10 << 20,
+		"10 mib": 10 << 20,
+		"1.5GB":  1_500_000_000,
+		"2Ki":    2048,
+		"1K":     1024,
+		" 64kb ": 64_000,
+		"0":      0,
+		"3.5 b":  3,
+		"1EiB":   1 << 60,
+		"7.5EiB": 15 << 59,
+	}
+	for in, want := range tests {
+		if got, err := util.ParseBytes(in); err != nil || got != want {
+			t.Errorf("ParseBytes(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "MiB", "10XB", "1.2.3MB", "-5MB", "9EiB"} {
+		if _, err := util.ParseBytes(in); !errors.Is(err, util.ErrInvalidByteSize) {
+			t.Errorf("ParseBytes(%q) = %v, want ErrInvalidByteSize", in, err)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1536:          "1.5 KiB",
+		10 << 20:      "10 MiB",
+		-2048:         "-2 KiB",
+		math.MaxInt64: "8 EiB",
+	}
+	for in, want := range tests {
+		if got := util.FormatBytes(in); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseDurationExtended(t *testing.T) {
+	tests := map[string]time.Duration{
+		"1d2h":     26 * time.Hour,
+		"2w":       14 * 24 * time.Hour,
+		"1.5d":     36 * time.Hour,
+		"-3d12h":   -84 * time.Hour,
+		"90s":      90 * time.Second,
+		"1h30m15s": time.Hour + 30*time.Minute + 15*time.Second,
+		"250ms":    250 * time.Millisecond,
+		"5µs":      5 * time.Microsecond,
+		"0":        0,
+	}
+	for in, want := range tests {
+		if got, err := util.ParseDurationExtended(in); err != nil || got != want {
+			t.Errorf("ParseDurationExtended(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "-", "d", "1x", "1.2.3d", "5", "200000w"} {
+		if _, err := util.ParseDurationExtended(in); !errors.Is(err, util.ErrInvalidDuration) {
+			t.Errorf("ParseDurationExtended(%q) = %v, want ErrInvalidDuration", in, err)
+		}
+	}
+}
+
+func TestFormatDurationShort(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                              "0s",
+		250 * time.Millisecond:         "250ms",
+		1500*time.Millisecond + 300:    "1.5s",
+		5*time.Minute + 30*time.Second: "5m30s",
+		26 * time.Hour:                 "1d2h",
+		-(90 * time.Minute):            "-1h30m",
+		3*24*time.Hour + 4*time.Second: "3d4s",
+	}
+	for in, want := range tests {
+		if got := util.FormatDurationShort(in); got != want {
+			t.Errorf("FormatDurationShort(%v) = %q, want %q", in, got, want)
+		}
+	}
+	if got := util.FormatDurationShort(math.MinInt64); got != "-106751d23h47m16s" {
+		t.Errorf("FormatDurationShort(MinInt64) = %q", got)
+	}
+}
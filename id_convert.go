@@ -0,0 +1,149 @@
+package util
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/xid"
+)
+
+const (
+	uuidLength      = 36
+	uuidHexLength   = 32
+	xidStringLength = 20
+	xidSize         = len(xid.ID{})
+)
+
+// ErrUnknownIDFormat is returned by AnyIDToBytes for strings that are not
+// a UUID, ULID, typed ID or xid.
+var ErrUnknownIDFormat = errors.New("unknown ID format")
+
+// ULIDToUUID returns the UUID with the same 128 bits as a ULID, in the
+// canonical lowercase 8-4-4-4-12 form. The conversion is lossless and
+// UUIDToULID reverses it, so a ULID can be stored in a UUID column.
+//
+// Example:
+//
+//	uuid, err := ULIDToUUID("01J1QW4C9Z8K3V6R0M5E2A7D4B")
+func ULIDToUUID(id string) (string, error) {
+	raw, err := decodeULID(id)
+	if err != nil {
+		return "", err
+	}
+	return formatUUID(raw), nil
+}
+
+// UUIDToULID returns the ULID with the same 128 bits as uuid, which may be
+// in the 8-4-4-4-12 form or 32 hexadecimal digits. The ULID timestamp of a
+// UUID that was not converted from a ULID is meaningless, though the
+// conversion is still lossless.
+func UUIDToULID(uuid string) (string, error) {
+	raw, err := parseUUID(uuid)
+	if err != nil {
+		return "", err
+	}
+	return encodeULIDBytes(raw), nil
+}
+
+// XIDToUUID returns a UUID holding the 12 bytes of an xid produced by
+// IDString followed by four zero bytes. UUIDs from xids sort in the same
+// order as the xids, and UUIDToXID reverses the conversion.
+//
+// There is no conversion between xids and ULIDs: a ULID would carry a
+// wrong timestamp, and a ULID does not fit in an xid.
+func XIDToUUID(id string) (string, error) {
+	raw, err := xidBytes(id)
+	if err != nil {
+		return "", err
+	}
+	return formatUUID(raw), nil
+}
+
+// UUIDToXID reverses XIDToUUID. UUIDs not produced by XIDToUUID, whose last
+// four bytes are not zero, cannot be represented as an xid and return an error.
+func UUIDToXID(uuid string) (string, error) {
+	raw, err := parseUUID(uuid)
+	if err != nil {
+		return "", err
+	}
+	if [ulidSize - xidSize]byte(raw[xidSize:]) != [ulidSize - xidSize]byte{} {
+		return "", fmt.Errorf("UUID %q was not converted from an xid", uuid)
+	}
+	return xid.ID(raw[:xidSize]).String(), nil
+}
+
+// AnyIDToBytes normalizes a UUID, ULID, typed ID (see NewTypedID) or xid to
+// 16 bytes, detecting the format from its length. The same identifier in any
+// of its converted forms yields the same bytes, so systems migrating between
+// ID schemes can index and compare old and new IDs uniformly. xids are
+// padded as by XIDToUUID.
+//
+// Returns an error wrapping ErrUnknownIDFormat when id matches none of the formats.
+//
+// Example:
+//
+//	key, err := AnyIDToBytes(r.PathValue("id")) // accepts old xids and new ULIDs
+func AnyIDToBytes(id string) ([]byte, error) {
+	if strings.Contains(id, typedIDSeparator) {
+		typed, err := ParseTypedID(id, "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnknownIDFormat, err)
+		}
+		id = typed.ULID
+	}
+
+	var raw [ulidSize]byte
+	var err error
+	switch len(id) {
+	case uuidLength, uuidHexLength:
+		raw, err = parseUUID(id)
+	case ulidLength:
+		raw, err = decodeULID(id)
+	case xidStringLength:
+		raw, err = xidBytes(id)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownIDFormat, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownIDFormat, err)
+	}
+	return raw[:], nil
+}
+
+func xidBytes(id string) ([ulidSize]byte, error) {
+	var raw [ulidSize]byte
+	parsed, err := xid.FromString(id)
+	if err != nil {
+		return raw, fmt.Errorf("invalid xid %q: %w", id, err)
+	}
+	copy(raw[:], parsed[:])
+	return raw, nil
+}
+
+// parseUUID decodes a UUID in the 8-4-4-4-12 form or as 32 hexadecimal digits.
+func parseUUID(uuid string) ([ulidSize]byte, error) {
+	var raw [ulidSize]byte
+	digits := uuid
+	if len(uuid) == uuidLength {
+		for _, pos := range []int{8, 13, 18, 23} {
+			if uuid[pos] != '-' {
+				return raw, fmt.Errorf("invalid UUID %q", uuid)
+			}
+		}
+		digits = strings.ReplaceAll(uuid, "-", "")
+	}
+	if len(digits) != uuidHexLength {
+		return raw, fmt.Errorf("invalid UUID %q", uuid)
+	}
+	if _, err := hex.Decode(raw[:], []byte(digits)); err != nil {
+		return raw, fmt.Errorf("invalid UUID %q: %w", uuid, err)
+	}
+	return raw, nil
+}
+
+func formatUUID(raw [ulidSize]byte) string {
+	digits := hex.EncodeToString(raw[:])
+	return digits[0:8] + "-" + digits[8:12] + "-" + digits[12:16] + "-" + digits[16:20] + "-" + digits[20:]
+}
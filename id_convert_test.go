@@ -0,0 +1,89 @@
+package util_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestULIDUUIDConversion(t *testing.T) {
+	id := util.ULID()
+	uuid, err := util.ULIDToUUID(id)
+	if err != nil {
+		t.Fatalf("ULIDToUUID() error = %v", err)
+	}
+	if len(uuid) != 36 || strings.Count(uuid, "-") != 4 {
+		t.Errorf("ULIDToUUID() = %q, want the 8-4-4-4-12 form", uuid)
+	}
+
+	back, err := util.UUIDToULID(uuid)
+	if err != nil || back != id {
+		t.Errorf("UUIDToULID(%q) = %q, %v; want %q", uuid, back, err, id)
+	}
+	if back, err = util.UUIDToULID(strings.ToUpper(strings.ReplaceAll(uuid, "-", ""))); err != nil || back != id {
+		t.Errorf("UUIDToULID() of the bare hex form = %q, %v; want %q", back, err, id)
+	}
+
+	if got, _ := util.ULIDToUUID("00000000000000000000000000"); got != "00000000-0000-0000-0000-000000000000" {
+		t.Errorf("ULIDToUUID(zero) = %q", got)
+	}
+	if got, _ := util.UUIDToULID("ffffffff-ffff-ffff-ffff-ffffffffffff"); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("UUIDToULID(max) = %q, want the maximum ULID", got)
+	}
+
+	for _, invalid := range []string{"", "not-a-uuid", "0000000000000000000000000000000g", "00000000x0000-0000-0000-000000000000"} {
+		if _, err = util.UUIDToULID(invalid); err == nil {
+			t.Errorf("UUIDToULID(%q) succeeded", invalid)
+		}
+	}
+}
+
+func TestXIDUUIDConversion(t *testing.T) {
+	id := util.IDString()
+	uuid, err := util.XIDToUUID(id)
+	if err != nil || !strings.HasSuffix(uuid, "00000000") {
+		t.Fatalf("XIDToUUID() = %q, %v; want a UUID ending in zeros", uuid, err)
+	}
+	if back, backErr := util.UUIDToXID(uuid); backErr != nil || back != id {
+		t.Errorf("UUIDToXID(%q) = %q, %v; want %q", uuid, back, backErr, id)
+	}
+
+	ulidUUID, _ := util.ULIDToUUID(util.ULID())
+	if _, err = util.UUIDToXID(ulidUUID); err == nil {
+		t.Errorf("UUIDToXID(%q) succeeded for a UUID not converted from an xid", ulidUUID)
+	}
+}
+
+func TestAnyIDToBytes(t *testing.T) {
+	ulid := util.ULID()
+	uuid, _ := util.ULIDToUUID(ulid)
+	typed := "usr_" + strings.ToLower(ulid)
+
+	want, err := util.AnyIDToBytes(ulid)
+	if err != nil || len(want) != 16 {
+		t.Fatalf("AnyIDToBytes(%q) = %x, %v; want 16 bytes", ulid, want, err)
+	}
+	for _, id := range []string{uuid, strings.ReplaceAll(uuid, "-", ""), strings.ToLower(ulid), typed} {
+		got, convErr := util.AnyIDToBytes(id)
+		if convErr != nil || !bytes.Equal(got, want) {
+			t.Errorf("AnyIDToBytes(%q) = %x, %v; want %x", id, got, convErr, want)
+		}
+	}
+
+	xid := util.IDString()
+	xidUUID, _ := util.XIDToUUID(xid)
+	fromXID, _ := util.AnyIDToBytes(xid)
+	fromUUID, _ := util.AnyIDToBytes(xidUUID)
+	if !bytes.Equal(fromXID, fromUUID) {
+		t.Errorf("AnyIDToBytes() of an xid = %x, of its UUID = %x; want equal", fromXID, fromUUID)
+	}
+
+	for _, invalid := range []string{"", "short", "usr_notaulid", strings.Repeat("!", 26)} {
+		if _, err = util.AnyIDToBytes(invalid); !errors.Is(err, util.ErrUnknownIDFormat) {
+			t.Errorf("AnyIDToBytes(%q) error = %v, want ErrUnknownIDFormat", invalid, err)
+		}
+	}
+}
@@ -6,64 +6,89 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
 // GetIP retrieves the client's IP address from an HTTP request.
 // It checks for common proxy headers and falls back to the remote address.
+//
+// The headers are believed whoever sent them, so clients can spoof the
+// result. Use an IPResolver when the IP is used for rate limiting, auditing
+// or access control.
 func GetIP(r *http.Request) string {
-	// 1. Check for X-Forwarded-For header
-	xForwardedFor := r.Header.Get("X-Forwarded-For")
-	if xForwardedFor != "" {
-		// The X-Forwarded-For header can contain a comma-separated list of IPs.
-		// The first one is the original client.
-		ips := strings.Split(xForwardedFor, ",")
-		for i, ip := range ips {
-			ips[i] = strings.TrimSpace(ip)
-		}
-		// It's important to return the first IP in the list.
-		if len(ips) > 0 {
-			return ips[0]
+	addr, err := GetAddr(r)
+	if err != nil {
+		return remoteHost(r.RemoteAddr)
+	}
+	return addr.String()
+}
+
+// GetAddr returns the client's IP address like GetIP, as a netip.Addr that
+// is validated, comparable and allocation free. IPv4-mapped IPv6 addresses
+// are unmapped. Malformed header values are skipped.
+//
+// Returns an error when neither the headers nor RemoteAddr hold an address.
+func GetAddr(r *http.Request) (netip.Addr, error) {
+	// The X-Forwarded-For header can contain a comma-separated list of IPs.
+	// The first one is the original client.
+	if first, _, _ := strings.Cut(r.Header.Get(HeaderForwardedFor), ","); first != "" {
+		if addr, err := parseForwardedAddr(first); err == nil {
+			return addr, nil
 		}
 	}
 
-	// 2. Check for X-Real-IP header
-	xRealIP := r.Header.Get("X-Real-IP")
-	if xRealIP != "" {
-		return xRealIP
+	if realIP := r.Header.Get(HeaderRealIP); realIP != "" {
+		if addr, err := parseForwardedAddr(realIP); err == nil {
+			return addr, nil
+		}
 	}
 
-	// 3. Fallback to RemoteAddr
-	// RemoteAddr contains IP and port, so we need to split it.
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	// RemoteAddr contains IP and port, or sometimes just the IP.
+	addr, err := netip.ParseAddr(remoteHost(r.RemoteAddr))
 	if err != nil {
-		// If splitting fails, it might be just an IP address without a port.
-		return r.RemoteAddr
+		return netip.Addr{}, fmt.Errorf("no client address in request: %w", err)
 	}
-
-	return ip
+	return addr.Unmap(), nil
 }
 
 // GetLocalIP convenience method that obtains the non localhost ip address for machine running app.
+// The address is whichever the system lists first; use GetLocalIPWith to
+// choose the IP version or interface.
 func GetLocalIP() string {
-	addrs, _ := net.InterfaceAddrs()
+	addr := GetLocalAddr()
+	if !addr.IsValid() {
+		return ""
+	}
+	return addr.String()
+}
 
-	currentIP := ""
+// GetLocalAddr returns the first non-loopback address of the machine, or a
+// loopback address when it has no other, or the zero Addr when it has none.
+func GetLocalAddr() netip.Addr {
+	addrs, _ := net.InterfaceAddrs()
 
+	var current netip.Addr
 	for _, address := range addrs {
-		if ipnet, ok := address.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			currentIP = ipnet.IP.String()
-			break
+		ipnet, ok := address.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipnet.IP)
+		if !ok {
+			continue
 		}
-		if ipnet, ok := address.(*net.IPNet); ok {
-			currentIP = ipnet.IP.String()
+		current = addr.Unmap()
+		if !current.IsLoopback() {
+			break
 		}
 	}
-
-	return currentIP
+	return current
 }
 
 // GetMacAddress convenience method to get some unique address based on the network interfaces the application is running on.
+// The result exposes the hardware address and changes with the network
+// configuration; use MachineFingerprint for a stable, anonymous identifier.
 func GetMacAddress() string {
 	currentIP := GetLocalIP()
 
@@ -80,3 +105,50 @@ func GetMacAddress() string {
 	}
 	return ""
 }
+
+// Prefix lengths kept by AnonymizeIP by default: the /24 network of an IPv4
+// address and the /48 site of an IPv6 address.
+const (
+	DefaultAnonymizeIPv4Bits = 24
+	DefaultAnonymizeIPv6Bits = 48
+)
+
+// AnonymizeIP zeroes the host bits of addr, keeping the first v4Bits of an
+// IPv4 address or v6Bits of an IPv6 address, so client IPs can be logged for
+// abuse analysis without identifying individual users, as GDPR expects. A
+// bit count <= 0 selects the default; counts beyond the address length keep
+// the whole address.
+//
+// Example:
+//
+//	addr, _ := GetAddr(r)
+//	log.WithField("client_ip", AnonymizeIP(addr, 0, 0)).Info("request served") // 203.0.113.0
+func AnonymizeIP(addr netip.Addr, v4Bits, v6Bits int) netip.Addr {
+	addr = addr.Unmap()
+	bits := v6Bits
+	if addr.Is4() {
+		bits = v4Bits
+		if bits <= 0 {
+			bits = DefaultAnonymizeIPv4Bits
+		}
+	} else if bits <= 0 {
+		bits = DefaultAnonymizeIPv6Bits
+	}
+
+	prefix, err := addr.Prefix(min(bits, addr.BitLen()))
+	if err != nil {
+		return netip.Addr{}
+	}
+	return prefix.Addr()
+}
+
+// AnonymizeIPString anonymizes a textual address, such as the result of
+// GetIP, with the default prefix lengths. Values that are not an IP address
+// are replaced with an empty string rather than logged as is.
+func AnonymizeIPString(ip string) string {
+	addr, err := parseForwardedAddr(ip)
+	if err != nil {
+		return ""
+	}
+	return AnonymizeIP(addr, 0, 0).String()
+}
@@ -0,0 +1,90 @@
+package util_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestGetAddr(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"remote address", "203.0.113.7:4321", nil, "203.0.113.7"},
+		{"remote address without port", "203.0.113.7", nil, "203.0.113.7"},
+		{"first forwarded address", "10.0.0.1:80", map[string]string{"X-Forwarded-For": " 198.51.100.9 , 10.0.0.2"}, "198.51.100.9"},
+		{"real IP", "10.0.0.1:80", map[string]string{"X-Real-IP": "198.51.100.10"}, "198.51.100.10"},
+		{"malformed header is skipped", "10.0.0.1:80", map[string]string{"X-Forwarded-For": "garbage"}, "10.0.0.1"},
+		{"IPv4-mapped address is unmapped", "[::ffff:192.0.2.1]:80", nil, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			addr, err := util.GetAddr(req)
+			if err != nil || addr != netip.MustParseAddr(tt.want) {
+				t.Errorf("GetAddr() = %v, %v; want %s", addr, err, tt.want)
+			}
+			if got := util.GetIP(req); got != tt.want {
+				t.Errorf("GetIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "pipe"
+	if _, err := util.GetAddr(req); err == nil {
+		t.Error("GetAddr() succeeded without any address")
+	}
+	if got := util.GetIP(req); got != "pipe" {
+		t.Errorf("GetIP() = %q, want the raw RemoteAddr", got)
+	}
+}
+
+func TestGetLocalAddr(t *testing.T) {
+	addr := util.GetLocalAddr()
+	if got := util.GetLocalIP(); addr.IsValid() && got != addr.String() {
+		t.Errorf("GetLocalIP() = %q, want %q", got, addr)
+	}
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		addr           string
+		v4Bits, v6Bits int
+		want           string
+	}{
+		{"203.0.113.77", 0, 0, "203.0.113.0"},
+		{"203.0.113.77", 16, 0, "203.0.0.0"},
+		{"203.0.113.77", 64, 0, "203.0.113.77"},
+		{"::ffff:203.0.113.77", 0, 0, "203.0.113.0"},
+		{"2001:db8:abcd:12:1:2:3:4", 0, 0, "2001:db8:abcd::"},
+		{"2001:db8:abcd:12:1:2:3:4", 0, 64, "2001:db8:abcd:12::"},
+	}
+	for _, tt := range tests {
+		if got := util.AnonymizeIP(netip.MustParseAddr(tt.addr), tt.v4Bits, tt.v6Bits); got.String() != tt.want {
+			t.Errorf("AnonymizeIP(%s, %d, %d) = %s, want %s", tt.addr, tt.v4Bits, tt.v6Bits, got, tt.want)
+		}
+	}
+
+	if got := util.AnonymizeIPString("198.51.100.9"); got != "198.51.100.0" {
+		t.Errorf("AnonymizeIPString() = %q, want 198.51.100.0", got)
+	}
+	if got := util.AnonymizeIPString("not-an-ip"); got != "" {
+		t.Errorf("AnonymizeIPString(invalid) = %q, want empty", got)
+	}
+	if got := util.AnonymizeIP(netip.Addr{}, 0, 0); got.IsValid() {
+		t.Errorf("AnonymizeIP(zero) = %v, want the zero Addr", got)
+	}
+}
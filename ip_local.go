@@ -0,0 +1,178 @@
+package util
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// ErrNoLocalIP is returned when no network interface has an address
+// matching the requested criteria.
+var ErrNoLocalIP = errors.New("no matching local IP address")
+
+// localIPOptions contains configuration for GetLocalIPWith and GetLocalIPs.
+type localIPOptions struct {
+	// preferIPv6 ranks IPv6 addresses before IPv4 ones
+	preferIPv6 bool
+
+	// interfaces restricts candidates to the named interfaces, in order of preference
+	interfaces []string
+
+	// linkLocal includes link-local addresses (169.254.0.0/16, fe80::/10)
+	linkLocal bool
+
+	// loopback includes loopback addresses
+	loopback bool
+}
+
+// LocalIPOption is a function that configures GetLocalIPWith and GetLocalIPs.
+type LocalIPOption func(*localIPOptions)
+
+// WithLocalIPPreferIPv6 ranks IPv6 addresses before IPv4 addresses, which
+// are preferred by default.
+func WithLocalIPPreferIPv6() LocalIPOption {
+	return func(o *localIPOptions) {
+		o.preferIPv6 = true
+	}
+}
+
+// WithLocalIPInterfaces only considers the named interfaces, such as "eth0",
+// ranking addresses by the order of names.
+func WithLocalIPInterfaces(names ...string) LocalIPOption {
+	return func(o *localIPOptions) {
+		o.interfaces = names
+	}
+}
+
+// WithLocalIPLinkLocal includes link-local addresses, ranked after all
+// others. They are excluded by default since other hosts can rarely reach them.
+func WithLocalIPLinkLocal() LocalIPOption {
+	return func(o *localIPOptions) {
+		o.linkLocal = true
+	}
+}
+
+// WithLocalIPLoopback includes loopback addresses, ranked last, so a machine
+// without a network still gets an address.
+func WithLocalIPLoopback() LocalIPOption {
+	return func(o *localIPOptions) {
+		o.loopback = true
+	}
+}
+
+// GetLocalIPWith returns the best local address of the machine by the
+// ranking of GetLocalIPs. Unlike GetLocalIP, the choice is deterministic
+// and failures are reported: it returns ErrNoLocalIP when no address
+// matches.
+//
+// Example:
+//
+//	addr, err := GetLocalIPWith(WithLocalIPInterfaces("eth0", "en0"))
+func GetLocalIPWith(opts ...LocalIPOption) (netip.Addr, error) {
+	addrs, err := GetLocalIPs(opts...)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addrs[0], nil
+}
+
+// GetLocalIPs returns every address of the machine's up interfaces that
+// matches opts, best first: addresses of the preferred IP version before
+// the other, then, when WithLocalIPInterfaces is set, by interface order,
+// then routable addresses before link-local and loopback ones.
+//
+// Returns ErrNoLocalIP when no address matches.
+func GetLocalIPs(opts ...LocalIPOption) ([]netip.Addr, error) {
+	options := &localIPOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	type candidate struct {
+		addr  netip.Addr
+		iface int
+	}
+	var candidates []candidate
+	for _, iface := range interfaces {
+		ifaceRank := 0
+		if len(options.interfaces) > 0 {
+			if ifaceRank = slices.Index(options.interfaces, iface.Name); ifaceRank < 0 {
+				continue
+			}
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		addrs, addrErr := iface.Addrs()
+		if addrErr != nil {
+			continue
+		}
+		for _, address := range addrs {
+			ipnet, ok := address.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			addr, ok := netip.AddrFromSlice(ipnet.IP)
+			if !ok || !options.accepts(addr.Unmap()) {
+				continue
+			}
+			candidates = append(candidates, candidate{addr: addr.Unmap(), iface: ifaceRank})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoLocalIP
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(options.versionRank(a.addr), options.versionRank(b.addr)),
+			cmp.Compare(a.iface, b.iface),
+			cmp.Compare(scopeRank(a.addr), scopeRank(b.addr)),
+		)
+	})
+
+	out := make([]netip.Addr, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.addr
+	}
+	return out, nil
+}
+
+func (o *localIPOptions) accepts(addr netip.Addr) bool {
+	switch {
+	case addr.IsLoopback():
+		return o.loopback
+	case addr.IsLinkLocalUnicast():
+		return o.linkLocal
+	default:
+		return addr.IsGlobalUnicast()
+	}
+}
+
+func (o *localIPOptions) versionRank(addr netip.Addr) int {
+	if addr.Is6() == o.preferIPv6 {
+		return 0
+	}
+	return 1
+}
+
+// scopeRank orders routable addresses before link-local and loopback ones.
+func scopeRank(addr netip.Addr) int {
+	switch {
+	case addr.IsLoopback():
+		return 2 //nolint:mnd // last
+	case addr.IsLinkLocalUnicast():
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,46 @@
+package util_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestGetLocalIPs(t *testing.T) {
+	addrs, err := util.GetLocalIPs(util.WithLocalIPLoopback(), util.WithLocalIPLinkLocal())
+	if err != nil {
+		t.Skipf("no network addresses in this environment: %v", err)
+	}
+
+	best, err := util.GetLocalIPWith(util.WithLocalIPLoopback(), util.WithLocalIPLinkLocal())
+	if err != nil || best != addrs[0] {
+		t.Errorf("GetLocalIPWith() = %v, %v; want the first ranked address %v", best, err, addrs[0])
+	}
+
+	seenLoopback := false
+	for _, addr := range addrs {
+		if addr.IsLoopback() {
+			seenLoopback = true
+		} else if seenLoopback && addr.Is4() == addrs[0].Is4() {
+			t.Errorf("GetLocalIPs() = %v, ranks a routable address after a loopback one", addrs)
+		}
+	}
+
+	if v6, v6Err := util.GetLocalIPs(util.WithLocalIPLoopback(), util.WithLocalIPPreferIPv6()); v6Err == nil {
+		hasV6 := false
+		for _, addr := range v6 {
+			hasV6 = hasV6 || addr.Is6()
+		}
+		if hasV6 && !v6[0].Is6() {
+			t.Errorf("GetLocalIPs() with IPv6 preferred = %v, want an IPv6 address first", v6)
+		}
+	}
+}
+
+func TestGetLocalIPWithUnknownInterface(t *testing.T) {
+	_, err := util.GetLocalIPWith(util.WithLocalIPInterfaces("no-such-interface0"), util.WithLocalIPLoopback())
+	if !errors.Is(err, util.ErrNoLocalIP) {
+		t.Errorf("GetLocalIPWith() error = %v, want ErrNoLocalIP", err)
+	}
+}
@@ -0,0 +1,219 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Forwarding headers understood by IPResolver.
+const (
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+	HeaderForwarded    = "Forwarded"
+)
+
+// IPResolver determines the client IP of a request, trusting forwarding
+// headers only when they were set by a known proxy. Create one with
+// NewIPResolver. An IPResolver is safe for concurrent use.
+type IPResolver struct {
+	trusted     *CIDRSet
+	headerOrder []string
+	cdns        []cdnSource
+}
+
+// NewIPResolver creates an IPResolver that trusts forwarding headers only on
+// requests whose RemoteAddr is in trustedCIDRs, such as your load balancer's
+// subnet. Entries may be CIDRs ("10.0.0.0/8") or single addresses.
+//
+// headerOrder lists the headers to consult, first match wins; the default
+// is X-Forwarded-For then X-Real-IP. X-Forwarded-For and Forwarded (RFC 7239)
+// are walked from the right, skipping trusted proxies, so entries a client
+// prepends to spoof its address are ignored. Other headers are taken as a
+// single address.
+//
+// Unlike GetIP, which believes any X-Forwarded-For header, the result
+// cannot be forged by clients connecting directly. CDN client IP headers are
+// enabled with WithCDNClientIP and take precedence over headerOrder.
+//
+// Example:
+//
+//	resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, nil)
+//	if err != nil {
+//	    return err
+//	}
+//	ip := resolver.ClientIP(r)
+func NewIPResolver(trustedCIDRs []string, headerOrder []string, opts ...IPResolverOption) (*IPResolver, error) {
+	options := &ipResolverOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	trusted, err := NewCIDRSet(trustedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	cdns, err := newCDNSources(options.cdns)
+	if err != nil {
+		return nil, err
+	}
+	if len(headerOrder) == 0 {
+		headerOrder = []string{HeaderForwardedFor, HeaderRealIP}
+	}
+	return &IPResolver{trusted: trusted, headerOrder: headerOrder, cdns: cdns}, nil
+}
+
+// ClientIP returns the IP address of the client that sent r: the address
+// from a CDN header when the request came from that CDN, from the first
+// configured forwarding header when it came through a trusted proxy, and
+// the host of RemoteAddr otherwise.
+func (ir *IPResolver) ClientIP(r *http.Request) string {
+	addr, err := ir.ClientAddr(r)
+	if err != nil {
+		return remoteHost(r.RemoteAddr)
+	}
+	return addr.String()
+}
+
+// ClientAddr returns the client address like ClientIP, as a netip.Addr.
+// Returns an error when RemoteAddr holds no address.
+func (ir *IPResolver) ClientAddr(r *http.Request) (netip.Addr, error) {
+	remote, err := netip.ParseAddr(remoteHost(r.RemoteAddr))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("no client address in request: %w", err)
+	}
+	remote = remote.Unmap()
+	if addr, ok := ir.cdnClientAddr(r, remote); ok {
+		return addr, nil
+	}
+	if !ir.isTrusted(remote) {
+		return remote, nil
+	}
+
+	for _, header := range ir.headerOrder {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch http.CanonicalHeaderKey(header) {
+		case HeaderForwardedFor:
+			if addr, ok := ir.rightmostUntrusted(splitHeaderList(values), nil); ok {
+				return addr, nil
+			}
+		case HeaderForwarded:
+			if addr, ok := ir.rightmostUntrusted(splitHeaderList(values), forwardedFor); ok {
+				return addr, nil
+			}
+		default:
+			if addr, parseErr := parseForwardedAddr(values[0]); parseErr == nil {
+				return addr, nil
+			}
+		}
+	}
+	return remote, nil
+}
+
+// cdnClientAddr returns the address from the header of the CDN that
+// delivered r, if any. The CDN is the peer that connected to us, or, behind
+// trusted proxies, the nearest untrusted hop they recorded.
+func (ir *IPResolver) cdnClientAddr(r *http.Request, remote netip.Addr) (netip.Addr, bool) {
+	if len(ir.cdns) == 0 {
+		return netip.Addr{}, false
+	}
+
+	peer := remote
+	if ir.isTrusted(remote) {
+		if hop, ok := ir.rightmostUntrusted(splitHeaderList(r.Header.Values(HeaderForwardedFor)), nil); ok {
+			peer = hop
+		}
+	}
+
+	for _, cdn := range ir.cdns {
+		value := r.Header.Get(cdn.header)
+		if value == "" || !cdn.ranges.Contains(peer) {
+			continue
+		}
+		if addr, err := parseForwardedAddr(value); err == nil {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func (ir *IPResolver) isTrusted(addr netip.Addr) bool {
+	return ir.trusted.Contains(addr)
+}
+
+// rightmostUntrusted walks a forwarding chain from the proxy nearest to us
+// towards the client and returns the first address not belonging to a
+// trusted proxy, or the leftmost address when every hop is trusted.
+// extract, when set, pulls the address out of each element.
+func (ir *IPResolver) rightmostUntrusted(chain []string, extract func(string) string) (netip.Addr, bool) {
+	var leftmost netip.Addr
+	for i := len(chain) - 1; i >= 0; i-- {
+		element := chain[i]
+		if extract != nil {
+			element = extract(element)
+		}
+		addr, err := parseForwardedAddr(element)
+		if err != nil {
+			// A malformed hop was not written by a trusted proxy; stop here.
+			return leftmost, leftmost.IsValid()
+		}
+		if !ir.isTrusted(addr) {
+			return addr, true
+		}
+		leftmost = addr
+	}
+	return leftmost, leftmost.IsValid()
+}
+
+// splitHeaderList splits comma-separated header values, across repeated
+// header lines, into trimmed elements.
+func splitHeaderList(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for element := range strings.SplitSeq(value, ",") {
+			out = append(out, strings.TrimSpace(element))
+		}
+	}
+	return out
+}
+
+// forwardedFor returns the for= parameter of a Forwarded header element.
+func forwardedFor(element string) string {
+	for pair := range strings.SplitSeq(element, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.EqualFold(key, "for") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// parseForwardedAddr parses an address from a forwarding header, which may
+// carry a port or, for IPv6, brackets.
+func parseForwardedAddr(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid forwarded address %q: %w", value, err)
+	}
+	return addr.Unmap(), nil
+}
+
+// remoteHost returns the host part of a RemoteAddr, or the whole value when
+// it carries no port.
+func remoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,135 @@
+package util
+
+import "fmt"
+
+// CDN identifies a content delivery network that reports the client IP in
+// its own request header.
+type CDN string
+
+const (
+	// CDNCloudflare sends CF-Connecting-IP.
+	CDNCloudflare CDN = "cloudflare"
+	// CDNAkamai sends True-Client-IP.
+	CDNAkamai CDN = "akamai"
+	// CDNFastly sends Fastly-Client-IP.
+	CDNFastly CDN = "fastly"
+	// CDNAzureFrontDoor sends X-Azure-ClientIP.
+	CDNAzureFrontDoor CDN = "azure-front-door"
+)
+
+// Client IP headers set by CDNs.
+const (
+	HeaderCFConnectingIP = "CF-Connecting-IP"
+	HeaderTrueClientIP   = "True-Client-IP"
+	HeaderFastlyClientIP = "Fastly-Client-IP"
+	HeaderAzureClientIP  = "X-Azure-ClientIP"
+)
+
+// Header returns the request header in which the CDN reports the client IP.
+func (c CDN) Header() string {
+	switch c {
+	case CDNCloudflare:
+		return HeaderCFConnectingIP
+	case CDNAkamai:
+		return HeaderTrueClientIP
+	case CDNFastly:
+		return HeaderFastlyClientIP
+	case CDNAzureFrontDoor:
+		return HeaderAzureClientIP
+	default:
+		return ""
+	}
+}
+
+// PublishedRanges returns the egress ranges the CDN publishes, as built into
+// this package, or nil for CDNs that do not publish a stable list. The lists
+// change rarely but do change: prefer fetching the current ranges at startup
+// (https://www.cloudflare.com/ips/, https://api.fastly.com/public-ip-list)
+// and passing them to WithCDNClientIP.
+func (c CDN) PublishedRanges() []string {
+	switch c {
+	case CDNCloudflare:
+		return []string{
+			"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+			"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+			"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+			"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+			"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+			"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+		}
+	case CDNFastly:
+		return []string{
+			"23.235.32.0/20", "43.249.72.0/22", "103.244.50.0/24", "103.245.222.0/23",
+			"103.245.224.0/24", "104.156.80.0/20", "140.248.64.0/18", "140.248.128.0/17",
+			"146.75.0.0/17", "151.101.0.0/16", "157.52.64.0/18", "167.82.0.0/17",
+			"167.82.128.0/20", "167.82.160.0/20", "167.82.224.0/20", "172.111.64.0/18",
+			"185.31.16.0/22", "199.27.72.0/21", "199.232.0.0/16",
+			"2a04:4e40::/32", "2a04:4e42::/32",
+		}
+	default:
+		return nil
+	}
+}
+
+// ipResolverOptions contains configuration for NewIPResolver.
+type ipResolverOptions struct {
+	// cdns lists the CDNs whose client IP header is honored, in order
+	cdns []cdnConfig
+}
+
+type cdnConfig struct {
+	cdn    CDN
+	ranges []string
+}
+
+// IPResolverOption is a function that configures NewIPResolver.
+type IPResolverOption func(*ipResolverOptions)
+
+// WithCDNClientIP makes the resolver honor the client IP header of cdn, but
+// only on requests that reached the service from one of ranges: directly,
+// or through trusted proxies that recorded the CDN in X-Forwarded-For.
+// Requests from anywhere else cannot set the client IP with a forged CDN
+// header.
+//
+// Without ranges the CDN's PublishedRanges are used; NewIPResolver fails
+// for CDNs without published ranges, such as Akamai and Azure Front Door,
+// whose ranges must be passed explicitly.
+//
+// Example:
+//
+//	resolver, err := NewIPResolver([]string{"10.0.0.0/8"}, nil, WithCDNClientIP(CDNCloudflare))
+func WithCDNClientIP(cdn CDN, ranges ...string) IPResolverOption {
+	return func(o *ipResolverOptions) {
+		o.cdns = append(o.cdns, cdnConfig{cdn: cdn, ranges: ranges})
+	}
+}
+
+// cdnSource is a CDN client IP header together with the ranges it is
+// accepted from.
+type cdnSource struct {
+	header string
+	ranges *CIDRSet
+}
+
+func newCDNSources(configs []cdnConfig) ([]cdnSource, error) {
+	sources := make([]cdnSource, 0, len(configs))
+	for _, config := range configs {
+		header := config.cdn.Header()
+		if header == "" {
+			return nil, fmt.Errorf("unknown CDN %q", config.cdn)
+		}
+		ranges := config.ranges
+		if len(ranges) == 0 {
+			ranges = config.cdn.PublishedRanges()
+		}
+		if len(ranges) == 0 {
+			return nil, fmt.Errorf("CDN %q publishes no IP ranges; pass them to WithCDNClientIP", config.cdn)
+		}
+		set, err := NewCIDRSet(ranges)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s range: %w", config.cdn, err)
+		}
+		sources = append(sources, cdnSource{header: header, ranges: set})
+	}
+	return sources, nil
+}
@@ -0,0 +1,188 @@
+package util_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestIPResolver(t *testing.T) {
+	resolver, err := util.NewIPResolver([]string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"}, nil)
+	if err != nil {
+		t.Fatalf("NewIPResolver() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "direct client spoofing X-Forwarded-For",
+			remoteAddr: "203.0.113.7:4321",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.5:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.9"}},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "client-prepended entry is ignored",
+			remoteAddr: "10.0.0.5:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.9, 10.1.1.1"}},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "chain across repeated headers",
+			remoteAddr: "192.168.1.1:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.9", "10.2.2.2"}},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "all hops trusted",
+			remoteAddr: "10.0.0.5:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.9.9.9, 10.1.1.1"}},
+			want:       "10.9.9.9",
+		},
+		{
+			name:       "falls back to X-Real-IP",
+			remoteAddr: "10.0.0.5:80",
+			headers:    map[string][]string{"X-Real-IP": {"198.51.100.10"}},
+			want:       "198.51.100.10",
+		},
+		{
+			name:       "malformed header falls back to the proxy",
+			remoteAddr: "10.0.0.5:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"garbage"}},
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "IPv6 proxy and client",
+			remoteAddr: "[fd00::1]:443",
+			headers:    map[string][]string{"X-Forwarded-For": {"2001:db8::7"}},
+			want:       "2001:db8::7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, values := range tt.headers {
+				for _, value := range values {
+					req.Header.Add(key, value)
+				}
+			}
+			if got := resolver.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPResolverForwarded(t *testing.T) {
+	resolver, err := util.NewIPResolver([]string{"10.0.0.0/8"}, []string{"Forwarded"})
+	if err != nil {
+		t.Fatalf("NewIPResolver() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.5:80"
+	req.Header.Set("Forwarded", `for=1.2.3.4, for="[2001:db8::7]:8080";proto=https, for=10.1.1.1`)
+	req.Header.Set("X-Forwarded-For", "198.51.100.9")
+
+	if got := resolver.ClientIP(req); got != "2001:db8::7" {
+		t.Errorf("ClientIP() = %q, want 2001:db8::7", got)
+	}
+}
+
+func TestNewIPResolverInvalid(t *testing.T) {
+	for _, cidr := range []string{"10.0.0.0/33", "not-an-ip"} {
+		if _, err := util.NewIPResolver([]string{cidr}, nil); err == nil {
+			t.Errorf("NewIPResolver(%q) succeeded", cidr)
+		}
+	}
+}
+
+func TestIPResolverCDN(t *testing.T) {
+	resolver, err := util.NewIPResolver([]string{"10.0.0.0/8"}, nil,
+		util.WithCDNClientIP(util.CDNCloudflare),
+		util.WithCDNClientIP(util.CDNAkamai, "192.0.2.0/24"))
+	if err != nil {
+		t.Fatalf("NewIPResolver() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "direct from Cloudflare",
+			remoteAddr: "104.16.0.1:443",
+			headers:    map[string]string{"CF-Connecting-IP": "198.51.100.9"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "Cloudflare behind a trusted load balancer",
+			remoteAddr: "10.0.0.5:80",
+			headers: map[string]string{
+				"CF-Connecting-IP": "198.51.100.9",
+				"X-Forwarded-For":  "198.51.100.9, 162.158.1.1",
+			},
+			want: "198.51.100.9",
+		},
+		{
+			name:       "forged CDN header from a direct client",
+			remoteAddr: "203.0.113.7:4321",
+			headers:    map[string]string{"CF-Connecting-IP": "1.2.3.4"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "Akamai from its configured range",
+			remoteAddr: "192.0.2.10:443",
+			headers:    map[string]string{"True-Client-IP": "198.51.100.20"},
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "header of another CDN",
+			remoteAddr: "192.0.2.10:443",
+			headers:    map[string]string{"CF-Connecting-IP": "1.2.3.4"},
+			want:       "192.0.2.10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if got := resolver.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPResolverCDNWithoutRanges(t *testing.T) {
+	if _, err := util.NewIPResolver(nil, nil, util.WithCDNClientIP(util.CDNAzureFrontDoor)); err == nil {
+		t.Error("NewIPResolver() accepted Azure Front Door without ranges")
+	}
+	if _, err := util.NewIPResolver(nil, nil, util.WithCDNClientIP("unknown-cdn", "192.0.2.0/24")); err == nil {
+		t.Error("NewIPResolver() accepted an unknown CDN")
+	}
+	for _, cdn := range []util.CDN{util.CDNCloudflare, util.CDNFastly} {
+		if _, err := util.NewCIDRSet(cdn.PublishedRanges()); err != nil {
+			t.Errorf("%s published ranges are invalid: %v", cdn, err)
+		}
+	}
+}
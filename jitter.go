@@ -0,0 +1,46 @@
+package util
+
+import (
+	"math"
+	"time"
+)
+
+// Jitter returns d randomly spread by up to fraction of d in either
+// direction, uniformly in [d-fraction*d, d+fraction*d]. fraction is clamped
+// to [0, 1], so the result is never negative. Spreading retry delays and
+// scheduled jobs this way keeps many clients from acting in lockstep.
+//
+// Example:
+//
+//	time.Sleep(Jitter(backoff, 0.2)) // backoff ± 20%
+func Jitter(d time.Duration, fraction float64) time.Duration {
+	if d <= 0 || !(fraction > 0) { // also rejects NaN
+		return d
+	}
+	fraction = min(fraction, 1)
+
+	spread := fraction * float64(d)
+	jittered := float64(d) - spread + mustRandomFloat()*2*spread
+	if jittered >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(jittered)
+}
+
+// FullJitter returns a random duration uniformly distributed in
+// [0, maxDelay], the "full jitter" strategy for exponential backoff, which
+// spreads retries the most. It returns 0 when maxDelay <= 0.
+//
+// Example:
+//
+//	delay := FullJitter(min(base<<attempt, maxBackoff))
+func FullJitter(maxDelay time.Duration) time.Duration {
+	if maxDelay <= 0 {
+		return 0
+	}
+	r, err := RandomInt(0, int64(maxDelay))
+	if err != nil {
+		panic(err)
+	}
+	return time.Duration(r)
+}
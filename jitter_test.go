@@ -0,0 +1,53 @@
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestJitter(t *testing.T) {
+	const d = time.Second
+	spread := false
+	for range 200 {
+		got := util.Jitter(d, 0.2)
+		if got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("Jitter(1s, 0.2) = %v, want within 800ms..1.2s", got)
+		}
+		spread = spread || got != d
+	}
+	if !spread {
+		t.Error("Jitter() never changed the duration")
+	}
+
+	for range 200 {
+		if got := util.Jitter(d, 5); got < 0 || got > 2*d {
+			t.Fatalf("Jitter(1s, 5) = %v, want the fraction clamped to 1", got)
+		}
+	}
+	if got := util.Jitter(d, 0); got != d {
+		t.Errorf("Jitter(1s, 0) = %v, want 1s", got)
+	}
+	if got := util.Jitter(-d, 0.5); got != -d {
+		t.Errorf("Jitter(-1s, 0.5) = %v, want -1s unchanged", got)
+	}
+}
+
+func TestFullJitter(t *testing.T) {
+	var total time.Duration
+	const rounds = 1000
+	for range rounds {
+		got := util.FullJitter(time.Second)
+		if got < 0 || got > time.Second {
+			t.Fatalf("FullJitter(1s) = %v, out of range", got)
+		}
+		total += got
+	}
+	if mean := total / rounds; mean < 400*time.Millisecond || mean > 600*time.Millisecond {
+		t.Errorf("FullJitter(1s) mean = %v, want about 500ms", mean)
+	}
+	if got := util.FullJitter(0); got != 0 {
+		t.Errorf("FullJitter(0) = %v, want 0", got)
+	}
+}
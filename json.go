@@ -161,6 +161,11 @@ func respond(w http.ResponseWriter, req *http.Request, res JSONResponse) {
 
 func setCustomHeaders(w http.ResponseWriter, headers map[string]any) {
 	for headerName, rawValue := range headers {
+		// A response's Content-Type, such as application/problem+json,
+		// replaces the default; other headers add to those already set.
+		if http.CanonicalHeaderKey(headerName) == "Content-Type" {
+			w.Header().Del(headerName)
+		}
 		headerValues := toHeaderValues(rawValue)
 		for _, item := range headerValues {
 			addHeaderValue(w, headerName, item)
@@ -193,11 +198,21 @@ func addHeaderValue(w http.ResponseWriter, headerName string, v any) {
 	}
 }
 
+// responseBuffers holds the buffers JSON responses are encoded into.
+var responseBuffers = NewBufferPool(maxPooledResponseSize) //nolint:gochecknoglobals // shared by all responses
+
+// maxPooledResponseSize is the largest response buffer kept for reuse.
+const maxPooledResponseSize = 64 << 10
+
 func writeResponseJSON(w http.ResponseWriter, logger *LogEntry, res JSONResponse) {
-	enc := json.NewEncoder(w)
+	buf := responseBuffers.Get()
+	defer responseBuffers.Put(buf)
+
+	enc := json.NewEncoder(buf)
 	enc.SetEscapeHTML(true)
 	err := enc.Encode(res.JSON)
 	if err == nil {
+		_, _ = w.Write(buf.Bytes())
 		return
 	}
 
@@ -206,10 +221,13 @@ func writeResponseJSON(w http.ResponseWriter, logger *LogEntry, res JSONResponse
 		return
 	}
 
+	buf.Reset()
 	fallback := MessageResponse(StatusInternalServerError, "Internal Server Error")
 	if fallbackErr := enc.Encode(fallback.JSON); fallbackErr != nil {
 		logger.WithError(fallbackErr).Error("Failed to encode fallback JSONResponse")
+		return
 	}
+	_, _ = w.Write(buf.Bytes())
 }
 
 // WithCORSOptions intercepts all OPTIONS requests and responds with CORS headers. The request handler
@@ -100,6 +100,23 @@ func TestMakeJSONAPICustomHeaders(t *testing.T) {
 	}
 }
 
+func TestMakeJSONAPIKeepsEarlierHeaders(t *testing.T) {
+	mock := MockJSONRequestHandler{func(_ *http.Request) util.JSONResponse {
+		return util.JSONResponse{
+			Code:    200,
+			JSON:    MockResponse{"yep"},
+			Headers: map[string]any{"Vary": "Accept-Language"},
+		}
+	}}
+	mockReq, _ := http.NewRequest(http.MethodGet, "http://example.com/foo", nil)
+	mockWriter := httptest.NewRecorder()
+	mockWriter.Header().Set("Vary", "Origin")
+	util.MakeJSONAPI(&mock)(mockWriter, mockReq)
+	if got := mockWriter.Header().Values("Vary"); len(got) != 2 || got[0] != "Origin" || got[1] != "Accept-Language" {
+		t.Errorf("Vary = %v, want the middleware's value kept", got)
+	}
+}
+
 func TestMakeJSONAPIRedirect(t *testing.T) {
 	mock := MockJSONRequestHandler{func(_ *http.Request) util.JSONResponse {
 		return util.RedirectResponse("https://matrix.org")
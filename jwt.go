@@ -0,0 +1,491 @@
+package util
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWT verification errors. Every verification failure wraps one of these.
+var (
+	// ErrInvalidJWT is returned for malformed tokens, unexpected algorithms and bad signatures.
+	ErrInvalidJWT = errors.New("invalid JWT")
+	// ErrJWTExpired is returned when the current time is at or after the exp claim.
+	ErrJWTExpired = errors.New("JWT has expired")
+	// ErrJWTNotValidYet is returned when the nbf claim lies in the future.
+	ErrJWTNotValidYet = errors.New("JWT is not valid yet")
+	// ErrJWTClaimMismatch is returned when the aud or iss claim does not match.
+	ErrJWTClaimMismatch = errors.New("JWT claim mismatch")
+)
+
+const ctxValueJWTClaims = contextKeyType("jwt_claims")
+
+// JWTAlgorithm is a JWS signing algorithm name as used in the alg header.
+type JWTAlgorithm string
+
+// Supported JWT signing algorithms.
+const (
+	// JWTAlgHS256 signs with HMAC-SHA256 using a shared []byte key of at least 32 bytes.
+	JWTAlgHS256 JWTAlgorithm = "HS256"
+	// JWTAlgRS256 signs with RSASSA-PKCS1-v1_5 SHA-256 using *rsa.PrivateKey / *rsa.PublicKey.
+	JWTAlgRS256 JWTAlgorithm = "RS256"
+	// JWTAlgEdDSA signs with Ed25519 using ed25519.PrivateKey / ed25519.PublicKey.
+	JWTAlgEdDSA JWTAlgorithm = "EdDSA"
+)
+
+const (
+	jwtSegments      = 3
+	minJWTHMACKeyLen = sha256.Size
+)
+
+// JWTHeader is the decoded JOSE header of a token.
+type JWTHeader struct {
+	Algorithm JWTAlgorithm `json:"alg"`
+	Type      string       `json:"typ,omitempty"`
+	KeyID     string       `json:"kid,omitempty"`
+}
+
+// JWTAudience is the aud claim. It decodes from either a single string or an
+// array and encodes a single audience as a plain string.
+type JWTAudience []string
+
+// MarshalJSON implements json.Marshaler.
+func (a JWTAudience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *JWTAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = JWTAudience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("aud must be a string or an array of strings: %w", err)
+	}
+	*a = many
+	return nil
+}
+
+// JWTClaims holds the registered claims, the tenancy claims and any custom claims.
+//
+// JWTClaims implements TenancyInfo through the tenant_id, partition_id and
+// access_id claims, so verified tokens can be placed on the context with
+// ContextWithJWTClaims. Time claims are Unix seconds; zero means absent.
+type JWTClaims struct {
+	Issuer    string      `json:"iss,omitempty"`
+	Subject   string      `json:"sub,omitempty"`
+	Audience  JWTAudience `json:"aud,omitempty"`
+	ExpiresAt int64       `json:"exp,omitempty"`
+	NotBefore int64       `json:"nbf,omitempty"`
+	IssuedAt  int64       `json:"iat,omitempty"`
+	ID        string      `json:"jti,omitempty"`
+
+	TenantID    string `json:"tenant_id,omitempty"`
+	PartitionID string `json:"partition_id,omitempty"`
+	AccessID    string `json:"access_id,omitempty"`
+
+	// Extra holds any other claims. Keys matching the fields above are ignored on encode.
+	Extra map[string]any `json:"-"`
+}
+
+// jwtRegisteredClaims lists the claim names backed by JWTClaims fields.
+//
+//nolint:gochecknoglobals // fixed list of claim names
+var jwtRegisteredClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "tenant_id", "partition_id", "access_id",
+}
+
+// jwtClaimsFields has the same fields as JWTClaims without its methods, so
+// MarshalJSON and UnmarshalJSON can delegate to encoding/json.
+type jwtClaimsFields JWTClaims
+
+// MarshalJSON implements json.Marshaler, merging Extra into the claim set.
+func (c JWTClaims) MarshalJSON() ([]byte, error) {
+	registered, err := json.Marshal(jwtClaimsFields(c))
+	if err != nil || len(c.Extra) == 0 {
+		return registered, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(c.Extra))
+	for k, v := range c.Extra {
+		raw, marshalErr := json.Marshal(v)
+		if marshalErr != nil {
+			return nil, fmt.Errorf("failed to marshal claim %q: %w", k, marshalErr)
+		}
+		merged[k] = raw
+	}
+	for _, name := range jwtRegisteredClaims {
+		delete(merged, name)
+	}
+	if err = json.Unmarshal(registered, &merged); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, collecting unknown claims into Extra.
+func (c *JWTClaims) UnmarshalJSON(data []byte) error {
+	var fields jwtClaimsFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, name := range jwtRegisteredClaims {
+		delete(all, name)
+	}
+	if len(all) > 0 {
+		fields.Extra = all
+	}
+
+	*c = JWTClaims(fields)
+	return nil
+}
+
+// GetTenantID returns the tenant_id claim.
+func (c *JWTClaims) GetTenantID() string { return c.TenantID }
+
+// GetPartitionID returns the partition_id claim.
+func (c *JWTClaims) GetPartitionID() string { return c.PartitionID }
+
+// GetAccessID returns the access_id claim.
+func (c *JWTClaims) GetAccessID() string { return c.AccessID }
+
+// jwtSignOptions contains configuration for SignJWT.
+type jwtSignOptions struct {
+	// keyID is written to the kid header
+	keyID string
+
+	// ttl sets iat and exp when the claims do not carry an expiry
+	ttl time.Duration
+}
+
+// JWTSignOption is a function that configures SignJWT.
+type JWTSignOption func(*jwtSignOptions)
+
+// WithJWTKeyID sets the kid header so verifiers can select the right key.
+func WithJWTKeyID(keyID string) JWTSignOption {
+	return func(o *jwtSignOptions) {
+		o.keyID = keyID
+	}
+}
+
+// WithJWTTTL sets iat to now and exp to now+ttl unless the claims already carry an expiry.
+func WithJWTTTL(ttl time.Duration) JWTSignOption {
+	return func(o *jwtSignOptions) {
+		o.ttl = ttl
+	}
+}
+
+// SignJWT signs claims and returns a compact JWS token.
+//
+// The algorithm follows from the key type: a []byte key selects HS256, an
+// *rsa.PrivateKey RS256 and an ed25519.PrivateKey EdDSA. HMAC keys must be
+// at least 32 bytes long.
+//
+// Example:
+//
+//	token, err := SignJWT(signingKey, &JWTClaims{
+//		Issuer:   "https://auth.example.com",
+//		Subject:  userID,
+//		TenantID: tenantID,
+//	}, WithJWTTTL(15*time.Minute), WithJWTKeyID("2024-06"))
+func SignJWT(key any, claims *JWTClaims, opts ...JWTSignOption) (string, error) {
+	options := &jwtSignOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	alg, err := jwtSigningAlgorithm(key)
+	if err != nil {
+		return "", err
+	}
+
+	c := JWTClaims{}
+	if claims != nil {
+		c = *claims
+	}
+	if options.ttl > 0 && c.ExpiresAt == 0 {
+		now := time.Now()
+		c.IssuedAt = now.Unix()
+		c.ExpiresAt = now.Add(options.ttl).Unix()
+	}
+
+	header, err := json.Marshal(JWTHeader{Algorithm: alg, Type: "JWT", KeyID: options.keyID})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT header: %w", err)
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := jwtSign(key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// JWTKeyFunc returns the verification key for a token given its header,
+// typically by looking up header.KeyID. It returns a []byte for HS256, an
+// *rsa.PublicKey for RS256 or an ed25519.PublicKey for EdDSA.
+type JWTKeyFunc func(header JWTHeader) (any, error)
+
+// StaticJWTKey returns a JWTKeyFunc that always returns key.
+func StaticJWTKey(key any) JWTKeyFunc {
+	return func(JWTHeader) (any, error) {
+		return key, nil
+	}
+}
+
+// jwtVerifyOptions contains configuration for VerifyJWT.
+type jwtVerifyOptions struct {
+	// audience must appear in the aud claim when set
+	audience string
+
+	// issuer must equal the iss claim when set
+	issuer string
+
+	// leeway tolerates clock skew on exp and nbf
+	leeway time.Duration
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// JWTVerifyOption is a function that configures VerifyJWT.
+type JWTVerifyOption func(*jwtVerifyOptions)
+
+// WithJWTAudience requires the aud claim to contain audience.
+func WithJWTAudience(audience string) JWTVerifyOption {
+	return func(o *jwtVerifyOptions) {
+		o.audience = audience
+	}
+}
+
+// WithJWTIssuer requires the iss claim to equal issuer.
+func WithJWTIssuer(issuer string) JWTVerifyOption {
+	return func(o *jwtVerifyOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithJWTLeeway tolerates clock skew between issuer and verifier when checking exp and nbf.
+func WithJWTLeeway(leeway time.Duration) JWTVerifyOption {
+	return func(o *jwtVerifyOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithJWTClock overrides the time source used to check exp and nbf.
+func WithJWTClock(now func() time.Time) JWTVerifyOption {
+	return func(o *jwtVerifyOptions) {
+		o.now = now
+	}
+}
+
+// VerifyJWT verifies a compact JWS token and validates its claims.
+//
+// The key returned by keyFunc determines the only accepted algorithm, so a
+// token cannot downgrade itself to "none" or trick an RSA verifier into
+// treating the public key as an HMAC secret. The exp and nbf claims are
+// checked when present; aud and iss only when required through options.
+//
+// Errors wrap ErrInvalidJWT, ErrJWTExpired, ErrJWTNotValidYet or ErrJWTClaimMismatch.
+//
+// Example:
+//
+//	claims, err := VerifyJWT(StaticJWTKey(publicKey), token,
+//		WithJWTIssuer("https://auth.example.com"), WithJWTAudience("billing"))
+//	if err != nil {
+//		return err
+//	}
+//	ctx = ContextWithJWTClaims(ctx, claims)
+func VerifyJWT(keyFunc JWTKeyFunc, token string, opts ...JWTVerifyOption) (*JWTClaims, error) {
+	options := &jwtVerifyOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != jwtSegments {
+		return nil, fmt.Errorf("%w: expected %d segments", ErrInvalidJWT, jwtSegments)
+	}
+
+	var header JWTHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidJWT, err)
+	}
+
+	key, err := keyFunc(header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no key: %w", ErrInvalidJWT, err)
+	}
+	alg, err := jwtVerificationAlgorithm(key)
+	if err != nil {
+		return nil, err
+	}
+	if header.Algorithm != alg {
+		return nil, fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidJWT, header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature encoding: %w", ErrInvalidJWT, err)
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	if !jwtVerifySignature(key, signingInput, signature) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidJWT)
+	}
+
+	var claims JWTClaims
+	if err = decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %w", ErrInvalidJWT, err)
+	}
+	if err = options.validate(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (o *jwtVerifyOptions) validate(claims *JWTClaims) error {
+	now := o.now()
+
+	if claims.ExpiresAt != 0 && !now.Before(time.Unix(claims.ExpiresAt, 0).Add(o.leeway)) {
+		return ErrJWTExpired
+	}
+	if claims.NotBefore != 0 && now.Add(o.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrJWTNotValidYet
+	}
+	if o.issuer != "" && claims.Issuer != o.issuer {
+		return fmt.Errorf("%w: iss %q", ErrJWTClaimMismatch, claims.Issuer)
+	}
+	if o.audience != "" && !slices.Contains(claims.Audience, o.audience) {
+		return fmt.Errorf("%w: aud does not contain %q", ErrJWTClaimMismatch, o.audience)
+	}
+	return nil
+}
+
+// ContextWithJWTClaims stores verified claims on the context and, when the
+// token carries a tenant_id, records them as the context's tenancy so
+// GetTenancy and ComputeTenantLookupToken work downstream.
+func ContextWithJWTClaims(ctx context.Context, claims *JWTClaims) context.Context {
+	ctx = context.WithValue(ctx, ctxValueJWTClaims, claims)
+	if claims != nil && claims.TenantID != "" {
+		ctx = SetTenancy(ctx, claims)
+	}
+	return ctx
+}
+
+// JWTClaimsFromContext returns the claims stored with ContextWithJWTClaims, or nil.
+func JWTClaimsFromContext(ctx context.Context) *JWTClaims {
+	claims, ok := ctx.Value(ctxValueJWTClaims).(*JWTClaims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
+
+func decodeJWTSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func jwtSigningAlgorithm(key any) (JWTAlgorithm, error) {
+	switch k := key.(type) {
+	case []byte:
+		if len(k) < minJWTHMACKeyLen {
+			return "", fmt.Errorf("HS256 key must be at least %d bytes long", minJWTHMACKeyLen)
+		}
+		return JWTAlgHS256, nil
+	case *rsa.PrivateKey:
+		return JWTAlgRS256, nil
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return "", fmt.Errorf("Ed25519 private key must be %d bytes long", ed25519.PrivateKeySize)
+		}
+		return JWTAlgEdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported JWT signing key type %T", key)
+	}
+}
+
+func jwtVerificationAlgorithm(key any) (JWTAlgorithm, error) {
+	switch k := key.(type) {
+	case []byte:
+		if len(k) < minJWTHMACKeyLen {
+			return "", fmt.Errorf("%w: HS256 key must be at least %d bytes long", ErrInvalidJWT, minJWTHMACKeyLen)
+		}
+		return JWTAlgHS256, nil
+	case *rsa.PublicKey:
+		return JWTAlgRS256, nil
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return "", fmt.Errorf("%w: Ed25519 public key must be %d bytes long", ErrInvalidJWT, ed25519.PublicKeySize)
+		}
+		return JWTAlgEdDSA, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported verification key type %T", ErrInvalidJWT, key)
+	}
+}
+
+func jwtSign(key any, signingInput []byte) ([]byte, error) {
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signingInput)
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(signingInput)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign JWT: %w", err)
+		}
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, signingInput), nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing key type %T", key)
+	}
+}
+
+func jwtVerifySignature(key any, signingInput, signature []byte) bool {
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signingInput)
+		return SecureCompare(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signingInput)
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, signingInput, signature)
+	default:
+		return false
+	}
+}
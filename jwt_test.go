@@ -0,0 +1,185 @@
+package util_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSignVerifyJWT(t *testing.T) {
+	hmacKey := make([]byte, 32)
+	rand.Read(hmacKey)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		signKey any
+		verKey  any
+		alg     util.JWTAlgorithm
+	}{
+		{"HS256", hmacKey, hmacKey, util.JWTAlgHS256},
+		{"RS256", rsaKey, &rsaKey.PublicKey, util.JWTAlgRS256},
+		{"EdDSA", edPriv, edPub, util.JWTAlgEdDSA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &util.JWTClaims{
+				Issuer:   "issuer",
+				Subject:  "user-1",
+				Audience: util.JWTAudience{"billing"},
+				TenantID: "tenant-a",
+				Extra:    map[string]any{"role": "admin"},
+			}
+			token, signErr := util.SignJWT(tt.signKey, claims, util.WithJWTTTL(time.Minute), util.WithJWTKeyID("k1"))
+			if signErr != nil {
+				t.Fatalf("SignJWT() failed: %v", signErr)
+			}
+
+			var gotHeader util.JWTHeader
+			got, verifyErr := util.VerifyJWT(func(h util.JWTHeader) (any, error) {
+				gotHeader = h
+				return tt.verKey, nil
+			}, token, util.WithJWTIssuer("issuer"), util.WithJWTAudience("billing"))
+			if verifyErr != nil {
+				t.Fatalf("VerifyJWT() failed: %v", verifyErr)
+			}
+			if gotHeader.Algorithm != tt.alg || gotHeader.KeyID != "k1" {
+				t.Errorf("VerifyJWT() header = %+v, want alg %s kid k1", gotHeader, tt.alg)
+			}
+			if got.Subject != "user-1" || got.TenantID != "tenant-a" || got.Extra["role"] != "admin" {
+				t.Errorf("VerifyJWT() claims = %+v", got)
+			}
+			if got.ExpiresAt == 0 || got.IssuedAt == 0 {
+				t.Error("WithJWTTTL() should set exp and iat")
+			}
+		})
+	}
+}
+
+func TestVerifyJWTRejects(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	otherKey := make([]byte, 32)
+	rand.Read(otherKey)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	sign := func(c *util.JWTClaims) string {
+		token, signErr := util.SignJWT(key, c)
+		if signErr != nil {
+			t.Fatal(signErr)
+		}
+		return token
+	}
+	valid := sign(&util.JWTClaims{Issuer: "issuer", Audience: util.JWTAudience{"a", "b"}})
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		strings.Split(valid, ".")[1] + "."
+	rsaToken, err := util.SignJWT(rsaKey, &util.JWTClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		key     any
+		opts    []util.JWTVerifyOption
+		wantErr error
+	}{
+		{"malformed", "abc.def", key, nil, util.ErrInvalidJWT},
+		{"wrong key", valid, otherKey, nil, util.ErrInvalidJWT},
+		{"alg none", unsigned, key, nil, util.ErrInvalidJWT},
+		{"algorithm confusion", rsaToken, key, nil, util.ErrInvalidJWT},
+		{"tampered", valid[:len(valid)-2] + "AA", key, nil, util.ErrInvalidJWT},
+		{"expired", sign(&util.JWTClaims{ExpiresAt: now.Add(-time.Minute).Unix()}), key, nil, util.ErrJWTExpired},
+		{
+			"expired within leeway",
+			sign(&util.JWTClaims{ExpiresAt: now.Add(-time.Minute).Unix()}), key,
+			[]util.JWTVerifyOption{util.WithJWTLeeway(2 * time.Minute)}, nil,
+		},
+		{
+			"expires now",
+			sign(&util.JWTClaims{ExpiresAt: now.Unix()}), key,
+			[]util.JWTVerifyOption{util.WithJWTClock(func() time.Time { return time.Unix(now.Unix(), 0) })},
+			util.ErrJWTExpired,
+		},
+		{"not yet valid", sign(&util.JWTClaims{NotBefore: now.Add(time.Hour).Unix()}), key, nil, util.ErrJWTNotValidYet},
+		{"wrong issuer", valid, key, []util.JWTVerifyOption{util.WithJWTIssuer("other")}, util.ErrJWTClaimMismatch},
+		{"wrong audience", valid, key, []util.JWTVerifyOption{util.WithJWTAudience("c")}, util.ErrJWTClaimMismatch},
+		{"audience in list", valid, key, []util.JWTVerifyOption{util.WithJWTAudience("b")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, verifyErr := util.VerifyJWT(util.StaticJWTKey(tt.key), tt.token, tt.opts...)
+			if !errors.Is(verifyErr, tt.wantErr) || (tt.wantErr == nil && verifyErr != nil) {
+				t.Errorf("VerifyJWT() error = %v, want %v", verifyErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignJWTRejectsShortHMACKey(t *testing.T) {
+	if _, err := util.SignJWT([]byte("short"), &util.JWTClaims{}); err == nil {
+		t.Error("SignJWT() should reject HMAC keys shorter than 32 bytes")
+	}
+}
+
+func TestJWTClaimsExtraCannotSetRegisteredClaims(t *testing.T) {
+	claims := util.JWTClaims{
+		Subject: "user-1",
+		Extra:   map[string]any{"exp": 1, "iss": "forged", "tenant_id": "other", "role": "admin"},
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Marshal() failed: %v", err)
+	}
+
+	var got map[string]any
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"sub": "user-1", "role": "admin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal() = %s, want only sub and role", data)
+	}
+}
+
+func TestContextWithJWTClaims(t *testing.T) {
+	claims := &util.JWTClaims{TenantID: "tenant-a", PartitionID: "p1", AccessID: "acc"}
+	ctx := util.ContextWithJWTClaims(context.Background(), claims)
+
+	if util.JWTClaimsFromContext(ctx) != claims {
+		t.Error("JWTClaimsFromContext() should return the stored claims")
+	}
+	tenancy := util.GetTenancy(ctx)
+	if tenancy == nil || tenancy.GetTenantID() != "tenant-a" || tenancy.GetPartitionID() != "p1" {
+		t.Errorf("GetTenancy() = %v, want the JWT tenancy claims", tenancy)
+	}
+
+	ctx = util.ContextWithJWTClaims(context.Background(), &util.JWTClaims{Subject: "svc"})
+	if util.GetTenancy(ctx) != nil {
+		t.Error("ContextWithJWTClaims() should not set tenancy without a tenant_id claim")
+	}
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseKDF selects the password-based key derivation function.
+type PassphraseKDF int
+
+const (
+	// KDFScrypt derives keys with scrypt (the default).
+	KDFScrypt PassphraseKDF = iota
+	// KDFPBKDF2 derives keys with PBKDF2-HMAC-SHA256, for FIPS-constrained deployments.
+	KDFPBKDF2
+)
+
+// Passphrase KDF defaults: scrypt N=2^15, r=8, p=1 and 600,000 PBKDF2-SHA256
+// iterations, following current OWASP guidance.
+const (
+	defaultScryptN          = 1 << 15
+	defaultScryptR          = 8
+	defaultScryptP          = 1
+	defaultPBKDF2Iterations = 600_000
+	minPassphraseSaltLength = 16
+)
+
+// PassphraseKeyParams controls DeriveKeyFromPassphrase.
+type PassphraseKeyParams struct {
+	// KDF selects scrypt or PBKDF2.
+	KDF PassphraseKDF
+	// ScryptN is the scrypt CPU/memory cost; it must be a power of two.
+	ScryptN int
+	// ScryptR is the scrypt block size.
+	ScryptR int
+	// ScryptP is the scrypt parallelism.
+	ScryptP int
+	// PBKDF2Iterations is the PBKDF2 iteration count.
+	PBKDF2Iterations int
+}
+
+// DefaultPassphraseKeyParams returns scrypt parameters suitable for interactive use.
+// In FIPS mode (see FIPSMode) select KDFPBKDF2 instead.
+func DefaultPassphraseKeyParams() PassphraseKeyParams {
+	return PassphraseKeyParams{
+		KDF:              KDFScrypt,
+		ScryptN:          defaultScryptN,
+		ScryptR:          defaultScryptR,
+		ScryptP:          defaultScryptP,
+		PBKDF2Iterations: defaultPBKDF2Iterations,
+	}
+}
+
+// DeriveKey expands high-entropy secret material into a key of length bytes
+// using HKDF-SHA256 (RFC 5869).
+//
+// Use it to derive independent sub-keys from one master key, for example an
+// encryption key and an HMAC key for ComputeLookupToken, by varying info.
+// The secret must already be uniformly random; use DeriveKeyFromPassphrase
+// for human-chosen passwords.
+//
+// Parameters:
+//   - secret: Input keying material (a master key or shared secret)
+//   - salt: Optional non-secret random value; may be nil
+//   - info: Context string binding the key to its purpose, e.g. "users.email.v1"
+//   - length: Number of bytes to derive (at most 255*32)
+//
+// Example:
+//
+//	encKey, err := DeriveKey(masterKey, nil, "orders.encryption", 32)
+//	macKey, err := DeriveKey(masterKey, nil, "orders.lookup", 32)
+func DeriveKey(secret, salt []byte, info string, length int) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret cannot be empty")
+	}
+	if length <= 0 {
+		return nil, errors.New("key length must be greater than zero")
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, salt, info, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key, nil
+}
+
+// DeriveKeyFromPassphrase stretches a human-chosen passphrase into a key of
+// length bytes suitable for EncryptValue, using scrypt or PBKDF2.
+//
+// Never pass a raw passphrase to EncryptValue as an AES key: it has far less
+// entropy than its length suggests and is trivially brute-forced. The salt
+// must be at least 16 random bytes, stored next to the ciphertext, and unique
+// per passphrase. A nil params uses DefaultPassphraseKeyParams.
+//
+// Example:
+//
+//	salt := make([]byte, 16)
+//	rand.Read(salt)
+//	key, err := DeriveKeyFromPassphrase("correct horse battery staple", salt, 32, nil)
+func DeriveKeyFromPassphrase(passphrase string, salt []byte, length int, params *PassphraseKeyParams) ([]byte, error) {
+	p := DefaultPassphraseKeyParams()
+	if params != nil {
+		p = *params
+	}
+
+	if passphrase == "" {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	if len(salt) < minPassphraseSaltLength {
+		return nil, fmt.Errorf("salt must be at least %d bytes long", minPassphraseSaltLength)
+	}
+	if length <= 0 {
+		return nil, errors.New("key length must be greater than zero")
+	}
+
+	switch p.KDF {
+	case KDFScrypt:
+		if err := checkFIPSApproved("scrypt"); err != nil {
+			return nil, err
+		}
+		key, err := scrypt.Key([]byte(passphrase), salt, p.ScryptN, p.ScryptR, p.ScryptP, length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key with scrypt: %w", err)
+		}
+		return key, nil
+	case KDFPBKDF2:
+		if p.PBKDF2Iterations <= 0 {
+			return nil, errors.New("PBKDF2 iterations must be greater than zero")
+		}
+		key, err := pbkdf2.Key(sha256.New, passphrase, salt, p.PBKDF2Iterations, length)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key with PBKDF2: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported passphrase KDF %d", p.KDF)
+	}
+}
@@ -0,0 +1,85 @@
+package util_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestDeriveKey(t *testing.T) {
+	secret := []byte("master-key-material-0123456789ab")
+
+	key, err := util.DeriveKey(secret, nil, "orders.encryption", 32)
+	if err != nil {
+		t.Fatalf("DeriveKey() failed: %v", err)
+	}
+	want := "bf816071a03abd1f0b256ebfd363c814758d521e9f59f5c2186a3a47457c23f7"
+	if hex.EncodeToString(key) != want {
+		t.Errorf("DeriveKey() = %x, want %s", key, want)
+	}
+
+	other, _ := util.DeriveKey(secret, nil, "orders.lookup", 32)
+	if hex.EncodeToString(other) == want {
+		t.Error("DeriveKey() should produce independent keys for different info")
+	}
+
+	if _, err = util.DeriveKey(nil, nil, "x", 32); err == nil {
+		t.Error("DeriveKey() should reject an empty secret")
+	}
+	if _, err = util.DeriveKey(secret, nil, "x", 0); err == nil {
+		t.Error("DeriveKey() should reject a zero length")
+	}
+}
+
+func TestDeriveKeyFromPassphrase(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+
+	tests := []struct {
+		name    string
+		params  *util.PassphraseKeyParams
+		salt    []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "scrypt",
+			params: &util.PassphraseKeyParams{KDF: util.KDFScrypt, ScryptN: 1024, ScryptR: 8, ScryptP: 1},
+			salt:   salt,
+			want:   "bc8a19b6bf1b912af4206589193fbfb13d41a08b810ea1d43bc52ae7715f8fa5",
+		},
+		{
+			name:   "pbkdf2",
+			params: &util.PassphraseKeyParams{KDF: util.KDFPBKDF2, PBKDF2Iterations: 1000},
+			salt:   salt,
+			want:   "79fb009af2209905f16f041902cbeb904a639320ff0965fd3c787458e830c779",
+		},
+		{
+			name:    "short salt",
+			params:  &util.PassphraseKeyParams{KDF: util.KDFPBKDF2, PBKDF2Iterations: 1000},
+			salt:    []byte("short"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid scrypt cost",
+			params:  &util.PassphraseKeyParams{KDF: util.KDFScrypt, ScryptN: 1000, ScryptR: 8, ScryptP: 1},
+			salt:    salt,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.params.KDF == util.KDFScrypt && !tt.wantErr {
+				skipInFIPSMode(t, "scrypt")
+			}
+			key, err := util.DeriveKeyFromPassphrase("passphrase", tt.salt, 32, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeriveKeyFromPassphrase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && hex.EncodeToString(key) != tt.want {
+				t.Errorf("DeriveKeyFromPassphrase() = %x, want %s", key, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+	"slices"
+)
+
+// sharedSecretInfo is the default HKDF context for SharedSecret keys.
+const sharedSecretInfo = "util.x25519.shared-secret.v1"
+
+// sharedSecretKeySize is the length of SharedSecret keys: an AES-256 key.
+const sharedSecretKeySize = 32
+
+// keyAgreementOptions contains configuration for SharedSecret.
+type keyAgreementOptions struct {
+	// info is the HKDF context string mixed into the derived key
+	info string
+}
+
+// KeyAgreementOption is a function that configures SharedSecret.
+type KeyAgreementOption func(*keyAgreementOptions)
+
+// WithKeyAgreementInfo binds the derived key to a purpose, e.g.
+// "billing-events.v1", so one key pair can safely serve several channels.
+// Both parties must use the same info.
+func WithKeyAgreementInfo(info string) KeyAgreementOption {
+	return func(o *keyAgreementOptions) {
+		o.info = info
+	}
+}
+
+// GenerateX25519KeyPair generates an X25519 key pair and returns the 32-byte
+// private and public keys. Publish the public key; keep the private key secret.
+func GenerateX25519KeyPair() ([]byte, []byte, error) {
+	if err := checkFIPSApproved("X25519"); err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate X25519 key: %w", err)
+	}
+	return key.Bytes(), key.PublicKey().Bytes(), nil
+}
+
+// SharedSecret performs an X25519 Diffie-Hellman exchange and expands the
+// result with HKDF-SHA256 into a 32-byte AES-256 key for EncryptValue.
+//
+// Both parties compute the same key from their own private key and the other
+// party's public key. Both public keys form the HKDF salt, so the key is
+// bound to this exact pair of participants. The raw Diffie-Hellman output is
+// never returned; it is not uniformly random and must not be used as a key.
+//
+// Parameters:
+//   - privateKey: This party's 32-byte X25519 private key
+//   - peerPublicKey: The other party's 32-byte X25519 public key
+//   - opts: Optional purpose binding (WithKeyAgreementInfo)
+//
+// Example:
+//
+//	// Service A
+//	key, err := SharedSecret(aPrivate, bPublic)
+//	payload, err := EncryptValue(key, []byte("hello B"))
+//
+//	// Service B
+//	key, err := SharedSecret(bPrivate, aPublic)
+//	plaintext, err := DecryptValue(key, payload)
+func SharedSecret(privateKey, peerPublicKey []byte, opts ...KeyAgreementOption) ([]byte, error) {
+	options := &keyAgreementOptions{info: sharedSecretInfo}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if err := checkFIPSApproved("X25519"); err != nil {
+		return nil, err
+	}
+
+	priv, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 private key: %w", err)
+	}
+	peer, err := ecdh.X25519().NewPublicKey(peerPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid X25519 public key: %w", err)
+	}
+
+	// ECDH rejects low-order peer keys that would yield an all-zero secret.
+	secret, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+	defer Zeroize(secret)
+
+	// Order the public keys so both sides build the same salt.
+	first, second := priv.PublicKey().Bytes(), peerPublicKey
+	if bytes.Compare(first, second) > 0 {
+		first, second = second, first
+	}
+	salt := slices.Concat(first, second)
+
+	return DeriveKey(secret, salt, options.info, sharedSecretKeySize)
+}
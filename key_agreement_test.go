@@ -0,0 +1,79 @@
+package util_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSharedSecret(t *testing.T) {
+	skipInFIPSMode(t, "X25519")
+	aPriv, aPub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("GenerateX25519KeyPair() failed: %v", err)
+	}
+	bPriv, bPub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("GenerateX25519KeyPair() failed: %v", err)
+	}
+	_, cPub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("GenerateX25519KeyPair() failed: %v", err)
+	}
+
+	aKey, err := util.SharedSecret(aPriv, bPub)
+	if err != nil {
+		t.Fatalf("SharedSecret() failed: %v", err)
+	}
+	bKey, err := util.SharedSecret(bPriv, aPub)
+	if err != nil {
+		t.Fatalf("SharedSecret() failed: %v", err)
+	}
+	if len(aKey) != 32 || !bytes.Equal(aKey, bKey) {
+		t.Fatal("SharedSecret() should derive the same 32-byte key on both sides")
+	}
+
+	payload, err := util.EncryptValue(aKey, []byte("hello B"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := util.DecryptValue(bKey, payload)
+	if err != nil || string(plaintext) != "hello B" {
+		t.Errorf("DecryptValue() = %q, %v", plaintext, err)
+	}
+
+	otherPeer, _ := util.SharedSecret(aPriv, cPub)
+	if bytes.Equal(aKey, otherPeer) {
+		t.Error("SharedSecret() should differ for a different peer")
+	}
+	otherInfo, _ := util.SharedSecret(aPriv, bPub, util.WithKeyAgreementInfo("other.v1"))
+	if bytes.Equal(aKey, otherInfo) {
+		t.Error("SharedSecret() should differ for a different info")
+	}
+}
+
+func TestSharedSecretRejectsInvalidKeys(t *testing.T) {
+	skipInFIPSMode(t, "X25519")
+	priv, pub, err := util.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("GenerateX25519KeyPair() failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		priv, pub []byte
+	}{
+		{"short private key", priv[:16], pub},
+		{"short public key", priv, pub[:16]},
+		{"low order public key", priv, make([]byte, 32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := util.SharedSecret(tt.priv, tt.pub); err == nil {
+				t.Error("SharedSecret() should fail")
+			}
+		})
+	}
+}
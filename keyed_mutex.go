@@ -0,0 +1,220 @@
+package util
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSemaphoreWeight is returned when acquiring more weight than a
+// Semaphore's size, which could never succeed.
+var ErrSemaphoreWeight = errors.New("weight exceeds semaphore size")
+
+// KeyedMutex provides a mutual exclusion lock per key, such as a tenant or
+// account ID, so operations on one key are serialized while different keys
+// proceed in parallel. A key's lock is dropped once no goroutine holds or
+// waits for it, so memory is bounded by the keys in use. The zero value is
+// ready to use, and a KeyedMutex must not be copied after first use.
+//
+// Example:
+//
+//	var tenantLocks KeyedMutex
+//	unlock := tenantLocks.Lock(tenantID)
+//	defer unlock()
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	// held has a value while the lock is held
+	held chan struct{}
+	// refs counts the goroutines holding or waiting for the lock
+	refs int
+}
+
+// Lock locks key, waiting until it is available, and returns the function
+// that unlocks it.
+func (m *KeyedMutex) Lock(key string) (unlock func()) {
+	l := m.acquire(key)
+	l.held <- struct{}{}
+	return m.unlocker(key, l)
+}
+
+// LockContext is Lock giving up when ctx is done, in which case it returns
+// ctx's error and a nil unlock function.
+func (m *KeyedMutex) LockContext(ctx context.Context, key string) (unlock func(), err error) {
+	l := m.acquire(key)
+	select {
+	case l.held <- struct{}{}:
+		return m.unlocker(key, l), nil
+	case <-ctx.Done():
+		m.drop(key, l)
+		return nil, ctx.Err()
+	}
+}
+
+// TryLock locks key if it is available, reporting whether it did.
+func (m *KeyedMutex) TryLock(key string) (unlock func(), ok bool) {
+	l := m.acquire(key)
+	select {
+	case l.held <- struct{}{}:
+		return m.unlocker(key, l), true
+	default:
+		m.drop(key, l)
+		return nil, false
+	}
+}
+
+func (m *KeyedMutex) acquire(key string) *keyedLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedLock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{held: make(chan struct{}, 1)}
+		m.locks[key] = l
+	}
+	l.refs++
+	return l
+}
+
+// unlocker returns the function releasing l, which panics when called
+// twice like unlocking an unlocked sync.Mutex.
+func (m *KeyedMutex) unlocker(key string, l *keyedLock) func() {
+	var once sync.Once
+	return func() {
+		released := false
+		once.Do(func() {
+			<-l.held
+			m.drop(key, l)
+			released = true
+		})
+		if !released {
+			panic("util.KeyedMutex: unlock of unlocked key " + key)
+		}
+	}
+}
+
+func (m *KeyedMutex) drop(key string, l *keyedLock) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(m.locks, key)
+	}
+}
+
+// Semaphore is a weighted semaphore bounding the total weight of concurrent
+// holders, such as the bytes of in-flight uploads or the connections of a
+// shared pool. Waiters are served in order, so a heavy request is not
+// starved by a stream of light ones. A Semaphore is safe for concurrent use.
+//
+// Example:
+//
+//	memory := NewSemaphore(512 << 20)
+//	if err := memory.Acquire(ctx, size); err != nil {
+//	    return err
+//	}
+//	defer memory.Release(size)
+type Semaphore struct {
+	size int64
+
+	mu      sync.Mutex
+	used    int64
+	waiters list.List // of *semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewSemaphore returns a Semaphore allowing a total weight of size.
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// Acquire takes n of the semaphore's weight, waiting until it is available
+// or ctx is done, in which case it returns ctx's error. It returns
+// ErrSemaphoreWeight at once when n exceeds the size.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n > s.size {
+		return ErrSemaphoreWeight
+	}
+	s.mu.Lock()
+	if s.waiters.Len() == 0 && s.size-s.used >= n {
+		s.used += n
+		s.mu.Unlock()
+		return nil
+	}
+	w := &semaphoreWaiter{n: n, ready: make(chan struct{})}
+	el := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Acquired while ctx was ending; give it back.
+			s.used -= n
+			s.notifyLocked()
+		default:
+			isFront := s.waiters.Front() == el
+			s.waiters.Remove(el)
+			// Removing the head may let the waiters behind it proceed.
+			if isFront {
+				s.notifyLocked()
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes n of the semaphore's weight if it is available now,
+// reporting whether it did.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.waiters.Len() == 0 && s.size-s.used >= n {
+		s.used += n
+		return true
+	}
+	return false
+}
+
+// Release returns n of the semaphore's weight. It panics when releasing
+// more than is held.
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used -= n
+	if s.used < 0 {
+		panic("util.Semaphore: released more than held")
+	}
+	s.notifyLocked()
+}
+
+// notifyLocked wakes the waiters at the front of the queue that now fit.
+func (s *Semaphore) notifyLocked() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w, _ := front.Value.(*semaphoreWaiter)
+		if s.size-s.used < w.n {
+			return
+		}
+		s.used += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
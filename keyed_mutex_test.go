@@ -0,0 +1,98 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var locks util.KeyedMutex
+	var inside atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			unlock := locks.Lock("tenant-a")
+			defer unlock()
+			if inside.Add(1) > 1 {
+				t.Error("two goroutines hold the same key")
+			}
+			time.Sleep(time.Millisecond)
+			inside.Add(-1)
+		})
+	}
+
+	unlockB, ok := locks.TryLock("tenant-b")
+	if !ok {
+		t.Fatal("a different key is blocked")
+	}
+	if _, ok := locks.TryLock("tenant-b"); ok {
+		t.Error("TryLock of a held key succeeded")
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Millisecond)
+	defer cancel()
+	if _, err := locks.LockContext(ctx, "tenant-b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("LockContext of a held key = %v", err)
+	}
+	unlockB()
+
+	unlock, err := locks.LockContext(t.Context(), "tenant-b")
+	if err != nil {
+		t.Fatalf("LockContext after unlock = %v", err)
+	}
+	unlock()
+	defer func() {
+		if recover() == nil {
+			t.Error("unlocking twice did not panic")
+		}
+	}()
+	unlock()
+}
+
+func TestSemaphore(t *testing.T) {
+	sem := util.NewSemaphore(10)
+	ctx := t.Context()
+
+	if err := sem.Acquire(ctx, 11); !errors.Is(err, util.ErrSemaphoreWeight) {
+		t.Errorf("Acquire beyond the size = %v", err)
+	}
+	if err := sem.Acquire(ctx, 7); err != nil {
+		t.Fatalf("Acquire = %v", err)
+	}
+	if sem.TryAcquire(4) {
+		t.Error("TryAcquire beyond the free weight succeeded")
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		if err := sem.Acquire(ctx, 8); err == nil {
+			close(acquired)
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if sem.TryAcquire(1) {
+		t.Error("TryAcquire jumped ahead of a waiter")
+	}
+	sem.Release(7)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken by Release")
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	if err := sem.Acquire(timeout, 5); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire past the deadline = %v", err)
+	}
+	if !sem.TryAcquire(2) {
+		t.Error("a cancelled waiter still holds its place")
+	}
+}
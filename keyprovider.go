@@ -0,0 +1,283 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrKeyNotFound is returned by a KeyProvider when the requested key does not exist.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrKeyNotExportable is returned by KeyProvider.GetKey when the provider keeps
+// key material inside a remote KMS and only supports wrapping operations.
+var ErrKeyNotExportable = errors.New("key material is not exportable")
+
+const (
+	envelopeVersion1   = 0x01
+	envelopeHeaderSize = 4 // version + key id length + wrapped key length (uint16)
+	dataKeySize        = 32
+)
+
+// KeyProvider sources key material for EncryptValue/DecryptValue and envelope
+// encryption without hard-coding secrets in application code.
+//
+// Implementations backed by a remote KMS (AWS KMS, GCP KMS, Vault Transit)
+// usually never release the master key; they return ErrKeyNotExportable from
+// GetKey and implement WrapKey/UnwrapKey through the remote service.
+// See NewKMSKeyProvider for a ready-made adapter.
+type KeyProvider interface {
+	// GetKey returns the raw key material identified by keyID.
+	GetKey(ctx context.Context, keyID string) ([]byte, error)
+	// WrapKey encrypts a data encryption key under the key identified by keyID.
+	WrapKey(ctx context.Context, keyID string, dek []byte) ([]byte, error)
+	// UnwrapKey decrypts a data encryption key previously produced by WrapKey.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// KMSClient is the minimal surface of a remote key management service.
+// Thin adapters around the AWS KMS, GCP KMS or Vault Transit SDK clients
+// satisfy it in a few lines each.
+type KMSClient interface {
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// NewKMSKeyProvider adapts a KMSClient to the KeyProvider interface.
+// GetKey always fails with ErrKeyNotExportable.
+func NewKMSKeyProvider(client KMSClient) KeyProvider {
+	return &kmsKeyProvider{client: client}
+}
+
+type kmsKeyProvider struct {
+	client KMSClient
+}
+
+func (p *kmsKeyProvider) GetKey(_ context.Context, keyID string) ([]byte, error) {
+	return nil, fmt.Errorf("kms key %q: %w", keyID, ErrKeyNotExportable)
+}
+
+func (p *kmsKeyProvider) WrapKey(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+	wrapped, err := p.client.Encrypt(ctx, keyID, dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key with kms key %q: %w", keyID, err)
+	}
+	return wrapped, nil
+}
+
+func (p *kmsKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	dek, err := p.client.Decrypt(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key with kms key %q: %w", keyID, err)
+	}
+	return dek, nil
+}
+
+// NewEnvKeyProvider returns a KeyProvider reading keys from environment variables.
+//
+// The variable name is the prefix followed by the upper-cased key ID, with any
+// character outside [A-Z0-9_] replaced by an underscore; key ID "primary" with
+// prefix "APP_KEY_" is read from APP_KEY_PRIMARY. Values are base64 encoded
+// (standard or URL alphabet, padding optional) or hex encoded with a "hex:" prefix.
+func NewEnvKeyProvider(prefix string) KeyProvider {
+	return &localKeyProvider{
+		source: "env",
+		lookup: func(keyID string) (string, error) {
+			raw, ok := os.LookupEnv(prefix + envKeyName(keyID))
+			if !ok {
+				return "", ErrKeyNotFound
+			}
+			return raw, nil
+		},
+	}
+}
+
+// NewFileKeyProvider returns a KeyProvider reading keys from files in dir,
+// one file per key ID. File contents use the same encoding as NewEnvKeyProvider;
+// surrounding whitespace is ignored so files written by secret mounts work as is.
+func NewFileKeyProvider(dir string) KeyProvider {
+	return &localKeyProvider{
+		source: "file",
+		lookup: func(keyID string) (string, error) {
+			if !filepath.IsLocal(keyID) {
+				return "", ErrKeyNotFound
+			}
+			data, err := os.ReadFile(filepath.Join(dir, keyID))
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", ErrKeyNotFound
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to read key file: %w", err)
+			}
+			return string(data), nil
+		},
+	}
+}
+
+// localKeyProvider holds master keys locally and wraps data keys with EncryptValue.
+type localKeyProvider struct {
+	source string
+	lookup func(keyID string) (string, error)
+}
+
+func (p *localKeyProvider) GetKey(_ context.Context, keyID string) ([]byte, error) {
+	if keyID == "" {
+		return nil, errors.New("key id cannot be empty")
+	}
+	raw, err := p.lookup(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("%s key %q: %w", p.source, keyID, err)
+	}
+	key, err := decodeKeyMaterial(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s key %q: %w", p.source, keyID, err)
+	}
+	return key, nil
+}
+
+func (p *localKeyProvider) WrapKey(ctx context.Context, keyID string, dek []byte) ([]byte, error) {
+	kek, err := p.GetKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptValue(kek, dek)
+}
+
+func (p *localKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	kek, err := p.GetKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptValue(kek, wrapped)
+}
+
+func envKeyName(keyID string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, keyID)
+}
+
+func decodeKeyMaterial(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("key material is empty")
+	}
+
+	if hexKey, ok := strings.CutPrefix(raw, "hex:"); ok {
+		key, err := hex.DecodeString(hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex key material: %w", err)
+		}
+		return key, nil
+	}
+
+	raw = strings.TrimPrefix(raw, "base64:")
+	raw = strings.TrimRight(raw, "=")
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(raw, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	key, err := encoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 key material: %w", err)
+	}
+	return key, nil
+}
+
+// EnvelopeEncrypt encrypts plaintext under a freshly generated 256-bit data key
+// and stores the data key wrapped by the provider's key keyID alongside the ciphertext.
+//
+// The returned payload format is:
+// [version][key-id-length][key-id][wrapped-key-length (uint16)][wrapped-key][EncryptValue payload]
+//
+// Use EnvelopeDecrypt with a provider that can unwrap keyID to decrypt.
+func EnvelopeEncrypt(ctx context.Context, provider KeyProvider, keyID string, plaintext []byte) ([]byte, error) {
+	if len(keyID) == 0 || len(keyID) > math.MaxUint8 {
+		return nil, errors.New("key id must be between 1 and 255 bytes long")
+	}
+
+	dek := make([]byte, dataKeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	defer Zeroize(dek)
+
+	wrapped, err := provider.WrapKey(ctx, keyID, dek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) > math.MaxUint16 {
+		return nil, errors.New("wrapped data key is too large")
+	}
+
+	ciphertext, err := EncryptValue(dek, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, envelopeHeaderSize+len(keyID)+len(wrapped)+len(ciphertext))
+	result = append(result, envelopeVersion1, byte(len(keyID)))
+	result = append(result, keyID...)
+	result = binary.BigEndian.AppendUint16(result, uint16(len(wrapped)))
+	result = append(result, wrapped...)
+	result = append(result, ciphertext...)
+	return result, nil
+}
+
+// EnvelopeDecrypt reverses EnvelopeEncrypt, unwrapping the embedded data key
+// through provider before decrypting the payload.
+func EnvelopeDecrypt(ctx context.Context, provider KeyProvider, payload []byte) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := parseEnvelope(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	dek, err := provider.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	defer Zeroize(dek)
+
+	return DecryptValue(dek, ciphertext)
+}
+
+func parseEnvelope(payload []byte) (string, []byte, []byte, error) {
+	if len(payload) < envelopeHeaderSize {
+		return "", nil, nil, errors.New("envelope payload too short")
+	}
+	if payload[0] != envelopeVersion1 {
+		return "", nil, nil, fmt.Errorf("unsupported envelope version %d", payload[0])
+	}
+
+	rest := payload[2:]
+	keyIDLen := int(payload[1])
+	if len(rest) < keyIDLen+2 {
+		return "", nil, nil, errors.New("envelope payload truncated")
+	}
+	keyID := string(rest[:keyIDLen])
+	rest = rest[keyIDLen:]
+
+	wrappedLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < wrappedLen {
+		return "", nil, nil, errors.New("envelope payload truncated")
+	}
+
+	return keyID, rest[:wrappedLen], rest[wrappedLen:], nil
+}
@@ -0,0 +1,148 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestEnvKeyProvider(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	t.Setenv("TEST_KEY_PRIMARY", base64.StdEncoding.EncodeToString(key))
+	t.Setenv("TEST_KEY_BILLING_2024", "hex:"+hex.EncodeToString(key))
+	t.Setenv("TEST_KEY_BROKEN", "not base64 !!")
+
+	provider := util.NewEnvKeyProvider("TEST_KEY_")
+
+	tests := []struct {
+		name     string
+		keyID    string
+		wantErr  bool
+		notFound bool
+	}{
+		{"base64 encoded key", "primary", false, false},
+		{"hex encoded key with normalized name", "billing-2024", false, false},
+		{"missing key", "absent", true, true},
+		{"undecodable key", "broken", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provider.GetKey(t.Context(), tt.keyID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, util.ErrKeyNotFound) != tt.notFound {
+				t.Errorf("GetKey() error = %v, want ErrKeyNotFound %v", err, tt.notFound)
+			}
+			if !tt.wantErr && !bytes.Equal(got, key) {
+				t.Errorf("GetKey() = %x, want %x", got, key)
+			}
+		})
+	}
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	dir := t.TempDir()
+	key := make([]byte, 16)
+	rand.Read(key)
+	encoded := base64.RawURLEncoding.EncodeToString(key) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "primary"), []byte(encoded), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := util.NewFileKeyProvider(dir)
+
+	got, err := provider.GetKey(t.Context(), "primary")
+	if err != nil {
+		t.Fatalf("GetKey() failed: %v", err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Errorf("GetKey() = %x, want %x", got, key)
+	}
+
+	if _, err = provider.GetKey(t.Context(), "../primary"); !errors.Is(err, util.ErrKeyNotFound) {
+		t.Errorf("GetKey() with path traversal error = %v, want ErrKeyNotFound", err)
+	}
+	if _, err = provider.GetKey(t.Context(), "missing"); !errors.Is(err, util.ErrKeyNotFound) {
+		t.Errorf("GetKey() for a missing file error = %v, want ErrKeyNotFound", err)
+	}
+
+	if err = os.Mkdir(filepath.Join(dir, "unreadable"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.GetKey(t.Context(), "unreadable")
+	if err == nil || errors.Is(err, util.ErrKeyNotFound) {
+		t.Errorf("GetKey() for an unreadable file error = %v, want a read error", err)
+	}
+}
+
+func TestEnvelopeEncryptRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	t.Setenv("ENVELOPE_KEY_PRIMARY", base64.StdEncoding.EncodeToString(key))
+	provider := util.NewEnvKeyProvider("ENVELOPE_KEY_")
+
+	plaintext := []byte("envelope protected data")
+	payload, err := util.EnvelopeEncrypt(t.Context(), provider, "primary", plaintext)
+	if err != nil {
+		t.Fatalf("EnvelopeEncrypt() failed: %v", err)
+	}
+
+	decrypted, err := util.EnvelopeDecrypt(t.Context(), provider, payload)
+	if err != nil {
+		t.Fatalf("EnvelopeDecrypt() failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("EnvelopeDecrypt() = %q, want %q", decrypted, plaintext)
+	}
+
+	if _, err = util.EnvelopeDecrypt(t.Context(), provider, payload[:3]); err == nil {
+		t.Error("EnvelopeDecrypt() expected error for truncated payload")
+	}
+}
+
+// fakeKMS wraps keys by XOR-ing with a fixed pad, standing in for a remote KMS.
+type fakeKMS struct{}
+
+func (fakeKMS) Encrypt(_ context.Context, _ string, plaintext []byte) ([]byte, error) {
+	out := make([]byte, len(plaintext))
+	for i, b := range plaintext {
+		out[i] = b ^ 0x5a
+	}
+	return out, nil
+}
+
+func (f fakeKMS) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	return f.Encrypt(ctx, keyID, ciphertext)
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	provider := util.NewKMSKeyProvider(fakeKMS{})
+
+	if _, err := provider.GetKey(t.Context(), "arn:aws:kms:key"); !errors.Is(err, util.ErrKeyNotExportable) {
+		t.Errorf("GetKey() error = %v, want ErrKeyNotExportable", err)
+	}
+
+	plaintext := []byte("kms protected data")
+	payload, err := util.EnvelopeEncrypt(t.Context(), provider, "arn:aws:kms:key", plaintext)
+	if err != nil {
+		t.Fatalf("EnvelopeEncrypt() failed: %v", err)
+	}
+	decrypted, err := util.EnvelopeDecrypt(t.Context(), provider, payload)
+	if err != nil {
+		t.Fatalf("EnvelopeDecrypt() failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("EnvelopeDecrypt() = %q, want %q", decrypted, plaintext)
+	}
+}
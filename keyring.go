@@ -0,0 +1,154 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoMatchingKey is returned when no key in a Keyring can decrypt a payload.
+var ErrNoMatchingKey = errors.New("no key in the keyring can decrypt the payload")
+
+// Keyring holds the current (primary) encryption key together with the keys
+// it replaced, so data encrypted before a rotation stays readable.
+//
+// New data is always encrypted with the primary key. Decryption tries the
+// primary first and then each previous key in order; authenticated
+// encryption guarantees a wrong key fails rather than producing garbage.
+// Use ReEncrypt or ReEncryptBatch to move old payloads to the primary key
+// and eventually retire previous keys.
+//
+// A Keyring is immutable and safe for concurrent use.
+type Keyring struct {
+	keys [][]byte
+}
+
+// NewKeyring creates a keyring with primary as the encryption key and
+// previous as decrypt-only keys, most recent first. Keys are copied.
+//
+// Example:
+//
+//	ring, err := NewKeyring(keyV3, keyV2, keyV1)
+//	payload, err := ring.Encrypt([]byte("data")) // encrypted with keyV3
+//	plaintext, err := ring.Decrypt(oldPayload)   // works for V1, V2 or V3 payloads
+func NewKeyring(primary []byte, previous ...[]byte) (*Keyring, error) {
+	keys := make([][]byte, 0, len(previous)+1)
+	for i, key := range append([][]byte{primary}, previous...) {
+		if _, err := newAEAD(AlgorithmAESGCM, key); err != nil {
+			return nil, fmt.Errorf("keyring key %d: %w", i, err)
+		}
+		keys = append(keys, bytes.Clone(key))
+	}
+	return &Keyring{keys: keys}, nil
+}
+
+// Primary returns a copy of the primary key.
+func (k *Keyring) Primary() []byte {
+	return bytes.Clone(k.keys[0])
+}
+
+// Encrypt encrypts plaintext with the primary key using EncryptValue.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return EncryptValue(k.keys[0], plaintext)
+}
+
+// Decrypt decrypts a payload produced with any key in the keyring.
+// Returns ErrNoMatchingKey when none of the keys can open it.
+func (k *Keyring) Decrypt(payload []byte) ([]byte, error) {
+	plaintext, _, _, err := k.open(payload)
+	return plaintext, err
+}
+
+// open decrypts payload with the first key that authenticates it and reports
+// the algorithm recorded in the payload, or AES-GCM for legacy payloads.
+func (k *Keyring) open(payload []byte) ([]byte, Algorithm, bool, error) {
+	if len(payload) == 0 {
+		return nil, 0, false, errors.New("payload cannot be empty")
+	}
+
+	for _, key := range k.keys {
+		if plaintext, versioned, err := decryptVersioned(key, payload); versioned && err == nil {
+			return plaintext, Algorithm(payload[1]), true, nil
+		}
+		gcm, err := newAEAD(AlgorithmAESGCM, key)
+		if err != nil {
+			continue
+		}
+		if plaintext, err := openLegacy(nil, gcm, payload); err == nil {
+			return plaintext, AlgorithmAESGCM, false, nil
+		}
+	}
+	return nil, 0, false, ErrNoMatchingKey
+}
+
+// ReEncrypt decrypts payload with any key in oldKeyring and encrypts the
+// plaintext again under newKey, for migrating stored data after a key rotation.
+//
+// Versioned payloads keep their algorithm and compression; legacy payloads
+// are re-encrypted with EncryptValue. The intermediate plaintext is zeroized before returning.
+//
+// Example:
+//
+//	ring, _ := NewKeyring(oldKey)
+//	migrated, err := ReEncrypt(ring, newKey, row.Ciphertext)
+func ReEncrypt(oldKeyring *Keyring, newKey []byte, payload []byte) ([]byte, error) {
+	plaintext, algo, versioned, err := oldKeyring.open(payload)
+	if err != nil {
+		return nil, err
+	}
+	defer Zeroize(plaintext)
+
+	if versioned {
+		compression := CompressionNone
+		if payload[0] == payloadVersion2 {
+			compression = Compression(payload[2])
+		}
+		enc, encErr := NewEncryptor(newKey, WithEncryptorAlgorithm(algo), WithEncryptorCompression(compression))
+		if encErr != nil {
+			return nil, encErr
+		}
+		return enc.Encrypt(plaintext)
+	}
+	return EncryptValue(newKey, plaintext)
+}
+
+// ReEncryptProgress is called by ReEncryptBatch after each payload with the
+// number processed so far and the total.
+type ReEncryptProgress func(done, total int)
+
+// ReEncryptBatch re-encrypts payloads like ReEncrypt, reporting progress
+// after each one so long-running migrations can log or checkpoint.
+//
+// Failures do not stop the batch: the result for a failed payload is nil and
+// the returned error joins one error per failure, each naming its index.
+// Cancelling ctx stops the batch early and returns ctx's error alongside the
+// results completed so far. progress may be nil.
+func ReEncryptBatch(
+	ctx context.Context,
+	oldKeyring *Keyring,
+	newKey []byte,
+	payloads [][]byte,
+	progress ReEncryptProgress,
+) ([][]byte, error) {
+	results := make([][]byte, len(payloads))
+	var errs []error
+
+	for i, payload := range payloads {
+		if err := ctx.Err(); err != nil {
+			return results, errors.Join(append(errs, err)...)
+		}
+
+		migrated, err := ReEncrypt(oldKeyring, newKey, payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("payload %d: %w", i, err))
+		} else {
+			results[i] = migrated
+		}
+
+		if progress != nil {
+			progress(i+1, len(payloads))
+		}
+	}
+	return results, errors.Join(errs...)
+}
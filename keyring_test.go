@@ -0,0 +1,150 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func newTestKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKeyringDecryptsHistoricalPayloads(t *testing.T) {
+	v1, v2, v3 := newTestKey(t), newTestKey(t), newTestKey(t)
+	ring, err := util.NewKeyring(v3, v2, v1)
+	if err != nil {
+		t.Fatalf("NewKeyring() failed: %v", err)
+	}
+
+	legacyV1 := sealLegacyPayload(t, v1, []byte("legacy v1"))
+	current, err := ring.Encrypt([]byte("current"))
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+	if got, _ := util.DecryptValue(v3, current); string(got) != "current" {
+		t.Error("Encrypt() should use the primary key")
+	}
+
+	type historical struct {
+		payload []byte
+		want    string
+	}
+	tests := []historical{
+		{legacyV1, "legacy v1"},
+		{current, "current"},
+	}
+	// ChaCha20-Poly1305 is not FIPS approved, so FIPS mode cannot seal it.
+	if !util.FIPSMode() {
+		chachaV2, encryptErr := util.EncryptValueWithAlgo(util.AlgorithmChaCha20Poly1305, v2, []byte("chacha v2"))
+		if encryptErr != nil {
+			t.Fatalf("EncryptValueWithAlgo() failed: %v", encryptErr)
+		}
+		tests = append(tests, historical{chachaV2, "chacha v2"})
+	}
+	for _, tt := range tests {
+		got, decryptErr := ring.Decrypt(tt.payload)
+		if decryptErr != nil || string(got) != tt.want {
+			t.Errorf("Decrypt() = %q, %v, want %q", got, decryptErr, tt.want)
+		}
+	}
+
+	foreign, err := util.EncryptValue(newTestKey(t), []byte("foreign"))
+	if err != nil {
+		t.Fatalf("EncryptValue() failed: %v", err)
+	}
+	if _, err = ring.Decrypt(foreign); !errors.Is(err, util.ErrNoMatchingKey) {
+		t.Errorf("Decrypt() error = %v, want ErrNoMatchingKey", err)
+	}
+
+	if !bytes.Equal(ring.Primary(), v3) {
+		t.Error("Primary() should return the primary key")
+	}
+	if _, err = util.NewKeyring(v3, []byte("short")); err == nil {
+		t.Error("NewKeyring() should reject invalid keys")
+	}
+}
+
+func TestReEncrypt(t *testing.T) {
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	ring, err := util.NewKeyring(oldKey)
+	if err != nil {
+		t.Fatalf("NewKeyring() failed: %v", err)
+	}
+
+	legacy := sealLegacyPayload(t, oldKey, []byte("legacy"))
+	migrated, err := util.ReEncrypt(ring, newKey, legacy)
+	if err != nil {
+		t.Fatalf("ReEncrypt() failed: %v", err)
+	}
+	if got, _ := util.DecryptValue(newKey, migrated); string(got) != "legacy" {
+		t.Errorf("DecryptValue() after ReEncrypt() = %q", got)
+	}
+	if _, err = util.DecryptValue(oldKey, migrated); err == nil {
+		t.Error("ReEncrypt() output should not open with the old key")
+	}
+
+	skipUnlessFIPSApproved(t, util.AlgorithmXChaCha20Poly1305)
+	xchacha, err := util.EncryptValueWithAlgo(util.AlgorithmXChaCha20Poly1305, oldKey, []byte("xchacha"))
+	if err != nil {
+		t.Fatalf("EncryptValueWithAlgo() failed: %v", err)
+	}
+	migrated, err = util.ReEncrypt(ring, newKey, xchacha)
+	if err != nil {
+		t.Fatalf("ReEncrypt() failed: %v", err)
+	}
+	if migrated[0] != 0x01 || util.Algorithm(migrated[1]) != util.AlgorithmXChaCha20Poly1305 {
+		t.Errorf("ReEncrypt() header = %x, want the original algorithm preserved", migrated[:2])
+	}
+}
+
+func TestReEncryptBatch(t *testing.T) {
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	ring, _ := util.NewKeyring(oldKey)
+
+	payloads := make([][]byte, 5)
+	for i := range payloads {
+		payloads[i], _ = util.EncryptValue(oldKey, []byte{byte('a' + i)})
+	}
+	payloads[2] = []byte("corrupt payload that no key opens")
+
+	var calls []int
+	results, err := util.ReEncryptBatch(t.Context(), ring, newKey, payloads, func(done, total int) {
+		if total != len(payloads) {
+			t.Errorf("progress total = %d, want %d", total, len(payloads))
+		}
+		calls = append(calls, done)
+	})
+	if !errors.Is(err, util.ErrNoMatchingKey) {
+		t.Fatalf("ReEncryptBatch() error = %v, want ErrNoMatchingKey for the corrupt payload", err)
+	}
+	if len(calls) != len(payloads) || calls[len(calls)-1] != len(payloads) {
+		t.Errorf("progress calls = %v", calls)
+	}
+	for i, result := range results {
+		if i == 2 {
+			if result != nil {
+				t.Error("ReEncryptBatch() should leave failed results nil")
+			}
+			continue
+		}
+		if got, _ := util.DecryptValue(newKey, result); len(got) != 1 || got[0] != byte('a'+i) {
+			t.Errorf("result %d decrypts to %q", i, got)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err = util.ReEncryptBatch(ctx, ring, newKey, payloads, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReEncryptBatch() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,188 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Address prefixes understood by Listen.
+const (
+	ListenUnixPrefix    = "unix:"
+	ListenSystemdPrefix = "systemd:"
+	listenSystemd       = "systemd"
+)
+
+// systemdListenFDsStart is the first file descriptor passed by systemd
+// socket activation (SD_LISTEN_FDS_START).
+const systemdListenFDsStart = 3
+
+// listenOptions contains configuration for Listen.
+type listenOptions struct {
+	// network is "tcp", "tcp4" or "tcp6"
+	network string
+
+	// reusePort sets SO_REUSEPORT
+	reusePort bool
+
+	// unixMode sets the permissions of a unix socket file
+	unixMode fs.FileMode
+}
+
+// ListenOption is a function that configures Listen.
+type ListenOption func(*listenOptions)
+
+// WithListenIPv4Only binds TCP addresses to IPv4 only. By default a wildcard
+// address such as ":8080" accepts both IPv4 and IPv6 connections.
+func WithListenIPv4Only() ListenOption {
+	return func(o *listenOptions) {
+		o.network = "tcp4"
+	}
+}
+
+// WithListenIPv6Only binds TCP addresses to IPv6 only.
+func WithListenIPv6Only() ListenOption {
+	return func(o *listenOptions) {
+		o.network = "tcp6"
+	}
+}
+
+// WithListenReusePort sets SO_REUSEPORT, so several processes can listen on
+// the same port and the kernel balances connections between them, as during
+// a zero-downtime restart. Listen fails on platforms without SO_REUSEPORT.
+func WithListenReusePort() ListenOption {
+	return func(o *listenOptions) {
+		o.reusePort = true
+	}
+}
+
+// WithListenUnixMode sets the permissions of a unix socket file, e.g. 0o660
+// to admit a group. The default leaves them to the umask.
+func WithListenUnixMode(mode fs.FileMode) ListenOption {
+	return func(o *listenOptions) {
+		o.unixMode = mode
+	}
+}
+
+// Listen opens a stream listener for addr, which is one of:
+//   - a TCP address such as ":8080" or "127.0.0.1:8080", dual-stack for
+//     wildcard hosts unless WithListenIPv4Only or WithListenIPv6Only is set;
+//   - "unix:" and a socket path; a stale socket file left by a previous run is
+//     removed first;
+//   - "systemd" or "systemd:" and a socket name, for a socket passed by
+//     systemd socket activation: the first socket, or the one whose
+//     FileDescriptorName= matches. Each activated socket can be taken once.
+//
+// Example:
+//
+//	l, err := Listen(ctx, os.Getenv("LISTEN_ADDR"), WithListenReusePort())
+//	if err != nil {
+//	    return err
+//	}
+//	return http.Serve(l, handler)
+func Listen(ctx context.Context, addr string, opts ...ListenOption) (net.Listener, error) {
+	options := &listenOptions{network: "tcp"}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	switch {
+	case addr == listenSystemd || strings.HasPrefix(addr, ListenSystemdPrefix):
+		return systemdListener(strings.TrimPrefix(strings.TrimPrefix(addr, listenSystemd), ":"))
+	case strings.HasPrefix(addr, ListenUnixPrefix):
+		return listenUnix(ctx, strings.TrimPrefix(addr, ListenUnixPrefix), options)
+	default:
+		config := net.ListenConfig{}
+		if options.reusePort {
+			config.Control = reusePortControl
+		}
+		l, err := config.Listen(ctx, options.network, addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		return l, nil
+	}
+}
+
+func listenUnix(ctx context.Context, path string, options *listenOptions) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("unix socket path is empty")
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		// A connectable socket belongs to a running process; anything else is stale.
+		var dialer net.Dialer
+		if conn, dialErr := dialer.DialContext(ctx, "unix", path); dialErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("unix socket %s is in use", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	var config net.ListenConfig
+	l, err := config.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on unix socket %s: %w", path, err)
+	}
+	if options.unixMode != 0 {
+		if err = os.Chmod(path, options.unixMode); err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("failed to set permissions of %s: %w", path, err)
+		}
+	}
+	return l, nil
+}
+
+// systemdListener returns the socket passed by systemd under name, or the
+// first socket when name is empty, following sd_listen_fds(3).
+func systemdListener(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("no sockets passed by systemd socket activation")
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, errors.New("no sockets passed by systemd socket activation")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for i := range count {
+		fdName := ""
+		if i < len(names) {
+			fdName = names[i]
+		}
+		if name != "" && fdName != name {
+			continue
+		}
+
+		file := os.NewFile(uintptr(systemdListenFDsStart+i), fdName) //nolint:gosec // small descriptor number
+		if file == nil {
+			return nil, fmt.Errorf("systemd socket %d is not a valid file descriptor", i)
+		}
+		// FileListener duplicates the descriptor, so the original is closed either way.
+		l, listenErr := net.FileListener(file)
+		_ = file.Close()
+		if listenErr != nil {
+			return nil, fmt.Errorf("systemd socket %d (%s): %w", i, fdName, listenErr)
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("no systemd socket named %q", name)
+}
+
+// reusePortControl sets SO_REUSEPORT on a socket before it is bound.
+func reusePortControl(_, _ string, c syscall.RawConn) error {
+	var sockErr error
+	if err := c.Control(func(fd uintptr) {
+		sockErr = setReusePort(fd)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
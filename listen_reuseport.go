@@ -0,0 +1,16 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package util
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func setReusePort(fd uintptr) error {
+	if err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); err != nil { //nolint:gosec // fd fits an int
+		return fmt.Errorf("failed to set SO_REUSEPORT: %w", err)
+	}
+	return nil
+}
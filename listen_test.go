@@ -0,0 +1,91 @@
+package util_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestListenTCP(t *testing.T) {
+	l, err := util.Listen(t.Context(), "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	conn.Close()
+}
+
+func TestListenReusePort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not supported on Windows")
+	}
+
+	first, err := util.Listen(t.Context(), "127.0.0.1:0", util.WithListenReusePort())
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer first.Close()
+
+	second, err := util.Listen(t.Context(), first.Addr().String(), util.WithListenReusePort())
+	if err != nil {
+		t.Fatalf("second Listen() on %s with SO_REUSEPORT error = %v", first.Addr(), err)
+	}
+	second.Close()
+
+	if l, plainErr := util.Listen(t.Context(), first.Addr().String()); plainErr == nil {
+		l.Close()
+		t.Errorf("Listen() on %s without SO_REUSEPORT succeeded", first.Addr())
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket permissions are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "app.sock")
+
+	l, err := util.Listen(t.Context(), "unix:"+path, util.WithListenUnixMode(0o600))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	if _, err = util.Listen(t.Context(), "unix:"+path); err == nil {
+		t.Error("Listen() on a socket in use succeeded")
+	}
+
+	// Leave a stale socket file behind, as a crashed process would.
+	unixListener, ok := l.(*net.UnixListener)
+	if !ok {
+		t.Fatalf("Listen() returned %T, want *net.UnixListener", l)
+	}
+	unixListener.SetUnlinkOnClose(false)
+	unixListener.Close()
+	l, err = util.Listen(t.Context(), "unix:"+path)
+	if err != nil {
+		t.Fatalf("Listen() over a stale socket error = %v", err)
+	}
+	l.Close()
+}
+
+func TestListenSystemdWithoutActivation(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+	for _, addr := range []string{"systemd", "systemd:web"} {
+		if _, err := util.Listen(t.Context(), addr); err == nil {
+			t.Errorf("Listen(%q) without socket activation succeeded", addr)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	lockFilePerm = 0o600
+	lockDirPerm  = 0o750
+
+	// lockRenewFraction is the share of the TTL between renewals.
+	lockRenewFraction = 3
+)
+
+var (
+	// ErrLockHeld is returned by Locker.Acquire when another owner holds the
+	// lock.
+	ErrLockHeld = errors.New("lock is held by another owner")
+
+	// ErrLockNotHeld is returned by Locker.Renew and Locker.Release for a
+	// lease that is no longer the lock's owner, such as one that expired.
+	ErrLockNotHeld = errors.New("lock is not held")
+)
+
+// LockLease is the ownership of a lock acquired from a Locker.
+type LockLease struct {
+	// Key is the name of the lock.
+	Key string
+
+	// Token identifies this ownership, so a lease that expired and was
+	// taken over cannot renew or release the new owner's lock.
+	Token string
+
+	// ExpiresAt is when the lock is released unless renewed.
+	ExpiresAt time.Time
+}
+
+// Locker is a named lock shared between processes, such as the instances of
+// a service, used to run singleton background jobs or elect a leader. A
+// lock is held for a TTL and must be renewed before it ends, so the lock of
+// a crashed owner is eventually released.
+//
+// FileLocker implements it for processes on one host. Backends for a
+// cluster, such as PostgreSQL advisory locks keyed by LockKeyID, implement
+// the same interface in the package owning the database client.
+type Locker interface {
+	// Acquire takes the lock key for ttl, or returns ErrLockHeld when
+	// another owner holds it. It does not wait; see RunWithLock.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (*LockLease, error)
+
+	// Renew extends lease by ttl from now, or returns ErrLockNotHeld when it
+	// is no longer the owner.
+	Renew(ctx context.Context, lease *LockLease, ttl time.Duration) error
+
+	// Release gives up the lock of lease, or returns ErrLockNotHeld when it
+	// is no longer the owner.
+	Release(ctx context.Context, lease *LockLease) error
+}
+
+// LockKeyID maps a lock name to a 64-bit integer, for backends such as
+// PostgreSQL advisory locks that identify locks by number. Every
+// implementation must use it so they agree on the numbers.
+//
+// Example:
+//
+//	_, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", LockKeyID(key))
+func LockKeyID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64()) //nolint:gosec // wrapping to a signed ID is intended
+}
+
+// RunWithLock runs fn while holding the lock key, for jobs that must run on
+// one instance at a time. It waits for the lock, retrying every third of
+// ttl, renews it at the same interval while fn runs, and releases it when fn
+// returns. If a renewal fails, fn's context is cancelled with that error as
+// its cause, as another instance may take over. RunWithLock returns fn's
+// error, or ctx's error when ctx ends before the lock is acquired.
+//
+// Example:
+//
+//	err := RunWithLock(ctx, locker, "nightly-billing", time.Minute, func(ctx context.Context) error {
+//	    return runBilling(ctx)
+//	})
+func RunWithLock(
+	ctx context.Context,
+	locker Locker,
+	key string,
+	ttl time.Duration,
+	fn func(ctx context.Context) error,
+) error {
+	interval := ttl / lockRenewFraction
+	if interval <= 0 {
+		return fmt.Errorf("invalid lock TTL %v", ttl)
+	}
+
+	lease, err := acquireLock(ctx, locker, key, ttl, interval)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if renewErr := locker.Renew(runCtx, lease, ttl); renewErr != nil && runCtx.Err() == nil {
+					cancel(fmt.Errorf("failed to renew lock %s: %w", key, renewErr))
+					return
+				}
+			}
+		}
+	}()
+
+	err = fn(runCtx)
+	cancel(nil)
+	<-renewed
+
+	if releaseErr := locker.Release(context.WithoutCancel(ctx), lease); releaseErr != nil {
+		Log(ctx).WithError(releaseErr).WithField("lock", key).Warn("failed to release lock")
+	}
+	return err
+}
+
+func acquireLock(ctx context.Context, locker Locker, key string, ttl, retry time.Duration) (*LockLease, error) {
+	for {
+		lease, err := locker.Acquire(ctx, key, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return lease, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+// FileLocker is a Locker for processes on one host, backed by advisory
+// file locks (flock) on files in a directory. The operating system releases
+// the lock when its owner exits, even by crashing, so the TTL only sets the
+// ExpiresAt of leases. FileLocker is not supported on Windows.
+//
+// Example:
+//
+//	locker, err := NewFileLocker(filepath.Join(os.TempDir(), "myservice-locks"))
+//	if err != nil {
+//	    return err
+//	}
+//	go RunWithLock(ctx, locker, "cleanup", time.Minute, cleanup)
+type FileLocker struct {
+	dir string
+
+	mu   sync.Mutex
+	held map[string]*fileLock
+}
+
+type fileLock struct {
+	file  *os.File
+	token string
+}
+
+// NewFileLocker returns a FileLocker keeping its lock files in dir, which is
+// created if needed.
+func NewFileLocker(dir string) (*FileLocker, error) {
+	if err := EnsureDir(dir, lockDirPerm); err != nil {
+		return nil, err
+	}
+	return &FileLocker{dir: dir, held: make(map[string]*fileLock)}, nil
+}
+
+// Acquire implements Locker.
+func (l *FileLocker) Acquire(_ context.Context, key string, ttl time.Duration) (*LockLease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[key]; ok {
+		return nil, ErrLockHeld
+	}
+
+	path := filepath.Join(l.dir, url.PathEscape(key)+".lock")
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, lockFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err = tryLockFile(file); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	token := ULID()
+	// Record the owner for operators inspecting the lock file.
+	if err = file.Truncate(0); err == nil {
+		_, err = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+" "+token+"\n"), 0)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write lock file: %w", err)
+	}
+
+	l.held[key] = &fileLock{file: file, token: token}
+	return &LockLease{Key: key, Token: token, ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+// Renew implements Locker.
+func (l *FileLocker) Renew(_ context.Context, lease *LockLease, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if held, ok := l.held[lease.Key]; !ok || held.token != lease.Token {
+		return ErrLockNotHeld
+	}
+	lease.ExpiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+// Release implements Locker.
+func (l *FileLocker) Release(_ context.Context, lease *LockLease) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	held, ok := l.held[lease.Key]
+	if !ok || held.token != lease.Token {
+		return ErrLockNotHeld
+	}
+	delete(l.held, lease.Key)
+	// Closing the file releases the lock.
+	if err := held.file.Close(); err != nil {
+		return fmt.Errorf("failed to close lock file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// tryLockFile takes an exclusive flock on file without waiting, returning
+// ErrLockHeld when another open file holds it.
+func tryLockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB) //nolint:gosec // fd fits an int
+	if errors.Is(err, unix.EWOULDBLOCK) {
+		return ErrLockHeld
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock file: %w", err)
+	}
+	return nil
+}
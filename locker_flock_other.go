@@ -0,0 +1,12 @@
+//go:build !unix
+
+package util
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLockFile(*os.File) error {
+	return errors.New("file locks are not supported on this platform")
+}
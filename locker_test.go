@@ -0,0 +1,145 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestFileLocker(t *testing.T) {
+	dir := t.TempDir()
+	first, err := util.NewFileLocker(dir)
+	if err != nil {
+		t.Fatalf("NewFileLocker: %v", err)
+	}
+	second, _ := util.NewFileLocker(dir) // as another process would
+	ctx := t.Context()
+
+	lease, err := first.Acquire(ctx, "jobs/billing", time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if _, err = second.Acquire(ctx, "jobs/billing", time.Minute); !errors.Is(err, util.ErrLockHeld) {
+		t.Fatalf("second Acquire = %v, want ErrLockHeld", err)
+	}
+	if _, err = first.Acquire(ctx, "jobs/billing", time.Minute); !errors.Is(err, util.ErrLockHeld) {
+		t.Fatalf("repeated Acquire = %v, want ErrLockHeld", err)
+	}
+	other, err := second.Acquire(ctx, "jobs/cleanup", time.Minute)
+	if err != nil {
+		t.Fatalf("Acquire of another key: %v", err)
+	}
+
+	expires := lease.ExpiresAt
+	time.Sleep(time.Millisecond)
+	if err = first.Renew(ctx, lease, time.Hour); err != nil || !lease.ExpiresAt.After(expires) {
+		t.Fatalf("Renew = %v, expires %v", err, lease.ExpiresAt)
+	}
+	stale := &util.LockLease{Key: lease.Key, Token: "stale"}
+	if err = first.Renew(ctx, stale, time.Hour); !errors.Is(err, util.ErrLockNotHeld) {
+		t.Fatalf("Renew of a stale lease = %v, want ErrLockNotHeld", err)
+	}
+	if err = first.Release(ctx, stale); !errors.Is(err, util.ErrLockNotHeld) {
+		t.Fatalf("Release of a stale lease = %v, want ErrLockNotHeld", err)
+	}
+
+	if err = first.Release(ctx, lease); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err = second.Acquire(ctx, "jobs/billing", time.Minute); err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	if err = second.Release(ctx, other); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+}
+
+func TestRunWithLockExclusive(t *testing.T) {
+	dir := t.TempDir()
+	var running, peak, runs atomic.Int32
+	var wg sync.WaitGroup
+	for range 3 {
+		locker, err := util.NewFileLocker(dir)
+		if err != nil {
+			t.Fatalf("NewFileLocker: %v", err)
+		}
+		wg.Go(func() {
+			err := util.RunWithLock(t.Context(), locker, "singleton", 30*time.Millisecond, func(context.Context) error {
+				n := running.Add(1)
+				peak.Store(max(peak.Load(), n))
+				time.Sleep(20 * time.Millisecond)
+				running.Add(-1)
+				runs.Add(1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("RunWithLock: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if runs.Load() != 3 || peak.Load() != 1 {
+		t.Fatalf("runs = %d, peak concurrency = %d; want 3 and 1", runs.Load(), peak.Load())
+	}
+}
+
+// losingLocker grants locks but fails to renew them.
+type losingLocker struct {
+	released atomic.Bool
+}
+
+func (l *losingLocker) Acquire(_ context.Context, key string, ttl time.Duration) (*util.LockLease, error) {
+	return &util.LockLease{Key: key, Token: "t", ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+func (l *losingLocker) Renew(context.Context, *util.LockLease, time.Duration) error {
+	return util.ErrLockNotHeld
+}
+
+func (l *losingLocker) Release(context.Context, *util.LockLease) error {
+	l.released.Store(true)
+	return nil
+}
+
+func TestRunWithLockLost(t *testing.T) {
+	locker := &losingLocker{}
+	err := util.RunWithLock(t.Context(), locker, "job", 15*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return context.Cause(ctx)
+	})
+	if !errors.Is(err, util.ErrLockNotHeld) {
+		t.Fatalf("RunWithLock = %v, want the renewal error", err)
+	}
+	if !locker.released.Load() {
+		t.Fatal("lock not released")
+	}
+}
+
+func TestRunWithLockWaitCancelled(t *testing.T) {
+	dir := t.TempDir()
+	holder, _ := util.NewFileLocker(dir)
+	if _, err := holder.Acquire(t.Context(), "busy", time.Minute); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	waiter, _ := util.NewFileLocker(dir)
+	ctx, cancel := context.WithTimeout(t.Context(), 30*time.Millisecond)
+	defer cancel()
+	err := util.RunWithLock(ctx, waiter, "busy", 30*time.Millisecond, func(context.Context) error {
+		t.Error("fn ran without the lock")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunWithLock = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestLockKeyID(t *testing.T) {
+	if util.LockKeyID("billing") != util.LockKeyID("billing") || util.LockKeyID("billing") == util.LockKeyID("cleanup") {
+		t.Fatal("LockKeyID should be stable and differ between keys")
+	}
+}
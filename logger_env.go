@@ -0,0 +1,64 @@
+package util
+
+import "context"
+
+// Environment variables read by LogOptionsFromEnv.
+const (
+	EnvLogLevel     = "LOG_LEVEL"
+	EnvLogFormat    = "LOG_FORMAT"
+	EnvLogAddSource = "LOG_ADD_SOURCE"
+	EnvLogNoColor   = "LOG_NO_COLOR"
+)
+
+// logEnvConfig is the logging configuration read from the environment.
+type logEnvConfig struct {
+	Level     string `env:"LOG_LEVEL,default=info"`
+	Format    string `env:"LOG_FORMAT,default=text"`
+	AddSource bool   `env:"LOG_ADD_SOURCE"`
+	NoColor   bool   `env:"LOG_NO_COLOR"`
+}
+
+// LogOptionsFromEnv builds logger options from the LOG_LEVEL ("debug",
+// "info", "warn" or "error"), LOG_FORMAT ("text" or "json"), LOG_ADD_SOURCE
+// and LOG_NO_COLOR (booleans) environment variables, the conventions every
+// service shares.
+//
+// The options are usable even when err is not nil: a variable that fails to
+// parse, such as LOG_ADD_SOURCE=maybe, is left at its default and reported
+// in err.
+//
+// Example:
+//
+//	opts, err := LogOptionsFromEnv()
+//	log := NewLogger(ctx, append(opts, WithLogTenancy())...)
+//	if err != nil {
+//	    log.WithError(err).Warn("invalid logging configuration")
+//	}
+func LogOptionsFromEnv() ([]Option, error) {
+	var cfg logEnvConfig
+	err := LoadEnv(&cfg)
+	level, _ := ParseLevel(cfg.Level)
+	return []Option{
+		WithLogLevel(level),
+		WithLogFormat(cfg.Format),
+		WithLogAddSource(cfg.AddSource),
+		WithLogNoColor(cfg.NoColor),
+	}, err
+}
+
+// NewLoggerFromEnv constructs a logger configured by LogOptionsFromEnv, with
+// opts applied on top. An invalid variable is logged as a warning on the new
+// logger rather than failing, so a typo never silences a service's logs.
+//
+// Example:
+//
+//	log := NewLoggerFromEnv(ctx, WithLogTenancy())
+//	ctx = ContextWithLogger(ctx, log)
+func NewLoggerFromEnv(ctx context.Context, opts ...Option) *LogEntry {
+	envOpts, err := LogOptionsFromEnv()
+	logger := NewLogger(ctx, append(envOpts, opts...)...)
+	if err != nil {
+		logger.WithError(err).Warn("invalid logging configuration in the environment")
+	}
+	return logger
+}
@@ -0,0 +1,53 @@
+package util_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestNewLoggerFromEnv(t *testing.T) {
+	util.SetEnvForTest(t, util.EnvLogLevel, "warn")
+	util.SetEnvForTest(t, util.EnvLogFormat, "json")
+	util.SetEnvForTest(t, util.EnvLogAddSource, "true")
+
+	var buf bytes.Buffer
+	logger := util.NewLoggerFromEnv(t.Context(), util.WithLogOutput(&buf))
+	defer logger.Release()
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want only the warning: %q", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if record["msg"] != "shown" || record["source"] == nil {
+		t.Errorf("record = %v, want msg=shown with a source", record)
+	}
+}
+
+func TestLogOptionsFromEnvInvalid(t *testing.T) {
+	util.SetEnvForTest(t, util.EnvLogAddSource, "maybe")
+	util.SetEnvForTest(t, util.EnvLogFormat, "json")
+
+	opts, err := util.LogOptionsFromEnv()
+	if err == nil || !strings.Contains(err.Error(), util.EnvLogAddSource) {
+		t.Errorf("LogOptionsFromEnv error = %v, want one naming %s", err, util.EnvLogAddSource)
+	}
+
+	var buf bytes.Buffer
+	logger := util.NewLogger(t.Context(), append(opts, util.WithLogOutput(&buf))...)
+	defer logger.Release()
+	logger.Info("still works")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("valid variables were not applied: %q", buf.String())
+	}
+}
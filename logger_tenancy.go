@@ -0,0 +1,109 @@
+package util
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// Log attribute keys added by WithLogTenancy and LogWithTenancy.
+const (
+	LogAttrTenantID    = "tenant_id"
+	LogAttrPartitionID = "partition_id"
+	LogAttrAccessID    = "access_id"
+)
+
+// LogWithTenancy returns the logger on ctx bound to ctx, with the tenant,
+// partition and access IDs of the tenancy on ctx attached as attributes.
+// Without tenancy on ctx it is equivalent to Log(ctx).WithContext(ctx).
+//
+// Use it where the logger was not built with WithLogTenancy; combining the
+// two logs each attribute twice.
+//
+// Example:
+//
+//	LogWithTenancy(ctx).Info("invoice sent", "invoice", id)
+func LogWithTenancy(ctx context.Context) *LogEntry {
+	attrs := tenancyLogAttrs(ctx)
+	args := make([]any, len(attrs))
+	for i, attr := range attrs {
+		args[i] = attr
+	}
+	return Log(ctx).WithContext(ctx).With(args...)
+}
+
+// tenancyLogAttrs returns the tenancy on ctx as slog attributes, skipping empty IDs.
+func tenancyLogAttrs(ctx context.Context) []slog.Attr {
+	tenancy := GetTenancy(ctx)
+	if tenancy == nil {
+		return nil
+	}
+
+	attrs := make([]slog.Attr, 0, 3) //nolint:mnd // tenant, partition and access
+	for _, attr := range []slog.Attr{
+		slog.String(LogAttrTenantID, tenancy.GetTenantID()),
+		slog.String(LogAttrPartitionID, tenancy.GetPartitionID()),
+		slog.String(LogAttrAccessID, tenancy.GetAccessID()),
+	} {
+		if attr.Value.String() != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// tenancyHandler adds the tenancy on each record's context to the record,
+// at the top level even when the logger has groups.
+type tenancyHandler struct {
+	slog.Handler
+
+	// base is the handler before the first group, and since the groups and
+	// attributes applied after it, replayed on top of the tenancy attributes
+	base  slog.Handler
+	since []func(slog.Handler) slog.Handler
+}
+
+func (h *tenancyHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := tenancyLogAttrs(ctx)
+	switch {
+	case len(attrs) == 0:
+		return h.Handler.Handle(ctx, r)
+	case h.base == nil:
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+		return h.Handler.Handle(ctx, r)
+	}
+
+	handler := h.base.WithAttrs(attrs)
+	for _, apply := range h.since {
+		handler = apply(handler)
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h *tenancyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &tenancyHandler{Handler: h.Handler.WithAttrs(attrs), base: h.base}
+	if h.base != nil {
+		next.since = append(slices.Clip(h.since), func(handler slog.Handler) slog.Handler {
+			return handler.WithAttrs(attrs)
+		})
+	}
+	return next
+}
+
+func (h *tenancyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	base := h.base
+	if base == nil {
+		base = h.Handler
+	}
+	return &tenancyHandler{
+		Handler: h.Handler.WithGroup(name),
+		base:    base,
+		since: append(slices.Clip(h.since), func(handler slog.Handler) slog.Handler {
+			return handler.WithGroup(name)
+		}),
+	}
+}
@@ -0,0 +1,92 @@
+package util_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func decodeLogLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log output %q is not JSON: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return line
+}
+
+func TestWithLogTenancy(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, nil)
+	logger := util.NewLogger(t.Context(),
+		util.WithLogHandler(handler), util.WithLogHandlerExclusive(), util.WithLogTenancy())
+	defer logger.Release()
+
+	ctx := util.SetTenancy(t.Context(), util.DefaultTenancy{TenantID: "acme", PartitionID: "eu"})
+	logger.WithContext(ctx).WithField("order", "o-1").Info("order created")
+
+	line := decodeLogLine(t, &buf)
+	if line[util.LogAttrTenantID] != "acme" || line[util.LogAttrPartitionID] != "eu" {
+		t.Errorf("tenancy attributes missing: %v", line)
+	}
+	if _, ok := line[util.LogAttrAccessID]; ok {
+		t.Errorf("empty access ID should be omitted: %v", line)
+	}
+	if line["order"] != "o-1" {
+		t.Errorf("field attribute missing: %v", line)
+	}
+
+	logger.Info("no tenancy")
+	if line = decodeLogLine(t, &buf); line[util.LogAttrTenantID] != nil {
+		t.Errorf("unexpected tenancy attribute without tenancy: %v", line)
+	}
+}
+
+func TestWithLogTenancyInGroup(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, nil)
+	logger := util.NewLogger(t.Context(),
+		util.WithLogHandler(handler), util.WithLogHandlerExclusive(), util.WithLogTenancy())
+	defer logger.Release()
+
+	ctx := util.SetTenancy(t.Context(), util.DefaultTenancy{TenantID: "acme"})
+	logger.SLog().WithGroup("http").With("method", "GET").InfoContext(ctx, "request", "status", 200)
+
+	line := decodeLogLine(t, &buf)
+	if line[util.LogAttrTenantID] != "acme" {
+		t.Errorf("tenant ID not at the top level: %v", line)
+	}
+	group, _ := line["http"].(map[string]any)
+	if group["method"] != "GET" || group["status"] != float64(200) {
+		t.Errorf("group attributes = %v", line["http"])
+	}
+	if _, ok := group[util.LogAttrTenantID]; ok {
+		t.Errorf("tenant ID logged inside the group: %v", group)
+	}
+}
+
+func TestLogWithTenancy(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewJSONHandler(&buf, nil)
+	logger := util.NewLogger(t.Context(), util.WithLogHandler(handler), util.WithLogHandlerExclusive())
+	defer logger.Release()
+
+	ctx := util.ContextWithLogger(t.Context(), logger)
+	ctx = util.SetTenancy(ctx, util.DefaultTenancy{TenantID: "acme", PartitionID: "eu", AccessID: "key-1"})
+	util.LogWithTenancy(ctx).Info("invoice sent")
+
+	line := decodeLogLine(t, &buf)
+	for key, want := range map[string]string{
+		util.LogAttrTenantID:    "acme",
+		util.LogAttrPartitionID: "eu",
+		util.LogAttrAccessID:    "key-1",
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %q", key, line[key], want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package util
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrMissingTenancy is returned when an operation requires tenancy information
+// on the context and none was set with SetTenancy.
+var ErrMissingTenancy = errors.New("tenancy information missing from context")
+
+// tenantLookupTokenDomain separates tenant-scoped tokens from plain
+// ComputeLookupToken output so the two can never collide.
+const tenantLookupTokenDomain = "util.tenant-lookup-token.v1"
+
+// Lookup token truncation bounds. Eight bytes is the smallest size whose
+// collision probability stays negligible for realistic table sizes.
+const (
+	minLookupTokenSize = 8
+	maxLookupTokenSize = sha256.Size
+)
+
+// LookupHash selects the keyed hash used to compute lookup tokens.
+type LookupHash int
+
+const (
+	// LookupHashHMACSHA256 computes HMAC-SHA256. This is the default.
+	LookupHashHMACSHA256 LookupHash = iota
+	// LookupHashHMACSHA512_256 computes HMAC-SHA512/256, which is faster than
+	// SHA-256 on 64-bit CPUs without SHA extensions.
+	LookupHashHMACSHA512_256 //nolint:revive,staticcheck // mirrors the algorithm name
+	// LookupHashBLAKE2b computes keyed BLAKE2b-256. Keys longer than 64 bytes
+	// are first hashed with BLAKE2b-512, as HMAC does for long keys. It is
+	// not FIPS approved: in FIPS mode tokens use HMAC-SHA256 instead.
+	LookupHashBLAKE2b
+)
+
+// String returns the name of the hash.
+func (h LookupHash) String() string {
+	switch h {
+	case LookupHashHMACSHA256:
+		return "HMAC-SHA256"
+	case LookupHashHMACSHA512_256:
+		return "HMAC-SHA512/256"
+	case LookupHashBLAKE2b:
+		return "BLAKE2b-256"
+	default:
+		return "unknown"
+	}
+}
+
+// LookupTokenEncoding selects how ComputeLookupTokenString renders a token.
+type LookupTokenEncoding int
+
+const (
+	// LookupEncodingHex renders lowercase hexadecimal. This is the default.
+	LookupEncodingHex LookupTokenEncoding = iota
+	// LookupEncodingBase32 renders unpadded, lowercase RFC 4648 base32, which
+	// survives case-insensitive collations unchanged.
+	LookupEncodingBase32
+	// LookupEncodingBase64URL renders unpadded base64url, the most compact text form.
+	LookupEncodingBase64URL
+)
+
+// lookupTokenOptions contains configuration for lookup token computation.
+type lookupTokenOptions struct {
+	// hash selects the keyed hash used to compute the token
+	hash LookupHash
+
+	// nfkc applies Unicode NFKC normalization to the input
+	nfkc bool
+
+	// caseFold applies Unicode case folding to the input
+	caseFold bool
+
+	// size truncates the token to this many bytes; 0 keeps the full MAC
+	size int
+
+	// encoding selects the text form used by ComputeLookupTokenString
+	encoding LookupTokenEncoding
+}
+
+// LookupTokenOption is a function that configures lookup token computation.
+type LookupTokenOption func(*lookupTokenOptions)
+
+// WithLookupNFKC applies Unicode NFKC normalization to the input, so visually
+// identical strings typed on different devices ("ﬁ" and "fi", full-width and
+// ASCII digits) produce the same token.
+func WithLookupNFKC() LookupTokenOption {
+	return func(o *lookupTokenOptions) {
+		o.nfkc = true
+	}
+}
+
+// WithLookupCaseFold applies Unicode case folding to the input, making tokens
+// case-insensitive ("Straße" and "STRASSE" match). Folding runs after NFKC
+// when both are enabled.
+func WithLookupCaseFold() LookupTokenOption {
+	return func(o *lookupTokenOptions) {
+		o.caseFold = true
+	}
+}
+
+// WithLookupTruncate truncates tokens to size bytes for compact index columns.
+// Sizes below 8 are raised to 8 and sizes above 32 keep the full token.
+//
+// Truncation trades space for collision resistance. By the birthday bound,
+// n distinct values collide with probability about n²/2^(8·size+1):
+//   - 8 bytes (64 bits): ~3e-8 for one million rows, ~50% near four billion
+//   - 16 bytes (128 bits): negligible for any realistic table
+//
+// Use 8 bytes (or ComputeLookupTokenUint64) only where an occasional false
+// match is resolved by decrypting and comparing the candidate rows.
+func WithLookupTruncate(size int) LookupTokenOption {
+	return func(o *lookupTokenOptions) {
+		switch {
+		case size < minLookupTokenSize:
+			o.size = minLookupTokenSize
+		case size >= maxLookupTokenSize:
+			o.size = 0
+		default:
+			o.size = size
+		}
+	}
+}
+
+// WithLookupHash selects the keyed hash used to compute tokens, for
+// deployments whose crypto policy mandates a specific algorithm. Tokens from
+// different hashes never match, so changing it requires re-indexing.
+func WithLookupHash(h LookupHash) LookupTokenOption {
+	return func(o *lookupTokenOptions) {
+		o.hash = h
+	}
+}
+
+// WithLookupEncoding selects the text encoding used by ComputeLookupTokenString.
+func WithLookupEncoding(encoding LookupTokenEncoding) LookupTokenOption {
+	return func(o *lookupTokenOptions) {
+		o.encoding = encoding
+	}
+}
+
+func newLookupTokenOptions(opts []LookupTokenOption) *lookupTokenOptions {
+	options := &lookupTokenOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// normalize applies the configured Unicode normalization to input.
+func (o *lookupTokenOptions) normalize(input string) string {
+	if o.nfkc {
+		input = norm.NFKC.String(input)
+	}
+	if o.caseFold {
+		input = cases.Fold().String(input)
+	}
+	return input
+}
+
+// newMAC returns the configured keyed hash. Unknown values, and BLAKE2b in
+// FIPS mode, fall back to HMAC-SHA256 so that lookup token computation
+// cannot fail.
+func (o *lookupTokenOptions) newMAC(key []byte) hash.Hash {
+	switch o.hash {
+	case LookupHashHMACSHA512_256:
+		return hmac.New(sha512.New512_256, key)
+	case LookupHashBLAKE2b:
+		if checkFIPSApproved("BLAKE2b") != nil {
+			return hmac.New(sha256.New, key)
+		}
+		if len(key) > blake2b.Size {
+			sum := blake2b.Sum512(key)
+			key = sum[:]
+		}
+		// New256 only fails for keys longer than blake2b.Size, handled above.
+		mac, _ := blake2b.New256(key)
+		return mac
+	default:
+		return hmac.New(sha256.New, key)
+	}
+}
+
+// finish applies the configured truncation to a computed MAC.
+func (o *lookupTokenOptions) finish(sum []byte) []byte {
+	if o.size > 0 && o.size < len(sum) {
+		return sum[:o.size]
+	}
+	return sum
+}
+
+// encode renders token in the configured text encoding.
+func (o *lookupTokenOptions) encode(token []byte) string {
+	switch o.encoding {
+	case LookupEncodingBase32:
+		return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(token))
+	case LookupEncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(token)
+	default:
+		return hex.EncodeToString(token)
+	}
+}
+
+// ComputeLookupTokenString computes a lookup token with ComputeLookupToken and
+// renders it as text for string index columns and cache keys.
+//
+// Hex is used unless WithLookupEncoding selects another encoding.
+//
+// Example:
+//
+//	token := ComputeLookupTokenString(key, email, WithLookupTruncate(16), WithLookupEncoding(LookupEncodingBase64URL))
+//	// len(token) == 22
+func ComputeLookupTokenString(hmacKey []byte, normalized string, opts ...LookupTokenOption) string {
+	options := newLookupTokenOptions(opts)
+	return options.encode(ComputeLookupToken(hmacKey, normalized, opts...))
+}
+
+// ComputeLookupTokenUint64 computes a lookup token truncated to 64 bits and
+// returns it as an integer for BIGINT index columns.
+//
+// See WithLookupTruncate for the collision probability of 64-bit tokens.
+// Truncation and encoding options are ignored.
+func ComputeLookupTokenUint64(hmacKey []byte, normalized string, opts ...LookupTokenOption) uint64 {
+	// Truncation keeps a prefix, so the first 8 bytes are the same at any size.
+	return binary.BigEndian.Uint64(ComputeLookupToken(hmacKey, normalized, opts...))
+}
+
+// ComputeTenantLookupToken computes a lookup token bound to the tenant and
+// partition recorded on ctx with SetTenancy.
+//
+// The tenant ID, partition ID and value are each length-prefixed and mixed
+// into the HMAC behind a fixed domain label, so equal values in different
+// tenants or partitions always produce unrelated tokens and no choice of
+// value can impersonate another tenant's input. This formalizes the advice
+// on ComputeLookupToken to include the tenant in the normalized input.
+//
+// Returns ErrMissingTenancy when ctx carries no tenancy information.
+//
+// Example:
+//
+//	token, err := ComputeTenantLookupToken(ctx, key, email, WithLookupNFKC(), WithLookupCaseFold())
+func ComputeTenantLookupToken(
+	ctx context.Context,
+	hmacKey []byte,
+	normalized string,
+	opts ...LookupTokenOption,
+) ([]byte, error) {
+	tenancy := GetTenancy(ctx)
+	if tenancy == nil {
+		return nil, ErrMissingTenancy
+	}
+
+	options := newLookupTokenOptions(opts)
+
+	mac := options.newMAC(hmacKey)
+	mac.Write([]byte(tenantLookupTokenDomain))
+	for _, part := range []string{tenancy.GetTenantID(), tenancy.GetPartitionID(), options.normalize(normalized)} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(part))) //nolint:gosec // strings longer than 4 GiB are not tokenized
+		mac.Write(length[:])
+		mac.Write([]byte(part))
+	}
+	return options.finish(mac.Sum(nil)), nil
+}
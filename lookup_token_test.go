@@ -0,0 +1,198 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type stubTenancy struct {
+	tenantID, partitionID, accessID string
+}
+
+func (s stubTenancy) GetTenantID() string    { return s.tenantID }
+func (s stubTenancy) GetPartitionID() string { return s.partitionID }
+func (s stubTenancy) GetAccessID() string    { return s.accessID }
+
+func TestComputeLookupTokenNormalization(t *testing.T) {
+	key := []byte("test-key-16-bytes-")
+
+	tests := []struct {
+		name  string
+		a, b  string
+		opts  []util.LookupTokenOption
+		equal bool
+	}{
+		{"case differs without folding", "User@Example.com", "user@example.com", nil, false},
+		{"case folding", "User@Example.com", "user@example.com", []util.LookupTokenOption{util.WithLookupCaseFold()}, true},
+		{"full width without nfkc", "ｕｓｅｒ１", "user1", nil, false},
+		{"nfkc", "ｕｓｅｒ１", "user1", []util.LookupTokenOption{util.WithLookupNFKC()}, true},
+		{
+			"nfkc and case folding",
+			"ＳＴＲＡＳＳＥ", "straße",
+			[]util.LookupTokenOption{util.WithLookupNFKC(), util.WithLookupCaseFold()},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := util.ComputeLookupToken(key, tt.a, tt.opts...)
+			b := util.ComputeLookupToken(key, tt.b, tt.opts...)
+			if bytes.Equal(a, b) != tt.equal {
+				t.Errorf("tokens equal = %v, want %v", bytes.Equal(a, b), tt.equal)
+			}
+		})
+	}
+}
+
+func TestComputeTenantLookupToken(t *testing.T) {
+	key := []byte("test-key-16-bytes-")
+	ctxA := util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant-a", partitionID: "p1"})
+	ctxB := util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant-b", partitionID: "p1"})
+	ctxShift := util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant-ap", partitionID: "1"})
+
+	tokenA, err := util.ComputeTenantLookupToken(ctxA, key, "user@example.com")
+	if err != nil {
+		t.Fatalf("ComputeTenantLookupToken() failed: %v", err)
+	}
+	again, _ := util.ComputeTenantLookupToken(ctxA, key, "user@example.com")
+	if !bytes.Equal(tokenA, again) {
+		t.Error("ComputeTenantLookupToken() should be deterministic")
+	}
+
+	tokenB, _ := util.ComputeTenantLookupToken(ctxB, key, "user@example.com")
+	if bytes.Equal(tokenA, tokenB) {
+		t.Error("ComputeTenantLookupToken() should differ across tenants")
+	}
+
+	shifted, _ := util.ComputeTenantLookupToken(ctxShift, key, "user@example.com")
+	if bytes.Equal(tokenA, shifted) {
+		t.Error("ComputeTenantLookupToken() should not be ambiguous across field boundaries")
+	}
+
+	if bytes.Equal(tokenA, util.ComputeLookupToken(key, "user@example.com")) {
+		t.Error("ComputeTenantLookupToken() should be domain separated from ComputeLookupToken")
+	}
+
+	if _, err = util.ComputeTenantLookupToken(context.Background(), key, "x"); !errors.Is(err, util.ErrMissingTenancy) {
+		t.Errorf("ComputeTenantLookupToken() error = %v, want ErrMissingTenancy", err)
+	}
+}
+
+func TestComputeLookupTokenTruncation(t *testing.T) {
+	key := []byte("test-key-16-bytes-")
+	full := util.ComputeLookupToken(key, "user@example.com")
+
+	tests := []struct {
+		name    string
+		size    int
+		wantLen int
+	}{
+		{"8 bytes", 8, 8},
+		{"16 bytes", 16, 16},
+		{"below minimum", 4, 8},
+		{"full size", 32, 32},
+		{"above full size", 64, 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := util.ComputeLookupToken(key, "user@example.com", util.WithLookupTruncate(tt.size))
+			if len(got) != tt.wantLen {
+				t.Fatalf("ComputeLookupToken() length = %d, want %d", len(got), tt.wantLen)
+			}
+			if !bytes.Equal(got, full[:tt.wantLen]) {
+				t.Error("ComputeLookupToken() truncation should keep a prefix of the full token")
+			}
+		})
+	}
+
+	want := binary.BigEndian.Uint64(full)
+	if got := util.ComputeLookupTokenUint64(key, "user@example.com"); got != want {
+		t.Errorf("ComputeLookupTokenUint64() = %x, want %x", got, want)
+	}
+}
+
+func TestComputeLookupTokenString(t *testing.T) {
+	key := []byte("test-key-16-bytes-")
+	token := util.ComputeLookupToken(key, "user@example.com", util.WithLookupTruncate(16))
+
+	tests := []struct {
+		name     string
+		encoding util.LookupTokenEncoding
+		want     string
+	}{
+		{"hex", util.LookupEncodingHex, hex.EncodeToString(token)},
+		{
+			"base32",
+			util.LookupEncodingBase32,
+			strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(token)),
+		},
+		{"base64url", util.LookupEncodingBase64URL, base64.RawURLEncoding.EncodeToString(token)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := util.ComputeLookupTokenString(key, "user@example.com",
+				util.WithLookupTruncate(16), util.WithLookupEncoding(tt.encoding))
+			if got != tt.want {
+				t.Errorf("ComputeLookupTokenString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeLookupTokenHash(t *testing.T) {
+	key := []byte("test-key-16-bytes-")
+	longKey := make([]byte, 80)
+	for i := range longKey {
+		longKey[i] = byte(i)
+	}
+
+	tests := []struct {
+		name string
+		key  []byte
+		hash util.LookupHash
+		want string
+	}{
+		{
+			"hmac sha512/256", key, util.LookupHashHMACSHA512_256,
+			"1f1bb25b432e9ab5208d83770c91e44ff695d11e0e8c56b1e96747b58a7d54ac",
+		},
+		{
+			"blake2b", key, util.LookupHashBLAKE2b,
+			"b1cd9ec041465a117b373d3c819b2b0b4ced3db20e438943545421cd89e355fd",
+		},
+		{
+			"blake2b long key", longKey, util.LookupHashBLAKE2b,
+			"bb5812717f932a829383e09fc4ce0b13f1cdebcb43d39d5b2c1f15ea9313dd4b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if util.FIPSMode() && tt.hash == util.LookupHashBLAKE2b {
+				t.Skip("BLAKE2b is not available in FIPS mode")
+			}
+			got := util.ComputeLookupToken(tt.key, "user@example.com", util.WithLookupHash(tt.hash))
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("ComputeLookupToken(%s) = %x, want %s", tt.hash, got, tt.want)
+			}
+		})
+	}
+
+	def := util.ComputeLookupToken(key, "user@example.com")
+	explicit := util.ComputeLookupToken(key, "user@example.com", util.WithLookupHash(util.LookupHashHMACSHA256))
+	if !bytes.Equal(def, explicit) {
+		t.Error("ComputeLookupToken() default hash should be HMAC-SHA256")
+	}
+}
@@ -0,0 +1,340 @@
+package util
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metricNamePattern is the metric name syntax of the Prometheus data model.
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`) //nolint:gochecknoglobals // compiled once
+
+// MetricsRegistry is a small in-process registry of counters, gauges,
+// timers and latency histograms, exposed as an expvar variable or in the Prometheus text format,
+// for services that want basic metrics without a metrics library. Metrics
+// are identified by name; asking for an existing name returns the existing
+// metric. A MetricsRegistry is safe for concurrent use.
+//
+// Example:
+//
+//	metrics := NewMetricsRegistry()
+//	requests := metrics.Counter("http_requests_total", "HTTP requests served.")
+//	latency := metrics.Timer("http_request_duration_seconds", "HTTP request latency.")
+//	mux.Handle("GET /metrics", metrics)
+//	expvar.Publish("app", metrics.Expvar())
+//
+//	requests.Inc()
+//	defer latency.Time()()
+type MetricsRegistry struct {
+	mu      sync.RWMutex
+	metrics map[string]registeredMetric
+}
+
+type registeredMetric struct {
+	help   string
+	metric metric
+}
+
+type metric interface {
+	// kind is the Prometheus metric type
+	kind() string
+	// samples returns the metric's sample suffixes and values
+	samples() []metricSample
+	// expvarValue returns the value published through expvar
+	expvarValue() any
+}
+
+type metricSample struct {
+	suffix string
+	value  float64
+}
+
+// NewMetricsRegistry returns an empty MetricsRegistry.
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{metrics: make(map[string]registeredMetric)}
+}
+
+// Counter returns the counter called name, registering it with help text
+// if it does not exist. It panics when name is not a valid Prometheus
+// metric name or is registered as another kind of metric.
+func (r *MetricsRegistry) Counter(name, help string) *Counter {
+	return register(r, name, help, func() *Counter { return &Counter{} })
+}
+
+// Gauge returns the gauge called name, registering it with help text if it
+// does not exist. It panics like Counter.
+func (r *MetricsRegistry) Gauge(name, help string) *Gauge {
+	return register(r, name, help, func() *Gauge { return &Gauge{} })
+}
+
+// Timer returns the timer called name, registering it with help text if it
+// does not exist. It panics like Counter.
+func (r *MetricsRegistry) Timer(name, help string) *Timer {
+	return register(r, name, help, func() *Timer { return &Timer{} })
+}
+
+// Histogram returns the latency histogram called name, registering it with
+// help text and the bucket bounds of NewLatencyHistogram if it does not
+// exist. It panics like Counter.
+func (r *MetricsRegistry) Histogram(name, help string, buckets []time.Duration) *LatencyHistogram {
+	return register(r, name, help, func() *LatencyHistogram { return NewLatencyHistogram(buckets) })
+}
+
+// CounterFunc registers a counter called name whose value is read from fn
+// when exposed, for counts a component already keeps, such as CacheStats.
+// It panics like Counter, and also when name is already registered.
+func (r *MetricsRegistry) CounterFunc(name, help string, fn func() float64) {
+	registerFunc(r, name, help, &funcMetric{metricKind: "counter", fn: fn})
+}
+
+// GaugeFunc registers a gauge called name whose value is read from fn when
+// exposed, such as a queue length. It panics like CounterFunc.
+func (r *MetricsRegistry) GaugeFunc(name, help string, fn func() float64) {
+	registerFunc(r, name, help, &funcMetric{metricKind: "gauge", fn: fn})
+}
+
+func register[M metric](r *MetricsRegistry, name, help string, create func() M) M {
+	checkMetricName(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.metrics[name]; ok {
+		m, isKind := existing.metric.(M)
+		if !isKind {
+			panic(fmt.Sprintf("util.MetricsRegistry: %s is registered as a %s", name, existing.metric.kind()))
+		}
+		return m
+	}
+	m := create()
+	r.metrics[name] = registeredMetric{help: help, metric: m}
+	return m
+}
+
+func registerFunc(r *MetricsRegistry, name, help string, m *funcMetric) {
+	checkMetricName(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("util.MetricsRegistry: " + name + " is already registered")
+	}
+	r.metrics[name] = registeredMetric{help: help, metric: m}
+}
+
+func checkMetricName(name string) {
+	if !metricNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("util.MetricsRegistry: invalid metric name %q", name))
+	}
+}
+
+// WritePrometheus writes all metrics to w in the Prometheus text exposition
+// format, sorted by name. Timers are written as summaries without
+// quantiles, and durations are in seconds.
+func (r *MetricsRegistry) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, name := range r.names() {
+		r.mu.RLock()
+		entry := r.metrics[name]
+		r.mu.RUnlock()
+
+		if entry.help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, escapeMetricHelp(entry.help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, entry.metric.kind())
+		for _, sample := range entry.metric.samples() {
+			fmt.Fprintf(bw, "%s%s %s\n", name, sample.suffix, formatMetricValue(sample.value))
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format, so
+// a MetricsRegistry can be mounted as a scrape endpoint.
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WritePrometheus(w)
+}
+
+// Expvar returns an expvar.Var exposing the current value of every metric
+// as a JSON object keyed by name, for publishing with expvar.Publish.
+// Timers are exposed as their count and total seconds, and histograms also
+// with their median, 90th and 99th percentile.
+func (r *MetricsRegistry) Expvar() expvar.Var {
+	return expvar.Func(func() any {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		values := make(map[string]any, len(r.metrics))
+		for name, entry := range r.metrics {
+			values[name] = entry.metric.expvarValue()
+		}
+		return values
+	})
+}
+
+func (r *MetricsRegistry) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func escapeMetricHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func formatMetricValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a monotonically increasing count, such as requests served.
+type Counter struct {
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) {
+	c.value.Add(n)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+func (c *Counter) kind() string { return "counter" }
+
+func (c *Counter) samples() []metricSample {
+	return []metricSample{{value: float64(c.Value())}}
+}
+
+func (c *Counter) expvarValue() any { return c.Value() }
+
+// Gauge is a value that goes up and down, such as connections in use.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.bits.Store(math.Float64bits(v))
+}
+
+// Add adds delta, which may be negative, to the gauge.
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		if g.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) kind() string { return "gauge" }
+
+func (g *Gauge) samples() []metricSample {
+	return []metricSample{{value: g.Value()}}
+}
+
+func (g *Gauge) expvarValue() any { return g.Value() }
+
+// Timer records the number and total duration of timed operations, from
+// which the mean duration and rate can be derived.
+type Timer struct {
+	mu    sync.Mutex
+	count uint64
+	total time.Duration
+}
+
+// Observe records an operation that took d.
+func (t *Timer) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count++
+	t.total += d
+}
+
+// Time starts timing an operation and returns the function that records
+// it, for use with defer.
+//
+// Example:
+//
+//	defer timer.Time()()
+func (t *Timer) Time() func() {
+	sw := NewStopwatch()
+	return func() { t.Observe(sw.Elapsed()) }
+}
+
+// Count returns the number of operations recorded.
+func (t *Timer) Count() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.count
+}
+
+// Total returns the total duration of the operations recorded.
+func (t *Timer) Total() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+func (t *Timer) kind() string { return "summary" }
+
+func (t *Timer) samples() []metricSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return []metricSample{
+		{suffix: "_sum", value: t.total.Seconds()},
+		{suffix: "_count", value: float64(t.count)},
+	}
+}
+
+func (t *Timer) expvarValue() any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return map[string]any{"count": t.count, "seconds": t.total.Seconds()}
+}
+
+// funcMetric is a counter or gauge read from a function.
+type funcMetric struct {
+	metricKind string
+	fn         func() float64
+}
+
+func (f *funcMetric) kind() string { return f.metricKind }
+
+func (f *funcMetric) samples() []metricSample {
+	return []metricSample{{value: f.fn()}}
+}
+
+func (f *funcMetric) expvarValue() any { return f.fn() }
@@ -0,0 +1,131 @@
+package util_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestMetricsRegistryPrometheus(t *testing.T) {
+	reg := util.NewMetricsRegistry()
+	requests := reg.Counter("requests_total", "Requests served.")
+	requests.Inc()
+	requests.Add(2)
+	if again := reg.Counter("requests_total", "ignored"); again != requests {
+		t.Fatal("Counter with an existing name returned a new counter")
+	}
+	reg.Gauge("in_flight", "Requests in progress.\nMultiline \\ help.").Set(1.5)
+	reg.Timer("request_duration_seconds", "").Observe(1500 * time.Millisecond)
+	reg.GaugeFunc("queue_length", "", func() float64 { return 7 })
+
+	var b strings.Builder
+	if err := reg.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	want := `# HELP in_flight Requests in progress.\nMultiline \\ help.
+# TYPE in_flight gauge
+in_flight 1.5
+# TYPE queue_length gauge
+queue_length 7
+# TYPE request_duration_seconds summary
+request_duration_seconds_sum 1.5
+request_duration_seconds_count 1
+# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total 3
+`
+	if b.String() != want {
+		t.Fatalf("WritePrometheus wrote\n%s\nwant\n%s", b.String(), want)
+	}
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Body.String() != want || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("ServeHTTP = %q with %q", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestMetricsRegistryExpvar(t *testing.T) {
+	reg := util.NewMetricsRegistry()
+	reg.Counter("hits", "").Add(4)
+	reg.Timer("latency", "").Observe(2 * time.Second)
+
+	var values map[string]any
+	if err := json.Unmarshal([]byte(reg.Expvar().String()), &values); err != nil {
+		t.Fatalf("Expvar is not JSON: %v", err)
+	}
+	if values["hits"] != float64(4) {
+		t.Fatalf("hits = %v, want 4", values["hits"])
+	}
+	latency, _ := values["latency"].(map[string]any)
+	if latency["count"] != float64(1) || latency["seconds"] != float64(2) {
+		t.Fatalf("latency = %v, want count 1 and 2 seconds", values["latency"])
+	}
+}
+
+func TestMetricsRegistryPanics(t *testing.T) {
+	reg := util.NewMetricsRegistry()
+	reg.Counter("jobs", "")
+	for name, register := range map[string]func(){
+		"invalid name":   func() { reg.Counter("jobs-done", "") },
+		"different kind": func() { reg.Gauge("jobs", "") },
+		"func duplicate": func() { reg.CounterFunc("jobs", "", func() float64 { return 0 }) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected a panic")
+				}
+			}()
+			register()
+		})
+	}
+}
+
+func TestMetricsConcurrentUpdates(t *testing.T) {
+	reg := util.NewMetricsRegistry()
+	counter := reg.Counter("ops_total", "")
+	gauge := reg.Gauge("level", "")
+	timer := reg.Timer("op_seconds", "")
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			for range 100 {
+				counter.Inc()
+				gauge.Add(0.5)
+				timer.Observe(time.Millisecond)
+			}
+		})
+	}
+	wg.Wait()
+
+	if counter.Value() != 5000 || gauge.Value() != 2500 || timer.Count() != 5000 || timer.Total() != 5*time.Second {
+		t.Fatalf("got counter %d, gauge %v, timer %d/%v", counter.Value(), gauge.Value(), timer.Count(), timer.Total())
+	}
+}
+
+func TestCacheRegisterMetrics(t *testing.T) {
+	reg := util.NewMetricsRegistry()
+	cache := util.NewCache[string, int]()
+	cache.RegisterMetrics(reg, "users")
+	cache.Set("a", 1)
+	cache.Get("a")
+	cache.Get("b")
+
+	var b strings.Builder
+	if err := reg.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	for _, line := range []string{"users_hits_total 1\n", "users_misses_total 1\n", "users_entries 1\n"} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("metrics lack %q:\n%s", line, b.String())
+		}
+	}
+}
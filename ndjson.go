@@ -0,0 +1,81 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// StreamNDJSON writes rows to w as newline-delimited JSON, one object per
+// line, stopping with ctx's error when ctx is done.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "application/x-ndjson")
+//	err := StreamNDJSON(ctx, w, events(ctx))
+func StreamNDJSON[T any](ctx context.Context, w io.Writer, rows iter.Seq[T]) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for row := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := enc.Encode(row); err != nil {
+			return fmt.Errorf("failed to write NDJSON: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write NDJSON: %w", err)
+	}
+	return nil
+}
+
+// ReadNDJSON returns an iterator over the lines of the newline-delimited
+// JSON in r decoded into T. Blank lines are skipped. A line that cannot be
+// decoded is yielded with a *LineError, and reading goes on. Reading stops
+// after yielding ctx's error when ctx is done, or a read error.
+//
+// Example:
+//
+//	for event, err := range ReadNDJSON[Event](ctx, req.Body) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    handle(ctx, event)
+//	}
+func ReadNDJSON[T any](ctx context.Context, r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		br := bufio.NewReader(r)
+		for lineNumber := 1; ; lineNumber++ {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			line, readErr := br.ReadBytes('\n')
+			if readErr != nil && !errors.Is(readErr, io.EOF) {
+				yield(zero, fmt.Errorf("failed to read NDJSON: %w", readErr))
+				return
+			}
+
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				var row T
+				if err := json.Unmarshal(line, &row); err != nil {
+					if !yield(zero, &LineError{Line: lineNumber, Err: err}) {
+						return
+					}
+				} else if !yield(row, nil) {
+					return
+				}
+			}
+			if readErr != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type ndjsonEvent struct {
+	Type string `json:"type"`
+	N    int    `json:"n"`
+}
+
+func TestNDJSONRoundTrip(t *testing.T) {
+	events := []ndjsonEvent{{"a", 1}, {"b", 2}}
+	var b strings.Builder
+	if err := util.StreamNDJSON(t.Context(), &b, slices.Values(events)); err != nil {
+		t.Fatalf("StreamNDJSON: %v", err)
+	}
+	if b.String() != "{\"type\":\"a\",\"n\":1}\n{\"type\":\"b\",\"n\":2}\n" {
+		t.Fatalf("StreamNDJSON wrote %q", b.String())
+	}
+
+	var got []ndjsonEvent
+	for event, err := range util.ReadNDJSON[ndjsonEvent](t.Context(), strings.NewReader(b.String())) {
+		if err != nil {
+			t.Fatalf("ReadNDJSON: %v", err)
+		}
+		got = append(got, event)
+	}
+	if !slices.Equal(got, events) {
+		t.Fatalf("ReadNDJSON = %v, want %v", got, events)
+	}
+}
+
+func TestReadNDJSONLineErrors(t *testing.T) {
+	input := "{\"type\":\"a\"}\n\n{broken\n{\"type\":\"c\"}"
+	var types []string
+	var lines []int
+	for event, err := range util.ReadNDJSON[ndjsonEvent](t.Context(), strings.NewReader(input)) {
+		if lineErr, ok := errors.AsType[*util.LineError](err); ok {
+			lines = append(lines, lineErr.Line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ReadNDJSON: %v", err)
+		}
+		types = append(types, event.Type)
+	}
+	if !slices.Equal(types, []string{"a", "c"}) || !slices.Equal(lines, []int{3}) {
+		t.Fatalf("types %v, error lines %v; want [a c] and [3]", types, lines)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	for _, err := range util.ReadNDJSON[ndjsonEvent](ctx, strings.NewReader(input)) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("ReadNDJSON = %v, want Canceled", err)
+		}
+	}
+}
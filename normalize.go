@@ -0,0 +1,134 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps the Cyrillic and Greek letters most often substituted
+// for Latin ones in look-alike identifiers to those Latin letters. It covers
+// common homoglyph attacks, not the full Unicode confusables data (UTS #39).
+//
+//nolint:gochecknoglobals // fixed lookup table
+var confusables = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i',
+	'ј': 'j', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O',
+	'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J',
+	// Greek
+	'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'τ': 't',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K',
+	'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// normalizeOptions contains configuration for Normalize.
+type normalizeOptions struct {
+	// keepCase skips case folding
+	keepCase bool
+
+	// confusables maps look-alike letters to Latin
+	confusables bool
+
+	// email applies the e-mail address rules
+	email bool
+}
+
+// NormalizeOption is a function that configures Normalize.
+type NormalizeOption func(*normalizeOptions)
+
+// WithNormalizeKeepCase skips case folding, for identifiers that are case
+// sensitive.
+func WithNormalizeKeepCase() NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.keepCase = true
+	}
+}
+
+// WithNormalizeConfusables maps Cyrillic and Greek letters that look like
+// Latin ones to those Latin letters, so "paypal" spelled with the Cyrillic
+// U+0430 in place of an "a" normalizes to the Latin "paypal". Only use it
+// for identifiers expected to be Latin, as it changes legitimate Cyrillic
+// and Greek text.
+func WithNormalizeConfusables() NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.confusables = true
+	}
+}
+
+// WithNormalizeEmail applies e-mail address rules after the general ones:
+// all whitespace is removed and a "+tag" sub-address is dropped from the
+// local part, so "Jane.Doe+news@Example.com " normalizes to
+// "jane.doe@example.com". The domain is always lower-cased, even with
+// WithNormalizeKeepCase.
+func WithNormalizeEmail() NormalizeOption {
+	return func(o *normalizeOptions) {
+		o.email = true
+	}
+}
+
+// Normalize converts an identifier such as a user name or e-mail address to
+// its canonical form, the pre-processing step before ComputeLookupToken so
+// that every spelling of the same identifier produces the same token. It
+// removes invisible format characters (zero-width spaces and joiners, bidi
+// controls, soft hyphens), applies Unicode NFKC normalization, folds case,
+// and trims and collapses runs of whitespace to single spaces. Normalize is
+// idempotent.
+//
+// Changing the options changes the canonical form, so tokens computed
+// before and after are not comparable.
+//
+// Example:
+//
+//	token := ComputeLookupToken(key, Normalize(email, WithNormalizeEmail()))
+func Normalize(s string, opts ...NormalizeOption) string {
+	var options normalizeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	s = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+	s = norm.NFKC.String(s)
+	if options.confusables {
+		s = strings.Map(func(r rune) rune {
+			if latin, ok := confusables[r]; ok {
+				return latin
+			}
+			return r
+		}, s)
+	}
+	if !options.keepCase {
+		// Folding can produce text that is no longer in NFKC.
+		s = norm.NFKC.String(cases.Fold().String(s))
+	}
+	s = strings.Join(strings.Fields(s), " ")
+
+	if options.email {
+		s = normalizeEmail(s)
+	}
+	return s
+}
+
+// normalizeEmail removes whitespace and the sub-address from an address
+// and lower-cases its domain.
+func normalizeEmail(s string) string {
+	s = strings.ReplaceAll(s, " ", "")
+	at := strings.LastIndexByte(s, '@')
+	if at < 0 {
+		return s
+	}
+	local, domain := s[:at], strings.ToLower(s[at+1:])
+	if plus := strings.IndexByte(local, '+'); plus > 0 {
+		local = local[:plus]
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,54 @@
+package util_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts []util.NormalizeOption
+		want string
+	}{
+		{in: "  Jane \t Doe\n", want: "jane doe"},
+		{in: "Straße", want: "strasse"},
+		{in: "Ｊａｎｅ", want: "jane"},             // full-width
+		{in: "ja\u200bne\u00ad", want: "jane"}, // zero-width space, soft hyphen
+		{in: "ﬁle", want: "file"},              // ligature
+		{in: "Jane", opts: []util.NormalizeOption{util.WithNormalizeKeepCase()}, want: "Jane"},
+		{in: "p\u0430yp\u0430l", opts: []util.NormalizeOption{util.WithNormalizeConfusables()}, want: "paypal"},
+		{in: "p\u0430yp\u0430l", want: "p\u0430yp\u0430l"},
+		{
+			in:   " Jane.Doe+news@Example.COM ",
+			opts: []util.NormalizeOption{util.WithNormalizeEmail()},
+			want: "jane.doe@example.com",
+		},
+		{
+			in:   "Jane+x@Example.COM",
+			opts: []util.NormalizeOption{util.WithNormalizeEmail(), util.WithNormalizeKeepCase()},
+			want: "Jane@example.com",
+		},
+		{in: "+only@host", opts: []util.NormalizeOption{util.WithNormalizeEmail()}, want: "+only@host"},
+	}
+	for _, tt := range tests {
+		got := util.Normalize(tt.in, tt.opts...)
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if again := util.Normalize(got, tt.opts...); again != got {
+			t.Errorf("Normalize is not idempotent on %q: %q", got, again)
+		}
+	}
+}
+
+func TestNormalizeLookupToken(t *testing.T) {
+	key := []byte("32-byte-secret-key-for-hmac-test")
+	a := util.ComputeLookupToken(key, util.Normalize("JANE@example.com ", util.WithNormalizeEmail()))
+	b := util.ComputeLookupToken(key, util.Normalize("jane+promo@EXAMPLE.com", util.WithNormalizeEmail()))
+	if !bytes.Equal(a, b) {
+		t.Error("spellings of the same address produce different lookup tokens")
+	}
+}
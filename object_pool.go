@@ -0,0 +1,280 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Pool.Get after the pool was closed.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// poolOptions contains configuration for a Pool.
+type poolOptions struct {
+	// maxSize bounds the objects in use and idle
+	maxSize int
+
+	// maxIdle bounds the idle objects kept for reuse
+	maxIdle int
+
+	// idleTimeout is how long an object may stay idle, zero for no limit
+	idleTimeout time.Duration
+
+	// now is the time source
+	now func() time.Time
+}
+
+// PoolOption is a function that configures a Pool.
+type PoolOption func(*poolOptions)
+
+// WithPoolMaxSize bounds the number of objects of the pool, in use and idle
+// together; Get waits for one to be returned when the bound is reached. The
+// default is GOMAXPROCS.
+func WithPoolMaxSize(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.maxSize = n
+	}
+}
+
+// WithPoolMaxIdle bounds the number of idle objects kept for reuse; objects
+// returned beyond it are closed. The default is the maximum size.
+func WithPoolMaxIdle(n int) PoolOption {
+	return func(o *poolOptions) {
+		o.maxIdle = n
+	}
+}
+
+// WithPoolIdleTimeout closes objects that stayed idle for longer than d,
+// such as connections the server side would have timed out. By default idle
+// objects are kept indefinitely.
+func WithPoolIdleTimeout(d time.Duration) PoolOption {
+	return func(o *poolOptions) {
+		o.idleTimeout = d
+	}
+}
+
+// WithPoolClock overrides the time source used for idle timeouts.
+func WithPoolClock(now func() time.Time) PoolOption {
+	return func(o *poolOptions) {
+		o.now = now
+	}
+}
+
+// PoolFuncs are the functions a Pool manages its objects with.
+type PoolFuncs[T any] struct {
+	// New creates an object. It is required.
+	New func(ctx context.Context) (T, error)
+
+	// Validate, when set, checks an idle object before Get returns it, such
+	// as pinging a connection; objects failing it are closed.
+	Validate func(ctx context.Context, v T) error
+
+	// Close, when set, releases an object the pool drops.
+	Close func(v T) error
+}
+
+// Pool is a bounded pool of expensive objects, such as client connections
+// or compressors, that are created on demand, reused while idle, checked
+// before reuse and closed when no longer wanted. Unlike sync.Pool it never
+// drops objects silently, bounds how many exist, and lets callers wait for
+// one with a context. A Pool is safe for concurrent use.
+//
+// Example:
+//
+//	conns := NewPool(PoolFuncs[*Conn]{
+//	    New:      func(ctx context.Context) (*Conn, error) { return Dial(ctx, addr) },
+//	    Validate: (*Conn).Ping,
+//	    Close:    (*Conn).Close,
+//	}, WithPoolMaxSize(8), WithPoolIdleTimeout(time.Minute))
+//	defer conns.Close()
+//
+//	conn, err := conns.Get(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	defer conns.Put(conn)
+type Pool[T any] struct {
+	funcs    PoolFuncs[T]
+	options  poolOptions
+	counters poolCounters
+
+	mu     sync.Mutex
+	idle   []idleObject[T] // least recently returned first
+	size   int             // objects created and not yet closed
+	closed bool
+	// changed is closed and replaced when an object is returned or closed,
+	// waking waiting Gets
+	changed chan struct{}
+}
+
+type idleObject[T any] struct {
+	value T
+	since time.Time
+}
+
+// NewPool returns an empty Pool creating objects with funcs.New.
+func NewPool[T any](funcs PoolFuncs[T], opts ...PoolOption) *Pool[T] {
+	options := poolOptions{maxSize: runtime.GOMAXPROCS(0), now: time.Now}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.maxSize = max(options.maxSize, 1)
+	if options.maxIdle <= 0 || options.maxIdle > options.maxSize {
+		options.maxIdle = options.maxSize
+	}
+	return &Pool[T]{funcs: funcs, options: options, changed: make(chan struct{})}
+}
+
+// Get returns an idle object that passes validation, or a new one when
+// there is none and the pool is below its maximum size. Otherwise it waits
+// for an object to be returned, until ctx is done. The object must be
+// given back with Put, or with Discard when it turned out to be broken.
+func (p *Pool[T]) Get(ctx context.Context) (T, error) {
+	var zero T
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return zero, ErrPoolClosed
+		}
+
+		if n := len(p.idle); n > 0 {
+			obj := p.idle[n-1]
+			p.idle[n-1] = idleObject[T]{}
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+			if p.expired(obj) {
+				p.Discard(obj.value)
+				continue
+			}
+			if p.funcs.Validate != nil {
+				if err := p.funcs.Validate(ctx, obj.value); err != nil {
+					Log(ctx).WithError(err).Debug("discarding pooled object that failed validation")
+					p.Discard(obj.value)
+					continue
+				}
+			}
+			p.counters.gets.Add(1)
+			return obj.value, nil
+		}
+
+		if p.size < p.options.maxSize {
+			p.size++
+			p.mu.Unlock()
+			v, err := p.funcs.New(ctx)
+			if err != nil {
+				p.release()
+				return zero, fmt.Errorf("failed to create pooled object: %w", err)
+			}
+			p.counters.gets.Add(1)
+			p.counters.news.Add(1)
+			return v, nil
+		}
+
+		changed := p.changed
+		p.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Put returns v to the pool for reuse. It is closed instead when the pool
+// already keeps its maximum of idle objects or was closed. v must not be
+// used afterwards.
+func (p *Pool[T]) Put(v T) {
+	p.mu.Lock()
+	if p.closed || len(p.idle) >= p.options.maxIdle {
+		p.mu.Unlock()
+		p.Discard(v)
+		return
+	}
+	p.idle = append(p.idle, idleObject[T]{value: v, since: p.options.now()})
+	expired := p.takeExpired()
+	p.counters.puts.Add(1)
+	p.notify()
+	p.mu.Unlock()
+
+	for _, obj := range expired {
+		p.Discard(obj.value)
+	}
+}
+
+// Discard closes v instead of returning it to the pool, for objects found
+// to be broken, freeing its place for a new one.
+func (p *Pool[T]) Discard(v T) {
+	p.counters.discarded.Add(1)
+	if p.funcs.Close != nil {
+		_ = p.funcs.Close(v)
+	}
+	p.release()
+}
+
+// Close closes the idle objects and makes Get fail with ErrPoolClosed;
+// objects in use are closed when they are returned. It returns the errors
+// of closing the idle objects.
+func (p *Pool[T]) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.notify()
+	p.mu.Unlock()
+
+	var errs []error
+	for _, obj := range idle {
+		p.counters.discarded.Add(1)
+		if p.funcs.Close != nil {
+			if err := p.funcs.Close(obj.value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		p.release()
+	}
+	return errors.Join(errs...)
+}
+
+// Stats returns the pool's counters. Discarded counts the objects closed.
+func (p *Pool[T]) Stats() PoolStats {
+	return p.counters.stats()
+}
+
+// release gives up the place of an object that was closed or failed to be
+// created.
+func (p *Pool[T]) release() {
+	p.mu.Lock()
+	p.size--
+	p.notify()
+	p.mu.Unlock()
+}
+
+// notify wakes the waiting Gets; p.mu must be held.
+func (p *Pool[T]) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *Pool[T]) expired(obj idleObject[T]) bool {
+	return p.options.idleTimeout > 0 && p.options.now().Sub(obj.since) > p.options.idleTimeout
+}
+
+// takeExpired removes and returns the idle objects past the idle timeout;
+// p.mu must be held.
+func (p *Pool[T]) takeExpired() []idleObject[T] {
+	n := 0
+	for n < len(p.idle) && p.expired(p.idle[n]) {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	expired := slices.Clone(p.idle[:n])
+	p.idle = slices.Delete(p.idle, 0, n)
+	return expired
+}
@@ -0,0 +1,201 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type pooledConn struct {
+	id      int
+	healthy bool
+	closed  bool
+}
+
+type connFactory struct {
+	mu     sync.Mutex
+	nextID int
+	closed []int
+}
+
+func (f *connFactory) funcs() util.PoolFuncs[*pooledConn] {
+	return util.PoolFuncs[*pooledConn]{
+		New: func(context.Context) (*pooledConn, error) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.nextID++
+			return &pooledConn{id: f.nextID, healthy: true}, nil
+		},
+		Validate: func(_ context.Context, c *pooledConn) error {
+			if !c.healthy {
+				return errors.New("connection reset")
+			}
+			return nil
+		},
+		Close: func(c *pooledConn) error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			c.closed = true
+			f.closed = append(f.closed, c.id)
+			return nil
+		},
+	}
+}
+
+func TestPoolReuseAndValidation(t *testing.T) {
+	factory := &connFactory{}
+	pool := util.NewPool(factory.funcs(), util.WithPoolMaxSize(2))
+	ctx := t.Context()
+
+	first, _ := pool.Get(ctx)
+	pool.Put(first)
+	if again, _ := pool.Get(ctx); again != first {
+		t.Fatalf("Get returned conn %d, want the idle conn %d", again.id, first.id)
+	}
+
+	first.healthy = false
+	pool.Put(first)
+	replacement, err := pool.Get(ctx)
+	if err != nil || replacement == first || !first.closed {
+		t.Fatalf("Get = conn %v, %v; want a new conn replacing the unhealthy one", replacement, err)
+	}
+
+	want := util.PoolStats{Gets: 3, News: 2, Puts: 2, Discarded: 1}
+	if stats := pool.Stats(); stats != want {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestPoolWaitsAtMaxSize(t *testing.T) {
+	pool := util.NewPool((&connFactory{}).funcs(), util.WithPoolMaxSize(1))
+	held, _ := pool.Get(t.Context())
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get at max size = %v, want a deadline error", err)
+	}
+
+	got := make(chan *pooledConn)
+	go func() {
+		conn, _ := pool.Get(t.Context())
+		got <- conn
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pool.Put(held)
+	if conn := <-got; conn != held {
+		t.Fatalf("waiting Get returned conn %d, want the returned conn %d", conn.id, held.id)
+	}
+
+	go func() {
+		conn, _ := pool.Get(t.Context())
+		got <- conn
+	}()
+	time.Sleep(10 * time.Millisecond)
+	pool.Discard(held)
+	if conn := <-got; conn == held || conn.id != 2 {
+		t.Fatalf("Get after Discard returned conn %d, want a new conn", conn.id)
+	}
+}
+
+func TestPoolIdleTimeoutAndMaxIdle(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	factory := &connFactory{}
+	pool := util.NewPool(factory.funcs(),
+		util.WithPoolMaxSize(3), util.WithPoolMaxIdle(1),
+		util.WithPoolIdleTimeout(time.Minute), util.WithPoolClock(clock.Now))
+	ctx := t.Context()
+
+	a, _ := pool.Get(ctx)
+	b, _ := pool.Get(ctx)
+	pool.Put(a)
+	pool.Put(b)
+	if !b.closed || a.closed {
+		t.Fatal("the conn returned beyond the idle maximum was not closed")
+	}
+
+	clock.Advance(2 * time.Minute)
+	c, _ := pool.Get(ctx)
+	if c == a || !a.closed {
+		t.Fatal("Get reused a conn idle for longer than the idle timeout")
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	factory := &connFactory{}
+	pool := util.NewPool(factory.funcs(), util.WithPoolMaxSize(2))
+	ctx := t.Context()
+
+	idle, _ := pool.Get(ctx)
+	inUse, _ := pool.Get(ctx)
+	pool.Put(idle)
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !idle.closed || inUse.closed {
+		t.Fatal("Close should close idle conns only")
+	}
+	if _, err := pool.Get(ctx); !errors.Is(err, util.ErrPoolClosed) {
+		t.Fatalf("Get after Close = %v, want ErrPoolClosed", err)
+	}
+	pool.Put(inUse)
+	if !inUse.closed {
+		t.Fatal("a conn returned after Close was not closed")
+	}
+}
+
+func TestPoolNewError(t *testing.T) {
+	var attempts atomic.Int32
+	pool := util.NewPool(util.PoolFuncs[int]{
+		New: func(context.Context) (int, error) {
+			if attempts.Add(1) == 1 {
+				return 0, errors.New("dial failed")
+			}
+			return 7, nil
+		},
+	}, util.WithPoolMaxSize(1))
+
+	if _, err := pool.Get(t.Context()); err == nil {
+		t.Fatal("Get did not return the New error")
+	}
+	if v, err := pool.Get(t.Context()); err != nil || v != 7 {
+		t.Fatalf("Get after a failed New = %d, %v; want 7", v, err)
+	}
+}
+
+func TestPoolConcurrentUse(t *testing.T) {
+	factory := &connFactory{}
+	pool := util.NewPool(factory.funcs(), util.WithPoolMaxSize(4))
+	var inUse, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 32 {
+		wg.Go(func() {
+			for range 20 {
+				conn, err := pool.Get(t.Context())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				n := inUse.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				inUse.Add(-1)
+				pool.Put(conn)
+			}
+		})
+	}
+	wg.Wait()
+	if peak.Load() > 4 || factory.nextID > 4 {
+		t.Fatalf("peak in use %d, created %d; want at most 4", peak.Load(), factory.nextID)
+	}
+}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Optional holds a value of type T or nothing, telling an absent value apart
+// from the zero value without using a pointer. The zero value holds
+// nothing. In JSON, nothing is null; tag struct fields with omitzero to leave
+// them out instead.
+//
+// Example:
+//
+//	type UpdateUser struct {
+//	    Name     Optional[string] `json:"name,omitzero"`
+//	    Nickname Optional[string] `json:"nickname,omitzero"` // "" clears it
+//	}
+//
+//	if name, ok := req.Name.Get(); ok {
+//	    user.Name = name
+//	}
+type Optional[T any] struct {
+	value T
+	ok    bool
+}
+
+// Some returns an Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, ok: true}
+}
+
+// None returns an Optional holding nothing.
+func None[T any]() Optional[T] {
+	return Optional[T]{}
+}
+
+// OptionalFromPtr returns an Optional holding *p, or nothing when p is nil.
+func OptionalFromPtr[T any](p *T) Optional[T] {
+	if p == nil {
+		return None[T]()
+	}
+	return Some(*p)
+}
+
+// Get returns the value and whether there is one.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.ok
+}
+
+// IsSome reports whether o holds a value.
+func (o Optional[T]) IsSome() bool {
+	return o.ok
+}
+
+// OrElse returns the value, or def when there is none.
+func (o Optional[T]) OrElse(def T) T {
+	if o.ok {
+		return o.value
+	}
+	return def
+}
+
+// OrElseGet returns the value, or the result of fn when there is none, for
+// defaults that are costly to compute.
+func (o Optional[T]) OrElseGet(fn func() T) T {
+	if o.ok {
+		return o.value
+	}
+	return fn()
+}
+
+// Ptr returns a pointer to a copy of the value, or nil when there is none.
+func (o Optional[T]) Ptr() *T {
+	if !o.ok {
+		return nil
+	}
+	return Ptr(o.value)
+}
+
+// MarshalJSON implements json.Marshaler, encoding nothing as null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.ok {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding null as nothing.
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*o = None[T]()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
+
+// MapOptional returns an Optional holding fn applied to the value of o, or
+// nothing when o holds nothing.
+//
+// Example:
+//
+//	domain := MapOptional(req.Email, emailDomain)
+func MapOptional[T, U any](o Optional[T], fn func(T) U) Optional[U] {
+	if !o.ok {
+		return None[U]()
+	}
+	return Some(fn(o.value))
+}
@@ -0,0 +1,72 @@
+package util_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestOptional(t *testing.T) {
+	var none util.Optional[int]
+	if none.IsSome() || none.OrElse(7) != 7 || none.Ptr() != nil {
+		t.Fatal("zero Optional should hold nothing")
+	}
+	if got := none.OrElseGet(func() int { return 9 }); got != 9 {
+		t.Fatalf("OrElseGet = %d, want 9", got)
+	}
+
+	some := util.Some(0)
+	if v, ok := some.Get(); !ok || v != 0 || some.OrElse(7) != 0 || *some.Ptr() != 0 {
+		t.Fatal("Some(0) should hold the zero value")
+	}
+
+	if util.OptionalFromPtr[int](nil).IsSome() {
+		t.Fatal("OptionalFromPtr(nil) should hold nothing")
+	}
+	if v, _ := util.OptionalFromPtr(util.Ptr(3)).Get(); v != 3 {
+		t.Fatalf("OptionalFromPtr = %d, want 3", v)
+	}
+
+	mapped := util.MapOptional(util.Some(42), strconv.Itoa)
+	if v, _ := mapped.Get(); v != "42" {
+		t.Fatalf("MapOptional = %q, want \"42\"", v)
+	}
+	if util.MapOptional(none, strconv.Itoa).IsSome() {
+		t.Fatal("MapOptional of nothing should hold nothing")
+	}
+}
+
+func TestOptionalJSON(t *testing.T) {
+	type update struct {
+		Name     util.Optional[string] `json:"name,omitzero"`
+		Nickname util.Optional[string] `json:"nickname"`
+	}
+
+	out, err := json.Marshal(update{Name: util.Some("Jane")})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(out) != `{"name":"Jane","nickname":null}` {
+		t.Fatalf("Marshal = %s", out)
+	}
+	out, _ = json.Marshal(update{})
+	if string(out) != `{"nickname":null}` {
+		t.Fatalf("Marshal of nothing = %s", out)
+	}
+
+	var decoded update
+	if err = json.Unmarshal([]byte(`{"name":"","nickname":null}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if v, ok := decoded.Name.Get(); !ok || v != "" {
+		t.Fatal("an empty string should decode to Some(\"\")")
+	}
+	if decoded.Nickname.IsSome() {
+		t.Fatal("null should decode to nothing")
+	}
+	if err = json.Unmarshal([]byte(`{"name":1}`), &decoded); err == nil {
+		t.Fatal("Unmarshal of a mistyped value should fail")
+	}
+}
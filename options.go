@@ -43,6 +43,9 @@ type logOptions struct {
 	// handlerWrapper wraps the stdout handler (tint or JSON) before it is added to the MultiHandler.
 	// Use this to inject middleware such as trace context injection without adding dependencies to util.
 	handlerWrapper func(slog.Handler) slog.Handler
+
+	// tenancy adds the tenancy on each record's context as attributes
+	tenancy bool
 }
 
 // Option is a function that configures logOptions.
@@ -71,6 +74,9 @@ func defaultHandlerCreator(writer io.Writer, opts *logOptions) slog.Handler {
 
 	if opts.handler != nil {
 		if opts.handlerExclusive {
+			if opts.tenancy {
+				return &tenancyHandler{Handler: opts.handler}
+			}
 			return opts.handler
 		}
 	}
@@ -100,6 +106,9 @@ func defaultHandlerCreator(writer io.Writer, opts *logOptions) slog.Handler {
 		multiHandler.extendHandler(opts.handler)
 	}
 
+	if opts.tenancy {
+		return &tenancyHandler{Handler: multiHandler}
+	}
 	return multiHandler
 }
 
@@ -178,6 +187,19 @@ func WithLogHandlerWrapper(wrapper func(slog.Handler) slog.Handler) Option {
 	}
 }
 
+// WithLogTenancy adds tenant_id, partition_id and access_id attributes to
+// every record whose context carries tenancy (see GetTenancy), so each log
+// line of a multi-tenant service can be filtered by tenant. The tenancy is
+// read when the record is logged, so one logger serves every tenant:
+//
+//	log := NewLogger(ctx, WithLogTenancy())
+//	log.WithContext(r.Context()).Info("order created") // tenant_id=acme ...
+func WithLogTenancy() Option {
+	return func(o *logOptions) {
+		o.tenancy = true
+	}
+}
+
 // ParseLevel converts a string to a log.level.
 // It is case-insensitive.
 // Returns an error if the string does not match a known level.
@@ -0,0 +1,223 @@
+package util
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"strconv"
+)
+
+// OrderedMap is a map that remembers the order keys were first set in, and
+// keeps it through JSON encoding, for output whose field order matters,
+// such as payloads that are signed or diffed. Setting an existing key
+// updates its value in place; deleting and setting it again moves it to the
+// end. Get, Set and Delete run in constant time.
+//
+// Keys are encoded to JSON like encoding/json encodes map keys: strings,
+// integers and encoding.TextMarshaler implementations are supported.
+//
+// The zero value is an empty map ready to use. An OrderedMap is not safe for
+// concurrent use.
+//
+// Example:
+//
+//	m := NewOrderedMap[string, any]()
+//	m.Set("z", 1)
+//	m.Set("a", 2)
+//	out, _ := json.Marshal(m) // {"z":1,"a":2}
+type OrderedMap[K comparable, V any] struct {
+	entries     map[K]*orderedMapEntry[K, V]
+	first, last *orderedMapEntry[K, V]
+}
+
+type orderedMapEntry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *orderedMapEntry[K, V]
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{}
+}
+
+// Len returns the number of keys in m.
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.entries)
+}
+
+// Get returns the value of key and whether m holds it.
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if e, ok := m.entries[key]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Set sets the value of key, appending key to the order when m does not
+// hold it yet.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if e, ok := m.entries[key]; ok {
+		e.value = value
+		return
+	}
+	if m.entries == nil {
+		m.entries = make(map[K]*orderedMapEntry[K, V])
+	}
+	e := &orderedMapEntry[K, V]{key: key, value: value, prev: m.last}
+	if m.last != nil {
+		m.last.next = e
+	} else {
+		m.first = e
+	}
+	m.last = e
+	m.entries[key] = e
+}
+
+// Delete removes key and reports whether m held it.
+func (m *OrderedMap[K, V]) Delete(key K) bool {
+	e, ok := m.entries[key]
+	if !ok {
+		return false
+	}
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		m.first = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		m.last = e.prev
+	}
+	delete(m.entries, key)
+	return true
+}
+
+// Keys returns the keys of m in order.
+func (m *OrderedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, m.Len())
+	for e := m.first; e != nil; e = e.next {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+// All iterates over the keys and values of m in order. Deleting the
+// current key while iterating is safe.
+func (m *OrderedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := m.first; e != nil; {
+			next := e.next
+			if !yield(e.key, e.value) {
+				return
+			}
+			e = next
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, writing the keys in order.
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for e := m.first; e != nil; e = e.next {
+		if e != m.first {
+			buf.WriteByte(',')
+		}
+		key, err := orderedMapKeyString(e.key)
+		if err != nil {
+			return nil, err
+		}
+		encodedKey, _ := json.Marshal(key)
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, fmt.Errorf("ordered map key %q: %w", key, err)
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, adding the keys of a JSON
+// object in the order they appear. Existing keys are kept.
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return errors.New("ordered map must be a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		text, _ := tok.(string)
+		key, err := parseOrderedMapKey[K](text)
+		if err != nil {
+			return err
+		}
+		var value V
+		if err := dec.Decode(&value); err != nil {
+			return fmt.Errorf("ordered map key %q: %w", text, err)
+		}
+		m.Set(key, value)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// orderedMapKeyString converts key to a JSON object key.
+func orderedMapKeyString[K comparable](key K) (string, error) {
+	if tm, ok := any(key).(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	v := reflect.ValueOf(key)
+	switch v.Kind() { //nolint:exhaustive // other kinds cannot be JSON keys
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported ordered map key type %T", key)
+	}
+}
+
+// parseOrderedMapKey converts a JSON object key to K.
+func parseOrderedMapKey[K comparable](text string) (K, error) {
+	var key K
+	if tu, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(text))
+		return key, err
+	}
+	v := reflect.ValueOf(&key).Elem()
+	switch v.Kind() { //nolint:exhaustive // other kinds cannot be JSON keys
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("invalid ordered map key %q: %w", text, err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return key, fmt.Errorf("invalid ordered map key %q: %w", text, err)
+		}
+		v.SetUint(n)
+	default:
+		return key, fmt.Errorf("unsupported ordered map key type %T", key)
+	}
+	return key, nil
+}
@@ -0,0 +1,94 @@
+package util_test
+
+import (
+	"encoding/json"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestOrderedMap(t *testing.T) {
+	m := util.NewOrderedMap[string, int]()
+	m.Set("z", 1)
+	m.Set("a", 2)
+	m.Set("m", 3)
+	m.Set("z", 10)
+
+	if got := m.Keys(); !slices.Equal(got, []string{"z", "a", "m"}) {
+		t.Errorf("Keys = %q, want [z a m]", got)
+	}
+	if v, ok := m.Get("z"); !ok || v != 10 {
+		t.Errorf("Get(z) = %d, %v, want 10", v, ok)
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Error("Get of a missing key succeeded")
+	}
+
+	if !m.Delete("a") || m.Delete("a") {
+		t.Error("Delete did not report whether the key was present")
+	}
+	m.Set("a", 4)
+	if got := m.Keys(); !slices.Equal(got, []string{"z", "m", "a"}) || m.Len() != 3 {
+		t.Errorf("Keys after delete and set = %q", got)
+	}
+
+	for k := range m.All() {
+		m.Delete(k)
+	}
+	if m.Len() != 0 || len(m.Keys()) != 0 {
+		t.Errorf("map not empty after deleting every key while iterating: %q", m.Keys())
+	}
+}
+
+func TestOrderedMapJSON(t *testing.T) {
+	var m util.OrderedMap[string, any]
+	m.Set("zeta", 1)
+	m.Set("alpha", []string{"x"})
+	m.Set("mid", map[string]int{"b": 2, "a": 1})
+
+	out, err := json.Marshal(&m)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"zeta":1,"alpha":["x"],"mid":{"a":1,"b":2}}`; string(out) != want {
+		t.Errorf("Marshal = %s, want %s", out, want)
+	}
+
+	var decoded util.OrderedMap[string, json.RawMessage]
+	if err := json.Unmarshal([]byte(`{"b": 1, "a": {"nested": true}, "c": null}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := decoded.Keys(); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Errorf("decoded keys = %q, want [b a c]", got)
+	}
+	if out, _ := json.Marshal(&decoded); string(out) != `{"b":1,"a":{"nested":true},"c":null}` {
+		t.Errorf("round trip = %s", out)
+	}
+
+	if err := json.Unmarshal([]byte(`[1]`), &decoded); err == nil {
+		t.Error("Unmarshal of an array succeeded")
+	}
+}
+
+func TestOrderedMapJSONKeys(t *testing.T) {
+	var ints util.OrderedMap[int, string]
+	if err := json.Unmarshal([]byte(`{"3":"c","1":"a"}`), &ints); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := ints.Keys(); !slices.Equal(got, []int{3, 1}) {
+		t.Errorf("int keys = %v", got)
+	}
+	if err := json.Unmarshal([]byte(`{"x":"bad"}`), &ints); err == nil {
+		t.Error("Unmarshal of a non-integer key succeeded")
+	}
+
+	var addrs util.OrderedMap[netip.Addr, int]
+	addrs.Set(netip.MustParseAddr("10.0.0.2"), 2)
+	addrs.Set(netip.MustParseAddr("10.0.0.1"), 1)
+	out, err := json.Marshal(&addrs)
+	if err != nil || string(out) != `{"10.0.0.2":2,"10.0.0.1":1}` {
+		t.Errorf("Marshal with TextMarshaler keys = %s, %v", out, err)
+	}
+}
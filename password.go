@@ -0,0 +1,199 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnsupportedPasswordHash is returned when a stored hash is neither an
+// Argon2id PHC string nor a bcrypt hash.
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash format")
+
+// Argon2id defaults following the second recommended option of RFC 9106.
+const (
+	defaultArgon2Memory      = 64 * 1024 // KiB
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 4
+	defaultArgon2SaltLength  = 16
+	defaultArgon2KeyLength   = 32
+
+	argon2idPrefix   = "$argon2id$"
+	argon2PHCFields  = 6 // "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	bcryptPrefixSize = 4
+)
+
+// PasswordParams controls the cost of Argon2id password hashes.
+type PasswordParams struct {
+	// Memory is the memory cost in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Parallelism is the number of lanes (threads) used.
+	Parallelism uint8
+	// SaltLength is the length of the random salt in bytes.
+	SaltLength uint32
+	// KeyLength is the length of the derived hash in bytes.
+	KeyLength uint32
+}
+
+// DefaultPasswordParams returns the recommended Argon2id parameters:
+// 64 MiB of memory, 3 iterations and 4 lanes with a 16 byte salt and 32 byte hash.
+func DefaultPasswordParams() PasswordParams {
+	return PasswordParams{
+		Memory:      defaultArgon2Memory,
+		Iterations:  defaultArgon2Iterations,
+		Parallelism: defaultArgon2Parallelism,
+		SaltLength:  defaultArgon2SaltLength,
+		KeyLength:   defaultArgon2KeyLength,
+	}
+}
+
+// HashPassword hashes password with Argon2id and returns it as a PHC string:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<base64 salt>$<base64 hash>
+//
+// A nil params uses DefaultPasswordParams. The encoded string carries every
+// parameter needed to verify it, so parameters can be raised over time and
+// existing hashes upgraded on login with NeedsRehash.
+func HashPassword(password string, params *PasswordParams) (string, error) {
+	p := DefaultPasswordParams()
+	if params != nil {
+		p = *params
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || p.SaltLength == 0 || p.KeyLength == 0 {
+		return "", errors.New("password params must all be greater than zero")
+	}
+	if err := checkFIPSApproved("Argon2id"); err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// VerifyPassword reports whether password matches the stored hash.
+//
+// Both Argon2id PHC strings from HashPassword and bcrypt hashes ($2a$, $2b$,
+// $2y$) are accepted, so users migrating from bcrypt can verify existing
+// hashes and re-hash with Argon2id once NeedsRehash reports true.
+// A non-nil error means the hash itself is malformed, not that the password is wrong.
+func VerifyPassword(hash, password string) (bool, error) {
+	if isBcryptHash(hash) {
+		if err := checkFIPSApproved("bcrypt"); err != nil {
+			return false, err
+		}
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		switch {
+		case err == nil:
+			return true, nil
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return false, nil
+		default:
+			return false, fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+	}
+
+	p, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false, err
+	}
+	if err = checkFIPSApproved("Argon2id"); err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return SecureCompare(candidate, key), nil
+}
+
+// NeedsRehash reports whether hash should be replaced by a fresh HashPassword
+// result: it is a bcrypt hash, it is malformed, or its Argon2id parameters
+// differ from params (DefaultPasswordParams when nil).
+func NeedsRehash(hash string, params *PasswordParams) bool {
+	want := DefaultPasswordParams()
+	if params != nil {
+		want = *params
+	}
+
+	if isBcryptHash(hash) {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return p != want
+}
+
+func isBcryptHash(hash string) bool {
+	if len(hash) < bcryptPrefixSize {
+		return false
+	}
+	switch hash[:bcryptPrefixSize] {
+	case "$2a$", "$2b$", "$2y$":
+		return true
+	default:
+		return false
+	}
+}
+
+func decodeArgon2idHash(hash string) (PasswordParams, []byte, []byte, error) {
+	var p PasswordParams
+
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return p, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != argon2PHCFields {
+		return p, nil, nil, errors.New("invalid argon2id hash: wrong number of fields")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash version: %w", err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash parameters: %w", err)
+	}
+	if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, errors.New("invalid argon2id hash parameters: zero cost")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if len(key) == 0 {
+		return p, nil, nil, errors.New("invalid argon2id hash: empty key")
+	}
+
+	//nolint:gosec // lengths are bounded by the encoded hash size
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
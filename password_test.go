@@ -0,0 +1,107 @@
+package util_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastPasswordParams keeps the tests quick; production code should use the defaults.
+func fastPasswordParams() *util.PasswordParams {
+	return &util.PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestHashPasswordPHCFormat(t *testing.T) {
+	skipInFIPSMode(t, "Argon2id")
+	hash, err := util.HashPassword("correct horse battery staple", fastPasswordParams())
+	if err != nil {
+		t.Fatalf("HashPassword() failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("HashPassword() = %q, want argon2id PHC string", hash)
+	}
+
+	other, err := util.HashPassword("correct horse battery staple", fastPasswordParams())
+	if err != nil {
+		t.Fatalf("HashPassword() failed: %v", err)
+	}
+	if hash == other {
+		t.Error("HashPassword() should use a random salt")
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	skipInFIPSMode(t, "Argon2id")
+	argonHash, err := util.HashPassword("s3cret", fastPasswordParams())
+	if err != nil {
+		t.Fatalf("HashPassword() failed: %v", err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+		wantErr  bool
+	}{
+		{"argon2id match", argonHash, "s3cret", true, false},
+		{"argon2id mismatch", argonHash, "wrong", false, false},
+		{"bcrypt match", string(bcryptHash), "s3cret", true, false},
+		{"bcrypt mismatch", string(bcryptHash), "wrong", false, false},
+		{"unknown format", "plaintext", "s3cret", false, true},
+		{"truncated argon2id", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", "s3cret", false, true},
+		{"zero cost argon2id", "$argon2id$v=19$m=0,t=1,p=1$c2FsdA$aGFzaA", "s3cret", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, verifyErr := util.VerifyPassword(tt.hash, tt.password)
+			if (verifyErr != nil) != tt.wantErr {
+				t.Fatalf("VerifyPassword() error = %v, wantErr %v", verifyErr, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err = util.VerifyPassword("plaintext", "x"); !errors.Is(err, util.ErrUnsupportedPasswordHash) {
+		t.Errorf("VerifyPassword() error = %v, want ErrUnsupportedPasswordHash", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	skipInFIPSMode(t, "Argon2id")
+	params := fastPasswordParams()
+	hash, err := util.HashPassword("s3cret", params)
+	if err != nil {
+		t.Fatalf("HashPassword() failed: %v", err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword() failed: %v", err)
+	}
+
+	stronger := *params
+	stronger.Iterations = 2
+
+	if util.NeedsRehash(hash, params) {
+		t.Error("NeedsRehash() should be false for matching parameters")
+	}
+	if !util.NeedsRehash(hash, &stronger) {
+		t.Error("NeedsRehash() should be true when parameters were raised")
+	}
+	if !util.NeedsRehash(string(bcryptHash), params) {
+		t.Error("NeedsRehash() should be true for bcrypt hashes")
+	}
+	if !util.NeedsRehash("garbage", nil) {
+		t.Error("NeedsRehash() should be true for malformed hashes")
+	}
+}
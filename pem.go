@@ -0,0 +1,249 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// PEM block types.
+const (
+	pemTypePrivateKey    = "PRIVATE KEY"
+	pemTypeRSAPrivateKey = "RSA PRIVATE KEY"
+	pemTypeECPrivateKey  = "EC PRIVATE KEY"
+	pemTypePublicKey     = "PUBLIC KEY"
+	pemTypeCertificate   = "CERTIFICATE"
+)
+
+const (
+	defaultSelfSignedValidity = 365 * 24 * time.Hour
+	serialNumberBits          = 128
+	// certBackdate covers clock skew between the generating host and its clients.
+	certBackdate = time.Hour
+)
+
+// GenerateRSAKey generates an RSA private key of 2048, 3072 or 4096 bits.
+func GenerateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	switch bits {
+	case 2048, 3072, 4096: //nolint:mnd // the permitted RSA sizes are self-describing
+	default:
+		return nil, fmt.Errorf("unsupported RSA key size %d: use 2048, 3072 or 4096", bits)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+	}
+	return key, nil
+}
+
+// GenerateECDSAKey generates a P-256 ECDSA private key.
+func GenerateECDSAKey() (*ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ECDSA key: %w", err)
+	}
+	return key, nil
+}
+
+// MarshalPrivateKeyPEM encodes an RSA, ECDSA or Ed25519 private key as a
+// PKCS#8 "PRIVATE KEY" PEM block.
+func MarshalPrivateKeyPEM(key crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM decodes the first private key PEM block in data.
+//
+// PKCS#8 ("PRIVATE KEY") is preferred; the legacy PKCS#1 ("RSA PRIVATE KEY")
+// and SEC 1 ("EC PRIVATE KEY") forms written by older tooling are also
+// accepted. Encrypted PEM blocks are not supported.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, err := decodePEMBlock(data, pemTypePrivateKey, pemTypeRSAPrivateKey, pemTypeECPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var key any
+	switch block.Type {
+	case pemTypeRSAPrivateKey:
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case pemTypeECPrivateKey:
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// MarshalPublicKeyPEM encodes a public key as a SubjectPublicKeyInfo
+// "PUBLIC KEY" PEM block.
+func MarshalPublicKeyPEM(key crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePublicKey, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM decodes the first SubjectPublicKeyInfo "PUBLIC KEY" PEM
+// block in data, returning *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, err := decodePEMBlock(data, pemTypePublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return key, nil
+}
+
+// selfSignedCertOptions contains configuration for GenerateSelfSignedCert.
+type selfSignedCertOptions struct {
+	// validity is how long the certificate is valid for
+	validity time.Duration
+
+	// key signs the certificate; a P-256 key is generated when nil
+	key crypto.Signer
+}
+
+// SelfSignedCertOption is a function that configures GenerateSelfSignedCert.
+type SelfSignedCertOption func(*selfSignedCertOptions)
+
+// WithCertValidity sets how long the certificate is valid for. The default is one year.
+func WithCertValidity(validity time.Duration) SelfSignedCertOption {
+	return func(o *selfSignedCertOptions) {
+		o.validity = validity
+	}
+}
+
+// WithCertKey signs the certificate with key instead of a freshly generated P-256 key.
+func WithCertKey(key crypto.Signer) SelfSignedCertOption {
+	return func(o *selfSignedCertOptions) {
+		o.key = key
+	}
+}
+
+// GenerateSelfSignedCert creates a self-signed server certificate for hosts
+// and returns the certificate and its private key as PEM.
+//
+// Hosts may be DNS names or IP addresses; the first becomes the subject
+// common name. The certificate is meant for development and tests only:
+// clients must be told to trust it explicitly.
+//
+// Example:
+//
+//	certPEM, keyPEM, err := GenerateSelfSignedCert([]string{"localhost", "127.0.0.1"})
+//	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+func GenerateSelfSignedCert(hosts []string, opts ...SelfSignedCertOption) ([]byte, []byte, error) {
+	options := &selfSignedCertOptions{validity: defaultSelfSignedValidity}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	if len(hosts) == 0 {
+		return nil, nil, errors.New("at least one host is required")
+	}
+
+	key := options.key
+	if key == nil {
+		generated, err := GenerateECDSAKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		key = generated
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[0]},
+		NotBefore:             now.Add(-certBackdate),
+		NotAfter:              now.Add(options.validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if _, isRSA := key.(*rsa.PrivateKey); isRSA {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyPEM, err := MarshalPrivateKeyPEM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: der}), keyPEM, nil
+}
+
+// SelfSignedTLSConfig returns a server TLS configuration using a fresh
+// self-signed certificate for hosts, for local development servers.
+func SelfSignedTLSConfig(hosts ...string) (*tls.Config, error) {
+	certPEM, keyPEM, err := GenerateSelfSignedCert(hosts)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// decodePEMBlock returns the first PEM block in data with one of the allowed types.
+func decodePEMBlock(data []byte, types ...string) (*pem.Block, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no %s PEM block found", types[0])
+		}
+		for _, t := range types {
+			if block.Type == t {
+				return block, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package util_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestPrivateKeyPEMRoundTrip(t *testing.T) {
+	rsaKey, err := util.GenerateRSAKey(2048)
+	if err != nil {
+		t.Fatalf("GenerateRSAKey() failed: %v", err)
+	}
+	ecKey, err := util.GenerateECDSAKey()
+	if err != nil {
+		t.Fatalf("GenerateECDSAKey() failed: %v", err)
+	}
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"rsa", rsaKey},
+		{"ecdsa", ecKey},
+		{"ed25519", edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privPEM, marshalErr := util.MarshalPrivateKeyPEM(tt.key)
+			if marshalErr != nil {
+				t.Fatalf("MarshalPrivateKeyPEM() failed: %v", marshalErr)
+			}
+			parsed, parseErr := util.ParsePrivateKeyPEM(privPEM)
+			if parseErr != nil {
+				t.Fatalf("ParsePrivateKeyPEM() failed: %v", parseErr)
+			}
+			if !parsed.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.key.Public()) {
+				t.Error("ParsePrivateKeyPEM() returned a different key")
+			}
+
+			pubPEM, marshalErr := util.MarshalPublicKeyPEM(tt.key.Public())
+			if marshalErr != nil {
+				t.Fatalf("MarshalPublicKeyPEM() failed: %v", marshalErr)
+			}
+			pub, parseErr := util.ParsePublicKeyPEM(pubPEM)
+			if parseErr != nil {
+				t.Fatalf("ParsePublicKeyPEM() failed: %v", parseErr)
+			}
+			if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(tt.key.Public()) {
+				t.Error("ParsePublicKeyPEM() returned a different key")
+			}
+		})
+	}
+}
+
+func TestParsePrivateKeyPEMLegacyFormats(t *testing.T) {
+	rsaKey, _ := util.GenerateRSAKey(2048)
+	ecKey, _ := util.GenerateECDSAKey()
+	ecDER, _ := x509.MarshalECPrivateKey(ecKey)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	sec1 := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})
+
+	if key, err := util.ParsePrivateKeyPEM(pkcs1); err != nil || !key.(*rsa.PrivateKey).Equal(rsaKey) {
+		t.Errorf("ParsePrivateKeyPEM(PKCS#1) = %v, %v", key, err)
+	}
+	if key, err := util.ParsePrivateKeyPEM(sec1); err != nil || !key.(*ecdsa.PrivateKey).Equal(ecKey) {
+		t.Errorf("ParsePrivateKeyPEM(SEC 1) = %v, %v", key, err)
+	}
+	if _, err := util.ParsePrivateKeyPEM([]byte("not pem")); err == nil {
+		t.Error("ParsePrivateKeyPEM() should reject non-PEM input")
+	}
+}
+
+func TestGenerateRSAKeyRejectsWeakSizes(t *testing.T) {
+	if _, err := util.GenerateRSAKey(1024); err == nil {
+		t.Error("GenerateRSAKey(1024) should fail")
+	}
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	certPEM, keyPEM, err := util.GenerateSelfSignedCert([]string{"localhost", "127.0.0.1"},
+		util.WithCertValidity(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateSelfSignedCert() failed: %v", err)
+	}
+	if _, err = tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Fatalf("tls.X509KeyPair() failed: %v", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cert.VerifyHostname("localhost"); err != nil {
+		t.Errorf("VerifyHostname(localhost) failed: %v", err)
+	}
+	if len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("IPAddresses = %v, want [127.0.0.1]", cert.IPAddresses)
+	}
+	if cert.NotAfter.After(time.Now().Add(2 * time.Hour)) {
+		t.Errorf("NotAfter = %v, want about one hour from now", cert.NotAfter)
+	}
+
+	if _, _, err = util.GenerateSelfSignedCert(nil); err == nil {
+		t.Error("GenerateSelfSignedCert() should require a host")
+	}
+}
+
+func TestSelfSignedTLSConfig(t *testing.T) {
+	config, err := util.SelfSignedTLSConfig("127.0.0.1")
+	if err != nil {
+		t.Fatalf("SelfSignedTLSConfig() failed: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(config.Certificates[0].Leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET over self-signed TLS failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,303 @@
+package util
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+)
+
+const defaultPipelineBuffer = 16
+
+// pipelineOptions contains configuration for a Pipeline.
+type pipelineOptions struct {
+	// buffer is the capacity of the channels between stages
+	buffer int
+}
+
+// PipelineOption is a function that configures a Pipeline.
+type PipelineOption func(*pipelineOptions)
+
+// WithPipelineBuffer sets the capacity of the channels between stages,
+// which bounds how far a stage can run ahead of the next. The default is 16.
+func WithPipelineBuffer(size int) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.buffer = size
+	}
+}
+
+// stageOptions contains configuration for a pipeline stage.
+type stageOptions struct {
+	// workers is the number of goroutines running the stage
+	workers int
+
+	// onError decides whether an item's error stops the pipeline
+	onError func(ctx context.Context, err error) error
+}
+
+// StageOption is a function that configures a pipeline stage.
+type StageOption func(*stageOptions)
+
+// WithStageWorkers runs the stage on n goroutines, for stages that wait on
+// I/O. Items then leave the stage in the order they finish.
+func WithStageWorkers(n int) StageOption {
+	return func(o *stageOptions) {
+		o.workers = n
+	}
+}
+
+// WithStageErrorHandler sets what happens when the stage fails for an item.
+// handler returns nil to drop the item and go on, for example after logging
+// it, or an error to stop the pipeline with. By default the pipeline stops
+// with the stage's error.
+//
+// Example:
+//
+//	WithStageErrorHandler(func(ctx context.Context, err error) error {
+//	    Log(ctx).WithError(err).Warn("skipping malformed record")
+//	    return nil
+//	})
+func WithStageErrorHandler(handler func(ctx context.Context, err error) error) StageOption {
+	return func(o *stageOptions) {
+		o.onError = handler
+	}
+}
+
+// pipelineRun is the state shared by the stages of a pipeline.
+type pipelineRun struct {
+	ctx     context.Context //nolint:containedctx // shared by the stage goroutines
+	cancel  context.CancelCauseFunc
+	wg      sync.WaitGroup
+	options pipelineOptions
+}
+
+// Pipeline is a chain of processing stages connected by bounded channels,
+// for ETL-style workloads: items flow from a source through PipeMap,
+// PipeFilter and PipeBatch stages, each running on its own goroutines, to a
+// sink such as ForEach or Collect that runs the pipeline.
+//
+// The first error stops the whole pipeline: every stage's context is
+// cancelled and the sink returns the error. Stages can instead drop failing
+// items with WithStageErrorHandler. A Pipeline is consumed by the one stage
+// or sink it is passed to.
+//
+// Example:
+//
+//	p := NewPipeline(ctx, slices.Values(files))
+//	records := PipeMap(p, parseFile, WithStageWorkers(4))
+//	valid := PipeFilter(records, func(_ context.Context, r Record) (bool, error) { return r.Valid(), nil })
+//	batches := PipeBatch(valid, 500, time.Second)
+//	err := batches.ForEach(func(ctx context.Context, batch []Record) error {
+//	    return store.InsertMany(ctx, batch)
+//	})
+type Pipeline[T any] struct {
+	run *pipelineRun
+	out <-chan T
+}
+
+// NewPipeline returns a Pipeline whose items are produced by source. The
+// pipeline stops when ctx is done. Stopping waits for source to yield its
+// next item or return, so a source blocking on input, such as a channel,
+// must also give up when ctx is done.
+func NewPipeline[T any](ctx context.Context, source iter.Seq[T], opts ...PipelineOption) *Pipeline[T] {
+	options := pipelineOptions{buffer: defaultPipelineBuffer}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &pipelineRun{ctx: ctx, cancel: cancel, options: options}
+
+	out := make(chan T, max(options.buffer, 0))
+	run.wg.Go(func() {
+		defer close(out)
+		for item := range source {
+			if !pipelineSend(ctx, out, item) {
+				return
+			}
+		}
+	})
+	return &Pipeline[T]{run: run, out: out}
+}
+
+// PipeMap returns a Pipeline of fn applied to every item of p.
+func PipeMap[T, U any](p *Pipeline[T], fn func(ctx context.Context, item T) (U, error), opts ...StageOption) *Pipeline[U] {
+	return pipeStage(p, opts, func(ctx context.Context, item T, out chan<- U) (bool, error) {
+		mapped, err := fn(ctx, item)
+		if err != nil {
+			return true, err
+		}
+		return pipelineSend(ctx, out, mapped), nil
+	})
+}
+
+// PipeFilter returns a Pipeline of the items of p for which keep is true.
+func PipeFilter[T any](p *Pipeline[T], keep func(ctx context.Context, item T) (bool, error), opts ...StageOption) *Pipeline[T] {
+	return pipeStage(p, opts, func(ctx context.Context, item T, out chan<- T) (bool, error) {
+		ok, err := keep(ctx, item)
+		if err != nil || !ok {
+			return true, err
+		}
+		return pipelineSend(ctx, out, item), nil
+	})
+}
+
+// PipeBatch returns a Pipeline grouping the items of p into slices of size
+// items, for stages such as bulk inserts that work best on many items at
+// once. When maxWait is positive, a partial batch is also passed on once
+// its first item has waited that long, so a slow source does not hold items
+// back. The last batch may be smaller.
+func PipeBatch[T any](p *Pipeline[T], size int, maxWait time.Duration) *Pipeline[[]T] {
+	run := p.run
+	ctx := run.ctx
+	out := make(chan []T, max(run.options.buffer, 0))
+	size = max(size, 1)
+
+	run.wg.Go(func() {
+		defer close(out)
+		var (
+			batch   []T
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			full := batch
+			batch = nil
+			return pipelineSend(ctx, out, full)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				if !flush() {
+					return
+				}
+			case item, ok := <-p.out:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, item)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+				if len(batch) >= size && !flush() {
+					return
+				}
+			}
+		}
+	})
+	return &Pipeline[[]T]{run: run, out: out}
+}
+
+// ForEach runs the pipeline, calling fn for every item that reaches the
+// end, and returns the first error of a stage or fn, or ctx's error when
+// the pipeline's context ended first. It returns once every stage has
+// stopped.
+func (p *Pipeline[T]) ForEach(fn func(ctx context.Context, item T) error) error {
+	run := p.run
+	for item := range p.out {
+		if run.ctx.Err() != nil {
+			break
+		}
+		if err := fn(run.ctx, item); err != nil {
+			run.cancel(err)
+			break
+		}
+	}
+	// Once cancelled, stages blocked sending give up, so this returns.
+	run.wg.Wait()
+	err := context.Cause(run.ctx)
+	run.cancel(nil)
+	return err
+}
+
+// Collect runs the pipeline like ForEach and returns the items that reach
+// the end.
+func (p *Pipeline[T]) Collect() ([]T, error) {
+	var items []T
+	err := p.ForEach(func(_ context.Context, item T) error {
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// pipeStage starts the goroutines of a stage calling process for every item
+// of p. process returns false when the stage must stop, and the item's
+// error.
+func pipeStage[T, U any](
+	p *Pipeline[T],
+	opts []StageOption,
+	process func(ctx context.Context, item T, out chan<- U) (bool, error),
+) *Pipeline[U] {
+	options := stageOptions{workers: 1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	run := p.run
+	ctx := run.ctx
+	out := make(chan U, max(run.options.buffer, 0))
+
+	var workers sync.WaitGroup
+	for range max(options.workers, 1) {
+		workers.Go(func() {
+			for item := range p.out {
+				if ctx.Err() != nil {
+					return
+				}
+				ok, err := runStageItem(ctx, item, out, process)
+				if err != nil && options.onError != nil {
+					err = options.onError(ctx, err)
+				}
+				if err != nil {
+					run.cancel(err)
+					return
+				}
+				if !ok {
+					return
+				}
+			}
+		})
+	}
+	run.wg.Go(func() {
+		workers.Wait()
+		close(out)
+	})
+	return &Pipeline[U]{run: run, out: out}
+}
+
+// runStageItem calls process, converting a panic into an error wrapping
+// ErrTaskPanicked.
+func runStageItem[T, U any](
+	ctx context.Context,
+	item T,
+	out chan<- U,
+	process func(ctx context.Context, item T, out chan<- U) (bool, error),
+) (ok bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok, err = true, panicError(r)
+		}
+	}()
+	return process(ctx, item, out)
+}
+
+// pipelineSend sends item on out, reporting false when ctx ended first.
+func pipelineSend[T any](ctx context.Context, out chan<- T, item T) bool {
+	select {
+	case out <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestPipeline(t *testing.T) {
+	p := util.NewPipeline(t.Context(), slices.Values([]string{"1", "2", "3", "4", "5", "6", "7"}))
+	numbers := util.PipeMap(p, func(_ context.Context, s string) (int, error) { return strconv.Atoi(s) })
+	odd := util.PipeFilter(numbers, func(_ context.Context, n int) (bool, error) { return n%2 == 1, nil })
+	batches := util.PipeBatch(odd, 3, 0)
+
+	got, err := batches.Collect()
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	want := [][]int{{1, 3, 5}, {7}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Collect = %v, want %v", got, want)
+	}
+}
+
+func TestPipelineParallelStage(t *testing.T) {
+	var running, peak atomic.Int32
+	p := util.NewPipeline(t.Context(), slices.Values(make([]int, 20)))
+	squared := util.PipeMap(p, func(context.Context, int) (int, error) {
+		n := running.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return 1, nil
+	}, util.WithStageWorkers(4))
+
+	got, err := squared.Collect()
+	if err != nil || len(got) != 20 {
+		t.Fatalf("Collect = %d items, %v", len(got), err)
+	}
+	if p := peak.Load(); p < 2 || p > 4 {
+		t.Fatalf("peak concurrency = %d, want 2 to 4", p)
+	}
+}
+
+func TestPipelineStopsOnError(t *testing.T) {
+	errBad := errors.New("bad item")
+	var produced atomic.Int32
+	source := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			produced.Add(1)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	p := util.NewPipeline(t.Context(), source, util.WithPipelineBuffer(1))
+	mapped := util.PipeMap(p, func(_ context.Context, n int) (int, error) {
+		if n == 5 {
+			return 0, errBad
+		}
+		return n, nil
+	})
+	err := mapped.ForEach(func(context.Context, int) error { return nil })
+	if !errors.Is(err, errBad) {
+		t.Fatalf("ForEach = %v, want %v", err, errBad)
+	}
+	if n := produced.Load(); n > 20 {
+		t.Fatalf("source produced %d items after the error, want it stopped", n)
+	}
+}
+
+func TestPipelineErrorHandlerSkips(t *testing.T) {
+	var skipped atomic.Int32
+	p := util.NewPipeline(t.Context(), slices.Values([]string{"1", "x", "3", "y"}))
+	numbers := util.PipeMap(p, func(_ context.Context, s string) (int, error) {
+		if s == "y" {
+			panic("unparseable")
+		}
+		return strconv.Atoi(s)
+	}, util.WithStageErrorHandler(func(context.Context, error) error {
+		skipped.Add(1)
+		return nil
+	}))
+
+	got, err := numbers.Collect()
+	if err != nil || fmt.Sprint(got) != "[1 3]" || skipped.Load() != 2 {
+		t.Fatalf("Collect = %v, %v with %d skipped", got, err, skipped.Load())
+	}
+}
+
+func TestPipelineSinkErrorAndCancel(t *testing.T) {
+	errSink := errors.New("sink failed")
+	p := util.NewPipeline(t.Context(), slices.Values([]int{1, 2, 3}))
+	if err := p.ForEach(func(context.Context, int) error { return errSink }); !errors.Is(err, errSink) {
+		t.Fatalf("ForEach = %v, want %v", err, errSink)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	endless := func(yield func(int) bool) {
+		for yield(0) {
+		}
+	}
+	if err := util.NewPipeline(ctx, endless).ForEach(func(context.Context, int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ForEach on a cancelled context = %v, want Canceled", err)
+	}
+}
+
+func TestPipeBatchMaxWait(t *testing.T) {
+	items := make(chan int)
+	source := func(yield func(int) bool) {
+		for item := range items {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+	batches := util.PipeBatch(util.NewPipeline(t.Context(), source), 10, 10*time.Millisecond)
+
+	go func() {
+		items <- 1
+		items <- 2
+		time.Sleep(50 * time.Millisecond)
+		items <- 3
+		close(items)
+	}()
+	got, err := batches.Collect()
+	if err != nil || fmt.Sprint(got) != "[[1 2] [3]]" {
+		t.Fatalf("Collect = %v, %v; want [[1 2] [3]]", got, err)
+	}
+}
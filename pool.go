@@ -0,0 +1,168 @@
+package util
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// PoolStats counts the use of a BufferPool, BytesPool or Pool, to check that
+// pooling pays off: a high New to Gets ratio means buffers are rarely
+// reused, and a high Discarded count that the maximum capacity is too low.
+type PoolStats struct {
+	// Gets counts buffers taken from the pool.
+	Gets uint64 `json:"gets"`
+
+	// News counts buffers allocated because the pool had none.
+	News uint64 `json:"news"`
+
+	// Puts counts buffers returned to the pool.
+	Puts uint64 `json:"puts"`
+
+	// Discarded counts returned buffers dropped for being too large, or too
+	// small for any size class.
+	Discarded uint64 `json:"discarded"`
+}
+
+type poolCounters struct {
+	gets, news, puts, discarded atomic.Uint64
+}
+
+func (c *poolCounters) stats() PoolStats {
+	return PoolStats{
+		Gets:      c.gets.Load(),
+		News:      c.news.Load(),
+		Puts:      c.puts.Load(),
+		Discarded: c.discarded.Load(),
+	}
+}
+
+// BufferPool is a pool of bytes.Buffers for building encoded output, such
+// as JSON responses, without allocating a buffer each time. Buffers grown
+// beyond the pool's maximum capacity are dropped rather than returned, so
+// one large payload does not keep its memory pinned. A BufferPool is safe
+// for concurrent use.
+//
+// Example:
+//
+//	var buffers = NewBufferPool(64 << 10)
+//
+//	buf := buffers.Get()
+//	defer buffers.Put(buf)
+//	if err := json.NewEncoder(buf).Encode(v); err != nil {
+//	    return err
+//	}
+//	_, err := w.Write(buf.Bytes())
+type BufferPool struct {
+	pool        sync.Pool
+	maxCapacity int
+	counters    poolCounters
+}
+
+// NewBufferPool returns a BufferPool keeping buffers with a capacity of at
+// most maxCapacity bytes.
+func NewBufferPool(maxCapacity int) *BufferPool {
+	p := &BufferPool{maxCapacity: maxCapacity}
+	p.pool.New = func() any {
+		p.counters.news.Add(1)
+		return new(bytes.Buffer)
+	}
+	return p
+}
+
+// Get returns an empty buffer.
+func (p *BufferPool) Get() *bytes.Buffer {
+	p.counters.gets.Add(1)
+	buf, _ := p.pool.Get().(*bytes.Buffer)
+	return buf
+}
+
+// Put resets buf and returns it to the pool, or drops it when its capacity
+// exceeds the maximum. buf must not be used afterwards.
+func (p *BufferPool) Put(buf *bytes.Buffer) {
+	if buf.Cap() > p.maxCapacity {
+		p.counters.discarded.Add(1)
+		return
+	}
+	p.counters.puts.Add(1)
+	buf.Reset()
+	p.pool.Put(buf)
+}
+
+// Stats returns the pool's counters.
+func (p *BufferPool) Stats() PoolStats {
+	return p.counters.stats()
+}
+
+// BytesPool is a pool of byte slices in fixed size classes, for scratch
+// space such as read buffers whose size varies by request. A slice is
+// served from the smallest class that fits the requested length, so slices
+// are reused across similar sizes without the waste of one class for all.
+// Lengths above the largest class are allocated and not pooled. A
+// BytesPool is safe for concurrent use.
+//
+// Example:
+//
+//	var scratch = NewBytesPool(4<<10, 64<<10, 1<<20)
+//
+//	buf := scratch.Get(size)
+//	defer scratch.Put(buf)
+type BytesPool struct {
+	classes  []int
+	pools    []sync.Pool
+	counters poolCounters
+}
+
+// NewBytesPool returns a BytesPool with the given size classes in bytes.
+// Their order does not matter; non-positive sizes are ignored.
+func NewBytesPool(sizeClasses ...int) *BytesPool {
+	classes := slices.DeleteFunc(slices.Clone(sizeClasses), func(size int) bool { return size <= 0 })
+	slices.Sort(classes)
+	classes = slices.Compact(classes)
+
+	p := &BytesPool{classes: classes, pools: make([]sync.Pool, len(classes))}
+	for i, size := range classes {
+		p.pools[i].New = func() any {
+			p.counters.news.Add(1)
+			b := make([]byte, size)
+			return &b
+		}
+	}
+	return p
+}
+
+// Get returns a slice of length n with the capacity of its size class. Its
+// content is not zeroed.
+func (p *BytesPool) Get(n int) []byte {
+	p.counters.gets.Add(1)
+	i, _ := slices.BinarySearch(p.classes, n)
+	if i == len(p.classes) {
+		p.counters.news.Add(1)
+		return make([]byte, n)
+	}
+	b, _ := p.pools[i].Get().(*[]byte)
+	return (*b)[:n]
+}
+
+// Put returns b to the pool of the largest size class its capacity covers.
+// Slices larger than the largest class or smaller than the smallest are
+// dropped. b must not be used afterwards.
+func (p *BytesPool) Put(b []byte) {
+	i, found := slices.BinarySearch(p.classes, cap(b))
+	if !found {
+		i--
+	}
+	if i < 0 || cap(b) > p.classes[len(p.classes)-1] {
+		p.counters.discarded.Add(1)
+		return
+	}
+	p.counters.puts.Add(1)
+	b = b[:p.classes[i]]
+	p.pools[i].Put(&b)
+}
+
+// Stats returns the pool's counters.
+func (p *BytesPool) Stats() PoolStats {
+	return p.counters.stats()
+}
@@ -0,0 +1,84 @@
+package util_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := util.NewBufferPool(1024)
+
+	buf := pool.Get()
+	buf.WriteString("hello")
+	pool.Put(buf)
+	if again := pool.Get(); again.Len() != 0 {
+		t.Fatalf("Get returned a buffer holding %q, want an empty one", again.String())
+	}
+
+	large := bytes.NewBuffer(make([]byte, 0, 4096))
+	pool.Put(large)
+
+	stats := pool.Stats()
+	if stats.Gets != 2 || stats.Puts != 1 || stats.Discarded != 1 || stats.News < 1 {
+		t.Fatalf("Stats() = %+v, want 2 gets, 1 put and 1 discarded", stats)
+	}
+}
+
+func TestBytesPool(t *testing.T) {
+	pool := util.NewBytesPool(4096, 512, 0, 512)
+
+	tests := []struct {
+		n       int
+		wantCap int
+	}{
+		{0, 512},
+		{100, 512},
+		{512, 512},
+		{513, 4096},
+		{5000, 5000},
+	}
+	for _, tt := range tests {
+		b := pool.Get(tt.n)
+		if len(b) != tt.n || cap(b) != tt.wantCap {
+			t.Errorf("Get(%d) has len %d cap %d, want len %d cap %d", tt.n, len(b), cap(b), tt.n, tt.wantCap)
+		}
+		pool.Put(b)
+	}
+
+	pool.Put(make([]byte, 1000)) // kept in the 512 class
+	if b := pool.Get(512); cap(b) < 512 {
+		t.Fatalf("Get(512) after Put of 1000 bytes has cap %d", cap(b))
+	}
+	pool.Put(make([]byte, 100))
+
+	stats := pool.Stats()
+	if stats.Gets != 6 || stats.Puts != 5 || stats.Discarded != 2 {
+		t.Fatalf("Stats() = %+v, want 6 gets, 5 puts and 2 discarded", stats)
+	}
+}
+
+func TestBytesPoolConcurrent(t *testing.T) {
+	pool := util.NewBytesPool(64, 1024)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			for range 200 {
+				b := pool.Get(i * 50)
+				for j := range b {
+					b[j] = byte(i)
+				}
+				for j := range b {
+					if b[j] != byte(i) {
+						t.Errorf("buffer shared between goroutines")
+						return
+					}
+				}
+				pool.Put(b)
+			}
+		})
+	}
+	wg.Wait()
+}
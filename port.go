@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	waitForPortInitialDelay = 10 * time.Millisecond
+	waitForPortMaxDelay     = 500 * time.Millisecond
+	waitForPortJitter       = 0.2
+)
+
+// GetFreePort returns a TCP port on localhost that is free at the time of
+// the call, for starting test servers. Another process may take the port
+// before it is used, so prefer listening on ":0" where the API allows it.
+//
+// Example:
+//
+//	port, err := GetFreePort()
+//	cfg.HTTPAddr = fmt.Sprintf("127.0.0.1:%d", port)
+func GetFreePort() (int, error) {
+	ports, err := GetFreePorts(1)
+	if err != nil {
+		return 0, err
+	}
+	return ports[0], nil
+}
+
+// GetFreePorts returns n distinct free TCP ports on localhost. All ports are
+// held open until every one is allocated, so they never repeat.
+func GetFreePorts(n int) ([]int, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("port count cannot be negative: %d", n)
+	}
+
+	ports := make([]int, 0, n)
+	listeners := make([]net.Listener, 0, n)
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+
+	for range n {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate a free port: %w", err)
+		}
+		listeners = append(listeners, l)
+		addr, ok := l.Addr().(*net.TCPAddr)
+		if !ok {
+			return nil, fmt.Errorf("unexpected listener address %s", l.Addr())
+		}
+		ports = append(ports, addr.Port)
+	}
+	return ports, nil
+}
+
+// WaitForPort polls until a TCP connection to host:port succeeds, backing off
+// from 10ms to 500ms between attempts, for waiting on a service started by
+// a test or an orchestrator. It gives up after timeout, or when ctx is done,
+// returning an error that wraps the last dial error.
+//
+// Example:
+//
+//	if err := WaitForPort(ctx, "localhost", 5432, 30*time.Second); err != nil {
+//	    t.Fatalf("database did not start: %v", err)
+//	}
+func WaitForPort(ctx context.Context, host string, port int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	var dialer net.Dialer
+	delay := waitForPortInitialDelay
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			return conn.Close()
+		}
+
+		timer := time.NewTimer(Jitter(delay, waitForPortJitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s not reachable after %s: %w", address, timeout, errors.Join(ctx.Err(), err))
+		case <-timer.C:
+		}
+		delay = min(delay*2, waitForPortMaxDelay) //nolint:mnd // exponential backoff
+	}
+}
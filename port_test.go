@@ -0,0 +1,74 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestGetFreePorts(t *testing.T) {
+	ports, err := util.GetFreePorts(5)
+	if err != nil {
+		t.Fatalf("GetFreePorts() error = %v", err)
+	}
+	seen := map[int]bool{}
+	for _, port := range ports {
+		if port <= 0 || seen[port] {
+			t.Fatalf("GetFreePorts() = %v, want distinct positive ports", ports)
+		}
+		seen[port] = true
+	}
+
+	port, err := util.GetFreePort()
+	if err != nil {
+		t.Fatalf("GetFreePort() error = %v", err)
+	}
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("port %d from GetFreePort() is not free: %v", port, err)
+	}
+	l.Close()
+}
+
+func TestWaitForPort(t *testing.T) {
+	port, err := util.GetFreePort()
+	if err != nil {
+		t.Fatalf("GetFreePort() error = %v", err)
+	}
+
+	listener := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l, _ := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		listener <- l
+	}()
+
+	err = util.WaitForPort(t.Context(), "127.0.0.1", port, 5*time.Second)
+	if l := <-listener; l != nil {
+		l.Close()
+	}
+	if err != nil {
+		t.Errorf("WaitForPort() error = %v", err)
+	}
+}
+
+func TestWaitForPortTimeout(t *testing.T) {
+	port, err := util.GetFreePort()
+	if err != nil {
+		t.Fatalf("GetFreePort() error = %v", err)
+	}
+
+	start := time.Now()
+	err = util.WaitForPort(t.Context(), "127.0.0.1", port, 100*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForPort() error = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("WaitForPort() took %v, want about 100ms", elapsed)
+	}
+}
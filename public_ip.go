@@ -0,0 +1,357 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPublicIPTimeout  = 5 * time.Second
+	defaultPublicIPCacheTTL = 5 * time.Minute
+	defaultPublicIPQuorum   = 2
+	maxPublicIPResponse     = 256
+
+	stunHeaderSize        = 20
+	stunMagicCookie       = 0x2112A442
+	stunBindingRequest    = 0x0001
+	stunBindingResponse   = 0x0101
+	stunAttrMappedAddress = 0x0001
+	stunAttrXORMapped     = 0x0020
+	stunFamilyIPv4        = 0x01
+	stunFamilyIPv6        = 0x02
+	stunMaxMessage        = 1500
+	stunAttrHeaderSize    = 4
+	stunAddrHeaderSize    = 4
+	stunAttrAlignment     = 4
+)
+
+// ErrNoPublicIPConsensus is returned by GetPublicIP when the discovery
+// sources do not agree on an address.
+var ErrNoPublicIPConsensus = errors.New("public IP sources do not agree")
+
+// defaultPublicIPEndpoints returns the HTTPS services, run by different
+// operators, that GetPublicIP asks by default.
+func defaultPublicIPEndpoints() []string {
+	return []string{
+		"https://api.ipify.org",
+		"https://checkip.amazonaws.com",
+		"https://icanhazip.com",
+		"https://ifconfig.me/ip",
+	}
+}
+
+// publicIPOptions contains configuration for GetPublicIP.
+type publicIPOptions struct {
+	// endpoints are HTTPS URLs returning the caller's address as plain text
+	endpoints []string
+
+	// stunServers are host:port addresses of STUN servers
+	stunServers []string
+
+	// timeout bounds the whole discovery
+	timeout time.Duration
+
+	// cacheTTL is how long a discovered address is reused
+	cacheTTL time.Duration
+
+	// quorum is the number of sources that must agree
+	quorum int
+
+	// client performs the HTTPS requests
+	client *http.Client
+}
+
+// PublicIPOption is a function that configures GetPublicIP.
+type PublicIPOption func(*publicIPOptions)
+
+// WithPublicIPEndpoints replaces the default HTTPS endpoints (ipify, Amazon
+// checkip, icanhazip and ifconfig.me) with urls returning the caller's
+// address as plain text. Pass none to use only STUN.
+func WithPublicIPEndpoints(urls ...string) PublicIPOption {
+	return func(o *publicIPOptions) {
+		o.endpoints = urls
+	}
+}
+
+// WithPublicIPSTUN adds STUN servers (RFC 5389), given as host:port, such
+// as "stun.l.google.com:19302". STUN reports the address as seen by a UDP
+// peer, which can differ from the HTTPS one behind some NATs.
+func WithPublicIPSTUN(servers ...string) PublicIPOption {
+	return func(o *publicIPOptions) {
+		o.stunServers = servers
+	}
+}
+
+// WithPublicIPTimeout bounds the whole discovery. The default is 5 seconds.
+func WithPublicIPTimeout(timeout time.Duration) PublicIPOption {
+	return func(o *publicIPOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithPublicIPCacheTTL sets how long a discovered address is reused by
+// later calls with the same sources. The default is 5 minutes; 0 disables
+// caching.
+func WithPublicIPCacheTTL(ttl time.Duration) PublicIPOption {
+	return func(o *publicIPOptions) {
+		o.cacheTTL = ttl
+	}
+}
+
+// WithPublicIPQuorum sets how many sources must report the same address.
+// The default is 2, or 1 when only one source is configured.
+func WithPublicIPQuorum(quorum int) PublicIPOption {
+	return func(o *publicIPOptions) {
+		o.quorum = quorum
+	}
+}
+
+// WithPublicIPHTTPClient sets the client used for the HTTPS endpoints.
+func WithPublicIPHTTPClient(client *http.Client) PublicIPOption {
+	return func(o *publicIPOptions) {
+		o.client = client
+	}
+}
+
+type cachedPublicIP struct {
+	addr    netip.Addr
+	expires time.Time
+}
+
+//nolint:gochecknoglobals // process-wide discovery cache
+var publicIPCache = struct {
+	sync.Mutex
+	entries map[string]cachedPublicIP
+}{entries: map[string]cachedPublicIP{}}
+
+// GetPublicIP discovers the machine's public address, for services that
+// must register their external address. It queries every configured source
+// concurrently and returns as soon as a quorum of them agree, so a single
+// wrong or compromised echo service cannot decide the result.
+//
+// Returns ErrNoPublicIPConsensus, joined with the source errors, when no
+// address reaches the quorum before the timeout.
+//
+// Example:
+//
+//	addr, err := GetPublicIP(ctx, WithPublicIPSTUN("stun.l.google.com:19302"))
+func GetPublicIP(ctx context.Context, opts ...PublicIPOption) (netip.Addr, error) {
+	options := &publicIPOptions{
+		endpoints: defaultPublicIPEndpoints(),
+		timeout:   defaultPublicIPTimeout,
+		cacheTTL:  defaultPublicIPCacheTTL,
+		client:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	sources := len(options.endpoints) + len(options.stunServers)
+	if sources == 0 {
+		return netip.Addr{}, errors.New("no public IP sources configured")
+	}
+	quorum := options.quorum
+	if quorum <= 0 {
+		quorum = min(defaultPublicIPQuorum, sources)
+	}
+
+	cacheKey := strings.Join(slices.Concat(options.endpoints, options.stunServers), "|")
+	if options.cacheTTL > 0 {
+		publicIPCache.Lock()
+		cached, ok := publicIPCache.entries[cacheKey]
+		publicIPCache.Unlock()
+		if ok && time.Now().Before(cached.expires) {
+			return cached.addr, nil
+		}
+	}
+
+	addr, err := discoverPublicIP(ctx, options, sources, quorum)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	if options.cacheTTL > 0 {
+		publicIPCache.Lock()
+		publicIPCache.entries[cacheKey] = cachedPublicIP{addr: addr, expires: time.Now().Add(options.cacheTTL)}
+		publicIPCache.Unlock()
+	}
+	return addr, nil
+}
+
+type publicIPResult struct {
+	addr netip.Addr
+	err  error
+}
+
+func discoverPublicIP(ctx context.Context, options *publicIPOptions, sources, quorum int) (netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, options.timeout)
+	defer cancel()
+
+	// Buffered so the remaining lookups finish without blocking once a quorum is reached.
+	results := make(chan publicIPResult, sources)
+	for _, endpoint := range options.endpoints {
+		go func() {
+			addr, err := echoPublicIP(ctx, options.client, endpoint)
+			results <- publicIPResult{addr: addr, err: err}
+		}()
+	}
+	for _, server := range options.stunServers {
+		go func() {
+			addr, err := stunPublicIP(ctx, server)
+			results <- publicIPResult{addr: addr, err: err}
+		}()
+	}
+
+	votes := map[netip.Addr]int{}
+	var errs []error
+	for range sources {
+		result := <-results
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		if votes[result.addr]++; votes[result.addr] >= quorum {
+			return result.addr, nil
+		}
+	}
+	return netip.Addr{}, errors.Join(append([]error{ErrNoPublicIPConsensus}, errs...)...)
+}
+
+// echoPublicIP asks an HTTPS echo service for the caller's address.
+func echoPublicIP(ctx context.Context, client *http.Client, endpoint string) (netip.Addr, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("public IP endpoint %s: %w", endpoint, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("public IP endpoint %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("public IP endpoint %s: status %d", endpoint, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPublicIPResponse))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("public IP endpoint %s: %w", endpoint, err)
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(string(body)))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("public IP endpoint %s: %w", endpoint, err)
+	}
+	return addr.Unmap(), nil
+}
+
+// stunPublicIP sends a STUN Binding request and returns the mapped address
+// from the response.
+func stunPublicIP(ctx context.Context, server string) (netip.Addr, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("STUN server %s: %w", server, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	if _, err = rand.Read(request[8:stunHeaderSize]); err != nil {
+		return netip.Addr{}, fmt.Errorf("STUN server %s: %w", server, err)
+	}
+	if _, err = conn.Write(request); err != nil {
+		return netip.Addr{}, fmt.Errorf("STUN server %s: %w", server, err)
+	}
+
+	response := make([]byte, stunMaxMessage)
+	n, err := conn.Read(response)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("STUN server %s: %w", server, err)
+	}
+	addr, err := parseSTUNResponse(response[:n], request[8:stunHeaderSize])
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("STUN server %s: %w", server, err)
+	}
+	return addr, nil
+}
+
+// parseSTUNResponse extracts the XOR-MAPPED-ADDRESS, or the legacy
+// MAPPED-ADDRESS, from a Binding success response to transactionID.
+func parseSTUNResponse(msg, transactionID []byte) (netip.Addr, error) {
+	if len(msg) < stunHeaderSize ||
+		binary.BigEndian.Uint16(msg[0:2]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie ||
+		string(msg[8:stunHeaderSize]) != string(transactionID) {
+		return netip.Addr{}, errors.New("unexpected STUN response")
+	}
+
+	var mapped netip.Addr
+	attrs := msg[stunHeaderSize:]
+	for len(attrs) >= stunAttrHeaderSize {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		length := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if len(attrs) < stunAttrHeaderSize+length {
+			break
+		}
+		value := attrs[stunAttrHeaderSize : stunAttrHeaderSize+length]
+
+		switch attrType {
+		case stunAttrXORMapped:
+			if addr, ok := stunAddress(value, msg[4:stunHeaderSize]); ok {
+				return addr, nil
+			}
+		case stunAttrMappedAddress:
+			mapped, _ = stunAddress(value, nil)
+		}
+
+		padded := (length + stunAttrAlignment - 1) / stunAttrAlignment * stunAttrAlignment
+		attrs = attrs[min(stunAttrHeaderSize+padded, len(attrs)):]
+	}
+	if mapped.IsValid() {
+		return mapped, nil
+	}
+	return netip.Addr{}, errors.New("STUN response carries no mapped address")
+}
+
+// stunAddress decodes a (XOR-)MAPPED-ADDRESS value. xorKey is the magic
+// cookie followed by the transaction ID, or nil for a plain address.
+func stunAddress(value, xorKey []byte) (netip.Addr, bool) {
+	if len(value) < stunAddrHeaderSize {
+		return netip.Addr{}, false
+	}
+	raw := slices.Clone(value[stunAddrHeaderSize:])
+	for i := range raw {
+		if i < len(xorKey) {
+			raw[i] ^= xorKey[i]
+		}
+	}
+
+	switch value[1] {
+	case stunFamilyIPv4:
+		if len(raw) != net.IPv4len {
+			return netip.Addr{}, false
+		}
+	case stunFamilyIPv6:
+		if len(raw) != net.IPv6len {
+			return netip.Addr{}, false
+		}
+	default:
+		return netip.Addr{}, false
+	}
+	addr, ok := netip.AddrFromSlice(raw)
+	return addr.Unmap(), ok
+}
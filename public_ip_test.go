@@ -0,0 +1,110 @@
+package util_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func echoServer(t *testing.T, body string, hits *atomic.Int32) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits != nil {
+			hits.Add(1)
+		}
+		fmt.Fprintln(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// stunServer answers Binding requests with addr as the XOR-MAPPED-ADDRESS.
+func stunServer(t *testing.T, addr netip.Addr) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, peer, readErr := conn.ReadFrom(buf)
+			if readErr != nil {
+				return
+			}
+			if n < 20 {
+				continue
+			}
+			header := buf[:20]
+			raw := addr.As4()
+			value := []byte{0, 0x01, 0, 0}
+			for i, b := range raw {
+				value = append(value, b^header[4+i])
+			}
+
+			resp := make([]byte, 20, 32)
+			binary.BigEndian.PutUint16(resp[0:2], 0x0101)
+			binary.BigEndian.PutUint16(resp[2:4], uint16(4+len(value)))
+			copy(resp[4:20], header[4:20])
+			resp = binary.BigEndian.AppendUint16(resp, 0x0020)
+			resp = binary.BigEndian.AppendUint16(resp, uint16(len(value)))
+			resp = append(resp, value...)
+			_, _ = conn.WriteTo(resp, peer)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestGetPublicIP(t *testing.T) {
+	want := netip.MustParseAddr("203.0.113.7")
+	addr, err := util.GetPublicIP(t.Context(),
+		util.WithPublicIPEndpoints(echoServer(t, "203.0.113.7", nil), echoServer(t, "198.51.100.1", nil)),
+		util.WithPublicIPSTUN(stunServer(t, want)),
+		util.WithPublicIPCacheTTL(0))
+	if err != nil || addr != want {
+		t.Fatalf("GetPublicIP() = %v, %v; want %v agreed by the echo and STUN sources", addr, err, want)
+	}
+}
+
+func TestGetPublicIPNoConsensus(t *testing.T) {
+	_, err := util.GetPublicIP(t.Context(),
+		util.WithPublicIPEndpoints(echoServer(t, "203.0.113.7", nil), echoServer(t, "198.51.100.1", nil)),
+		util.WithPublicIPCacheTTL(0),
+		util.WithPublicIPTimeout(time.Second))
+	if !errors.Is(err, util.ErrNoPublicIPConsensus) {
+		t.Errorf("GetPublicIP() error = %v, want ErrNoPublicIPConsensus", err)
+	}
+
+	_, err = util.GetPublicIP(t.Context(),
+		util.WithPublicIPEndpoints(echoServer(t, "not an address", nil)),
+		util.WithPublicIPCacheTTL(0))
+	if !errors.Is(err, util.ErrNoPublicIPConsensus) {
+		t.Errorf("GetPublicIP() with a bad response error = %v, want ErrNoPublicIPConsensus", err)
+	}
+}
+
+func TestGetPublicIPCache(t *testing.T) {
+	var hits atomic.Int32
+	endpoint := echoServer(t, "203.0.113.9", &hits)
+
+	for range 3 {
+		addr, err := util.GetPublicIP(t.Context(), util.WithPublicIPEndpoints(endpoint), util.WithPublicIPCacheTTL(time.Minute))
+		if err != nil || addr != netip.MustParseAddr("203.0.113.9") {
+			t.Fatalf("GetPublicIP() = %v, %v", addr, err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("endpoint queried %d times, want 1 with caching", n)
+	}
+}
@@ -0,0 +1,238 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Push when the queue is full and its
+	// overflow policy is OverflowDropNewest.
+	ErrQueueFull = errors.New("queue is full")
+
+	// ErrQueueClosed is returned by Queue.Push after Close, and by Queue.Pop
+	// once a closed queue is drained.
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+// queueOptions contains configuration for a Queue.
+type queueOptions struct {
+	// overflow applies when the queue is full
+	overflow OverflowPolicy
+}
+
+// QueueOption is a function that configures a Queue.
+type QueueOption func(*queueOptions)
+
+// WithQueueOverflow sets what Push does when the queue is full, as for
+// EventBus subscribers. The default is OverflowBlock.
+func WithQueueOverflow(policy OverflowPolicy) QueueOption {
+	return func(o *queueOptions) {
+		o.overflow = policy
+	}
+}
+
+// QueueStats are the counters of a Queue since it was created.
+type QueueStats struct {
+	// Pushed counts the items accepted by Push.
+	Pushed uint64 `json:"pushed"`
+
+	// Dropped counts the items discarded because the queue was full: the
+	// pushed ones with OverflowDropNewest, the oldest queued ones with
+	// OverflowDropOldest.
+	Dropped uint64 `json:"dropped"`
+
+	// Len is the number of queued items, and Capacity the most it holds.
+	Len      int `json:"len"`
+	Capacity int `json:"capacity"`
+}
+
+// Queue is a bounded FIFO queue between producers and consumers, with an
+// OverflowPolicy deciding whether a full queue makes producers wait, as by
+// default, or drops items, so that backpressure is a deliberate choice. A
+// Queue is safe for concurrent use.
+//
+// Example:
+//
+//	jobs := NewQueue[Job](1000, WithQueueOverflow(OverflowDropOldest))
+//	jobs.RegisterMetrics(metrics, "job_queue")
+//	go func() {
+//	    for job := range jobs.C() {
+//	        run(ctx, job)
+//	    }
+//	}()
+//
+//	err := jobs.Push(ctx, job)
+type Queue[T any] struct {
+	options queueOptions
+	pushed  atomic.Uint64
+	dropped atomic.Uint64
+
+	// done is closed on Close, releasing blocked producers
+	done chan struct{}
+	once sync.Once
+
+	// mu guards closed and the registration of senders, which Close waits
+	// for before closing ch; the sends themselves happen without it
+	mu      sync.Mutex
+	senders sync.WaitGroup
+	ch      chan T
+	closed  bool
+}
+
+// NewQueue returns an empty Queue holding up to capacity items.
+func NewQueue[T any](capacity int, opts ...QueueOption) *Queue[T] {
+	options := queueOptions{overflow: OverflowBlock}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Queue[T]{options: options, done: make(chan struct{}), ch: make(chan T, max(capacity, 0))}
+}
+
+// Push adds item to the queue. When the queue is full it waits, for at
+// most as long as ctx, or drops an item, according to the overflow policy;
+// with OverflowDropNewest it returns ErrQueueFull. After Close it returns
+// ErrQueueClosed.
+func (q *Queue[T]) Push(ctx context.Context, item T) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	q.senders.Add(1)
+	q.mu.Unlock()
+	defer q.senders.Done()
+
+	sent, evicted, err := sendWithOverflow(ctx, q.ch, item, q.options.overflow, q.done)
+	if evicted {
+		q.dropped.Add(1)
+	}
+	switch {
+	case err != nil:
+		return err
+	case sent:
+		q.pushed.Add(1)
+		return nil
+	case q.options.overflow == OverflowBlock:
+		return ErrQueueClosed
+	}
+	q.dropped.Add(1)
+	return ErrQueueFull
+}
+
+// Pop removes and returns the oldest item, waiting for one until ctx is
+// done. Once the queue is closed and drained it returns ErrQueueClosed.
+func (q *Queue[T]) Pop(ctx context.Context) (T, error) {
+	select {
+	case item, ok := <-q.ch:
+		if !ok {
+			return item, ErrQueueClosed
+		}
+		return item, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// C returns the channel the items are queued on, for receiving them in a
+// select or range loop; it is closed by Close.
+func (q *Queue[T]) C() <-chan T {
+	return q.ch
+}
+
+// Len returns the number of queued items.
+func (q *Queue[T]) Len() int {
+	return len(q.ch)
+}
+
+// Close stops the queue accepting items and releases blocked producers.
+// Items already queued can still be received.
+func (q *Queue[T]) Close() {
+	q.once.Do(func() {
+		close(q.done)
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+		q.senders.Wait()
+		close(q.ch)
+	})
+}
+
+// Stats returns the queue's counters and length.
+func (q *Queue[T]) Stats() QueueStats {
+	return QueueStats{
+		Pushed:   q.pushed.Load(),
+		Dropped:  q.dropped.Load(),
+		Len:      len(q.ch),
+		Capacity: cap(q.ch),
+	}
+}
+
+// RegisterMetrics exposes the queue's counters and length in reg, as
+// metrics named prefix followed by "_pushed_total", "_dropped_total" and
+// "_length".
+func (q *Queue[T]) RegisterMetrics(reg *MetricsRegistry, prefix string) {
+	reg.CounterFunc(prefix+"_pushed_total", "Items pushed to the queue.",
+		func() float64 { return float64(q.pushed.Load()) })
+	reg.CounterFunc(prefix+"_dropped_total", "Items dropped because the queue was full.",
+		func() float64 { return float64(q.dropped.Load()) })
+	reg.GaugeFunc(prefix+"_length", "Items in the queue.", func() float64 { return float64(q.Len()) })
+}
+
+// sendWithOverflow sends item on the buffered channel ch, applying policy
+// when ch is full. It reports whether item was sent and whether an older
+// item was evicted for it; a blocking send gives up, without error, when
+// done is closed, or with ctx's error when ctx is done. Concurrent senders
+// are fine, but the caller must prevent ch from being closed meanwhile.
+func sendWithOverflow[T any](
+	ctx context.Context,
+	ch chan T,
+	item T,
+	policy OverflowPolicy,
+	done <-chan struct{},
+) (sent, evicted bool, err error) {
+	select {
+	case ch <- item:
+		return true, false, nil
+	default:
+	}
+
+	switch policy {
+	case OverflowDropOldest:
+		// Another sender may take the freed slot, so evict until item fits.
+		// When there is nothing left to evict a consumer has drained ch since
+		// the send above, so try once more before giving up.
+		for {
+			select {
+			case <-ch:
+				evicted = true
+			default:
+				select {
+				case ch <- item:
+					return true, evicted, nil
+				default:
+					return false, evicted, nil
+				}
+			}
+			select {
+			case ch <- item:
+				return true, evicted, nil
+			default:
+			}
+		}
+	case OverflowBlock:
+		select {
+		case ch <- item:
+			return true, false, nil
+		case <-done:
+			return false, false, nil
+		case <-ctx.Done():
+			return false, false, ctx.Err()
+		}
+	case OverflowDropNewest:
+	}
+	return false, evicted, nil
+}
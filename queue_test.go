@@ -0,0 +1,157 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestQueueBlocking(t *testing.T) {
+	q := util.NewQueue[int](1)
+	ctx := t.Context()
+	if err := q.Push(ctx, 1); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := q.Push(short, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Push to a full blocking queue = %v, want a deadline error", err)
+	}
+
+	pushed := make(chan error)
+	go func() { pushed <- q.Push(ctx, 3) }()
+	if v, err := q.Pop(ctx); err != nil || v != 1 {
+		t.Fatalf("Pop = %d, %v; want 1", v, err)
+	}
+	if err := <-pushed; err != nil {
+		t.Fatalf("blocked Push = %v", err)
+	}
+	if v, _ := q.Pop(ctx); v != 3 {
+		t.Fatalf("Pop = %d, want 3", v)
+	}
+}
+
+func TestQueueBlockedProducersHonourTheirContext(t *testing.T) {
+	q := util.NewQueue[int](1)
+	defer q.Close()
+	ctx := t.Context()
+	if err := q.Push(ctx, 1); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- q.Push(ctx, 2) }()
+	time.Sleep(10 * time.Millisecond)
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	pushed := make(chan error, 1)
+	go func() { pushed <- q.Push(short, 3) }()
+	select {
+	case err := <-pushed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("second Push = %v, want a deadline error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second Push waited on the first blocked producer")
+	}
+
+	q.Close()
+	if err := <-blocked; !errors.Is(err, util.ErrQueueClosed) {
+		t.Fatalf("blocked Push after Close = %v, want ErrQueueClosed", err)
+	}
+}
+
+func TestQueueDropping(t *testing.T) {
+	ctx := t.Context()
+	newest := util.NewQueue[int](2, util.WithQueueOverflow(util.OverflowDropNewest))
+	oldest := util.NewQueue[int](2, util.WithQueueOverflow(util.OverflowDropOldest))
+	for i := 1; i <= 3; i++ {
+		err := newest.Push(ctx, i)
+		if (i == 3) != errors.Is(err, util.ErrQueueFull) {
+			t.Fatalf("DropNewest Push(%d) = %v", i, err)
+		}
+		if err = oldest.Push(ctx, i); err != nil {
+			t.Fatalf("DropOldest Push(%d) = %v", i, err)
+		}
+	}
+
+	for q, want := range map[*util.Queue[int]][]int{newest: {1, 2}, oldest: {2, 3}} {
+		q.Close()
+		var got []int
+		for v := range q.C() {
+			got = append(got, v)
+		}
+		if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("queue held %v, want %v", got, want)
+		}
+	}
+
+	if stats := oldest.Stats(); stats.Pushed != 3 || stats.Dropped != 1 || stats.Capacity != 2 {
+		t.Errorf("DropOldest Stats = %+v", stats)
+	}
+	if stats := newest.Stats(); stats.Pushed != 2 || stats.Dropped != 1 {
+		t.Errorf("DropNewest Stats = %+v", stats)
+	}
+}
+
+func TestQueueDropOldestWithConsumer(t *testing.T) {
+	q := util.NewQueue[int](1, util.WithQueueOverflow(util.OverflowDropOldest))
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		for range q.C() {
+		}
+	}()
+
+	for i := range 10000 {
+		if err := q.Push(t.Context(), i); err != nil {
+			t.Fatalf("Push(%d) with a consumer draining the queue = %v", i, err)
+		}
+	}
+	q.Close()
+	<-consumed
+}
+
+func TestQueueClose(t *testing.T) {
+	q := util.NewQueue[string](1)
+	ctx := t.Context()
+	_ = q.Push(ctx, "queued")
+
+	blocked := make(chan error)
+	go func() { blocked <- q.Push(ctx, "waiting") }()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	if err := <-blocked; !errors.Is(err, util.ErrQueueClosed) {
+		t.Fatalf("blocked Push after Close = %v, want ErrQueueClosed", err)
+	}
+	if err := q.Push(ctx, "late"); !errors.Is(err, util.ErrQueueClosed) {
+		t.Fatalf("Push after Close = %v, want ErrQueueClosed", err)
+	}
+	if v, err := q.Pop(ctx); err != nil || v != "queued" {
+		t.Fatalf("Pop = %q, %v; want the queued item", v, err)
+	}
+	if _, err := q.Pop(ctx); !errors.Is(err, util.ErrQueueClosed) {
+		t.Fatalf("Pop on a drained closed queue = %v, want ErrQueueClosed", err)
+	}
+}
+
+func TestQueueRegisterMetrics(t *testing.T) {
+	q := util.NewQueue[int](4)
+	_ = q.Push(t.Context(), 1)
+	reg := util.NewMetricsRegistry()
+	q.RegisterMetrics(reg, "jobs")
+
+	var b strings.Builder
+	_ = reg.WritePrometheus(&b)
+	for _, want := range []string{"jobs_pushed_total 1\n", "jobs_dropped_total 0\n", "jobs_length 1\n"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -4,8 +4,11 @@ package util
 
 import (
 	"crypto/rand"
-	"math/big"
+	"encoding/binary"
+	"fmt"
+	"io"
 	"time"
+	"unicode/utf8"
 
 	"github.com/rs/xid"
 )
@@ -13,27 +16,237 @@ import (
 const (
 	alphanumerics = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	numerics      = "0123456789"
+
+	hexDigits         = "0123456789abcdef"
+	base32Alphabet    = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+	defaultNanoIDSize = 21
+
+	byteValues       = 256
+	wordValues       = uint64(1) << 32
+	wordBytes        = 4
+	randomBlockSlack = 16
+	maxRandomBlock   = 4096
 )
 
 // RandomString generates a cryptographically secure random string of length n
-// using the provided character set.
+// using the provided character set. Every byte of charset is equally likely
+// at every position. It panics when charset is empty or crypto/rand fails.
+//
+// charset is treated as bytes; use RandomStringWithAlphabet for alphabets
+// with non-ASCII characters.
 func RandomString(n int, charset string) string {
+	return randomString(rand.Reader, n, charset)
+}
+
+func randomString(source io.Reader, n int, charset string) string {
 	if n <= 0 {
 		return ""
 	}
+	if charset == "" {
+		panic("util: RandomString called with an empty charset")
+	}
 
-	maxLen := big.NewInt(int64(len(charset)))
 	b := make([]byte, n)
+	sampleIndexes(source, n, len(charset), func(i, idx int) {
+		b[i] = charset[idx]
+	})
+	return string(b)
+}
+
+// RandomStringWithAlphabet generates a cryptographically secure random
+// string of n characters drawn uniformly from alphabet. Unlike RandomString,
+// alphabet may contain any Unicode characters, e.g. an emoji set or a
+// localized script. It panics when alphabet is empty or crypto/rand fails.
+func RandomStringWithAlphabet(n int, alphabet string) string {
+	if len(alphabet) == utf8.RuneCountInString(alphabet) {
+		return RandomString(n, alphabet)
+	}
+	if n <= 0 {
+		return ""
+	}
+
+	runes := []rune(alphabet)
+	out := make([]rune, n)
+	sampleIndexes(rand.Reader, n, len(runes), func(i, idx int) {
+		out[i] = runes[idx]
+	})
+	return string(out)
+}
+
+// RandomHex generates a random string of n lowercase hexadecimal characters.
+func RandomHex(n int) string {
+	return RandomString(n, hexDigits)
+}
 
-	for i := range n {
-		idx, err := rand.Int(rand.Reader, maxLen)
-		if err != nil {
-			panic(err)
+// RandomBase32 generates a random string of n characters from the RFC 4648
+// base32 alphabet (A-Z and 2-7), which is case-insensitive and avoids 0, 1 and 8.
+func RandomBase32(n int) string {
+	return RandomString(n, base32Alphabet)
+}
+
+// RandomURLSafe generates a random string of n characters from the base64url
+// alphabet (A-Z, a-z, 0-9, '-' and '_'), safe in URLs and file names without
+// escaping. Each character carries 6 bits, so 22 characters exceed 128 bits.
+func RandomURLSafe(n int) string {
+	return RandomString(n, base64URLAlphabet)
+}
+
+// RandomDigits generates a random string of n decimal digits, for one-time
+// passwords and verification codes. Leading zeros are kept, so treat the
+// result as a string, not a number.
+func RandomDigits(n int) string {
+	return RandomString(n, numerics)
+}
+
+// NanoID generates a Nano ID (https://github.com/ai/nanoid): a random,
+// URL-safe ID of 21 characters from the nanoid alphabet (A-Z, a-z, 0-9, '_'
+// and '-'), with about the collision resistance of a UUIDv4. Pass size to
+// generate shorter or longer IDs.
+//
+// Example:
+//
+//	id := NanoID()    // "V1StGXR8_Z5jdHi6B-myT"
+//	short := NanoID(10)
+func NanoID(size ...int) string {
+	n := defaultNanoIDSize
+	if len(size) > 0 {
+		n = size[0]
+	}
+	return RandomString(n, base64URLAlphabet)
+}
+
+// NanoIDWithAlphabet generates a Nano ID of size characters from a custom
+// alphabet, like nanoid's customAlphabet. alphabet may contain any Unicode
+// characters; it panics when alphabet is empty.
+//
+// Example:
+//
+//	orderID := NanoIDWithAlphabet("0123456789ABCDEF", 12)
+func NanoIDWithAlphabet(alphabet string, size int) string {
+	return RandomStringWithAlphabet(size, alphabet)
+}
+
+// sampleIndexes calls emit with n uniformly random indexes below size, read from source.
+// Random samples at or above the largest multiple of size are rejected, so
+// the modulo is unbiased. Sizes up to 256 sample bytes; larger ones sample
+// 32-bit words.
+func sampleIndexes(source io.Reader, n, size int, emit func(i, idx int)) {
+	if size > byteValues {
+		sampleWordIndexes(source, n, size, emit)
+		return
+	}
+
+	limit := byteValues - byteValues%size
+	buf := make([]byte, randomBlockSize(n))
+	for i := 0; i < n; {
+		readRandom(source, buf)
+		for _, r := range buf {
+			if int(r) >= limit {
+				continue
+			}
+			emit(i, int(r)%size)
+			if i++; i == n {
+				return
+			}
 		}
-		b[i] = charset[idx.Int64()]
 	}
+}
 
-	return string(b)
+func sampleWordIndexes(source io.Reader, n, size int, emit func(i, idx int)) {
+	limit := wordValues - wordValues%uint64(size)
+	buf := make([]byte, randomBlockSize(n)*wordBytes)
+	for i := 0; i < n; {
+		readRandom(source, buf)
+		for j := 0; j < len(buf) && i < n; j += wordBytes {
+			r := uint64(binary.LittleEndian.Uint32(buf[j:]))
+			if r >= limit {
+				continue
+			}
+			emit(i, int(r%uint64(size))) //nolint:gosec // below size
+			i++
+		}
+	}
+}
+
+// randomBlockSize returns how many random samples to read at once for n
+// characters: enough to finish in one read at the worst acceptance rate of
+// one half in most cases, capped to bound the allocation.
+func randomBlockSize(n int) int {
+	return min(2*n+randomBlockSlack, maxRandomBlock) //nolint:mnd // worst-case acceptance is 1/2
+}
+
+func readRandom(source io.Reader, buf []byte) {
+	if _, err := io.ReadFull(source, buf); err != nil {
+		panic(err)
+	}
+}
+
+// RandomBytes returns n cryptographically secure random bytes. Unlike the
+// string helpers it returns the crypto/rand error instead of panicking.
+//
+// Example:
+//
+//	salt, err := RandomBytes(16)
+//	if err != nil {
+//	    return err
+//	}
+func RandomBytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("random byte count cannot be negative: %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return b, nil
+}
+
+// RandomInt returns a cryptographically secure random integer uniformly
+// distributed in [minValue, maxValue], both ends included. The full int64
+// range is allowed. Returns an error when minValue > maxValue or crypto/rand
+// fails.
+//
+// Example:
+//
+//	delay, err := RandomInt(100, 500) // milliseconds of jitter
+func RandomInt(minValue, maxValue int64) (int64, error) {
+	if minValue > maxValue {
+		return 0, fmt.Errorf("invalid random range [%d, %d]", minValue, maxValue)
+	}
+
+	// The span wraps to 0 for the full int64 range.
+	span := uint64(maxValue-minValue) + 1 //nolint:gosec // two's complement difference is the span
+	r, err := randomBelow(span)
+	if err != nil {
+		return 0, err
+	}
+	return minValue + int64(r), nil //nolint:gosec // wraps within [minValue, maxValue]
+}
+
+// randomBelow returns a uniformly random integer below span from
+// crypto/rand, or any uint64 when span is 0. Samples at or above the largest
+// multiple of span are rejected, so the modulo is unbiased.
+func randomBelow(span uint64) (uint64, error) {
+	var limit uint64
+	if span != 0 {
+		limit = -(-span % span) // largest multiple of span, modulo 2^64
+	}
+
+	var buf [8]byte
+	for {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return 0, fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		r := binary.LittleEndian.Uint64(buf[:])
+		if span == 0 {
+			return r, nil
+		}
+		if limit == 0 || r < limit {
+			return r % span, nil
+		}
+	}
 }
 
 // RandomAlphaNumericString generates a cryptographically secure alphanumeric string.
@@ -46,10 +259,67 @@ func RandomNumericString(n int) string {
 	return RandomString(n, numerics)
 }
 
+// IDString returns a new globally unique, sortable xid
+// (https://github.com/rs/xid), such as "9m4e2mr0ui3e8a215n4g".
 func IDString() string {
 	return IDStringWithTime(time.Now())
 }
 
+// IDStringWithTime returns a new xid carrying t, to the second, as its creation time.
 func IDStringWithTime(t time.Time) string {
 	return xid.NewWithTime(t).String()
 }
+
+// IDParts are the components of an xid produced by IDString.
+type IDParts struct {
+	// Time is the creation time, to the second.
+	Time time.Time
+	// Machine is the 3-byte identifier of the host that generated the ID.
+	Machine []byte
+	// Pid is the process ID of the generator, truncated to 16 bits.
+	Pid uint16
+	// Counter is the per-process counter, which starts at a random value.
+	Counter int32
+}
+
+// ParseID decodes an xid produced by IDString into its components.
+func ParseID(id string) (IDParts, error) {
+	parsed, err := xid.FromString(id)
+	if err != nil {
+		return IDParts{}, fmt.Errorf("invalid ID %q: %w", id, err)
+	}
+	return IDParts{
+		Time:    parsed.Time(),
+		Machine: parsed.Machine(),
+		Pid:     parsed.Pid(),
+		Counter: parsed.Counter(),
+	}, nil
+}
+
+// IDTime returns the creation time of an xid produced by IDString, to the second.
+//
+// Example:
+//
+//	created, err := IDTime(order.ID)
+func IDTime(id string) (time.Time, error) {
+	parts, err := ParseID(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parts.Time, nil
+}
+
+// IDLowerBound returns the smallest xid with creation time t. Since xids
+// sort by creation time, IDs created in [from, to) are those with
+// IDLowerBound(from) <= id < IDLowerBound(to), which turns time range queries
+// into ID range queries.
+//
+// Example:
+//
+//	rows, err := db.Query(ctx, "SELECT * FROM orders WHERE id >= $1 AND id < $2",
+//	    IDLowerBound(dayStart), IDLowerBound(dayStart.AddDate(0, 0, 1)))
+func IDLowerBound(t time.Time) string {
+	var id xid.ID
+	binary.BigEndian.PutUint32(id[:4], uint32(t.Unix())) //nolint:gosec // xid time is 32-bit seconds
+	return id.String()
+}
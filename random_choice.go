@@ -0,0 +1,205 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// float64Bits is the precision of a float64 mantissa, used to draw uniform floats.
+const float64Bits = 53
+
+// ErrInvalidWeights is returned when weights for a weighted choice do not
+// match the items, are negative or not finite, or are all zero.
+var ErrInvalidWeights = errors.New("invalid weights")
+
+// Shuffle randomly permutes s in place with the Fisher-Yates algorithm over
+// crypto/rand, so every permutation is equally likely and unpredictable.
+// It panics when crypto/rand fails.
+//
+// Example:
+//
+//	Shuffle(candidates)
+//	winner := candidates[0]
+func Shuffle[T any](s []T) {
+	for i := len(s) - 1; i > 0; i-- {
+		j := mustRandomIndex(i + 1)
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// Sample returns k distinct elements of s chosen uniformly at random, in
+// random order, without modifying s. k is capped at len(s); Sample returns
+// nil when k <= 0. It panics when crypto/rand fails.
+//
+// Example:
+//
+//	targets := Sample(endpoints, 3) // probe three random endpoints
+func Sample[T any](s []T, k int) []T {
+	k = min(k, len(s))
+	if k <= 0 {
+		return nil
+	}
+
+	// A partial Fisher-Yates over the indexes: after step i, positions [0, i]
+	// hold a uniform sample. Swapped indexes are tracked in a map, so the cost
+	// is O(k) however large s is.
+	swapped := make(map[int]int, k)
+	at := func(i int) int {
+		if j, ok := swapped[i]; ok {
+			return j
+		}
+		return i
+	}
+
+	out := make([]T, k)
+	for i := range k {
+		j := i + mustRandomIndex(len(s)-i)
+		out[i] = s[at(j)]
+		swapped[j] = at(i)
+	}
+	return out
+}
+
+// mustRandomIndex returns a uniformly random index below n, panicking when
+// crypto/rand fails like the other helpers without an error result.
+func mustRandomIndex(n int) int {
+	r, err := randomBelow(uint64(n)) //nolint:gosec // n is a positive length
+	if err != nil {
+		panic(err)
+	}
+	return int(r) //nolint:gosec // below n
+}
+
+// WeightedChoice returns one of items chosen at random, with item i chosen
+// with probability weights[i] divided by the sum of the weights. Weights
+// need not sum to 1; items with weight 0 are never chosen.
+//
+// Returns an error wrapping ErrInvalidWeights when the weights are invalid.
+// For repeated draws from the same items, NewWeightedSampler is faster.
+//
+// Example:
+//
+//	backend, err := WeightedChoice([]string{"stable", "canary"}, []float64{95, 5})
+func WeightedChoice[T any](items []T, weights []float64) (T, error) {
+	var zero T
+	total, err := checkWeights(len(items), weights)
+	if err != nil {
+		return zero, err
+	}
+
+	target := mustRandomFloat() * total
+	last := 0
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if target < w {
+			return items[i], nil
+		}
+		target -= w
+		last = i
+	}
+	// Rounding may leave target just above the last positive weight.
+	return items[last], nil
+}
+
+// WeightedSampler draws items at random in proportion to their weights in
+// constant time per draw, using Vose's alias method. Create one with
+// NewWeightedSampler. A WeightedSampler is immutable and safe for
+// concurrent use.
+type WeightedSampler[T any] struct {
+	items []T
+	// prob is the probability of keeping column i rather than taking alias[i].
+	prob  []float64
+	alias []int
+}
+
+// NewWeightedSampler builds a sampler over items with the given weights, in
+// O(len(items)) time. Returns an error wrapping ErrInvalidWeights when the
+// weights are invalid.
+//
+// Example:
+//
+//	sampler, err := NewWeightedSampler(backends, weights)
+//	if err != nil {
+//	    return err
+//	}
+//	backend := sampler.Next()
+func NewWeightedSampler[T any](items []T, weights []float64) (*WeightedSampler[T], error) {
+	total, err := checkWeights(len(items), weights)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(items)
+	prob := make([]float64, n)
+	alias := make([]int, n)
+	var small, large []int
+	for i, w := range weights {
+		prob[i] = w * float64(n) / total
+		if prob[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	for len(small) > 0 && len(large) > 0 {
+		s, l := small[len(small)-1], large[len(large)-1]
+		small = small[:len(small)-1]
+		alias[s] = l
+		prob[l] -= 1 - prob[s]
+		if prob[l] < 1 {
+			large = large[:len(large)-1]
+			small = append(small, l)
+		}
+	}
+	// Columns left over are full up to rounding error.
+	for _, i := range append(small, large...) {
+		prob[i] = 1
+	}
+
+	return &WeightedSampler[T]{items: append([]T(nil), items...), prob: prob, alias: alias}, nil
+}
+
+// Next returns an item chosen at random in proportion to its weight. It
+// panics when crypto/rand fails.
+func (s *WeightedSampler[T]) Next() T {
+	i := mustRandomIndex(len(s.items))
+	if mustRandomFloat() < s.prob[i] {
+		return s.items[i]
+	}
+	return s.items[s.alias[i]]
+}
+
+// checkWeights validates weights for n items and returns their sum.
+func checkWeights(n int, weights []float64) (float64, error) {
+	if n == 0 {
+		return 0, fmt.Errorf("%w: no items to choose from", ErrInvalidWeights)
+	}
+	if len(weights) != n {
+		return 0, fmt.Errorf("%w: %d weights for %d items", ErrInvalidWeights, len(weights), n)
+	}
+
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return 0, fmt.Errorf("%w: weight %d is %v", ErrInvalidWeights, i, w)
+		}
+		total += w
+	}
+	if total == 0 || math.IsInf(total, 0) {
+		return 0, fmt.Errorf("%w: weights sum to %v", ErrInvalidWeights, total)
+	}
+	return total, nil
+}
+
+// mustRandomFloat returns a uniformly random float64 in [0, 1).
+func mustRandomFloat() float64 {
+	r, err := randomBelow(1 << float64Bits)
+	if err != nil {
+		panic(err)
+	}
+	return float64(r) / (1 << float64Bits)
+}
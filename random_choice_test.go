@@ -0,0 +1,124 @@
+package util_test
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestShuffle(t *testing.T) {
+	s := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	util.Shuffle(s)
+	if sorted := slices.Sorted(slices.Values(s)); !slices.Equal(sorted, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Fatalf("Shuffle() = %v, not a permutation", s)
+	}
+
+	// Each of the 6 permutations of 3 elements should appear about equally often.
+	counts := map[[3]int]int{}
+	const rounds = 6000
+	for range rounds {
+		p := []int{0, 1, 2}
+		util.Shuffle(p)
+		counts[[3]int(p)]++
+	}
+	if len(counts) != 6 {
+		t.Fatalf("Shuffle() produced %d distinct permutations, want 6", len(counts))
+	}
+	for p, n := range counts {
+		if n < rounds/6*8/10 || n > rounds/6*12/10 {
+			t.Errorf("permutation %v appeared %d times, want about %d", p, n, rounds/6)
+		}
+	}
+
+	util.Shuffle([]string{})
+}
+
+func TestSample(t *testing.T) {
+	s := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	original := slices.Clone(s)
+
+	got := util.Sample(s, 4)
+	if len(got) != 4 {
+		t.Fatalf("Sample(s, 4) = %v, want 4 elements", got)
+	}
+	if len(slices.Compact(slices.Sorted(slices.Values(got)))) != 4 {
+		t.Errorf("Sample() = %v, contains duplicates", got)
+	}
+	if !slices.Equal(s, original) {
+		t.Errorf("Sample() modified its input: %v", s)
+	}
+
+	if all := util.Sample(s, 20); len(all) != len(s) {
+		t.Errorf("Sample(s, 20) = %v, want all %d elements", all, len(s))
+	}
+	if none := util.Sample(s, 0); none != nil {
+		t.Errorf("Sample(s, 0) = %v, want nil", none)
+	}
+
+	counts := make([]int, len(s))
+	const rounds = 5000
+	for range rounds {
+		for _, v := range util.Sample(s, 2) {
+			counts[v]++
+		}
+	}
+	for v, n := range counts {
+		if want := rounds * 2 / len(s); n < want*8/10 || n > want*12/10 {
+			t.Errorf("element %d sampled %d times, want about %d", v, n, want)
+		}
+	}
+}
+
+func TestWeightedChoice(t *testing.T) {
+	items := []string{"stable", "canary", "never"}
+	weights := []float64{3, 1, 0}
+
+	counts := map[string]int{}
+	const rounds = 8000
+	for range rounds {
+		item, err := util.WeightedChoice(items, weights)
+		if err != nil {
+			t.Fatalf("WeightedChoice() error = %v", err)
+		}
+		counts[item]++
+	}
+	if counts["never"] != 0 {
+		t.Errorf("WeightedChoice() chose a zero-weight item %d times", counts["never"])
+	}
+	if n := counts["canary"]; n < rounds/4*8/10 || n > rounds/4*12/10 {
+		t.Errorf("WeightedChoice() chose canary %d times, want about %d", n, rounds/4)
+	}
+
+	for _, bad := range [][]float64{nil, {1, 1}, {1, -1, 1}, {0, 0, 0}, {1, math.NaN(), 1}, {1, math.Inf(1), 1}} {
+		if _, err := util.WeightedChoice(items, bad); !errors.Is(err, util.ErrInvalidWeights) {
+			t.Errorf("WeightedChoice() with weights %v error = %v, want ErrInvalidWeights", bad, err)
+		}
+	}
+}
+
+func TestWeightedSampler(t *testing.T) {
+	weights := []float64{0.5, 0.25, 0.125, 0.125, 0}
+	sampler, err := util.NewWeightedSampler([]int{0, 1, 2, 3, 4}, weights)
+	if err != nil {
+		t.Fatalf("NewWeightedSampler() error = %v", err)
+	}
+
+	counts := make([]int, len(weights))
+	const rounds = 16000
+	for range rounds {
+		counts[sampler.Next()]++
+	}
+	for i, w := range weights {
+		want := int(w * rounds)
+		if counts[i] < want*85/100 || counts[i] > want*115/100 {
+			t.Errorf("item %d drawn %d times, want about %d", i, counts[i], want)
+		}
+	}
+
+	if _, err = util.NewWeightedSampler([]int{}, nil); !errors.Is(err, util.ErrInvalidWeights) {
+		t.Errorf("NewWeightedSampler() with no items error = %v, want ErrInvalidWeights", err)
+	}
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+)
+
+const (
+	ctxValueRandSource  = contextKeyType("rand_source")
+	ctxValueIDGenerator = contextKeyType("id_generator")
+)
+
+// ContextWithRandSource returns a copy of ctx whose random values, as
+// returned by RandSource and RandomStringContext, are read from source
+// instead of crypto/rand.
+//
+// It is a seam for tests that need reproducible output; production code
+// should never set it. source must not return errors.
+//
+// Example:
+//
+//	ctx := ContextWithRandSource(t.Context(), mathrand.NewChaCha8([32]byte{}))
+//	code := RandomStringContext(ctx, 8, "ABC") // the same on every run
+func ContextWithRandSource(ctx context.Context, source io.Reader) context.Context {
+	return context.WithValue(ctx, ctxValueRandSource, source)
+}
+
+// RandSource returns the random source set on ctx by ContextWithRandSource,
+// or crypto/rand.Reader when there is none.
+func RandSource(ctx context.Context) io.Reader {
+	if source, ok := ctx.Value(ctxValueRandSource).(io.Reader); ok {
+		return source
+	}
+	return rand.Reader
+}
+
+// RandomStringContext generates a random string like RandomString, reading
+// from the random source of ctx.
+func RandomStringContext(ctx context.Context, n int, charset string) string {
+	return randomString(RandSource(ctx), n, charset)
+}
+
+// ContextWithIDGenerator returns a copy of ctx on which IDStringContext
+// calls generate instead of IDString, so tests can assert exact IDs.
+//
+// Example:
+//
+//	next := 0
+//	ctx := ContextWithIDGenerator(t.Context(), func() string {
+//	    next++
+//	    return fmt.Sprintf("id-%d", next)
+//	})
+func ContextWithIDGenerator(ctx context.Context, generate func() string) context.Context {
+	return context.WithValue(ctx, ctxValueIDGenerator, generate)
+}
+
+// IDStringContext returns a new ID from the generator set on ctx by
+// ContextWithIDGenerator, or from IDString when there is none.
+func IDStringContext(ctx context.Context) string {
+	if generate, ok := ctx.Value(ctxValueIDGenerator).(func() string); ok {
+		return generate()
+	}
+	return IDString()
+}
@@ -0,0 +1,47 @@
+package util_test
+
+import (
+	"context"
+	"fmt"
+	mathrand "math/rand/v2"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestRandomStringContext(t *testing.T) {
+	seeded := func() context.Context {
+		return util.ContextWithRandSource(t.Context(), mathrand.NewChaCha8([32]byte{1}))
+	}
+
+	a := util.RandomStringContext(seeded(), 32, "abcdef")
+	b := util.RandomStringContext(seeded(), 32, "abcdef")
+	if a != b || len(a) != 32 {
+		t.Errorf("RandomStringContext() with the same seed = %q and %q, want equal", a, b)
+	}
+
+	if util.RandSource(t.Context()) == nil {
+		t.Error("RandSource() without an injected source = nil")
+	}
+	if util.RandomStringContext(t.Context(), 32, "abcdef") == a {
+		t.Error("RandomStringContext() without an injected source matched the seeded output")
+	}
+}
+
+func TestIDStringContext(t *testing.T) {
+	next := 0
+	ctx := util.ContextWithIDGenerator(t.Context(), func() string {
+		next++
+		return fmt.Sprintf("id-%d", next)
+	})
+
+	if got := util.IDStringContext(ctx); got != "id-1" {
+		t.Errorf("IDStringContext() = %q, want id-1", got)
+	}
+	if got := util.IDStringContext(util.DetachContext(ctx)); got != "id-2" {
+		t.Errorf("IDStringContext() on a detached context = %q, want id-2", got)
+	}
+	if _, err := util.ParseID(util.IDStringContext(t.Context())); err != nil {
+		t.Errorf("IDStringContext() without a generator is not an xid: %v", err)
+	}
+}
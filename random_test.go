@@ -0,0 +1,230 @@
+package util_test
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pitabwire/util"
+)
+
+func TestParseID(t *testing.T) {
+	created := time.Date(2025, time.March, 1, 12, 30, 45, 0, time.UTC)
+	id := util.IDStringWithTime(created)
+
+	parts, err := util.ParseID(id)
+	if err != nil {
+		t.Fatalf("ParseID() error = %v", err)
+	}
+	if !parts.Time.Equal(created) || len(parts.Machine) != 3 {
+		t.Errorf("ParseID() = %+v, want time %v and a 3-byte machine ID", parts, created)
+	}
+
+	if got, timeErr := util.IDTime(id); timeErr != nil || !got.Equal(created) {
+		t.Errorf("IDTime() = %v, %v; want %v", got, timeErr, created)
+	}
+	if _, err = util.IDTime("not-an-id"); err == nil {
+		t.Error("IDTime() accepted an invalid ID")
+	}
+}
+
+func TestIDLowerBound(t *testing.T) {
+	from := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+	lower, upper := util.IDLowerBound(from), util.IDLowerBound(to)
+
+	for _, tt := range []struct {
+		at     time.Time
+		within bool
+	}{
+		{from, true},
+		{from.Add(30 * time.Minute), true},
+		{to.Add(-time.Second), true},
+		{from.Add(-time.Second), false},
+		{to, false},
+	} {
+		id := util.IDStringWithTime(tt.at)
+		if within := lower <= id && id < upper; within != tt.within {
+			t.Errorf("ID at %v within [%v, %v) = %v, want %v", tt.at, from, to, within, tt.within)
+		}
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	if got := util.RandomString(0, "ab"); got != "" {
+		t.Errorf("RandomString(0) = %q, want empty", got)
+	}
+
+	// A charset longer than 256 bytes takes the 32-bit sampling path.
+	long := strings.Repeat("abc", 100)
+	for _, charset := range []string{"01", "0123456789", long} {
+		got := util.RandomString(1000, charset)
+		if len(got) != 1000 {
+			t.Fatalf("RandomString() length = %d, want 1000", len(got))
+		}
+		if strings.Trim(got, charset) != "" {
+			t.Errorf("RandomString() = %q has characters outside %q", got, charset)
+		}
+	}
+}
+
+func TestRandomStringDistribution(t *testing.T) {
+	// 62 characters do not divide 256, so a biased modulo would favour the
+	// first 8 characters by about 4%.
+	const samples = 620000
+	counts := make(map[rune]int)
+	for _, c := range util.RandomString(samples, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789") {
+		counts[c]++
+	}
+
+	var chiSquare float64
+	expected := float64(samples) / 62
+	for _, count := range counts {
+		diff := float64(count) - expected
+		chiSquare += diff * diff / expected
+	}
+	// The 99.99th percentile of chi-square with 61 degrees of freedom is about 112.
+	if len(counts) != 62 || chiSquare > 112 {
+		t.Errorf("distribution is not uniform: %d characters, chi-square %.1f", len(counts), chiSquare)
+	}
+}
+
+func TestRandomStringEmptyCharset(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RandomString() did not panic for an empty charset")
+		}
+	}()
+	util.RandomString(4, "")
+}
+
+func BenchmarkRandomAlphaNumericString(b *testing.B) {
+	for _, n := range []int{12, 32, 256} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_ = util.RandomAlphaNumericString(n)
+			}
+		})
+	}
+}
+
+func BenchmarkRandomNumericString(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		_ = util.RandomNumericString(6)
+	}
+}
+
+func TestRandomFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate func(int) string
+		alphabet string
+	}{
+		{"hex", util.RandomHex, "0123456789abcdef"},
+		{"base32", util.RandomBase32, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"},
+		{"url safe", util.RandomURLSafe, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"},
+		{"digits", util.RandomDigits, "0123456789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.generate(2000)
+			if len(got) != 2000 || strings.Trim(got, tt.alphabet) != "" {
+				t.Errorf("output has the wrong length or characters outside %q", tt.alphabet)
+			}
+			for _, c := range tt.alphabet {
+				if !strings.ContainsRune(got, c) {
+					t.Errorf("2000 characters never included %q", c)
+				}
+			}
+		})
+	}
+}
+
+func TestRandomStringWithAlphabet(t *testing.T) {
+	const alphabet = "αβγδ🙂"
+	got := util.RandomStringWithAlphabet(500, alphabet)
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != 500 {
+		t.Fatalf("RandomStringWithAlphabet() = %q is not 500 valid characters", got)
+	}
+	for _, c := range alphabet {
+		if !strings.ContainsRune(got, c) {
+			t.Errorf("500 characters never included %q", c)
+		}
+	}
+	if strings.Trim(got, alphabet) != "" {
+		t.Errorf("RandomStringWithAlphabet() = %q has characters outside the alphabet", got)
+	}
+
+	if got = util.RandomStringWithAlphabet(8, "xyz"); len(got) != 8 || strings.Trim(got, "xyz") != "" {
+		t.Errorf("RandomStringWithAlphabet() with an ASCII alphabet = %q", got)
+	}
+}
+
+func TestRandomBytes(t *testing.T) {
+	a, err := util.RandomBytes(32)
+	if err != nil || len(a) != 32 {
+		t.Fatalf("RandomBytes(32) = %d bytes, %v", len(a), err)
+	}
+	b, _ := util.RandomBytes(32)
+	if string(a) == string(b) {
+		t.Error("RandomBytes() returned the same bytes twice")
+	}
+	if _, err = util.RandomBytes(-1); err == nil {
+		t.Error("RandomBytes(-1) succeeded")
+	}
+}
+
+func TestRandomInt(t *testing.T) {
+	seen := map[int64]bool{}
+	for range 1000 {
+		n, err := util.RandomInt(-3, 3)
+		if err != nil {
+			t.Fatalf("RandomInt() error = %v", err)
+		}
+		if n < -3 || n > 3 {
+			t.Fatalf("RandomInt(-3, 3) = %d, out of range", n)
+		}
+		seen[n] = true
+	}
+	if len(seen) != 7 {
+		t.Errorf("RandomInt(-3, 3) produced %d distinct values, want 7", len(seen))
+	}
+
+	if n, err := util.RandomInt(5, 5); err != nil || n != 5 {
+		t.Errorf("RandomInt(5, 5) = %d, %v; want 5", n, err)
+	}
+	if _, err := util.RandomInt(math.MinInt64, math.MaxInt64); err != nil {
+		t.Errorf("RandomInt() over the full range error = %v", err)
+	}
+	if _, err := util.RandomInt(2, 1); err == nil {
+		t.Error("RandomInt(2, 1) succeeded")
+	}
+}
+
+func TestNanoID(t *testing.T) {
+	id := util.NanoID()
+	if len(id) != 21 {
+		t.Fatalf("NanoID() = %q, want 21 characters", id)
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_", r) {
+			t.Fatalf("NanoID() = %q, contains %q outside the URL-safe alphabet", id, r)
+		}
+	}
+	if util.NanoID() == id {
+		t.Error("NanoID() returned the same ID twice")
+	}
+	if got := util.NanoID(10); len(got) != 10 {
+		t.Errorf("NanoID(10) = %q, want 10 characters", got)
+	}
+
+	custom := util.NanoIDWithAlphabet("01", 64)
+	if len(custom) != 64 || strings.Trim(custom, "01") != "" {
+		t.Errorf("NanoIDWithAlphabet(\"01\", 64) = %q", custom)
+	}
+}
@@ -0,0 +1,347 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultLimiterIdleTTL = 10 * time.Minute
+
+// ErrRateLimited is returned by Wait when the wait for a permit would
+// outlast the context's deadline.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Limiter is implemented by the rate limiters returned by NewRateLimiter and
+// NewSlidingWindowLimiter.
+type Limiter interface {
+	// Allow takes a permit if one is available now and reports whether it did.
+	Allow() bool
+
+	// Wait blocks until a permit is available and takes it. It returns
+	// ErrRateLimited without waiting when ctx's deadline would pass first,
+	// and ctx's error when ctx is done while waiting.
+	Wait(ctx context.Context) error
+
+	// Reserve takes the next permit, now or in the future, and returns how
+	// long to wait before acting on it.
+	Reserve() *Reservation
+}
+
+// Reservation is a permit taken in advance by Limiter.Reserve.
+type Reservation struct {
+	at     time.Time
+	now    func() time.Time
+	cancel func()
+	once   sync.Once
+}
+
+// Delay returns how long to wait before acting on the permit, zero when it
+// may be used now.
+func (r *Reservation) Delay() time.Duration {
+	return max(r.at.Sub(r.now()), 0)
+}
+
+// Cancel returns the permit to the limiter for others to use, if it is not
+// due yet. Calling it more than once has no further effect.
+func (r *Reservation) Cancel() {
+	r.once.Do(func() {
+		if r.now().Before(r.at) {
+			r.cancel()
+		}
+	})
+}
+
+// rateLimiterOptions contains configuration for the rate limiters.
+type rateLimiterOptions struct {
+	// now is the time source
+	now func() time.Time
+}
+
+// RateLimiterOption is a function that configures a rate limiter.
+type RateLimiterOption func(*rateLimiterOptions)
+
+// WithRateLimiterClock overrides the time source of a rate limiter.
+func WithRateLimiterClock(now func() time.Time) RateLimiterOption {
+	return func(o *rateLimiterOptions) {
+		o.now = now
+	}
+}
+
+func newRateLimiterOptions(opts []RateLimiterOption) rateLimiterOptions {
+	options := rateLimiterOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// RateLimiter is a token-bucket rate limiter: it grants rate permits per
+// second on average and up to burst at once. A RateLimiter is safe for
+// concurrent use.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a token-bucket limiter granting rate permits per
+// second with bursts of up to burst, starting full. burst is at least one.
+//
+// Example:
+//
+//	limiter := NewRateLimiter(50, 100)
+//	if !limiter.Allow() {
+//	    return ErrTooManyRequests
+//	}
+func NewRateLimiter(rate float64, burst int, opts ...RateLimiterOption) *RateLimiter {
+	options := newRateLimiterOptions(opts)
+	b := float64(max(burst, 1))
+	return &RateLimiter{rate: rate, burst: b, now: options.now, tokens: b, last: options.now()}
+}
+
+// Allow implements Limiter.
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(l.now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait implements Limiter.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return waitReservation(ctx, l.Reserve())
+}
+
+// Reserve implements Limiter. The tokens may go negative, so that permits
+// reserved in advance are granted in order.
+func (l *RateLimiter) Reserve() *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.refill(now)
+	l.tokens--
+
+	at := now
+	if l.tokens < 0 {
+		if l.rate <= 0 {
+			at = now.Add(math.MaxInt64) // never, the bucket does not refill
+		} else {
+			at = now.Add(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+		}
+	}
+	return &Reservation{at: at, now: l.now, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.tokens = min(l.tokens+1, l.burst)
+	}}
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.tokens+elapsed.Seconds()*l.rate, l.burst)
+		l.last = now
+	}
+}
+
+// SlidingWindowLimiter is a rate limiter granting at most limit permits in
+// any window of time, for quotas stated as "N per minute" that must not be
+// exceeded at window boundaries. It remembers the time of each permit in the
+// current window, so memory grows with limit. A SlidingWindowLimiter is safe
+// for concurrent use.
+type SlidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// granted holds the times of the permits in the current window, sorted
+	granted []time.Time
+}
+
+// NewSlidingWindowLimiter returns a limiter granting at most limit permits
+// in any window. limit is at least one.
+//
+// Example:
+//
+//	limiter := NewSlidingWindowLimiter(100, time.Minute)
+func NewSlidingWindowLimiter(limit int, window time.Duration, opts ...RateLimiterOption) *SlidingWindowLimiter {
+	options := newRateLimiterOptions(opts)
+	limit = max(limit, 1)
+	return &SlidingWindowLimiter{limit: limit, window: window, now: options.now, granted: make([]time.Time, 0, limit)}
+}
+
+// Allow implements Limiter.
+func (l *SlidingWindowLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.expire(now)
+	if len(l.granted) >= l.limit {
+		return false
+	}
+	l.granted = append(l.granted, now)
+	return true
+}
+
+// Wait implements Limiter.
+func (l *SlidingWindowLimiter) Wait(ctx context.Context) error {
+	return waitReservation(ctx, l.Reserve())
+}
+
+// Reserve implements Limiter.
+func (l *SlidingWindowLimiter) Reserve() *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.expire(now)
+
+	at := now
+	if n := len(l.granted); n >= l.limit {
+		// The permit frees up when the one limit permits before it leaves
+		// the window.
+		at = l.granted[n-l.limit].Add(l.window)
+	}
+	l.granted = append(l.granted, at)
+	return &Reservation{at: at, now: l.now, cancel: func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if i := slices.Index(l.granted, at); i >= 0 {
+			l.granted = slices.Delete(l.granted, i, i+1)
+		}
+	}}
+}
+
+// expire drops the permits that have left the window.
+func (l *SlidingWindowLimiter) expire(now time.Time) {
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.granted) && !l.granted[i].After(cutoff) {
+		i++
+	}
+	l.granted = slices.Delete(l.granted, 0, i)
+}
+
+// waitReservation waits for r to be due, cancelling it when ctx ends first.
+func waitReservation(ctx context.Context, r *Reservation) error {
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && r.now().Add(delay).After(deadline) {
+		r.Cancel()
+		return ErrRateLimited
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// KeyedLimiter keeps a separate rate limiter per key, such as a client IP,
+// API key or tenant, and forgets the limiters of keys idle for longer than
+// its TTL so memory stays bounded by the active keys. A KeyedLimiter is safe
+// for concurrent use.
+type KeyedLimiter struct {
+	newLimiter func() Limiter
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	limiters  map[string]*keyedLimiterEntry
+	lastSweep time.Time
+}
+
+type keyedLimiterEntry struct {
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+// NewKeyedLimiter returns a KeyedLimiter creating each key's limiter with
+// newLimiter and forgetting keys idle for longer than idleTTL, ten minutes
+// when idleTTL is not positive. Only the clock of opts applies.
+//
+// Example:
+//
+//	perClient := NewKeyedLimiter(func() Limiter { return NewRateLimiter(5, 10) }, time.Hour)
+//	if !perClient.Allow(clientIP) {
+//	    http.Error(w, "too many requests", http.StatusTooManyRequests)
+//	    return
+//	}
+func NewKeyedLimiter(newLimiter func() Limiter, idleTTL time.Duration, opts ...RateLimiterOption) *KeyedLimiter {
+	options := newRateLimiterOptions(opts)
+	if idleTTL <= 0 {
+		idleTTL = defaultLimiterIdleTTL
+	}
+	return &KeyedLimiter{
+		newLimiter: newLimiter,
+		ttl:        idleTTL,
+		now:        options.now,
+		limiters:   make(map[string]*keyedLimiterEntry),
+		lastSweep:  options.now(),
+	}
+}
+
+// Allow calls Allow on key's limiter.
+func (k *KeyedLimiter) Allow(key string) bool {
+	return k.limiter(key).Allow()
+}
+
+// Wait calls Wait on key's limiter.
+func (k *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return k.limiter(key).Wait(ctx)
+}
+
+// Reserve calls Reserve on key's limiter.
+func (k *KeyedLimiter) Reserve(key string) *Reservation {
+	return k.limiter(key).Reserve()
+}
+
+// Len returns the number of keys with a limiter.
+func (k *KeyedLimiter) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// limiter returns key's limiter, creating it if needed, and forgets idle
+// keys at most once per TTL.
+func (k *KeyedLimiter) limiter(key string) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	if now.Sub(k.lastSweep) >= k.ttl {
+		for other, e := range k.limiters {
+			if now.Sub(e.lastUsed) >= k.ttl {
+				delete(k.limiters, other)
+			}
+		}
+		k.lastSweep = now
+	}
+
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &keyedLimiterEntry{limiter: k.newLimiter()}
+		k.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.limiter
+}
@@ -0,0 +1,136 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+// fakeClock is a time source advanced by hand.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestRateLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	limiter := util.NewRateLimiter(2, 3, util.WithRateLimiterClock(clock.Now))
+
+	for i := range 3 {
+		if !limiter.Allow() {
+			t.Fatalf("burst permit %d denied", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Error("permit granted beyond the burst")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if !limiter.Allow() || limiter.Allow() {
+		t.Error("half a second at 2/s should refill exactly one permit")
+	}
+
+	r := limiter.Reserve()
+	if d := r.Delay(); d != 500*time.Millisecond {
+		t.Errorf("Delay = %v, want 500ms", d)
+	}
+	r.Cancel()
+	clock.Advance(500 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Error("a cancelled reservation did not return its permit")
+	}
+
+	clock.Advance(time.Hour)
+	for range 3 {
+		limiter.Allow()
+	}
+	if limiter.Allow() {
+		t.Error("idle time refilled the bucket beyond its burst")
+	}
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	limiter := util.NewSlidingWindowLimiter(2, time.Minute, util.WithRateLimiterClock(clock.Now))
+
+	if !limiter.Allow() {
+		t.Fatal("first permit denied")
+	}
+	clock.Advance(40 * time.Second)
+	if !limiter.Allow() || limiter.Allow() {
+		t.Fatal("window should hold exactly two permits")
+	}
+
+	// The window slides: the first permit leaves it after a minute.
+	clock.Advance(20 * time.Second)
+	r := limiter.Reserve()
+	if d := r.Delay(); d != 0 {
+		t.Errorf("Delay once the first permit expired = %v, want 0", d)
+	}
+	r = limiter.Reserve()
+	if d := r.Delay(); d != 40*time.Second {
+		t.Errorf("Delay = %v, want 40s until the second permit expires", d)
+	}
+	r.Cancel()
+	clock.Advance(40 * time.Second)
+	if !limiter.Allow() {
+		t.Error("a cancelled reservation still held its slot")
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	limiter := util.NewRateLimiter(100, 1)
+	ctx := t.Context()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("Wait with a full bucket = %v", err)
+	}
+	start := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Errorf("Wait returned after %v, want about 10ms", waited)
+	}
+
+	slow := util.NewRateLimiter(0.001, 1)
+	slow.Allow()
+	deadlineCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := slow.Wait(deadlineCtx); !errors.Is(err, util.ErrRateLimited) {
+		t.Errorf("Wait past the deadline = %v, want ErrRateLimited", err)
+	}
+
+	cancelCtx, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	if err := slow.Wait(cancelCtx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait on a cancelled context = %v", err)
+	}
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	keyed := util.NewKeyedLimiter(func() util.Limiter {
+		return util.NewRateLimiter(1, 1, util.WithRateLimiterClock(clock.Now))
+	}, time.Minute, util.WithRateLimiterClock(clock.Now))
+
+	if !keyed.Allow("a") || keyed.Allow("a") {
+		t.Error("key a should get exactly one permit")
+	}
+	if !keyed.Allow("b") {
+		t.Error("key b shares key a's limiter")
+	}
+	if keyed.Len() != 2 {
+		t.Errorf("Len = %d, want 2", keyed.Len())
+	}
+
+	clock.Advance(30 * time.Second)
+	keyed.Allow("b")
+	clock.Advance(40 * time.Second)
+	keyed.Allow("c")
+	if keyed.Len() != 2 {
+		t.Errorf("Len after a went idle = %d, want b and c", keyed.Len())
+	}
+}
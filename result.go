@@ -0,0 +1,114 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Result holds the outcome of one operation, a value or an error, so that
+// per-item outcomes of batch work can be collected in a slice and returned
+// or sent over a channel as one value. In JSON a Result is
+// {"value": ...} or {"error": "message"}.
+//
+// Example:
+//
+//	results := make([]Result[*User], len(ids))
+//	for i, id := range ids {
+//	    results[i] = ResultOf(store.GetUser(ctx, id))
+//	}
+type Result[T any] struct {
+	// Value is the operation's value, meaningful when Err is nil.
+	Value T
+
+	// Err is the operation's error.
+	Err error
+}
+
+// ResultOf returns the Result of an operation returning v and err.
+func ResultOf[T any](v T, err error) Result[T] {
+	return Result[T]{Value: v, Err: err}
+}
+
+// OkResult returns a successful Result holding v.
+func OkResult[T any](v T) Result[T] {
+	return Result[T]{Value: v}
+}
+
+// ErrResult returns a failed Result holding err.
+func ErrResult[T any](err error) Result[T] {
+	return Result[T]{Err: err}
+}
+
+// Get returns the value and error, to hand the outcome back to code using
+// Go's usual returns.
+func (r Result[T]) Get() (T, error) {
+	return r.Value, r.Err
+}
+
+// IsOk reports whether the operation succeeded.
+func (r Result[T]) IsOk() bool {
+	return r.Err == nil
+}
+
+// OrElse returns the value, or def when the operation failed.
+func (r Result[T]) OrElse(def T) T {
+	if r.Err != nil {
+		return def
+	}
+	return r.Value
+}
+
+type resultJSON[T any] struct {
+	Value *T     `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Result[T]) MarshalJSON() ([]byte, error) {
+	if r.Err != nil {
+		return json.Marshal(resultJSON[T]{Error: r.Err.Error()})
+	}
+	return json.Marshal(resultJSON[T]{Value: &r.Value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A decoded error only keeps
+// its message.
+func (r *Result[T]) UnmarshalJSON(data []byte) error {
+	var decoded resultJSON[T]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = Result[T]{}
+	if decoded.Error != "" {
+		r.Err = errors.New(decoded.Error)
+	} else if decoded.Value != nil {
+		r.Value = *decoded.Value
+	}
+	return nil
+}
+
+// MapResult returns the Result of fn applied to the value of r, or r's
+// error when it failed.
+//
+// Example:
+//
+//	names := MapResult(ResultOf(store.GetUser(ctx, id)), func(u *User) (string, error) {
+//	    return u.Name, nil
+//	})
+func MapResult[T, U any](r Result[T], fn func(T) (U, error)) Result[U] {
+	if r.Err != nil {
+		return ErrResult[U](r.Err)
+	}
+	return ResultOf(fn(r.Value))
+}
+
+// SplitResults separates results into their values and errors, where
+// values[i] and errs[i] belong to results[i].
+func SplitResults[T any](results []Result[T]) (values []T, errs []error) {
+	values = make([]T, len(results))
+	errs = make([]error, len(results))
+	for i, r := range results {
+		values[i], errs[i] = r.Value, r.Err
+	}
+	return values, errs
+}
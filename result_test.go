@@ -0,0 +1,60 @@
+package util_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestResult(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	ok := util.ResultOf(strconv.Atoi("12"))
+	if !ok.IsOk() || ok.OrElse(-1) != 12 {
+		t.Fatalf("ResultOf = %+v, want 12", ok)
+	}
+	failed := util.ErrResult[int](errFailed)
+	if failed.IsOk() || failed.OrElse(-1) != -1 {
+		t.Fatalf("ErrResult = %+v", failed)
+	}
+	if _, err := failed.Get(); !errors.Is(err, errFailed) {
+		t.Fatalf("Get error = %v, want %v", err, errFailed)
+	}
+
+	doubled := util.MapResult(ok, func(n int) (string, error) { return strconv.Itoa(n * 2), nil })
+	if v, err := doubled.Get(); err != nil || v != "24" {
+		t.Fatalf("MapResult = %q, %v", v, err)
+	}
+	called := false
+	mapped := util.MapResult(failed, func(int) (string, error) { called = true; return "", nil })
+	if called || !errors.Is(mapped.Err, errFailed) {
+		t.Fatal("MapResult should pass the error through without calling fn")
+	}
+
+	values, errs := util.SplitResults([]util.Result[int]{util.OkResult(1), failed})
+	if values[0] != 1 || errs[0] != nil || !errors.Is(errs[1], errFailed) {
+		t.Fatalf("SplitResults = %v, %v", values, errs)
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	results := []util.Result[int]{util.OkResult(0), util.ErrResult[int](errors.New("not found"))}
+	out, err := json.Marshal(results)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(out) != `[{"value":0},{"error":"not found"}]` {
+		t.Fatalf("Marshal = %s", out)
+	}
+
+	var decoded []util.Result[int]
+	if err = json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !decoded[0].IsOk() || decoded[0].Value != 0 || decoded[1].Err == nil || decoded[1].Err.Error() != "not found" {
+		t.Fatalf("Unmarshal = %+v", decoded)
+	}
+}
@@ -0,0 +1,92 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// redacted is printed in place of a Secret's value.
+const redacted = "[REDACTED]"
+
+// Secret wraps a sensitive value so it cannot be printed, logged or
+// serialized by accident.
+//
+// Every output path renders "[REDACTED]": String, GoString, all fmt verbs
+// (including %v, %+v and %#v inside structs), MarshalJSON, MarshalText and
+// slog (through LogValue), so a Secret passed to the logger as an attribute,
+// inside WithFields or nested in a logged struct never reaches the output.
+// The value is only reachable through Reveal, which makes every use explicit
+// and easy to audit.
+//
+// Secrets can still be loaded: UnmarshalJSON reads the plain value, so a
+// Secret[string] field in a configuration struct decodes as usual.
+//
+// Example:
+//
+//	type Config struct {
+//		DatabaseURL Secret[string] `json:"database_url"`
+//	}
+//
+//	log.Info("loaded config", "config", cfg) // database_url=[REDACTED]
+//	db, err := sql.Open("postgres", cfg.DatabaseURL.Reveal())
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret wraps value in a Secret.
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Reveal returns the wrapped value. Keep the result out of logs and errors.
+func (s Secret[T]) Reveal() T {
+	return s.value
+}
+
+// String implements fmt.Stringer.
+func (s Secret[T]) String() string {
+	return redacted
+}
+
+// GoString implements fmt.GoStringer.
+func (s Secret[T]) GoString() string {
+	return redacted
+}
+
+// Format implements fmt.Formatter so that no verb prints the value.
+func (s Secret[T]) Format(f fmt.State, _ rune) {
+	_, _ = io.WriteString(f, redacted)
+}
+
+// LogValue implements slog.LogValuer.
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Secret[T]) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// secretJSON encodes the wrapped value for SnapshotConfig, which hashes it.
+func (s Secret[T]) secretJSON() ([]byte, error) {
+	return json.Marshal(s.value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding the plain value.
+func (s *Secret[T]) UnmarshalJSON(data []byte) error {
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		// The error can quote the input, so it must not be wrapped.
+		return fmt.Errorf("invalid secret value of type %T", value)
+	}
+	s.value = value
+	return nil
+}
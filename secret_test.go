@@ -0,0 +1,96 @@
+package util_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type secretConfig struct {
+	User     string                `json:"user"`
+	Password util.Secret[string]   `json:"password"`
+	APIKeys  []util.Secret[string] `json:"api_keys"`
+}
+
+func TestSecretRedactsOutput(t *testing.T) {
+	cfg := secretConfig{
+		User:     "svc",
+		Password: util.NewSecret("hunter2"),
+		APIKeys:  []util.Secret[string]{util.NewSecret("key-abc")},
+	}
+	jsonOut, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := map[string]string{
+		"String":   cfg.Password.String(),
+		"%s":       fmt.Sprintf("%s", cfg.Password),
+		"%q":       fmt.Sprintf("%q", cfg.Password),
+		"%x":       fmt.Sprintf("%x", cfg.Password),
+		"%v":       fmt.Sprintf("%v", cfg),
+		"%+v":      fmt.Sprintf("%+v", cfg),
+		"%#v":      fmt.Sprintf("%#v", cfg),
+		"json":     string(jsonOut),
+		"errorf":   fmt.Errorf("connect failed with %v", cfg.Password).Error(),
+		"embedded": fmt.Sprint(&cfg),
+	}
+
+	for name, out := range outputs {
+		if strings.Contains(out, "hunter2") || strings.Contains(out, "key-abc") {
+			t.Errorf("%s output leaks the secret: %s", name, out)
+		}
+		if !strings.Contains(out, "[REDACTED]") {
+			t.Errorf("%s output = %s, want [REDACTED]", name, out)
+		}
+	}
+
+	if cfg.Password.Reveal() != "hunter2" {
+		t.Errorf("Reveal() = %q, want %q", cfg.Password.Reveal(), "hunter2")
+	}
+}
+
+func TestSecretUnmarshalJSON(t *testing.T) {
+	var cfg secretConfig
+	if err := json.Unmarshal([]byte(`{"user":"svc","password":"hunter2","api_keys":["a","b"]}`), &cfg); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if cfg.Password.Reveal() != "hunter2" || len(cfg.APIKeys) != 2 || cfg.APIKeys[1].Reveal() != "b" {
+		t.Errorf("json.Unmarshal() = %+v", cfg)
+	}
+
+	var n util.Secret[int]
+	err := json.Unmarshal([]byte(`"not-a-number-hunter2"`), &n)
+	if err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("json.Unmarshal() error = %v, want an error that does not echo the input", err)
+	}
+}
+
+func TestSecretLogging(t *testing.T) {
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := util.NewLogger(t.Context(), util.WithLogOutput(&buf), util.WithLogFormat(format),
+				util.WithLogNoColor(true))
+			defer logger.Release()
+
+			password := util.NewSecret("hunter2")
+			logger.Info("attr", "password", password)
+			logger.WithField("password", password).Info("field")
+			logger.WithFields(map[string]any{"nested": secretConfig{Password: password}}).Info("fields")
+			logger.Printf("printf %v", password)
+
+			out := buf.String()
+			if strings.Contains(out, "hunter2") {
+				t.Errorf("logger output leaks the secret:\n%s", out)
+			}
+			if strings.Count(out, "[REDACTED]") < 4 {
+				t.Errorf("logger output should redact every occurrence:\n%s", out)
+			}
+		})
+	}
+}
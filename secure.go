@@ -0,0 +1,52 @@
+package util
+
+import (
+	"crypto/subtle"
+	"runtime"
+)
+
+// SecureCompare reports whether a and b are equal in constant time.
+//
+// Use it for every comparison involving secrets: API keys, lookup tokens,
+// CSRF tokens and MACs. Comparing with == or bytes.Equal returns at the first
+// differing byte, letting an attacker recover a secret byte by byte from
+// response timings.
+//
+// The running time depends only on the length of a, so when a is the
+// attacker-supplied value and b the secret, the secret's length is not
+// revealed either. Nil and empty slices compare equal.
+//
+// Example:
+//
+//	if !SecureCompare([]byte(r.Header.Get("X-Api-Key")), expectedKey) {
+//		return errUnauthorized
+//	}
+func SecureCompare(a, b []byte) bool {
+	lengthMatch := subtle.ConstantTimeEq(int32(len(a)), int32(len(b))) //nolint:gosec // slice lengths beyond 2 GiB are not secrets
+	if lengthMatch != 1 {
+		// Still walk a so that the time taken does not depend on len(b).
+		subtle.ConstantTimeCompare(a, a)
+		return false
+	}
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// Zeroize overwrites b with zeros so key material does not linger in memory
+// after use.
+//
+// The write is kept alive so the compiler cannot drop it as a dead store.
+// Copies made elsewhere (string conversions, earlier appends, the garbage
+// collector moving data) are not reached, so keep secrets in a single []byte
+// and zeroize it as soon as it is no longer needed.
+//
+// Example:
+//
+//	dek, err := provider.UnwrapKey(ctx, keyID, wrapped)
+//	if err != nil {
+//		return err
+//	}
+//	defer Zeroize(dek)
+func Zeroize(b []byte) {
+	clear(b)
+	runtime.KeepAlive(b)
+}
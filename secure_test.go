@@ -0,0 +1,47 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSecureCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []byte
+		want bool
+	}{
+		{"equal", []byte("secret-token"), []byte("secret-token"), true},
+		{"different content", []byte("secret-token"), []byte("secret-tokex"), false},
+		{"shorter", []byte("secret"), []byte("secret-token"), false},
+		{"longer", []byte("secret-token"), []byte("secret"), false},
+		{"both empty", []byte{}, []byte{}, true},
+		{"nil and empty", nil, []byte{}, true},
+		{"empty and non-empty", nil, []byte("x"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := util.SecureCompare(tt.a, tt.b); got != tt.want {
+				t.Errorf("SecureCompare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroize(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	util.Zeroize(key)
+
+	if len(key) != 16 {
+		t.Fatalf("Zeroize() changed length to %d", len(key))
+	}
+	for i, b := range key {
+		if b != 0 {
+			t.Fatalf("Zeroize() left byte %d = %#x", i, b)
+		}
+	}
+
+	util.Zeroize(nil)
+}
@@ -0,0 +1,361 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrSelfTestFailed is returned by CryptoSelfTest when a primitive produces
+// an output that differs from its known answer.
+var ErrSelfTestFailed = errors.New("crypto self-test failed")
+
+// aeadKAT is a known-answer test for one AEAD: sealing plaintext under key,
+// nonce and additionalData must produce sealed (ciphertext followed by tag).
+type aeadKAT struct {
+	algo           Algorithm
+	key            string
+	nonce          string
+	plaintext      string
+	additionalData string
+	sealed         string
+}
+
+// aeadKATs returns published test vectors where one exists (NIST GCM test
+// case 2, RFC 8452 C.1, RFC 5297 A.1) and vectors cross-checked against
+// independent implementations for the ChaCha20 variants.
+func aeadKATs() []aeadKAT {
+	return []aeadKAT{
+		{
+			algo:      AlgorithmAESGCM,
+			key:       "00000000000000000000000000000000",
+			nonce:     "000000000000000000000000",
+			plaintext: "00000000000000000000000000000000",
+			sealed:    "0388dace60b6a392f328c2b971b2fe78" + "ab6e47d42cec13bdf53a67b21257bddf",
+		},
+		{
+			algo:           AlgorithmChaCha20Poly1305,
+			key:            "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+			nonce:          "404142434445464748494a4b",
+			plaintext:      hex.EncodeToString([]byte("util self-test")),
+			additionalData: hex.EncodeToString([]byte("header")),
+			sealed:         "28f43b56be6a5e994f826f14b7ad71b775ba2bdb92f68c3cf87e967b8fbd",
+		},
+		{
+			algo:           AlgorithmXChaCha20Poly1305,
+			key:            "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+			nonce:          "404142434445464748494a4b4c4d4e4f5051525354555657",
+			plaintext:      hex.EncodeToString([]byte("util self-test")),
+			additionalData: hex.EncodeToString([]byte("header")),
+			sealed:         "84781a987b8391369d3f03b685da03a9f6de391517fa4ca5ed68ff5c0167",
+		},
+		{
+			algo:   AlgorithmAESGCMSIV,
+			key:    "01000000000000000000000000000000",
+			nonce:  "030000000000000000000000",
+			sealed: "dc20e2d83f25705bb49e439eca56de25",
+		},
+		{
+			algo:           AlgorithmAESSIV,
+			key:            "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			plaintext:      "112233445566778899aabbccddee",
+			additionalData: "101112131415161718191a1b1c1d1e1f2021222324252627",
+			sealed:         "85632d07c6e8f37f950acd320a2ecc93" + "40c02b9690c4dc04daef7f6afe5c",
+		},
+	}
+}
+
+// rfc9500RSAPrimeP and rfc9500RSAPrimeQ are the primes of the testRSA2048 key
+// published in RFC 9500, section 2.1, for use in tests such as these.
+const (
+	rfc9500RSAPrimeP = "dd105702382f232b3681f53791e22617c7bf4e9acb81ed48daf6d6995da3eab6" +
+		"42839aff012d2ea628b90af279fd3e6f7c93cd80f072f01ff2443b3ee8f24ed4" +
+		"69a79613a41bd24020f92fd11059bd1d0f301b5ba7a9d3637ca8d65c1a981541" +
+		"7d8eab734b0b4f3a2c661d9a1a82f3ac734c40530669ab8e473045a58e65539d"
+	rfc9500RSAPrimeQ = "ccf1e5bb90c8e9781ea75bebf10bc252e11eb023a0260f1887552a56863f4a64" +
+		"21e8c600bf523d6cb1b0adbdd65bfee4a88a037e3d1a415e5bb95648da5a0ca2" +
+		"6b54f4a63948522c3d5f89b94a72efff95134d5940ce45758f30898090895658" +
+		"8eef575b3e4bc4c368cfe813ee9c252c2b02e0df91f1aa01938d38685d60ba6f"
+)
+
+// errKnownAnswer reports a primitive whose output differs from its known answer.
+var errKnownAnswer = errors.New("output does not match the known answer")
+
+// primitiveKAT is a known-answer test for a primitive other than the AEADs.
+type primitiveKAT struct {
+	name string
+
+	// approved reports whether the primitive is FIPS 140-3 approved
+	approved bool
+
+	run func() error
+}
+
+// primitiveKATs returns the known-answer tests of the remaining primitives,
+// using published vectors where one exists.
+func primitiveKATs() []primitiveKAT {
+	return []primitiveKAT{
+		{"SHA-256", true, katSHA256},
+		{"HMAC-SHA256", true, katHMACSHA256},
+		{"HKDF-SHA256", true, katHKDFSHA256},
+		{"Argon2id", false, katArgon2id},
+		{"Fernet", true, katFernet},
+		{"JWT HS256", true, katJWTHS256},
+		{"JWT RS256", true, katJWTRS256},
+		{"JWT EdDSA", true, katJWTEdDSA},
+		{"X25519", false, katX25519},
+		{"BLAKE2b lookup token", false, katBLAKE2bLookupToken},
+	}
+}
+
+// CryptoSelfTest runs known-answer tests for every primitive the package
+// ships: each supported AEAD, SHA-256, HMAC-SHA256, HKDF-SHA256, Argon2id
+// password hashing, Fernet, JWT signing with HS256, RS256 and EdDSA, X25519
+// key agreement and BLAKE2b lookup tokens.
+//
+// Call it once at startup, before serving traffic, so a broken build,
+// miscompiled assembly or faulty hardware acceleration is caught before any
+// data is encrypted with it. In FIPS mode (see FIPSMode) algorithms that are
+// not FIPS approved are skipped, since they cannot be used.
+//
+// Returns nil on success, or an error wrapping ErrSelfTestFailed that names
+// every failing primitive.
+//
+// Example:
+//
+//	if err := util.CryptoSelfTest(); err != nil {
+//		log.Fatal("refusing to start", "error", err)
+//	}
+func CryptoSelfTest() error {
+	var errs []error
+	for _, kat := range aeadKATs() {
+		if FIPSMode() && !kat.algo.FIPSApproved() {
+			continue
+		}
+		if err := kat.run(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrSelfTestFailed, kat.algo, err))
+		}
+	}
+	for _, kat := range primitiveKATs() {
+		if FIPSMode() && !kat.approved {
+			continue
+		}
+		if err := kat.run(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrSelfTestFailed, kat.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func katSHA256() error {
+	if got := sha256.Sum256([]byte("abc")); hex.EncodeToString(got[:]) !=
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// katHMACSHA256 checks RFC 4231 test case 2.
+func katHMACSHA256() error {
+	mac := hmac.New(sha256.New, []byte("Jefe"))
+	mac.Write([]byte("what do ya want for nothing?"))
+	if hex.EncodeToString(mac.Sum(nil)) !=
+		"5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843" {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// katHKDFSHA256 checks RFC 5869 test case 1.
+func katHKDFSHA256() error {
+	okm, err := DeriveKey(
+		mustDecodeHex("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+		mustDecodeHex("000102030405060708090a0b0c"),
+		string(mustDecodeHex("f0f1f2f3f4f5f6f7f8f9")),
+		42, //nolint:mnd // length of the RFC 5869 vector
+	)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(okm) != "3cb25f25faacd57a90434f64d0362f2a"+
+		"2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865" {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// katArgon2id verifies a hash produced by the reference argon2 CLI for
+// password "password", salt "somesalt", t=2, m=64 and p=1.
+func katArgon2id() error {
+	ok, err := VerifyPassword("$argon2id$v=19$m=64,t=2,p=1$c29tZXNhbHQ$Bo1ismRVk2qm6+YAYLCmWHDb+j3fjUH3", "password")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// katFernet decrypts the token of the Fernet specification's test vectors.
+func katFernet() error {
+	key, err := DecodeFernetKey("cw_0x689RpI-jtRR7oE8h_eQsKImvJapLeSbXpwF4e4=")
+	if err != nil {
+		return err
+	}
+	plaintext, err := FernetDecrypt(key,
+		"gAAAAAAdwJ6wAAECAwQFBgcICQoLDA0ODy021cpGVWKZ_eEwCGM4BLLF_5CV9dOPmrhuVUPgJobwOz7JcbmrR64jVmpU4IwqDA==",
+		WithFernetClock(func() time.Time { return time.Unix(499162800, 0) }), //nolint:mnd // the token's timestamp
+	)
+	if err != nil {
+		return err
+	}
+	if string(plaintext) != "hello" {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// katJWTHS256 checks the JWS of RFC 7515, appendix A.1.
+func katJWTHS256() error {
+	key, err := base64.RawURLEncoding.DecodeString(
+		"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	if err != nil {
+		return err
+	}
+	return katJWT(key, key,
+		"eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9."+
+			"eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+		"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+}
+
+// katJWTRS256 signs the payload of RFC 7515, appendix A.2, with the RFC 9500
+// test key; the signature was cross-checked with OpenSSL.
+func katJWTRS256() error {
+	p, _ := new(big.Int).SetString(rfc9500RSAPrimeP, 16)
+	q, _ := new(big.Int).SetString(rfc9500RSAPrimeQ, 16)
+	one := big.NewInt(1)
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: new(big.Int).Mul(p, q), E: 65537}, //nolint:mnd // RSA exponent F4
+		Primes:    []*big.Int{p, q},
+	}
+	key.D = new(big.Int).ModInverse(big.NewInt(int64(key.E)), phi)
+	key.Precompute()
+
+	return katJWT(key, &key.PublicKey,
+		"eyJhbGciOiJSUzI1NiJ9."+
+			"eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+		"agEwf_mzrI9JIywOQ5mLUNt8yvGPv_lzXRrNHAAidF7xbdD8vJcI_68xf7l4_4l2qkGDsq8bEJwcLN0MGq9S_xs-V6iwg2xvU6rjK3-l07It"+
+			"G8UExEiJrnNPuHVqgJOiFopAV8axaclAoR-24CeyGyiyImeNwaZsQfO1xbYJLlEiOYItvXDyb2sMGiDy9acV4_DU9krlKPK0banIHOdUHK_R"+
+			"ACBnz87EE0dKLueVjWZkf4LbUCVO4NgSPIdwpCqU4trWFwxitRJW_4lFoQCOQvJgGEwyrQL1SGFledYtVPlWhGaAC43J9BUGPen4jqu-6tGf"+
+			"FdkVe2sDwGJia1McLA")
+}
+
+// katJWTEdDSA checks the JWS of RFC 8037, appendix A.4.
+func katJWTEdDSA() error {
+	seed, err := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	if err != nil {
+		return err
+	}
+	key := ed25519.NewKeyFromSeed(seed)
+	public, _ := key.Public().(ed25519.PublicKey)
+	if base64.RawURLEncoding.EncodeToString(public) != "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo" {
+		return errKnownAnswer
+	}
+	return katJWT(key, public,
+		"eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc",
+		"hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg")
+}
+
+// katJWT signs signingInput with key, compares the signature with the known
+// answer and checks that verifyKey accepts it.
+func katJWT(key, verifyKey any, signingInput, signature string) error {
+	want, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	got, err := jwtSign(key, []byte(signingInput))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, want) {
+		return errKnownAnswer
+	}
+	if !jwtVerifySignature(verifyKey, []byte(signingInput), got) {
+		return errors.New("signature does not verify")
+	}
+	return nil
+}
+
+// katX25519 checks the key agreement of RFC 7748, section 6.1.
+func katX25519() error {
+	priv, err := ecdh.X25519().NewPrivateKey(
+		mustDecodeHex("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"))
+	if err != nil {
+		return err
+	}
+	peer, err := ecdh.X25519().NewPublicKey(
+		mustDecodeHex("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f"))
+	if err != nil {
+		return err
+	}
+	secret, err := priv.ECDH(peer)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(priv.PublicKey().Bytes()) !=
+		"8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a" ||
+		hex.EncodeToString(secret) != "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742" {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// katBLAKE2bLookupToken checks a keyed BLAKE2b-256 lookup token,
+// cross-checked with Python's hashlib.
+func katBLAKE2bLookupToken() error {
+	token := ComputeLookupToken([]byte("test-key-16-bytes-"), "user@example.com", WithLookupHash(LookupHashBLAKE2b))
+	if hex.EncodeToString(token) != "b1cd9ec041465a117b373d3c819b2b0b4ced3db20e438943545421cd89e355fd" {
+		return errKnownAnswer
+	}
+	return nil
+}
+
+// run seals the vector's plaintext, compares it with the known answer and
+// checks that opening it returns the plaintext again.
+func (k aeadKAT) run() error {
+	aead, err := newAEAD(k.algo, mustDecodeHex(k.key))
+	if err != nil {
+		return err
+	}
+
+	nonce, plaintext, ad := mustDecodeHex(k.nonce), mustDecodeHex(k.plaintext), mustDecodeHex(k.additionalData)
+	sealed := aead.Seal(nil, nonce, plaintext, ad)
+	if !bytes.Equal(sealed, mustDecodeHex(k.sealed)) {
+		return errors.New("ciphertext does not match the known answer")
+	}
+	opened, err := aead.Open(nil, nonce, sealed, ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		return errors.New("decryption does not match the known answer")
+	}
+	return nil
+}
+
+// mustDecodeHex decodes a hex literal from the known-answer tables.
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,13 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestCryptoSelfTest(t *testing.T) {
+	if err := util.CryptoSelfTest(); err != nil {
+		t.Fatalf("CryptoSelfTest() error = %v", err)
+	}
+}
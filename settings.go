@@ -0,0 +1,119 @@
+package util
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Settings holds a config struct loaded by LoadEnv that can be reloaded
+// while the service runs, so tuning values such as limits and timeouts
+// change without a restart.
+//
+// Get returns the current values without locking. Reload, called directly
+// or on SIGHUP by WatchSignals, loads them again and notifies the OnChange
+// callbacks when they differ. A failed reload keeps the previous values. A
+// Settings is safe for concurrent use.
+//
+// Example:
+//
+//	type Tuning struct {
+//	    MaxBatch int           `env:"MAX_BATCH,default=100"`
+//	    Timeout  time.Duration `env:"TIMEOUT,default=5s"`
+//	}
+//
+//	settings, err := NewSettings[Tuning](WithEnvPrefix("APP_"))
+//	if err != nil {
+//	    return err
+//	}
+//	settings.OnChange(func(prev, next Tuning) {
+//	    log.Info("tuning changed", "max_batch", next.MaxBatch)
+//	})
+//	go settings.WatchSignals(ctx)
+//
+//	batch := settings.Get().MaxBatch
+type Settings[T any] struct {
+	opts      []EnvLoadOption
+	current   atomic.Pointer[T]
+	mu        sync.Mutex
+	callbacks []func(prev, next T)
+}
+
+// NewSettings loads T with LoadEnv and opts, returning an error when the
+// initial load fails. T must be a struct type.
+func NewSettings[T any](opts ...EnvLoadOption) (*Settings[T], error) {
+	s := &Settings[T]{opts: opts}
+	values, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.current.Store(values)
+	return s, nil
+}
+
+// Get returns the current values. Callers must not modify slices or maps in
+// the result, which are shared.
+func (s *Settings[T]) Get() T {
+	return *s.current.Load()
+}
+
+// OnChange registers fn to be called after each reload that changes the
+// values, with the previous and the new values. Callbacks run in
+// registration order on the goroutine that reloads.
+func (s *Settings[T]) OnChange(fn func(prev, next T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callbacks = append(s.callbacks, fn)
+}
+
+// Reload loads the values again. When they changed, it stores them and calls
+// the OnChange callbacks. On error the previous values stay in effect.
+func (s *Settings[T]) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		return err
+	}
+	prev := s.current.Load()
+	if reflect.DeepEqual(prev, next) {
+		return nil
+	}
+	s.current.Store(next)
+	for _, fn := range s.callbacks {
+		fn(*prev, *next)
+	}
+	return nil
+}
+
+// WatchSignals calls Reload on every SIGHUP until ctx is done, logging
+// failed reloads to the logger in ctx. Run it in its own goroutine.
+func (s *Settings[T]) WatchSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := s.Reload(); err != nil {
+				Log(ctx).WithError(err).Error("failed to reload settings")
+			}
+		}
+	}
+}
+
+func (s *Settings[T]) load() (*T, error) {
+	values := new(T)
+	if err := LoadEnv(values, s.opts...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
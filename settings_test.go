@@ -0,0 +1,112 @@
+package util_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+type tuning struct {
+	MaxBatch int           `env:"MAX_BATCH,default=100"`
+	Timeout  time.Duration `env:"TIMEOUT,default=5s"`
+	Hosts    []string      `env:"HOSTS"`
+}
+
+func TestSettingsReload(t *testing.T) {
+	util.SetEnvForTest(t, "SETTINGS_TEST_MAX_BATCH", "10")
+
+	settings, err := util.NewSettings[tuning](util.WithEnvPrefix("SETTINGS_TEST_"))
+	if err != nil {
+		t.Fatalf("NewSettings: %v", err)
+	}
+	if got := settings.Get(); got.MaxBatch != 10 || got.Timeout != 5*time.Second {
+		t.Fatalf("Get = %+v", got)
+	}
+
+	var changes []int
+	settings.OnChange(func(prev, next tuning) {
+		changes = append(changes, prev.MaxBatch, next.MaxBatch)
+	})
+
+	if err := settings.Reload(); err != nil || len(changes) != 0 {
+		t.Errorf("Reload without changes = %v, callbacks %v", err, changes)
+	}
+
+	util.SetEnvForTest(t, "SETTINGS_TEST_MAX_BATCH", "20")
+	if err := settings.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if got := settings.Get().MaxBatch; got != 20 || len(changes) != 2 || changes[0] != 10 || changes[1] != 20 {
+		t.Errorf("after Reload MaxBatch = %d, callbacks %v", got, changes)
+	}
+
+	util.SetEnvForTest(t, "SETTINGS_TEST_MAX_BATCH", "many")
+	if err := settings.Reload(); err == nil {
+		t.Error("Reload of an invalid value succeeded")
+	}
+	if got := settings.Get().MaxBatch; got != 20 {
+		t.Errorf("after a failed Reload MaxBatch = %d, want the previous 20", got)
+	}
+}
+
+func TestNewSettingsFails(t *testing.T) {
+	util.SetEnvForTest(t, "SETTINGS_BAD_TIMEOUT", "soon")
+	if _, err := util.NewSettings[tuning](util.WithEnvPrefix("SETTINGS_BAD_")); err == nil {
+		t.Error("NewSettings with an invalid value succeeded")
+	}
+}
+
+func TestSettingsWatchSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP cannot be sent on windows")
+	}
+	util.SetEnvForTest(t, "SETTINGS_SIG_MAX_BATCH", "1")
+	settings, err := util.NewSettings[tuning](util.WithEnvPrefix("SETTINGS_SIG_"))
+	if err != nil {
+		t.Fatalf("NewSettings: %v", err)
+	}
+	changed := make(chan int, 1)
+	settings.OnChange(func(_, next tuning) { changed <- next.MaxBatch })
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		settings.WatchSignals(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Keep SIGHUP from terminating the test binary before WatchSignals
+	// subscribes, which it does asynchronously; signal until it reacts.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	util.SetEnvForTest(t, "SETTINGS_SIG_MAX_BATCH", "2")
+	for {
+		if err := self.Signal(syscall.SIGHUP); err != nil {
+			t.Fatalf("signal: %v", err)
+		}
+		select {
+		case got := <-changed:
+			if got != 2 {
+				t.Errorf("MaxBatch after SIGHUP = %d, want 2", got)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	// maxShamirShares is the number of non-zero x coordinates in GF(2^8).
+	maxShamirShares = 255
+	// minShamirThreshold keeps a single share from revealing the secret.
+	minShamirThreshold = 2
+	// gf256Reduce is the low byte of the AES field polynomial x^8+x^4+x^3+x+1.
+	gf256Reduce = 0x1b
+)
+
+// SplitSecret splits secret into n shares using Shamir's secret sharing over
+// GF(2^8), such that any k shares reconstruct it with CombineShares and any
+// k-1 shares reveal nothing about it.
+//
+// Use it to escrow a master key among operators instead of storing it whole:
+// with n=5, k=3 any three operators can restore the key, and two colluding
+// operators learn nothing. Each share is one byte longer than the secret;
+// its first byte is the share's x coordinate.
+//
+// Shares carry no integrity protection. A corrupted share reconstructs a
+// wrong secret without error, so verify the result, for example by
+// decrypting a known payload or comparing a stored hash.
+//
+// Parameters:
+//   - secret: The secret to split, e.g. a 32-byte master key
+//   - n: Number of shares to produce (2 to 255)
+//   - k: Number of shares required to reconstruct (2 to n)
+//
+// Example:
+//
+//	shares, err := SplitSecret(masterKey, 5, 3)
+//	// hand shares[i] to operator i
+//	restored, err := CombineShares([][]byte{shares[0], shares[2], shares[4]})
+func SplitSecret(secret []byte, n, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret cannot be empty")
+	}
+	if n < minShamirThreshold || n > maxShamirShares {
+		return nil, fmt.Errorf("share count must be between %d and %d", minShamirThreshold, maxShamirShares)
+	}
+	if k < minShamirThreshold || k > n {
+		return nil, fmt.Errorf("threshold must be between %d and the share count", minShamirThreshold)
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][0] = byte(i + 1)
+	}
+
+	// One random polynomial of degree k-1 per secret byte, with the secret
+	// byte as its constant term.
+	coefficients := make([]byte, k)
+	defer Zeroize(coefficients)
+	for idx, b := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("failed to generate coefficients: %w", err)
+		}
+		coefficients[0] = b
+
+		for _, share := range shares {
+			share[idx+1] = gf256EvalPolynomial(coefficients, share[0])
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares reconstructs a secret from at least k shares produced by
+// SplitSecret. Passing fewer than k shares yields a wrong secret rather than
+// an error; see SplitSecret.
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < minShamirThreshold {
+		return nil, fmt.Errorf("at least %d shares are required", minShamirThreshold)
+	}
+
+	size := len(shares[0])
+	if size < minShamirThreshold {
+		return nil, errors.New("share is too short")
+	}
+	var seen [maxShamirShares + 1]bool
+	for _, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+		if share[0] == 0 || seen[share[0]] {
+			return nil, errors.New("shares must have distinct, non-zero identifiers")
+		}
+		seen[share[0]] = true
+	}
+
+	// Lagrange interpolation at x=0; in GF(2^8) subtraction is XOR.
+	secret := make([]byte, size-1)
+	for i, share := range shares {
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			basis = gf256Mul(basis, gf256Div(other[0], other[0]^share[0]))
+		}
+		for idx := range secret {
+			secret[idx] ^= gf256Mul(basis, share[idx+1])
+		}
+	}
+	return secret, nil
+}
+
+// gf256EvalPolynomial evaluates the polynomial with the given coefficients
+// (constant term first) at x using Horner's method.
+func gf256EvalPolynomial(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gf256Mul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// gf256Mul multiplies in GF(2^8) without data-dependent branches.
+func gf256Mul(a, b byte) byte {
+	var product byte
+	for range 8 {
+		product ^= a & -(b & 1)
+		b >>= 1
+		a = a<<1 ^ gf256Reduce&-(a>>7)
+	}
+	return product
+}
+
+// gf256Div divides a by the non-zero b, computing b's inverse as b^254.
+func gf256Div(a, b byte) byte {
+	inverse := b
+	for range 6 {
+		inverse = gf256Mul(gf256Mul(inverse, inverse), b)
+	}
+	return gf256Mul(a, gf256Mul(inverse, inverse))
+}
@@ -0,0 +1,92 @@
+package util_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSplitCombineSecret(t *testing.T) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	shares, err := util.SplitSecret(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("SplitSecret() failed: %v", err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("SplitSecret() returned %d shares, want 5", len(shares))
+	}
+
+	tests := []struct {
+		name    string
+		indexes []int
+		want    bool
+	}{
+		{"first three", []int{0, 1, 2}, true},
+		{"last three reordered", []int{4, 2, 3}, true},
+		{"all five", []int{0, 1, 2, 3, 4}, true},
+		{"below threshold", []int{1, 3}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subset := make([][]byte, 0, len(tt.indexes))
+			for _, i := range tt.indexes {
+				subset = append(subset, shares[i])
+			}
+			got, combineErr := util.CombineShares(subset)
+			if combineErr != nil {
+				t.Fatalf("CombineShares() failed: %v", combineErr)
+			}
+			if bytes.Equal(got, secret) != tt.want {
+				t.Errorf("CombineShares() recovered secret = %v, want %v", !tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSecretValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret []byte
+		n, k   int
+	}{
+		{"empty secret", nil, 3, 2},
+		{"threshold of one", []byte("s"), 3, 1},
+		{"threshold above count", []byte("s"), 3, 4},
+		{"too many shares", []byte("s"), 256, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := util.SplitSecret(tt.secret, tt.n, tt.k); err == nil {
+				t.Error("SplitSecret() should fail")
+			}
+		})
+	}
+}
+
+func TestCombineSharesValidation(t *testing.T) {
+	shares, _ := util.SplitSecret([]byte("secret"), 3, 2)
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+	}{
+		{"single share", shares[:1]},
+		{"duplicate share", [][]byte{shares[0], shares[0]}},
+		{"mismatched lengths", [][]byte{shares[0], shares[1][:3]}},
+		{"zero identifier", [][]byte{shares[0], append([]byte{0}, shares[1][1:]...)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := util.CombineShares(tt.shares); err == nil {
+				t.Error("CombineShares() should fail")
+			}
+		})
+	}
+}
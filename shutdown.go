@@ -0,0 +1,243 @@
+package util
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultShutdownHookTimeout = 10 * time.Second
+
+// ErrShutdownHookTimeout is returned for a shutdown hook that did not finish
+// within its timeout.
+var ErrShutdownHookTimeout = errors.New("shutdown hook timed out")
+
+// shutdownOptions contains configuration for a ShutdownManager.
+type shutdownOptions struct {
+	// signals start the shutdown when received
+	signals []os.Signal
+
+	// hookTimeout bounds hooks registered without their own timeout
+	hookTimeout time.Duration
+}
+
+// ShutdownOption is a function that configures a ShutdownManager.
+type ShutdownOption func(*shutdownOptions)
+
+// WithShutdownSignals sets the signals that start the shutdown. The default
+// is SIGINT and SIGTERM.
+func WithShutdownSignals(signals ...os.Signal) ShutdownOption {
+	return func(o *shutdownOptions) {
+		o.signals = signals
+	}
+}
+
+// WithShutdownTimeout sets how long each hook may run unless it was
+// registered with WithHookTimeout. The default is ten seconds.
+func WithShutdownTimeout(timeout time.Duration) ShutdownOption {
+	return func(o *shutdownOptions) {
+		o.hookTimeout = timeout
+	}
+}
+
+// shutdownHook is a registered hook with its configuration.
+type shutdownHook struct {
+	name     string
+	fn       func(context.Context) error
+	priority int
+	timeout  time.Duration
+}
+
+// ShutdownHookOption is a function that configures a hook registered with
+// ShutdownManager.Register.
+type ShutdownHookOption func(*shutdownHook)
+
+// WithHookPriority sets the phase a hook runs in. Phases run in ascending
+// order of priority, and the hooks of a phase run concurrently. The default
+// is 0.
+func WithHookPriority(priority int) ShutdownHookOption {
+	return func(h *shutdownHook) {
+		h.priority = priority
+	}
+}
+
+// WithHookTimeout sets how long the hook may run, overriding the manager's
+// WithShutdownTimeout.
+func WithHookTimeout(timeout time.Duration) ShutdownHookOption {
+	return func(h *shutdownHook) {
+		h.timeout = timeout
+	}
+}
+
+// ShutdownManager coordinates the graceful shutdown of a process. Subsystems
+// register named hooks; on SIGINT or SIGTERM, when the manager's context is
+// done, or when Shutdown is called, the hooks run phase by phase, each
+// bounded by its timeout, with progress logged to the logger in the
+// manager's context. A ShutdownManager is safe for concurrent use.
+//
+// Example:
+//
+//	shutdown := NewShutdownManager(ctx)
+//	shutdown.Register("http server", server.Shutdown)
+//	shutdown.Register("database", func(context.Context) error { return db.Close() },
+//	    WithHookPriority(1))
+//	shutdown.Register("logger", func(context.Context) error { return logFile.Sync() },
+//	    WithHookPriority(2))
+//
+//	go func() {
+//	    if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+//	        shutdown.Shutdown(ctx)
+//	    }
+//	}()
+//	if err := shutdown.Wait(); err != nil {
+//	    os.Exit(1)
+//	}
+type ShutdownManager struct {
+	ctx     context.Context //nolint:containedctx // used by shutdowns the signals trigger
+	options shutdownOptions
+
+	mu    sync.Mutex
+	hooks []shutdownHook
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// NewShutdownManager returns a ShutdownManager listening for the shutdown
+// signals until the shutdown starts. Cancelling ctx starts the shutdown too.
+func NewShutdownManager(ctx context.Context, opts ...ShutdownOption) *ShutdownManager {
+	options := shutdownOptions{
+		signals:     []os.Signal{os.Interrupt, syscall.SIGTERM},
+		hookTimeout: defaultShutdownHookTimeout,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	m := &ShutdownManager{ctx: ctx, options: options, done: make(chan struct{})}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, options.signals...)
+	go m.listen(signals)
+	return m
+}
+
+// Register adds a hook run during the shutdown. The hook's context is done
+// when its timeout elapses; a hook still running then is abandoned and
+// reported as ErrShutdownHookTimeout. Hooks registered once the shutdown
+// has started are not run.
+func (m *ShutdownManager) Register(name string, hook func(context.Context) error, opts ...ShutdownHookOption) {
+	h := shutdownHook{name: name, fn: hook, timeout: m.options.hookTimeout}
+	for _, opt := range opts {
+		opt(&h)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, h)
+}
+
+// Shutdown starts the shutdown, if it has not started yet, and waits for it
+// to finish. It returns the errors of the failed hooks joined. ctx bounds
+// the whole shutdown in addition to the per-hook timeouts.
+func (m *ShutdownManager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		m.err = m.run(ctx)
+		close(m.done)
+	})
+	<-m.done
+	return m.err
+}
+
+// Wait blocks until the shutdown has finished and returns the same error as
+// Shutdown, for the end of main.
+func (m *ShutdownManager) Wait() error {
+	<-m.done
+	return m.err
+}
+
+func (m *ShutdownManager) listen(signals chan os.Signal) {
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		Log(m.ctx).WithField("signal", sig.String()).Info("shutdown signal received")
+	case <-m.ctx.Done():
+	case <-m.done:
+		return
+	}
+	// The hooks still need a live context after m.ctx is cancelled.
+	_ = m.Shutdown(context.WithoutCancel(m.ctx))
+}
+
+func (m *ShutdownManager) run(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := slices.Clone(m.hooks)
+	m.hooks = nil
+	m.mu.Unlock()
+
+	slices.SortStableFunc(hooks, func(a, b shutdownHook) int { return cmp.Compare(a.priority, b.priority) })
+
+	log := Log(m.ctx)
+	log.WithField("hooks", len(hooks)).Info("shutting down")
+	began := time.Now()
+
+	var (
+		errsMu sync.Mutex
+		errs   []error
+	)
+	for start := 0; start < len(hooks); {
+		end := start + 1
+		for end < len(hooks) && hooks[end].priority == hooks[start].priority {
+			end++
+		}
+		var wg sync.WaitGroup
+		for _, h := range hooks[start:end] {
+			wg.Go(func() {
+				hookStart := time.Now()
+				err := runShutdownHook(ctx, h)
+				entry := log.WithField("hook", h.name).WithField("duration", time.Since(hookStart))
+				if err != nil {
+					entry.WithError(err).Error("shutdown hook failed")
+					errsMu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+					errsMu.Unlock()
+					return
+				}
+				entry.Debug("shutdown hook finished")
+			})
+		}
+		wg.Wait()
+		start = end
+	}
+
+	err := errors.Join(errs...)
+	log.WithField("duration", time.Since(began)).WithField("failed", len(errs)).Info("shutdown complete")
+	return err
+}
+
+// runShutdownHook runs h, returning ErrShutdownHookTimeout if it does not
+// return within its timeout.
+func runShutdownHook(ctx context.Context, h shutdownHook) error {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- h.fn(ctx) }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrShutdownHookTimeout, ctx.Err())
+	}
+}
@@ -0,0 +1,122 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestShutdownManagerPhases(t *testing.T) {
+	ctx := t.Context()
+	shutdown := util.NewShutdownManager(ctx)
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return err
+		}
+	}
+	errDB := errors.New("db close failed")
+	shutdown.Register("logger", record("logger", nil), util.WithHookPriority(2))
+	shutdown.Register("db", record("db", errDB), util.WithHookPriority(1))
+	shutdown.Register("server", record("server", nil))
+
+	err := shutdown.Shutdown(ctx)
+	if !errors.Is(err, errDB) {
+		t.Errorf("Shutdown = %v, want the db failure", err)
+	}
+	if !slices.Equal(order, []string{"server", "db", "logger"}) {
+		t.Errorf("hook order = %q", order)
+	}
+
+	if again := shutdown.Shutdown(ctx); !errors.Is(again, errDB) || len(order) != 3 {
+		t.Errorf("second Shutdown = %v and ran %q", again, order)
+	}
+	if waited := shutdown.Wait(); !errors.Is(waited, errDB) {
+		t.Errorf("Wait = %v", waited)
+	}
+}
+
+func TestShutdownManagerHookTimeout(t *testing.T) {
+	ctx := t.Context()
+	shutdown := util.NewShutdownManager(ctx, util.WithShutdownTimeout(10*time.Millisecond))
+
+	release := make(chan struct{})
+	defer close(release)
+	shutdown.Register("stuck", func(context.Context) error {
+		<-release
+		return nil
+	})
+	shutdown.Register("patient", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, util.WithHookTimeout(time.Millisecond))
+
+	err := shutdown.Shutdown(ctx)
+	if !errors.Is(err, util.ErrShutdownHookTimeout) {
+		t.Errorf("Shutdown = %v, want ErrShutdownHookTimeout", err)
+	}
+}
+
+func TestShutdownManagerContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	shutdown := util.NewShutdownManager(ctx)
+
+	ran := make(chan error, 1)
+	shutdown.Register("hook", func(ctx context.Context) error {
+		ran <- ctx.Err()
+		return nil
+	})
+	cancel()
+
+	if err := shutdown.Wait(); err != nil {
+		t.Errorf("Wait = %v", err)
+	}
+	if err := <-ran; err != nil {
+		t.Errorf("hook context was already done: %v", err)
+	}
+}
+
+func TestShutdownManagerSignal(t *testing.T) {
+	// Keep SIGHUP from terminating the test binary before the manager
+	// subscribes, which it does before NewShutdownManager returns.
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+
+	shutdown := util.NewShutdownManager(t.Context(), util.WithShutdownSignals(syscall.SIGHUP))
+	shutdown.Register("hook", func(context.Context) error { return nil })
+
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := self.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- shutdown.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Wait = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SIGHUP did not start the shutdown")
+	}
+}
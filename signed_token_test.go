@@ -0,0 +1,66 @@
+package util_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSignedToken(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	token, err := util.NewSignedToken(secret, []byte("user-42"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewSignedToken() error = %v", err)
+	}
+	payload, err := util.VerifySignedToken(secret, token)
+	if err != nil || string(payload) != "user-42" {
+		t.Fatalf("VerifySignedToken() = %q, %v; want user-42", payload, err)
+	}
+
+	rotated := []byte("fedcba9876543210fedcba9876543210")
+	if _, err = util.VerifySignedToken(rotated, token, secret); err != nil {
+		t.Errorf("VerifySignedToken() with the old secret as previous error = %v", err)
+	}
+	if _, err = util.VerifySignedToken(rotated, token); !errors.Is(err, util.ErrInvalidSignedToken) {
+		t.Errorf("VerifySignedToken() with the wrong secret error = %v, want ErrInvalidSignedToken", err)
+	}
+
+	tampered := []byte(token)
+	tampered[len(tampered)/2] ^= 1
+	if _, err = util.VerifySignedToken(secret, string(tampered)); !errors.Is(err, util.ErrInvalidSignedToken) {
+		t.Errorf("VerifySignedToken() on a tampered token error = %v, want ErrInvalidSignedToken", err)
+	}
+	for _, malformed := range []string{"", "not base64!", "AQ"} {
+		if _, err = util.VerifySignedToken(secret, malformed); !errors.Is(err, util.ErrInvalidSignedToken) {
+			t.Errorf("VerifySignedToken(%q) error = %v, want ErrInvalidSignedToken", malformed, err)
+		}
+	}
+
+	if _, err = util.NewSignedToken(nil, []byte("x"), 0); err == nil {
+		t.Error("NewSignedToken() without a secret succeeded")
+	}
+}
+
+func TestSignedTokenExpiry(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	forever, err := util.NewSignedToken(secret, nil, 0)
+	if err != nil {
+		t.Fatalf("NewSignedToken() error = %v", err)
+	}
+	if payload, verifyErr := util.VerifySignedToken(secret, forever); verifyErr != nil || len(payload) != 0 {
+		t.Errorf("VerifySignedToken() without expiry = %q, %v", payload, verifyErr)
+	}
+
+	short, err := util.NewSignedToken(secret, []byte("x"), time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewSignedToken() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err = util.VerifySignedToken(secret, short); !errors.Is(err, util.ErrSignedTokenExpired) {
+		t.Errorf("VerifySignedToken() after expiry error = %v, want ErrSignedTokenExpired", err)
+	}
+}
@@ -0,0 +1,116 @@
+package util
+
+import "slices"
+
+// Map returns the result of applying fn to each element of s.
+//
+// Example:
+//
+//	ids := Map(users, func(u User) string { return u.ID })
+func Map[T, U any](s []T, fn func(T) U) []U {
+	if s == nil {
+		return nil
+	}
+	out := make([]U, len(s))
+	for i, v := range s {
+		out[i] = fn(v)
+	}
+	return out
+}
+
+// Filter returns the elements of s for which keep returns true, in order.
+// s is not modified.
+//
+// Example:
+//
+//	active := Filter(users, func(u User) bool { return u.Active })
+func Filter[T any](s []T, keep func(T) bool) []T {
+	if s == nil {
+		return nil
+	}
+	out := make([]T, 0, len(s))
+	for _, v := range s {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Reduce folds s into a single value, calling fn with the running result,
+// starting from initial, and each element in order.
+//
+// Example:
+//
+//	total := Reduce(orders, 0, func(sum int, o Order) int { return sum + o.Amount })
+func Reduce[T, R any](s []T, initial R, fn func(R, T) R) R {
+	result := initial
+	for _, v := range s {
+		result = fn(result, v)
+	}
+	return result
+}
+
+// Partition splits s into the elements for which pred returns true and
+// those for which it returns false, each in order.
+//
+// Example:
+//
+//	valid, invalid := Partition(rows, func(r Row) bool { return r.Validate() == nil })
+func Partition[T any](s []T, pred func(T) bool) ([]T, []T) {
+	// Both results share one allocation: matches fill it from the front and
+	// the rest from the back, reversed into order afterwards.
+	buf := make([]T, len(s))
+	front, back := 0, len(s)
+	for _, v := range s {
+		if pred(v) {
+			buf[front] = v
+			front++
+		} else {
+			back--
+			buf[back] = v
+		}
+	}
+	rest := buf[front:]
+	slices.Reverse(rest)
+	return buf[:front:front], rest
+}
+
+// GroupBy groups the elements of s by the key fn returns for them, keeping
+// the order of s within each group.
+//
+// Example:
+//
+//	byTenant := GroupBy(jobs, func(j Job) string { return j.TenantID })
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	groups := make(map[K][]T)
+	for _, v := range s {
+		k := key(v)
+		groups[k] = append(groups[k], v)
+	}
+	return groups
+}
+
+// Chunk splits s into consecutive chunks of size elements; the last chunk
+// holds the remainder. Chunks share s's memory but have their capacity
+// capped, so appending to one never overwrites the next. Chunk panics if
+// size is less than 1.
+//
+// Example:
+//
+//	for _, page := range Chunk(ids, 500) {
+//	    if err := db.DeleteByIDs(ctx, page); err != nil {
+//	        return err
+//	    }
+//	}
+func Chunk[T any](s []T, size int) [][]T {
+	if size < 1 {
+		panic("util: Chunk size must be at least 1")
+	}
+	chunks := make([][]T, 0, (len(s)+size-1)/size)
+	for start := 0; start < len(s); start += size {
+		end := min(start+size, len(s))
+		chunks = append(chunks, s[start:end:end])
+	}
+	return chunks
+}
@@ -0,0 +1,92 @@
+package util_test
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestMap(t *testing.T) {
+	if got := util.Map([]int{1, 2, 3}, strconv.Itoa); !slices.Equal(got, []string{"1", "2", "3"}) {
+		t.Errorf("Map = %q", got)
+	}
+	if got := util.Map(nil, strconv.Itoa); got != nil {
+		t.Errorf("Map(nil) = %q, want nil", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	even := func(n int) bool { return n%2 == 0 }
+	if got := util.Filter([]int{1, 2, 3, 4, 6}, even); !slices.Equal(got, []int{2, 4, 6}) {
+		t.Errorf("Filter = %v", got)
+	}
+	if got := util.Filter(nil, even); got != nil {
+		t.Errorf("Filter(nil) = %v, want nil", got)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := util.Reduce([]int{1, 2, 3, 4}, 10, func(acc, n int) int { return acc + n })
+	if sum != 20 {
+		t.Errorf("Reduce = %d, want 20", sum)
+	}
+	joined := util.Reduce([]int{1, 2}, "", func(acc string, n int) string { return acc + strconv.Itoa(n) })
+	if joined != "12" {
+		t.Errorf("Reduce to a string = %q, want 12", joined)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	input := []int{1, 2, 3, 4, 5, 6, 7}
+	odd, even := util.Partition(input, func(n int) bool { return n%2 == 1 })
+	if !slices.Equal(odd, []int{1, 3, 5, 7}) || !slices.Equal(even, []int{2, 4, 6}) {
+		t.Errorf("Partition = %v, %v", odd, even)
+	}
+
+	// Appending to the first result must not overwrite the second.
+	odd = append(odd, 9)
+	if !slices.Equal(even, []int{2, 4, 6}) || len(odd) != 5 {
+		t.Errorf("append to the first result changed the second: %v", even)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	words := []string{"apple", "bee", "avocado", "cat", "banana"}
+	groups := util.GroupBy(words, func(w string) byte { return w[0] })
+	want := map[byte][]string{
+		'a': {"apple", "avocado"},
+		'b': {"bee", "banana"},
+		'c': {"cat"},
+	}
+	if !maps.EqualFunc(groups, want, slices.Equal) {
+		t.Errorf("GroupBy = %q", groups)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5, 6, 7}
+	chunks := util.Chunk(s, 3)
+	want := [][]int{{1, 2, 3}, {4, 5, 6}, {7}}
+	if !slices.EqualFunc(chunks, want, slices.Equal) {
+		t.Fatalf("Chunk = %v, want %v", chunks, want)
+	}
+
+	_ = append(chunks[0], 99)
+	if s[3] != 4 {
+		t.Error("appending to a chunk overwrote the next one")
+	}
+
+	if got := util.Chunk([]int{}, 2); len(got) != 0 {
+		t.Errorf("Chunk of an empty slice = %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Chunk with size 0 did not panic")
+		}
+	}()
+	util.Chunk(s, 0)
+}
@@ -0,0 +1,213 @@
+package util
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// slidingWindowBinGrowth is the ratio between consecutive value bins,
+// bounding the relative error of percentiles to about 1%.
+const slidingWindowBinGrowth = 1.02
+
+// slidingWindowOptions contains configuration for a SlidingWindow.
+type slidingWindowOptions struct {
+	// now is the time source
+	now func() time.Time
+}
+
+// SlidingWindowOption is a function that configures a SlidingWindow.
+type SlidingWindowOption func(*slidingWindowOptions)
+
+// WithSlidingWindowClock overrides the time source of the window.
+func WithSlidingWindowClock(now func() time.Time) SlidingWindowOption {
+	return func(o *slidingWindowOptions) {
+		o.now = now
+	}
+}
+
+// SlidingWindow aggregates values observed over the last size of time, such
+// as request latencies or failures, into a count, sum, rate and percentiles,
+// for decisions like tripping a circuit or alerting on an error rate. Time
+// is divided into slots of resolution, and a whole slot expires at once, so
+// the window covers between size minus resolution and size. Adding a value
+// takes constant time and the memory used is bounded by the number of slots
+// and of distinct value magnitudes, not by the number of values. A
+// SlidingWindow is safe for concurrent use.
+//
+// Example:
+//
+//	latency := NewSlidingWindow(time.Minute, time.Second)
+//	latency.Add(elapsed.Seconds())
+//	if latency.Percentile(99) > 0.5 {
+//	    Log(ctx).WithField("rate", latency.Rate()).Warn("p99 latency above 500ms")
+//	}
+type SlidingWindow struct {
+	size       time.Duration
+	resolution time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	slots []windowSlot
+}
+
+// windowSlot aggregates the values of one slot of time.
+type windowSlot struct {
+	// epoch is the slot's start divided by the resolution
+	epoch    int64
+	count    uint64
+	sum      float64
+	min, max float64
+	// bins counts the values by magnitude, the key being the exponent of
+	// slidingWindowBinGrowth below the value, or math.MinInt for values
+	// that are not positive
+	bins map[int]uint64
+}
+
+// NewSlidingWindow returns an empty SlidingWindow covering size of time in
+// slots of resolution; resolution defaults to a tenth of size when not
+// positive, and is at most size.
+func NewSlidingWindow(size, resolution time.Duration, opts ...SlidingWindowOption) *SlidingWindow {
+	options := slidingWindowOptions{now: time.Now}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	size = max(size, 1)
+	if resolution <= 0 {
+		resolution = max(size/10, 1) //nolint:mnd // a tenth of the window
+	}
+	resolution = min(resolution, size)
+	slots := int((size + resolution - 1) / resolution)
+	return &SlidingWindow{
+		size:       size,
+		resolution: resolution,
+		now:        options.now,
+		slots:      make([]windowSlot, slots),
+	}
+}
+
+// Add records an observation of v.
+func (w *SlidingWindow) Add(v float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	epoch := w.epoch()
+	slot := &w.slots[epoch%int64(len(w.slots))]
+	if slot.epoch != epoch || slot.bins == nil {
+		bins := slot.bins
+		if bins == nil {
+			bins = make(map[int]uint64)
+		}
+		clear(bins)
+		*slot = windowSlot{epoch: epoch, min: v, max: v, bins: bins}
+	}
+	slot.count++
+	slot.sum += v
+	slot.min = min(slot.min, v)
+	slot.max = max(slot.max, v)
+	slot.bins[windowBin(v)]++
+}
+
+// Inc records an observation of 1, for counting events.
+func (w *SlidingWindow) Inc() {
+	w.Add(1)
+}
+
+// Count returns the number of observations in the window.
+func (w *SlidingWindow) Count() uint64 {
+	var count uint64
+	w.each(func(slot *windowSlot) { count += slot.count })
+	return count
+}
+
+// Sum returns the sum of the observations in the window.
+func (w *SlidingWindow) Sum() float64 {
+	var sum float64
+	w.each(func(slot *windowSlot) { sum += slot.sum })
+	return sum
+}
+
+// Mean returns the mean of the observations in the window, or 0 when there
+// are none.
+func (w *SlidingWindow) Mean() float64 {
+	var count uint64
+	var sum float64
+	w.each(func(slot *windowSlot) {
+		count += slot.count
+		sum += slot.sum
+	})
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// Rate returns the number of observations per second over the window size.
+func (w *SlidingWindow) Rate() float64 {
+	return float64(w.Count()) / w.size.Seconds()
+}
+
+// Percentile estimates the p-th percentile, for p between 0 and 100, of the
+// observations in the window, within about 1% for positive values; values
+// that are not positive are reported as the smallest one observed. The 0th
+// and 100th percentiles are the exact minimum and maximum. It returns 0
+// when the window is empty.
+func (w *SlidingWindow) Percentile(p float64) float64 {
+	bins := make(map[int]uint64)
+	var count uint64
+	lowest, highest := math.Inf(1), math.Inf(-1)
+	w.each(func(slot *windowSlot) {
+		for bin, n := range slot.bins {
+			bins[bin] += n
+		}
+		count += slot.count
+		lowest = min(lowest, slot.min)
+		highest = max(highest, slot.max)
+	})
+	switch {
+	case count == 0:
+		return 0
+	case p <= 0:
+		return lowest
+	case p >= 100: //nolint:mnd // percent
+		return highest
+	}
+
+	rank := max(uint64(math.Ceil(p/100*float64(count))), 1) //nolint:mnd // percent
+	var seen uint64
+	for _, bin := range slices.Sorted(maps.Keys(bins)) {
+		seen += bins[bin]
+		if seen >= rank {
+			if bin == math.MinInt {
+				return lowest
+			}
+			mid := math.Pow(slidingWindowBinGrowth, float64(bin)+0.5) //nolint:mnd // bin midpoint
+			return min(max(mid, lowest), highest)
+		}
+	}
+	return highest
+}
+
+// each calls fn with every slot in the window.
+func (w *SlidingWindow) each(fn func(slot *windowSlot)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	oldest := w.epoch() - int64(len(w.slots)) + 1
+	for i := range w.slots {
+		if slot := &w.slots[i]; slot.bins != nil && slot.epoch >= oldest {
+			fn(slot)
+		}
+	}
+}
+
+func (w *SlidingWindow) epoch() int64 {
+	return w.now().UnixNano() / int64(w.resolution)
+}
+
+func windowBin(v float64) int {
+	if !(v > 0) { // also NaN
+		return math.MinInt
+	}
+	return int(math.Floor(math.Log(v) / math.Log(slidingWindowBinGrowth)))
+}
@@ -0,0 +1,86 @@
+package util_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSlidingWindowExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	w := util.NewSlidingWindow(10*time.Second, time.Second, util.WithSlidingWindowClock(clock.Now))
+
+	for range 5 {
+		w.Inc()
+	}
+	clock.Advance(5 * time.Second)
+	w.Add(10)
+	if w.Count() != 6 || w.Sum() != 15 || w.Mean() != 2.5 {
+		t.Fatalf("Count, Sum, Mean = %d, %v, %v; want 6, 15, 2.5", w.Count(), w.Sum(), w.Mean())
+	}
+	if got := w.Rate(); got != 0.6 {
+		t.Fatalf("Rate = %v, want 0.6", got)
+	}
+
+	clock.Advance(5 * time.Second)
+	if w.Count() != 1 || w.Sum() != 10 {
+		t.Fatalf("after the first slot expired: Count, Sum = %d, %v; want 1, 10", w.Count(), w.Sum())
+	}
+
+	clock.Advance(time.Hour)
+	if w.Count() != 0 || w.Mean() != 0 || w.Percentile(50) != 0 {
+		t.Fatal("the window is not empty after every slot expired")
+	}
+	w.Add(3)
+	if w.Count() != 1 || w.Sum() != 3 {
+		t.Fatalf("a reused slot kept old values: Count, Sum = %d, %v", w.Count(), w.Sum())
+	}
+}
+
+func TestSlidingWindowPercentile(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	w := util.NewSlidingWindow(time.Minute, 0, util.WithSlidingWindowClock(clock.Now))
+	for i := 1; i <= 1000; i++ {
+		w.Add(float64(i))
+		if i%100 == 0 {
+			clock.Advance(time.Second)
+		}
+	}
+
+	for p, want := range map[float64]float64{50: 500, 90: 900, 99: 990} {
+		if got := w.Percentile(p); math.Abs(got-want)/want > 0.02 {
+			t.Errorf("Percentile(%v) = %v, want about %v", p, got, want)
+		}
+	}
+	if got := w.Percentile(0); got != 1 {
+		t.Errorf("Percentile(0) = %v, want the minimum 1", got)
+	}
+	if got := w.Percentile(100); got != 1000 {
+		t.Errorf("Percentile(100) = %v, want the maximum 1000", got)
+	}
+
+	w.Add(0)
+	w.Add(-5)
+	if got := w.Percentile(0); got != -5 {
+		t.Errorf("Percentile(0) with non-positive values = %v, want -5", got)
+	}
+}
+
+func TestSlidingWindowConcurrentAdd(t *testing.T) {
+	w := util.NewSlidingWindow(time.Hour, time.Minute)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				w.Inc()
+			}
+		})
+	}
+	wg.Wait()
+	if w.Count() != 8000 {
+		t.Fatalf("Count = %d, want 8000", w.Count())
+	}
+}
@@ -0,0 +1,162 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	snowflakeIDBits          = 63
+	defaultSnowflakeNodeBits = 10
+	defaultSnowflakeSeqBits  = 12
+	// defaultSnowflakeEpochMs is 2024-01-01T00:00:00Z in Unix milliseconds.
+	defaultSnowflakeEpochMs = 1704067200000
+)
+
+// ErrSnowflakeExhausted is returned when a Snowflake's timestamp no longer
+// fits its bit layout.
+var ErrSnowflakeExhausted = errors.New("snowflake timestamp bits exhausted")
+
+// snowflakeOptions contains configuration for a Snowflake generator.
+type snowflakeOptions struct {
+	// epoch is the zero point of the timestamp
+	epoch time.Time
+
+	// nodeBits and seqBits size the node and sequence fields
+	nodeBits uint
+	seqBits  uint
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// SnowflakeOption is a function that configures NewSnowflake.
+type SnowflakeOption func(*snowflakeOptions)
+
+// WithSnowflakeEpoch sets the time the timestamp counts from. The default is
+// 2024-01-01 UTC. Every generator of a system must use the same epoch.
+func WithSnowflakeEpoch(epoch time.Time) SnowflakeOption {
+	return func(o *snowflakeOptions) {
+		o.epoch = epoch
+	}
+}
+
+// WithSnowflakeBits sets the number of bits for the node ID and the
+// per-millisecond sequence; the timestamp gets the rest of the 63 bits. The
+// default is 10 node bits (1024 nodes) and 12 sequence bits (4096 IDs per
+// millisecond per node), leaving 41 timestamp bits (about 69 years).
+func WithSnowflakeBits(nodeBits, seqBits uint) SnowflakeOption {
+	return func(o *snowflakeOptions) {
+		o.nodeBits, o.seqBits = nodeBits, seqBits
+	}
+}
+
+// WithSnowflakeClock overrides the time source of the generator.
+func WithSnowflakeClock(now func() time.Time) SnowflakeOption {
+	return func(o *snowflakeOptions) {
+		o.now = now
+	}
+}
+
+// Snowflake generates 63-bit, roughly time-sortable integer IDs made of a
+// millisecond timestamp, a node ID and a sequence, for systems that need
+// numeric keys. IDs are unique as long as every concurrently running
+// generator has its own node ID. A Snowflake is safe for concurrent use.
+type Snowflake struct {
+	options  snowflakeOptions
+	node     int64
+	maxSeq   int64
+	maxTime  int64
+	mu       sync.Mutex
+	lastTime int64
+	seq      int64
+}
+
+// NewSnowflake creates a generator for nodeID, which must fit the node bits
+// (0 to 1023 by default). See SnowflakeNodeID for deriving it from the host.
+//
+// Example:
+//
+//	ids, err := NewSnowflake(SnowflakeNodeID(10))
+//	id, err := ids.Next()
+func NewSnowflake(nodeID int64, opts ...SnowflakeOption) (*Snowflake, error) {
+	options := snowflakeOptions{
+		epoch:    time.UnixMilli(defaultSnowflakeEpochMs),
+		nodeBits: defaultSnowflakeNodeBits,
+		seqBits:  defaultSnowflakeSeqBits,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.nodeBits+options.seqBits >= snowflakeIDBits {
+		return nil, errors.New("snowflake node and sequence bits leave no room for the timestamp")
+	}
+	if maxNode := int64(1)<<options.nodeBits - 1; nodeID < 0 || nodeID > maxNode {
+		return nil, fmt.Errorf("snowflake node ID %d is outside 0 to %d", nodeID, maxNode)
+	}
+
+	timeBits := snowflakeIDBits - options.nodeBits - options.seqBits
+	return &Snowflake{
+		options:  options,
+		node:     nodeID,
+		maxSeq:   int64(1)<<options.seqBits - 1,
+		maxTime:  int64(1)<<timeBits - 1,
+		lastTime: -1,
+	}, nil
+}
+
+// Next returns a new ID, greater than every ID this generator returned before.
+//
+// When the sequence of the current millisecond is used up, or the clock
+// steps backwards, Next keeps counting from the last timestamp it used
+// instead of waiting or failing, borrowing from the next milliseconds.
+// Returns ErrSnowflakeExhausted once the timestamp outgrows its bits.
+func (s *Snowflake) Next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.options.now().Sub(s.options.epoch).Milliseconds()
+	switch {
+	case now > s.lastTime:
+		s.lastTime, s.seq = now, 0
+	case s.seq < s.maxSeq:
+		s.seq++
+	default:
+		s.lastTime, s.seq = s.lastTime+1, 0
+	}
+
+	if s.lastTime < 0 || s.lastTime > s.maxTime {
+		return 0, ErrSnowflakeExhausted
+	}
+	return s.lastTime<<(s.options.nodeBits+s.options.seqBits) | s.node<<s.options.seqBits | s.seq, nil
+}
+
+// Decompose splits an ID of this generator's layout into its creation time,
+// node ID and sequence.
+func (s *Snowflake) Decompose(id int64) (time.Time, int64, int64) {
+	seq := id & s.maxSeq
+	node := id >> s.options.seqBits & (int64(1)<<s.options.nodeBits - 1)
+	ms := id >> (s.options.nodeBits + s.options.seqBits)
+	return s.options.epoch.Add(time.Duration(ms) * time.Millisecond), node, seq
+}
+
+// SnowflakeNodeID derives a node ID of nodeBits bits from the host's MAC
+// address, or its local IP when there is none, for deployments without a
+// coordinator handing out node IDs.
+//
+// Distinct hosts can still derive the same ID; where collisions matter,
+// assign node IDs explicitly, e.g. from a StatefulSet ordinal.
+func SnowflakeNodeID(nodeBits uint) int64 {
+	identity := GetMacAddress()
+	if identity == "" {
+		identity = GetLocalIP()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(identity))
+	return int64(h.Sum64() & (uint64(1)<<nodeBits - 1)) //nolint:gosec // masked to nodeBits < 63
+}
@@ -0,0 +1,101 @@
+package util_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSnowflake(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	ids, err := util.NewSnowflake(7, util.WithSnowflakeClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewSnowflake() error = %v", err)
+	}
+
+	first, err := ids.Next()
+	if err != nil || first <= 0 {
+		t.Fatalf("Next() = %d, %v", first, err)
+	}
+	created, node, seq := ids.Decompose(first)
+	if !created.Equal(now) || node != 7 || seq != 0 {
+		t.Errorf("Decompose() = %v, %d, %d; want %v, 7, 0", created, node, seq, now)
+	}
+
+	// Exhausting the sequence of a millisecond, or a clock stepping back,
+	// must not produce duplicates or smaller IDs.
+	previous := first
+	for i := range 5000 {
+		if i == 2500 {
+			now = now.Add(-time.Second)
+		}
+		next, nextErr := ids.Next()
+		if nextErr != nil || next <= previous {
+			t.Fatalf("Next() = %d, %v after %d", next, nextErr, previous)
+		}
+		previous = next
+	}
+}
+
+func TestSnowflakeLayout(t *testing.T) {
+	epoch := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := epoch.Add(90 * time.Millisecond)
+	ids, err := util.NewSnowflake(3,
+		util.WithSnowflakeEpoch(epoch), util.WithSnowflakeBits(4, 8),
+		util.WithSnowflakeClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewSnowflake() error = %v", err)
+	}
+	id, _ := ids.Next()
+	if want := int64(90)<<12 | 3<<8; id != want {
+		t.Errorf("Next() = %d, want %d", id, want)
+	}
+
+	now = epoch.Add(-time.Millisecond)
+	fresh, _ := util.NewSnowflake(3, util.WithSnowflakeEpoch(epoch), util.WithSnowflakeClock(func() time.Time { return now }))
+	if _, err = fresh.Next(); !errors.Is(err, util.ErrSnowflakeExhausted) {
+		t.Errorf("Next() before the epoch error = %v, want ErrSnowflakeExhausted", err)
+	}
+}
+
+func TestNewSnowflakeValidation(t *testing.T) {
+	if _, err := util.NewSnowflake(1024); err == nil {
+		t.Error("NewSnowflake() accepted a node ID wider than 10 bits")
+	}
+	if _, err := util.NewSnowflake(-1); err == nil {
+		t.Error("NewSnowflake() accepted a negative node ID")
+	}
+	if _, err := util.NewSnowflake(0, util.WithSnowflakeBits(40, 23)); err == nil {
+		t.Error("NewSnowflake() accepted a layout without timestamp bits")
+	}
+	if node := util.SnowflakeNodeID(10); node < 0 || node >= 1024 {
+		t.Errorf("SnowflakeNodeID(10) = %d, want 0 to 1023", node)
+	}
+}
+
+func TestSnowflakeConcurrent(t *testing.T) {
+	ids, err := util.NewSnowflake(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				id, nextErr := ids.Next()
+				mu.Lock()
+				if nextErr != nil || seen[id] {
+					t.Errorf("Next() = %d, %v (duplicate: %v)", id, nextErr, seen[id])
+				}
+				seen[id] = true
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+}
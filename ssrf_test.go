@@ -0,0 +1,91 @@
+package util_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestSafeDialer(t *testing.T) {
+	control, err := util.SafeDialer(true, "198.51.100.0/24")
+	if err != nil {
+		t.Fatalf("SafeDialer() error = %v", err)
+	}
+
+	for address, allowed := range map[string]bool{
+		"93.184.216.34:443":     true,
+		"[2001:4860::8888]:443": true,
+		"127.0.0.1:80":          false,
+		"[::1]:80":              false,
+		"169.254.169.254:80":    false,
+		"[fe80::1]:80":          false,
+		"0.0.0.0:80":            false,
+		"10.0.0.1:80":           false,
+		"[::ffff:10.0.0.1]:80":  false,
+		"198.51.100.7:80":       false,
+		"224.0.0.1:80":          false,
+	} {
+		err = control("tcp", address, nil)
+		if allowed && err != nil {
+			t.Errorf("control(%s) error = %v, want allowed", address, err)
+		}
+		if !allowed && !errors.Is(err, util.ErrForbiddenAddress) {
+			t.Errorf("control(%s) error = %v, want ErrForbiddenAddress", address, err)
+		}
+	}
+
+	permissive, _ := util.SafeDialer(false)
+	if err = permissive("tcp", "10.0.0.1:80", nil); err != nil {
+		t.Errorf("control() with private addresses allowed error = %v", err)
+	}
+	if _, err = util.SafeDialer(true, "bogus"); err == nil {
+		t.Error("SafeDialer() accepted an invalid CIDR")
+	}
+}
+
+func TestSafeDialerBlocksRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	control, _ := util.SafeDialer(true)
+	dialer := &net.Dialer{Timeout: time.Second, Control: control}
+	client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+
+	resp, err := client.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, util.ErrForbiddenAddress) {
+		t.Errorf("GET %s through SafeDialer error = %v, want ErrForbiddenAddress", srv.URL, err)
+	}
+}
+
+func TestValidateOutboundURL(t *testing.T) {
+	for rawURL, wantForbidden := range map[string]bool{
+		"http://127.0.0.1/admin":                  true,
+		"http://[::1]:8080/":                      true,
+		"http://169.254.169.254/latest/meta-data": true,
+		"https://10.1.2.3/":                       true,
+		"http://localhost/":                       true,
+		"https://93.184.216.34/":                  false,
+	} {
+		err := util.ValidateOutboundURL(t.Context(), rawURL)
+		if got := errors.Is(err, util.ErrForbiddenAddress); got != wantForbidden {
+			t.Errorf("ValidateOutboundURL(%q) error = %v, want forbidden %v", rawURL, err, wantForbidden)
+		}
+	}
+
+	for _, rawURL := range []string{"file:///etc/passwd", "gopher://example.com/", "http://", "http://user:pw@93.184.216.34/"} {
+		if err := util.ValidateOutboundURL(t.Context(), rawURL); err == nil {
+			t.Errorf("ValidateOutboundURL(%q) succeeded", rawURL)
+		}
+	}
+	if err := util.ValidateOutboundURL(t.Context(), "https://93.184.216.34/", "93.184.216.0/24"); !errors.Is(err, util.ErrForbiddenAddress) {
+		t.Errorf("ValidateOutboundURL() with an extra deny range error = %v, want ErrForbiddenAddress", err)
+	}
+}
@@ -0,0 +1,348 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStartupStepTimeout = 30 * time.Second
+	defaultStartupRetryDelay  = 100 * time.Millisecond
+)
+
+// ErrStartupDependency is returned by Startup.Run for a step depending on
+// an unregistered step, or for dependencies forming a cycle.
+var ErrStartupDependency = errors.New("invalid startup dependency")
+
+// StepStatus is the state of a startup step.
+type StepStatus string
+
+const (
+	// StepPending means the step has not started.
+	StepPending StepStatus = "pending"
+	// StepRunning means the step is running or waiting to retry.
+	StepRunning StepStatus = "running"
+	// StepReady means the step succeeded.
+	StepReady StepStatus = "ready"
+	// StepFailed means the step failed, retries included.
+	StepFailed StepStatus = "failed"
+	// StepSkipped means the step did not run because a dependency failed.
+	StepSkipped StepStatus = "skipped"
+)
+
+// startupOptions contains configuration for a Startup.
+type startupOptions struct {
+	// stepTimeout bounds steps registered without their own timeout
+	stepTimeout time.Duration
+}
+
+// StartupOption is a function that configures a Startup.
+type StartupOption func(*startupOptions)
+
+// WithStartupTimeout sets how long each attempt of a step may run unless it
+// was registered with WithStepTimeout. The default is thirty seconds.
+func WithStartupTimeout(timeout time.Duration) StartupOption {
+	return func(o *startupOptions) {
+		o.stepTimeout = timeout
+	}
+}
+
+// startupStep is a registered step with its configuration.
+type startupStep struct {
+	name       string
+	fn         func(context.Context) error
+	dependsOn  []string
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+// StartupStepOption is a function that configures a step registered with
+// Startup.Register.
+type StartupStepOption func(*startupStep)
+
+// WithStepDependsOn makes the step run only after the named steps
+// succeeded; it is skipped when one of them fails.
+func WithStepDependsOn(names ...string) StartupStepOption {
+	return func(s *startupStep) {
+		s.dependsOn = append(s.dependsOn, names...)
+	}
+}
+
+// WithStepTimeout sets how long each attempt of the step may run,
+// overriding the Startup's WithStartupTimeout.
+func WithStepTimeout(timeout time.Duration) StartupStepOption {
+	return func(s *startupStep) {
+		s.timeout = timeout
+	}
+}
+
+// WithStepRetries retries the step up to retries more times when it fails,
+// for dependencies that may still be coming up. The first retry waits
+// delay, 100ms when not positive, and each following one twice as long, up
+// to 8 times delay, with 10% jitter.
+func WithStepRetries(retries int, delay time.Duration) StartupStepOption {
+	return func(s *startupStep) {
+		s.retries = retries
+		s.retryDelay = delay
+	}
+}
+
+// StartupReport is the state of a Startup, for readiness checks.
+type StartupReport struct {
+	// Ready is true once every step succeeded.
+	Ready bool `json:"ready"`
+
+	// Steps are the steps in registration order.
+	Steps []StartupStepReport `json:"steps"`
+}
+
+// StartupStepReport is the state of a startup step.
+type StartupStepReport struct {
+	Name     string        `json:"name"`
+	Status   StepStatus    `json:"status"`
+	Attempts int           `json:"attempts,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Startup initializes the components of a process in dependency order, the
+// counterpart of ShutdownManager. Components register named init steps with
+// the steps they depend on; Run starts each step once its dependencies are
+// ready, running independent steps concurrently, bounds each attempt by a
+// timeout, retries failing steps as configured, and logs progress to the
+// logger in the Startup's context. Its report, also served by ServeHTTP,
+// tells a readiness probe which steps are done. A Startup is safe for
+// concurrent use.
+//
+// Example:
+//
+//	startup := NewStartup(ctx)
+//	startup.Register("config", loadConfig)
+//	startup.Register("database", connectDB, WithStepDependsOn("config"),
+//	    WithStepRetries(5, time.Second))
+//	startup.Register("cache", warmCache, WithStepDependsOn("database"))
+//	mux.Handle("GET /readyz", startup)
+//
+//	if err := startup.Run(ctx); err != nil {
+//	    Log(ctx).WithError(err).Fatal("startup failed")
+//	}
+type Startup struct {
+	ctx     context.Context //nolint:containedctx // carries the logger for step logging
+	options startupOptions
+
+	mu      sync.Mutex
+	steps   []startupStep
+	reports map[string]*StartupStepReport
+}
+
+// NewStartup returns a Startup without steps.
+func NewStartup(ctx context.Context, opts ...StartupOption) *Startup {
+	options := startupOptions{stepTimeout: defaultStartupStepTimeout}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Startup{ctx: ctx, options: options, reports: make(map[string]*StartupStepReport)}
+}
+
+// Register adds a step called name, run by Run. It panics when a step with
+// the same name is already registered.
+func (s *Startup) Register(name string, init func(context.Context) error, opts ...StartupStepOption) {
+	step := startupStep{name: name, fn: init, timeout: s.options.stepTimeout}
+	for _, opt := range opts {
+		opt(&step)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.reports[name]; ok {
+		panic("util.Startup: step " + name + " is already registered")
+	}
+	s.steps = append(s.steps, step)
+	s.reports[name] = &StartupStepReport{Name: name, Status: StepPending}
+}
+
+// Run runs the registered steps, each once its dependencies are ready, and
+// waits for them. It returns the errors of the failed steps joined, or an
+// error wrapping ErrStartupDependency without running anything when a
+// dependency is unknown or cyclic. ctx bounds the whole startup.
+func (s *Startup) Run(ctx context.Context) error {
+	s.mu.Lock()
+	steps := slices.Clone(s.steps)
+	s.mu.Unlock()
+	if err := checkStartupDependencies(steps); err != nil {
+		return err
+	}
+
+	log := Log(s.ctx)
+	log.WithField("steps", len(steps)).Info("starting up")
+	began := time.Now()
+
+	done := make(map[string]chan struct{}, len(steps))
+	for _, step := range steps {
+		done[step.name] = make(chan struct{})
+	}
+	var (
+		errsMu sync.Mutex
+		errs   []error
+	)
+	var wg sync.WaitGroup
+	for _, step := range steps {
+		wg.Go(func() {
+			defer close(done[step.name])
+			for _, dep := range step.dependsOn {
+				<-done[dep]
+				if s.status(dep) != StepReady {
+					s.update(step.name, func(r *StartupStepReport) { r.Status = StepSkipped })
+					log.WithField("step", step.name).WithField("dependency", dep).Warn("startup step skipped")
+					return
+				}
+			}
+			if err := s.runStep(ctx, step); err != nil {
+				errsMu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+				errsMu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	log.WithField("duration", time.Since(began)).WithField("failed", len(errs)).Info("startup complete")
+	return err
+}
+
+// Report returns the state of every step.
+func (s *Startup) Report() StartupReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report := StartupReport{Ready: true, Steps: make([]StartupStepReport, 0, len(s.steps))}
+	for _, step := range s.steps {
+		r := *s.reports[step.name]
+		report.Ready = report.Ready && r.Status == StepReady
+		report.Steps = append(report.Steps, r)
+	}
+	return report
+}
+
+// Ready reports whether every step succeeded.
+func (s *Startup) Ready() bool {
+	return s.Report().Ready
+}
+
+// ServeHTTP serves the Report as JSON, with status 200 once ready and 503
+// before, so a Startup can be mounted as a readiness endpoint.
+func (s *Startup) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	report := s.Report()
+	w.Header().Set("Content-Type", "application/json")
+	if report.Ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// runStep runs step with its retries, recording its progress.
+func (s *Startup) runStep(ctx context.Context, step startupStep) error {
+	log := Log(s.ctx).WithField("step", step.name)
+	s.update(step.name, func(r *StartupStepReport) { r.Status = StepRunning })
+	began := time.Now()
+
+	delay := step.retryDelay
+	if delay <= 0 {
+		delay = defaultStartupRetryDelay
+	}
+	retry := newBackoff(delay)
+	for attempt := 1; ; attempt++ {
+		s.update(step.name, func(r *StartupStepReport) { r.Attempts = attempt })
+		err := runStartupAttempt(ctx, step)
+		if err == nil {
+			s.update(step.name, func(r *StartupStepReport) {
+				r.Status, r.Duration, r.Error = StepReady, time.Since(began), ""
+			})
+			log.WithField("duration", time.Since(began)).Info("startup step ready")
+			return nil
+		}
+
+		s.update(step.name, func(r *StartupStepReport) { r.Error = err.Error() })
+		if attempt > step.retries || ctx.Err() != nil {
+			s.update(step.name, func(r *StartupStepReport) { r.Status, r.Duration = StepFailed, time.Since(began) })
+			log.WithError(err).WithField("attempts", attempt).Error("startup step failed")
+			return err
+		}
+		log.WithError(err).WithField("attempt", attempt).WithField("retry_in", retry.next).Warn("startup step failed, retrying")
+
+		if waitErr := retry.wait(ctx); waitErr != nil {
+			s.update(step.name, func(r *StartupStepReport) { r.Status, r.Duration = StepFailed, time.Since(began) })
+			return fmt.Errorf("%w: %w", err, waitErr)
+		}
+	}
+}
+
+// runStartupAttempt runs step once, bounded by its timeout.
+func runStartupAttempt(ctx context.Context, step startupStep) error {
+	if step.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.timeout)
+		defer cancel()
+	}
+	return step.fn(ctx)
+}
+
+func (s *Startup) status(name string) StepStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reports[name].Status
+}
+
+func (s *Startup) update(name string, fn func(r *StartupStepReport)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.reports[name])
+}
+
+// checkStartupDependencies reports unknown dependencies and cycles.
+func checkStartupDependencies(steps []startupStep) error {
+	byName := make(map[string]startupStep, len(steps))
+	for _, step := range steps {
+		byName[step.name] = step
+	}
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("%w: cycle %v", ErrStartupDependency, append(path, name))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range byName[name].dependsOn {
+			if _, ok := byName[dep]; !ok {
+				return fmt.Errorf("%w: step %s depends on unknown step %s", ErrStartupDependency, name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, step := range steps {
+		if err := visit(step.name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
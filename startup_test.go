@@ -0,0 +1,149 @@
+package util_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestStartupRunsInDependencyOrder(t *testing.T) {
+	startup := util.NewStartup(t.Context())
+	var mu sync.Mutex
+	var order []string
+	step := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+	startup.Register("cache", step("cache"), util.WithStepDependsOn("database"))
+	startup.Register("database", step("database"), util.WithStepDependsOn("config"))
+	startup.Register("config", step("config"))
+
+	if err := startup.Run(t.Context()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if want := []string{"config", "database", "cache"}; !slices.Equal(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	if !startup.Ready() {
+		t.Fatal("Ready = false after every step succeeded")
+	}
+}
+
+func TestStartupRetriesAndSkips(t *testing.T) {
+	startup := util.NewStartup(t.Context())
+	calls := 0
+	startup.Register("database", func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, util.WithStepRetries(2, time.Millisecond))
+	startup.Register("broker", func(context.Context) error { return errors.New("no route") })
+	startup.Register("consumer", func(context.Context) error {
+		t.Error("a step whose dependency failed was run")
+		return nil
+	}, util.WithStepDependsOn("broker", "database"))
+
+	err := startup.Run(t.Context())
+	if err == nil || err.Error() != "broker: no route" {
+		t.Fatalf("Run = %v, want the broker failure", err)
+	}
+
+	report := startup.Report()
+	want := map[string]util.StartupStepReport{
+		"database": {Name: "database", Status: util.StepReady, Attempts: 3},
+		"broker":   {Name: "broker", Status: util.StepFailed, Attempts: 1, Error: "no route"},
+		"consumer": {Name: "consumer", Status: util.StepSkipped},
+	}
+	if report.Ready || len(report.Steps) != len(want) {
+		t.Fatalf("Report = %+v", report)
+	}
+	for _, step := range report.Steps {
+		step.Duration = 0
+		if step != want[step.Name] {
+			t.Errorf("step report = %+v, want %+v", step, want[step.Name])
+		}
+	}
+}
+
+func TestStartupRetryDefaultDelay(t *testing.T) {
+	startup := util.NewStartup(t.Context())
+	var attempts []time.Time
+	startup.Register("database", func(context.Context) error {
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, util.WithStepRetries(1, 0))
+
+	if err := startup.Run(t.Context()); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if gap := attempts[1].Sub(attempts[0]); gap < 80*time.Millisecond {
+		t.Errorf("retried after %v without a configured delay, want about 100ms", gap)
+	}
+}
+
+func TestStartupStepTimeout(t *testing.T) {
+	startup := util.NewStartup(t.Context(), util.WithStartupTimeout(10*time.Millisecond))
+	startup.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := startup.Run(t.Context()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want a deadline error", err)
+	}
+}
+
+func TestStartupInvalidDependencies(t *testing.T) {
+	noop := func(context.Context) error { return nil }
+
+	unknown := util.NewStartup(t.Context())
+	unknown.Register("api", noop, util.WithStepDependsOn("database"))
+	if err := unknown.Run(t.Context()); !errors.Is(err, util.ErrStartupDependency) {
+		t.Fatalf("Run with an unknown dependency = %v", err)
+	}
+
+	cyclic := util.NewStartup(t.Context())
+	cyclic.Register("a", noop, util.WithStepDependsOn("b"))
+	cyclic.Register("b", noop, util.WithStepDependsOn("a"))
+	if err := cyclic.Run(t.Context()); !errors.Is(err, util.ErrStartupDependency) {
+		t.Fatalf("Run with a cycle = %v", err)
+	}
+	if report := cyclic.Report(); report.Steps[0].Status != util.StepPending {
+		t.Fatalf("a step ran despite the cycle: %+v", report)
+	}
+}
+
+func TestStartupServeHTTP(t *testing.T) {
+	startup := util.NewStartup(t.Context())
+	startup.Register("config", func(context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	startup.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status before Run = %d, want 503", rec.Code)
+	}
+
+	_ = startup.Run(t.Context())
+	rec = httptest.NewRecorder()
+	startup.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report util.StartupReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK || !report.Ready {
+		t.Fatalf("after Run: status %d, report %+v, %v", rec.Code, report, err)
+	}
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Stopwatch measures the elapsed time of an operation and of its steps, for
+// timing logs and metrics. It uses the monotonic clock, so it is not
+// affected by wall clock changes. A Stopwatch is safe for concurrent use.
+//
+// Example:
+//
+//	sw := NewStopwatch()
+//	rows := fetch(ctx)
+//	fetched := sw.Lap()
+//	render(rows)
+//	Log(ctx).WithField("fetch", fetched).WithField("total", sw.Elapsed()).Info("report built")
+type Stopwatch struct {
+	start time.Time
+
+	mu      sync.Mutex
+	lastLap time.Time
+}
+
+// NewStopwatch returns a Stopwatch started now.
+func NewStopwatch() *Stopwatch {
+	now := time.Now()
+	return &Stopwatch{start: now, lastLap: now}
+}
+
+// Lap returns the time since the previous call of Lap, or since the
+// Stopwatch started for the first call, and starts the next lap.
+func (s *Stopwatch) Lap() time.Duration {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lap := now.Sub(s.lastLap)
+	s.lastLap = now
+	return lap
+}
+
+// Elapsed returns the time since the Stopwatch started.
+func (s *Stopwatch) Elapsed() time.Duration {
+	return time.Since(s.start)
+}
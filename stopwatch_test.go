@@ -0,0 +1,24 @@
+package util_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestStopwatch(t *testing.T) {
+	sw := util.NewStopwatch()
+	time.Sleep(10 * time.Millisecond)
+	first := sw.Lap()
+	if first < 10*time.Millisecond {
+		t.Fatalf("first lap = %v, want at least 10ms", first)
+	}
+	second := sw.Lap()
+	if second >= first {
+		t.Fatalf("second lap = %v, want less than the first (%v)", second, first)
+	}
+	if elapsed := sw.Elapsed(); elapsed < first+second {
+		t.Fatalf("Elapsed() = %v, want at least the sum of laps %v", elapsed, first+second)
+	}
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+const ctxValueStore = contextKeyType("store")
+
+// Store is a mutable key/value store scoped to one request. Middleware
+// writes computed data to it, such as parsed credentials or rate-limit
+// decisions, and later handlers read it, without deriving a new context for
+// every value.
+//
+// A Store is safe for concurrent use. Keys follow the rules of context
+// values: use unexported key types, or ContextKey, to avoid collisions.
+type Store struct {
+	mu     sync.RWMutex
+	values map[any]any
+}
+
+// ContextWithStore returns a copy of ctx carrying a new, empty Store, along
+// with that store. Install it once per request, early in the middleware
+// chain; WithStore does so for HTTP handlers.
+func ContextWithStore(ctx context.Context) (context.Context, *Store) {
+	store := &Store{values: make(map[any]any)}
+	return context.WithValue(ctx, ctxValueStore, store), store
+}
+
+// WithStore returns middleware that installs a new Store on the context of
+// each request.
+//
+// Example:
+//
+//	handler := WithStore(authMiddleware(mux))
+func WithStore(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, _ := ContextWithStore(r.Context())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// StoreInContext returns the Store on ctx, or nil when ContextWithStore was
+// not called. The methods of a nil Store are no-ops, so callers need not check.
+//
+// Example:
+//
+//	util.StoreInContext(r.Context()).Set(rateLimitKey{}, decision)
+func StoreInContext(ctx context.Context) *Store {
+	store, _ := ctx.Value(ctxValueStore).(*Store)
+	return store
+}
+
+// Set stores value under key, replacing any previous value.
+func (s *Store) Set(key, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Get returns the value stored under key and whether there was one.
+func (s *Store) Get(key any) (any, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Delete removes the value stored under key.
+func (s *Store) Delete(key any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// StoreGet returns the value of type T stored under key in the Store on ctx.
+// The boolean result is false when there is no store or value, or the value
+// is not a T.
+func StoreGet[T any](ctx context.Context, key any) (T, bool) {
+	value, ok := StoreInContext(ctx).Get(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	typed, ok := value.(T)
+	return typed, ok
+}
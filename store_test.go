@@ -0,0 +1,84 @@
+package util_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+type storeKey string
+
+func TestStore(t *testing.T) {
+	ctx, store := util.ContextWithStore(context.Background())
+	if util.StoreInContext(ctx) != store {
+		t.Fatal("StoreInContext() did not return the installed store")
+	}
+
+	// A value set downstream is visible upstream without a new context.
+	func(ctx context.Context) {
+		util.StoreInContext(ctx).Set(storeKey("user"), "user-7")
+	}(ctx)
+
+	if got, ok := util.StoreGet[string](ctx, storeKey("user")); !ok || got != "user-7" {
+		t.Errorf("StoreGet() = %q, %v, want user-7", got, ok)
+	}
+	if _, ok := util.StoreGet[int](ctx, storeKey("user")); ok {
+		t.Error("StoreGet() returned a value of the wrong type")
+	}
+
+	store.Delete(storeKey("user"))
+	if _, ok := store.Get(storeKey("user")); ok {
+		t.Error("Get() found a deleted value")
+	}
+}
+
+func TestStoreNil(t *testing.T) {
+	store := util.StoreInContext(context.Background())
+	if store != nil {
+		t.Fatal("StoreInContext() without a store should be nil")
+	}
+	store.Set("key", "value")
+	store.Delete("key")
+	if _, ok := store.Get("key"); ok {
+		t.Error("nil Store returned a value")
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	_, store := util.ContextWithStore(context.Background())
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Go(func() {
+			store.Set(i, i)
+			store.Get(i)
+		})
+	}
+	wg.Wait()
+	for i := range 16 {
+		if got, _ := store.Get(i); got != i {
+			t.Errorf("Get(%d) = %v", i, got)
+		}
+	}
+}
+
+func TestWithStore(t *testing.T) {
+	var got string
+	inner := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got, _ = util.StoreGet[string](r.Context(), storeKey("decision"))
+	})
+	middleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			util.StoreInContext(r.Context()).Set(storeKey("decision"), "allow")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	util.WithStore(middleware(inner)).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "allow" {
+		t.Errorf("handler read %q from the store, want allow", got)
+	}
+}
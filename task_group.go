@@ -0,0 +1,51 @@
+package util
+
+import "context"
+
+// TaskGroup runs a fan-out of goroutines working on parts of one task, in
+// the manner of errgroup: the first error cancels the group's context, so
+// the other goroutines can stop early, and is the one Wait returns. It is a
+// WorkerPool with WithFailFast, returned together with its context.
+//
+// Every goroutine gets a context derived from the one the group was created
+// with, so the logger, request ID, tenancy and other request values reach
+// it, and a panicking goroutine is reported as a *PanicError instead of
+// crashing the process.
+//
+// Example:
+//
+//	group, ctx := NewTaskGroup(ctx, 4)
+//	for _, shard := range shards {
+//	    group.Go(func(ctx context.Context) error {
+//	        return reindex(ctx, shard)
+//	    })
+//	}
+//	if err := group.Wait(); err != nil {
+//	    return err
+//	}
+type TaskGroup struct {
+	pool *WorkerPool
+}
+
+// NewTaskGroup returns a TaskGroup running at most limit goroutines at
+// once, or any number when limit is less than one, and the context its
+// goroutines receive. The context is cancelled by the first failure or
+// when Wait returns.
+func NewTaskGroup(ctx context.Context, limit int) (*TaskGroup, context.Context) {
+	pool := NewWorkerPool(ctx, limit, WithFailFast())
+	return &TaskGroup{pool: pool}, pool.ctx
+}
+
+// Go runs task in a new goroutine, first waiting for a free slot when the
+// group is at its limit. Once the group's context is done, tasks are no
+// longer started.
+func (g *TaskGroup) Go(task func(ctx context.Context) error) {
+	g.pool.Go(task)
+}
+
+// Wait waits for the started goroutines to finish and returns the first
+// error, or the context's error when tasks were not started because it
+// ended.
+func (g *TaskGroup) Wait() error {
+	return g.pool.Wait()
+}
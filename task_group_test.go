@@ -0,0 +1,65 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTaskGroupFirstErrorCancels(t *testing.T) {
+	errFirst := errors.New("first")
+	group, ctx := util.NewTaskGroup(t.Context(), 0)
+	group.Go(func(context.Context) error { return errFirst })
+	group.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := group.Wait(); !errors.Is(err, errFirst) {
+		t.Fatalf("Wait = %v, want %v", err, errFirst)
+	}
+	if !errors.Is(context.Cause(ctx), errFirst) {
+		t.Fatalf("context cause = %v, want %v", context.Cause(ctx), errFirst)
+	}
+}
+
+func TestTaskGroupPropagatesContextValues(t *testing.T) {
+	ctx := util.ContextWithRequestID(t.Context(), "req-1")
+	group, _ := util.NewTaskGroup(ctx, 2)
+	for range 4 {
+		group.Go(func(ctx context.Context) error {
+			if got := util.GetRequestID(ctx); got != "req-1" {
+				return errors.New("request ID lost: " + got)
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+}
+
+func TestTaskGroupPanic(t *testing.T) {
+	group, _ := util.NewTaskGroup(t.Context(), 0)
+	group.Go(func(context.Context) error { panic("boom") })
+	err := group.Wait()
+
+	var panicErr *util.PanicError
+	if !errors.As(err, &panicErr) || !errors.Is(err, util.ErrTaskPanicked) {
+		t.Fatalf("Wait = %v, want a PanicError", err)
+	}
+	if panicErr.Value != "boom" || !strings.Contains(panicErr.Stack, "task_group_test.go") {
+		t.Fatalf("PanicError = %v with stack %q", panicErr.Value, panicErr.Stack)
+	}
+
+	var buf bytes.Buffer
+	logger := util.NewLogger(t.Context(), util.WithLogOutput(&buf))
+	logger.WithError(err).Error("fan-out failed")
+	if out := buf.String(); !strings.Contains(out, "boom") || !strings.Contains(out, "stack") {
+		t.Fatalf("log output lacks the panic attributes: %s", out)
+	}
+}
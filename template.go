@@ -0,0 +1,120 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateFuncs returns the functions available to RenderTemplate, for
+// templates parsed elsewhere that should offer the same ones:
+//
+//   - env KEY, envOr KEY FALLBACK: environment variables, as GetEnv
+//   - now: the current time; date LAYOUT TIME formats a time.Time in a
+//     time.Format layout; duration D formats as FormatDurationShort
+//   - bytes N: a byte count, as FormatBytes
+//   - ulid, id: a new ULID or xid
+//   - lower, upper, trim, trimPrefix P S, trimSuffix S2 S, replace OLD NEW S,
+//     contains SUB S, hasPrefix P S, hasSuffix S2 S, split SEP S,
+//     join SEP LIST, quote S: string helpers, taking the piped value last
+//   - default DEF V: V, or DEF when V is empty
+//   - toJSON V: V encoded as JSON
+//
+// None of them touches files, the network or other processes.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"env":   func(key string) string { return GetEnv(key) },
+		"envOr": func(key, fallback string) string { return GetEnv(key, fallback) },
+
+		"now":      time.Now,
+		"date":     func(layout string, t time.Time) string { return t.Format(layout) },
+		"duration": FormatDurationShort,
+		"bytes":    FormatBytes,
+
+		"ulid": ULID,
+		"id":   IDString,
+
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       templateJoin,
+		"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+
+		"default": templateDefault,
+		"toJSON":  templateToJSON,
+	}
+}
+
+// RenderTemplate parses tmpl as a text/template called name, with the
+// functions of TemplateFuncs, and executes it with data, for configuration
+// files and notification messages. It is strict: referring to a missing map
+// key is an error rather than "<no value>".
+//
+// The env functions expose the process environment, so only render
+// templates from trusted sources.
+//
+// Example:
+//
+//	body, err := RenderTemplate("welcome", `Hi {{ .Name | default "there" }}, `+
+//	    `your code {{ .Code }} expires {{ .Expires | date "Jan 2 15:04" }}.`, data)
+func RenderTemplate(name, tmpl string, data any) (string, error) {
+	t, err := template.New(name).Funcs(TemplateFuncs()).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	var b strings.Builder
+	if err = t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+func templateJoin(sep string, list any) (string, error) {
+	switch l := list.(type) {
+	case []string:
+		return strings.Join(l, sep), nil
+	case nil:
+		return "", nil
+	}
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %T is not a list", list)
+	}
+	parts := make([]string, v.Len())
+	for i := range v.Len() {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func templateDefault(def, v any) any {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	if rv.IsZero() {
+		return def
+	}
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.Len() == 0 {
+		return def
+	}
+	return v
+}
+
+func templateToJSON(v any) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJSON: %w", err)
+	}
+	return string(out), nil
+}
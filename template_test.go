@@ -0,0 +1,62 @@
+package util_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	t.Setenv("APP_NAME", "Shop")
+	data := map[string]any{
+		"Name":    "",
+		"Code":    "1234",
+		"Expires": time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC),
+		"Tags":    []string{"a", "b"},
+		"Counts":  []int{1, 2},
+		"TTL":     90 * time.Minute,
+		"Size":    int64(1536),
+	}
+
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{`Hi {{ .Name | default "there" }}`, "Hi there"},
+		{`{{ env "APP_NAME" }}/{{ envOr "APP_MISSING" "none" }}`, "Shop/none"},
+		{`{{ .Expires | date "Jan 2 15:04" }}`, "Mar 5 14:30"},
+		{`{{ .TTL | duration }} {{ .Size | bytes }}`, "1h30m 1.5 KiB"},
+		{`{{ join ", " .Tags }} {{ join "+" .Counts }}`, "a, b 1+2"},
+		{`{{ "  Hello World " | trim | lower | replace "world" "there" }}`, "hello there"},
+		{`{{ .Code | quote }} {{ .Tags | toJSON }}`, `"1234" ["a","b"]`},
+		{`{{ if hasPrefix "12" .Code }}yes{{ end }}`, "yes"},
+	}
+	for _, tt := range tests {
+		got, err := util.RenderTemplate("test", tt.tmpl, data)
+		if err != nil {
+			t.Errorf("RenderTemplate(%q): %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	if id, err := util.RenderTemplate("id", `{{ ulid }}`, nil); err != nil || len(id) != 26 {
+		t.Errorf("ulid rendered %q, %v", id, err)
+	}
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	if _, err := util.RenderTemplate("missing", `{{ .Nope }}`, map[string]any{}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("missing key error = %v, want an error naming the template", err)
+	}
+	if _, err := util.RenderTemplate("bad", `{{ .Name `, nil); err == nil {
+		t.Error("parse error not returned")
+	}
+	if _, err := util.RenderTemplate("join", `{{ join "," 5 }}`, nil); err == nil {
+		t.Error("join of a non-list did not fail")
+	}
+}
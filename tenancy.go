@@ -2,20 +2,49 @@ package util
 
 import (
 	"context"
+	"slices"
 )
 
 const ctxValueTenancyData = contextKeyType("tenancy_info")
 
+// TenancyInfo is the core tenancy model carried on a context: the tenant,
+// the partition within it and the access (client or credential) acting on it.
+//
+// Implementations may carry more through the optional capability interfaces
+// ProfileBearer, SessionBearer and RoleBearer. Code that needs those values
+// reads them with GetProfileID, GetSessionID, GetRoles and HasRole, which
+// return zero values when the tenancy on the context lacks the capability,
+// so minimal and extended implementations can be used interchangeably.
 type TenancyInfo interface {
 	GetTenantID() string
 	GetPartitionID() string
 	GetAccessID() string
 }
 
+// ProfileBearer is implemented by tenancy that identifies the end user
+// (profile) acting within the tenant.
+type ProfileBearer interface {
+	GetProfileID() string
+}
+
+// SessionBearer is implemented by tenancy that identifies the session the
+// request belongs to.
+type SessionBearer interface {
+	GetSessionID() string
+}
+
+// RoleBearer is implemented by tenancy that carries the roles granted to the
+// caller within the tenant.
+type RoleBearer interface {
+	GetRoles() []string
+}
+
+// SetTenancy returns a copy of ctx carrying tenancyInfo.
 func SetTenancy(ctx context.Context, tenancyInfo TenancyInfo) context.Context {
 	return context.WithValue(ctx, ctxValueTenancyData, tenancyInfo)
 }
 
+// GetTenancy returns the tenancy on ctx, or nil when there is none.
 func GetTenancy(ctx context.Context) TenancyInfo {
 	info, ok := ctx.Value(ctxValueTenancyData).(TenancyInfo)
 	if !ok {
@@ -23,3 +52,158 @@ func GetTenancy(ctx context.Context) TenancyInfo {
 	}
 	return info
 }
+
+// GetTenantID returns the tenant ID of the tenancy on ctx, or "" when there
+// is no tenancy.
+func GetTenantID(ctx context.Context) string {
+	if info := GetTenancy(ctx); info != nil {
+		return info.GetTenantID()
+	}
+	return ""
+}
+
+// GetPartitionID returns the partition ID of the tenancy on ctx, or "" when
+// there is no tenancy.
+func GetPartitionID(ctx context.Context) string {
+	if info := GetTenancy(ctx); info != nil {
+		return info.GetPartitionID()
+	}
+	return ""
+}
+
+// GetAccessID returns the access ID of the tenancy on ctx, or "" when there
+// is no tenancy.
+func GetAccessID(ctx context.Context) string {
+	if info := GetTenancy(ctx); info != nil {
+		return info.GetAccessID()
+	}
+	return ""
+}
+
+// GetProfileID returns the profile ID of the tenancy on ctx, or "" when there
+// is no tenancy or it does not implement ProfileBearer.
+func GetProfileID(ctx context.Context) string {
+	if bearer, ok := GetTenancy(ctx).(ProfileBearer); ok {
+		return bearer.GetProfileID()
+	}
+	return ""
+}
+
+// GetSessionID returns the session ID of the tenancy on ctx, or "" when there
+// is no tenancy or it does not implement SessionBearer.
+func GetSessionID(ctx context.Context) string {
+	if bearer, ok := GetTenancy(ctx).(SessionBearer); ok {
+		return bearer.GetSessionID()
+	}
+	return ""
+}
+
+// GetRoles returns the roles of the tenancy on ctx, or nil when there is no
+// tenancy or it does not implement RoleBearer.
+func GetRoles(ctx context.Context) []string {
+	if bearer, ok := GetTenancy(ctx).(RoleBearer); ok {
+		return bearer.GetRoles()
+	}
+	return nil
+}
+
+// DefaultTenancy is a ready-made TenancyInfo that also implements
+// ProfileBearer, SessionBearer and RoleBearer. Its JSON form uses the same
+// claim names as JWTClaims.
+//
+// Build one with NewTenancy, or fill the fields directly.
+type DefaultTenancy struct {
+	TenantID    string   `json:"tenant_id"`
+	PartitionID string   `json:"partition_id"`
+	AccessID    string   `json:"access_id,omitempty"`
+	ProfileID   string   `json:"profile_id,omitempty"`
+	SessionID   string   `json:"session_id,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+}
+
+// GetTenantID implements TenancyInfo.
+func (t DefaultTenancy) GetTenantID() string { return t.TenantID }
+
+// GetPartitionID implements TenancyInfo.
+func (t DefaultTenancy) GetPartitionID() string { return t.PartitionID }
+
+// GetAccessID implements TenancyInfo.
+func (t DefaultTenancy) GetAccessID() string { return t.AccessID }
+
+// GetProfileID implements ProfileBearer.
+func (t DefaultTenancy) GetProfileID() string { return t.ProfileID }
+
+// GetSessionID implements SessionBearer.
+func (t DefaultTenancy) GetSessionID() string { return t.SessionID }
+
+// GetRoles implements RoleBearer.
+func (t DefaultTenancy) GetRoles() []string { return t.Roles }
+
+// TenancyBuilder assembles a DefaultTenancy. Each setter returns the builder
+// so calls can be chained.
+type TenancyBuilder struct {
+	tenancy DefaultTenancy
+}
+
+// NewTenancy starts building a DefaultTenancy.
+//
+// Example:
+//
+//	ctx = NewTenancy().
+//	    TenantID("acme").
+//	    PartitionID("eu").
+//	    ProfileID(userID).
+//	    Roles("admin").
+//	    Context(ctx)
+func NewTenancy() *TenancyBuilder {
+	return &TenancyBuilder{}
+}
+
+// TenantID sets the tenant ID.
+func (b *TenancyBuilder) TenantID(id string) *TenancyBuilder {
+	b.tenancy.TenantID = id
+	return b
+}
+
+// PartitionID sets the partition ID.
+func (b *TenancyBuilder) PartitionID(id string) *TenancyBuilder {
+	b.tenancy.PartitionID = id
+	return b
+}
+
+// AccessID sets the access ID.
+func (b *TenancyBuilder) AccessID(id string) *TenancyBuilder {
+	b.tenancy.AccessID = id
+	return b
+}
+
+// ProfileID sets the profile ID.
+func (b *TenancyBuilder) ProfileID(id string) *TenancyBuilder {
+	b.tenancy.ProfileID = id
+	return b
+}
+
+// SessionID sets the session ID.
+func (b *TenancyBuilder) SessionID(id string) *TenancyBuilder {
+	b.tenancy.SessionID = id
+	return b
+}
+
+// Roles adds roles to the tenancy.
+func (b *TenancyBuilder) Roles(roles ...string) *TenancyBuilder {
+	b.tenancy.Roles = append(b.tenancy.Roles, roles...)
+	return b
+}
+
+// Build returns the assembled tenancy. Later changes to the builder do not
+// affect tenancies already built.
+func (b *TenancyBuilder) Build() DefaultTenancy {
+	tenancy := b.tenancy
+	tenancy.Roles = slices.Clone(b.tenancy.Roles)
+	return tenancy
+}
+
+// Context returns a copy of ctx carrying the assembled tenancy, as SetTenancy does.
+func (b *TenancyBuilder) Context(ctx context.Context) context.Context {
+	return SetTenancy(ctx, b.Build())
+}
@@ -0,0 +1,75 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+// auditContext returns a context whose logger writes JSON to buf.
+func auditContext(t *testing.T, buf *bytes.Buffer) context.Context {
+	t.Helper()
+	logger := util.NewLogger(t.Context(),
+		util.WithLogHandler(slog.NewJSONHandler(buf, nil)), util.WithLogHandlerExclusive())
+	t.Cleanup(logger.Release)
+	return util.ContextWithLogger(t.Context(), logger)
+}
+
+func TestAsSystem(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := auditContext(t, &buf)
+	if util.IsSystemContext(ctx) {
+		t.Fatal("IsSystemContext() = true before AsSystem")
+	}
+
+	ctx = util.AsSystem(ctx)
+	if !util.IsSystemContext(ctx) {
+		t.Error("IsSystemContext() = false after AsSystem")
+	}
+	if !util.IsSystemContext(util.DetachContext(ctx)) {
+		t.Error("DetachContext() dropped the system actor")
+	}
+	if !strings.Contains(buf.String(), `"audit":"system_actor"`) {
+		t.Errorf("no audit event logged: %s", buf.String())
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	var buf bytes.Buffer
+	admin := util.DefaultTenancy{TenantID: "ops", ProfileID: "admin-1"}
+	customer := util.DefaultTenancy{TenantID: "acme", PartitionID: "eu"}
+	ctx := util.SetTenancy(auditContext(t, &buf), admin)
+
+	impersonated, err := util.Impersonate(ctx, customer, "ticket 4821")
+	if err != nil {
+		t.Fatalf("Impersonate() error = %v", err)
+	}
+	if got := util.GetTenantID(impersonated); got != "acme" {
+		t.Errorf("GetTenantID() = %q, want acme", got)
+	}
+
+	record, ok := util.GetImpersonation(impersonated)
+	if !ok || record.Actor.GetTenantID() != "ops" || record.Target.GetTenantID() != "acme" || record.Reason != "ticket 4821" {
+		t.Errorf("GetImpersonation() = %+v, %v", record, ok)
+	}
+	if _, ok = util.GetImpersonation(ctx); ok {
+		t.Error("GetImpersonation() reported an impersonation on the original context")
+	}
+	for _, want := range []string{`"actor_profile_id":"admin-1"`, `"target_tenant_id":"acme"`, `"reason":"ticket 4821"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("audit event missing %s: %s", want, buf.String())
+		}
+	}
+
+	if _, err = util.Impersonate(ctx, nil, "reason"); !errors.Is(err, util.ErrMissingTenancy) {
+		t.Errorf("Impersonate(nil) error = %v, want ErrMissingTenancy", err)
+	}
+	if _, err = util.Impersonate(ctx, customer, ""); err == nil {
+		t.Error("Impersonate() without a reason succeeded")
+	}
+}
@@ -0,0 +1,218 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// tenancyBinaryVersion starts the binary tenancy encoding. JSON encodings
+// start with '{', so UnmarshalTenancy tells the two apart from the first byte.
+const tenancyBinaryVersion = 0x01
+
+// Message attribute keys written by AttachTenancyToAttributes.
+const (
+	AttributeTenantID    = "tenant_id"
+	AttributePartitionID = "partition_id"
+	AttributeAccessID    = "access_id"
+	AttributeProfileID   = "profile_id"
+	AttributeSessionID   = "session_id"
+	AttributeRoles       = "roles"
+)
+
+// errInvalidTenancyBinary reports a truncated or corrupt binary tenancy.
+var errInvalidTenancyBinary = errors.New("invalid binary tenancy encoding")
+
+// toDefaultTenancy copies info, including the optional capabilities it
+// implements, into a DefaultTenancy.
+func toDefaultTenancy(info TenancyInfo) DefaultTenancy {
+	tenancy := DefaultTenancy{
+		TenantID:    info.GetTenantID(),
+		PartitionID: info.GetPartitionID(),
+		AccessID:    info.GetAccessID(),
+	}
+	if bearer, ok := info.(ProfileBearer); ok {
+		tenancy.ProfileID = bearer.GetProfileID()
+	}
+	if bearer, ok := info.(SessionBearer); ok {
+		tenancy.SessionID = bearer.GetSessionID()
+	}
+	if bearer, ok := info.(RoleBearer); ok {
+		tenancy.Roles = slices.Clone(bearer.GetRoles())
+	}
+	return tenancy
+}
+
+// MarshalTenancy encodes info as JSON for queue messages and job payloads,
+// including the profile, session and roles when info carries them.
+//
+// Example:
+//
+//	payload, err := MarshalTenancy(GetTenancy(ctx))
+//	job.Tenancy = payload
+func MarshalTenancy(info TenancyInfo) ([]byte, error) {
+	if info == nil {
+		return nil, ErrMissingTenancy
+	}
+	return json.Marshal(toDefaultTenancy(info))
+}
+
+// MarshalTenancyBinary encodes info in a compact binary form, for transports
+// where every byte counts. UnmarshalTenancy decodes it.
+//
+// The layout is a version byte followed by the tenant, partition, access,
+// profile and session IDs and then the roles, each as a uvarint length and
+// the bytes, with the roles preceded by their uvarint count.
+func MarshalTenancyBinary(info TenancyInfo) ([]byte, error) {
+	if info == nil {
+		return nil, ErrMissingTenancy
+	}
+	t := toDefaultTenancy(info)
+
+	out := []byte{tenancyBinaryVersion}
+	for _, field := range []string{t.TenantID, t.PartitionID, t.AccessID, t.ProfileID, t.SessionID} {
+		out = appendTenancyString(out, field)
+	}
+	out = binary.AppendUvarint(out, uint64(len(t.Roles)))
+	for _, role := range t.Roles {
+		out = appendTenancyString(out, role)
+	}
+	return out, nil
+}
+
+// UnmarshalTenancy decodes a tenancy produced by MarshalTenancy or
+// MarshalTenancyBinary, detecting the encoding automatically.
+//
+// Example:
+//
+//	tenancy, err := UnmarshalTenancy(job.Tenancy)
+//	if err != nil {
+//	    return err
+//	}
+//	ctx = SetTenancy(ctx, tenancy)
+func UnmarshalTenancy(data []byte) (DefaultTenancy, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	switch {
+	case len(trimmed) == 0:
+		return DefaultTenancy{}, errors.New("tenancy data is empty")
+	case trimmed[0] == '{':
+		var tenancy DefaultTenancy
+		if err := json.Unmarshal(trimmed, &tenancy); err != nil {
+			return DefaultTenancy{}, fmt.Errorf("invalid JSON tenancy encoding: %w", err)
+		}
+		return tenancy, nil
+	case data[0] == tenancyBinaryVersion:
+		return unmarshalTenancyBinary(data[1:])
+	default:
+		return DefaultTenancy{}, fmt.Errorf("unknown tenancy encoding 0x%02x", data[0])
+	}
+}
+
+func unmarshalTenancyBinary(data []byte) (DefaultTenancy, error) {
+	var t DefaultTenancy
+	var err error
+	for _, field := range []*string{&t.TenantID, &t.PartitionID, &t.AccessID, &t.ProfileID, &t.SessionID} {
+		if *field, data, err = readTenancyString(data); err != nil {
+			return DefaultTenancy{}, err
+		}
+	}
+
+	count, n := binary.Uvarint(data)
+	// Every role takes at least one byte, which bounds the allocation.
+	if n <= 0 || count > uint64(len(data)-n) {
+		return DefaultTenancy{}, errInvalidTenancyBinary
+	}
+	data = data[n:]
+	if count > 0 {
+		t.Roles = make([]string, count)
+	}
+	for i := range t.Roles {
+		if t.Roles[i], data, err = readTenancyString(data); err != nil {
+			return DefaultTenancy{}, err
+		}
+	}
+
+	if len(data) != 0 {
+		return DefaultTenancy{}, errInvalidTenancyBinary
+	}
+	return t, nil
+}
+
+func appendTenancyString(out []byte, s string) []byte {
+	out = binary.AppendUvarint(out, uint64(len(s)))
+	return append(out, s...)
+}
+
+func readTenancyString(data []byte) (string, []byte, error) {
+	size, n := binary.Uvarint(data)
+	if n <= 0 || size > uint64(len(data)-n) {
+		return "", nil, errInvalidTenancyBinary
+	}
+	end := n + int(size) //nolint:gosec // bounded by len(data) above
+	return string(data[n:end]), data[end:], nil
+}
+
+// AttachTenancyToAttributes writes info into message attributes, such as
+// pub/sub message attributes or job metadata, under the Attribute* keys.
+// Empty values are omitted and roles are encoded as a JSON array, so a role
+// containing a comma stays one role.
+//
+// Attributes are not signed: only attach tenancy to messages on transports
+// that producers outside the trust boundary cannot write to.
+//
+// Example:
+//
+//	attrs := map[string]string{}
+//	AttachTenancyToAttributes(attrs, GetTenancy(ctx))
+//	topic.Publish(ctx, &pubsub.Message{Data: data, Attributes: attrs})
+func AttachTenancyToAttributes(attrs map[string]string, info TenancyInfo) {
+	if info == nil {
+		return
+	}
+	t := toDefaultTenancy(info)
+	var roles string
+	if len(t.Roles) > 0 {
+		encoded, _ := json.Marshal(t.Roles)
+		roles = string(encoded)
+	}
+	for key, value := range map[string]string{
+		AttributeTenantID:    t.TenantID,
+		AttributePartitionID: t.PartitionID,
+		AttributeAccessID:    t.AccessID,
+		AttributeProfileID:   t.ProfileID,
+		AttributeSessionID:   t.SessionID,
+		AttributeRoles:       roles,
+	} {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+}
+
+// TenancyFromAttributes restores the tenancy written by
+// AttachTenancyToAttributes. The boolean result is false when attrs carries
+// no tenant ID. Roles that are not a valid JSON array are dropped.
+//
+// Example:
+//
+//	if tenancy, ok := TenancyFromAttributes(msg.Attributes); ok {
+//	    ctx = SetTenancy(ctx, tenancy)
+//	}
+func TenancyFromAttributes(attrs map[string]string) (DefaultTenancy, bool) {
+	tenancy := DefaultTenancy{
+		TenantID:    attrs[AttributeTenantID],
+		PartitionID: attrs[AttributePartitionID],
+		AccessID:    attrs[AttributeAccessID],
+		ProfileID:   attrs[AttributeProfileID],
+		SessionID:   attrs[AttributeSessionID],
+	}
+	if roles := attrs[AttributeRoles]; roles != "" {
+		if err := json.Unmarshal([]byte(roles), &tenancy.Roles); err != nil {
+			tenancy.Roles = nil
+		}
+	}
+	return tenancy, tenancy.TenantID != ""
+}
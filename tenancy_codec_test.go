@@ -0,0 +1,111 @@
+package util_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestMarshalTenancyRoundTrip(t *testing.T) {
+	full := util.NewTenancy().
+		TenantID("acme").PartitionID("eu").AccessID("svc").
+		ProfileID("user-7").SessionID("sess-3").Roles("admin", "billing").
+		Build()
+	minimal := stubTenancy{tenantID: "acme", partitionID: "eu"}
+
+	for _, marshal := range []struct {
+		name string
+		fn   func(util.TenancyInfo) ([]byte, error)
+	}{
+		{"json", util.MarshalTenancy},
+		{"binary", util.MarshalTenancyBinary},
+	} {
+		t.Run(marshal.name, func(t *testing.T) {
+			for _, tt := range []struct {
+				info util.TenancyInfo
+				want util.DefaultTenancy
+			}{
+				{full, full},
+				{minimal, util.DefaultTenancy{TenantID: "acme", PartitionID: "eu"}},
+			} {
+				data, err := marshal.fn(tt.info)
+				if err != nil {
+					t.Fatalf("marshal failed: %v", err)
+				}
+				got, err := util.UnmarshalTenancy(data)
+				if err != nil || !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("UnmarshalTenancy() = %+v, %v; want %+v", got, err, tt.want)
+				}
+			}
+
+			if _, err := marshal.fn(nil); err == nil {
+				t.Error("marshalling nil tenancy should fail")
+			}
+		})
+	}
+}
+
+func TestMarshalTenancyBinaryIsCompact(t *testing.T) {
+	tenancy := util.NewTenancy().TenantID("acme").PartitionID("eu").Build()
+	jsonData, _ := util.MarshalTenancy(tenancy)
+	binaryData, _ := util.MarshalTenancyBinary(tenancy)
+	if len(binaryData) >= len(jsonData)/2 {
+		t.Errorf("binary encoding is %d bytes, JSON %d; want binary much smaller", len(binaryData), len(jsonData))
+	}
+}
+
+func TestUnmarshalTenancyRejectsMalformedInput(t *testing.T) {
+	valid, _ := util.MarshalTenancyBinary(util.NewTenancy().TenantID("acme").Roles("admin").Build())
+
+	for _, data := range [][]byte{
+		nil,
+		[]byte("  "),
+		[]byte("{not json"),
+		{0x07, 0x00},
+		valid[:len(valid)-1],
+		append(valid[:len(valid):len(valid)], 0x00),
+		{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x0f},
+	} {
+		if got, err := util.UnmarshalTenancy(data); err == nil {
+			t.Errorf("UnmarshalTenancy(%x) = %+v, want an error", data, got)
+		}
+	}
+}
+
+func TestTenancyAttributes(t *testing.T) {
+	tenancy := util.NewTenancy().TenantID("acme").PartitionID("eu").ProfileID("user-7").Roles("admin", "billing").Build()
+
+	attrs := map[string]string{"event": "order.created"}
+	util.AttachTenancyToAttributes(attrs, tenancy)
+	want := map[string]string{
+		"event":        "order.created",
+		"tenant_id":    "acme",
+		"partition_id": "eu",
+		"profile_id":   "user-7",
+		"roles":        `["admin","billing"]`,
+	}
+	if !reflect.DeepEqual(attrs, want) {
+		t.Errorf("AttachTenancyToAttributes() = %v, want %v", attrs, want)
+	}
+
+	got, ok := util.TenancyFromAttributes(attrs)
+	if !ok || !reflect.DeepEqual(got, tenancy) {
+		t.Errorf("TenancyFromAttributes() = %+v, %v; want %+v", got, ok, tenancy)
+	}
+
+	comma := util.NewTenancy().TenantID("acme").Roles("admin,billing").Build()
+	attrs = map[string]string{}
+	util.AttachTenancyToAttributes(attrs, comma)
+	if got, _ = util.TenancyFromAttributes(attrs); !reflect.DeepEqual(got.Roles, []string{"admin,billing"}) {
+		t.Errorf("a role with a comma round-tripped to %q", got.Roles)
+	}
+	invalid, _ := util.TenancyFromAttributes(map[string]string{"tenant_id": "acme", "roles": "admin,billing"})
+	if invalid.Roles != nil {
+		t.Errorf("roles that are not JSON = %q, want none", invalid.Roles)
+	}
+
+	if _, ok = util.TenancyFromAttributes(map[string]string{"event": "x"}); ok {
+		t.Error("TenancyFromAttributes() reported tenancy for attributes without a tenant")
+	}
+}
@@ -0,0 +1,264 @@
+package util
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers carrying tenancy between services. See InjectTenancyHeaders.
+const (
+	HeaderTenantID         = "X-Tenant-ID"
+	HeaderPartitionID      = "X-Partition-ID"
+	HeaderAccessID         = "X-Access-ID"
+	HeaderProfileID        = "X-Profile-ID"
+	HeaderTenancyTimestamp = "X-Tenancy-Timestamp"
+	HeaderTenancySignature = "X-Tenancy-Signature"
+)
+
+const (
+	tenancySignatureDomain  = "util.tenancy-headers.v1"
+	defaultTenancyHeaderAge = 5 * time.Minute
+	// tenancyHeaderClockSkew tolerates signers whose clock runs slightly ahead.
+	tenancyHeaderClockSkew = time.Minute
+)
+
+// ErrInvalidTenancyHeaders is returned when tenancy headers are missing a
+// signature, carry a wrong one, or have expired.
+var ErrInvalidTenancyHeaders = errors.New("invalid tenancy headers")
+
+// tenancyHeaderOptions contains configuration for tenancy header propagation.
+type tenancyHeaderOptions struct {
+	// keys sign (the first) and verify (any) tenancy headers
+	keys [][]byte
+
+	// maxAge rejects headers signed longer ago than this
+	maxAge time.Duration
+
+	// required rejects requests that carry no tenancy headers
+	required bool
+
+	// now returns the current time
+	now func() time.Time
+}
+
+// TenancyHeaderOption is a function that configures the tenancy header
+// functions: InjectTenancyHeaders, SetTenancyHeaders, ExtractTenancyHeaders,
+// ContextWithTenancyHeaders and WithTenancyFromHeaders.
+type TenancyHeaderOption func(*tenancyHeaderOptions)
+
+// WithTenancyHeaderKey sets the HMAC key shared by the services exchanging
+// tenancy. Headers are signed with key and accepted when signed with key or
+// any of previous, so the key can be rotated without downtime. Keys should
+// be at least 32 random bytes.
+//
+// Without a key, InjectTenancyHeaders fails and incoming tenancy headers are
+// never trusted.
+func WithTenancyHeaderKey(key []byte, previous ...[]byte) TenancyHeaderOption {
+	return func(o *tenancyHeaderOptions) {
+		o.keys = append([][]byte{key}, previous...)
+	}
+}
+
+// WithTenancyHeaderMaxAge rejects headers signed longer ago than maxAge,
+// limiting how long captured headers can be replayed. The default is 5 minutes.
+func WithTenancyHeaderMaxAge(maxAge time.Duration) TenancyHeaderOption {
+	return func(o *tenancyHeaderOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithTenancyHeaderRequired makes WithTenancyFromHeaders reject requests
+// without tenancy headers with 401 Unauthorized instead of passing them on
+// without tenancy.
+func WithTenancyHeaderRequired() TenancyHeaderOption {
+	return func(o *tenancyHeaderOptions) {
+		o.required = true
+	}
+}
+
+// WithTenancyHeaderClock overrides the time source used to sign and check headers.
+func WithTenancyHeaderClock(now func() time.Time) TenancyHeaderOption {
+	return func(o *tenancyHeaderOptions) {
+		o.now = now
+	}
+}
+
+func newTenancyHeaderOptions(opts []TenancyHeaderOption) *tenancyHeaderOptions {
+	options := &tenancyHeaderOptions{maxAge: defaultTenancyHeaderAge, now: time.Now}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// InjectTenancyHeaders writes info to the tenancy headers of an outgoing
+// request, signed so the receiving service can trust them.
+//
+// The tenant, partition and access IDs are always sent; the profile ID is
+// sent when info implements ProfileBearer. The signature covers every
+// tenancy header and a timestamp, so none can be altered, dropped or added
+// in transit.
+//
+// Example:
+//
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+//	if err := InjectTenancyHeaders(req, GetTenancy(ctx), WithTenancyHeaderKey(key)); err != nil {
+//	    return err
+//	}
+func InjectTenancyHeaders(req *http.Request, info TenancyInfo, opts ...TenancyHeaderOption) error {
+	return SetTenancyHeaders(req.Header, info, opts...)
+}
+
+// SetTenancyHeaders writes info to h as signed tenancy headers, like
+// InjectTenancyHeaders. Use it for transports that carry HTTP-style headers
+// without an *http.Request, such as message metadata.
+func SetTenancyHeaders(h http.Header, info TenancyInfo, opts ...TenancyHeaderOption) error {
+	options := newTenancyHeaderOptions(opts)
+	if len(options.keys) == 0 || len(options.keys[0]) == 0 {
+		return errors.New("a tenancy header key is required to sign tenancy headers")
+	}
+	if info == nil {
+		return ErrMissingTenancy
+	}
+
+	var profileID string
+	if bearer, ok := info.(ProfileBearer); ok {
+		profileID = bearer.GetProfileID()
+	}
+
+	timestamp := strconv.FormatInt(options.now().Unix(), 10)
+	fields := []string{timestamp, info.GetTenantID(), info.GetPartitionID(), info.GetAccessID(), profileID}
+
+	h.Set(HeaderTenantID, info.GetTenantID())
+	h.Set(HeaderPartitionID, info.GetPartitionID())
+	setOrDelHeader(h, HeaderAccessID, info.GetAccessID())
+	setOrDelHeader(h, HeaderProfileID, profileID)
+	h.Set(HeaderTenancyTimestamp, timestamp)
+	h.Set(HeaderTenancySignature, base64.RawURLEncoding.EncodeToString(signTenancyFields(options.keys[0], fields)))
+	return nil
+}
+
+// ExtractTenancyHeaders verifies the tenancy headers written by
+// InjectTenancyHeaders and returns the tenancy they carry.
+//
+// Returns ErrMissingTenancy when h carries no tenancy signature and an error
+// wrapping ErrInvalidTenancyHeaders when the signature is wrong or expired.
+func ExtractTenancyHeaders(h http.Header, opts ...TenancyHeaderOption) (DefaultTenancy, error) {
+	return newTenancyHeaderOptions(opts).extract(h)
+}
+
+func (o *tenancyHeaderOptions) extract(h http.Header) (DefaultTenancy, error) {
+	encoded := h.Get(HeaderTenancySignature)
+	if encoded == "" {
+		return DefaultTenancy{}, ErrMissingTenancy
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return DefaultTenancy{}, fmt.Errorf("%w: malformed signature", ErrInvalidTenancyHeaders)
+	}
+
+	timestamp := h.Get(HeaderTenancyTimestamp)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return DefaultTenancy{}, fmt.Errorf("%w: malformed timestamp", ErrInvalidTenancyHeaders)
+	}
+
+	tenancy := DefaultTenancy{
+		TenantID:    h.Get(HeaderTenantID),
+		PartitionID: h.Get(HeaderPartitionID),
+		AccessID:    h.Get(HeaderAccessID),
+		ProfileID:   h.Get(HeaderProfileID),
+	}
+	fields := []string{timestamp, tenancy.TenantID, tenancy.PartitionID, tenancy.AccessID, tenancy.ProfileID}
+
+	verified := false
+	for _, key := range o.keys {
+		if len(key) > 0 && hmac.Equal(signature, signTenancyFields(key, fields)) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return DefaultTenancy{}, fmt.Errorf("%w: signature mismatch", ErrInvalidTenancyHeaders)
+	}
+
+	age := o.now().Sub(time.Unix(signedAt, 0))
+	if age > o.maxAge || age < -tenancyHeaderClockSkew {
+		return DefaultTenancy{}, fmt.Errorf("%w: signed %s ago", ErrInvalidTenancyHeaders, age.Round(time.Second))
+	}
+	return tenancy, nil
+}
+
+// ContextWithTenancyHeaders verifies the tenancy headers in h and returns a
+// copy of ctx carrying their tenancy. It applies the policy of
+// WithTenancyFromHeaders for use by middleware on other transports.
+//
+// When h carries no tenancy headers, ctx is returned unchanged, or
+// ErrMissingTenancy when WithTenancyHeaderRequired is set. Invalid or
+// expired headers return an error wrapping ErrInvalidTenancyHeaders.
+func ContextWithTenancyHeaders(ctx context.Context, h http.Header, opts ...TenancyHeaderOption) (context.Context, error) {
+	return newTenancyHeaderOptions(opts).contextWithTenancy(ctx, h)
+}
+
+func (o *tenancyHeaderOptions) contextWithTenancy(ctx context.Context, h http.Header) (context.Context, error) {
+	tenancy, err := o.extract(h)
+	switch {
+	case err == nil:
+		return SetTenancy(ctx, tenancy), nil
+	case errors.Is(err, ErrMissingTenancy) && !o.required:
+		return ctx, nil
+	default:
+		return ctx, err
+	}
+}
+
+// WithTenancyFromHeaders returns middleware that verifies the tenancy
+// headers of each request and puts the tenancy on the request context, where
+// GetTenancy, GetProfileID and the other accessors find it.
+//
+// Requests with invalid or expired headers are rejected with 401
+// Unauthorized. Requests without tenancy headers pass through without
+// tenancy unless WithTenancyHeaderRequired is set.
+//
+// Example:
+//
+//	handler := WithTenancyFromHeaders(mux, WithTenancyHeaderKey(key), WithTenancyHeaderRequired())
+func WithTenancyFromHeaders(next http.Handler, opts ...TenancyHeaderOption) http.Handler {
+	options := newTenancyHeaderOptions(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := options.contextWithTenancy(r.Context(), r.Header)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// signTenancyFields computes the HMAC over length-prefixed fields, so no two
+// different sets of header values share a signature.
+func signTenancyFields(key []byte, fields []string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(tenancySignatureDomain))
+	for _, field := range fields {
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(len(field)))) //nolint:gosec // header values are short
+		mac.Write([]byte(field))
+	}
+	return mac.Sum(nil)
+}
+
+func setOrDelHeader(h http.Header, key, value string) {
+	if value == "" {
+		h.Del(key)
+		return
+	}
+	h.Set(key, value)
+}
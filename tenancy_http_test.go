@@ -0,0 +1,155 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTenancyHeadersRoundTrip(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	tenancy := util.NewTenancy().TenantID("acme").PartitionID("eu").AccessID("svc").ProfileID("user-7").Build()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := util.InjectTenancyHeaders(req, tenancy, util.WithTenancyHeaderKey(key)); err != nil {
+		t.Fatalf("InjectTenancyHeaders() failed: %v", err)
+	}
+	if req.Header.Get(util.HeaderTenantID) != "acme" || req.Header.Get(util.HeaderTenancySignature) == "" {
+		t.Fatalf("InjectTenancyHeaders() headers = %v", req.Header)
+	}
+
+	got, err := util.ExtractTenancyHeaders(req.Header, util.WithTenancyHeaderKey(key))
+	if err != nil {
+		t.Fatalf("ExtractTenancyHeaders() failed: %v", err)
+	}
+	if got.TenantID != "acme" || got.PartitionID != "eu" || got.AccessID != "svc" || got.ProfileID != "user-7" {
+		t.Errorf("ExtractTenancyHeaders() = %+v, want %+v", got, tenancy)
+	}
+
+	// A rotated-out key still verifies when listed as previous.
+	if _, err = util.ExtractTenancyHeaders(req.Header,
+		util.WithTenancyHeaderKey([]byte("a-new-key-that-replaced-the-old-one"), key)); err != nil {
+		t.Errorf("ExtractTenancyHeaders() with a previous key failed: %v", err)
+	}
+}
+
+func TestExtractTenancyHeadersRejectsTampering(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Unix(1_700_000_000, 0)
+	signed := func() http.Header {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		tenancy := util.NewTenancy().TenantID("acme").PartitionID("eu").Build()
+		if err := util.InjectTenancyHeaders(req, tenancy,
+			util.WithTenancyHeaderKey(key), util.WithTenancyHeaderClock(func() time.Time { return now })); err != nil {
+			t.Fatal(err)
+		}
+		return req.Header
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(h http.Header)
+		at     time.Time
+	}{
+		{"changed tenant", func(h http.Header) { h.Set(util.HeaderTenantID, "evil") }, now},
+		{"added profile", func(h http.Header) { h.Set(util.HeaderProfileID, "admin") }, now},
+		{"changed timestamp", func(h http.Header) { h.Set(util.HeaderTenancyTimestamp, "1700000100") }, now},
+		{"malformed signature", func(h http.Header) { h.Set(util.HeaderTenancySignature, "!!") }, now},
+		{"expired", func(http.Header) {}, now.Add(6 * time.Minute)},
+		{"from the future", func(http.Header) {}, now.Add(-2 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := signed()
+			tt.mutate(h)
+			_, err := util.ExtractTenancyHeaders(h,
+				util.WithTenancyHeaderKey(key), util.WithTenancyHeaderClock(func() time.Time { return tt.at }))
+			if !errors.Is(err, util.ErrInvalidTenancyHeaders) {
+				t.Errorf("ExtractTenancyHeaders() error = %v, want ErrInvalidTenancyHeaders", err)
+			}
+		})
+	}
+
+	if _, err := util.ExtractTenancyHeaders(signed()); !errors.Is(err, util.ErrInvalidTenancyHeaders) {
+		t.Errorf("ExtractTenancyHeaders() without a key error = %v, want ErrInvalidTenancyHeaders", err)
+	}
+	if err := util.InjectTenancyHeaders(httptest.NewRequest(http.MethodGet, "/", nil), util.DefaultTenancy{}); err == nil {
+		t.Error("InjectTenancyHeaders() without a key should fail")
+	}
+}
+
+func TestWithTenancyFromHeaders(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	handler := func(opts ...util.TenancyHeaderOption) http.Handler {
+		return util.WithTenancyFromHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tenancy := util.GetTenancy(r.Context()); tenancy != nil {
+				_, _ = w.Write([]byte(tenancy.GetTenantID() + "/" + util.GetProfileID(r.Context())))
+			}
+		}), append([]util.TenancyHeaderOption{util.WithTenancyHeaderKey(key)}, opts...)...)
+	}
+
+	signedReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	tenancy := util.NewTenancy().TenantID("acme").ProfileID("user-7").Build()
+	if err := util.InjectTenancyHeaders(signedReq, tenancy, util.WithTenancyHeaderKey(key)); err != nil {
+		t.Fatal(err)
+	}
+	forgedReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	forgedReq.Header = signedReq.Header.Clone()
+	forgedReq.Header.Set(util.HeaderTenantID, "other")
+	unsignedReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	unsignedReq.Header.Set(util.HeaderTenantID, "acme")
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		opts       []util.TenancyHeaderOption
+		wantStatus int
+		wantBody   string
+	}{
+		{"signed", signedReq, nil, http.StatusOK, "acme/user-7"},
+		{"forged", forgedReq, nil, http.StatusUnauthorized, "Unauthorized\n"},
+		{"unsigned headers are ignored", unsignedReq, nil, http.StatusOK, ""},
+		{"unsigned when required", unsignedReq, []util.TenancyHeaderOption{util.WithTenancyHeaderRequired()},
+			http.StatusUnauthorized, "Unauthorized\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(tt.opts...).ServeHTTP(rec, tt.req)
+			if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestContextWithTenancyHeaders(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	h := http.Header{}
+	if err := util.SetTenancyHeaders(h, util.NewTenancy().TenantID("acme").Build(), util.WithTenancyHeaderKey(key)); err != nil {
+		t.Fatalf("SetTenancyHeaders() failed: %v", err)
+	}
+
+	ctx, err := util.ContextWithTenancyHeaders(context.Background(), h, util.WithTenancyHeaderKey(key))
+	if err != nil || util.GetTenancy(ctx).GetTenantID() != "acme" {
+		t.Errorf("ContextWithTenancyHeaders() = %v, %v; want tenant acme", util.GetTenancy(ctx), err)
+	}
+
+	ctx, err = util.ContextWithTenancyHeaders(context.Background(), http.Header{}, util.WithTenancyHeaderKey(key))
+	if err != nil || util.GetTenancy(ctx) != nil {
+		t.Errorf("ContextWithTenancyHeaders() without headers = %v, %v; want no tenancy", util.GetTenancy(ctx), err)
+	}
+
+	_, err = util.ContextWithTenancyHeaders(context.Background(), http.Header{},
+		util.WithTenancyHeaderKey(key), util.WithTenancyHeaderRequired())
+	if !errors.Is(err, util.ErrMissingTenancy) {
+		t.Errorf("ContextWithTenancyHeaders() required error = %v, want ErrMissingTenancy", err)
+	}
+}
@@ -0,0 +1,196 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ClaimMapping names the JWT claims that carry each tenancy field, for
+// identity providers that do not use this package's claim names. Empty
+// fields fall back to DefaultClaimMapping.
+type ClaimMapping struct {
+	TenantID    string
+	PartitionID string
+	AccessID    string
+	// ProfileID is the claim identifying the end user, usually the subject.
+	ProfileID string
+	SessionID string
+	// Roles is a claim holding an array of strings or a space-separated
+	// string, as in the OAuth scope claim.
+	Roles string
+}
+
+// DefaultClaimMapping returns the claim names written by SignJWT and used in
+// the JSON form of DefaultTenancy, with the subject as the profile ID.
+func DefaultClaimMapping() ClaimMapping {
+	return ClaimMapping{
+		TenantID:    "tenant_id",
+		PartitionID: "partition_id",
+		AccessID:    "access_id",
+		ProfileID:   "sub",
+		SessionID:   "session_id",
+		Roles:       "roles",
+	}
+}
+
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	defaults := DefaultClaimMapping()
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&m.TenantID, defaults.TenantID},
+		{&m.PartitionID, defaults.PartitionID},
+		{&m.AccessID, defaults.AccessID},
+		{&m.ProfileID, defaults.ProfileID},
+		{&m.SessionID, defaults.SessionID},
+		{&m.Roles, defaults.Roles},
+	} {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+	}
+	return m
+}
+
+// TenancyFromClaims builds the tenancy carried by a verified token's claims,
+// reading each field from the claim mapping names.
+//
+// Returns ErrMissingTenancy when the tenant claim is absent or empty, and an
+// error when a mapped claim has an unexpected type. Only pass claims that
+// have been verified, e.g. by VerifyJWT.
+//
+// Example:
+//
+//	tenancy, err := TenancyFromClaims(claims, ClaimMapping{TenantID: "org", ProfileID: "sub"})
+//	if err != nil {
+//	    return err
+//	}
+//	ctx = SetTenancy(ctx, tenancy)
+func TenancyFromClaims(claims map[string]any, mapping ClaimMapping) (DefaultTenancy, error) {
+	mapping = mapping.withDefaults()
+
+	var tenancy DefaultTenancy
+	for _, field := range []struct {
+		value *string
+		claim string
+	}{
+		{&tenancy.TenantID, mapping.TenantID},
+		{&tenancy.PartitionID, mapping.PartitionID},
+		{&tenancy.AccessID, mapping.AccessID},
+		{&tenancy.ProfileID, mapping.ProfileID},
+		{&tenancy.SessionID, mapping.SessionID},
+	} {
+		raw, ok := claims[field.claim]
+		if !ok {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return DefaultTenancy{}, fmt.Errorf("claim %q must be a string, got %T", field.claim, raw)
+		}
+		*field.value = value
+	}
+
+	roles, err := claimRoles(claims[mapping.Roles])
+	if err != nil {
+		return DefaultTenancy{}, fmt.Errorf("claim %q: %w", mapping.Roles, err)
+	}
+	tenancy.Roles = roles
+
+	if tenancy.TenantID == "" {
+		return DefaultTenancy{}, ErrMissingTenancy
+	}
+	return tenancy, nil
+}
+
+func claimRoles(raw any) ([]string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return strings.Fields(v), nil
+	case []string:
+		return v, nil
+	case []any:
+		roles := make([]string, 0, len(v))
+		for _, item := range v {
+			role, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("roles must be strings, got %T", item)
+			}
+			roles = append(roles, role)
+		}
+		return roles, nil
+	default:
+		return nil, fmt.Errorf("roles must be an array or a space-separated string, got %T", raw)
+	}
+}
+
+// WithTenancyFromJWT returns middleware that verifies the bearer token in
+// the Authorization header with VerifyJWT and puts its tenancy, read with
+// TenancyFromClaims, on the request context. The verified claims are also
+// stored with ContextWithJWTClaims.
+//
+// Requests without a bearer token, with a token that fails verification or
+// without a tenant claim are rejected with 401 Unauthorized.
+//
+// Example:
+//
+//	handler := WithTenancyFromJWT(mux, StaticJWTKey(publicKey),
+//	    ClaimMapping{TenantID: "org_id"}, WithJWTIssuer("https://auth.example.com"))
+func WithTenancyFromJWT(
+	next http.Handler,
+	keyFunc JWTKeyFunc,
+	mapping ClaimMapping,
+	opts ...JWTVerifyOption,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		token, ok := bearerToken(r)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		claims, err := VerifyJWT(keyFunc, token, opts...)
+		if err != nil {
+			Log(ctx).WithError(err).Debug("rejected bearer token")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		tenancy, err := jwtTenancy(claims, mapping)
+		if err != nil {
+			Log(ctx).WithError(err).Debug("bearer token carries no tenancy")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		ctx = SetTenancy(ContextWithJWTClaims(ctx, claims), tenancy)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// jwtTenancy flattens claims, including Extra, into a claim set for TenancyFromClaims.
+func jwtTenancy(claims *JWTClaims, mapping ClaimMapping) (DefaultTenancy, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return DefaultTenancy{}, err
+	}
+	var all map[string]any
+	if err = json.Unmarshal(data, &all); err != nil {
+		return DefaultTenancy{}, err
+	}
+	return TenancyFromClaims(all, mapping)
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,101 @@
+package util_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTenancyFromClaims(t *testing.T) {
+	claims := map[string]any{
+		"org":   "acme",
+		"team":  "eu",
+		"sub":   "user-7",
+		"sid":   "sess-3",
+		"scope": "read write",
+	}
+	tenancy, err := util.TenancyFromClaims(claims, util.ClaimMapping{
+		TenantID: "org", PartitionID: "team", SessionID: "sid", Roles: "scope",
+	})
+	if err != nil {
+		t.Fatalf("TenancyFromClaims() error = %v", err)
+	}
+	want := util.DefaultTenancy{
+		TenantID: "acme", PartitionID: "eu", ProfileID: "user-7", SessionID: "sess-3",
+		Roles: []string{"read", "write"},
+	}
+	if tenancy.TenantID != want.TenantID || tenancy.PartitionID != want.PartitionID ||
+		tenancy.ProfileID != want.ProfileID || tenancy.SessionID != want.SessionID ||
+		!slices.Equal(tenancy.Roles, want.Roles) {
+		t.Errorf("TenancyFromClaims() = %+v, want %+v", tenancy, want)
+	}
+
+	tenancy, err = util.TenancyFromClaims(map[string]any{
+		"tenant_id": "acme", "roles": []any{"admin", "reader"},
+	}, util.ClaimMapping{})
+	if err != nil || !slices.Equal(tenancy.Roles, []string{"admin", "reader"}) {
+		t.Errorf("TenancyFromClaims() with default mapping = %+v, %v", tenancy, err)
+	}
+
+	if _, err = util.TenancyFromClaims(map[string]any{"sub": "user-7"}, util.ClaimMapping{}); !errors.Is(err, util.ErrMissingTenancy) {
+		t.Errorf("TenancyFromClaims() without tenant error = %v, want ErrMissingTenancy", err)
+	}
+	if _, err = util.TenancyFromClaims(map[string]any{"tenant_id": 42.0}, util.ClaimMapping{}); err == nil {
+		t.Error("TenancyFromClaims() accepted a non-string tenant claim")
+	}
+	if _, err = util.TenancyFromClaims(map[string]any{"tenant_id": "acme", "roles": []any{1.0}}, util.ClaimMapping{}); err == nil {
+		t.Error("TenancyFromClaims() accepted non-string roles")
+	}
+}
+
+func TestWithTenancyFromJWT(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	handler := util.WithTenancyFromJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if util.GetTenantID(r.Context()) != "acme" || !util.HasRole(r.Context(), "admin") ||
+			util.JWTClaimsFromContext(r.Context()) == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}), util.StaticJWTKey(key), util.ClaimMapping{TenantID: "org"})
+
+	sign := func(claims *util.JWTClaims) string {
+		token, err := util.SignJWT(key, claims)
+		if err != nil {
+			t.Fatalf("SignJWT() error = %v", err)
+		}
+		return token
+	}
+	valid := sign(&util.JWTClaims{Subject: "user-7", Extra: map[string]any{"org": "acme", "roles": []string{"admin"}}})
+	noTenant := sign(&util.JWTClaims{Subject: "user-7"})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"valid token", "Bearer " + valid, http.StatusNoContent},
+		{"lowercase scheme", "bearer " + valid, http.StatusNoContent},
+		{"missing header", "", http.StatusUnauthorized},
+		{"basic scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"tampered token", "Bearer " + valid + "x", http.StatusUnauthorized},
+		{"no tenant claim", "Bearer " + noTenant, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// TenancyField names a field of the tenancy on a context, for RequireTenancy.
+type TenancyField string
+
+// Tenancy fields. The values match the JSON names of DefaultTenancy.
+const (
+	TenancyFieldTenantID    TenancyField = "tenant_id"
+	TenancyFieldPartitionID TenancyField = "partition_id"
+	TenancyFieldAccessID    TenancyField = "access_id"
+	TenancyFieldProfileID   TenancyField = "profile_id"
+	TenancyFieldSessionID   TenancyField = "session_id"
+)
+
+// Error codes in the body of responses rejected by RequireTenancy.
+const (
+	TenancyErrMissing    = "TENANCY_MISSING"
+	TenancyErrIncomplete = "TENANCY_INCOMPLETE"
+)
+
+// tenancyErrorBody is the JSON body of a response rejected by RequireTenancy.
+type tenancyErrorBody struct {
+	ErrCode       string         `json:"errcode"`
+	Error         string         `json:"error"`
+	MissingFields []TenancyField `json:"missing_fields,omitempty"`
+}
+
+// value returns the field of the tenancy on ctx, or "" when it is not set.
+func (f TenancyField) value(ctx context.Context) (string, error) {
+	switch f {
+	case TenancyFieldTenantID:
+		return GetTenantID(ctx), nil
+	case TenancyFieldPartitionID:
+		return GetPartitionID(ctx), nil
+	case TenancyFieldAccessID:
+		return GetAccessID(ctx), nil
+	case TenancyFieldProfileID:
+		return GetProfileID(ctx), nil
+	case TenancyFieldSessionID:
+		return GetSessionID(ctx), nil
+	default:
+		return "", fmt.Errorf("unknown tenancy field %q", string(f))
+	}
+}
+
+// MissingTenancyFields returns the fields that are empty in the tenancy on
+// ctx, or every field when ctx carries no tenancy.
+func MissingTenancyFields(ctx context.Context, fields ...TenancyField) ([]TenancyField, error) {
+	var missing []TenancyField
+	for _, field := range fields {
+		value, err := field.value(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			missing = append(missing, field)
+		}
+	}
+	return missing, nil
+}
+
+// RequireTenancy wraps handler so it only sees requests whose context
+// carries a tenancy with every one of fields set, letting it assume a
+// complete tenancy. Without fields the tenant and partition IDs are required.
+//
+// Requests without any tenancy are rejected with 401 Unauthorized and the
+// error code TenancyErrMissing; requests whose tenancy lacks a field are
+// rejected with 400 Bad Request, TenancyErrIncomplete and the missing fields:
+//
+//	{"errcode":"TENANCY_INCOMPLETE","error":"tenancy is incomplete","missing_fields":["partition_id"]}
+//
+// Tenancy must already be on the context, e.g. from WithTenancyFromJWT.
+// RequireTenancy panics when fields contains an unknown field.
+//
+// Example:
+//
+//	handler := WithTenancyFromJWT(RequireTenancy(api), keyFunc, ClaimMapping{})
+func RequireTenancy(handler http.Handler, fields ...TenancyField) http.Handler {
+	if len(fields) == 0 {
+		fields = []TenancyField{TenancyFieldTenantID, TenancyFieldPartitionID}
+	}
+	for _, field := range fields {
+		if _, err := field.value(context.Background()); err != nil {
+			panic(err)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if GetTenancy(ctx) == nil {
+			respondTenancyError(w, req, http.StatusUnauthorized, tenancyErrorBody{
+				ErrCode: TenancyErrMissing,
+				Error:   "tenancy is required",
+			})
+			return
+		}
+
+		// Fields were validated above, so the lookup cannot fail.
+		missing, _ := MissingTenancyFields(ctx, fields...)
+		if len(missing) > 0 {
+			respondTenancyError(w, req, http.StatusBadRequest, tenancyErrorBody{
+				ErrCode:       TenancyErrIncomplete,
+				Error:         "tenancy is incomplete",
+				MissingFields: missing,
+			})
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+func respondTenancyError(w http.ResponseWriter, req *http.Request, code int, body tenancyErrorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	respond(w, req, JSONResponse{Code: code, JSON: body})
+}
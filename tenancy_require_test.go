@@ -0,0 +1,93 @@
+package util_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestRequireTenancy(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name        string
+		handler     http.Handler
+		ctx         context.Context
+		wantCode    int
+		wantErrCode string
+		wantMissing []util.TenancyField
+	}{
+		{
+			name:     "complete tenancy",
+			handler:  util.RequireTenancy(ok),
+			ctx:      util.NewTenancy().TenantID("acme").PartitionID("eu").Context(context.Background()),
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:        "no tenancy",
+			handler:     util.RequireTenancy(ok),
+			ctx:         context.Background(),
+			wantCode:    http.StatusUnauthorized,
+			wantErrCode: util.TenancyErrMissing,
+		},
+		{
+			name:        "missing partition",
+			handler:     util.RequireTenancy(ok),
+			ctx:         util.NewTenancy().TenantID("acme").Context(context.Background()),
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: util.TenancyErrIncomplete,
+			wantMissing: []util.TenancyField{util.TenancyFieldPartitionID},
+		},
+		{
+			name:        "custom fields",
+			handler:     util.RequireTenancy(ok, util.TenancyFieldTenantID, util.TenancyFieldProfileID, util.TenancyFieldAccessID),
+			ctx:         util.NewTenancy().TenantID("acme").Context(context.Background()),
+			wantCode:    http.StatusBadRequest,
+			wantErrCode: util.TenancyErrIncomplete,
+			wantMissing: []util.TenancyField{util.TenancyFieldProfileID, util.TenancyFieldAccessID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, httptest.NewRequestWithContext(tt.ctx, http.MethodGet, "/", nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantErrCode == "" {
+				return
+			}
+
+			var body struct {
+				ErrCode       string              `json:"errcode"`
+				Error         string              `json:"error"`
+				MissingFields []util.TenancyField `json:"missing_fields"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q is not JSON: %v", rec.Body.String(), err)
+			}
+			if body.ErrCode != tt.wantErrCode || body.Error == "" {
+				t.Errorf("body = %+v, want errcode %s", body, tt.wantErrCode)
+			}
+			if !slices.Equal(body.MissingFields, tt.wantMissing) {
+				t.Errorf("missing_fields = %v, want %v", body.MissingFields, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestRequireTenancyUnknownField(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RequireTenancy() did not panic for an unknown field")
+		}
+	}()
+	util.RequireTenancy(http.NotFoundHandler(), util.TenancyField("region"))
+}
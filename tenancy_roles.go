@@ -0,0 +1,85 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	// RoleWildcard granted as a role matches every role.
+	RoleWildcard = "*"
+	// RoleSeparator separates the levels of a hierarchical role such as "admin:billing".
+	RoleSeparator = ":"
+)
+
+// RoleMatches reports whether the granted role satisfies the required one.
+//
+// Roles are hierarchical, with levels separated by RoleSeparator. A granted
+// role matches a required role that is equal to it; a granted role ending in
+// ":*" matches every role below its prefix, so "admin:*" matches
+// "admin:billing" and "admin:billing:refunds" but not "admin" itself; and
+// RoleWildcard matches every role. Wildcards only have meaning in granted
+// roles: a required role of "admin:*" is only satisfied by a granted
+// "admin:*" or a broader wildcard.
+func RoleMatches(granted, required string) bool {
+	if granted == required || granted == RoleWildcard {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(granted, RoleSeparator+RoleWildcard)
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(required, prefix+RoleSeparator)
+}
+
+// HasRole reports whether the tenancy on ctx grants role, following the
+// wildcard rules of RoleMatches. It is false when the tenancy does not
+// implement RoleBearer.
+func HasRole(ctx context.Context, role string) bool {
+	return grantsRole(GetRoles(ctx), role)
+}
+
+// HasAllRoles reports whether the tenancy on ctx grants every one of roles.
+func HasAllRoles(ctx context.Context, roles ...string) bool {
+	granted := GetRoles(ctx)
+	for _, role := range roles {
+		if !grantsRole(granted, role) {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireRoles returns middleware that passes a request on only when the
+// tenancy on its context grants every one of roles (see HasRole). Other
+// requests, including those without tenancy, receive 403 Forbidden with a
+// JSON message body. With no roles, any request carrying tenancy passes.
+//
+// Tenancy must already be on the context, e.g. from WithTenancyFromHeaders.
+//
+// Example:
+//
+//	mux.Handle("/admin/", RequireRoles("admin:*")(adminHandler))
+func RequireRoles(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+			if GetTenancy(ctx) == nil || !HasAllRoles(ctx, roles...) {
+				w.Header().Set("Content-Type", "application/json")
+				respond(w, req, MessageResponse(http.StatusForbidden, "missing required role"))
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func grantsRole(granted []string, required string) bool {
+	for _, role := range granted {
+		if RoleMatches(role, required) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package util_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestRoleMatches(t *testing.T) {
+	tests := []struct {
+		granted, required string
+		want              bool
+	}{
+		{"admin", "admin", true},
+		{"admin", "admin:billing", false},
+		{"admin:*", "admin:billing", true},
+		{"admin:*", "admin:billing:refunds", true},
+		{"admin:*", "admin", false},
+		{"admin:*", "administrator:billing", false},
+		{"admin:billing:*", "admin:support", false},
+		{"*", "anything:at:all", true},
+		{"admin:billing", "admin:*", false},
+		{"admin:*", "admin:*", true},
+	}
+	for _, tt := range tests {
+		if got := util.RoleMatches(tt.granted, tt.required); got != tt.want {
+			t.Errorf("RoleMatches(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestHasAllRoles(t *testing.T) {
+	ctx := util.NewTenancy().TenantID("acme").Roles("admin:*", "reader").Context(context.Background())
+	if !util.HasRole(ctx, "admin:billing") {
+		t.Error("HasRole(admin:billing) = false with admin:* granted")
+	}
+	if !util.HasAllRoles(ctx, "reader", "admin:users") {
+		t.Error("HasAllRoles() = false for granted roles")
+	}
+	if util.HasAllRoles(ctx, "reader", "writer") {
+		t.Error("HasAllRoles() = true with writer missing")
+	}
+	if !util.HasAllRoles(context.Background()) {
+		t.Error("HasAllRoles() with no roles required should be true")
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	handler := util.RequireRoles("admin:billing", "reader")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"no tenancy", context.Background(), http.StatusForbidden},
+		{"missing role", util.NewTenancy().TenantID("acme").Roles("admin:*").Context(context.Background()), http.StatusForbidden},
+		{"granted", util.NewTenancy().TenantID("acme").Roles("admin:*", "reader").Context(context.Background()), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequestWithContext(tt.ctx, http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusForbidden {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			var body struct {
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Message == "" {
+				t.Errorf("body %q is not a JSON message: %v", rec.Body.String(), err)
+			}
+		})
+	}
+}
+
+func TestRequireRolesWithoutRoles(t *testing.T) {
+	handler := util.RequireRoles()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"no tenancy", context.Background(), http.StatusForbidden},
+		{"tenancy", util.NewTenancy().TenantID("acme").Context(context.Background()), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequestWithContext(tt.ctx, http.MethodGet, "/", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package util_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+// extendedTenancy implements every optional tenancy capability.
+type extendedTenancy struct {
+	stubTenancy
+
+	profileID, sessionID string
+	roles                []string
+}
+
+func (e extendedTenancy) GetProfileID() string { return e.profileID }
+func (e extendedTenancy) GetSessionID() string { return e.sessionID }
+func (e extendedTenancy) GetRoles() []string   { return e.roles }
+
+func TestTenancyCapabilities(t *testing.T) {
+	extended := extendedTenancy{
+		stubTenancy: stubTenancy{tenantID: "tenant", partitionID: "partition", accessID: "access"},
+		profileID:   "profile",
+		sessionID:   "session",
+		roles:       []string{"admin", "billing"},
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		wantProfile string
+		wantSession string
+		wantAdmin   bool
+	}{
+		{"no tenancy", context.Background(), "", "", false},
+		{"minimal tenancy", util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant"}), "", "", false},
+		{"extended tenancy", util.SetTenancy(context.Background(), extended), "profile", "session", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := util.GetProfileID(tt.ctx); got != tt.wantProfile {
+				t.Errorf("GetProfileID() = %q, want %q", got, tt.wantProfile)
+			}
+			if got := util.GetSessionID(tt.ctx); got != tt.wantSession {
+				t.Errorf("GetSessionID() = %q, want %q", got, tt.wantSession)
+			}
+			if got := util.HasRole(tt.ctx, "admin"); got != tt.wantAdmin {
+				t.Errorf("HasRole(admin) = %v, want %v", got, tt.wantAdmin)
+			}
+			if util.HasRole(tt.ctx, "owner") {
+				t.Error("HasRole(owner) = true for a role that was not granted")
+			}
+		})
+	}
+
+	if got := util.GetTenancy(util.SetTenancy(context.Background(), extended)); got.GetTenantID() != "tenant" {
+		t.Errorf("GetTenancy().GetTenantID() = %q, want tenant", got.GetTenantID())
+	}
+}
+
+func TestTenancyAccessors(t *testing.T) {
+	ctx := util.SetTenancy(context.Background(), stubTenancy{tenantID: "tenant", partitionID: "partition", accessID: "access"})
+	if got := util.GetTenantID(ctx); got != "tenant" {
+		t.Errorf("GetTenantID() = %q, want tenant", got)
+	}
+	if got := util.GetPartitionID(ctx); got != "partition" {
+		t.Errorf("GetPartitionID() = %q, want partition", got)
+	}
+	if got := util.GetAccessID(ctx); got != "access" {
+		t.Errorf("GetAccessID() = %q, want access", got)
+	}
+
+	empty := context.Background()
+	if util.GetTenantID(empty) != "" || util.GetPartitionID(empty) != "" || util.GetAccessID(empty) != "" {
+		t.Error("accessors returned values for a context without tenancy")
+	}
+}
+
+func TestNewTenancy(t *testing.T) {
+	builder := util.NewTenancy().
+		TenantID("acme").
+		PartitionID("eu").
+		AccessID("client-1").
+		ProfileID("user-7").
+		SessionID("sess-3").
+		Roles("admin", "billing")
+
+	tenancy := builder.Build()
+	want := util.DefaultTenancy{
+		TenantID:    "acme",
+		PartitionID: "eu",
+		AccessID:    "client-1",
+		ProfileID:   "user-7",
+		SessionID:   "sess-3",
+		Roles:       []string{"admin", "billing"},
+	}
+	if !reflect.DeepEqual(tenancy, want) {
+		t.Errorf("Build() = %+v, want %+v", tenancy, want)
+	}
+
+	// Built tenancies are independent of later builder changes.
+	builder.Roles("owner")
+	if len(tenancy.Roles) != 2 {
+		t.Errorf("Build() result changed after the builder was reused: %v", tenancy.Roles)
+	}
+
+	ctx := builder.Context(context.Background())
+	if util.GetTenancy(ctx).GetTenantID() != "acme" || util.GetProfileID(ctx) != "user-7" ||
+		util.GetSessionID(ctx) != "sess-3" || !util.HasRole(ctx, "owner") {
+		t.Errorf("Context() tenancy = %+v", util.GetTenancy(ctx))
+	}
+}
+
+func TestDefaultTenancyJSON(t *testing.T) {
+	tenancy := util.NewTenancy().TenantID("acme").PartitionID("eu").ProfileID("user-7").Build()
+
+	data, err := json.Marshal(tenancy)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+	if want := `{"tenant_id":"acme","partition_id":"eu","profile_id":"user-7"}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded util.DefaultTenancy
+	if err = json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, tenancy) {
+		t.Errorf("json.Unmarshal() = %+v, %v; want %+v", decoded, err, tenancy)
+	}
+}
@@ -0,0 +1,91 @@
+package util
+
+import "context"
+
+// TestingT is the part of testing.TB used by the test helpers
+// ContextWithTestTenancy and AssertContext, so this package does not import
+// testing.
+type TestingT interface {
+	Helper()
+	Context() context.Context
+	Errorf(format string, args ...any)
+}
+
+// TestTenancyOption customizes a tenancy built by TestTenancy.
+type TestTenancyOption func(*DefaultTenancy)
+
+// WithTestTenantID sets the tenant ID of a test tenancy.
+func WithTestTenantID(id string) TestTenancyOption {
+	return func(t *DefaultTenancy) {
+		t.TenantID = id
+	}
+}
+
+// WithTestPartitionID sets the partition ID of a test tenancy.
+func WithTestPartitionID(id string) TestTenancyOption {
+	return func(t *DefaultTenancy) {
+		t.PartitionID = id
+	}
+}
+
+// WithTestAccessID sets the access ID of a test tenancy.
+func WithTestAccessID(id string) TestTenancyOption {
+	return func(t *DefaultTenancy) {
+		t.AccessID = id
+	}
+}
+
+// WithTestProfileID sets the profile ID of a test tenancy.
+func WithTestProfileID(id string) TestTenancyOption {
+	return func(t *DefaultTenancy) {
+		t.ProfileID = id
+	}
+}
+
+// WithTestRoles sets the roles of a test tenancy.
+func WithTestRoles(roles ...string) TestTenancyOption {
+	return func(t *DefaultTenancy) {
+		t.Roles = roles
+	}
+}
+
+// TestTenancy returns a fully populated tenancy for tests. Every ID is
+// unique to the call, so tenancies built for different cases never collide
+// and cross-tenant leaks show up as mismatches. Options override fields.
+//
+// Example:
+//
+//	tests := []struct {
+//	    name    string
+//	    tenancy DefaultTenancy
+//	}{
+//	    {"admin", TestTenancy(WithTestRoles("admin"))},
+//	    {"other tenant", TestTenancy(WithTestTenantID("other"))},
+//	}
+func TestTenancy(opts ...TestTenancyOption) DefaultTenancy {
+	tenancy := DefaultTenancy{
+		TenantID:    "tenant-" + IDString(),
+		PartitionID: "partition-" + IDString(),
+		AccessID:    "access-" + IDString(),
+		ProfileID:   "profile-" + IDString(),
+		SessionID:   "session-" + IDString(),
+	}
+	for _, opt := range opts {
+		opt(&tenancy)
+	}
+	return tenancy
+}
+
+// ContextWithTestTenancy returns the test's context carrying a TestTenancy
+// built with opts. The context is canceled when the test ends.
+//
+// Example:
+//
+//	func TestCreateOrder(t *testing.T) {
+//	    ctx := util.ContextWithTestTenancy(t, util.WithTestRoles("orders:write"))
+//	    ...
+//	}
+func ContextWithTestTenancy(t TestingT, opts ...TestTenancyOption) context.Context {
+	t.Helper()
+	return SetTenancy(t.Context(), TestTenancy(opts...))
+}
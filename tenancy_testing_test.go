@@ -0,0 +1,37 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTestTenancy(t *testing.T) {
+	first, second := util.TestTenancy(), util.TestTenancy()
+	for name, pair := range map[string][2]string{
+		"tenant":    {first.TenantID, second.TenantID},
+		"partition": {first.PartitionID, second.PartitionID},
+		"access":    {first.AccessID, second.AccessID},
+		"profile":   {first.ProfileID, second.ProfileID},
+		"session":   {first.SessionID, second.SessionID},
+	} {
+		if pair[0] == "" || pair[0] == pair[1] {
+			t.Errorf("%s IDs %q and %q are not unique and populated", name, pair[0], pair[1])
+		}
+	}
+
+	custom := util.TestTenancy(util.WithTestTenantID("acme"), util.WithTestRoles("admin"))
+	if custom.TenantID != "acme" || len(custom.Roles) != 1 || custom.PartitionID == "" {
+		t.Errorf("TestTenancy() with options = %+v", custom)
+	}
+}
+
+func TestContextWithTestTenancy(t *testing.T) {
+	ctx := util.ContextWithTestTenancy(t, util.WithTestRoles("orders:*"))
+	if util.GetTenantID(ctx) == "" || util.GetPartitionID(ctx) == "" {
+		t.Error("context tenancy is not populated")
+	}
+	if !util.HasRole(ctx, "orders:write") {
+		t.Error("context tenancy lacks the requested role")
+	}
+}
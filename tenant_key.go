@@ -0,0 +1,39 @@
+package util
+
+import (
+	"context"
+	"strings"
+)
+
+// tenantKeySeparator separates the segments of a TenantKey.
+const tenantKeySeparator = ":"
+
+// tenantKeyEscaper escapes the separator and the escape character itself, so
+// no segment can contain the separator and every key splits back into its
+// segments unambiguously.
+var tenantKeyEscaper = strings.NewReplacer("%", "%25", ":", "%3A") //nolint:gochecknoglobals // immutable replacer
+
+// TenantKey composes the tenant and partition IDs on ctx with parts into a
+// key for caches, locks and HMAC inputs, of the form
+// "tenant:partition:part1:part2".
+//
+// Each segment is escaped, ':' as "%3A" and '%' as "%25", so different
+// tenancies or parts never produce the same key: a caller-provided part
+// cannot forge a boundary and reach into another tenant's keys. The key is
+// deterministic, so every instance of a service derives the same one.
+//
+// Without tenancy on ctx the tenant and partition segments are empty, which
+// is a namespace of its own that no tenant shares.
+//
+// Example:
+//
+//	cache.Get(ctx, TenantKey(ctx, "invoice", invoiceID)) // "acme:eu:invoice:inv-42"
+func TenantKey(ctx context.Context, parts ...string) string {
+	segments := make([]string, 0, len(parts)+2) //nolint:mnd // tenant and partition
+	segments = append(segments, GetTenantID(ctx), GetPartitionID(ctx))
+	segments = append(segments, parts...)
+	for i, segment := range segments {
+		segments[i] = tenantKeyEscaper.Replace(segment)
+	}
+	return strings.Join(segments, tenantKeySeparator)
+}
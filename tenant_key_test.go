@@ -0,0 +1,35 @@
+package util_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTenantKey(t *testing.T) {
+	acme := util.NewTenancy().TenantID("acme").PartitionID("eu").Context(context.Background())
+
+	if got := util.TenantKey(acme, "invoice", "inv-42"); got != "acme:eu:invoice:inv-42" {
+		t.Errorf("TenantKey() = %q", got)
+	}
+	if got := util.TenantKey(acme, "user:7", "100%"); got != "acme:eu:user%3A7:100%25" {
+		t.Errorf("TenantKey() with separators = %q", got)
+	}
+	if got := util.TenantKey(context.Background(), "x"); got != "::x" {
+		t.Errorf("TenantKey() without tenancy = %q", got)
+	}
+
+	// Keys that would collide under naive joining must differ.
+	colliding := util.NewTenancy().TenantID("acme:eu").PartitionID("x").Context(context.Background())
+	pairs := [][2]string{
+		{util.TenantKey(acme, "x:y"), util.TenantKey(acme, "x", "y")},
+		{util.TenantKey(colliding), util.TenantKey(acme, "x")},
+		{util.TenantKey(acme, "a%3Ab"), util.TenantKey(acme, "a:b")},
+	}
+	for _, pair := range pairs {
+		if pair[0] == pair[1] {
+			t.Errorf("distinct inputs produced the same key %q", pair[0])
+		}
+	}
+}
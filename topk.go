@@ -0,0 +1,176 @@
+package util
+
+import (
+	"cmp"
+	"container/heap"
+)
+
+// TopK returns the k greatest elements of s according to less, greatest
+// first, e.g. a leaderboard's leaders. It keeps a heap of k elements, so it
+// runs in O(n log k) time and O(k) memory rather than sorting s, which is not
+// modified. Among equal elements the earlier ones win.
+//
+// Example:
+//
+//	leaders := TopK(players, 10, func(a, b Player) bool { return a.Score < b.Score })
+func TopK[T any](s []T, k int, less func(a, b T) bool) []T {
+	if k <= 0 || len(s) == 0 {
+		return nil
+	}
+	k = min(k, len(s))
+
+	// h is a min-heap of the best k seen so far, keyed by position to
+	// prefer earlier elements among equals.
+	h := &topKHeap[T]{less: less, items: make([]topKItem[T], 0, k)}
+	for i, v := range s {
+		item := topKItem[T]{value: v, index: i}
+		if h.Len() < k {
+			heap.Push(h, item)
+		} else if h.better(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+
+	out := make([]T, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		item, _ := heap.Pop(h).(topKItem[T])
+		out[i] = item.value
+	}
+	return out
+}
+
+type topKItem[T any] struct {
+	value T
+	index int
+}
+
+type topKHeap[T any] struct {
+	less  func(a, b T) bool
+	items []topKItem[T]
+}
+
+// better reports whether a ranks above b.
+func (h *topKHeap[T]) better(a, b topKItem[T]) bool {
+	if h.less(b.value, a.value) {
+		return true
+	}
+	if h.less(a.value, b.value) {
+		return false
+	}
+	return a.index < b.index
+}
+
+func (h *topKHeap[T]) Len() int           { return len(h.items) }
+func (h *topKHeap[T]) Less(i, j int) bool { return h.better(h.items[j], h.items[i]) }
+func (h *topKHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *topKHeap[T]) Push(x any)         { item, _ := x.(topKItem[T]); h.items = append(h.items, item) }
+
+func (h *topKHeap[T]) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// mergeSortedOptions contains configuration for MergeSorted.
+type mergeSortedOptions struct {
+	// dedupe drops elements equal to the previous one
+	dedupe bool
+}
+
+// MergeSortedOption is a function that configures MergeSorted and
+// MergeSortedFunc.
+type MergeSortedOption func(*mergeSortedOptions)
+
+// WithMergeDedupe drops elements equal to the previously merged one, so the
+// result holds each value once.
+func WithMergeDedupe() MergeSortedOption {
+	return func(o *mergeSortedOptions) {
+		o.dedupe = true
+	}
+}
+
+// MergeSorted merges slices sorted in ascending order into one sorted slice
+// in O(n log m) for n elements in m slices. Equal elements keep the order of
+// the slices they come from.
+//
+// Example:
+//
+//	ids := MergeSorted([][]int{shardA, shardB, shardC}, WithMergeDedupe())
+func MergeSorted[T cmp.Ordered](lists [][]T, opts ...MergeSortedOption) []T {
+	return MergeSortedFunc(lists, cmp.Compare[T], opts...)
+}
+
+// MergeSortedFunc is MergeSorted for slices sorted by compare, such as log
+// records sorted by timestamp.
+//
+// Example:
+//
+//	merged := MergeSortedFunc([][]Record{nodeA, nodeB}, func(a, b Record) int {
+//	    return a.Time.Compare(b.Time)
+//	})
+func MergeSortedFunc[T any](lists [][]T, compare func(a, b T) int, opts ...MergeSortedOption) []T {
+	var options mergeSortedOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	total := 0
+	h := &mergeHeap[T]{compare: compare}
+	for i, list := range lists {
+		total += len(list)
+		if len(list) > 0 {
+			h.cursors = append(h.cursors, mergeCursor[T]{list: list, source: i})
+		}
+	}
+	heap.Init(h)
+
+	out := make([]T, 0, total)
+	for h.Len() > 0 {
+		cursor := &h.cursors[0]
+		v := cursor.list[cursor.pos]
+		if !options.dedupe || len(out) == 0 || compare(out[len(out)-1], v) != 0 {
+			out = append(out, v)
+		}
+		cursor.pos++
+		if cursor.pos == len(cursor.list) {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return out
+}
+
+type mergeCursor[T any] struct {
+	list   []T
+	pos    int
+	source int
+}
+
+type mergeHeap[T any] struct {
+	compare func(a, b T) int
+	cursors []mergeCursor[T]
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.cursors[i], h.cursors[j]
+	if c := h.compare(a.list[a.pos], b.list[b.pos]); c != 0 {
+		return c < 0
+	}
+	return a.source < b.source
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+func (h *mergeHeap[T]) Push(x any) {
+	cursor, _ := x.(mergeCursor[T])
+	h.cursors = append(h.cursors, cursor)
+}
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
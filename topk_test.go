@@ -0,0 +1,66 @@
+package util_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTopK(t *testing.T) {
+	type player struct {
+		name  string
+		score int
+	}
+	players := []player{{"a", 5}, {"b", 9}, {"c", 7}, {"d", 9}, {"e", 1}, {"f", 7}}
+	byScore := func(x, y player) bool { return x.score < y.score }
+
+	got := util.TopK(players, 3, byScore)
+	want := []player{{"b", 9}, {"d", 9}, {"c", 7}}
+	if !slices.Equal(got, want) {
+		t.Errorf("TopK = %v, want %v", got, want)
+	}
+
+	if got := util.TopK(players, 10, byScore); len(got) != len(players) || got[len(got)-1].name != "e" {
+		t.Errorf("TopK with k > len = %v", got)
+	}
+	if got := util.TopK(players, 0, byScore); got != nil {
+		t.Errorf("TopK with k = 0 = %v, want nil", got)
+	}
+
+	less := func(a, b int) bool { return a < b }
+	input := []int{4, 8, 1, 9, 3}
+	if got := util.TopK(input, 2, less); !slices.Equal(got, []int{9, 8}) {
+		t.Errorf("TopK of ints = %v", got)
+	}
+	if !slices.Equal(input, []int{4, 8, 1, 9, 3}) {
+		t.Errorf("TopK modified its input: %v", input)
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	lists := [][]int{{1, 4, 7}, {2, 4, 8, 9}, nil, {0, 4}}
+	if got := util.MergeSorted(lists); !slices.Equal(got, []int{0, 1, 2, 4, 4, 4, 7, 8, 9}) {
+		t.Errorf("MergeSorted = %v", got)
+	}
+	if got := util.MergeSorted(lists, util.WithMergeDedupe()); !slices.Equal(got, []int{0, 1, 2, 4, 7, 8, 9}) {
+		t.Errorf("MergeSorted with dedupe = %v", got)
+	}
+	if got := util.MergeSorted[int](nil); len(got) != 0 {
+		t.Errorf("MergeSorted(nil) = %v", got)
+	}
+}
+
+func TestMergeSortedFuncIsStable(t *testing.T) {
+	type record struct {
+		time int
+		node string
+	}
+	a := []record{{1, "a"}, {3, "a"}}
+	b := []record{{1, "b"}, {2, "b"}, {3, "b"}}
+	got := util.MergeSortedFunc([][]record{a, b}, func(x, y record) int { return x.time - y.time })
+	want := []record{{1, "a"}, {1, "b"}, {2, "b"}, {3, "a"}, {3, "b"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("MergeSortedFunc = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	typedIDSeparator    = "_"
+	maxTypedIDPrefixLen = 16
+)
+
+// Typed ID errors.
+var (
+	// ErrInvalidTypedID is returned for strings that are not a well-formed typed ID.
+	ErrInvalidTypedID = errors.New("invalid typed ID")
+	// ErrWrongIDKind is returned when a typed ID has a different prefix than expected.
+	ErrWrongIDKind = errors.New("wrong kind of ID")
+)
+
+// TypedID is a parsed prefixed ID such as "usr_01hx5k8m2v7q9r3t6w0y4z1b2c".
+type TypedID struct {
+	// Prefix names the kind of entity, e.g. "usr".
+	Prefix string
+	// ULID is the lowercase ULID following the prefix.
+	ULID string
+}
+
+// String returns the ID in its "prefix_ulid" form.
+func (id TypedID) String() string {
+	return id.Prefix + typedIDSeparator + id.ULID
+}
+
+// Time returns the creation time encoded in the ID's ULID.
+func (id TypedID) Time() time.Time {
+	t, _ := ULIDTime(id.ULID)
+	return t
+}
+
+// NewTypedID returns a new Stripe-style ID: prefix, an underscore and a
+// lowercase ULID, e.g. "usr_01hx5k8m2v7q9r3t6w0y4z1b2c". The prefix makes the
+// kind of entity obvious in logs and URLs, and IDs sort by creation time.
+//
+// prefix must be 1 to 16 lowercase ASCII letters or digits; NewTypedID panics
+// otherwise, since prefixes are fixed in code.
+//
+// Example:
+//
+//	user.ID = NewTypedID("usr")
+func NewTypedID(prefix string) string {
+	if err := validateTypedIDPrefix(prefix); err != nil {
+		panic(err)
+	}
+	return prefix + typedIDSeparator + strings.ToLower(ULID())
+}
+
+// ParseTypedID parses id and checks that it carries prefix, so a handler
+// can reject, say, an order ID passed where a user ID belongs. An empty
+// prefix accepts any kind.
+//
+// Returns an error wrapping ErrInvalidTypedID for malformed IDs and
+// ErrWrongIDKind for IDs of another kind.
+//
+// Example:
+//
+//	userID, err := ParseTypedID(r.PathValue("id"), "usr")
+//	if err != nil {
+//	    return MessageResponse(http.StatusBadRequest, err.Error())
+//	}
+func ParseTypedID(id, prefix string) (TypedID, error) {
+	gotPrefix, ulid, ok := strings.Cut(id, typedIDSeparator)
+	if !ok || validateTypedIDPrefix(gotPrefix) != nil {
+		return TypedID{}, fmt.Errorf("%w: missing or malformed prefix", ErrInvalidTypedID)
+	}
+	if ulid != strings.ToLower(ulid) {
+		return TypedID{}, fmt.Errorf("%w: ID must be lowercase", ErrInvalidTypedID)
+	}
+	if _, err := ULIDTime(ulid); err != nil {
+		return TypedID{}, fmt.Errorf("%w: %w", ErrInvalidTypedID, err)
+	}
+	if prefix != "" && gotPrefix != prefix {
+		return TypedID{}, fmt.Errorf("%w: got %q, want %q", ErrWrongIDKind, gotPrefix, prefix)
+	}
+	return TypedID{Prefix: gotPrefix, ULID: ulid}, nil
+}
+
+func validateTypedIDPrefix(prefix string) error {
+	if prefix == "" || len(prefix) > maxTypedIDPrefixLen {
+		return fmt.Errorf("typed ID prefix %q must be 1 to %d characters", prefix, maxTypedIDPrefixLen)
+	}
+	for i := range len(prefix) {
+		if c := prefix[i]; (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return fmt.Errorf("typed ID prefix %q must be lowercase letters and digits", prefix)
+		}
+	}
+	return nil
+}
+
+// TypedIDRegistry records the ID prefixes a service uses and the kind of
+// entity each names, so prefixes stay unique and IDs of unknown kinds are
+// rejected. It is safe for concurrent use.
+type TypedIDRegistry struct {
+	mu    sync.RWMutex
+	kinds map[string]string
+}
+
+// NewTypedIDRegistry creates an empty registry.
+//
+// Example:
+//
+//	ids := NewTypedIDRegistry()
+//	_ = ids.Register("usr", "user")
+//	_ = ids.Register("ord", "order")
+//	orderID, err := ids.Parse(r.PathValue("id"), "ord")
+func NewTypedIDRegistry() *TypedIDRegistry {
+	return &TypedIDRegistry{kinds: make(map[string]string)}
+}
+
+// Register adds prefix for the entity kind. Returns an error when prefix is
+// malformed or already registered for another kind.
+func (r *TypedIDRegistry) Register(prefix, kind string) error {
+	if err := validateTypedIDPrefix(prefix); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.kinds[prefix]; ok && existing != kind {
+		return fmt.Errorf("typed ID prefix %q is already registered for %s", prefix, existing)
+	}
+	r.kinds[prefix] = kind
+	return nil
+}
+
+// Kind returns the entity kind registered for prefix.
+func (r *TypedIDRegistry) Kind(prefix string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kind, ok := r.kinds[prefix]
+	return kind, ok
+}
+
+// New returns a new ID with a registered prefix, like NewTypedID.
+func (r *TypedIDRegistry) New(prefix string) (string, error) {
+	if _, ok := r.Kind(prefix); !ok {
+		return "", fmt.Errorf("typed ID prefix %q is not registered", prefix)
+	}
+	return NewTypedID(prefix), nil
+}
+
+// Parse parses id like ParseTypedID and additionally rejects prefixes that
+// are not registered with ErrWrongIDKind. An empty prefix accepts any
+// registered kind.
+func (r *TypedIDRegistry) Parse(id, prefix string) (TypedID, error) {
+	parsed, err := ParseTypedID(id, prefix)
+	if err != nil {
+		return TypedID{}, err
+	}
+	if _, ok := r.Kind(parsed.Prefix); !ok {
+		return TypedID{}, fmt.Errorf("%w: unregistered prefix %q", ErrWrongIDKind, parsed.Prefix)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,76 @@
+package util_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestTypedID(t *testing.T) {
+	id := util.NewTypedID("usr")
+	if !strings.HasPrefix(id, "usr_") || len(id) != len("usr_")+26 || id != strings.ToLower(id) {
+		t.Fatalf("NewTypedID() = %q", id)
+	}
+
+	parsed, err := util.ParseTypedID(id, "usr")
+	if err != nil || parsed.Prefix != "usr" || parsed.String() != id {
+		t.Fatalf("ParseTypedID() = %+v, %v", parsed, err)
+	}
+	if age := time.Since(parsed.Time()); age < 0 || age > time.Minute {
+		t.Errorf("Time() = %v, want about now", parsed.Time())
+	}
+	if _, err = util.ParseTypedID(id, ""); err != nil {
+		t.Errorf("ParseTypedID() with any prefix error = %v", err)
+	}
+
+	if _, err = util.ParseTypedID(id, "ord"); !errors.Is(err, util.ErrWrongIDKind) {
+		t.Errorf("ParseTypedID() with another prefix error = %v, want ErrWrongIDKind", err)
+	}
+	for _, bad := range []string{"", "usr", "usr_", "_01hx5k8m2v7q9r3t6w0y4z1b2c", "USR_" + id[4:], strings.ToUpper(id), "usr_notaulid"} {
+		if _, err = util.ParseTypedID(bad, ""); !errors.Is(err, util.ErrInvalidTypedID) {
+			t.Errorf("ParseTypedID(%q) error = %v, want ErrInvalidTypedID", bad, err)
+		}
+	}
+}
+
+func TestNewTypedIDPanicsOnBadPrefix(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewTypedID() did not panic for an invalid prefix")
+		}
+	}()
+	util.NewTypedID("User")
+}
+
+func TestTypedIDRegistry(t *testing.T) {
+	ids := util.NewTypedIDRegistry()
+	if err := ids.Register("usr", "user"); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := ids.Register("usr", "user"); err != nil {
+		t.Errorf("re-registering the same kind error = %v", err)
+	}
+	if err := ids.Register("usr", "order"); err == nil {
+		t.Error("Register() allowed a prefix for two kinds")
+	}
+	if kind, ok := ids.Kind("usr"); !ok || kind != "user" {
+		t.Errorf("Kind() = %q, %v", kind, ok)
+	}
+
+	id, err := ids.New("usr")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err = ids.Parse(id, ""); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+	if _, err = ids.New("ord"); err == nil {
+		t.Error("New() accepted an unregistered prefix")
+	}
+	if _, err = ids.Parse(util.NewTypedID("ord"), ""); !errors.Is(err, util.ErrWrongIDKind) {
+		t.Errorf("Parse() of an unregistered kind error = %v, want ErrWrongIDKind", err)
+	}
+}
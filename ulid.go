@@ -0,0 +1,167 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ulidLength        = 26
+	ulidSize          = 16
+	ulidEntropySize   = 10
+	ulidBitsPerChar   = 5
+	ulidCharMask      = 0x1f
+	ulidWordBits      = 64
+	ulidMaxFirstChar  = '7'
+	ulidMaxTimestamp  = 1<<48 - 1
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// ErrInvalidULID is returned by ULIDTime for strings that are not a ULID.
+var ErrInvalidULID = errors.New("invalid ULID")
+
+// ulidState holds the last timestamp and entropy issued, so ULIDs created
+// within the same millisecond increase monotonically.
+type ulidState struct {
+	mu      sync.Mutex
+	ms      uint64
+	entropy [ulidEntropySize]byte
+}
+
+var ulidMonotonic ulidState //nolint:gochecknoglobals // process-wide monotonic sequence
+
+// ULID returns a new ULID (https://github.com/ulid/spec) for the current
+// time: 26 Crockford base32 characters that sort lexicographically in
+// creation order, with 80 bits of cryptographically random entropy.
+//
+// ULIDs created in the same millisecond share the random part incremented by
+// one, so they stay strictly increasing even under concurrent use.
+//
+// Example:
+//
+//	id := ULID() // "01J1QW4C9Z8K3V6R0M5E2A7D4B"
+func ULID() string {
+	return ULIDWithTime(time.Now())
+}
+
+// ULIDWithTime returns a new ULID for t, truncated to the millisecond.
+// Monotonicity is kept for repeated calls with the latest time used; a ULID
+// for an earlier time gets fresh entropy. Times outside the ULID range of
+// 1970 to 10889 are clamped to it.
+func ULIDWithTime(t time.Time) string {
+	var ms uint64
+	if unixMs := t.UnixMilli(); unixMs > 0 {
+		ms = min(uint64(unixMs), ulidMaxTimestamp)
+	}
+	ms, entropy := ulidMonotonic.next(ms)
+	return encodeULID(ms, entropy)
+}
+
+func (s *ulidState) next(ms uint64) (uint64, [ulidEntropySize]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ms < s.ms {
+		return ms, randomULIDEntropy()
+	}
+	if ms == s.ms && incrementEntropy(&s.entropy) {
+		return s.ms, s.entropy
+	}
+	if ms == s.ms {
+		// The entropy overflowed: move to the next millisecond so IDs keep increasing.
+		ms++
+	}
+	s.ms, s.entropy = ms, randomULIDEntropy()
+	return s.ms, s.entropy
+}
+
+// incrementEntropy adds one to the big-endian entropy, reporting false on overflow.
+func incrementEntropy(entropy *[ulidEntropySize]byte) bool {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func randomULIDEntropy() [ulidEntropySize]byte {
+	var entropy [ulidEntropySize]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		panic(err)
+	}
+	return entropy
+}
+
+// encodeULID encodes the 48-bit timestamp and 80-bit entropy as 26 base32
+// characters, most significant first.
+func encodeULID(ms uint64, entropy [ulidEntropySize]byte) string {
+	var raw [ulidSize]byte
+	binary.BigEndian.PutUint16(raw[0:2], uint16(ms>>32)) //nolint:gosec,mnd // top 16 of 48 bits
+	binary.BigEndian.PutUint32(raw[2:6], uint32(ms))     //nolint:gosec // low 32 of 48 bits
+	copy(raw[6:], entropy[:])
+	return encodeULIDBytes(raw)
+}
+
+// encodeULIDBytes encodes 128 bits as 26 base32 characters.
+func encodeULIDBytes(raw [ulidSize]byte) string {
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+
+	var out [ulidLength]byte
+	for i := ulidLength - 1; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&ulidCharMask]
+		lo = lo>>ulidBitsPerChar | hi<<(ulidWordBits-ulidBitsPerChar)
+		hi >>= ulidBitsPerChar
+	}
+	return string(out[:])
+}
+
+// decodeULID decodes a ULID, case-insensitively, into its 128 bits.
+func decodeULID(id string) ([ulidSize]byte, error) {
+	var raw [ulidSize]byte
+	if len(id) != ulidLength || id[0] > ulidMaxFirstChar {
+		return raw, ErrInvalidULID
+	}
+
+	var hi, lo uint64
+	for i := range len(id) {
+		value := strings.IndexByte(crockfordAlphabet, upperASCII(id[i]))
+		if value < 0 {
+			return raw, ErrInvalidULID
+		}
+		hi = hi<<ulidBitsPerChar | lo>>(ulidWordBits-ulidBitsPerChar)
+		lo = lo<<ulidBitsPerChar | uint64(value)
+	}
+	binary.BigEndian.PutUint64(raw[0:8], hi)
+	binary.BigEndian.PutUint64(raw[8:16], lo)
+	return raw, nil
+}
+
+// ULIDTime returns the creation time encoded in a ULID, to the millisecond.
+// Decoding is case-insensitive. Returns ErrInvalidULID when id is not a
+// well-formed ULID.
+//
+// Example:
+//
+//	created, err := ULIDTime(order.ID)
+func ULIDTime(id string) (time.Time, error) {
+	raw, err := decodeULID(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ms := uint64(binary.BigEndian.Uint16(raw[0:2]))<<32 | uint64(binary.BigEndian.Uint32(raw[2:6])) //nolint:mnd // 48 bits
+	return time.UnixMilli(int64(ms)), nil                                                           //nolint:gosec // at most 48 bits
+}
+
+func upperASCII(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
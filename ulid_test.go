@@ -0,0 +1,78 @@
+package util_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestULIDTime(t *testing.T) {
+	// Example from the ULID specification.
+	got, err := util.ULIDTime("01ARYZ6S41TSV4RRFFQ69G5FAV")
+	if err != nil || got.UnixMilli() != 1469918176385 {
+		t.Errorf("ULIDTime() = %v, %v; want 1469918176385 ms", got.UnixMilli(), err)
+	}
+	if lower, _ := util.ULIDTime("01aryz6s41tsv4rrffq69g5fav"); !lower.Equal(got) {
+		t.Error("ULIDTime() is not case-insensitive")
+	}
+
+	for _, id := range []string{"", "01ARYZ6S41", "81ARYZ6S41TSV4RRFFQ69G5FAV", "01ARYZ6S41TSV4RRFFQ69G5FAU!", "01ARYZ6S41TSV4RRFFQ69G5FAI"} {
+		if _, err = util.ULIDTime(id); !errors.Is(err, util.ErrInvalidULID) {
+			t.Errorf("ULIDTime(%q) error = %v, want ErrInvalidULID", id, err)
+		}
+	}
+}
+
+func TestULIDWithTime(t *testing.T) {
+	now := time.UnixMilli(1469918176385)
+	id := util.ULIDWithTime(now)
+	if len(id) != 26 || !strings.HasPrefix(id, "01ARYZ6S41") {
+		t.Errorf("ULIDWithTime() = %q, want the 01ARYZ6S41 time prefix", id)
+	}
+	if got, err := util.ULIDTime(id); err != nil || !got.Equal(now) {
+		t.Errorf("ULIDTime(ULIDWithTime()) = %v, %v; want %v", got, err, now)
+	}
+
+	// IDs for the same millisecond increase monotonically. The time must not
+	// precede IDs generated earlier in the process, so use the current time.
+	now = time.Now()
+	previous := util.ULIDWithTime(now)
+	for range 1000 {
+		next := util.ULIDWithTime(now)
+		if next <= previous {
+			t.Fatalf("ULID %q is not greater than %q", next, previous)
+		}
+		previous = next
+	}
+}
+
+func TestULIDConcurrent(t *testing.T) {
+	const workers, perWorker = 8, 500
+	results := make([][]string, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Go(func() {
+			for range perWorker {
+				results[w] = append(results[w], util.ULID())
+			}
+		})
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, workers*perWorker)
+	for _, ids := range results {
+		for i, id := range ids {
+			if seen[id] {
+				t.Fatalf("duplicate ULID %q", id)
+			}
+			seen[id] = true
+			if i > 0 && id <= ids[i-1] {
+				t.Fatalf("ULIDs from one goroutine are not increasing: %q after %q", id, ids[i-1])
+			}
+		}
+	}
+}
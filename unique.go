@@ -3,8 +3,14 @@
 package util
 
 import (
+	"cmp"
 	"errors"
+	"slices"
 	"sort"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Unique removes duplicate items from a sorted list in place.
@@ -12,6 +18,9 @@ import (
 // Returns the length of the data without duplicates
 // Uses the last occurrence of a duplicate.
 // O(n).
+//
+// Deprecated: Use UniqueSlice or UniqueSorted, which need no sort.Interface
+// wrapper type.
 func Unique(data sort.Interface) int {
 	if !sort.IsSorted(data) {
 		panic(errors.New("util: the input to Unique() must be sorted"))
@@ -43,12 +52,268 @@ func Unique(data sort.Interface) int {
 }
 
 // SortAndUnique sorts the data and removes duplicates. O(nlog(n)).
+//
+// Deprecated: Use UniqueSorted.
 func SortAndUnique(data sort.Interface) int {
 	sort.Sort(data)
 	return Unique(data)
 }
 
+// uniqueStringsOptions contains configuration for UniqueStrings.
+type uniqueStringsOptions struct {
+	// caseInsensitive compares strings by their Unicode case folding
+	caseInsensitive bool
+
+	// trimSpace trims surrounding whitespace and drops empty strings
+	trimSpace bool
+
+	// normalize converts strings to Unicode NFC
+	normalize bool
+}
+
+// UniqueStringsOption is a function that configures UniqueStrings.
+type UniqueStringsOption func(*uniqueStringsOptions)
+
+// WithUniqueCaseInsensitive treats strings differing only in case as
+// duplicates, using full Unicode case folding. The first spelling is kept.
+func WithUniqueCaseInsensitive() UniqueStringsOption {
+	return func(o *uniqueStringsOptions) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithUniqueTrimSpace trims surrounding whitespace from each string and
+// drops the strings left empty.
+func WithUniqueTrimSpace() UniqueStringsOption {
+	return func(o *uniqueStringsOptions) {
+		o.trimSpace = true
+	}
+}
+
+// WithUniqueNormalize converts each string to Unicode normalization form
+// NFC, so precomposed and decomposed spellings of the same text, such as
+// "é" and "e\u0301", are duplicates.
+func WithUniqueNormalize() UniqueStringsOption {
+	return func(o *uniqueStringsOptions) {
+		o.normalize = true
+	}
+}
+
 // UniqueStrings returns a sorted slice of unique strings. O(nlog(n)).
-func UniqueStrings(strings []string) []string {
-	return strings[:SortAndUnique(sort.StringSlice(strings))]
+//
+// Without options it sorts and compacts values in place and returns a
+// prefix of it. With options it leaves values untouched and returns the
+// cleaned, deduplicated strings sorted by their comparison key, as needed
+// for user-entered emails and usernames.
+//
+// Example:
+//
+//	emails := UniqueStrings(input, WithUniqueTrimSpace(), WithUniqueCaseInsensitive())
+//	// [" Ann@example.com", "ann@example.com ", "bob@example.com"] -> [Ann@example.com bob@example.com]
+func UniqueStrings(values []string, opts ...UniqueStringsOption) []string {
+	if len(opts) == 0 {
+		return values[:SortAndUnique(sort.StringSlice(values))]
+	}
+
+	var options uniqueStringsOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	type entry struct{ key, value string }
+	seen := make(map[string]struct{}, len(values))
+	entries := make([]entry, 0, len(values))
+	for _, value := range values {
+		if options.trimSpace {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+		}
+		if options.normalize {
+			value = norm.NFC.String(value)
+		}
+		key := value
+		if options.caseInsensitive {
+			key = cases.Fold().String(value)
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		entries = append(entries, entry{key: key, value: value})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int { return cmp.Compare(a.key, b.key) })
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.value
+	}
+	return out
+}
+
+// UniqueSlice returns the distinct elements of s in the order they first
+// appear, without requiring s to be sorted. s is not modified. O(n).
+//
+// Example:
+//
+//	UniqueSlice([]string{"b", "a", "b", "c"}) // [b a c]
+func UniqueSlice[T comparable](s []T) []T {
+	seen := make(map[T]struct{}, len(s))
+	out := make([]T, 0, len(s))
+	for _, v := range s {
+		if _, dup := seen[v]; !dup {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// UniqueSorted returns the distinct elements of s in ascending order. s is
+// not modified. O(nlog(n)).
+//
+// Example:
+//
+//	UniqueSorted([]int{3, 1, 3, 2}) // [1 2 3]
+func UniqueSorted[T cmp.Ordered](s []T) []T {
+	out := slices.Clone(s)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// uniqueOptions contains configuration for UniqueFunc.
+type uniqueOptions struct {
+	// keepLast keeps the last element of each key instead of the first
+	keepLast bool
+}
+
+// UniqueOption is a function that configures UniqueFunc.
+type UniqueOption func(*uniqueOptions)
+
+// WithUniqueKeepLast makes UniqueFunc keep the last element with each key
+// instead of the first, e.g. the latest version of each record. The element
+// still takes the position where its key first appears.
+func WithUniqueKeepLast() UniqueOption {
+	return func(o *uniqueOptions) {
+		o.keepLast = true
+	}
+}
+
+// UniqueFunc returns the elements of s with distinct keys, in the order
+// their keys first appear. By default the first element with each key is
+// kept; see WithUniqueKeepLast. s is not modified. O(n).
+//
+// Example:
+//
+//	users = UniqueFunc(users, func(u User) string { return u.ID })
+//	tags = UniqueFunc(tags, strings.ToLower) // case-insensitive
+func UniqueFunc[T any, K comparable](s []T, key func(T) K, opts ...UniqueOption) []T {
+	var options uniqueOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	positions := make(map[K]int, len(s))
+	out := make([]T, 0, len(s))
+	for _, v := range s {
+		k := key(v)
+		if i, dup := positions[k]; dup {
+			if options.keepLast {
+				out[i] = v
+			}
+			continue
+		}
+		positions[k] = len(out)
+		out = append(out, v)
+	}
+	return out
+}
+
+// IntersectSorted returns the elements present in both a and b, which must
+// be sorted in ascending order. Duplicates appear once in the result, which
+// is sorted. O(len(a)+len(b)), without maps.
+//
+// Example:
+//
+//	IntersectSorted([]int{1, 2, 4, 5}, []int{2, 3, 5}) // [2 5]
+func IntersectSorted[T cmp.Ordered](a, b []T) []T {
+	out := make([]T, 0, min(len(a), len(b)))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch c := cmp.Compare(a[i], b[j]); {
+		case c < 0:
+			i++
+		case c > 0:
+			j++
+		default:
+			out = appendDistinct(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// UnionSorted returns the elements present in a or b, which must be sorted
+// in ascending order. Duplicates appear once in the result, which is sorted.
+// O(len(a)+len(b)), without maps.
+//
+// Example:
+//
+//	UnionSorted([]int{1, 2, 4}, []int{2, 3}) // [1 2 3 4]
+func UnionSorted[T cmp.Ordered](a, b []T) []T {
+	out := make([]T, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch c := cmp.Compare(a[i], b[j]); {
+		case c < 0:
+			out = appendDistinct(out, a[i])
+			i++
+		case c > 0:
+			out = appendDistinct(out, b[j])
+			j++
+		default:
+			out = appendDistinct(out, a[i])
+			i++
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = appendDistinct(out, a[i])
+	}
+	for ; j < len(b); j++ {
+		out = appendDistinct(out, b[j])
+	}
+	return out
+}
+
+// DiffSorted returns the elements of a that are not in b, both sorted in
+// ascending order, e.g. the IDs to delete when reconciling a stored list
+// with a desired one. Duplicates appear once in the result, which is sorted.
+// O(len(a)+len(b)), without maps.
+//
+// Example:
+//
+//	toDelete := DiffSorted(storedIDs, desiredIDs)
+//	toCreate := DiffSorted(desiredIDs, storedIDs)
+func DiffSorted[T cmp.Ordered](a, b []T) []T {
+	out := make([]T, 0, len(a))
+	j := 0
+	for _, v := range a {
+		for j < len(b) && cmp.Less(b[j], v) {
+			j++
+		}
+		if j < len(b) && cmp.Compare(b[j], v) == 0 {
+			continue
+		}
+		out = appendDistinct(out, v)
+	}
+	return out
+}
+
+// appendDistinct appends v to the sorted slice s unless it is its last element.
+func appendDistinct[T cmp.Ordered](s []T, v T) []T {
+	if len(s) > 0 && cmp.Compare(s[len(s)-1], v) == 0 {
+		return s
+	}
+	return append(s, v)
 }
@@ -1,6 +1,8 @@
 package util_test
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/pitabwire/util"
@@ -25,7 +27,7 @@ func TestUnique(t *testing.T) {
 	for _, test := range testCases {
 		input := []byte(test.Input)
 		want := test.Want
-		got := string(input[:util.Unique(sortBytes(input))])
+		got := string(input[:util.Unique(sortBytes(input))]) //nolint:staticcheck // covers the deprecated API
 		if got != want {
 			t.Fatal("Wanted ", want, " got ", got)
 		}
@@ -49,7 +51,7 @@ func TestUniquePicksLastDuplicate(t *testing.T) {
 		"avacado",
 		"cucumber",
 	}
-	got := input[:util.Unique(sortByFirstByte(input))]
+	got := input[:util.Unique(sortByFirstByte(input))] //nolint:staticcheck // covers the deprecated API
 
 	if len(want) != len(got) {
 		t.Errorf("Wanted %#v got %#v", want, got)
@@ -68,7 +70,7 @@ func TestUniquePanicsIfNotSorted(t *testing.T) {
 		}
 	}()
 	unsorted := sortBytes{'b', 'a'}
-	_ = util.Unique(unsorted)
+	_ = util.Unique(unsorted) //nolint:staticcheck // covers the deprecated API
 }
 
 func TestUniqueStrings(t *testing.T) {
@@ -90,3 +92,102 @@ func TestUniqueStrings(t *testing.T) {
 		}
 	}
 }
+
+func TestUniqueSlice(t *testing.T) {
+	input := []string{"b", "a", "b", "c", "a"}
+	if got := util.UniqueSlice(input); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Errorf("UniqueSlice = %q, want [b a c]", got)
+	}
+	if !slices.Equal(input, []string{"b", "a", "b", "c", "a"}) {
+		t.Errorf("UniqueSlice modified its input: %q", input)
+	}
+	if got := util.UniqueSlice([]int(nil)); len(got) != 0 {
+		t.Errorf("UniqueSlice(nil) = %v", got)
+	}
+}
+
+func TestUniqueSorted(t *testing.T) {
+	input := []int{3, 1, 3, 2, 1}
+	if got := util.UniqueSorted(input); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("UniqueSorted = %v, want [1 2 3]", got)
+	}
+	if !slices.Equal(input, []int{3, 1, 3, 2, 1}) {
+		t.Errorf("UniqueSorted modified its input: %v", input)
+	}
+}
+
+func TestUniqueFunc(t *testing.T) {
+	type record struct {
+		ID      string
+		Version int
+	}
+	records := []record{{"a", 1}, {"b", 1}, {"a", 2}, {"c", 1}, {"b", 2}}
+	byID := func(r record) string { return r.ID }
+
+	want := []record{{"a", 1}, {"b", 1}, {"c", 1}}
+	if got := util.UniqueFunc(records, byID); !slices.Equal(got, want) {
+		t.Errorf("UniqueFunc = %v, want %v", got, want)
+	}
+
+	want = []record{{"a", 2}, {"b", 2}, {"c", 1}}
+	if got := util.UniqueFunc(records, byID, util.WithUniqueKeepLast()); !slices.Equal(got, want) {
+		t.Errorf("UniqueFunc keeping the last = %v, want %v", got, want)
+	}
+
+	tags := []string{"Go", "go", "Rust", "GO"}
+	if got := util.UniqueFunc(tags, strings.ToLower); !slices.Equal(got, []string{"Go", "Rust"}) {
+		t.Errorf("case-insensitive UniqueFunc = %q", got)
+	}
+}
+
+func TestSortedSetOperations(t *testing.T) {
+	a := []int{1, 2, 2, 4, 5, 7}
+	b := []int{2, 3, 5, 5, 8}
+
+	tests := []struct {
+		name string
+		got  []int
+		want []int
+	}{
+		{"IntersectSorted", util.IntersectSorted(a, b), []int{2, 5}},
+		{"UnionSorted", util.UnionSorted(a, b), []int{1, 2, 3, 4, 5, 7, 8}},
+		{"DiffSorted a-b", util.DiffSorted(a, b), []int{1, 4, 7}},
+		{"DiffSorted b-a", util.DiffSorted(b, a), []int{3, 8}},
+		{"IntersectSorted empty", util.IntersectSorted(a, nil), []int{}},
+		{"UnionSorted empty", util.UnionSorted(nil, b), []int{2, 3, 5, 8}},
+		{"DiffSorted empty", util.DiffSorted(a, nil), []int{1, 2, 4, 5, 7}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if got := util.IntersectSorted([]string{"a", "b", "c"}, []string{"b", "c", "d"}); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("IntersectSorted of strings = %q", got)
+	}
+}
+
+func TestUniqueStringsOptions(t *testing.T) {
+	input := []string{" Ann@example.com", "bob@example.com", "ann@EXAMPLE.com ", "", "  ", "Bob@example.com"}
+	original := slices.Clone(input)
+
+	got := util.UniqueStrings(input, util.WithUniqueTrimSpace(), util.WithUniqueCaseInsensitive())
+	if want := []string{"Ann@example.com", "bob@example.com"}; !slices.Equal(got, want) {
+		t.Errorf("UniqueStrings = %q, want %q", got, want)
+	}
+	if !slices.Equal(input, original) {
+		t.Errorf("UniqueStrings with options modified its input: %q", input)
+	}
+
+	got = util.UniqueStrings([]string{"café", "cafe\u0301", "Straße", "STRASSE"},
+		util.WithUniqueNormalize(), util.WithUniqueCaseInsensitive())
+	if want := []string{"café", "Straße"}; !slices.Equal(got, want) {
+		t.Errorf("normalized UniqueStrings = %q, want %q", got, want)
+	}
+
+	got = util.UniqueStrings([]string{"café", "cafe\u0301"}, util.WithUniqueTrimSpace())
+	if len(got) != 2 {
+		t.Errorf("UniqueStrings without normalization = %q, want both spellings", got)
+	}
+}
@@ -0,0 +1,214 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/rs/xid"
+)
+
+const (
+	maxEmailLength    = 254
+	maxHostnameLength = 253
+	maxLabelLength    = 63
+	maxE164Digits     = 15
+)
+
+var (
+	// ErrInvalidEmail is the sentinel of ValidateEmail errors.
+	ErrInvalidEmail = errors.New("invalid e-mail address")
+
+	// ErrInvalidPhone is the sentinel of ValidatePhoneE164 errors.
+	ErrInvalidPhone = errors.New("invalid phone number")
+
+	// ErrInvalidHostname is the sentinel of ValidateHostname errors.
+	ErrInvalidHostname = errors.New("invalid hostname")
+
+	// ErrInvalidUUID is the sentinel of ValidateUUID errors.
+	ErrInvalidUUID = errors.New("invalid UUID")
+
+	// ErrInvalidXID is the sentinel of ValidateXID errors.
+	ErrInvalidXID = errors.New("invalid xid")
+
+	// ErrInvalidSlug is the sentinel of ValidateSlug errors.
+	ErrInvalidSlug = errors.New("invalid slug")
+)
+
+// ValidationError is returned by the Validate functions for a value not in
+// the expected format. It unwraps to the format's sentinel, such as
+// ErrInvalidEmail, so callers can test for it with errors.Is, or extract
+// the details with errors.As to build a field-level error response.
+//
+// Example:
+//
+//	var verr *ValidationError
+//	if errors.As(err, &verr) {
+//	    return MessageResponse(http.StatusBadRequest, verr.Reason)
+//	}
+type ValidationError struct {
+	// Err is the sentinel of the format, such as ErrInvalidEmail
+	Err error
+
+	// Value is the rejected value
+	Value string
+
+	// Reason says what is wrong with Value
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v %q: %s", e.Err, e.Value, e.Reason)
+}
+
+// Unwrap returns the format's sentinel.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func newValidationError(sentinel error, value, reason string) error {
+	return &ValidationError{Err: sentinel, Value: value, Reason: reason}
+}
+
+// ValidateEmail checks that s is a bare e-mail address such as
+// "jane@example.com", without a display name or angle brackets, whose
+// domain is a valid hostname with at least two labels. It checks the form
+// only; whether the address receives mail is for a confirmation message to
+// tell.
+//
+// Example:
+//
+//	if err := ValidateEmail(Normalize(form.Email, WithNormalizeEmail())); err != nil {
+//	    return err
+//	}
+func ValidateEmail(s string) error {
+	if len(s) > maxEmailLength {
+		return newValidationError(ErrInvalidEmail, s, "longer than 254 characters")
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return newValidationError(ErrInvalidEmail, s, "not a bare address")
+	}
+	domain := s[strings.LastIndexByte(s, '@')+1:]
+	if !strings.Contains(strings.TrimSuffix(domain, "."), ".") {
+		return newValidationError(ErrInvalidEmail, s, "domain has a single label")
+	}
+	if reason := hostnameProblem(domain); reason != "" {
+		return newValidationError(ErrInvalidEmail, s, "domain "+reason)
+	}
+	return nil
+}
+
+// ValidatePhoneE164 checks that s is a phone number in E.164 form: a "+"
+// followed by the country code and subscriber number, at most 15 digits in
+// all, without spaces or punctuation, such as "+254712345678".
+func ValidatePhoneE164(s string) error {
+	digits, ok := strings.CutPrefix(s, "+")
+	switch {
+	case !ok:
+		return newValidationError(ErrInvalidPhone, s, `missing "+" prefix`)
+	case digits == "" || len(digits) > maxE164Digits:
+		return newValidationError(ErrInvalidPhone, s, "must have 1 to 15 digits")
+	case digits[0] == '0':
+		return newValidationError(ErrInvalidPhone, s, "country code starts with 0")
+	case strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }) >= 0:
+		return newValidationError(ErrInvalidPhone, s, "must contain only digits")
+	}
+	return nil
+}
+
+// ValidateHostname checks that s is a DNS hostname as in RFC 1123: at most
+// 253 characters of dot-separated labels of 1 to 63 ASCII letters, digits
+// and hyphens, not starting or ending with a hyphen. A trailing dot is
+// allowed. IP addresses are rejected, as the last label may not be all
+// digits.
+func ValidateHostname(s string) error {
+	if reason := hostnameProblem(s); reason != "" {
+		return newValidationError(ErrInvalidHostname, s, reason)
+	}
+	return nil
+}
+
+// hostnameProblem returns why s is not a valid hostname, or "" when it is.
+func hostnameProblem(s string) string {
+	name := strings.TrimSuffix(s, ".")
+	if name == "" {
+		return "is empty"
+	}
+	if len(name) > maxHostnameLength {
+		return "longer than 253 characters"
+	}
+	labels := strings.Split(name, ".")
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength {
+			return "has a label that is empty or longer than 63 characters"
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "has a label starting or ending with a hyphen"
+		}
+		for i := range len(label) {
+			if c := label[i]; !isASCIIAlnum(c) && c != '-' {
+				return fmt.Sprintf("contains %q", c)
+			}
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "has an all-numeric top-level label"
+	}
+	return ""
+}
+
+// ValidateUUID checks that s is a UUID in its canonical 8-4-4-4-12
+// hexadecimal form, such as "0190f5e2-3c4b-7d8e-9f01-23456789abcd", in
+// either case. The version and variant are not checked.
+func ValidateUUID(s string) error {
+	if len(s) != uuidLength {
+		return newValidationError(ErrInvalidUUID, s, "must be 36 characters")
+	}
+	if _, err := parseUUID(s); err != nil {
+		return newValidationError(ErrInvalidUUID, s, "not in 8-4-4-4-12 hexadecimal form")
+	}
+	return nil
+}
+
+// ValidateULID checks that s is a ULID as created by ULID, in either case.
+// The error unwraps to ErrInvalidULID.
+func ValidateULID(s string) error {
+	if _, err := decodeULID(s); err != nil {
+		return newValidationError(ErrInvalidULID, s, "not 26 Crockford base32 characters")
+	}
+	return nil
+}
+
+// ValidateXID checks that s is an xid as created by IDString.
+func ValidateXID(s string) error {
+	if _, err := xid.FromString(s); err != nil {
+		return newValidationError(ErrInvalidXID, s, "not 20 base32hex characters")
+	}
+	return nil
+}
+
+// ValidateSlug checks that s is a URL slug: lower-case ASCII letters and
+// digits in words joined by single hyphens, such as "spring-sale-2024".
+func ValidateSlug(s string) error {
+	if s == "" {
+		return newValidationError(ErrInvalidSlug, s, "is empty")
+	}
+	for word := range strings.SplitSeq(s, "-") {
+		if word == "" {
+			return newValidationError(ErrInvalidSlug, s, "has a leading, trailing or doubled hyphen")
+		}
+		for i := range len(word) {
+			if c := word[i]; !isASCIIAlnum(c) || (c >= 'A' && c <= 'Z') {
+				return newValidationError(ErrInvalidSlug, s, fmt.Sprintf("contains %q", c))
+			}
+		}
+	}
+	return nil
+}
+
+func isASCIIAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,97 @@
+package util_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestValidateFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		sentinel error
+		valid    []string
+		invalid  []string
+	}{
+		{
+			name:     "email",
+			validate: util.ValidateEmail,
+			sentinel: util.ErrInvalidEmail,
+			valid:    []string{"jane@example.com", "jane.doe+news@mail.example.co.ke"},
+			invalid: []string{
+				"", "jane", "jane@", "@example.com", "jane@localhost", "Jane <jane@example.com>",
+				" jane@example.com", "jane@-example.com", "jane@example..com",
+				strings.Repeat("a", 250) + "@example.com",
+			},
+		},
+		{
+			name:     "phone",
+			validate: util.ValidatePhoneE164,
+			sentinel: util.ErrInvalidPhone,
+			valid:    []string{"+254712345678", "+14155552671"},
+			invalid:  []string{"", "254712345678", "+", "+0712345678", "+254 712 345678", "+1234567890123456"},
+		},
+		{
+			name:     "hostname",
+			validate: util.ValidateHostname,
+			sentinel: util.ErrInvalidHostname,
+			valid:    []string{"example.com", "api-1.example.com.", "localhost", "xn--bcher-kva.example"},
+			invalid: []string{
+				"", ".", "-example.com", "example-.com", "exa_mple.com", "a..b",
+				"10.0.0.1", strings.Repeat("a", 64) + ".com",
+			},
+		},
+		{
+			name:     "uuid",
+			validate: util.ValidateUUID,
+			sentinel: util.ErrInvalidUUID,
+			valid:    []string{"0190f5e2-3c4b-7d8e-9f01-23456789abcd", "0190F5E2-3C4B-7D8E-9F01-23456789ABCD"},
+			invalid:  []string{"", "0190f5e23c4b7d8e9f0123456789abcd", "0190f5e2-3c4b-7d8e-9f01-23456789abcg", "0190f5e2+3c4b-7d8e-9f01-23456789abcd"},
+		},
+		{
+			name:     "ulid",
+			validate: util.ValidateULID,
+			sentinel: util.ErrInvalidULID,
+			valid:    []string{util.ULID(), "01j1qw4c9z8k3v6r0m5e2a7d4b"},
+			invalid:  []string{"", "01J1QW4C9Z8K3V6R0M5E2A7D4", "81J1QW4C9Z8K3V6R0M5E2A7D4B", "01J1QW4C9Z8K3V6R0M5E2A7D4U"},
+		},
+		{
+			name:     "xid",
+			validate: util.ValidateXID,
+			sentinel: util.ErrInvalidXID,
+			valid:    []string{util.IDString()},
+			invalid:  []string{"", "9m4e2mr0ui3e8a215n4", "9m4e2mr0ui3e8a215n4!"},
+		},
+		{
+			name:     "slug",
+			validate: util.ValidateSlug,
+			sentinel: util.ErrInvalidSlug,
+			valid:    []string{"spring-sale-2024", "a", "v2"},
+			invalid:  []string{"", "-sale", "sale-", "spring--sale", "Spring-sale", "spring_sale", "spring sale"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, value := range tt.valid {
+				if err := tt.validate(value); err != nil {
+					t.Errorf("validate(%q) = %v, want nil", value, err)
+				}
+			}
+			for _, value := range tt.invalid {
+				err := tt.validate(value)
+				if !errors.Is(err, tt.sentinel) {
+					t.Errorf("validate(%q) = %v, want %v", value, err, tt.sentinel)
+					continue
+				}
+				var verr *util.ValidationError
+				if !errors.As(err, &verr) || verr.Value != value || verr.Reason == "" {
+					t.Errorf("validate(%q) = %#v, want a ValidationError for the value", value, err)
+				}
+			}
+		})
+	}
+}
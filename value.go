@@ -0,0 +1,52 @@
+package util
+
+// Ptr returns a pointer to a copy of v, for optional fields set from
+// constants or expressions.
+//
+// Example:
+//
+//	req := UpdateRequest{Name: Ptr("renamed"), Limit: Ptr(10)}
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Deref returns the value p points to, or def when p is nil.
+//
+// Example:
+//
+//	limit := Deref(req.Limit, 100)
+func Deref[T any](p *T, def T) T {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+// Coalesce returns the first of vals that is not the zero value of T, or
+// the zero value when all are.
+//
+// Example:
+//
+//	name := Coalesce(req.DisplayName, user.Name, "anonymous")
+func Coalesce[T comparable](vals ...T) T {
+	var zero T
+	for _, v := range vals {
+		if v != zero {
+			return v
+		}
+	}
+	return zero
+}
+
+// Must returns v and panics when err is not nil, for initialization that
+// cannot fail in a correct program, such as parsing constant patterns.
+//
+// Example:
+//
+//	var allowed = Must(NewCIDRSet([]string{"10.0.0.0/8"}))
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
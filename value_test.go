@@ -0,0 +1,47 @@
+package util_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pitabwire/util"
+)
+
+func TestPtrAndDeref(t *testing.T) {
+	p := util.Ptr(42)
+	if *p != 42 {
+		t.Errorf("*Ptr(42) = %d", *p)
+	}
+	if got := util.Deref(p, 7); got != 42 {
+		t.Errorf("Deref of a pointer = %d, want 42", got)
+	}
+	if got := util.Deref(nil, 7); got != 7 {
+		t.Errorf("Deref(nil) = %d, want the default 7", got)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	if got := util.Coalesce("", "first", "second"); got != "first" {
+		t.Errorf("Coalesce = %q, want first", got)
+	}
+	if got := util.Coalesce(0, 0); got != 0 {
+		t.Errorf("Coalesce of zeros = %d", got)
+	}
+	if got := util.Coalesce[int](); got != 0 {
+		t.Errorf("Coalesce() = %d", got)
+	}
+}
+
+func TestMust(t *testing.T) {
+	if got := util.Must(5, nil); got != 5 {
+		t.Errorf("Must = %d, want 5", got)
+	}
+
+	boom := errors.New("boom")
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, boom) {
+			t.Errorf("Must panicked with %v, want boom", err)
+		}
+	}()
+	util.Must(0, boom)
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// waitMaxBackoffFactor caps backoff at this multiple of the interval.
+	waitMaxBackoffFactor = 8
+	waitJitter           = 0.1
+)
+
+// WaitFor polls cond until it reports true, for waiting on a dependency
+// such as a database accepting queries or a migration finishing. It checks
+// at once, then after interval, backing off to up to 8 times interval
+// between checks, and logs each unmet check at debug level to the logger in
+// ctx.
+//
+// WaitFor returns nil once cond is true, and stops with cond's error when
+// it returns one, treating it as permanent; report a condition that may
+// still come true as false instead. It returns an error wrapping
+// context.DeadlineExceeded after timeout, or ctx's error when ctx ends
+// first. cond receives a context that ends at the timeout. WaitFor panics
+// if interval is not positive.
+//
+// Example:
+//
+//	err := WaitFor(ctx, 100*time.Millisecond, 30*time.Second, func(ctx context.Context) (bool, error) {
+//	    return db.PingContext(ctx) == nil, nil
+//	})
+func WaitFor(
+	ctx context.Context,
+	interval, timeout time.Duration,
+	cond func(ctx context.Context) (bool, error),
+) error {
+	if interval <= 0 {
+		panic("util.WaitFor: interval must be positive")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	retry := newBackoff(interval)
+	for attempt := 1; ; attempt++ {
+		done, err := cond(ctx)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		Log(ctx).WithField("attempt", attempt).WithField("retry_in", retry.next).Debug("condition not met, waiting")
+
+		if err = retry.wait(ctx); err != nil {
+			return fmt.Errorf("condition not met after %d attempts: %w", attempt, err)
+		}
+	}
+}
+
+// Until calls fn repeatedly until ctx is done, waiting interval after each
+// call returns, for loops such as consuming a queue or keeping a watch
+// open. A failing call is logged to the logger in ctx and the wait doubles,
+// up to 8 times interval, until a call succeeds again, so a broken
+// dependency is not hammered. Panics in fn are recovered and handled as
+// errors. Until panics if interval is not positive.
+//
+// Unlike Every, calls never overlap and the wait counts from the end of the
+// previous call.
+//
+// Example:
+//
+//	go Until(ctx, func(ctx context.Context) error {
+//	    return consumer.Drain(ctx)
+//	}, time.Second)
+func Until(ctx context.Context, fn func(ctx context.Context) error, interval time.Duration) {
+	if interval <= 0 {
+		panic("util.Until: interval must be positive")
+	}
+
+	retry := newBackoff(interval)
+	for ctx.Err() == nil {
+		if err := runTask(ctx, fn); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			Log(ctx).WithError(err).WithField("retry_in", retry.next).Error("repeated task failed")
+		} else {
+			retry.reset()
+		}
+
+		if retry.wait(ctx) != nil {
+			return
+		}
+	}
+}
+
+// backoff spaces out retries: each wait doubles the previous one, from
+// initial up to waitMaxBackoffFactor times initial, with jitter.
+type backoff struct {
+	initial time.Duration
+	// next is the wait before the coming retry, before jitter
+	next time.Duration
+}
+
+func newBackoff(initial time.Duration) *backoff {
+	return &backoff{initial: initial, next: initial}
+}
+
+// wait sleeps for the next delay and doubles it, returning ctx's error when
+// ctx ends first.
+func (b *backoff) wait(ctx context.Context) error {
+	timer := time.NewTimer(Jitter(b.next, waitJitter))
+	defer timer.Stop()
+	b.next = min(b.next*2, b.initial*waitMaxBackoffFactor) //nolint:mnd // exponential backoff
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reset starts over from the initial delay, after a success.
+func (b *backoff) reset() {
+	b.next = b.initial
+}
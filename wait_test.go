@@ -0,0 +1,87 @@
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestWaitFor(t *testing.T) {
+	var calls atomic.Int32
+	err := util.WaitFor(t.Context(), time.Millisecond, time.Second, func(context.Context) (bool, error) {
+		return calls.Add(1) == 3, nil
+	})
+	if err != nil || calls.Load() != 3 {
+		t.Fatalf("WaitFor = %v after %d calls, want nil after 3", err, calls.Load())
+	}
+}
+
+func TestWaitForPermanentError(t *testing.T) {
+	errBroken := errors.New("broken")
+	var calls atomic.Int32
+	err := util.WaitFor(t.Context(), time.Millisecond, time.Second, func(context.Context) (bool, error) {
+		calls.Add(1)
+		return false, errBroken
+	})
+	if !errors.Is(err, errBroken) || calls.Load() != 1 {
+		t.Fatalf("WaitFor = %v after %d calls, want %v after 1", err, calls.Load(), errBroken)
+	}
+}
+
+func TestWaitForTimeout(t *testing.T) {
+	start := time.Now()
+	err := util.WaitFor(t.Context(), time.Millisecond, 30*time.Millisecond, func(context.Context) (bool, error) {
+		return false, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitFor = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("WaitFor took %v to time out", elapsed)
+	}
+}
+
+func TestUntil(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	var calls atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		util.Until(ctx, func(context.Context) error {
+			calls.Add(1)
+			return nil
+		}, time.Millisecond)
+	}()
+
+	waitFor(t, func() bool { return calls.Load() >= 5 })
+	cancel()
+	<-done
+}
+
+func TestUntilBacksOffOnErrors(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := util.ContextWithLogger(t.Context(), util.NewLogger(t.Context(), util.WithLogOutput(&buf)))
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	var failures atomic.Int32
+	util.Until(ctx, func(context.Context) error {
+		failures.Add(1)
+		panic("dependency down")
+	}, 10*time.Millisecond)
+
+	// Waits of 10, 20, 40 and 80ms fit about 4 calls in 100ms, against 10
+	// without backoff.
+	if n := failures.Load(); n < 2 || n > 6 {
+		t.Fatalf("fn called %d times in 100ms, want about 4", n)
+	}
+	if !strings.Contains(buf.String(), "repeated task failed") {
+		t.Fatalf("failures not logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,213 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrTaskPanicked is wrapped by the error reported for a task that panicked.
+var ErrTaskPanicked = errors.New("task panicked")
+
+// workerPoolOptions contains configuration for a WorkerPool.
+type workerPoolOptions struct {
+	// failFast cancels the remaining tasks after the first failure
+	failFast bool
+}
+
+// WorkerPoolOption is a function that configures a WorkerPool.
+type WorkerPoolOption func(*workerPoolOptions)
+
+// WithFailFast cancels the pool's context on the first failed task, so
+// running tasks can stop early and queued ones are not started, and makes
+// Wait return only that first error.
+func WithFailFast() WorkerPoolOption {
+	return func(o *workerPoolOptions) {
+		o.failFast = true
+	}
+}
+
+// WorkerPool runs tasks with bounded concurrency and collects their errors.
+// Tasks receive a context derived from the pool's, so the logger, tenancy
+// and other values in it reach the workers. A panicking task is recovered
+// and reported as an error wrapping ErrTaskPanicked with its stack.
+//
+// Example:
+//
+//	pool := NewWorkerPool(ctx, 8, WithFailFast())
+//	for _, id := range ids {
+//	    pool.Go(func(ctx context.Context) error {
+//	        return reindex(ctx, id)
+//	    })
+//	}
+//	if err := pool.Wait(); err != nil {
+//	    return err
+//	}
+type WorkerPool struct {
+	ctx     context.Context //nolint:containedctx // passed to every task
+	cancel  context.CancelCauseFunc
+	slots   chan struct{}
+	options workerPoolOptions
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	errs    []error
+	skipped bool
+}
+
+// NewWorkerPool returns a WorkerPool running at most limit tasks at once,
+// or any number when limit is less than one.
+func NewWorkerPool(ctx context.Context, limit int, opts ...WorkerPoolOption) *WorkerPool {
+	var options workerPoolOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	p := &WorkerPool{ctx: ctx, cancel: cancel, options: options}
+	if limit > 0 {
+		p.slots = make(chan struct{}, limit)
+	}
+	return p
+}
+
+// Go runs task in a new goroutine, first waiting for a free slot when the
+// pool is at its limit. Once the pool's context is done, tasks are no longer
+// started and Wait reports the context's error instead.
+func (p *WorkerPool) Go(task func(ctx context.Context) error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+		case <-p.ctx.Done():
+			p.skip()
+			return
+		}
+	}
+	if p.ctx.Err() != nil {
+		p.release()
+		p.skip()
+		return
+	}
+
+	p.wg.Go(func() {
+		defer p.release()
+		if err := runTask(p.ctx, task); err != nil {
+			p.fail(err)
+		}
+	})
+}
+
+// Wait waits for the started tasks to finish and returns their errors
+// joined, or only the first with WithFailFast. The pool cannot be reused
+// afterwards.
+func (p *WorkerPool) Wait() error {
+	p.wg.Wait()
+	defer p.cancel(nil)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.options.failFast && len(p.errs) > 0 {
+		return p.errs[0]
+	}
+	errs := p.errs
+	if p.skipped {
+		errs = append(errs, context.Cause(p.ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *WorkerPool) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+func (p *WorkerPool) skip() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.skipped = true
+}
+
+func (p *WorkerPool) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errs = append(p.errs, err)
+	if p.options.failFast && len(p.errs) == 1 {
+		p.cancel(err)
+	}
+}
+
+// RunConcurrently runs tasks with at most limit at once, or all at once
+// when limit is less than one, and returns their errors joined once all
+// have finished. It is a WorkerPool for a fixed set of tasks.
+//
+// Example:
+//
+//	err := RunConcurrently(ctx, 4,
+//	    func(ctx context.Context) error { return warmCache(ctx) },
+//	    func(ctx context.Context) error { return loadTemplates(ctx) },
+//	)
+func RunConcurrently(ctx context.Context, limit int, tasks ...func(ctx context.Context) error) error {
+	pool := NewWorkerPool(ctx, limit)
+	for _, task := range tasks {
+		pool.Go(task)
+	}
+	return pool.Wait()
+}
+
+// runTask calls task, converting a panic into an error wrapping
+// ErrTaskPanicked.
+func runTask(ctx context.Context, task func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError(r)
+		}
+	}()
+	return task(ctx)
+}
+
+// PanicError is the error reported for a task that panicked, wrapping
+// ErrTaskPanicked. It keeps the panic value and the stack of the panicking
+// goroutine, which are logged as separate attributes.
+//
+// Example:
+//
+//	var panicErr *PanicError
+//	if errors.As(err, &panicErr) {
+//	    Log(ctx).WithField("stack", panicErr.Stack).Error("report failed")
+//	}
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack string
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v\n%s", ErrTaskPanicked, e.Value, e.Stack)
+}
+
+// Unwrap returns ErrTaskPanicked.
+func (e *PanicError) Unwrap() error {
+	return ErrTaskPanicked
+}
+
+// LogValue implements slog.LogValuer, logging the panic value and stack as
+// separate attributes.
+func (e *PanicError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("error", ErrTaskPanicked.Error()),
+		slog.Any("panic", e.Value),
+		slog.String("stack", e.Stack),
+	)
+}
+
+// panicError converts a recovered panic into a PanicError with the stack of
+// the panicking goroutine.
+func panicError(r any) error {
+	return &PanicError{Value: r, Stack: string(debug.Stack())}
+}
@@ -0,0 +1,90 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pitabwire/util"
+)
+
+func TestWorkerPoolLimit(t *testing.T) {
+	pool := util.NewWorkerPool(t.Context(), 2)
+	var running, peak atomic.Int32
+	for range 8 {
+		pool.Go(func(context.Context) error {
+			n := running.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	if err := pool.Wait(); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestWorkerPoolErrors(t *testing.T) {
+	errA := errors.New("a failed")
+	err := util.RunConcurrently(t.Context(), 0,
+		func(context.Context) error { return errA },
+		func(context.Context) error { panic("boom") },
+		func(context.Context) error { return nil },
+	)
+	if !errors.Is(err, errA) || !errors.Is(err, util.ErrTaskPanicked) {
+		t.Errorf("RunConcurrently = %v, want the failure and the panic joined", err)
+	}
+	if !strings.Contains(err.Error(), "boom") {
+		t.Errorf("panic error %q does not include the panic value", err)
+	}
+}
+
+func TestWorkerPoolFailFast(t *testing.T) {
+	errFirst := errors.New("first")
+	pool := util.NewWorkerPool(t.Context(), 1, util.WithFailFast())
+
+	pool.Go(func(context.Context) error { return errFirst })
+	var started atomic.Bool
+	pool.Go(func(context.Context) error {
+		started.Store(true)
+		return nil
+	})
+
+	if err := pool.Wait(); !errors.Is(err, errFirst) {
+		t.Errorf("Wait = %v, want only the first error", err)
+	}
+	if started.Load() {
+		t.Error("a task started after the pool failed")
+	}
+}
+
+func TestWorkerPoolContextValues(t *testing.T) {
+	ctx := util.ContextWithRequestID(t.Context(), "req-1")
+	err := util.RunConcurrently(ctx, 1, func(ctx context.Context) error {
+		if got := util.GetRequestID(ctx); got != "req-1" {
+			t.Errorf("task context request ID = %q", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunConcurrently = %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := util.RunConcurrently(cancelled, 1, func(context.Context) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunConcurrently on a cancelled context = %v", err)
+	}
+}